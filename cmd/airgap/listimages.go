@@ -28,10 +28,14 @@ import (
 type CmdOpts config.CLIOptions
 
 func NewAirgapListImagesCmd() *cobra.Command {
+	var all bool
 	cmd := &cobra.Command{
-		Use:     "list-images",
-		Short:   "List image names and version needed for air-gap install",
-		Example: `k0s airgap list-images`,
+		Use:   "list-images",
+		Short: "List image names and version needed for air-gap install",
+		Long: `Lists the images the cluster runs with the config: the images of the network provider in use, and
+the kube-proxy image unless kube-proxy is disabled. Use --all to list the images of every provider.`,
+		Example: `k0s airgap list-images
+k0s airgap list-images --config k0s.yaml`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// we don't need warning messages in case of default config
 			logrus.SetLevel(logrus.ErrorLevel)
@@ -40,13 +44,17 @@ func NewAirgapListImagesCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			uris := airgap.GetImageURIs(cfg.Spec.Images)
+			uris := airgap.GetConfiguredImageURIs(cfg.Spec)
+			if all {
+				uris = airgap.GetImageURIs(cfg.Spec.Images)
+			}
 			for _, uri := range uris {
 				fmt.Println(uri)
 			}
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&all, "all", false, "list the images of every network provider, regardless of the config")
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
 }
@@ -17,11 +17,14 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"path"
 	"path/filepath"
@@ -40,18 +43,22 @@ import (
 	"github.com/k0sproject/k0s/pkg/config"
 	"github.com/k0sproject/k0s/pkg/etcd"
 	"github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/oidc"
+	"github.com/k0sproject/k0s/pkg/token"
 )
 
 type CmdOpts config.CLIOptions
 
 const (
-	workerRole     = "worker"
-	controllerRole = "controller"
+	workerRole      = "worker"
+	controllerRole  = "controller"
+	attestationRole = "attestation"
 )
 
 var allowedUsageByRole = map[string]string{
-	workerRole:     "usage-bootstrap-api-worker-calls",
-	controllerRole: "usage-controller-join",
+	workerRole:      "usage-bootstrap-api-worker-calls",
+	controllerRole:  "usage-controller-join",
+	attestationRole: "usage-bootstrap-api-attestation",
 }
 
 func NewAPICmd() *cobra.Command {
@@ -101,6 +108,25 @@ func (c *CmdOpts) startAPI() error {
 		c.workerHandler(c.kubeConfigHandler()),
 	)
 
+	verifier, err := c.newAttestationVerifier()
+	if err != nil {
+		return err
+	}
+	router.Path(prefix + "/attestation/challenge").Methods("POST").Handler(
+		c.attestationHandler(c.attestationChallengeHandler(verifier)),
+	)
+	router.Path(prefix + "/attestation/quote").Methods("POST").Handler(
+		c.attestationHandler(c.attestationQuoteHandler(verifier)),
+	)
+
+	if oidcSpec := c.ClusterConfig.Spec.OIDCProvider; oidcSpec != nil && oidcSpec.Enabled {
+		provider, err := oidc.NewProvider(oidcSpec, oidcSpec.IssuerURL(c.ClusterConfig.Spec.API), path.Join(c.K0sVars.CertRootDir, "oidc.key"))
+		if err != nil {
+			return err
+		}
+		provider.Register(router, "/oidc")
+	}
+
 	srv := &http.Server{
 		Handler:      router,
 		Addr:         fmt.Sprintf(":%d", c.ClusterConfig.Spec.API.K0sAPIPort),
@@ -125,7 +151,7 @@ func (c *CmdOpts) etcdHandler() http.Handler {
 			sendError(err, resp)
 			return
 		}
-		logrus.Infof("etcd API, adding new member: %s", etcdReq.PeerAddress)
+		logrus.Infof("etcd API, adding new member: %s (learner: %t)", etcdReq.PeerAddress, etcdReq.Learner)
 		err = etcdReq.Validate()
 		if err != nil {
 			sendError(err, resp)
@@ -138,7 +164,12 @@ func (c *CmdOpts) etcdHandler() http.Handler {
 			return
 		}
 
-		memberList, err := etcdClient.AddMember(ctx, etcdReq.Node, etcdReq.PeerAddress)
+		// the use is recorded first, a token used up by a concurrent join must not add the member
+		if !c.recordTokenUse(resp, req, etcdReq.Node) {
+			return
+		}
+
+		memberList, err := etcdClient.AddMember(ctx, etcdReq.Node, etcdReq.PeerAddress, etcdReq.Learner)
 		if err != nil {
 			sendError(err, resp)
 			return
@@ -268,6 +299,21 @@ func (c *CmdOpts) caHandler() http.Handler {
 		}
 		caResp.SAPub = saPub
 
+		if oidcKey, err := ioutil.ReadFile(path.Join(c.K0sVars.CertRootDir, "oidc.key")); err == nil {
+			caResp.OIDCKey = oidcKey
+		}
+		if oidcPub, err := ioutil.ReadFile(path.Join(c.K0sVars.CertRootDir, "oidc.pub")); err == nil {
+			caResp.OIDCPub = oidcPub
+		}
+
+		// on etcd the join completes with adding the etcd member, the token is consumed there
+		if c.ClusterConfig.Spec.Storage.Type != v1beta1.EtcdStorageType {
+			host, _, _ := net.SplitHostPort(req.RemoteAddr)
+			if !c.recordTokenUse(resp, req, host) {
+				return
+			}
+		}
+
 		resp.Header().Set("content-type", "application/json")
 		if err := json.NewEncoder(resp).Encode(caResp); err != nil {
 			sendError(err, resp)
@@ -276,57 +322,92 @@ func (c *CmdOpts) caHandler() http.Handler {
 	})
 }
 
+// recordTokenUse consumes a use of the token of the joining controller before the join is answered. The join is
+// refused if the token has been used up by a concurrent join in the meantime, or if the use can't be recorded.
+func (c *CmdOpts) recordTokenUse(resp http.ResponseWriter, req *http.Request, node string) bool {
+	parts := strings.Split(strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), ".")
+	err := token.NewManagerForClient(c.KubeClient).RecordUse(parts[0], node)
+	switch {
+	case errors.Is(err, token.ErrTokenConsumed):
+		sendError(fmt.Errorf("token %s has been used up", parts[0]), resp, http.StatusUnauthorized)
+		return false
+	case err != nil:
+		sendError(fmt.Errorf("failed to record the use of token %s by %s: %w", parts[0], node, err), resp)
+		return false
+	}
+	return true
+}
+
 /** The token is in form of xyz.foobar where:
 - xyz: the token "ID" in kube api
 - foobar: the token itself
 We need to validate:
 - that we find a secret with the ID
 - that the token matches whats inside the secret
+The usages of the secret are the roles granted by the token.
 */
-func (c *CmdOpts) isValidToken(token string, role string) bool {
+func (c *CmdOpts) tokenRoles(token string) (map[string]bool, bool) {
 	parts := strings.Split(token, ".")
-	logrus.Debugf("token parts: %v", parts)
 	if len(parts) != 2 {
-		return false
+		return nil, false
 	}
 
 	secretName := fmt.Sprintf("bootstrap-token-%s", parts[0])
 	secret, err := c.KubeClient.CoreV1().Secrets("kube-system").Get(context.TODO(), secretName, v1.GetOptions{})
 	if err != nil {
 		logrus.Errorf("failed to get bootstrap token: %s", err.Error())
-		return false
+		return nil, false
 	}
 
-	if string(secret.Data["token-secret"]) != parts[1] {
-		return false
+	if subtle.ConstantTimeCompare(secret.Data["token-secret"], []byte(parts[1])) != 1 {
+		return nil, false
 	}
 
-	usageValue, ok := secret.Data[allowedUsageByRole[role]]
-	if !ok || string(usageValue) != "true" {
-		return false
+	// the token cleaner of the controller manager deletes the expired tokens only periodically
+	if expiration, found := secret.Data["expiration"]; found {
+		expiry, err := time.Parse(time.RFC3339, string(expiration))
+		if err != nil || time.Now().After(expiry) {
+			return nil, false
+		}
 	}
 
-	return true
+	roles := map[string]bool{}
+	for role, usage := range allowedUsageByRole {
+		roles[role] = string(secret.Data[usage]) == "true"
+	}
+	return roles, true
+}
+
+// authorize checks the token of a request to an endpoint of the role. An unknown or invalid token is unauthorized, a
+// valid token which doesn't grant the role is forbidden, e.g. a worker token calling the controller join endpoints.
+func (c *CmdOpts) authorize(token string, role string) int {
+	roles, valid := c.tokenRoles(token)
+	switch {
+	case !valid:
+		return http.StatusUnauthorized
+	case !roles[role]:
+		return http.StatusForbidden
+	}
+	return http.StatusOK
 }
 
 func (c *CmdOpts) authMiddleware(next http.Handler, role string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auth := r.Header.Get("Authorization")
-		if auth == "" {
+		if !strings.HasPrefix(auth, "Bearer ") {
 			sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
 			return
 		}
 
-		parts := strings.Split(auth, "Bearer ")
-		if len(parts) == 2 {
-			token := parts[1]
-			if !c.isValidToken(token, role) {
-				sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
-				return
-			}
-		} else {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		switch c.authorize(token, role) {
+		case http.StatusUnauthorized:
 			sendError(fmt.Errorf("go away"), w, http.StatusUnauthorized)
 			return
+		case http.StatusForbidden:
+			tokenID := strings.Split(token, ".")[0]
+			sendError(fmt.Errorf("token %s is not allowed to call %s, it's not a %s token", tokenID, r.URL.Path, role), w, http.StatusForbidden)
+			return
 		}
 
 		next.ServeHTTP(w, r)
@@ -340,3 +421,7 @@ func (c *CmdOpts) controllerHandler(next http.Handler) http.Handler {
 func (c *CmdOpts) workerHandler(next http.Handler) http.Handler {
 	return c.authMiddleware(next, workerRole)
 }
+
+func (c *CmdOpts) attestationHandler(next http.Handler) http.Handler {
+	return c.authMiddleware(next, attestationRole)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func bootstrapToken(id, secret, usage string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "bootstrap-token-" + id, Namespace: "kube-system"},
+		Data: map[string][]byte{
+			"token-id":     []byte(id),
+			"token-secret": []byte(secret),
+			usage:          []byte("true"),
+		},
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	c := &CmdOpts{KubeClient: fake.NewSimpleClientset(
+		bootstrapToken("abcdef", "0123456789abcdef", allowedUsageByRole[workerRole]),
+		bootstrapToken("ghijkl", "0123456789abcdef", allowedUsageByRole[controllerRole]),
+	)}
+
+	assert.Equal(t, http.StatusOK, c.authorize("abcdef.0123456789abcdef", workerRole))
+	assert.Equal(t, http.StatusForbidden, c.authorize("abcdef.0123456789abcdef", controllerRole))
+	assert.Equal(t, http.StatusOK, c.authorize("ghijkl.0123456789abcdef", controllerRole))
+	assert.Equal(t, http.StatusForbidden, c.authorize("ghijkl.0123456789abcdef", attestationRole))
+	assert.Equal(t, http.StatusUnauthorized, c.authorize("abcdef.wrong", workerRole))
+	assert.Equal(t, http.StatusUnauthorized, c.authorize("unknown.0123456789abcdef", workerRole))
+	assert.Equal(t, http.StatusUnauthorized, c.authorize("abcdef", workerRole))
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	c := &CmdOpts{KubeClient: fake.NewSimpleClientset(
+		bootstrapToken("abcdef", "0123456789abcdef", allowedUsageByRole[workerRole]),
+	)}
+	handler := c.controllerHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for auth, status := range map[string]int{
+		"":                               http.StatusUnauthorized,
+		"Basic abcdef.0123456789abcdef":  http.StatusUnauthorized,
+		"Bearer abcdef.0123456789abcdef": http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1beta1/ca", nil)
+		req.Header.Set("Authorization", auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, auth)
+	}
+}
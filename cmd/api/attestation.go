@@ -0,0 +1,94 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/attestation"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+// attestedTokenTTL is the lifetime of the join tokens issued for the attested workers
+const attestedTokenTTL = 10 * time.Minute
+
+// newAttestationVerifier creates the verifier with a key derived from the CA key, so that all the controllers can
+// verify the challenges of each other
+func (c *CmdOpts) newAttestationVerifier() (*attestation.Verifier, error) {
+	caKey, err := ioutil.ReadFile(filepath.Join(c.K0sVars.CertRootDir, "ca.key"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA key for the attestation: %w", err)
+	}
+	key := sha256.Sum256(append([]byte("k0s-tpm-attestation:"), caKey...))
+	return attestation.NewVerifier(key[:], attestation.NewEnrollment(c.KubeClient)), nil
+}
+
+func (c *CmdOpts) attestationChallengeHandler(verifier *attestation.Verifier) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var challengeReq v1beta1.AttestationChallengeRequest
+		if err := json.NewDecoder(req.Body).Decode(&challengeReq); err != nil {
+			sendError(err, resp, http.StatusBadRequest)
+			return
+		}
+		challenge, err := verifier.Challenge(challengeReq)
+		if err != nil {
+			sendError(err, resp, http.StatusForbidden)
+			return
+		}
+		resp.Header().Set("content-type", "application/json")
+		if err := json.NewEncoder(resp).Encode(challenge); err != nil {
+			sendError(err, resp)
+			return
+		}
+	})
+}
+
+func (c *CmdOpts) attestationQuoteHandler(verifier *attestation.Verifier) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var quoteReq v1beta1.AttestationQuoteRequest
+		if err := json.NewDecoder(req.Body).Decode(&quoteReq); err != nil {
+			sendError(err, resp, http.StatusBadRequest)
+			return
+		}
+		fingerprint, err := verifier.Verify(quoteReq)
+		if err != nil {
+			sendError(fmt.Errorf("TPM attestation failed: %w", err), resp, http.StatusForbidden)
+			return
+		}
+
+		joinToken, err := token.CreateKubeletBootstrapConfig(c.ClusterConfig, c.K0sVars, workerRole, attestedTokenTTL, token.CreateOptions{})
+		if err != nil {
+			sendError(err, resp)
+			return
+		}
+		logrus.Infof("issued a join token for the worker attested with endorsement key %s", fingerprint)
+
+		resp.Header().Set("content-type", "application/json")
+		if err := json.NewEncoder(resp).Encode(v1beta1.AttestationResponse{Token: joinToken}); err != nil {
+			sendError(err, resp)
+			return
+		}
+	})
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/attestation"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+type CmdOpts config.CLIOptions
+
+func NewAttestationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attestation",
+		Short: "Manage the TPM endorsement keys of the worker nodes allowed to join with attestation",
+	}
+
+	cmd.SilenceUsage = true
+	cmd.AddCommand(ekCmd())
+	cmd.AddCommand(enrollCmd())
+	cmd.AddCommand(listCmd())
+	cmd.AddCommand(removeCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+func enrollment() (*attestation.Enrollment, error) {
+	c := CmdOpts(config.GetCmdOpts())
+	client, err := kubernetes.NewClient(c.K0sVars.AdminKubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	return attestation.NewEnrollment(client), nil
+}
+
+func ekCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "ek",
+		Short:   "Print the endorsement key of the TPM of this node",
+		Example: `k0s attestation ek > ek.pem`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			tpm, err := attestation.NewTPM(filepath.Join(c.K0sVars.DataDir, "attestation"))
+			if err != nil {
+				return err
+			}
+			defer tpm.Close()
+			ek, err := tpm.EKPublic()
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(ek))
+			return nil
+		},
+	}
+}
+
+func enrollCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "enroll [ek.pem...]",
+		Short:   "Allow the nodes with the given endorsement keys to join",
+		Example: `k0s attestation enroll ek.pem`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := enrollment()
+			if err != nil {
+				return err
+			}
+			for _, path := range args {
+				ek, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				fingerprint, err := e.Enroll(ek)
+				if err != nil {
+					return fmt.Errorf("failed to enroll %s: %w", path, err)
+				}
+				fmt.Printf("endorsement key %s enrolled successfully\n", fingerprint)
+			}
+			return nil
+		},
+	}
+}
+
+func listCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the fingerprints of the enrolled endorsement keys",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := enrollment()
+			if err != nil {
+				return err
+			}
+			keys, err := e.List()
+			if err != nil {
+				return err
+			}
+			if len(keys) == 0 {
+				fmt.Println("No enrolled endorsement keys found")
+				return nil
+			}
+			fingerprints := make([]string, 0, len(keys))
+			for fingerprint := range keys {
+				fingerprints = append(fingerprints, fingerprint)
+			}
+			sort.Strings(fingerprints)
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Fingerprint"})
+			table.SetAutoFormatHeaders(true)
+			table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+			table.SetAlignment(tablewriter.ALIGN_LEFT)
+			table.SetCenterSeparator("")
+			table.SetColumnSeparator("")
+			table.SetRowSeparator("")
+			table.SetHeaderLine(false)
+			table.SetBorder(false)
+			table.SetNoWhiteSpace(true)
+			for _, fingerprint := range fingerprints {
+				table.Append([]string{fingerprint})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+func removeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove [fingerprint...]",
+		Short:   "Remove enrolled endorsement keys",
+		Example: `k0s attestation remove 3f2c...`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			e, err := enrollment()
+			if err != nil {
+				return err
+			}
+			for _, fingerprint := range args {
+				if err := e.Remove(fingerprint); err != nil {
+					return err
+				}
+				fmt.Printf("endorsement key %s removed successfully\n", fingerprint)
+			}
+			return nil
+		},
+	}
+}
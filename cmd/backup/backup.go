@@ -34,7 +34,10 @@ import (
 
 type CmdOpts config.CLIOptions
 
-var savePath string
+var (
+	savePath string
+	output   string
+)
 
 func NewBackupCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -51,7 +54,8 @@ func NewBackupCmd() *cobra.Command {
 		},
 		PreRunE: preRunValidateConfig,
 	}
-	cmd.Flags().StringVar(&savePath, "save-path", "", "destination directory path for backup assets")
+	cmd.Flags().StringVar(&output, "output", "", "destination for the backup archive, a directory path or an S3 location s3://bucket/prefix (default: current directory)")
+	cmd.Flags().StringVar(&savePath, "save-path", "", "destination directory path for backup assets, same as --output")
 	cmd.SilenceUsage = true
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
@@ -69,8 +73,14 @@ func (c *CmdOpts) backup() error {
 		logger.Fatal("this command must be run as root!")
 	}
 
-	if !util.DirExists(savePath) {
-		logger.Fatalf("the save-path directory (%v) does not exist.", savePath)
+	if output == "" {
+		output = savePath
+	}
+	if output == "" {
+		output = "."
+	}
+	if backup.IsLocal(output) && !util.DirExists(strings.TrimPrefix(output, "file://")) {
+		logger.Fatalf("the output directory (%v) does not exist.", output)
 	}
 
 	if !util.DirExists(c.K0sVars.DataDir) {
@@ -85,7 +95,7 @@ func (c *CmdOpts) backup() error {
 		if err != nil {
 			return err
 		}
-		return mgr.RunBackup(c.CfgFile, c.ClusterConfig.Spec, c.K0sVars, savePath)
+		return mgr.RunBackup(c.CfgFile, c.ClusterConfig.Spec, c.K0sVars, output)
 	}
 	return fmt.Errorf("backup command must be run on the controller node, have `%s`", role)
 }
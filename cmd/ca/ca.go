@@ -0,0 +1,86 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ca
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/certificate"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/install"
+)
+
+type CmdOpts config.CLIOptions
+
+func NewCACmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Rotate the cluster CA in phases. Must be run as root (or with sudo) on the controllers",
+		Long: `Rotates the CA of the cluster in three phases, each of them run on every controller and followed by a
+restart of k0s on the controllers:
+
+  prepare   creates the next CA and makes the cluster trust it next to the current one
+  activate  issues the certificates with the next CA, the current one stays trusted
+  finish    stops trusting the previous CA
+
+The workers get the trusted CAs from the controllers and renew their client certificates by themselves.`,
+	}
+
+	cmd.SilenceUsage = true
+	cmd.AddCommand(phaseCmd("prepare", "Create the next CA and trust it next to the current one", (*certificate.Manager).PrepareCARotation))
+	cmd.AddCommand(phaseCmd("activate", "Issue the certificates with the next CA, the current one stays trusted", (*certificate.Manager).ActivateCARotation))
+	cmd.AddCommand(phaseCmd("finish", "Stop trusting the previous CA", (*certificate.Manager).FinishCARotation))
+	cmd.AddCommand(statusCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+func phaseCmd(phase, short string, run func(*certificate.Manager) error) *cobra.Command {
+	return &cobra.Command{
+		Use:   phase,
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if os.Geteuid() != 0 {
+				return fmt.Errorf("this command must be run as root")
+			}
+			if role := install.GetRoleByStagedKubelet(c.K0sVars.BinDir); !strings.Contains(role, "controller") {
+				return fmt.Errorf("ca command must be run on the controller node, have `%s`", role)
+			}
+			if err := run(&certificate.Manager{K0sVars: c.K0sVars}); err != nil {
+				return err
+			}
+			fmt.Printf("the CA rotation is %s, restart k0s to apply it\n", certificate.GetCARotationPhase(c.K0sVars.CertRootDir))
+			return nil
+		},
+	}
+}
+
+func statusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the phase of the CA rotation",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			fmt.Printf("CA rotation: %s\n", certificate.GetCARotationPhase(c.K0sVars.CertRootDir))
+			return nil
+		},
+	}
+}
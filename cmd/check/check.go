@@ -0,0 +1,100 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/cis"
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+type CmdOpts config.CLIOptions
+
+var output string
+
+func NewCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Helper command for checking the node against security benchmarks",
+	}
+	cmd.AddCommand(checkCISCmd())
+	cmd.SilenceUsage = true
+	return cmd
+}
+
+func checkCISCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cis",
+		Short: "Evaluate the running configuration against the CIS Kubernetes benchmark",
+		Long: `Evaluate the flags, file permissions and authentication settings of the k0s components running
+on this node against the applicable CIS Kubernetes benchmark controls.
+Controls for components not running on this node are skipped.`,
+		Example: `k0s check cis
+k0s check cis -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			results := cis.Run(c.K0sVars)
+
+			switch output {
+			case "json":
+				jsn, _ := json.MarshalIndent(results, "", "   ")
+				fmt.Println(string(jsn))
+			case "yaml":
+				ym, _ := yaml.Marshal(results)
+				fmt.Println(string(ym))
+			default:
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"ID", "Status", "Check", "Remediation"})
+				table.SetAutoWrapText(false)
+				table.SetAutoFormatHeaders(true)
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetCenterSeparator("")
+				table.SetColumnSeparator("")
+				table.SetRowSeparator("")
+				table.SetHeaderLine(false)
+				table.SetBorder(false)
+				table.SetTablePadding("\t") // pad with tabs
+				table.SetNoWhiteSpace(true)
+				for _, r := range results {
+					table.Append([]string{r.ID, r.Status, r.Text, r.Remediation})
+				}
+				table.Render()
+			}
+
+			failed := 0
+			for _, r := range results {
+				if r.Status == cis.StatusFail {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d CIS benchmark checks failed", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "out", "o", "", "sets type of output to json or yaml")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
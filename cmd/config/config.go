@@ -0,0 +1,135 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/exitcode"
+)
+
+type CmdOpts config.CLIOptions
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Configuration related sub-commands",
+	}
+	cmd.AddCommand(NewValidateCmd())
+	cmd.AddCommand(NewDefaultCmd())
+	cmd.AddCommand(NewRenderManifestsCmd())
+	cmd.SilenceUsage = true
+	return cmd
+}
+
+// NewValidateCmd validates the config file, the command fails when the config is invalid
+func NewValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the k0s configuration file",
+		Long: `Parses the config file and checks it the same way the controller does on start: the syntax, the
+values of each section, the overlaps of the networks, the clashes of the ports and the unsupported
+combinations of features. The problems are listed, and the command fails, if the config is invalid.`,
+		Example: `   k0s config validate --config path_to_config.yaml
+   k0s config validate --config base.yaml --config site.d/
+   cat k0s.yaml | k0s config validate --config -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			source := c.CfgFile
+			switch source {
+			case "":
+				source = "the default config"
+			case "-":
+				source = "the config from stdin"
+			}
+			if _, err := config.ValidateYaml(c.CfgFile, c.K0sVars); err != nil {
+				return fmt.Errorf("%s is invalid:\n%w", source, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", source)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+// NewDefaultCmd prints the default config, with every section filled in. The config given with --config has no say
+// in it, the flag is refused rather than silently ignored.
+func NewDefaultCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "default",
+		Short: "Output the default k0s configuration yaml to stdout",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("config") {
+				return exitcode.Wrap(exitcode.Usage, fmt.Errorf("the default config doesn't depend on --config, use `k0s config validate --config` to check a config"))
+			}
+			c := CmdOpts(config.GetCmdOpts())
+			conf, err := yaml.Marshal(v1beta1.DefaultClusterConfig(c.K0sVars))
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(conf)
+			return err
+		},
+	}
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+// NewRenderManifestsCmd writes the manifests the controllers would apply for the config, for reviewing and diffing
+// them before they reach the cluster
+func NewRenderManifestsCmd() *cobra.Command {
+	var (
+		outputDir string
+		opts      controller.RenderOptions
+	)
+	cmd := &cobra.Command{
+		Use:   "render-manifests",
+		Short: "Write the manifests k0s applies for the configuration into a directory",
+		Long: `Writes every manifest the controllers of this k0s version apply to the cluster for the config, laid out
+in the same stacks as in the manifests dir of the controllers. The same config and version always render the same
+manifests, render them with the k0s binaries of the current and the next version to see what an upgrade changes.
+The stacks of a previous render in the directory are replaced, the other files are kept.`,
+		Example: `   k0s config render-manifests --config k0s.yaml --output-dir manifests/
+   k0s config render-manifests --config k0s.yaml --output-dir manifests/ --node-count 50`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if outputDir == "" {
+				return fmt.Errorf("the output directory needs to be given with --output-dir")
+			}
+			clusterConfig, err := config.ValidateYaml(c.CfgFile, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			if err := controller.RenderManifests(clusterConfig, c.K0sVars, outputDir, opts); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "rendered the manifests into %s\n", outputDir)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "directory to write the manifests into")
+	cmd.Flags().IntVar(&opts.NodeCount, "node-count", 1, "number of nodes to scale CoreDNS and metrics-server for, as the controllers do")
+	cmd.Flags().BoolVar(&opts.SingleNode, "single", false, "render for a single node controller, without the konnectivity agents")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
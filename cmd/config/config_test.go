@@ -0,0 +1,55 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/exitcode"
+)
+
+func TestValidateExitCode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-validate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, ioutil.WriteFile(valid, []byte("apiVersion: k0s.k0sproject.io/v1beta1\nkind: Cluster\n"), 0600))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("apiVersion: k0s.k0sproject.io/v1beta1\nkind: Cluster\nspec:\n  network:\n    provider: unknown\n"), 0600))
+
+	for path, code := range map[string]int{valid: 0, invalid: 3, filepath.Join(dir, "missing.yaml"): 3} {
+		cmd := NewValidateCmd()
+		cmd.SetArgs([]string{"--config", path})
+		cmd.SetOut(ioutil.Discard)
+		cmd.SetErr(ioutil.Discard)
+		assert.Equal(t, code, exitcode.Code(cmd.Execute()), path)
+	}
+}
+
+func TestDefaultRefusesConfig(t *testing.T) {
+	cmd := NewDefaultCmd()
+	cmd.SetArgs([]string{"--config", "k0s.yaml"})
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+	assert.Equal(t, 2, exitcode.Code(cmd.Execute()))
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package controller
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/fs"
@@ -24,12 +25,15 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 
 	workercmd "github.com/k0sproject/k0s/cmd/worker"
 	"github.com/k0sproject/k0s/internal/util"
@@ -41,10 +45,21 @@ import (
 	"github.com/k0sproject/k0s/pkg/component/controller"
 	"github.com/k0sproject/k0s/pkg/config"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/crypt"
+	"github.com/k0sproject/k0s/pkg/diskspace"
+	"github.com/k0sproject/k0s/pkg/exitcode"
+	"github.com/k0sproject/k0s/pkg/hostnetwork"
 	"github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/leaderelection"
 	"github.com/k0sproject/k0s/pkg/performance"
+	"github.com/k0sproject/k0s/pkg/platform"
+	"github.com/k0sproject/k0s/pkg/provisioning"
+	"github.com/k0sproject/k0s/pkg/status"
+	"github.com/k0sproject/k0s/pkg/supervisor"
 	"github.com/k0sproject/k0s/pkg/telemetry"
 	"github.com/k0sproject/k0s/pkg/token"
+	"github.com/k0sproject/k0s/pkg/upgrade"
+	"github.com/k0sproject/k0s/pkg/watchdog"
 )
 
 type CmdOpts config.CLIOptions
@@ -63,19 +78,52 @@ func NewControllerCmd() *cobra.Command {
 	Note: Token can be passed either as a CLI argument or as a flag`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
+			if c.DataDirEncryptionDevice != "" {
+				// mounted before anything is read from or written into the data dir
+				volume := &crypt.Volume{
+					Device:     c.DataDirEncryptionDevice,
+					KeyFile:    c.DataDirEncryptionKeyFile,
+					ImageSize:  c.DataDirEncryptionImageSize,
+					MountPoint: c.K0sVars.DataDir,
+				}
+				if err := volume.Open(); err != nil {
+					return err
+				}
+				defer volume.Close()
+			}
 			if len(args) > 0 {
 				c.TokenArg = args[0]
+				logrus.Warnf("the join token given as an argument is visible in the process list, use --token-file - or the %s environment variable instead", token.EnvVar)
 			}
 			if len(c.TokenArg) > 0 && len(c.TokenFile) > 0 {
-				return fmt.Errorf("you can only pass one token argument either as a CLI argument 'k0s controller [join-token]' or as a flag 'k0s controller --token-file [path]'")
+				return exitcode.Wrap(exitcode.Usage, fmt.Errorf("you can only pass one token argument either as a CLI argument 'k0s controller [join-token]' or as a flag 'k0s controller --token-file [path]'"))
 			}
 			if len(c.TokenFile) > 0 {
-				bytes, err := ioutil.ReadFile(c.TokenFile)
+				tokenData, err := token.ReadFile(c.TokenFile)
+				if err != nil {
+					return err
+				}
+				c.TokenArg = tokenData
+			}
+			if envToken := token.FromEnv(); c.TokenArg == "" {
+				c.TokenArg = envToken
+			}
+			if c.ProvisioningPath != "" && c.TokenArg == "" && c.needToJoin() &&
+				!util.FileExists(c.K0sVars.ProvisionedTokenPath) && !util.FileExists(c.K0sVars.ProvisionedConfigPath) {
+				if err := c.provision(); err != nil {
+					return err
+				}
+			}
+			if c.TokenArg == "" && c.needToJoin() && util.FileExists(c.K0sVars.ProvisionedTokenPath) {
+				bytes, err := ioutil.ReadFile(c.K0sVars.ProvisionedTokenPath)
 				if err != nil {
 					return err
 				}
 				c.TokenArg = string(bytes)
 			}
+			if c.CfgFile == "" && util.FileExists(c.K0sVars.ProvisionedConfigPath) {
+				c.CfgFile = c.K0sVars.ProvisionedConfigPath
+			}
 			if c.SingleNode {
 				c.EnableWorker = true
 				c.K0sVars.DefaultStorageType = "kine"
@@ -92,6 +140,8 @@ func NewControllerCmd() *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(maintenanceCmd())
+
 	// append flags
 	cmd.Flags().AddFlagSet(config.GetPersistentFlagSet())
 	cmd.PersistentFlags().AddFlagSet(config.GetControllerFlags())
@@ -99,6 +149,18 @@ func NewControllerCmd() *cobra.Command {
 	return cmd
 }
 
+// provision waits for the join token and the config in the provisioning path and stores them into the data dir
+func (c *CmdOpts) provision() error {
+	bundle, err := provisioning.Wait(c.ProvisioningPath, provisioning.DefaultInterval)
+	if err != nil {
+		return err
+	}
+	if err := bundle.Persist(c.K0sVars); err != nil {
+		return err
+	}
+	return bundle.Consume()
+}
+
 // If we've got CA in place we assume the node has already joined previously
 func (c *CmdOpts) needToJoin() bool {
 	if util.FileExists(filepath.Join(c.K0sVars.CertRootDir, "ca.key")) &&
@@ -119,7 +181,12 @@ func writeCerts(caData v1beta1.CaResponse, certRootDir string) error {
 		{path: filepath.Join(certRootDir, "ca.crt"), data: caData.Cert, mode: constant.CertMode},
 		{path: filepath.Join(certRootDir, "sa.key"), data: caData.SAKey, mode: constant.CertSecureMode},
 		{path: filepath.Join(certRootDir, "sa.pub"), data: caData.SAPub, mode: constant.CertMode},
+		{path: filepath.Join(certRootDir, "oidc.key"), data: caData.OIDCKey, mode: constant.CertSecureMode},
+		{path: filepath.Join(certRootDir, "oidc.pub"), data: caData.OIDCPub, mode: constant.CertMode},
 	} {
+		if len(f.data) == 0 {
+			continue
+		}
 		err := ioutil.WriteFile(f.path, f.data, f.mode)
 		if err != nil {
 			return fmt.Errorf("failed to write %s: %w", f.path, err)
@@ -135,13 +202,22 @@ func joinController(tokenArg string, certRootDir string) (*token.JoinClient, err
 	}
 
 	var caData v1beta1.CaResponse
+	var rejected error
 	err = retry.Do(func() error {
 		caData, err = joinClient.GetCA()
+		if exitcode.CategoryOf(err) == exitcode.Join {
+			// retrying doesn't help with a refused token
+			rejected = fmt.Errorf("failed to sync CA: %w", err)
+			return nil
+		}
 		if err != nil {
 			return fmt.Errorf("failed to sync CA: %w", err)
 		}
 		return nil
 	})
+	if rejected != nil {
+		return nil, rejected
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +227,7 @@ func joinController(tokenArg string, certRootDir string) (*token.JoinClient, err
 func (c *CmdOpts) startController() error {
 	existingCNI := c.existingCNIProvider()
 	if existingCNI != "" && existingCNI != c.ClusterConfig.Spec.Network.Provider {
-		return fmt.Errorf("cannot change CNI from %s to %s", existingCNI, c.ClusterConfig.Spec.Network.Provider)
+		return exitcode.Wrap(exitcode.Config, fmt.Errorf("cannot change CNI from %s to %s", existingCNI, c.ClusterConfig.Spec.Network.Provider))
 	}
 	perfTimer := performance.NewTimer("controller-start").Buffer().Start()
 
@@ -162,8 +238,62 @@ func (c *CmdOpts) startController() error {
 	if err := util.InitDirectory(c.K0sVars.CertRootDir, constant.CertRootDirMode); err != nil {
 		return err
 	}
+	if err := diskspace.Preflight(c.K0sVars.DataDir, diskspace.DefaultThresholds); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+	if err := platform.Preflight(platform.RoleController); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+	if err := c.checkNetworkChange(); err != nil {
+		return err
+	}
+	network := c.ClusterConfig.Spec.Network
+	podCIDRs := strings.Split(network.BuildPodCIDR(), ",")
+	serviceCIDRs := strings.Split(network.BuildServiceCIDR(c.ClusterConfig.Spec.API.Address), ",")
+	if err := hostnetwork.Preflight(podCIDRs, serviceCIDRs, c.IgnoreNetOverlap); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+
+	// recorded before the start, the health checks of a prepared upgrade start from the upgrade
+	if err := upgrade.MarkStarted(c.K0sVars, build.Version, time.Now()); err != nil {
+		logrus.Warnf("failed to record the upgrade to %s: %v", build.Version, err)
+	}
+	history := status.NewHistory(c.K0sVars.StatusHistoryPath, status.DefaultHistoryRetention)
+	history.Record("controller", status.EventStarted, "")
+	defer history.Record("controller", status.EventStopped, "")
+
+	lowPower := c.ClusterConfig.Spec.LowPower
+	if lowPower.IsEnabled() {
+		logrus.Info("low-power mode: lengthening the reconcile intervals and disabling the nonessential components")
+	}
+	controller.SetLowPower(lowPower)
+	components := c.ClusterConfig.Spec.Components
+	if len(components.Disabled) > 0 {
+		logrus.Infof("disabled components: %s", strings.Join(components.Disabled, ", "))
+	}
 
 	componentManager := component.NewManager()
+	componentManager.History = history
+	// the components are started in parallel following the dependencies: storage -> api server -> the rest
+	componentManager.AddAfter(&diskspace.Monitor{Path: c.K0sVars.DataDir, History: history})
+	processWatchdog := &watchdog.Watchdog{
+		DumpDir:       c.K0sVars.LogDir,
+		MaxGoroutines: c.WatchdogMaxGoroutines,
+	}
+	if c.WatchdogRestart {
+		processWatchdog.Restart = componentManager.Restart
+	}
+	componentManager.AddAfter(processWatchdog)
+	if c.CollectCoreDumps {
+		if err := supervisor.EnableCoreDumps(); err != nil {
+			logrus.Warnf("the crashes are kept without core dumps: %v", err)
+		}
+	}
+	if c.ClusterConfig.Spec.Profiling.IsEnabled() {
+		componentManager.AddAfter(&controller.Profiling{SocketPath: c.K0sVars.ProfilingSocketPath})
+	}
+	// the ports are opened in parallel with the start of the storage, for the etcd peers joining
+	componentManager.AddAfter(&controller.Firewall{ClusterConfig: c.ClusterConfig, Worker: c.EnableWorker})
 	certificateManager := certificate.Manager{K0sVars: c.K0sVars}
 
 	var joinClient *token.JoinClient
@@ -171,15 +301,24 @@ func (c *CmdOpts) startController() error {
 
 	if c.TokenArg != "" && c.needToJoin() {
 		joinClient, err = joinController(c.TokenArg, c.K0sVars.CertRootDir)
+		if err == nil && util.FileExists(c.K0sVars.ProvisionedTokenPath) {
+			// the provisioned token is not needed anymore once joined
+			if err := provisioning.SecureDelete(c.K0sVars.ProvisionedTokenPath); err != nil {
+				logrus.Warnf("failed to delete the provisioned token: %v", err)
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("failed to join controller: %w", err)
 		}
 	}
-	componentManager.AddSync(&controller.Certificates{
+	// the join client holds the credentials from now on
+	c.TokenArg = ""
+	certificates := &controller.Certificates{
 		ClusterSpec: c.ClusterConfig.Spec,
 		CertManager: certificateManager,
 		K0sVars:     c.K0sVars,
-	})
+	}
+	componentManager.AddSync(certificates)
 
 	logrus.Infof("using api address: %s", c.ClusterConfig.Spec.API.Address)
 	logrus.Infof("using listen port: %d", c.ClusterConfig.Spec.API.Port)
@@ -204,96 +343,180 @@ func (c *CmdOpts) startController() error {
 			JoinClient:  joinClient,
 			K0sVars:     c.K0sVars,
 			LogLevel:    c.Logging["etcd"],
+			Profiling:   c.ClusterConfig.Spec.Profiling,
 		}
 	default:
 		return fmt.Errorf("invalid storage type: %s", c.ClusterConfig.Spec.Storage.Type)
 	}
 	logrus.Infof("Using storage backend %s", c.ClusterConfig.Spec.Storage.Type)
-	componentManager.Add(storageBackend)
+	componentManager.AddAfter(storageBackend, certificates)
+	if c.ClusterConfig.Spec.Storage.Type == v1beta1.EtcdStorageType {
+		if !lowPower.IsEnabled() {
+			componentManager.AddAfter(&controller.EtcdMetrics{K0sVars: c.K0sVars}, storageBackend)
+		}
+		if snapshots := c.ClusterConfig.Spec.Storage.Etcd.Snapshots; snapshots != nil {
+			if c.ClusterConfig.Spec.Storage.Etcd.Learner {
+				logrus.Info("etcd learners don't take snapshots, skipping the snapshot schedule")
+			} else {
+				componentManager.AddAfter(&controller.EtcdSnapshots{Spec: snapshots, K0sVars: c.K0sVars}, storageBackend)
+			}
+		}
+	}
 
 	// common factory to get the admin kube client that's needed in many components
 	adminClientFactory := kubernetes.NewAdminClientFactory(c.K0sVars)
+	maintenance := controller.NewMaintenance(c.K0sVars)
 
-	componentManager.Add(&controller.APIServer{
+	apiServer := &controller.APIServer{
 		ClusterConfig:      c.ClusterConfig,
 		K0sVars:            c.K0sVars,
 		LogLevel:           c.Logging["kube-apiserver"],
 		Storage:            storageBackend,
-		EnableKonnectivity: !c.SingleNode,
-	})
+		EnableKonnectivity: c.runKonnectivity(),
+		Maintenance:        maintenance,
+	}
+	componentManager.AddAfter(apiServer, storageBackend)
 
 	if c.ClusterConfig.Spec.API.ExternalAddress != "" {
-		componentManager.Add(&controller.K0sLease{
+		componentManager.AddAfter(&controller.K0sLease{
 			ClusterConfig:     c.ClusterConfig,
 			KubeClientFactory: adminClientFactory,
-		})
+		}, apiServer)
 	}
-	if !c.SingleNode {
-		componentManager.Add(&controller.Konnectivity{
+	if c.runKonnectivity() {
+		componentManager.AddAfter(&controller.Konnectivity{
 			ClusterConfig:     c.ClusterConfig,
 			LogLevel:          c.Logging["konnectivity-server"],
 			K0sVars:           c.K0sVars,
 			KubeClientFactory: adminClientFactory,
-		})
+		}, apiServer)
 	}
-	componentManager.Add(&controller.Scheduler{
-		ClusterConfig: c.ClusterConfig,
-		LogLevel:      c.Logging["kube-scheduler"],
-		K0sVars:       c.K0sVars,
-	})
-	componentManager.Add(&controller.Manager{
-		ClusterConfig: c.ClusterConfig,
-		LogLevel:      c.Logging["kube-controller-manager"],
-		K0sVars:       c.K0sVars,
-	})
 
 	// One leader elector per controller
 	var leaderElector controller.LeaderElector
 	if c.ClusterConfig.Spec.API.ExternalAddress != "" {
-		leaderElector = controller.NewLeaderElector(c.ClusterConfig, adminClientFactory)
+		leaderElector = controller.NewLeaderElector(c.ClusterConfig, adminClientFactory, maintenance)
 	} else {
-		leaderElector = &controller.DummyLeaderElector{Leader: true}
+		leaderElector = &controller.DummyLeaderElector{Leader: true, Maintenance: maintenance}
 	}
-	componentManager.Add(leaderElector)
 
-	componentManager.Add(&applier.Manager{K0sVars: c.K0sVars, KubeClientFactory: adminClientFactory, LeaderElector: leaderElector})
-	if !c.SingleNode {
-		componentManager.Add(&controller.K0SControlAPI{
+	// the standby needs the other controllers to take over, a single controller keeps running them
+	var standbyElector controller.LeaderElector
+	if c.EnableControlPlaneStandby {
+		if c.ClusterConfig.Spec.API.ExternalAddress != "" {
+			standbyElector = leaderElector
+		} else {
+			logrus.Warn("--enable-control-plane-standby needs spec.api.externalAddress, kube-scheduler and kube-controller-manager run on this controller")
+		}
+	}
+	componentManager.AddAfter(&controller.Scheduler{
+		ClusterConfig: c.ClusterConfig,
+		LogLevel:      c.Logging["kube-scheduler"],
+		K0sVars:       c.K0sVars,
+		LeaderElector: standbyElector,
+	}, apiServer)
+	componentManager.AddAfter(&controller.Manager{
+		ClusterConfig: c.ClusterConfig,
+		LogLevel:      c.Logging["kube-controller-manager"],
+		K0sVars:       c.K0sVars,
+		LeaderElector: standbyElector,
+	}, apiServer)
+	componentManager.AddAfter(&controller.CertRotation{
+		K0sVars:           c.K0sVars,
+		KubeClientFactory: adminClientFactory,
+		Restart:           componentManager.Restart,
+	}, apiServer)
+	componentManager.AddAfter(&controller.HostsSync{
+		ClusterConfig:     c.ClusterConfig,
+		KubeClientFactory: adminClientFactory,
+	}, apiServer)
+	componentManager.AddAfter(leaderElector, apiServer)
+	// stopped before the components reacting on the maintenance toggles
+	componentManager.AddAfter(maintenance, leaderElector)
+
+	componentManager.AddAfter(&applier.Manager{K0sVars: c.K0sVars, KubeClientFactory: adminClientFactory, LeaderElector: leaderElector, LowPower: lowPower.IsEnabled()}, leaderElector)
+	if !c.SingleNode && !components.IsDisabled(v1beta1.ControlAPIComponent) {
+		componentManager.AddAfter(&controller.K0SControlAPI{
 			ConfigPath: c.CfgFile,
 			K0sVars:    c.K0sVars,
-		})
+		}, apiServer)
 	}
-	if c.ClusterConfig.Spec.Telemetry.Enabled {
-		componentManager.Add(&telemetry.Component{
+	if c.ClusterConfig.Spec.Telemetry.Enabled && !lowPower.IsEnabled() {
+		componentManager.AddAfter(&telemetry.Component{
 			ClusterConfig:     c.ClusterConfig,
 			Version:           build.Version,
 			K0sVars:           c.K0sVars,
 			KubeClientFactory: adminClientFactory,
-		})
+		}, apiServer)
 	}
 
-	if c.ClusterConfig.Spec.API.ExternalAddress != "" {
-		componentManager.Add(controller.NewEndpointReconciler(
+	componentManager.AddAfter(&controller.ConnectionBrokerConfig{
+		ClusterConfig: c.ClusterConfig,
+		K0sVars:       c.K0sVars,
+	}, apiServer)
+
+	if !lowPower.IsEnabled() {
+		componentManager.AddAfter(&controller.ConfigDrift{
+			ClusterConfig: c.ClusterConfig,
+			K0sVars:       c.K0sVars,
+		}, apiServer)
+	}
+
+	componentManager.AddAfter(controller.NewClusterMetadata(
+		c.ClusterConfig,
+		c.K0sVars,
+		leaderElector,
+		adminClientFactory,
+	), leaderElector)
+
+	if c.ClusterConfig.Spec.API.ExternalAddress != "" && !components.IsDisabled(v1beta1.EndpointReconcilerComponent) {
+		componentManager.AddAfter(controller.NewEndpointReconciler(
 			c.ClusterConfig,
 			leaderElector,
 			adminClientFactory,
-		))
+		), leaderElector)
 	}
 
-	componentManager.Add(controller.NewCSRApprover(c.ClusterConfig,
-		leaderElector,
-		adminClientFactory))
+	if !components.IsDisabled(v1beta1.CSRApproverComponent) {
+		componentManager.AddAfter(controller.NewCSRApprover(c.ClusterConfig,
+			leaderElector,
+			adminClientFactory), leaderElector)
+	}
+
+	componentManager.AddAfter(controller.NewJoinQuota(leaderElector, adminClientFactory), leaderElector)
+	componentManager.AddAfter(controller.NewTokenUsage(leaderElector, adminClientFactory), leaderElector)
+
+	if c.ClusterConfig.Spec.NodeGC.IsEnabled() {
+		componentManager.AddAfter(controller.NewNodeGC(c.ClusterConfig.Spec.NodeGC, leaderElector, adminClientFactory), leaderElector)
+	}
+
+	if c.ClusterConfig.Spec.RemoteWrite.IsEnabled() {
+		componentManager.AddAfter(controller.NewRemoteWrite(c.ClusterConfig, c.K0sVars, leaderElector, adminClientFactory), leaderElector)
+	}
 
 	if c.EnableK0sCloudProvider {
-		componentManager.Add(
+		componentManager.AddAfter(
 			controller.NewK0sCloudProvider(
 				c.K0sVars.AdminKubeConfigPath,
 				c.K0sCloudProviderUpdateFrequency,
 				c.K0sCloudProviderPort,
 			),
+			apiServer,
 		)
 	}
 
+	// added last so that it's stopped first and the load balancers drop the controller before it goes down
+	if port := c.ClusterConfig.Spec.API.HealthPort; port != 0 {
+		componentManager.AddAfter(&controller.HealthEndpoint{
+			Port:        port,
+			Maintenance: maintenance,
+			Checks: []controller.HealthCheck{
+				{Name: "apiserver", Check: apiServer.Healthy},
+				{Name: string(c.ClusterConfig.Spec.Storage.Type), Check: storageBackend.Healthy},
+			},
+		}, apiServer)
+	}
+
 	perfTimer.Checkpoint("starting-component-init")
 	// init components
 	if err := componentManager.Init(); err != nil {
@@ -331,7 +554,8 @@ func (c *CmdOpts) startController() error {
 	}
 
 	// in-cluster component reconcilers
-	reconcilers, err := c.createClusterReconcilers(adminClientFactory, leaderElector)
+	currentClusterConfig = c.ClusterConfig
+	reconcilers, err := c.createClusterReconcilers(c.ClusterConfig, adminClientFactory, leaderElector)
 	if err != nil {
 		return err
 	}
@@ -349,6 +573,19 @@ func (c *CmdOpts) startController() error {
 
 	perfTimer.Checkpoint("started-reconcilers")
 
+	var clusterConfigReconciler *controller.ClusterConfigReconciler
+	if c.EnableDynamicConfig {
+		clusterConfigReconciler = controller.NewClusterConfigReconciler(c.ClusterConfig, leaderElector, adminClientFactory, func(spec *v1beta1.ClusterWideSpec) error {
+			return c.reloadClusterConfig(spec, componentManager, reconcilers, adminClientFactory, leaderElector)
+		})
+		if err := clusterConfigReconciler.Init(); err != nil {
+			return err
+		}
+		if err := clusterConfigReconciler.Run(); err != nil {
+			return err
+		}
+	}
+
 	if err == nil && c.EnableWorker {
 		perfTimer.Checkpoint("starting-worker")
 
@@ -370,11 +607,18 @@ func (c *CmdOpts) startController() error {
 	logrus.Debug("Context done in main")
 
 	// Stop all reconcilers first
+	if clusterConfigReconciler != nil {
+		if err := clusterConfigReconciler.Stop(); err != nil {
+			logrus.Warningf("failed to stop the cluster config reconciler: %s", err.Error())
+		}
+	}
+	clusterConfigMu.Lock()
 	for _, reconciler := range reconcilers {
 		if err := reconciler.Stop(); err != nil {
 			logrus.Warningf("failed to stop reconciler: %s", err.Error())
 		}
 	}
+	clusterConfigMu.Unlock()
 
 	// Stop components
 	if err := componentManager.Stop(); err != nil {
@@ -383,59 +627,215 @@ func (c *CmdOpts) startController() error {
 	return nil
 }
 
-func (c *CmdOpts) createClusterReconcilers(cf kubernetes.ClientFactory, leaderElector controller.LeaderElector) (map[string]component.Component, error) {
-	reconcilers := make(map[string]component.Component)
-	clusterSpec := c.ClusterConfig.Spec
+const (
+	// configRestartLease is held by the controller restarting its control plane components for a changed cluster
+	// config, so that the controllers restart them one at a time
+	configRestartLease = "k0s-config-restart"
+	// configRestartTimeout is how long the controller waits for the other controllers to finish their restarts
+	configRestartTimeout = 10 * time.Minute
+)
+
+var (
+	// clusterConfigMu guards the replacement of the cluster config and of the in-cluster reconcilers on reload. The
+	// config isn't changed in place: the reload hands a new snapshot to the recreated reconcilers and to the restarted
+	// control plane components, the other components keep the snapshot they were started with.
+	clusterConfigMu sync.Mutex
+	// currentClusterConfig is the latest snapshot of the cluster config, c.ClusterConfig stays the one k0s started with
+	currentClusterConfig *v1beta1.ClusterConfig
+	// pendingRestarts are the components still to restart with the changed cluster config, kept over the reloads
+	// so the failed restarts are retried with the next change
+	pendingRestarts = map[string]bool{}
+)
+
+// reloadClusterConfig applies the changed cluster-wide config, the in-cluster reconcilers are recreated with it and
+// the control plane components whose config changed are restarted
+func (c *CmdOpts) reloadClusterConfig(spec *v1beta1.ClusterWideSpec, componentManager *component.Manager, reconcilers map[string]component.Component, cf kubernetes.ClientFactory, leaderElector controller.LeaderElector) error {
+	clusterConfigMu.Lock()
+	previous := currentClusterConfig.Spec.ClusterWide()
+	for name, reconciler := range reconcilers {
+		if err := reconciler.Stop(); err != nil {
+			logrus.Warningf("failed to stop reconciler %s: %s", name, err.Error())
+		}
+		delete(reconcilers, name)
+	}
 
-	defaultPSP, err := controller.NewDefaultPSP(clusterSpec, c.K0sVars)
+	// the running components share the config, the changes go to a new snapshot which replaces it. ApplyClusterWide
+	// replaces the changed fields of the spec copy without touching the shared ones.
+	updated := *currentClusterConfig.Spec
+	updated.ApplyClusterWide(spec)
+	snapshot := *currentClusterConfig
+	snapshot.Spec = &updated
+	currentClusterConfig = &snapshot
+
+	created, err := c.createClusterReconcilers(&snapshot, cf, leaderElector)
+	for name, reconciler := range created {
+		reconcilers[name] = reconciler
+		logrus.Infof("running reconciler: %s", name)
+		if err := reconciler.Run(); err != nil {
+			logrus.Errorf("failed to start reconciler: %s", err.Error())
+		}
+	}
+	clusterConfigMu.Unlock()
 	if err != nil {
-		logrus.Warnf("failed to initialize default PSP reconciler: %s", err.Error())
-	} else {
-		reconcilers["default-psp"] = defaultPSP
+		return err
+	}
+
+	current := updated.ClusterWide()
+	changes := map[string]bool{
+		"APIServer": changed(previous.API, current.API),
+		"Manager":   changed(previous.ControllerManager, current.ControllerManager),
+		"Scheduler": changed(previous.Scheduler, current.Scheduler),
+	}
+	if !c.SingleNode && !updated.Components.IsDisabled(v1beta1.KonnectivityServerComponent) {
+		changes["Konnectivity"] = changed(previous.Konnectivity, current.Konnectivity)
+	}
+	for name, change := range changes {
+		if change {
+			pendingRestarts[name] = true
+		}
+	}
+	if len(pendingRestarts) == 0 {
+		return nil
+	}
+
+	// all the controllers see the change at about the same time, restarting the API servers at once would take
+	// the whole control plane down
+	release, err := acquireRestartLease(cf)
+	if err != nil {
+		return err
+	}
+	defer release()
+	for name := range pendingRestarts {
+		logrus.Infof("restarting %s with the changed cluster config", name)
+		if err := componentManager.RestartWith(name, func(comp component.Component) { setClusterConfig(comp, &snapshot) }); err != nil {
+			return err
+		}
+		delete(pendingRestarts, name)
+		if err := componentManager.WaitForHealthy(name); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	proxy, err := controller.NewKubeProxy(c.ClusterConfig, c.K0sVars)
+// setClusterConfig hands the config snapshot to the stopped control plane component before it's run again
+func setClusterConfig(comp component.Component, cfg *v1beta1.ClusterConfig) {
+	switch comp := comp.(type) {
+	case *controller.APIServer:
+		comp.ClusterConfig = cfg
+	case *controller.Manager:
+		comp.ClusterConfig = cfg
+	case *controller.Scheduler:
+		comp.ClusterConfig = cfg
+	case *controller.Konnectivity:
+		comp.ClusterConfig = cfg
+	}
+}
+
+// acquireRestartLease waits until the controller holds the restart lease, the returned func releases it. The lease
+// outlives the restart of the API server it's renewed through, so it isn't taken over in the middle of the restart.
+func acquireRestartLease(cf kubernetes.ClientFactory) (func(), error) {
+	client, err := cf.GetClient()
+	if err != nil {
+		return nil, fmt.Errorf("can't create kubernetes client for the restart lease: %w", err)
+	}
+	leasePool, err := leaderelection.NewLeasePool(client, configRestartLease,
+		leaderelection.WithDuration(2*time.Minute),
+		leaderelection.WithRenewDeadline(90*time.Second),
+		leaderelection.WithLogger(logrus.WithField("component", "clusterconfig")))
+	if err != nil {
+		return nil, err
+	}
+	// buffered, nobody reads the lost lease event after the release
+	events := &leaderelection.LeaseEvents{
+		AcquiredLease: make(chan struct{}, 1),
+		LostLease:     make(chan struct{}, 1),
+	}
+	_, cancel, err := leasePool.Watch(leaderelection.WithOutputChannels(events))
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Info("waiting for the other controllers to restart with the changed cluster config")
+	select {
+	case <-events.AcquiredLease:
+		return cancel, nil
+	case <-time.After(configRestartTimeout):
+		cancel()
+		return nil, fmt.Errorf("timed out waiting for the %s lease", configRestartLease)
+	}
+}
+
+// changed compares the specs through their yaml, which doesn't tell the empty maps from the missing ones
+func changed(previous, current interface{}) bool {
+	previousYaml, _ := yaml.Marshal(previous)
+	currentYaml, _ := yaml.Marshal(current)
+	return !bytes.Equal(previousYaml, currentYaml)
+}
+
+func (c *CmdOpts) createClusterReconcilers(clusterConfig *v1beta1.ClusterConfig, cf kubernetes.ClientFactory, leaderElector controller.LeaderElector) (map[string]component.Component, error) {
+	reconcilers := make(map[string]component.Component)
+	clusterSpec := clusterConfig.Spec
+	components := clusterSpec.Components
+
+	if !components.IsDisabled(v1beta1.DefaultPSPComponent) {
+		defaultPSP, err := controller.NewDefaultPSP(clusterSpec, c.K0sVars)
+		if err != nil {
+			logrus.Warnf("failed to initialize default PSP reconciler: %s", err.Error())
+		} else {
+			reconcilers["default-psp"] = defaultPSP
+		}
+	}
+
+	proxy, err := controller.NewKubeProxy(clusterConfig, c.K0sVars)
 	if err != nil {
 		logrus.Warnf("failed to initialize kube-proxy reconciler: %s", err.Error())
 	} else {
 		reconcilers["kube-proxy"] = proxy
 	}
 
-	coreDNS, err := controller.NewCoreDNS(c.ClusterConfig, c.K0sVars, cf)
-	if err != nil {
-		logrus.Warnf("failed to initialize CoreDNS reconciler: %s", err.Error())
-	} else {
-		reconcilers["coredns"] = coreDNS
+	if !components.IsDisabled(v1beta1.CoreDNSComponent) {
+		coreDNS, err := controller.NewCoreDNS(clusterConfig, c.K0sVars, cf)
+		if err != nil {
+			logrus.Warnf("failed to initialize CoreDNS reconciler: %s", err.Error())
+		} else {
+			reconcilers["coredns"] = coreDNS
+		}
 	}
 
-	logrus.Infof("initializing network reconciler for provider %s", c.ClusterConfig.Spec.Network.Provider)
-	switch c.ClusterConfig.Spec.Network.Provider {
+	logrus.Infof("initializing network reconciler for provider %s", clusterConfig.Spec.Network.Provider)
+	switch clusterConfig.Spec.Network.Provider {
 	case "custom":
 		logrus.Warnf("network provider set to custom, k0s will not manage it")
 	case "calico":
-		err = c.initCalico(reconcilers)
+		err = c.initCalico(clusterConfig, reconcilers)
 	case "kuberouter":
-		err = c.initKubeRouter(reconcilers)
+		err = c.initKubeRouter(clusterConfig, reconcilers)
 	}
 	if err != nil {
 		logrus.Warnf("failed to initialize network reconciler: %s", err.Error())
 		return reconcilers, err
 	}
 
+	// the ClusterConfig CRD is needed for the dynamic config, whether helm is enabled or not
 	manifestsSaver, err := controller.NewManifestsSaver("helm", c.K0sVars.DataDir)
 	if err != nil {
 		logrus.Warnf("failed to initialize reconcilers manifests saver: %s", err.Error())
 		return reconcilers, err
 	}
-	reconcilers["crd"] = controller.NewCRD(manifestsSaver)
-	reconcilers["helmAddons"] = controller.NewHelmAddons(c.ClusterConfig, manifestsSaver, c.K0sVars, cf, leaderElector)
+	reconcilers["crd"] = controller.NewCRD(manifestsSaver, !components.IsDisabled(v1beta1.HelmComponent))
+	if !components.IsDisabled(v1beta1.HelmComponent) {
+		reconcilers["helmAddons"] = controller.NewHelmAddons(clusterConfig, manifestsSaver, c.K0sVars, cf, leaderElector)
+	}
 
-	metricServer, err := controller.NewMetricServer(c.ClusterConfig, c.K0sVars, cf)
-	if err != nil {
-		logrus.Warnf("failed to initialize metric controller reconciler: %s", err.Error())
-		return reconcilers, err
+	if !components.IsDisabled(v1beta1.MetricsServerComponent) {
+		metricServer, err := controller.NewMetricServer(clusterConfig, c.K0sVars, cf)
+		if err != nil {
+			logrus.Warnf("failed to initialize metric controller reconciler: %s", err.Error())
+			return reconcilers, err
+		}
+		reconcilers["metricServer"] = metricServer
 	}
-	reconcilers["metricServer"] = metricServer
 
 	kubeletConfig, err := controller.NewKubeletConfig(clusterSpec, c.K0sVars)
 	if err != nil {
@@ -454,7 +854,12 @@ func (c *CmdOpts) createClusterReconcilers(cf kubernetes.ClientFactory, leaderEl
 	return reconcilers, nil
 }
 
-func (c *CmdOpts) initCalico(reconcilers map[string]component.Component) error {
+// runKonnectivity tells if the konnectivity server is run, a single node has no agents connecting to it
+func (c *CmdOpts) runKonnectivity() bool {
+	return !c.SingleNode && !c.ClusterConfig.Spec.Components.IsDisabled(v1beta1.KonnectivityServerComponent)
+}
+
+func (c *CmdOpts) initCalico(clusterConfig *v1beta1.ClusterConfig, reconcilers map[string]component.Component) error {
 	calicoSaver, err := controller.NewManifestsSaver("calico", c.K0sVars.DataDir)
 	if err != nil {
 		logrus.Warnf("failed to initialize reconcilers manifests saver: %s", err.Error())
@@ -465,7 +870,7 @@ func (c *CmdOpts) initCalico(reconcilers map[string]component.Component) error {
 		logrus.Warnf("failed to initialize reconcilers manifests saver: %s", err.Error())
 		return err
 	}
-	calico, err := controller.NewCalico(c.ClusterConfig, calicoInitSaver, calicoSaver)
+	calico, err := controller.NewCalico(clusterConfig, calicoInitSaver, calicoSaver)
 	if err != nil {
 		logrus.Warnf("failed to initialize calico reconciler: %s", err.Error())
 		return err
@@ -475,13 +880,13 @@ func (c *CmdOpts) initCalico(reconcilers map[string]component.Component) error {
 	return nil
 }
 
-func (c *CmdOpts) initKubeRouter(reconcilers map[string]component.Component) error {
+func (c *CmdOpts) initKubeRouter(clusterConfig *v1beta1.ClusterConfig, reconcilers map[string]component.Component) error {
 	mfSaver, err := controller.NewManifestsSaver("kuberouter", c.K0sVars.DataDir)
 	if err != nil {
 		logrus.Warnf("failed to initialize kube-router manifests saver: %s", err.Error())
 		return err
 	}
-	kubeRouter, err := controller.NewKubeRouter(c.ClusterConfig, mfSaver)
+	kubeRouter, err := controller.NewKubeRouter(clusterConfig, mfSaver)
 	if err != nil {
 		logrus.Warnf("failed to initialize kube-router reconciler: %s", err.Error())
 		return err
@@ -524,7 +929,7 @@ func (c *CmdOpts) startControllerWorker(_ context.Context, profile string) error
 			// we use retry.Do with 10 attempts, back-off delay and delay duration 500 ms which gives us
 			// 225 seconds here
 			tokenAge := time.Second * 225
-			cfg, err := token.CreateKubeletBootstrapConfig(c.ClusterConfig, c.K0sVars, "worker", tokenAge)
+			cfg, err := token.CreateKubeletBootstrapConfig(c.ClusterConfig, c.K0sVars, "worker", tokenAge, token.CreateOptions{})
 
 			if err != nil {
 				return err
@@ -543,5 +948,7 @@ func (c *CmdOpts) startControllerWorker(_ context.Context, profile string) error
 	workerCmdOpts := *(*workercmd.CmdOpts)(c)
 	workerCmdOpts.TokenArg = bootstrapConfig
 	workerCmdOpts.WorkerProfile = profile
+	workerCmdOpts.CNIConfDir = c.ClusterConfig.Spec.Network.CNIConfDir
+	workerCmdOpts.CNIBinDir = c.ClusterConfig.Spec.Network.CNIBinDir
 	return workerCmdOpts.StartWorker()
 }
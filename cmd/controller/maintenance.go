@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+func maintenanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Manage the maintenance mode of the controller running on this host",
+		Long: `Manage the maintenance mode of the controller running on this host.
+While in maintenance the controller is removed from the API endpoints and doesn't run the cluster wide reconcilers,
+but it stays an etcd member. This allows patching the host without removing the controller from the cluster.`,
+	}
+	cmd.AddCommand(maintenanceToggleCmd("enable", "Put the controller into maintenance mode", true))
+	cmd.AddCommand(maintenanceToggleCmd("disable", "Bring the controller back from maintenance mode", false))
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show if the controller is in maintenance mode",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if controller.MaintenanceEnabled(c.K0sVars) {
+				fmt.Fprintln(cmd.OutOrStdout(), "enabled")
+			} else {
+				fmt.Fprintln(cmd.OutOrStdout(), "disabled")
+			}
+			return nil
+		},
+	})
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+func maintenanceToggleCmd(use, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short + ". Must be run as root (or with sudo)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			cmd.SilenceUsage = true
+			if err := controller.SetMaintenance(c.K0sVars, enabled); err != nil {
+				return err
+			}
+			if enabled {
+				fmt.Fprintln(cmd.OutOrStdout(), "maintenance mode enabled, the controller steps down within a few seconds")
+			} else {
+				fmt.Fprintln(cmd.OutOrStdout(), "maintenance mode disabled, the controller rejoins within a few seconds")
+			}
+			return nil
+		},
+	}
+}
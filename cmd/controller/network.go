@@ -0,0 +1,98 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// networkState is the part of the network config a running cluster can't change freely
+type networkState struct {
+	PodCIDR         string `json:"podCIDR"`
+	ServiceCIDR     string `json:"serviceCIDR"`
+	IPv6PodCIDR     string `json:"ipv6PodCIDR,omitempty"`
+	IPv6ServiceCIDR string `json:"ipv6ServiceCIDR,omitempty"`
+	NodePortRange   string `json:"nodePortRange"`
+}
+
+func newNetworkState(n *v1beta1.Network) networkState {
+	state := networkState{
+		PodCIDR:       n.PodCIDR,
+		ServiceCIDR:   n.ServiceCIDR,
+		NodePortRange: n.NodePortRange,
+	}
+	if n.DualStack.Enabled {
+		state.IPv6PodCIDR = n.DualStack.IPv6PodCIDR
+		state.IPv6ServiceCIDR = n.DualStack.IPv6ServiceCIDR
+	}
+	return state
+}
+
+func (s networkState) network() *v1beta1.Network {
+	n := v1beta1.DefaultNetwork()
+	n.PodCIDR = s.PodCIDR
+	n.ServiceCIDR = s.ServiceCIDR
+	n.NodePortRange = s.NodePortRange
+	if s.IPv6ServiceCIDR != "" {
+		n.DualStack.Enabled = true
+		n.DualStack.IPv6PodCIDR = s.IPv6PodCIDR
+		n.DualStack.IPv6ServiceCIDR = s.IPv6ServiceCIDR
+	}
+	return n
+}
+
+// checkNetworkChange validates the network config against the one the controller last ran with and records it.
+// The service CIDR can only be expanded and the NodePort range changed, the API server and the controller manager
+// pick the new values up on start and the kube-proxy and CNI manifests are rendered again from the config.
+func (c *CmdOpts) checkNetworkChange() error {
+	current := newNetworkState(c.ClusterConfig.Spec.Network)
+	data, err := ioutil.ReadFile(c.K0sVars.NetworkStatePath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		var previous networkState
+		if err := json.Unmarshal(data, &previous); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", c.K0sVars.NetworkStatePath, err)
+		}
+		if previous == current {
+			return nil
+		}
+		if errs := c.ClusterConfig.Spec.Network.ValidateChange(previous.network()); len(errs) > 0 {
+			return fmt.Errorf("invalid network change: %v", errs)
+		}
+		if previous.ServiceCIDR != current.ServiceCIDR || previous.IPv6ServiceCIDR != current.IPv6ServiceCIDR {
+			logrus.Infof("expanding the service CIDR from %s to %s, restart the rest of the controllers with the same config", previous.ServiceCIDR, current.ServiceCIDR)
+		}
+		if previous.NodePortRange != current.NodePortRange {
+			logrus.Infof("changing the NodePort range from %s to %s, the existing services outside of the new range keep their ports until updated", previous.NodePortRange, current.NodePortRange)
+		}
+	}
+
+	data, err = json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.K0sVars.NetworkStatePath, data, 0600)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package crictl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/assets"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+type CmdOpts config.CLIOptions
+
+// NewCrictlCmd runs the crictl embedded into k0s against the containerd managed by k0s
+func NewCrictlCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "crictl",
+		Short: "CRI CLI",
+		Long: `Runs the crictl embedded into k0s. The runtime and the image endpoints default to the containerd managed by k0s,
+use --runtime-endpoint or the CONTAINER_RUNTIME_ENDPOINT environment variable for a custom container runtime.`,
+		DisableFlagParsing: true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if err := assets.Stage(c.K0sVars.BinDir, "crictl", constant.BinDirMode); err != nil {
+				return fmt.Errorf("failed to stage crictl: %w", err)
+			}
+
+			endpoint := "unix://" + filepath.Join(c.K0sVars.RunDir, "containerd.sock")
+			crictl := exec.Command(assets.BinPath("crictl", c.K0sVars.BinDir), crictlArgs(os.Args, endpoint)...)
+			crictl.Stdin = os.Stdin
+			crictl.Stdout = os.Stdout
+			crictl.Stderr = os.Stderr
+			err := crictl.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// crictl has already printed the error
+				os.Exit(exitErr.ExitCode())
+			}
+			return err
+		},
+	}
+	return cmd
+}
+
+// crictlArgs picks the crictl arguments from the k0s command line and points crictl to the endpoint, unless the
+// user has chosen the endpoints or the crictl config file explicitly
+func crictlArgs(osArgs []string, endpoint string) []string {
+	var args []string
+	for i, arg := range osArgs {
+		if arg == "crictl" {
+			args = osArgs[i+1:]
+			break
+		}
+	}
+
+	if os.Getenv("CONTAINER_RUNTIME_ENDPOINT") != "" {
+		return args
+	}
+	// only the global flags before the subcommand are looked at, e.g. "exec -i" is the interactive flag of exec
+	for i := 0; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		flag := strings.SplitN(args[i], "=", 2)
+		switch flag[0] {
+		case "-r", "--runtime-endpoint", "-i", "--image-endpoint", "-c", "--config":
+			return args
+		case "-t", "--timeout":
+			if len(flag) == 1 {
+				i++
+			}
+		}
+	}
+	return append([]string{"--runtime-endpoint", endpoint, "--image-endpoint", endpoint}, args...)
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/containerd/containerd/cmd/ctr/app"
 	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/spf13/cobra"
 	"github.com/urfave/cli"
 )
@@ -50,7 +51,7 @@ func setDefaultValues(flags []cli.Flag) {
 				f.Value = path.Join(config.GetCmdOpts().K0sVars.RunDir, "containerd.sock")
 				flags[i] = f
 			} else if f.Name == "namespace, n" {
-				f.Value = "k8s.io"
+				f.Value = constant.ContainerdNamespace
 				flags[i] = f
 			}
 		}
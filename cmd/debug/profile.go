@@ -0,0 +1,95 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package debug
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/profiling"
+)
+
+func debugProfileCmd() *cobra.Command {
+	var (
+		seconds  int
+		node     string
+		savePath string
+	)
+	cmd := &cobra.Command{
+		Use:       "profile <component>",
+		Short:     "Collect the CPU, memory and goroutine profiles of a component. Must be run as root (or with sudo)",
+		Long:      fmt.Sprintf("Collect the CPU, memory and goroutine profiles of a component into a tar.gz bundle.\nSupported components: %s", strings.Join(profiling.Components, ", ")),
+		Example:   `k0s debug profile kube-apiserver --seconds 30`,
+		ValidArgs: profiling.Components,
+		Args:      cobra.ExactValidArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			if seconds <= 0 {
+				return fmt.Errorf("--seconds must be positive")
+			}
+			if savePath != "" && !util.DirExists(savePath) {
+				return fmt.Errorf("the save-path directory (%v) does not exist", savePath)
+			}
+			if node == "" {
+				hostname, err := os.Hostname()
+				if err != nil {
+					return err
+				}
+				node = strings.ToLower(hostname)
+			}
+
+			component := args[0]
+			target, err := profiling.NewTarget(component, c.K0sVars, cfg.Spec.API.Port, node)
+			if err != nil {
+				return err
+			}
+
+			fileName := filepath.Join(savePath, fmt.Sprintf("k0s_profile_%s_%s.tar.gz", component, time.Now().Format("2006-01-02T15_04_05")))
+			f, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			logrus.Infof("collecting a %ds CPU profile of %s", seconds, component)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(seconds)*time.Second+time.Minute)
+			defer cancel()
+			if err := profiling.Collect(ctx, *target, seconds, f); err != nil {
+				os.Remove(fileName)
+				return err
+			}
+			logrus.Infof("profiles saved to %s", fileName)
+			return f.Close()
+		},
+	}
+	cmd.Flags().IntVar(&seconds, "seconds", 30, "length of the CPU profile in seconds")
+	cmd.Flags().StringVar(&node, "node", "", "name of the node for profiling the kubelet (default: hostname)")
+	cmd.Flags().StringVar(&savePath, "save-path", "", "destination directory of the profile bundle (default: current directory)")
+	return cmd
+}
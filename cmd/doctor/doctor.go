@@ -0,0 +1,135 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/doctor"
+	"github.com/k0sproject/k0s/pkg/install"
+)
+
+type CmdOpts config.CLIOptions
+
+var (
+	output string
+	since  time.Duration
+	fix    bool
+)
+
+func NewDoctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose the probable problems of the node and suggest fixes",
+		Long: `Correlate the status, the recorded events, the preflight checks and the output of the crashed
+components of this node into a list of probable problems, the most severe first, along with the suggested fixes.
+With --fix, the fixes which can't harm the node are applied, such as loading the missing kernel modules.`,
+		Example: `k0s doctor
+k0s doctor --since 24h -o yaml
+k0s doctor --fix`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runtime.GOOS == "windows" {
+				return fmt.Errorf("currently not supported on windows")
+			}
+			c := CmdOpts(config.GetCmdOpts())
+			node := doctor.Node{K0sVars: c.K0sVars, Since: since, Now: time.Now()}
+			if s, err := install.GetPid(); err == nil && s.Pid != 0 {
+				node.Role, _ = install.GetRoleByPID(s.Pid)
+			}
+			findings := doctor.Diagnose(node)
+
+			var fixErrors []string
+			if fix {
+				if os.Geteuid() != 0 {
+					return fmt.Errorf("k0s doctor --fix must be run as root")
+				}
+				for id, err := range doctor.Fix(findings) {
+					fixErrors = append(fixErrors, fmt.Sprintf("%s: %v", id, err))
+				}
+				sort.Strings(fixErrors)
+			}
+
+			switch output {
+			case "json":
+				jsn, _ := json.MarshalIndent(findings, "", "   ")
+				fmt.Println(string(jsn))
+			case "yaml":
+				ym, _ := yaml.Marshal(findings)
+				fmt.Println(string(ym))
+			default:
+				if len(findings) == 0 {
+					fmt.Println("No problems found")
+					break
+				}
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"Severity", "Problem", "Evidence", "Suggestion"})
+				table.SetAutoWrapText(false)
+				table.SetAutoFormatHeaders(true)
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetCenterSeparator("")
+				table.SetColumnSeparator("")
+				table.SetRowSeparator("")
+				table.SetHeaderLine(false)
+				table.SetBorder(false)
+				table.SetTablePadding("\t") // pad with tabs
+				table.SetNoWhiteSpace(true)
+				for _, f := range findings {
+					suggestion := f.Suggestion
+					switch {
+					case f.Fixed:
+						suggestion = "fixed: " + f.AutoFix
+					case f.AutoFix != "":
+						suggestion += " (--fix: " + f.AutoFix + ")"
+					}
+					table.Append([]string{f.Severity, f.Problem, strings.Join(f.Evidence, "; "), suggestion})
+				}
+				table.Render()
+			}
+
+			for _, e := range fixErrors {
+				fmt.Fprintf(os.Stderr, "failed to fix %s\n", e)
+			}
+			critical := 0
+			for _, f := range findings {
+				if f.Severity == doctor.SeverityCritical && !f.Fixed {
+					critical++
+				}
+			}
+			if critical > 0 {
+				return fmt.Errorf("%d critical problems found", critical)
+			}
+			return nil
+		},
+	}
+	cmd.SilenceUsage = true
+	cmd.Flags().StringVarP(&output, "out", "o", "", "sets type of output to json or yaml")
+	cmd.Flags().DurationVar(&since, "since", time.Hour, "how far back the crashes and the events are taken into account")
+	cmd.Flags().BoolVar(&fix, "fix", false, "apply the fixes which can't harm the node")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
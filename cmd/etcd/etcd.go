@@ -46,6 +46,8 @@ func NewEtcdCmd() *cobra.Command {
 	cmd.SilenceUsage = true
 	cmd.AddCommand(etcdLeaveCmd())
 	cmd.AddCommand(etcdListCmd())
+	cmd.AddCommand(etcdPromoteCmd())
+	cmd.AddCommand(etcdRemoveMemberCmd())
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
 }
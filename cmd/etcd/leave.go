@@ -46,7 +46,7 @@ func etcdLeaveCmd() *cobra.Command {
 				return fmt.Errorf("can't leave etcd cluster: peer address is empty, check the config file or use cli argument")
 			}
 
-			peerURL := fmt.Sprintf("https://%s:2380", etcdPeerAddress)
+			peerURL := etcd.PeerURL(etcdPeerAddress)
 			etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
 			if err != nil {
 				return fmt.Errorf("can't connect to the etcd: %v", err)
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+func etcdPromoteCmd() *cobra.Command {
+	var peerAddress string
+	cmd := &cobra.Command{
+		Use:     "promote",
+		Short:   "Promote an etcd learner to a voting member",
+		Example: `k0s etcd promote --peer-address 10.0.0.5`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if peerAddress == "" {
+				return fmt.Errorf("can't promote etcd learner: peer address is empty")
+			}
+
+			peerURL := etcd.PeerURL(peerAddress)
+			etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
+			if err != nil {
+				return fmt.Errorf("can't connect to the etcd: %v", err)
+			}
+			defer etcdClient.Close()
+
+			ctx := context.Background()
+			peerID, err := etcdClient.GetPeerIDByAddress(ctx, peerURL)
+			if err != nil {
+				return err
+			}
+			if err := etcdClient.PromoteMember(ctx, peerID); err != nil {
+				return fmt.Errorf("failed to promote %s: %w", peerURL, err)
+			}
+
+			logrus.
+				WithField("peerID", peerID).
+				Info("Successfully promoted")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&peerAddress, "peer-address", "", "etcd peer address of the learner")
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
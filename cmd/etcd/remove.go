@@ -0,0 +1,78 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+func etcdRemoveMemberCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove-member <peer>",
+		Short: "Remove another member from the etcd cluster",
+		Long: `Removes the member given by its name, peer address, peer URL or member ID from the etcd cluster.
+Run it on one of the remaining controllers after the controller of the member has been shut down,
+use "k0s etcd leave" to remove the member of the controller it's run on.`,
+		Example: `k0s etcd remove-member 10.0.0.5
+k0s etcd remove-member controller-3`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			c.ClusterConfig = cfg
+
+			etcdClient, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
+			if err != nil {
+				return fmt.Errorf("can't connect to the etcd: %v", err)
+			}
+			defer etcdClient.Close()
+
+			ctx := context.Background()
+			peerID, err := etcdClient.FindMember(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			// the local member would lose the cluster it's serving the API server from
+			if local := c.ClusterConfig.Spec.Storage.Etcd.PeerAddress; local != "" {
+				if localID, err := etcdClient.GetPeerIDByAddress(ctx, etcd.PeerURL(local)); err == nil && localID == peerID {
+					return fmt.Errorf("%s is the member of this controller, use k0s etcd leave to remove it", args[0])
+				}
+			}
+
+			if err := etcdClient.DeleteMember(ctx, peerID); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", args[0], err)
+			}
+
+			logrus.
+				WithField("peerID", fmt.Sprintf("%x", peerID)).
+				Info("Successfully removed")
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
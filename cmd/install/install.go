@@ -19,25 +19,49 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/build"
 	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/k0sproject/k0s/pkg/install"
+	"github.com/k0sproject/k0s/pkg/token"
 )
 
 type CmdOpts config.CLIOptions
 
+var (
+	enableAppArmor bool
+	packageMode    bool
+)
+
 func NewInstallCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "install",
 		Short: "Helper command for setting up k0s on a brand-new system. Must be run as root (or with sudo)",
+		Long: `Helper command for setting up k0s on a brand-new system. Must be run as root (or with sudo).
+
+With --package-mode and no subcommand, the previously recorded install is repeated. This is meant
+for the postinstall scripts of the distro packages, to update the service on package upgrades.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !packageMode {
+				return cmd.Help()
+			}
+			cmd.SilenceUsage = true
+			return reinstall()
+		},
 	}
 
 	cmd.AddCommand(installControllerCmd())
 	cmd.AddCommand(installWorkerCmd())
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	cmd.PersistentFlags().BoolVar(&enableAppArmor, "enable-apparmor", false, "generate and load AppArmor profiles for k0s, containerd and kubelet")
+	cmd.PersistentFlags().BoolVar(&packageMode, "package-mode", false, "cooperate with the distro packages: use the service unit shipped by the package and keep an existing install with the same arguments")
 	return cmd
 }
 
@@ -50,8 +74,10 @@ func (c *CmdOpts) setup(role string, args []string) error {
 	}
 
 	// if cfgFile is not provided k0s will handle this so no need to check if the file exists.
-	if c.CfgFile != "" && !util.IsDirectory(c.CfgFile) && !util.FileExists(c.CfgFile) {
-		return fmt.Errorf("file %s does not exist", c.CfgFile)
+	for _, cfgFile := range config.CfgFilePaths(c.CfgFile) {
+		if !util.IsDirectory(cfgFile) && !util.FileExists(cfgFile) {
+			return fmt.Errorf("file %s does not exist", cfgFile)
+		}
 	}
 	if role == "controller" {
 		cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
@@ -63,23 +89,106 @@ func (c *CmdOpts) setup(role string, args []string) error {
 			return fmt.Errorf("failed to create controller users: %v", err)
 		}
 	}
-	err := install.EnsureService(args)
-	if err != nil {
+	var userName string
+	if role == "worker" && c.RunAsUser != "" {
+		if err := install.EnsureUser(c.RunAsUser, c.K0sVars.DataDir); err != nil {
+			return fmt.Errorf("failed to create worker user: %v", err)
+		}
+		if err := util.InitDirectory(c.K0sVars.DataDir, constant.DataDirMode); err != nil {
+			return err
+		}
+		if err := util.ChownFile(c.K0sVars.DataDir, c.RunAsUser, constant.DataDirMode); err != nil {
+			return fmt.Errorf("failed to chown %s: %v", c.K0sVars.DataDir, err)
+		}
+		userName = c.RunAsUser
+	}
+	if enableAppArmor {
+		if err := install.InstallAppArmorProfiles(c.K0sVars); err != nil {
+			return fmt.Errorf("failed to install AppArmor profiles: %v", err)
+		}
+	}
+
+	state := install.InstallState{
+		Role:        role,
+		Args:        args,
+		CfgFile:     c.CfgFile,
+		DataDir:     c.K0sVars.DataDir,
+		RunDir:      config.ResolveRunDir(),
+		UserName:    userName,
+		AppArmor:    enableAppArmor,
+		PackageMode: packageMode,
+		Version:     build.Version,
+		InstalledAt: time.Now(),
+	}
+	if err := c.ensureService(state); err != nil {
 		return fmt.Errorf("failed to install k0s service: %v", err)
 	}
+	if err := install.WriteInstallState(constant.InstallStatePath, state); err != nil {
+		return fmt.Errorf("failed to write install state: %v", err)
+	}
 	return nil
 }
 
+// ensureService installs the k0s service. In package mode, the unit shipped by the distro package is used if there is one,
+// and an existing service with the same arguments is kept as is, so that the package scripts can run the install on every upgrade.
+func (c *CmdOpts) ensureService(state install.InstallState) error {
+	if !packageMode {
+		return install.EnsureService(state.Args, state.UserName)
+	}
+	if unit := install.PackagedUnit(state.Role); unit != "" {
+		logrus.Infof("Using the k0s service unit shipped by the package: %s", unit)
+		return install.EnsurePackagedService(state.Role, state.Args, state.UserName)
+	}
+
+	if _, stub, err := install.GetSysInit(state.Role); err == nil && stub != "" {
+		previous, _ := install.ReadInstallState(constant.InstallStatePath)
+		if previous.SameInstall(&state) {
+			logrus.Info("k0s service is already installed")
+			return nil
+		}
+		// the arguments have changed, the service is installed again with the new ones
+		if err := install.UninstallService(state.Role); err != nil {
+			return err
+		}
+	}
+	return install.EnsureService(state.Args, state.UserName)
+}
+
+// reinstall repeats the install recorded in the install state, for the package upgrades
+func reinstall() error {
+	state, err := install.ReadInstallState(constant.InstallStatePath)
+	if os.IsNotExist(err) {
+		fmt.Println("k0s has not been installed on this host, nothing to do")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	c := CmdOpts(config.GetCmdOpts())
+	c.CfgFile = state.CfgFile
+	if state.DataDir != "" || state.RunDir != "" {
+		c.K0sVars = constant.GetConfigWithRunDir(state.DataDir, state.RunDir)
+	}
+	c.RunAsUser = state.UserName
+	enableAppArmor = state.AppArmor
+	return c.setup(state.Role, state.Args)
+}
+
 // this command converts the file paths in the Cmd Opts struct to Absolute Paths
 // for flags passed to service init file, see the cmdFlagsToArgs func
 func (c *CmdOpts) convertFileParamsToAbsolute() (err error) {
 	// don't convert if cfgFile is empty
 
 	if c.CfgFile != "" {
-		c.CfgFile, err = filepath.Abs(c.CfgFile)
-		if err != nil {
-			return err
+		cfgFiles := config.CfgFilePaths(c.CfgFile)
+		for i := range cfgFiles {
+			cfgFiles[i], err = filepath.Abs(cfgFiles[i])
+			if err != nil {
+				return err
+			}
 		}
+		c.CfgFile = strings.Join(cfgFiles, ",")
 	}
 	if c.K0sVars.DataDir != "" {
 		c.K0sVars.DataDir, err = filepath.Abs(c.K0sVars.DataDir)
@@ -87,7 +196,14 @@ func (c *CmdOpts) convertFileParamsToAbsolute() (err error) {
 			return err
 		}
 	}
-	if c.TokenFile != "" {
+	if c.TokenFile == token.Stdin {
+		return fmt.Errorf("the service can't read the token from the standard input, give it in a file")
+	}
+	if token.IsURL(c.TokenFile) {
+		if err := token.CheckURL(c.TokenFile); err != nil {
+			return err
+		}
+	} else if c.TokenFile != "" {
 		c.TokenFile, err = filepath.Abs(c.TokenFile)
 		if err != nil {
 			return err
@@ -25,16 +25,33 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// installOnlyFlags are consumed by the install command itself and are not passed to the service
+var installOnlyFlags = map[string]struct{}{
+	"enable-apparmor": {},
+	"package-mode":    {},
+}
+
 func cmdFlagsToArgs(cmd *cobra.Command) []string {
 	var flagsAndVals []string
 	// Use visitor to collect all flags and vals into slice
 	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if _, found := installOnlyFlags[f.Name]; found {
+			return
+		}
 		val := f.Value.String()
 		switch f.Value.Type() {
 		case "stringSlice", "stringToString":
+			if f.Name == "config" {
+				paths := strings.Split(strings.Trim(val, "[]"), ",")
+				for i := range paths {
+					paths[i], _ = filepath.Abs(paths[i])
+				}
+				val = strings.Join(paths, ",")
+			}
 			flagsAndVals = append(flagsAndVals, fmt.Sprintf(`--%s="%s"`, f.Name, strings.Trim(val, "[]")))
 		default:
-			if f.Name == "data-dir" || f.Name == "token-file" || f.Name == "config" {
+			if f.Name == "data-dir" || f.Name == "run-dir" || f.Name == "token-file" ||
+				f.Name == "data-dir-encryption-device" || f.Name == "data-dir-encryption-key-file" {
 				val, _ = filepath.Abs(val)
 			}
 			flagsAndVals = append(flagsAndVals, fmt.Sprintf("--%s=%s", f.Name, val))
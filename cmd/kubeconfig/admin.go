@@ -16,45 +16,179 @@ limitations under the License.
 package kubeconfig
 
 import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/user"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/cloudflare/cfssl/log"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/config"
-	"github.com/spf13/cobra"
+)
+
+var (
+	adminTTL       time.Duration
+	adminLongLived bool
+	adminRenew     string
 )
 
 func kubeConfigAdminCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "admin [command]",
 		Short: "Display Admin's Kubeconfig file",
-		Long:  "Print kubeconfig for the Admin user to stdout",
+		Long: `Print a kubeconfig for the Admin user to stdout.
+By default the kubeconfig uses a client certificate valid for --ttl, issued for the name of the
+user running the command, so that the audit log of the cluster shows who used it.`,
 		Example: `	$ k0s kubeconfig admin > ~/.kube/config
 	$ export KUBECONFIG=~/.kube/config
-	$ kubectl get nodes`,
+	$ kubectl get nodes
+
+	Renew the client certificate of an existing kubeconfig:
+	$ k0s kubeconfig admin --renew ~/.kube/config`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// disable cfssl log
+			log.Level = log.LevelFatal
+
 			c := CmdOpts(config.GetCmdOpts())
-			if util.FileExists(c.K0sVars.AdminKubeConfigPath) {
-				content, err := ioutil.ReadFile(c.K0sVars.AdminKubeConfigPath)
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				clusterAPIURL, err := c.getAPIURL()
-				if err != nil {
-					return fmt.Errorf("failed to fetch cluster's API Address: %w", err)
-				}
-				newContent := strings.Replace(string(content), "https://localhost:6443", clusterAPIURL, -1)
-				os.Stdout.Write([]byte(newContent))
-			} else {
+			if !util.FileExists(c.K0sVars.AdminKubeConfigPath) {
 				return fmt.Errorf("failed to read admin config, check if the control plane is initialized on this node")
 			}
-			return nil
+			if adminLongLived {
+				return c.printLongLivedAdminConfig()
+			}
+			if adminRenew != "" {
+				return c.renewKubeconfig(adminRenew)
+			}
+
+			clusterAPIURL, err := c.getAPIURL()
+			if err != nil {
+				return fmt.Errorf("failed to fetch cluster's API Address: %w", err)
+			}
+			username := adminUsername()
+			cert, err := c.issueClientCert(username, "system:masters")
+			if err != nil {
+				return err
+			}
+			caCert, err := ioutil.ReadFile(certificate.CABundlePath(c.K0sVars.CertRootDir))
+			if err != nil {
+				return fmt.Errorf("failed to read cluster ca certificate: %w", err)
+			}
+
+			data := struct {
+				CACert     string
+				ClientCert string
+				ClientKey  string
+				User       string
+				JoinURL    string
+			}{
+				CACert:     base64.StdEncoding.EncodeToString(caCert),
+				ClientCert: base64.StdEncoding.EncodeToString([]byte(cert.Cert)),
+				ClientKey:  base64.StdEncoding.EncodeToString([]byte(cert.Key)),
+				User:       username,
+				JoinURL:    clusterAPIURL,
+			}
+			var buf bytes.Buffer
+			if err := userKubeconfigTemplate.Execute(&buf, &data); err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(buf.Bytes())
+			return err
 		},
 	}
+	cmd.Flags().DurationVar(&adminTTL, "ttl", 8*time.Hour, "validity of the issued client certificate")
+	cmd.Flags().BoolVar(&adminLongLived, "long-lived", false, "print the long-lived admin kubeconfig of the controller instead of issuing a new certificate")
+	cmd.Flags().BoolVar(&public, "public", false, "point the kubeconfig to the public address of the control plane (spec.api.publicAddress)")
+	cmd.Flags().StringVar(&adminRenew, "renew", "", "renew the client certificate of the given kubeconfig file in place, keeping its user and groups")
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
 }
+
+func (c *CmdOpts) printLongLivedAdminConfig() error {
+	content, err := ioutil.ReadFile(c.K0sVars.AdminKubeConfigPath)
+	if err != nil {
+		return err
+	}
+	clusterAPIURL, err := c.getAPIURL()
+	if err != nil {
+		return fmt.Errorf("failed to fetch cluster's API Address: %w", err)
+	}
+	newContent := strings.Replace(string(content), "https://localhost:6443", clusterAPIURL, -1)
+	_, err = os.Stdout.Write([]byte(newContent))
+	return err
+}
+
+func (c *CmdOpts) issueClientCert(cn, o string) (certificate.Certificate, error) {
+	certManager := certificate.Manager{
+		K0sVars: c.K0sVars,
+	}
+	cert, err := certManager.IssueCertificate(certificate.Request{
+		Name:   cn,
+		CN:     cn,
+		O:      o,
+		CACert: path.Join(c.K0sVars.CertRootDir, "ca.crt"),
+		CAKey:  path.Join(c.K0sVars.CertRootDir, "ca.key"),
+	}, adminTTL)
+	if err != nil {
+		return certificate.Certificate{}, fmt.Errorf("failed to issue client certificate: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "issued client certificate for %s, valid until %s\n", cn, time.Now().Add(adminTTL).Format(time.RFC3339))
+	return cert, nil
+}
+
+// renewKubeconfig replaces the client certificates of the kubeconfig with new ones for the same subjects
+func (c *CmdOpts) renewKubeconfig(kubeconfigPath string) error {
+	cfg, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig %s: %w", kubeconfigPath, err)
+	}
+	renewed := 0
+	for name, authInfo := range cfg.AuthInfos {
+		if len(authInfo.ClientCertificateData) == 0 {
+			continue
+		}
+		block, _ := pem.Decode(authInfo.ClientCertificateData)
+		if block == nil {
+			return fmt.Errorf("failed to decode the client certificate of %s", name)
+		}
+		current, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse the client certificate of %s: %w", name, err)
+		}
+		cert, err := c.issueClientCert(current.Subject.CommonName, strings.Join(current.Subject.Organization, ","))
+		if err != nil {
+			return err
+		}
+		authInfo.ClientCertificateData = []byte(cert.Cert)
+		authInfo.ClientKeyData = []byte(cert.Key)
+		renewed++
+	}
+	if renewed == 0 {
+		return fmt.Errorf("no embedded client certificates found in %s", kubeconfigPath)
+	}
+	return clientcmd.WriteToFile(*cfg, kubeconfigPath)
+}
+
+// adminUsername names the issued admin certificates after the user running the command
+func adminUsername() string {
+	name := os.Getenv("SUDO_USER")
+	if name == "" {
+		if u, err := user.Current(); err == nil {
+			name = u.Username
+		}
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	return "admin:" + name
+}
@@ -69,7 +69,10 @@ Note: A certificate once signed cannot be revoked for a particular user`,
 	$ k0s kubeconfig create [username]
 
 	optionally add groups:
-	$ k0s kubeconfig create [username] --groups [groups]`,
+	$ k0s kubeconfig create [username] --groups [groups]
+
+	for a user outside of the cluster network:
+	$ k0s kubeconfig create [username] --public`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// disable cfssl log
 			log.Level = log.LevelFatal
@@ -84,7 +87,7 @@ Note: A certificate once signed cannot be revoked for a particular user`,
 			if err != nil {
 				return fmt.Errorf("failed to fetch cluster's API Address: %w", err)
 			}
-			caCert, err := ioutil.ReadFile(path.Join(c.K0sVars.CertRootDir, "ca.crt"))
+			caCert, err := ioutil.ReadFile(certificate.CABundlePath(c.K0sVars.CertRootDir))
 			if err != nil {
 				return fmt.Errorf("failed to read cluster ca certificate: %w, check if the control plane is initialized on this node", err)
 			}
@@ -132,6 +135,7 @@ Note: A certificate once signed cannot be revoked for a particular user`,
 		},
 	}
 	cmd.Flags().StringVar(&groups, "groups", "", "Specify groups")
+	cmd.Flags().BoolVar(&public, "public", false, "point the kubeconfig to the public address of the control plane (spec.api.publicAddress)")
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	return cmd
 }
@@ -144,5 +148,8 @@ func (c *CmdOpts) getAPIURL() (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if public {
+		return clusterConfig.Spec.API.PublicAPIAddressURL(), nil
+	}
 	return clusterConfig.Spec.API.APIAddressURL(), nil
 }
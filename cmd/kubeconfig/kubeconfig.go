@@ -22,6 +22,9 @@ import (
 
 type CmdOpts config.CLIOptions
 
+// public makes the kubeconfigs point to the public address of the control plane
+var public bool
+
 func NewKubeConfigCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "kubeconfig [command]",
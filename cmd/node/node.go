@@ -0,0 +1,82 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/node"
+)
+
+type CmdOpts config.CLIOptions
+
+var (
+	gracePeriod time.Duration
+	timeout     time.Duration
+	force       bool
+)
+
+func NewNodeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Manage the nodes of the cluster",
+	}
+
+	cmd.SilenceUsage = true
+	cmd.AddCommand(removeCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+func removeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove [node]",
+		Short: "Drain the node and remove it from the cluster",
+		Long: `Cordons the node and evicts its pods, honoring the pod disruption budgets. The node is deleted once
+the pods have terminated and the volumes of the node have been detached. If that doesn't happen within
+the timeout, the node is left cordoned, unless --force is given.`,
+		Example: `k0s node remove worker-1`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			client, err := kubernetes.NewClient(c.K0sVars.AdminKubeConfigPath)
+			if err != nil {
+				return err
+			}
+			drainer := &node.Drainer{
+				Client:      client,
+				GracePeriod: gracePeriod,
+				Progress: func(format string, args ...interface{}) {
+					fmt.Printf(format+"\n", args...)
+				},
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			return drainer.Remove(ctx, args[0], force)
+		},
+	}
+	cmd.Flags().DurationVar(&gracePeriod, "grace-period", 30*time.Second, "grace period given to the evicted pods for terminating")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "time to wait for the pods to terminate and the volumes to detach")
+	cmd.Flags().BoolVar(&force, "force", false, "delete the node even if it couldn't be drained within the timeout")
+	return cmd
+}
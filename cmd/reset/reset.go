@@ -16,10 +16,15 @@ limitations under the License.
 package reset
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
+	"github.com/olekukonko/tablewriter"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -30,15 +35,27 @@ import (
 
 type CmdOpts config.CLIOptions
 
+var (
+	drainNode        bool
+	drainGracePeriod time.Duration
+	dryRun           bool
+	keepFirewall     bool
+	output           string
+	preserveData     bool
+	removeImages     bool
+	steps            []string
+	timeout          time.Duration
+)
+
 func NewResetCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "reset",
 		Short: "Helper command for uninstalling k0s. Must be run as root (or with sudo)",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if runtime.GOOS == "windows" {
-				return fmt.Errorf("currently not supported on windows")
-			}
 			c := CmdOpts(config.GetCmdOpts())
+			if runtime.GOOS == "windows" && c.WorkerOptions.CriSocket == "" {
+				return fmt.Errorf("windows workers use an external container runtime, give the --cri-socket of the worker")
+			}
 			return c.reset()
 		},
 		PreRunE: preRunValidateConfig,
@@ -46,6 +63,15 @@ func NewResetCmd() *cobra.Command {
 	cmd.SilenceUsage = true
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	cmd.Flags().AddFlagSet(config.GetCriSocketFlag())
+	cmd.Flags().BoolVar(&drainNode, "drain", false, "cordon the node and evict its pods through the API before force-stopping the containers, skipped if the API is unreachable")
+	cmd.Flags().DurationVar(&drainGracePeriod, "drain-grace-period", 30*time.Second, "time given to the pods for terminating when draining the node")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the mounts, directories, containers and files that reset would remove, without touching anything")
+	cmd.Flags().BoolVar(&keepFirewall, "keep-firewall-rules", false, "leave the iptables and ip6tables rules alone, for hosts whose firewall is managed externally")
+	cmd.Flags().StringVarP(&output, "out", "o", "", "sets type of the output to json")
+	cmd.Flags().BoolVar(&removeImages, "remove-images", false, "remove the images of the kubelet pods from the custom runtime given with --cri-socket too, which may share them with others")
+	cmd.Flags().BoolVar(&preserveData, "preserve-data", false, "keep the data dir with the certificates, the etcd or kine data and the manifests, for restarting k0s with the same cluster identity")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "time after which the remaining clean-up operations are given up on")
+	cmd.Flags().StringSliceVar(&steps, "steps", nil, fmt.Sprintf("run only the given clean-up steps (%s), all of them by default", strings.Join(cleanup.StepNames, ", ")))
 	return cmd
 }
 
@@ -57,12 +83,19 @@ func (c *CmdOpts) reset() error {
 
 	logger.SetFormatter(textFormatter)
 
-	if os.Geteuid() != 0 {
+	// windows has no euid, the clean-up operations fail without the administrator privileges
+	if runtime.GOOS != "windows" && os.Geteuid() != 0 {
 		logger.Fatal("this command must be run as root!")
 	}
+	if err := cleanup.ValidateSteps(steps); err != nil {
+		return err
+	}
+	if output != "" && output != "json" {
+		return fmt.Errorf("unsupported output format %q", output)
+	}
 
 	k0sStatus, _ := install.GetPid()
-	if k0sStatus.Pid != 0 {
+	if k0sStatus.Pid != 0 && !dryRun {
 		logger.Fatal("k0s seems to be running! please stop k0s before reset.")
 	}
 
@@ -72,13 +105,97 @@ func (c *CmdOpts) reset() error {
 		logger.Fatalf("failed to configure cleanup: %v", err)
 		return err
 	}
+	if drainNode {
+		cfg.DrainGracePeriod = drainGracePeriod
+	}
+	cfg.Steps = steps
+	cfg.KeepFirewallRules = keepFirewall
+	cfg.PreserveData = preserveData
+	cfg.RemoveImages = removeImages
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if dryRun {
+		return printPlan(ctx, cfg)
+	}
 
-	err = cfg.Cleanup()
+	result, err := cfg.Cleanup(ctx)
+	if printErr := printResult(result); printErr != nil {
+		return printErr
+	}
 
+	if preserveData {
+		logger.Infof("k0s cleanup operations done, the data dir %s was kept. k0s can be started again with the same cluster identity.", c.K0sVars.DataDir)
+		return err
+	}
 	logger.Info("k0s cleanup operations done. To ensure a full reset, a node reboot is recommended.")
 	return err
 }
 
+// printResult prints the outcomes of the clean-up operations in the requested format
+func printResult(result *cleanup.CleanupResult) error {
+	if output == "json" {
+		if result.Items == nil {
+			result.Items = []cleanup.ItemResult{}
+		}
+		data, err := json.MarshalIndent(result, "", "   ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if len(result.Items) == 0 {
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Step", "Action", "Target", "Result"})
+	table.SetAutoFormatHeaders(true)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, item := range result.Items {
+		outcome := "ok"
+		if item.Error != "" {
+			outcome = "failed: " + item.Error
+		}
+		table.Append([]string{item.Step, item.Action, item.Target, outcome})
+	}
+	table.Render()
+	return nil
+}
+
+// printPlan prints the operations of the clean-up in the requested format
+func printPlan(ctx context.Context, cfg *cleanup.Config) error {
+	actions, err := cfg.Plan(ctx)
+	switch output {
+	case "json":
+		if actions == nil {
+			actions = []cleanup.Action{}
+		}
+		data, err := json.MarshalIndent(actions, "", "   ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "":
+		if len(actions) == 0 {
+			fmt.Println("k0s reset has nothing to clean up")
+		}
+		step := ""
+		for _, a := range actions {
+			if a.Step != step {
+				step = a.Step
+				fmt.Printf("* %s\n", step)
+			}
+			fmt.Printf("  would %s\n", a)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	return err
+}
+
 func preRunValidateConfig(_ *cobra.Command, _ []string) error {
 	c := CmdOpts(config.GetCmdOpts())
 	_, err := config.ValidateYaml(c.CfgFile, c.K0sVars)
@@ -45,7 +45,7 @@ func NewRestoreCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
 			if len(args) != 1 {
-				return fmt.Errorf("path to backup archive expected")
+				return fmt.Errorf("path or S3 location of the backup archive expected")
 			}
 			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
 			if err != nil {
@@ -81,7 +81,7 @@ func (c *CmdOpts) restore(path string) error {
 		logger.Fatal("k0s seems to be running! k0s must be down during the restore operation.")
 	}
 
-	if !util.FileExists(path) {
+	if backup.IsLocal(path) && !util.FileExists(strings.TrimPrefix(path, "file://")) {
 		return fmt.Errorf("given file %s does not exist", path)
 	}
 
@@ -20,6 +20,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -29,13 +31,21 @@ import (
 
 	"github.com/k0sproject/k0s/cmd/airgap"
 	"github.com/k0sproject/k0s/cmd/api"
+	"github.com/k0sproject/k0s/cmd/attestation"
 	"github.com/k0sproject/k0s/cmd/backup"
+	"github.com/k0sproject/k0s/cmd/ca"
+	"github.com/k0sproject/k0s/cmd/check"
+	configcmd "github.com/k0sproject/k0s/cmd/config"
 	"github.com/k0sproject/k0s/cmd/controller"
+	"github.com/k0sproject/k0s/cmd/crictl"
 	"github.com/k0sproject/k0s/cmd/ctr"
+	"github.com/k0sproject/k0s/cmd/debug"
+	"github.com/k0sproject/k0s/cmd/doctor"
 	"github.com/k0sproject/k0s/cmd/etcd"
 	"github.com/k0sproject/k0s/cmd/install"
 	"github.com/k0sproject/k0s/cmd/kubeconfig"
 	"github.com/k0sproject/k0s/cmd/kubectl"
+	"github.com/k0sproject/k0s/cmd/node"
 	"github.com/k0sproject/k0s/cmd/reset"
 	"github.com/k0sproject/k0s/cmd/restore"
 	"github.com/k0sproject/k0s/cmd/start"
@@ -43,16 +53,23 @@ import (
 	"github.com/k0sproject/k0s/cmd/stop"
 	"github.com/k0sproject/k0s/cmd/sysinfo"
 	"github.com/k0sproject/k0s/cmd/token"
+	"github.com/k0sproject/k0s/cmd/upgrade"
 	"github.com/k0sproject/k0s/cmd/validate"
+	"github.com/k0sproject/k0s/cmd/vcluster"
 	"github.com/k0sproject/k0s/cmd/version"
 	"github.com/k0sproject/k0s/cmd/worker"
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/build"
 	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/exitcode"
 )
 
 var (
-	longDesc string
+	longDesc    string
+	extractOnly bool
+	errorFormat string
 )
 
 type cliOpts config.CLIOptions
@@ -72,17 +89,43 @@ func NewRootCmd() *cobra.Command {
 				}()
 			}
 		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !extractOnly {
+				return cmd.Help()
+			}
+			c := cliOpts(config.GetCmdOpts())
+			names, err := assets.StageAll(c.K0sVars.BinDir, constant.BinDirMode)
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				fmt.Println("no embedded binaries in this k0s build")
+				return nil
+			}
+			for _, name := range names {
+				fmt.Println(filepath.Join(c.K0sVars.BinDir, name))
+			}
+			return nil
+		},
 	}
 
 	cmd.AddCommand(airgap.NewAirgapCmd())
 	cmd.AddCommand(api.NewAPICmd())
+	cmd.AddCommand(attestation.NewAttestationCmd())
 	cmd.AddCommand(backup.NewBackupCmd())
+	cmd.AddCommand(ca.NewCACmd())
+	cmd.AddCommand(check.NewCheckCmd())
+	cmd.AddCommand(configcmd.NewConfigCmd())
 	cmd.AddCommand(controller.NewControllerCmd())
+	cmd.AddCommand(crictl.NewCrictlCmd())
 	cmd.AddCommand(ctr.NewCtrCommand())
+	cmd.AddCommand(debug.NewDebugCmd())
+	cmd.AddCommand(doctor.NewDoctorCmd())
 	cmd.AddCommand(etcd.NewEtcdCmd())
 	cmd.AddCommand(install.NewInstallCmd())
 	cmd.AddCommand(kubeconfig.NewKubeConfigCmd())
 	cmd.AddCommand(kubectl.NewK0sKubectlCmd())
+	cmd.AddCommand(node.NewNodeCmd())
 	cmd.AddCommand(reset.NewResetCmd())
 	cmd.AddCommand(restore.NewRestoreCmd())
 	cmd.AddCommand(start.NewStartCmd())
@@ -90,7 +133,9 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(stop.NewStopCmd())
 	cmd.AddCommand(sysinfo.NewSysinfoCmd())
 	cmd.AddCommand(token.NewTokenCmd())
+	cmd.AddCommand(upgrade.NewUpgradeCmd())
 	cmd.AddCommand(validate.NewValidateCmd())
+	cmd.AddCommand(vcluster.NewVClusterCmd())
 	cmd.AddCommand(version.NewVersionCmd())
 	cmd.AddCommand(worker.NewWorkerCmd())
 
@@ -98,6 +143,13 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(newDefaultConfigCmd())
 	cmd.AddCommand(newDocsCmd())
 
+	// the errors are printed by Execute, along with their category for --error-format json
+	cmd.SilenceErrors = true
+	cmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "format of the error printed on failure: text, or json with the error category and the exit code")
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return exitcode.Wrap(exitcode.Usage, err)
+	})
+
 	cmd.DisableAutoGenTag = true
 	longDesc = "k0s - The zero friction Kubernetes - https://k0sproject.io"
 	if build.EulaNotice != "" {
@@ -107,6 +159,7 @@ func NewRootCmd() *cobra.Command {
 
 	// workaround for the data-dir location input for the kubectl command
 	cmd.PersistentFlags().AddFlagSet(config.GetKubeCtlFlagSet())
+	cmd.Flags().BoolVar(&extractOnly, "extract-only", false, "stage the embedded binaries into the k0s bin dir and exit")
 
 	return cmd
 }
@@ -131,8 +184,9 @@ func newDocsCmd() *cobra.Command {
 
 func newDefaultConfigCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "default-config",
-		Short: "Output the default k0s configuration yaml to stdout",
+		Use:        "default-config",
+		Short:      "Output the default k0s configuration yaml to stdout",
+		Deprecated: "use 'k0s config default' instead",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := cliOpts(config.GetCmdOpts())
 			if err := c.buildConfig(); err != nil {
@@ -202,11 +256,15 @@ func (c *cliOpts) buildConfig() error {
 	return nil
 }
 
+// Execute runs the k0s command and exits with the exit code of the category of its error, see pkg/exitcode
 func Execute() {
-	// just a hack to trick linter which requires to check for errors
-	// cobra itself already prints out all errors that happen in subcommands
 	err := NewRootCmd().Execute()
-	if err != nil {
-		log.Fatal(err)
+	if err == nil {
+		return
+	}
+	if strings.HasPrefix(err.Error(), "unknown command") {
+		err = exitcode.Wrap(exitcode.Usage, err)
 	}
+	exitcode.Print(os.Stderr, err, errorFormat)
+	os.Exit(exitcode.Code(err))
 }
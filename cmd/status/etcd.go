@@ -0,0 +1,96 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+// etcdStatus is the etcd health shown by k0s status etcd
+type etcdStatus struct {
+	etcd.Metrics `json:",inline" yaml:",inline"`
+	Alerts       []string `json:"alerts" yaml:"alerts"`
+}
+
+func statusEtcdCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "etcd",
+		Short:   "Show the health metrics of the etcd member on this controller. Must be run as root (or with sudo)",
+		Example: `k0s status etcd -o json`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			client, err := etcd.NewClient(c.K0sVars.CertRootDir, c.K0sVars.EtcdCertDir)
+			if err != nil {
+				return fmt.Errorf("can't connect to etcd: %w", err)
+			}
+			defer client.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			m, err := client.Metrics(ctx)
+			if err != nil {
+				return err
+			}
+			s := etcdStatus{Metrics: *m, Alerts: m.Alerts()}
+			if s.Alerts == nil {
+				s.Alerts = []string{}
+			}
+
+			switch output {
+			case "json":
+				jsn, _ := json.MarshalIndent(s, "", "   ")
+				fmt.Println(string(jsn))
+			case "yaml":
+				ym, _ := yaml.Marshal(s)
+				fmt.Println(string(ym))
+			default:
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetAutoWrapText(false)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetCenterSeparator("")
+				table.SetColumnSeparator("")
+				table.SetRowSeparator("")
+				table.SetHeaderLine(false)
+				table.SetBorder(false)
+				table.SetTablePadding("\t") // pad with tabs
+				table.SetNoWhiteSpace(true)
+				table.Append([]string{"Has leader:", fmt.Sprint(m.HasLeader)})
+				table.Append([]string{"Leader changes:", fmt.Sprint(m.LeaderChanges)})
+				table.Append([]string{"DB size:", fmt.Sprintf("%d bytes (%.0f%% of the %d bytes quota)", m.DBSizeBytes, m.DBSizeRatio()*100, m.QuotaBytes)})
+				table.Append([]string{"WAL fsync p99:", fmt.Sprintf("%.1fms", m.FsyncP99Seconds*1000)})
+				table.Render()
+				for _, alert := range s.Alerts {
+					fmt.Println("WARNING:", alert)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.SilenceUsage = true
+	return cmd
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+var historySince time.Duration
+
+func statusHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "history",
+		Short:   "Show the recorded component health transitions and node lifecycle events",
+		Example: `k0s status history --since 24h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if !util.FileExists(c.K0sVars.StatusHistoryPath) {
+				fmt.Println("No k0s status history found")
+				return nil
+			}
+
+			var since time.Time
+			if historySince > 0 {
+				since = time.Now().Add(-historySince)
+			}
+			events, err := status.NewHistory(c.K0sVars.StatusHistoryPath, status.DefaultHistoryRetention).List(since)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				jsn, _ := json.MarshalIndent(events, "", "   ")
+				fmt.Println(string(jsn))
+			case "yaml":
+				ym, _ := yaml.Marshal(events)
+				fmt.Println(string(ym))
+			default:
+				table := tablewriter.NewWriter(os.Stdout)
+				table.SetHeader([]string{"Time", "Component", "Event", "Message"})
+				table.SetAutoWrapText(false)
+				table.SetAutoFormatHeaders(true)
+				table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+				table.SetAlignment(tablewriter.ALIGN_LEFT)
+				table.SetCenterSeparator("")
+				table.SetColumnSeparator("")
+				table.SetRowSeparator("")
+				table.SetHeaderLine(false)
+				table.SetBorder(false)
+				table.SetTablePadding("\t") // pad with tabs
+				table.SetNoWhiteSpace(true)
+				for _, e := range events {
+					table.Append([]string{e.Timestamp.Format(time.RFC3339), e.Component, e.Type, e.Message})
+				}
+				table.Render()
+			}
+			return nil
+		},
+	}
+	cmd.SilenceUsage = true
+	cmd.Flags().DurationVar(&historySince, "since", 0, "only show events newer than the given duration, e.g. 1h (default: all retained events)")
+	return cmd
+}
@@ -20,16 +20,21 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/k0sproject/k0s/pkg/config"
 	"github.com/k0sproject/k0s/pkg/install"
+	"github.com/k0sproject/k0s/pkg/status"
 )
 
 type CmdOpts config.CLIOptions
 
+// recentCrashesPeriod is how far back the crashes of the components are shown, the older ones stay in the crash dir
+const recentCrashesPeriod = 24 * time.Hour
+
 var (
 	output string
 	s      *install.K0sStatus
@@ -68,6 +73,28 @@ func NewStatusCmd() *cobra.Command {
 				if s.SysInit, s.StubFile, err = install.GetSysInit(strings.TrimSuffix(s.Role, "+worker")); err != nil {
 					return err
 				}
+
+				c := CmdOpts(config.GetCmdOpts())
+				if metadata, err := status.ReadClusterMetadata(c.K0sVars.ClusterMetadataPath); err == nil {
+					s.ClusterName = metadata.Name
+					s.ClusterLabels = metadata.Labels
+				}
+				if report, err := status.ReadDriftReport(c.K0sVars.ConfigDriftPath); err == nil {
+					s.ConfigDrift = report.Items
+				}
+				if snapshot, err := status.ReadSnapshotStatus(c.K0sVars.EtcdSnapshotStatusPath); err == nil {
+					s.EtcdSnapshot = snapshot
+				}
+				if crashes, err := status.ListCrashes(c.K0sVars.CrashDir); err != nil {
+					logrus.Warnf("failed to read the crashes: %v", err)
+				} else {
+					s.Crashes = recentCrashes(crashes, time.Now().Add(-recentCrashesPeriod))
+				}
+				if install.AppArmorEnabled() {
+					if s.AppArmor, err = install.AppArmorStatus(); err != nil {
+						logrus.Warnf("failed to read AppArmor status: %v", err)
+					}
+				}
 			} else {
 				fmt.Fprintln(os.Stderr, "K0s not running")
 				os.Exit(1)
@@ -79,5 +106,18 @@ func NewStatusCmd() *cobra.Command {
 	}
 	cmd.SilenceUsage = true
 	cmd.PersistentFlags().StringVarP(&output, "out", "o", "", "sets type of output to json or yaml")
+	cmd.AddCommand(statusHistoryCmd())
+	cmd.AddCommand(statusEtcdCmd())
 	return cmd
 }
+
+// recentCrashes returns the crashes since the given time, newest first
+func recentCrashes(crashes []status.Crash, since time.Time) []status.Crash {
+	var recent []status.Crash
+	for i := len(crashes) - 1; i >= 0; i-- {
+		if crashes[i].Timestamp.After(since) {
+			recent = append(recent, crashes[i])
+		}
+	}
+	return recent
+}
@@ -28,7 +28,15 @@ import (
 	"github.com/k0sproject/k0s/pkg/token"
 )
 
-var createTokenRole string
+var (
+	createTokenRole string
+	maxJoins        int
+	usageLimit      int
+	joinWindow      time.Duration
+	attestation     string
+	publicToken     bool
+	apiEndpoints    []string
+)
 
 func tokenCreateCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -36,6 +44,11 @@ func tokenCreateCmd() *cobra.Command {
 		Short: "Create join token",
 		Example: `k0s token create --role worker --expiry 100h //sets expiration time to 100 hours
 k0s token create --role worker --expiry 10m  //sets expiration time to 10 minutes
+k0s token create --role worker --max-joins 10 --join-window 1h //allows at most 10 nodes to join per hour
+k0s token create --role controller --usage-limit 1 //the token is deleted once a controller has joined with it
+k0s token create --role worker --attestation tpm //only allows nodes with an enrolled TPM to join
+k0s token create --role worker --public //joins through spec.api.publicAddress, for nodes outside of the cluster network
+k0s token create --role worker --api-endpoint 10.0.0.2 --api-endpoint 10.0.0.3 //fails over to the other controllers while joining
 `,
 		PreRunE: checkCreateTokenRole,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -61,7 +74,13 @@ k0s token create --role worker --expiry 10m  //sets expiration time to 10 minute
 			}, func(err error) bool {
 				return waitCreate
 			}, func() error {
-				bootstrapConfig, err = token.CreateKubeletBootstrapConfig(clusterConfig, c.K0sVars, createTokenRole, expiry)
+				bootstrapConfig, err = token.CreateKubeletBootstrapConfig(clusterConfig, c.K0sVars, createTokenRole, expiry, token.CreateOptions{
+					UsageLimit:   usageLimit,
+					Quota:        token.JoinQuota{MaxJoins: maxJoins, Window: joinWindow},
+					Attestation:  attestation == "tpm",
+					Public:       publicToken,
+					APIEndpoints: apiEndpoints,
+				})
 
 				return err
 			})
@@ -80,6 +99,12 @@ k0s token create --role worker --expiry 10m  //sets expiration time to 10 minute
 	cmd.Flags().StringVar(&tokenExpiry, "expiry", "0s", "Expiration time of the token. Format 1.5h, 2h45m or 300ms.")
 	cmd.Flags().StringVar(&createTokenRole, "role", "worker", "Either worker or controller")
 	cmd.Flags().BoolVar(&waitCreate, "wait", false, "wait forever (default false)")
+	cmd.Flags().IntVar(&maxJoins, "max-joins", 0, "Maximum number of worker nodes joining with the token within the join window, 0 means unlimited")
+	cmd.Flags().IntVar(&usageLimit, "usage-limit", 0, "Number of nodes that may join with the token before it's deleted, 0 means unlimited")
+	cmd.Flags().DurationVar(&joinWindow, "join-window", 0, "Sliding window the --max-joins quota applies to, 0 means the whole lifetime of the token")
+	cmd.Flags().BoolVar(&publicToken, "public", false, "Use the public address of the control plane (spec.api.publicAddress) in the token")
+	cmd.Flags().StringSliceVar(&apiEndpoints, "api-endpoint", nil, "Additional API server address, as host or host:port, the worker nodes fail over to while joining. Can be given multiple times")
+	cmd.Flags().StringVar(&attestation, "attestation", "", "Require the joining worker nodes to attest with an enrolled TPM, the only supported value is \"tpm\"")
 
 	return cmd
 }
@@ -89,5 +114,39 @@ func checkCreateTokenRole(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 		return fmt.Errorf("unsupported role %q, supported roles are %q and %q", createTokenRole, controllerRole, workerRole)
 	}
+	if maxJoins < 0 || joinWindow < 0 || usageLimit < 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("--max-joins, --join-window and --usage-limit must not be negative")
+	}
+	if usageLimit > 0 && maxJoins > usageLimit {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("--max-joins must not exceed --usage-limit")
+	}
+	if len(apiEndpoints) > 0 && createTokenRole != workerRole {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("API endpoints are only supported for %q tokens", workerRole)
+	}
+	if maxJoins > 0 && createTokenRole != workerRole {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("join quota is only supported for %q tokens", workerRole)
+	}
+	if attestation != "" {
+		cmd.SilenceUsage = true
+		if attestation != "tpm" {
+			return fmt.Errorf("unsupported attestation %q, the only supported attestation is \"tpm\"", attestation)
+		}
+		if createTokenRole != workerRole {
+			return fmt.Errorf("attestation is only supported for %q tokens", workerRole)
+		}
+		if maxJoins > 0 {
+			return fmt.Errorf("join quota is not supported for attestation tokens")
+		}
+		if usageLimit > 0 {
+			return fmt.Errorf("usage limit is not supported for attestation tokens")
+		}
+		if len(apiEndpoints) > 0 {
+			return fmt.Errorf("API endpoints are not supported for attestation tokens")
+		}
+	}
 	return nil
 }
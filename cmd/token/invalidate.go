@@ -16,6 +16,7 @@ limitations under the License.
 package token
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -26,9 +27,10 @@ import (
 
 func tokenInvalidateCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "invalidate",
-		Short:   "Invalidates existing join token",
-		Example: "k0s token invalidate xyz123",
+		Use:   "invalidate",
+		Short: "Invalidates existing join token",
+		Example: `k0s token invalidate xyz123
+k0s token invalidate xyz123 abc456 //invalidates several tokens, the IDs are shown by "k0s token list"`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
 			if len(args) < 1 {
@@ -39,13 +41,23 @@ func tokenInvalidateCmd() *cobra.Command {
 				return err
 			}
 
+			notFound := 0
 			for _, id := range args {
 				err := manager.Remove(id)
+				if errors.Is(err, token.ErrTokenNotFound) {
+					fmt.Printf("token %s not found\n", id)
+					notFound++
+					continue
+				}
 				if err != nil {
 					return err
 				}
 				fmt.Printf("token %s deleted succesfully\n", id)
 			}
+			if notFound > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of the tokens were not found, see `k0s token list`", notFound)
+			}
 			return nil
 		},
 	}
@@ -52,7 +52,7 @@ func tokenListCmd() *cobra.Command {
 
 			//fmt.Printf("Tokens: %v \n", tokens)
 			table := tablewriter.NewWriter(os.Stdout)
-			table.SetHeader([]string{"ID", "Role", "Expires at"})
+			table.SetHeader([]string{"ID", "Role", "Expires at", "Join quota", "Uses"})
 			table.SetAutoWrapText(false)
 			table.SetAutoFormatHeaders(true)
 			table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
@@ -0,0 +1,247 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package upgrade
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/component/controller"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/install"
+	"github.com/k0sproject/k0s/pkg/status"
+	"github.com/k0sproject/k0s/pkg/supervisor"
+	"github.com/k0sproject/k0s/pkg/upgrade"
+)
+
+type CmdOpts config.CLIOptions
+
+var (
+	window     time.Duration
+	force      bool
+	binaryOnly bool
+	configOut  string
+	logLevels  map[string]string
+	singleNode bool
+)
+
+func NewUpgradeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Prepare the upgrades of the controller and roll them back. Must be run as root (or with sudo)",
+	}
+
+	cmd.SilenceUsage = true
+	cmd.AddCommand(prepareCmd())
+	cmd.AddCommand(statusCmd())
+	cmd.AddCommand(diffCmd())
+	cmd.AddCommand(rollbackCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	return cmd
+}
+
+func prepareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prepare",
+		Short: "Keep the binary and a backup of the running controller for rolling back the upgrade",
+		Long: `Keeps a copy of the k0s binary and a backup of the controller in the upgrade dir of the data dir.
+Run it with the current version while the controller is running, then replace the binary and restart k0s.
+The controller then has the health check window to become healthy again with the new version.`,
+		Example: `k0s upgrade prepare --window 30m`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if os.Geteuid() != 0 {
+				return fmt.Errorf("this command must be run as root")
+			}
+			if role := install.GetRoleByStagedKubelet(c.K0sVars.BinDir); !strings.Contains(role, "controller") {
+				return fmt.Errorf("upgrade command must be run on the controller node, have `%s`", role)
+			}
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			state, err := upgrade.Prepare(c.CfgFile, cfg.Spec, c.K0sVars, window)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("kept k0s %s and the backup %s, the upgrade can be rolled back if the controller isn't healthy within %s of the upgrade\n", state.PreviousVersion, state.Backup, window)
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&window, "window", upgrade.DefaultWindow, "time the upgraded controller has to pass the health checks")
+	return cmd
+}
+
+func statusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the prepared upgrade and the result of its health checks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			state, err := upgrade.LoadState(c.K0sVars)
+			if err != nil {
+				return err
+			}
+			if state == nil {
+				fmt.Println("no upgrade has been prepared")
+			} else {
+				fmt.Printf("Previous version: %s\n", state.PreviousVersion)
+				fmt.Printf("Prepared at: %s\n", state.PreparedAt.Format(time.RFC3339))
+				if state.UpgradedAt != nil {
+					fmt.Printf("Upgraded to: %s at %s\n", state.Version, state.UpgradedAt.Format(time.RFC3339))
+				}
+				events, err := status.NewHistory(c.K0sVars.StatusHistoryPath, status.DefaultHistoryRetention).List(time.Time{})
+				if err != nil {
+					return err
+				}
+				health, reason := state.Check(events, time.Now())
+				fmt.Printf("Health checks: %s\n", health)
+				if reason != "" {
+					fmt.Printf("Reason: %s\n", reason)
+				}
+			}
+			return printArgChanges(c.K0sVars.ComponentArgsDir)
+		},
+	}
+}
+
+func diffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show the flag changes of the control plane components before switching the k0s version",
+		Long: `Renders the flags of kube-apiserver, kube-scheduler and kube-controller-manager with the running k0s binary and
+shows their differences to the flags the components were last started with. Run it with the new k0s binary
+before restarting k0s. The changes of the other components are shown by "k0s upgrade status" once they've started.`,
+		Example: `/tmp/k0s-new upgrade diff --config /etc/k0s/k0s.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			cfg, err := config.GetYamlFromFile(c.CfgFile, c.K0sVars)
+			if err != nil {
+				return err
+			}
+			rendered, err := controller.RenderArgs(cfg, c.K0sVars, logLevels, !singleNode && !cfg.Spec.Components.IsDisabled(v1beta1.KonnectivityServerComponent))
+			if err != nil {
+				return err
+			}
+			components := make([]string, 0, len(rendered))
+			for component := range rendered {
+				components = append(components, component)
+			}
+			sort.Strings(components)
+			for _, component := range components {
+				preview, err := supervisor.PreviewArgs(c.K0sVars.ComponentArgsDir, component, rendered[component])
+				if err != nil {
+					return err
+				}
+				if preview == nil {
+					fmt.Printf("%s: not started yet\n", component)
+					continue
+				}
+				if len(preview.Changes) == 0 {
+					fmt.Printf("%s: no flag changes to k0s %s\n", component, preview.PreviousVersion)
+					continue
+				}
+				fmt.Printf("%s: flag changes to k0s %s:\n", component, preview.PreviousVersion)
+				for _, change := range preview.Changes {
+					fmt.Printf("  - %s\n", change)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringToStringVarP(&logLevels, "logging", "l", config.DefaultLogLevels(), "Logging Levels for the different components, as given to the controller")
+	cmd.Flags().BoolVar(&singleNode, "single-node", false, "the controller runs with --single-node")
+	return cmd
+}
+
+// printArgChanges shows the flag and config changes of the components since they ran with another k0s version
+func printArgChanges(dir string) error {
+	components, err := status.ListComponentArgs(dir)
+	if err != nil {
+		return err
+	}
+	for _, args := range components {
+		if args.PreviousVersion == "" {
+			continue
+		}
+		if len(args.Changes) == 0 {
+			fmt.Printf("%s: no flag changes since k0s %s\n", args.Component, args.PreviousVersion)
+			continue
+		}
+		fmt.Printf("%s: flag changes since k0s %s:\n", args.Component, args.PreviousVersion)
+		for _, change := range args.Changes {
+			fmt.Printf("  - %s\n", change)
+		}
+	}
+	return nil
+}
+
+func rollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore the previous k0s version and the controller state if the upgrade failed its health checks",
+		Long: `Restores the k0s binary and the backup kept by "k0s upgrade prepare". k0s must be stopped. The rollback
+is refused unless the upgraded controller failed its health checks, or --force is given. The backup isn't
+restored on a controller whose etcd has other members, --binary-only restores only the k0s binary there.
+Start k0s again once rolled back.`,
+		Example: `k0s stop
+k0s upgrade rollback
+k0s start`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if os.Geteuid() != 0 {
+				return fmt.Errorf("this command must be run as root")
+			}
+			if k0sStatus, _ := install.GetPid(); k0sStatus.Pid != 0 {
+				return fmt.Errorf("k0s seems to be running, k0s must be down during the rollback")
+			}
+			if configOut == "" {
+				configOut = defaultConfigOut(c.CfgFile)
+			}
+			if err := upgrade.Rollback(c.K0sVars, configOut, force, binaryOnly); err != nil {
+				return err
+			}
+			fmt.Println("rolled back, start k0s again")
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "roll back even if the upgrade didn't fail its health checks")
+	cmd.Flags().BoolVar(&binaryOnly, "binary-only", false, "restore only the k0s binary and keep the controller state")
+	cmd.Flags().StringVar(&configOut, "config-out", "", "path the k0s.yaml of the backup is restored to (default: the --config file, or k0s.yaml in the current directory)")
+	return cmd
+}
+
+// defaultConfigOut restores the config over the config file in use, unless the config is merged from many files
+func defaultConfigOut(cfgFile string) string {
+	if util.FileExists(cfgFile) && !util.IsDirectory(cfgFile) && !strings.Contains(cfgFile, ",") {
+		return cfgFile
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cwd, "k0s.yaml")
+}
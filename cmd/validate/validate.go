@@ -26,8 +26,9 @@ type CmdOpts config.CLIOptions
 
 func NewValidateCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "validate",
-		Short: "Helper command for validating the config file",
+		Use:        "validate",
+		Short:      "Helper command for validating the config file",
+		Deprecated: "use 'k0s config validate' instead",
 	}
 	cmd.AddCommand(validateConfigCmd())
 	cmd.SilenceUsage = true
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vcluster
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/vcluster"
+)
+
+type CmdOpts config.CLIOptions
+
+var createOpts vcluster.Options
+
+func NewVClusterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vcluster",
+		Short: "Manage virtual clusters running as pods on this cluster",
+		Long: `Manage lightweight nested control planes for multi-tenant environments. Each virtual cluster
+runs a kine backed k0s control plane as a pod in its own namespace of the host cluster.
+The commands must be run on a controller node of the host cluster.`,
+	}
+	cmd.AddCommand(vclusterCreateCmd())
+	cmd.AddCommand(vclusterDeleteCmd())
+	cmd.AddCommand(vclusterListCmd())
+	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
+	cmd.SilenceUsage = true
+	return cmd
+}
+
+func vclusterCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a virtual cluster",
+		Example: `k0s vcluster create team-a
+kubectl -n vcluster-team-a exec vcluster-0 -- k0s kubeconfig admin > team-a.conf`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if err := c.checkController(); err != nil {
+				return err
+			}
+			opts := createOpts
+			opts.Name = args[0]
+			if err := vcluster.Create(c.K0sVars, opts); err != nil {
+				return err
+			}
+			fmt.Printf("virtual cluster %s created in namespace %s\n", opts.Name, vcluster.Namespace(opts.Name))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&createOpts.Image, "image", vcluster.DefaultImage(), "k0s image used for the virtual cluster control plane")
+	cmd.Flags().StringVar(&createOpts.StorageSize, "storage-size", "5Gi", "size of the persistent volume for the virtual cluster data")
+	cmd.Flags().StringVar(&createOpts.ServiceType, "service-type", "ClusterIP", "type of the service exposing the virtual cluster API (ClusterIP, NodePort or LoadBalancer)")
+	return cmd
+}
+
+func vclusterDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete [name]",
+		Short: "Delete a virtual cluster and all of its data",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if err := c.checkController(); err != nil {
+				return err
+			}
+			return vcluster.Delete(c.K0sVars, args[0])
+		},
+	}
+}
+
+func vclusterListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the virtual clusters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			if err := c.checkController(); err != nil {
+				return err
+			}
+			names, err := vcluster.List(c.K0sVars)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	}
+}
+
+func (c *CmdOpts) checkController() error {
+	if !util.DirExists(c.K0sVars.ManifestsDir) {
+		return fmt.Errorf("manifests dir %s not found, the command must be run on a controller node", c.K0sVars.ManifestsDir)
+	}
+	return nil
+}
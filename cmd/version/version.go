@@ -27,6 +27,7 @@ func NewVersionCmd() *cobra.Command {
 				Kine:         build.KineVersion,
 				Etcd:         build.EtcdVersion,
 				Konnectivity: build.KonnectivityVersion,
+				Crictl:       build.CrictlVersion,
 			}
 
 			info.String()
@@ -47,6 +48,7 @@ type versionInfo struct {
 	Kine         string `json:"kine,omitempty"`
 	Etcd         string `json:"etcd,omitempty"`
 	Konnectivity string `json:"konnectivity,omitempty"`
+	Crictl       string `json:"crictl,omitempty"`
 }
 
 func (v versionInfo) String() {
@@ -58,6 +60,7 @@ func (v versionInfo) String() {
 		fmt.Println("kine :", v.Kine)
 		fmt.Println("etcd :", v.Etcd)
 		fmt.Println("konnectivity :", v.Konnectivity)
+		fmt.Println("crictl :", v.Crictl)
 	} else if isJsn {
 		jsn, _ := json.MarshalIndent(v, "", "   ")
 		fmt.Println(string(jsn))
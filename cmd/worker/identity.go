@@ -0,0 +1,92 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/component/worker"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/install"
+)
+
+var (
+	identityOutput string
+	identityForce  bool
+)
+
+func newExportIdentityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-identity",
+		Short: "Export the kubelet identity of the node, to move it to replacement hardware",
+		Long: `Export the kubelet identity of the node: the kubelet kubeconfig and certificates and the node name.
+The archive can be imported with "k0s worker import-identity" on replacement hardware, so that the new node
+keeps the name and the node-bound resources of the replaced one, such as local persistent volumes.
+The archive contains the private keys of the node, keep it safe.`,
+		Example: `	$ k0s worker export-identity -o node-1.tar.gz`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			cmd.SilenceUsage = true
+
+			out, err := os.OpenFile(identityOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			if err := worker.ExportIdentity(c.K0sVars, out); err != nil {
+				return err
+			}
+			fmt.Printf("exported the node identity to %s\n", identityOutput)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&identityOutput, "output", "o", "k0s-worker-identity.tar.gz", "file to write the identity archive to")
+	return cmd
+}
+
+func newImportIdentityCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-identity [archive]",
+		Short: "Import the kubelet identity exported from a replaced node",
+		Long: `Import the kubelet identity exported with "k0s worker export-identity" from a replaced node.
+The worker then joins the cluster as the replaced node, no join token is needed. The replaced node must not run anymore.
+Give the same --data-dir and --kubelet-root-dir as used for running the worker.`,
+		Example: `	$ k0s worker import-identity node-1.tar.gz
+	$ k0s install worker`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := CmdOpts(config.GetCmdOpts())
+			cmd.SilenceUsage = true
+
+			if os.Geteuid() != 0 {
+				return fmt.Errorf("this command must be run as root")
+			}
+			if k0sStatus, _ := install.GetPid(); k0sStatus.Pid != 0 {
+				return fmt.Errorf("k0s seems to be running, please stop k0s before importing the identity")
+			}
+			if util.FileExists(c.K0sVars.KubeletAuthConfigPath) && !identityForce {
+				return fmt.Errorf("%s already exists, this node already has an identity. use --force to replace it", c.K0sVars.KubeletAuthConfigPath)
+			}
+			return worker.ImportIdentity(c.K0sVars, args[0], c.KubeletRootDir)
+		},
+	}
+	cmd.Flags().BoolVar(&identityForce, "force", false, "replace the existing identity of the node")
+	return cmd
+}
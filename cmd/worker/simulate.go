@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/component"
+	"github.com/k0sproject/k0s/pkg/component/worker"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/exitcode"
+)
+
+// simulate joins the simulated nodes with the join token, none of the worker components run on the host
+func (c *CmdOpts) simulate() error {
+	if c.TokenArg == "" && !util.FileExists(c.K0sVars.KubeletBootstrapConfigPath) {
+		return fmt.Errorf("the simulated nodes need a join token")
+	}
+	if err := util.InitDirectory(c.K0sVars.DataDir, constant.DataDirMode); err != nil {
+		return err
+	}
+	if c.TokenArg != "" && !util.FileExists(c.K0sVars.KubeletBootstrapConfigPath) {
+		if err := worker.CheckJoinAddressPreflight(c.TokenArg); err != nil {
+			return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+		}
+		if err := worker.HandleKubeletBootstrapToken(c.TokenArg, c.K0sVars); err != nil {
+			return err
+		}
+	}
+
+	componentManager := component.NewManager()
+	componentManager.Add(&worker.SimulatedNodes{
+		K0sVars:   c.K0sVars,
+		Count:     c.Simulate,
+		Labels:    c.Labels,
+		Ephemeral: c.Ephemeral,
+	})
+	if err := componentManager.Init(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(ch)
+
+	if err := componentManager.Start(ctx); err != nil {
+		return err
+	}
+	<-ch
+	logrus.Info("Shutting down the simulated nodes")
+	return componentManager.Stop()
+}
@@ -18,19 +18,34 @@ package worker
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/avast/retry-go"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/component"
+	"github.com/k0sproject/k0s/pkg/component/plugin"
 	"github.com/k0sproject/k0s/pkg/component/worker"
 	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/diskspace"
+	"github.com/k0sproject/k0s/pkg/exitcode"
+	"github.com/k0sproject/k0s/pkg/hostnetwork"
+	"github.com/k0sproject/k0s/pkg/platform"
+	"github.com/k0sproject/k0s/pkg/provisioning"
+	"github.com/k0sproject/k0s/pkg/status"
+	"github.com/k0sproject/k0s/pkg/supervisor"
+	"github.com/k0sproject/k0s/pkg/token"
+	"github.com/k0sproject/k0s/pkg/watchdog"
 )
 
 type CmdOpts config.CLIOptions
@@ -45,71 +60,240 @@ func NewWorkerCmd() *cobra.Command {
 
 	or CLI flag:
 	$ k0s worker --token-file [path_to_file]
+	$ k0s worker --token-file https://example.com/k0s/token
+	$ k0s worker --token-file - < [path_to_file]
+
+	or the K0S_TOKEN environment variable:
+	$ K0S_TOKEN=[token] k0s worker
+
+	or from the instance metadata of the cloud provider:
+	$ k0s worker --token-from-metadata aws
 	Note: Token can be passed either as a CLI argument or as a flag`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			c := CmdOpts(config.GetCmdOpts())
 			if len(args) > 0 {
 				c.TokenArg = args[0]
+				logrus.Warnf("the join token given as an argument is visible in the process list, use --token-file - or the %s environment variable instead", token.EnvVar)
 			}
 
 			c.Logging = util.MapMerge(c.CmdLogLevels, c.DefaultLogLevels)
-			if len(c.TokenArg) > 0 && len(c.TokenFile) > 0 {
-				return fmt.Errorf("you can only pass one token argument either as a CLI argument 'k0s worker [token]' or as a flag 'k0s worker --token-file [path]'")
+			sources := 0
+			for _, source := range []string{c.TokenArg, c.TokenFile, c.TokenFromMeta} {
+				if source != "" {
+					sources++
+				}
+			}
+			if sources > 1 {
+				return exitcode.Wrap(exitcode.Usage, fmt.Errorf("you can only pass one token argument either as a CLI argument 'k0s worker [token]' or as a flag 'k0s worker --token-file [path]' or 'k0s worker --token-from-metadata [provider]'"))
+			}
+
+			for _, taint := range c.Taints {
+				if _, err := v1beta1.ParseNodeTaint(taint); err != nil {
+					return exitcode.Wrap(exitcode.Usage, err)
+				}
 			}
 
-			if len(c.TokenFile) > 0 {
-				bytes, err := ioutil.ReadFile(c.TokenFile)
-				if err != nil {
+			joined := util.FileExists(c.K0sVars.KubeletAuthConfigPath) || util.FileExists(c.K0sVars.KubeletBootstrapConfigPath)
+			if err := c.loadToken(cmd.Context(), joined); err != nil {
+				return err
+			}
+			if c.ProvisioningPath != "" && c.TokenArg == "" && !joined {
+				if err := c.provision(); err != nil {
 					return err
 				}
-				c.TokenArg = string(bytes)
 			}
 			cmd.SilenceUsage = true
+			if c.Simulate > 0 {
+				return c.simulate()
+			}
 			return c.StartWorker()
 		},
 	}
 
+	cmd.AddCommand(newExportIdentityCmd())
+	cmd.AddCommand(newImportIdentityCmd())
+
 	// append flags
 	cmd.PersistentFlags().AddFlagSet(config.GetPersistentFlagSet())
 	cmd.PersistentFlags().AddFlagSet(config.GetWorkerFlags())
 	return cmd
 }
 
+// loadToken reads the token of --token-file, or fetches it from the URL or the instance metadata. The remote token is
+// fetched only until the worker has joined, the bootstrap kubeconfig keeps it over restarts. Without any of them the
+// token of K0S_TOKEN is used.
+func (c *CmdOpts) loadToken(ctx context.Context, joined bool) error {
+	if envToken := token.FromEnv(); c.TokenArg == "" && c.TokenFile == "" && c.TokenFromMeta == "" {
+		c.TokenArg = envToken
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var err error
+	switch {
+	case c.TokenFromMeta != "":
+		if !util.StringSliceContains(token.MetadataProviders, c.TokenFromMeta) {
+			return exitcode.Wrap(exitcode.Usage, fmt.Errorf("unknown metadata provider %q, must be one of %s", c.TokenFromMeta, strings.Join(token.MetadataProviders, ", ")))
+		}
+		if !joined {
+			c.TokenArg, err = token.FetchFromMetadata(ctx, c.TokenFromMeta)
+		}
+	case token.IsURL(c.TokenFile):
+		if !joined {
+			c.TokenArg, err = token.FetchFromURL(ctx, c.TokenFile)
+		}
+	case c.TokenFile != "":
+		c.TokenArg, err = token.ReadFile(c.TokenFile)
+	}
+	return err
+}
+
+// provision waits for the join token in the provisioning path, the bootstrap kubeconfig keeps it over restarts
+func (c *CmdOpts) provision() error {
+	bundle, err := provisioning.Wait(c.ProvisioningPath, provisioning.DefaultInterval)
+	if err != nil {
+		return err
+	}
+	if bundle.Token == "" {
+		return fmt.Errorf("no join token found in the provisioning path %s", c.ProvisioningPath)
+	}
+	if err := worker.HandleKubeletBootstrapToken(bundle.Token, c.K0sVars); err != nil {
+		return err
+	}
+	return bundle.Consume()
+}
+
 // StartWorker starts the worker components based on the CmdOpts config
 func (c *CmdOpts) StartWorker() error {
+	if err := worker.CheckNonRootPreflight(c.RunAsUser); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+	if err := platform.Preflight(platform.RoleWorker); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
 
 	worker.KernelSetup()
 	if c.TokenArg == "" && !util.FileExists(c.K0sVars.KubeletAuthConfigPath) {
-		return fmt.Errorf("normal kubelet kubeconfig does not exist and no join-token given. dunno how to make kubelet auth to api")
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("normal kubelet kubeconfig does not exist and no join-token given. dunno how to make kubelet auth to api"))
 	}
 
 	// Dump join token into kubelet-bootstrap kubeconfig if it does not already exist
 	if c.TokenArg != "" && !util.FileExists(c.K0sVars.KubeletBootstrapConfigPath) {
+		if err := worker.CheckJoinAddressPreflight(c.TokenArg); err != nil {
+			return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+		}
 		if err := worker.HandleKubeletBootstrapToken(c.TokenArg, c.K0sVars); err != nil {
 			return err
 		}
 	}
+	// the bootstrap kubeconfig holds the token from now on, only the windows bootstrap needs it later on
+	if runtime.GOOS != "windows" {
+		c.TokenArg = ""
+	}
 
 	kubeletConfigClient, err := worker.LoadKubeletConfigClient(c.K0sVars)
 	if err != nil {
 		return err
 	}
 
+	if err := util.InitDirectory(c.K0sVars.DataDir, constant.DataDirMode); err != nil {
+		return err
+	}
+	if err := diskspace.Preflight(c.K0sVars.DataDir, diskspace.DefaultThresholds); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+	history := status.NewHistory(c.K0sVars.StatusHistoryPath, status.DefaultHistoryRetention)
+	history.Record("worker", status.EventStarted, "")
+	defer history.Record("worker", status.EventStopped, "")
+
 	componentManager := component.NewManager()
+	componentManager.History = history
+	diskMonitor := &diskspace.Monitor{Path: c.K0sVars.DataDir, History: history}
+	componentManager.Add(diskMonitor)
+	// the controller runs the watchdog of the process when the worker is enabled on it
+	if !c.EnableWorker {
+		processWatchdog := &watchdog.Watchdog{
+			DumpDir:       c.K0sVars.LogDir,
+			MaxGoroutines: c.WatchdogMaxGoroutines,
+		}
+		if c.WatchdogRestart {
+			processWatchdog.Restart = componentManager.Restart
+		}
+		componentManager.Add(processWatchdog)
+		if c.CollectCoreDumps {
+			if err := supervisor.EnableCoreDumps(); err != nil {
+				logrus.Warnf("the crashes are kept without core dumps: %v", err)
+			}
+		}
+	}
 	if runtime.GOOS == "windows" && c.CriSocket == "" {
 		return fmt.Errorf("windows worker needs to have external CRI")
 	}
+	pauseImage := ""
 	if c.CriSocket == "" {
+		pauseImage = c.pauseImage(kubeletConfigClient)
+		cgroupDriver, err := c.cgroupDriver(kubeletConfigClient)
+		if err != nil {
+			return err
+		}
 		componentManager.Add(&worker.ContainerD{
-			LogLevel: c.Logging["containerd"],
-			K0sVars:  c.K0sVars,
+			LogLevel:     c.Logging["containerd"],
+			K0sVars:      c.K0sVars,
+			CNIConfDir:   c.CNIConfDir,
+			CNIBinDir:    c.CNIBinDir,
+			SandboxImage: pauseImage,
+			Registries:   c.registries(kubeletConfigClient),
+			Snapshotter:  c.Snapshotter,
+			CgroupDriver: cgroupDriver,
 		})
+		componentManager.Add(worker.NewOCIBundleReconciler(c.K0sVars, filepath.Join(c.K0sVars.RunDir, "containerd.sock"), pauseImage, diskMonitor))
+	}
+	// a containerd of the host is used as is, the bundles are imported into the namespace of the k0s workloads
+	sharedContainerd, err := worker.DetectSharedContainerd(c.CriSocket)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+	if sharedContainerd != nil {
+		pauseImage = sharedContainerd.SandboxImage
+		componentManager.Add(worker.NewOCIBundleReconciler(c.K0sVars, sharedContainerd.Address, pauseImage, diskMonitor))
 	}
-
-	componentManager.Add(worker.NewOCIBundleReconciler(c.K0sVars))
 	if c.WorkerProfile == "default" && runtime.GOOS == "windows" {
 		c.WorkerProfile = "default-windows"
 	}
+	if err := c.hostNetworkPreflight(kubeletConfigClient); err != nil {
+		return exitcode.Wrap(exitcode.Preflight, fmt.Errorf("preflight check failed: %w", err))
+	}
+
+	componentManager.Add(&worker.CertRotation{
+		K0sVars:             c.K0sVars,
+		KubeletConfigClient: kubeletConfigClient,
+		Profile:             c.WorkerProfile,
+		Restart:             componentManager.Restart,
+	})
+
+	componentManager.Add(&worker.ConnectionBroker{
+		K0sVars:             c.K0sVars,
+		KubeletConfigClient: kubeletConfigClient,
+		Labels:              c.Labels,
+	})
+
+	// the controller syncs the hosts file itself when the worker is enabled on it
+	if !c.EnableWorker && runtime.GOOS != "windows" {
+		componentManager.Add(&worker.HostsSync{K0sVars: c.K0sVars})
+	}
+
+	// the controller opens the ports of its worker itself
+	if !c.EnableWorker && runtime.GOOS == "linux" {
+		componentManager.Add(&worker.Firewall{
+			KubeletConfigClient: kubeletConfigClient,
+			Profile:             c.WorkerProfile,
+		})
+	}
+
+	// stopped after the kubelet and before the connection broker
+	if c.Ephemeral {
+		componentManager.Add(&worker.EphemeralNode{K0sVars: c.K0sVars})
+	}
 
 	componentManager.Add(&worker.Kubelet{
 		CRISocket:           c.CriSocket,
@@ -119,7 +303,19 @@ func (c *CmdOpts) StartWorker() error {
 		LogLevel:            c.Logging["kubelet"],
 		Profile:             c.WorkerProfile,
 		Labels:              c.Labels,
+		Taints:              c.Taints,
 		ExtraArgs:           c.KubeletExtraArgs,
+		RootDir:             c.KubeletRootDir,
+		BindMountRootDir:    c.KubeletBindMount,
+		PauseImage:          pauseImage,
+	})
+
+	// stopped before the kubelet, the node is cordoned on shutdown
+	componentManager.Add(&worker.ReadinessGate{
+		CRISocket:           c.CriSocket,
+		K0sVars:             c.K0sVars,
+		KubeletConfigClient: kubeletConfigClient,
+		Profile:             c.WorkerProfile,
 	})
 
 	if runtime.GOOS == "windows" {
@@ -139,6 +335,19 @@ func (c *CmdOpts) StartWorker() error {
 		})
 	}
 
+	// the node agents of third parties compiled into k0s, see pkg/component/plugin
+	agents, err := plugin.Components(plugin.Options{
+		K0sVars: c.K0sVars,
+		Profile: c.WorkerProfile,
+		Labels:  c.Labels,
+	}, c.DisablePlugins)
+	if err != nil {
+		return err
+	}
+	for _, agent := range agents {
+		componentManager.Add(agent)
+	}
+
 	// extract needed components
 	if err := componentManager.Init(); err != nil {
 		return err
@@ -181,3 +390,60 @@ func (c *CmdOpts) StartWorker() error {
 	}
 	return nil
 }
+
+// pauseImage returns the sandbox image published by the controllers for the profile. The default image is used if
+// it can't be fetched, or the controllers don't publish it yet.
+func (c *CmdOpts) pauseImage(client *worker.KubeletConfigClient) string {
+	image, err := client.PauseImage(c.WorkerProfile)
+	if err != nil {
+		logrus.Warnf("using the default pause image: %v", err)
+	}
+	if image == "" {
+		image = constant.KubePauseContainerImage + ":" + constant.KubePauseContainerImageVersion
+	}
+	return image
+}
+
+// registries returns the registry settings published by the controllers for the profile, containerd runs with its
+// defaults if they can't be fetched
+func (c *CmdOpts) registries(client *worker.KubeletConfigClient) *v1beta1.RegistriesSpec {
+	registries, err := client.Registries(c.WorkerProfile)
+	if err != nil {
+		logrus.Warnf("using the default registry settings: %v", err)
+	}
+	return registries
+}
+
+// cgroupDriver returns the cgroup driver of the kubelet config of the profile. It's retried like the kubelet fetches
+// its config, containerd running with another cgroup driver than the kubelet breaks the pods.
+func (c *CmdOpts) cgroupDriver(client *worker.KubeletConfigClient) (string, error) {
+	var driver string
+	err := retry.Do(func() error {
+		var err error
+		driver, err = client.CgroupDriver(c.WorkerProfile)
+		if err != nil {
+			logrus.Warnf("failed to get the cgroup driver of the kubelet config: %v", err)
+		}
+		return err
+	},
+		retry.Delay(time.Millisecond*500),
+		retry.DelayType(retry.BackOffDelay))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the cgroup driver of the kubelet config: %w", err)
+	}
+	return driver, nil
+}
+
+// hostNetworkPreflight checks the cluster CIDRs published by the controllers against the host networks. The check is
+// skipped if the CIDRs can't be fetched, the kubelet waits for the API anyway.
+func (c *CmdOpts) hostNetworkPreflight(client *worker.KubeletConfigClient) error {
+	cidrs, err := client.NetworkCIDRs(c.WorkerProfile)
+	if err != nil {
+		logrus.Warnf("skipping the network overlap check: %v", err)
+		return nil
+	}
+	if cidrs == nil {
+		return nil
+	}
+	return hostnetwork.Preflight(cidrs.PodCIDRs, cidrs.ServiceCIDRs, c.IgnoreNetOverlap)
+}
@@ -44,7 +44,7 @@ type fileInfo struct {
 	Offset, Size int64
 }
 
-func compressFiles(prefix string) []fileInfo {
+func compressFiles(prefix string, level int, only map[string]bool) []fileInfo {
 	var tmpFiles []fileInfo
 
 	// compress the files
@@ -55,6 +55,10 @@ func compressFiles(prefix string) []fileInfo {
 			log.Fatal(err)
 		}
 		for _, f := range files {
+			if len(only) > 0 && !only[f.Name()] {
+				fmt.Fprintf(os.Stderr, "%s: skipped\n", f.Name())
+				continue
+			}
 			tmpf, err := ioutil.TempFile("", f.Name())
 			if err != nil {
 				log.Fatal(err)
@@ -68,7 +72,7 @@ func compressFiles(prefix string) []fileInfo {
 				TempFile: tmpf.Name(),
 			})
 
-			gz, err := gzip.NewWriterLevel(tmpf, gzip.BestCompression)
+			gz, err := gzip.NewWriterLevel(tmpf, level)
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -102,7 +106,8 @@ func compressFiles(prefix string) []fileInfo {
 }
 
 func main() {
-	var prefix, pkg, outfile, gofile string
+	var prefix, pkg, outfile, gofile, onlyFiles string
+	var level int
 
 	var bindata []fileInfo
 
@@ -110,6 +115,8 @@ func main() {
 	flag.StringVar(&pkg, "pkg", "main", "Package name to use in the generated code.")
 	flag.StringVar(&outfile, "o", "./bindata", "Optional name of the output file to be generated.")
 	flag.StringVar(&gofile, "gofile", "./bindata.go", "Optional name of the go file to be generated.")
+	flag.IntVar(&level, "level", gzip.BestCompression, "Optional gzip compression level of the embedded files.")
+	flag.StringVar(&onlyFiles, "only", "", "Optional space or comma separated list of the file names to embed, all files are embedded when empty.")
 	flag.Parse()
 
 	if flag.NArg() == 0 {
@@ -117,7 +124,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	tmpFiles := compressFiles(prefix)
+	only := map[string]bool{}
+	for _, name := range strings.FieldsFunc(onlyFiles, func(r rune) bool { return r == ',' || r == ' ' }) {
+		only[name] = true
+	}
+
+	tmpFiles := compressFiles(prefix, level, only)
 
 	outf, err := os.Create(outfile)
 	if err != nil {
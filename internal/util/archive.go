@@ -42,7 +42,11 @@ func ExtractArchive(path, dst string) error {
 		return err
 	}
 	defer input.Close()
+	return ExtractArchiveFrom(input, dst)
+}
 
+// ExtractArchiveFrom extracts the tar.gz archive read from input to given dst path
+func ExtractArchiveFrom(input io.Reader, dst string) error {
 	gzr, err := gzip.NewReader(input)
 	if err != nil {
 		return err
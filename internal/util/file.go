@@ -86,6 +86,24 @@ func ChownFile(file, owner string, permissions os.FileMode) error {
 	return nil
 }
 
+// WriteFileAtomic writes the file through a temporary file renamed over it, so that the readers never see a partial
+// file
+func WriteFileAtomic(fileName string, data []byte, perm os.FileMode) error {
+	tmp := fileName + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, fileName); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // FileCopy copies file from src to dst
 func FileCopy(src, dst string) error {
 	sourceFileStat, err := os.Stat(src)
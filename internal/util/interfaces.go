@@ -32,9 +32,9 @@ func AllAddresses() ([]string, error) {
 	}
 
 	for _, a := range addrs {
-		// check the address type and skip if loopback
+		// check the address type and skip if loopback, the link-local IPv6 addresses can't be used as SANs
 		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
+			if ipnet.IP.To4() != nil || ipnet.IP.IsGlobalUnicast() {
 				addresses = append(addresses, ipnet.IP.String())
 			}
 		}
@@ -45,12 +45,14 @@ func AllAddresses() ([]string, error) {
 	return addresses, nil
 }
 
-// FirstPublicAddress return the first found non-local address that's not part of pod network
+// FirstPublicAddress return the first found non-local address that's not part of pod network. IPv4 addresses are
+// preferred, the first global IPv6 address is returned on IPv6-only hosts.
 func FirstPublicAddress() (string, error) {
 	ifs, err := net.Interfaces()
 	if err != nil {
 		return "127.0.0.1", fmt.Errorf("failed to list network interfaces: %w", err)
 	}
+	ipv6 := ""
 	for _, i := range ifs {
 		if i.Name == "vxlan.calico" {
 			// Skip calico interface
@@ -67,9 +69,16 @@ func FirstPublicAddress() (string, error) {
 				if ipnet.IP.To4() != nil && !ipnet.IP.IsLoopback() {
 					return ipnet.IP.String(), nil
 				}
+				if ipv6 == "" && ipnet.IP.IsGlobalUnicast() {
+					ipv6 = ipnet.IP.String()
+				}
 			}
 		}
 	}
+	if ipv6 != "" {
+		logrus.Infof("no IPv4 address found on host, using the IPv6 address %s as the public address", ipv6)
+		return ipv6, nil
+	}
 
 	logrus.Warn("failed to find any non-local, non podnetwork addresses on host, defaulting public address to 127.0.0.1")
 	return "127.0.0.1", nil
@@ -0,0 +1,59 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// a wednesday
+	now := time.Date(2021, 6, 16, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2021, 6, 16, 10, 45, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2021, 6, 16, 12, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, 6, 17, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 0", time.Date(2021, 6, 20, 2, 30, 0, 0, time.UTC)},
+		{"30 2 * * 7", time.Date(2021, 6, 20, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+		// the day of month or the day of week when both are restricted
+		{"0 0 1 * 5", time.Date(2021, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{"10,20 9-11 * * 1-5", time.Date(2021, 6, 16, 11, 10, 0, 0, time.UTC)},
+		{"@every 90m", now.Add(90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, s.Next(now))
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every 10s", "@often"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
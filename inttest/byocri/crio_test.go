@@ -0,0 +1,112 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package byocri
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/k0sproject/k0s/inttest/common"
+)
+
+const (
+	crioVersion = "1.21.1"
+	crioSocket  = "unix:///var/run/crio/crio.sock"
+)
+
+type CRIOSuite struct {
+	common.FootlooseSuite
+}
+
+func (s *CRIOSuite) TestK0sGetsUpAndResetsWithCRIO() {
+	s.NoError(s.InitController(0))
+	s.Require().NoError(s.runCRIOWorker())
+
+	kc, err := s.KubeClient(s.ControllerNode(0))
+	s.NoError(err)
+
+	err = s.WaitForNodeReady(s.WorkerNode(0), kc)
+	s.NoError(err)
+
+	s.T().Log("waiting to see CNI pods ready")
+	s.NoError(common.WaitForKubeRouterReady(kc), "CNI did not start")
+
+	sshWorker, err := s.SSH(s.WorkerNode(0))
+	s.Require().NoError(err)
+	defer sshWorker.Disconnect()
+
+	pods, err := sshWorker.ExecWithOutput(fmt.Sprintf("crictl --runtime-endpoint %s pods -q", crioSocket))
+	s.Require().NoError(err)
+	s.NotEmpty(strings.TrimSpace(pods), "expecting the kubelet to have created pods in CRI-O")
+
+	s.T().Log("resetting the worker")
+	_, err = sshWorker.ExecWithOutput("pkill k0s && while pidof k0s containerd kubelet; do sleep 0.1s; done")
+	s.Require().NoError(err)
+	_, err = sshWorker.ExecWithOutput(fmt.Sprintf("k0s reset --debug --cri-socket crio:%s", crioSocket))
+	s.NoError(err)
+
+	pods, err = sshWorker.ExecWithOutput(fmt.Sprintf("crictl --runtime-endpoint %s pods -q", crioSocket))
+	s.Require().NoError(err)
+	s.Empty(strings.TrimSpace(pods), "expecting k0s reset to remove the pods from CRI-O")
+}
+
+func (s *CRIOSuite) runCRIOWorker() error {
+	token, err := s.GetJoinToken("worker")
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return fmt.Errorf("got empty token for worker join")
+	}
+	sshWorker, err := s.SSH(s.WorkerNode(0))
+	if err != nil {
+		return err
+	}
+	defer sshWorker.Disconnect()
+
+	// the static CRI-O bundle ships crio, conmon, runc, crictl and the CNI plugins. There's no systemd in the
+	// footloose nodes, so CRI-O has to manage the cgroups with cgroupfs, as the kubelet does.
+	installCommand := fmt.Sprintf(`apk add make && \
+		curl -sSLf https://storage.googleapis.com/cri-o/artifacts/cri-o.amd64.v%s.tar.gz | tar -C /tmp -zx && \
+		make -C /tmp/cri-o install`, crioVersion)
+	if _, err = sshWorker.ExecWithOutput(installCommand); err != nil {
+		return err
+	}
+	crioCommand := `nohup crio --cgroup-manager=cgroupfs --conmon-cgroup=pod >/tmp/crio.log 2>&1 &`
+	if _, err = sshWorker.ExecWithOutput(crioCommand); err != nil {
+		return err
+	}
+	if _, err = sshWorker.ExecWithOutput("while ! test -S /var/run/crio/crio.sock; do sleep 0.1s; done"); err != nil {
+		return err
+	}
+
+	workerCommand := fmt.Sprintf(`nohup k0s --debug worker --cri-socket crio:%s "%s" >/tmp/k0s-worker.log 2>&1 &`, crioSocket, token)
+	_, err = sshWorker.ExecWithOutput(workerCommand)
+	return err
+}
+
+func TestCRIOSuite(t *testing.T) {
+	s := CRIOSuite{
+		common.FootlooseSuite{
+			ControllerCount: 1,
+			WorkerCount:     1,
+		},
+	}
+	suite.Run(t, &s)
+}
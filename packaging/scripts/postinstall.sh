@@ -0,0 +1,14 @@
+#!/bin/sh
+# postinstall script of the k0s deb and rpm packages
+set -e
+
+# a fresh package install doesn't configure anything, the admin runs
+# `k0s install <role> --package-mode` with the wanted flags. On upgrades the
+# recorded install is repeated and the running service restarted.
+if [ -f /etc/k0s/install-state.json ]; then
+	/usr/bin/k0s install --package-mode
+	if command -v systemctl >/dev/null 2>&1; then
+		systemctl daemon-reload
+		systemctl try-restart k0scontroller.service k0sworker.service || true
+	fi
+fi
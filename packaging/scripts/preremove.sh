@@ -0,0 +1,12 @@
+#!/bin/sh
+# preremove script of the k0s deb and rpm packages
+set -e
+
+# deb passes "remove" or "upgrade", rpm the number of the package versions left
+case "$1" in
+	remove|0)
+		if command -v systemctl >/dev/null 2>&1; then
+			systemctl stop k0scontroller.service k0sworker.service || true
+		fi
+		;;
+esac
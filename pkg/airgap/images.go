@@ -4,14 +4,9 @@ import (
 	"runtime"
 
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
-	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/platform"
 )
 
-var pauseImage = v1beta1.ImageSpec{
-	Image:   constant.KubePauseContainerImage,
-	Version: constant.KubePauseContainerImageVersion,
-}
-
 // GetImageURIs returns all image tags
 func GetImageURIs(spec *v1beta1.ClusterImages) []string {
 	images := []string{
@@ -19,16 +14,50 @@ func GetImageURIs(spec *v1beta1.ClusterImages) []string {
 		spec.CoreDNS.URI(),
 		spec.KubeProxy.URI(),
 		spec.MetricsServer.URI(),
-		pauseImage.URI(),
+		spec.Pause.URI(),
 		spec.KubeRouter.CNI.URI(),
 		spec.KubeRouter.CNIInstaller.URI(),
 	}
-	// Calico images do not currently support armv7, thus we need to exclude them from the list if we're running this on arm
-	if runtime.GOARCH != "arm" {
-		images = append(images,
-			spec.Calico.CNI.URI(),
-			spec.Calico.KubeControllers.URI(),
-			spec.Calico.Node.URI())
+	// Calico images are not published for all the architectures, thus we need to exclude them from the list on those
+	if platform.CalicoSupported(runtime.GOARCH) {
+		images = append(images, calicoImageURIs(spec)...)
 	}
 	return images
 }
+
+// GetConfiguredImageURIs returns the image tags the cluster runs with the given spec: the images of the network
+// provider in use only, and none of the disabled components. As in GetImageURIs, the Calico images are left out on the
+// architectures they aren't published for.
+func GetConfiguredImageURIs(spec *v1beta1.ClusterSpec) []string {
+	var images []string
+	if !spec.Components.IsDisabled(v1beta1.KonnectivityServerComponent) {
+		images = append(images, spec.Images.Konnectivity.URI())
+	}
+	if !spec.Components.IsDisabled(v1beta1.CoreDNSComponent) {
+		images = append(images, spec.Images.CoreDNS.URI())
+	}
+	if !spec.Components.IsDisabled(v1beta1.MetricsServerComponent) {
+		images = append(images, spec.Images.MetricsServer.URI())
+	}
+	images = append(images, spec.Images.Pause.URI())
+	if spec.Network.KubeProxy == nil || !spec.Network.KubeProxy.Disabled {
+		images = append(images, spec.Images.KubeProxy.URI())
+	}
+	switch spec.Network.Provider {
+	case "kuberouter":
+		images = append(images, spec.Images.KubeRouter.CNI.URI(), spec.Images.KubeRouter.CNIInstaller.URI())
+	case "calico":
+		if platform.CalicoSupported(runtime.GOARCH) {
+			images = append(images, calicoImageURIs(spec.Images)...)
+		}
+	}
+	return images
+}
+
+func calicoImageURIs(spec *v1beta1.ClusterImages) []string {
+	return []string{
+		spec.Calico.CNI.URI(),
+		spec.Calico.KubeControllers.URI(),
+		spec.Calico.Node.URI(),
+	}
+}
@@ -0,0 +1,40 @@
+package airgap
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/platform"
+)
+
+func TestGetConfiguredImageURIs(t *testing.T) {
+	spec := v1beta1.DefaultClusterSpec(constant.GetConfig(""))
+	images := GetConfiguredImageURIs(spec)
+	assert.Contains(t, images, spec.Images.Pause.URI())
+	assert.Contains(t, images, spec.Images.KubeProxy.URI())
+	assert.Contains(t, images, spec.Images.KubeRouter.CNI.URI())
+	assert.NotContains(t, images, spec.Images.Calico.Node.URI())
+
+	spec.Network.Provider = "calico"
+	spec.Network.KubeProxy.Disabled = true
+	images = GetConfiguredImageURIs(spec)
+	if platform.CalicoSupported(runtime.GOARCH) {
+		assert.Contains(t, images, spec.Images.Calico.Node.URI())
+	} else {
+		assert.NotContains(t, images, spec.Images.Calico.Node.URI())
+	}
+	assert.NotContains(t, images, spec.Images.KubeRouter.CNI.URI())
+	assert.NotContains(t, images, spec.Images.KubeProxy.URI())
+
+	spec.Network.Provider = "custom"
+	spec.Components.Disabled = []string{v1beta1.MetricsServerComponent}
+	images = GetConfiguredImageURIs(spec)
+	assert.NotContains(t, images, spec.Images.Calico.Node.URI())
+	assert.NotContains(t, images, spec.Images.KubeRouter.CNI.URI())
+	assert.NotContains(t, images, spec.Images.MetricsServer.URI())
+	assert.Contains(t, images, spec.Images.CoreDNS.URI())
+}
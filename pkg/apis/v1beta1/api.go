@@ -25,14 +25,20 @@ import (
 
 var _ Validateable = (*APISpec)(nil)
 
+// PublicAudienceLabel marks the nodes reaching the control plane through the public address, its value is "public"
+const PublicAudienceLabel = "k0sproject.io/api-audience"
+
 // APISpec ...
 type APISpec struct {
 	Address         string            `yaml:"address"`
 	Port            int               `yaml:"port"`
 	K0sAPIPort      int               `yaml:"k0sApiPort,omitempty"`
+	HealthPort      int               `yaml:"healthPort,omitempty"`
 	ExternalAddress string            `yaml:"externalAddress,omitempty"`
+	PublicAddress   string            `yaml:"publicAddress,omitempty"`
 	SANs            []string          `yaml:"sans"`
 	ExtraArgs       map[string]string `yaml:"extraArgs,omitempty"`
+	WatchCache      *WatchCacheSpec   `yaml:"watchCache,omitempty"`
 }
 
 // DefaultAPISpec default settings for api
@@ -59,7 +65,21 @@ func (a *APISpec) APIAddress() string {
 
 // APIAddressURL returns kube-apiserver external URI
 func (a *APISpec) APIAddressURL() string {
-	return a.getExternalURIForPort(a.Port)
+	return uriForPort(a.APIAddress(), a.Port)
+}
+
+// PublicAPIAddress returns the address of the control plane for the clients outside of the cluster network, which
+// is the cluster address unless a public address is given
+func (a *APISpec) PublicAPIAddress() string {
+	if a.PublicAddress != "" {
+		return a.PublicAddress
+	}
+	return a.APIAddress()
+}
+
+// PublicAPIAddressURL returns kube-apiserver URI for the clients outside of the cluster network
+func (a *APISpec) PublicAPIAddressURL() string {
+	return uriForPort(a.PublicAPIAddress(), a.Port)
 }
 
 // IsIPv6String returns if ip is IPv6.
@@ -70,21 +90,23 @@ func IsIPv6String(ip string) bool {
 
 // K0sControlPlaneAPIAddress returns the controller join APIs address
 func (a *APISpec) K0sControlPlaneAPIAddress() string {
-	return a.getExternalURIForPort(a.K0sAPIPort)
+	return uriForPort(a.APIAddress(), a.K0sAPIPort)
 }
 
-func (a *APISpec) getExternalURIForPort(port int) string {
-	addr := a.Address
-	if a.ExternalAddress != "" {
-		addr = a.ExternalAddress
-	}
+// PublicK0sControlPlaneAPIAddress returns the controller join APIs address for the clients outside of the cluster
+// network
+func (a *APISpec) PublicK0sControlPlaneAPIAddress() string {
+	return uriForPort(a.PublicAPIAddress(), a.K0sAPIPort)
+}
+
+func uriForPort(addr string, port int) string {
 	if IsIPv6String(addr) {
 		return fmt.Sprintf("https://[%s]:%d", addr, port)
 	}
 	return fmt.Sprintf("https://%s:%d", addr, port)
 }
 
-// Sans return the given SANS plus all local adresses and externalAddress if given
+// Sans return the given SANS plus all local adresses, externalAddress and publicAddress if given
 func (a *APISpec) Sans() []string {
 	sans, _ := util.AllAddresses()
 	sans = append(sans, a.Address)
@@ -92,6 +114,9 @@ func (a *APISpec) Sans() []string {
 	if a.ExternalAddress != "" {
 		sans = append(sans, a.ExternalAddress)
 	}
+	if a.PublicAddress != "" {
+		sans = append(sans, a.PublicAddress)
+	}
 
 	return util.Unique(sans)
 }
@@ -114,5 +139,15 @@ func (a *APISpec) Validate() []error {
 		errors = append(errors, fmt.Errorf("spec.api.address: %q is not IP address", a.Address))
 	}
 
+	if a.HealthPort < 0 || a.HealthPort > 65535 {
+		errors = append(errors, fmt.Errorf("spec.api.healthPort: %d is not a valid port", a.HealthPort))
+	} else if a.HealthPort != 0 && (a.HealthPort == a.Port || a.HealthPort == a.K0sAPIPort) {
+		errors = append(errors, fmt.Errorf("spec.api.healthPort: %d is already used by the API", a.HealthPort))
+	}
+
+	if a.WatchCache != nil {
+		errors = append(errors, a.WatchCache.Validate()...)
+	}
+
 	return errors
 }
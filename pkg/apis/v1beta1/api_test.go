@@ -18,6 +18,7 @@ package v1beta1
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -67,8 +68,52 @@ func (s *APISuite) TestValidation() {
 	})
 }
 
+func TestPublicAddress(t *testing.T) {
+	a := APISpec{Address: "10.0.0.1", Port: 6443, K0sAPIPort: 9443, ExternalAddress: "lb.internal"}
+	assert.Equal(t, "https://lb.internal:6443", a.PublicAPIAddressURL())
+	assert.Equal(t, "https://lb.internal:9443", a.PublicK0sControlPlaneAPIAddress())
+
+	a.PublicAddress = "2001:db8::1"
+	assert.Equal(t, "https://lb.internal:6443", a.APIAddressURL())
+	assert.Equal(t, "https://[2001:db8::1]:6443", a.PublicAPIAddressURL())
+	assert.Equal(t, "https://[2001:db8::1]:9443", a.PublicK0sControlPlaneAPIAddress())
+	assert.Contains(t, a.Sans(), "lb.internal")
+	assert.Contains(t, a.Sans(), "2001:db8::1")
+}
+
 func TestApiSuite(t *testing.T) {
 	apiSuite := &APISuite{}
 
 	suite.Run(t, apiSuite)
 }
+
+func TestWatchCacheArgs(t *testing.T) {
+	var w *WatchCacheSpec
+	assert.Empty(t, w.APIServerArgs(10))
+
+	args := w.APIServerArgs(400)
+	assert.Equal(t, "2000", args["default-watch-cache-size"])
+	assert.Equal(t, "nodes#2000,pods#20000", args["watch-cache-sizes"])
+
+	w = &WatchCacheSpec{DefaultSize: 500, Sizes: map[string]int{"pods": 1000, "deployments.apps": 200}}
+	assert.Empty(t, w.Validate())
+	args = w.APIServerArgs(400)
+	assert.Equal(t, "500", args["default-watch-cache-size"])
+	assert.Equal(t, "deployments.apps#200,nodes#2000,pods#1000", args["watch-cache-sizes"])
+
+	w = &WatchCacheSpec{DefaultSize: -1, Sizes: map[string]int{"Pods#": 10}}
+	assert.Len(t, w.Validate(), 2)
+}
+
+func TestHealthPortValidation(t *testing.T) {
+	a := DefaultAPISpec()
+	a.Address = "1.2.3.4"
+	a.HealthPort = 9444
+	assert.Empty(t, a.Validate())
+
+	a.HealthPort = a.K0sAPIPort
+	assert.Len(t, a.Validate(), 1)
+
+	a.HealthPort = 70000
+	assert.Len(t, a.Validate(), 1)
+}
@@ -20,6 +20,9 @@ import (
 	"github.com/k0sproject/k0s/internal/util"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/k0sproject/k0s/pkg/constant"
 )
@@ -40,6 +43,8 @@ type ClusterMeta struct {
 
 // ClusterSpec ...
 type ClusterSpec struct {
+	ClusterName       string                 `yaml:"clusterName,omitempty"`
+	ClusterLabels     map[string]string      `yaml:"clusterLabels,omitempty"`
 	API               *APISpec               `yaml:"api"`
 	ControllerManager *ControllerManagerSpec `yaml:"controllerManager,omitempty"`
 	Scheduler         *SchedulerSpec         `yaml:"scheduler,omitempty"`
@@ -52,6 +57,13 @@ type ClusterSpec struct {
 	Images            *ClusterImages         `yaml:"images"`
 	Extensions        *ClusterExtensions     `yaml:"extensions,omitempty"`
 	Konnectivity      *KonnectivitySpec      `yaml:"konnectivity,omitempty"`
+	ConnectionBroker  *ConnectionBrokerSpec  `yaml:"connectionBroker,omitempty"`
+	OIDCProvider      *OIDCProviderSpec      `yaml:"oidcProvider,omitempty"`
+	Profiling         *ProfilingSpec         `yaml:"profiling,omitempty"`
+	NodeGC            *NodeGCSpec            `yaml:"nodeGC,omitempty"`
+	RemoteWrite       *RemoteWriteSpec       `yaml:"remoteWrite,omitempty"`
+	LowPower          *LowPowerSpec          `yaml:"lowPower,omitempty"`
+	Components        *ComponentsSpec        `yaml:"components,omitempty"`
 }
 
 var _ Validateable = (*ControllerManagerSpec)(nil)
@@ -91,6 +103,8 @@ var _ Validateable = (*InstallSpec)(nil)
 // InstallSpec defines the required fields for the `k0s install` command
 type InstallSpec struct {
 	SystemUsers *SystemUser `yaml:"users,omitempty"`
+	// RunDir places the sockets and runtime state of k0s, as --run-dir does
+	RunDir string `yaml:"runDir,omitempty"`
 }
 
 // Validate stub for Validateable interface
@@ -118,10 +132,47 @@ func (c *ClusterConfig) Validate() []error {
 	errors = append(errors, validateSpecs(c.Spec.Install)...)
 	errors = append(errors, validateSpecs(c.Spec.Extensions)...)
 	errors = append(errors, validateSpecs(c.Spec.Konnectivity)...)
+	errors = append(errors, validateSpecs(c.Spec.ConnectionBroker)...)
+	errors = append(errors, validateSpecs(c.Spec.OIDCProvider)...)
+	errors = append(errors, validateSpecs(c.Spec.NodeGC)...)
+	errors = append(errors, validateSpecs(c.Spec.RemoteWrite)...)
+	errors = append(errors, validateSpecs(c.Spec.LowPower)...)
+	errors = append(errors, validateSpecs(c.Spec.Components)...)
+	errors = append(errors, validateSpecs(c.Spec.Images)...)
+	errors = append(errors, c.validateClusterMetadata()...)
+	errors = append(errors, c.validateSemantics()...)
 
 	return errors
 }
 
+// ClusterName returns spec.clusterName, falling back to the name in the metadata
+func (c *ClusterConfig) ClusterName() string {
+	if c.Spec.ClusterName != "" {
+		return c.Spec.ClusterName
+	}
+	if c.Metadata != nil {
+		return c.Metadata.Name
+	}
+	return ""
+}
+
+// validateClusterMetadata checks that the cluster name and labels can be used as node labels
+func (c *ClusterConfig) validateClusterMetadata() []error {
+	var errors []error
+	for _, msg := range validation.IsValidLabelValue(c.Spec.ClusterName) {
+		errors = append(errors, fmt.Errorf("spec.clusterName: %s", msg))
+	}
+	for key, value := range c.Spec.ClusterLabels {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errors = append(errors, fmt.Errorf("spec.clusterLabels: invalid key %q: %s", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errors = append(errors, fmt.Errorf("spec.clusterLabels: invalid value of %q: %s", key, msg))
+		}
+	}
+	return errors
+}
+
 // validateSpecs invokes validator Validate function
 func validateSpecs(v Validateable) []error {
 	return v.Validate()
@@ -133,6 +184,10 @@ func ConfigFromFile(filename string, k0sVars constant.CfgVars) (*ClusterConfig,
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file at %s: %w", filename, err)
 	}
+	buf, err = ExpandConfig(buf, filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
 
 	return configFromString(string(buf), k0sVars)
 }
@@ -143,6 +198,11 @@ func ConfigFromStdin(k0sVars constant.CfgVars) (*ClusterConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("can't read configration from stdin: %v", err)
 	}
+	// the includes of the config from stdin are relative to the working dir
+	input, err = ExpandConfig(input, ".")
+	if err != nil {
+		return nil, err
+	}
 	return configFromString(string(input), k0sVars)
 
 }
@@ -208,5 +268,12 @@ func DefaultClusterSpec(k0sVars constant.CfgVars) *ClusterSpec {
 		Images:            DefaultClusterImages(),
 		Telemetry:         DefaultClusterTelemetry(),
 		Konnectivity:      DefaultKonnectivitySpec(),
+		ConnectionBroker:  DefaultConnectionBrokerSpec(),
+		OIDCProvider:      DefaultOIDCProviderSpec(),
+		Profiling:         DefaultProfilingSpec(),
+		NodeGC:            DefaultNodeGCSpec(),
+		RemoteWrite:       DefaultRemoteWriteSpec(),
+		LowPower:          DefaultLowPowerSpec(),
+		Components:        DefaultComponentsSpec(),
 	}
 }
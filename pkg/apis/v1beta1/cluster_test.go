@@ -163,3 +163,43 @@ spec:
 	assert.Equal(t, "https://1.2.3.4:6443", c.Spec.API.APIAddressURL())
 	assert.Equal(t, "https://1.2.3.4:9443", c.Spec.API.K0sControlPlaneAPIAddress())
 }
+
+func TestClusterName(t *testing.T) {
+	c, err := configFromString("apiVersion: k0s.k0sproject.io/v1beta1", k0sVars)
+	assert.NoError(t, err)
+	assert.Equal(t, "k0s", c.ClusterName())
+
+	yamlData := `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: foobar
+spec:
+  clusterName: prod-eu
+  clusterLabels:
+    example.com/env: production
+`
+
+	c, err = configFromString(yamlData, k0sVars)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-eu", c.ClusterName())
+	assert.Equal(t, 0, len(c.Validate()))
+}
+
+func TestClusterNameValidation_Invalid(t *testing.T) {
+	yamlData := `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: foobar
+spec:
+  clusterName: "prod eu"
+  clusterLabels:
+    "example.com/env!": production
+`
+
+	c, err := configFromString(yamlData, k0sVars)
+	assert.NoError(t, err)
+	errors := c.Validate()
+	assert.Equal(t, 2, len(errors))
+}
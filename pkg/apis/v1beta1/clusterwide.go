@@ -0,0 +1,116 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+)
+
+// ClusterWideSpec is the part of the cluster config shared by all the controllers. With the dynamic config, it's kept
+// in the ClusterConfig resource in the API and the controllers reload it when it changes. The rest of the config,
+// such as the addresses and the storage, is local to the controller and read from the config file only.
+type ClusterWideSpec struct {
+	API               *ClusterWideAPISpec    `yaml:"api,omitempty"`
+	ControllerManager *ControllerManagerSpec `yaml:"controllerManager,omitempty"`
+	Scheduler         *SchedulerSpec         `yaml:"scheduler,omitempty"`
+	Network           *Network               `yaml:"network,omitempty"`
+	PodSecurityPolicy *PodSecurityPolicy     `yaml:"podSecurityPolicy,omitempty"`
+	WorkerProfiles    WorkerProfiles         `yaml:"workerProfiles,omitempty"`
+	Images            *ClusterImages         `yaml:"images,omitempty"`
+	Extensions        *ClusterExtensions     `yaml:"extensions,omitempty"`
+	Konnectivity      *KonnectivitySpec      `yaml:"konnectivity,omitempty"`
+}
+
+// ClusterWideAPISpec is the part of the API server config shared by all the controllers
+type ClusterWideAPISpec struct {
+	ExtraArgs  map[string]string `yaml:"extraArgs,omitempty"`
+	WatchCache *WatchCacheSpec   `yaml:"watchCache,omitempty"`
+}
+
+// ClusterWide returns the cluster-wide part of the spec
+func (s *ClusterSpec) ClusterWide() *ClusterWideSpec {
+	w := &ClusterWideSpec{
+		ControllerManager: s.ControllerManager,
+		Scheduler:         s.Scheduler,
+		Network:           s.Network,
+		PodSecurityPolicy: s.PodSecurityPolicy,
+		WorkerProfiles:    s.WorkerProfiles,
+		Images:            s.Images,
+		Extensions:        s.Extensions,
+		Konnectivity:      s.Konnectivity,
+	}
+	if s.API != nil {
+		w.API = &ClusterWideAPISpec{ExtraArgs: s.API.ExtraArgs, WatchCache: s.API.WatchCache}
+	}
+	return w
+}
+
+// ApplyClusterWide replaces the cluster-wide parts of the spec, the parts missing from w are kept. The sub-specs
+// are replaced rather than modified, so the specs sharing them with s are left alone.
+func (s *ClusterSpec) ApplyClusterWide(w *ClusterWideSpec) {
+	if w.API != nil {
+		api := APISpec{}
+		if s.API != nil {
+			api = *s.API
+		}
+		api.ExtraArgs = w.API.ExtraArgs
+		api.WatchCache = w.API.WatchCache
+		s.API = &api
+	}
+	if w.ControllerManager != nil {
+		s.ControllerManager = w.ControllerManager
+	}
+	if w.Scheduler != nil {
+		s.Scheduler = w.Scheduler
+	}
+	if w.Network != nil {
+		s.Network = w.Network
+	}
+	if w.PodSecurityPolicy != nil {
+		s.PodSecurityPolicy = w.PodSecurityPolicy
+	}
+	if w.WorkerProfiles != nil {
+		s.WorkerProfiles = w.WorkerProfiles
+	}
+	if w.Images != nil {
+		s.Images = w.Images
+	}
+	if w.Extensions != nil {
+		s.Extensions = w.Extensions
+	}
+	if w.Konnectivity != nil {
+		s.Konnectivity = w.Konnectivity
+	}
+}
+
+// ValidateChange checks that the running cluster can take w over the current spec. The CIDRs, the NodePort range
+// and the network provider are only changed in the config file, the controllers check them on start.
+func (w *ClusterWideSpec) ValidateChange(current *ClusterSpec) []error {
+	if w.Network == nil || current.Network == nil {
+		return nil
+	}
+	var errors []error
+	if w.Network.Provider != current.Network.Provider {
+		errors = append(errors, fmt.Errorf("spec.network.provider: cannot change the network provider from %s to %s", current.Network.Provider, w.Network.Provider))
+	}
+	if w.Network.PodCIDR != current.Network.PodCIDR || w.Network.ServiceCIDR != current.Network.ServiceCIDR || w.Network.DualStack != current.Network.DualStack {
+		errors = append(errors, fmt.Errorf("spec.network: the CIDRs can only be changed in the config file"))
+	}
+	if w.Network.NodePortRange != current.Network.NodePortRange {
+		errors = append(errors, fmt.Errorf("spec.network.nodePortRange: the NodePort range can only be changed in the config file"))
+	}
+	return errors
+}
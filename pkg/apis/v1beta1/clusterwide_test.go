@@ -0,0 +1,65 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestApplyClusterWide(t *testing.T) {
+	current := DefaultClusterSpec(k0sVars)
+	current.API.Address = "10.0.0.1"
+
+	wide := &ClusterWideSpec{}
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+api:
+  extraArgs:
+    audit-log-maxage: "7"
+scheduler:
+  extraArgs:
+    v: "4"
+`), wide))
+
+	spec := *current
+	spec.ApplyClusterWide(wide)
+	// the node-local settings are kept and the current spec is left alone
+	assert.Equal(t, "10.0.0.1", spec.API.Address)
+	assert.Equal(t, map[string]string{"audit-log-maxage": "7"}, spec.API.ExtraArgs)
+	assert.Empty(t, current.API.ExtraArgs)
+	assert.Equal(t, map[string]string{"v": "4"}, spec.Scheduler.ExtraArgs)
+	// the parts missing from the cluster-wide spec are kept
+	assert.Equal(t, current.Network, spec.Network)
+	assert.Equal(t, current.Images, spec.Images)
+}
+
+func TestClusterWideValidateChange(t *testing.T) {
+	current := DefaultClusterSpec(k0sVars)
+
+	wide := current.ClusterWide()
+	assert.Empty(t, wide.ValidateChange(current))
+
+	network := *current.Network
+	network.KubeProxy = &KubeProxy{Mode: "ipvs"}
+	wide.Network = &network
+	assert.Empty(t, wide.ValidateChange(current))
+
+	network.Provider = "calico"
+	network.PodCIDR = "10.245.0.0/16"
+	assert.Len(t, wide.ValidateChange(current), 2)
+}
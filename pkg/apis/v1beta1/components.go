@@ -0,0 +1,86 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"strings"
+)
+
+var _ Validateable = (*ComponentsSpec)(nil)
+
+// The control plane components which can be disabled
+const (
+	KonnectivityServerComponent = "konnectivity-server"
+	MetricsServerComponent      = "metrics-server"
+	CoreDNSComponent            = "coredns"
+	HelmComponent               = "helm"
+	DefaultPSPComponent         = "default-psp"
+	CSRApproverComponent        = "csr-approver"
+	EndpointReconcilerComponent = "endpoint-reconciler"
+	ControlAPIComponent         = "control-api"
+)
+
+var disableableComponents = []string{
+	KonnectivityServerComponent,
+	MetricsServerComponent,
+	CoreDNSComponent,
+	HelmComponent,
+	DefaultPSPComponent,
+	CSRApproverComponent,
+	EndpointReconcilerComponent,
+	ControlAPIComponent,
+}
+
+// ComponentsSpec lists the control plane components k0s doesn't run, for the clusters that have no use for them or
+// bring their own
+type ComponentsSpec struct {
+	Disabled []string `yaml:"disabled,omitempty"`
+}
+
+// DefaultComponentsSpec creates the config running every component
+func DefaultComponentsSpec() *ComponentsSpec {
+	return &ComponentsSpec{}
+}
+
+// IsDisabled tells if the named component is disabled
+func (c *ComponentsSpec) IsDisabled(name string) bool {
+	return c != nil && contains(c.Disabled, name)
+}
+
+// Validate validates the names of the disabled components, their dependencies are checked along with the rest of
+// the config
+func (c *ComponentsSpec) Validate() []error {
+	if c == nil {
+		return nil
+	}
+	var errors []error
+	for _, name := range c.Disabled {
+		if !contains(disableableComponents, name) {
+			errors = append(errors, fmt.Errorf("spec.components.disabled: unknown component %s, the components which can be disabled are %s", name, strings.Join(disableableComponents, ", ")))
+		}
+	}
+	return errors
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
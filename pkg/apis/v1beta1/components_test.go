@@ -0,0 +1,35 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentsSpec(t *testing.T) {
+	var c *ComponentsSpec
+	assert.False(t, c.IsDisabled(CoreDNSComponent))
+	assert.Empty(t, c.Validate())
+
+	c = &ComponentsSpec{Disabled: []string{CoreDNSComponent, "kube-dns"}}
+	assert.True(t, c.IsDisabled(CoreDNSComponent))
+	assert.False(t, c.IsDisabled(MetricsServerComponent))
+	errors := c.Validate()
+	assert.Len(t, errors, 1)
+	assert.Contains(t, errors[0].Error(), "spec.components.disabled: unknown component kube-dns")
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+)
+
+var _ Validateable = (*ConnectionBrokerSpec)(nil)
+
+// ConnectionBrokerSpec makes the workers connect to the nearest controller, for control planes stretched across regions
+type ConnectionBrokerSpec struct {
+	Enabled   bool                 `yaml:"enabled"`
+	Endpoints []ControllerEndpoint `yaml:"endpoints"`
+	// DiscoverEndpoints makes the workers track the controllers from the endpoints of the kubernetes service, in
+	// addition to the listed endpoints
+	DiscoverEndpoints bool   `yaml:"discoverEndpoints,omitempty"`
+	ProbeInterval     string `yaml:"probeInterval,omitempty"`
+}
+
+// ControllerEndpoint is a controller address the workers can connect to
+type ControllerEndpoint struct {
+	Address string `yaml:"address"`
+	Region  string `yaml:"region,omitempty"`
+}
+
+// DefaultConnectionBrokerSpec creates the disabled connection broker config
+func DefaultConnectionBrokerSpec() *ConnectionBrokerSpec {
+	return &ConnectionBrokerSpec{
+		ProbeInterval: "30s",
+	}
+}
+
+// ProbeIntervalDuration returns the parsed probe interval
+func (c *ConnectionBrokerSpec) ProbeIntervalDuration() time.Duration {
+	d, err := time.ParseDuration(c.ProbeInterval)
+	if err != nil || d <= 0 {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// Validate validates the endpoints
+func (c *ConnectionBrokerSpec) Validate() []error {
+	if c == nil || !c.Enabled {
+		return nil
+	}
+	var errors []error
+	if len(c.Endpoints) == 0 && !c.DiscoverEndpoints {
+		errors = append(errors, fmt.Errorf("spec.connectionBroker.endpoints: at least one endpoint is required unless discoverEndpoints is enabled"))
+	}
+	for _, e := range c.Endpoints {
+		if !govalidator.IsIP(e.Address) && !govalidator.IsDNSName(e.Address) {
+			errors = append(errors, fmt.Errorf("spec.connectionBroker.endpoints: %q is not a valid address", e.Address))
+		}
+	}
+	if c.ProbeInterval != "" {
+		if _, err := time.ParseDuration(c.ProbeInterval); err != nil {
+			errors = append(errors, fmt.Errorf("spec.connectionBroker.probeInterval: %w", err))
+		}
+	}
+	return errors
+}
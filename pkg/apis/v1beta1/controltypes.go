@@ -23,12 +23,16 @@ type CaResponse struct {
 	Cert  []byte `json:"cert"`
 	SAKey []byte `json:"saKey"`
 	SAPub []byte `json:"saPub"`
+	// OIDCKey is the signing key of the embedded OIDC provider, empty on clusters created before it existed
+	OIDCKey []byte `json:"oidcKey,omitempty"`
+	OIDCPub []byte `json:"oidcPub,omitempty"`
 }
 
 // EtcdRequest defines the etcd control api request structure
 type EtcdRequest struct {
 	Node        string `json:"node"`
 	PeerAddress string `json:"peerAddress"`
+	Learner     bool   `json:"learner,omitempty"`
 }
 
 // Validate validates the request
@@ -49,3 +53,39 @@ type EtcdResponse struct {
 	CA             CaResponse `json:"ca"`
 	InitialCluster []string   `json:"initialCluster"`
 }
+
+// AttestationChallengeRequest starts the TPM attestation of a joining worker
+type AttestationChallengeRequest struct {
+	// EKPublic is the PEM encoded public part of the endorsement key
+	EKPublic []byte `json:"ekPublic"`
+	// AKPublic is the TPM2B_PUBLIC structure of the attestation key
+	AKPublic []byte `json:"akPublic"`
+}
+
+// AttestationChallengeResponse holds the challenge the joining worker must solve with its TPM
+type AttestationChallengeResponse struct {
+	Session string `json:"session"`
+	// Nonce must be included in the quote
+	Nonce []byte `json:"nonce"`
+	// Credential is the credential blob to activate with the attestation and the endorsement key
+	Credential []byte `json:"credential"`
+}
+
+// AttestationQuoteRequest proves the possession of the keys and the state of the joining worker
+type AttestationQuoteRequest struct {
+	AttestationChallengeRequest
+
+	Session string `json:"session"`
+	// Secret is the activated credential
+	Secret []byte `json:"secret"`
+	// Quote is the TPMS_ATTEST structure signed by the attestation key
+	Quote []byte `json:"quote"`
+	// Signature is the TPMT_SIGNATURE of the quote
+	Signature []byte `json:"signature"`
+}
+
+// AttestationResponse holds the bootstrap credentials issued for the attested worker
+type AttestationResponse struct {
+	// Token is the worker join token
+	Token string `json:"token"`
+}
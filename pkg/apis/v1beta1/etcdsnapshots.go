@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/k0sproject/k0s/internal/util"
+)
+
+// defaultSnapshotRetention is the amount of snapshots kept when the retention isn't set
+const defaultSnapshotRetention = 5
+
+// EtcdSnapshotsSpec defines the periodic etcd snapshots taken by each controller under the data dir
+type EtcdSnapshotsSpec struct {
+	// Schedule is a cron schedule such as "0 */6 * * *", "@daily" or "@every 2h"
+	Schedule string `yaml:"schedule"`
+	// Retention is the amount of snapshots to keep (default: 5)
+	Retention int `yaml:"retention,omitempty"`
+	// StaleAfter is the age of the last snapshot after which the snapshots are reported stale (default: two
+	// schedule periods)
+	StaleAfter string `yaml:"staleAfter,omitempty"`
+}
+
+// Keep returns the amount of snapshots to keep
+func (e *EtcdSnapshotsSpec) Keep() int {
+	if e.Retention == 0 {
+		return defaultSnapshotRetention
+	}
+	return e.Retention
+}
+
+// StaleAfterDuration returns the age after which the last snapshot is stale, for the given schedule period
+func (e *EtcdSnapshotsSpec) StaleAfterDuration(period time.Duration) time.Duration {
+	if d, err := time.ParseDuration(e.StaleAfter); err == nil && d > 0 {
+		return d
+	}
+	return 2 * period
+}
+
+// Validate validates the etcd snapshot settings
+func (e *EtcdSnapshotsSpec) Validate() []error {
+	var errors []error
+	if schedule, err := util.ParseSchedule(e.Schedule); err != nil {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.snapshots.schedule: %w", err))
+	} else if schedule.Next(time.Now()).IsZero() {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.snapshots.schedule: %q never fires", e.Schedule))
+	}
+	if e.Retention < 0 {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.snapshots.retention: %d is negative", e.Retention))
+	}
+	if e.StaleAfter != "" {
+		if d, err := time.ParseDuration(e.StaleAfter); err != nil {
+			errors = append(errors, fmt.Errorf("spec.storage.etcd.snapshots.staleAfter: %w", err))
+		} else if d <= 0 {
+			errors = append(errors, fmt.Errorf("spec.storage.etcd.snapshots.staleAfter: %s isn't positive", d))
+		}
+	}
+	return errors
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// envVarPattern matches ${VAR} and ${VAR:-default}, $${ is kept as a literal ${
+	envVarPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+	// includePattern matches the lines whose value is an !include, as in "key: !include file", "- !include file"
+	// or a bare "!include file"
+	includePattern = regexp.MustCompile(`^(\s*)(-\s+)?([^\s#'"!-][^#'"]*?:)?\s*!include\s+(\S.*?)\s*$`)
+)
+
+// ExpandConfig expands the includes and the environment variables of the config. A value of "!include path" is
+// replaced with the content of the file, indented in place, relative paths being resolved against baseDir. The
+// included files are expanded as well. The comment lines are left as is. The variables are expanded after the
+// parsing, in the string values only, so a variable can't change the structure of the config: ${VAR} is replaced
+// with the variable, which must be set unless a default is given with ${VAR:-default}, and $${ is a literal ${.
+func ExpandConfig(data []byte, baseDir string) ([]byte, error) {
+	included, err := expandConfig(string(data), baseDir, "config", nil)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(included, []byte("${")) {
+		return included, nil
+	}
+
+	var out bytes.Buffer
+	decoder := yaml.NewDecoder(bytes.NewReader(included))
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse the config: %w", err)
+		}
+		if err := expandNode(&doc); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// expandNode expands the variables in the string values under the node, the mapping keys are left as is
+func expandNode(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := expandNode(n); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := expandNode(node.Content[i]); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" || !strings.Contains(node.Value, "${") {
+			return nil
+		}
+		value, err := expandEnv(node.Value)
+		if err != nil {
+			return fmt.Errorf("config, line %d: %w", node.Line, err)
+		}
+		node.Value = value
+		// the unquoted values are typed by what they expand to, e.g. a port from a variable is a number
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
+
+func expandConfig(data string, baseDir string, source string, includes []string) ([]byte, error) {
+	var out strings.Builder
+	lines := strings.Split(data, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			out.WriteString(line)
+		} else if err := expandLine(&out, line, baseDir, includes); err != nil {
+			return nil, fmt.Errorf("%s, line %d: %w", source, i+1, err)
+		}
+		if i < len(lines)-1 {
+			out.WriteString("\n")
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+func expandLine(out *strings.Builder, line string, baseDir string, includes []string) error {
+	m := includePattern.FindStringSubmatch(line)
+	if m == nil {
+		out.WriteString(line)
+		return nil
+	}
+	indent, dash, key, path := m[1], m[2], m[3], strings.Trim(m[4], `"'`)
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	for _, included := range includes {
+		if included == path {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(includes, " -> "), path)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to include %s: %w", path, err)
+	}
+	content, err := expandConfig(strings.TrimRight(string(data), "\n"), filepath.Dir(path), path, append(includes, path))
+	if err != nil {
+		return err
+	}
+
+	// the content goes below the key, or in place of the list item
+	first, rest := indent, indent
+	switch {
+	case dash != "" && key != "":
+		out.WriteString(indent + "- " + key + "\n")
+		first, rest = indent+"    ", indent+"    "
+	case dash != "":
+		first, rest = indent+"- ", indent+"  "
+	case key != "":
+		out.WriteString(indent + key + "\n")
+		first, rest = indent+"  ", indent+"  "
+	}
+	var written int
+	for _, l := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(l) == "---" {
+			continue
+		}
+		if written > 0 {
+			out.WriteString("\n")
+		}
+		if l != "" {
+			if written == 0 {
+				out.WriteString(first)
+			} else {
+				out.WriteString(rest)
+			}
+		}
+		out.WriteString(l)
+		written++
+	}
+	return nil
+}
+
+func expandEnv(value string) (string, error) {
+	var missing []string
+	expanded := envVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		m := envVarPattern.FindStringSubmatch(match)
+		if value, ok := os.LookupEnv(m[1]); ok && (value != "" || !strings.Contains(match, ":-")) {
+			return value
+		}
+		if strings.Contains(match, ":-") {
+			return m[2]
+		}
+		missing = append(missing, m[1])
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set, set it or give a default with ${%s:-default}", strings.Join(missing, ", "), missing[0])
+	}
+	return expanded, nil
+}
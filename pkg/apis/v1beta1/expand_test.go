@@ -0,0 +1,104 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExpandConfigEnv(t *testing.T) {
+	os.Setenv("K0S_TEST_ETCD_PASSWORD", "s3cr3t")
+	defer os.Unsetenv("K0S_TEST_ETCD_PASSWORD")
+	os.Setenv("K0S_TEST_PORT", "6443")
+	defer os.Unsetenv("K0S_TEST_PORT")
+	os.Setenv("K0S_TEST_INJECTED", "x\n  injected: true")
+	defer os.Unsetenv("K0S_TEST_INJECTED")
+
+	data, err := ExpandConfig([]byte(`spec:
+  # ${K0S_TEST_UNSET} in a comment
+  api:
+    port: ${K0S_TEST_PORT}
+    address: "${K0S_TEST_PORT}"
+  storage:
+    password: "${K0S_TEST_ETCD_PASSWORD}"
+    region: ${K0S_TEST_UNSET:-eu-west-1}
+    literal: $${K0S_TEST_ETCD_PASSWORD}
+    name: ${K0S_TEST_INJECTED}
+    ${K0S_TEST_UNSET}: key`), ".")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# ${K0S_TEST_UNSET} in a comment")
+
+	var config struct {
+		Spec struct {
+			API     map[string]interface{} `yaml:"api"`
+			Storage map[string]interface{} `yaml:"storage"`
+		} `yaml:"spec"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &config))
+	assert.Equal(t, map[string]interface{}{"port": 6443, "address": "6443"}, config.Spec.API)
+	assert.Equal(t, map[string]interface{}{
+		"password":          "s3cr3t",
+		"region":            "eu-west-1",
+		"literal":           "${K0S_TEST_ETCD_PASSWORD}",
+		"name":              "x\n  injected: true",
+		"${K0S_TEST_UNSET}": "key",
+	}, config.Spec.Storage)
+
+	_, err = ExpandConfig([]byte("spec:\n  password: ${K0S_TEST_UNSET}\n"), ".")
+	assert.EqualError(t, err, "config, line 2: environment variable K0S_TEST_UNSET is not set, set it or give a default with ${K0S_TEST_UNSET:-default}")
+}
+
+func TestExpandConfigInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "storage.yaml"), []byte("type: etcd\netcd:\n  peerAddress: 10.0.0.1\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "profile.yaml"), []byte("---\nname: small\nvalues:\n  maxPods: 50\n"), 0600))
+
+	data, err := ExpandConfig([]byte(`spec:
+  storage: !include storage.yaml
+  workerProfiles:
+  - !include profile.yaml
+  - name: big
+    values: !include "`+filepath.Join(dir, "profile.yaml")+`"`), dir)
+	require.NoError(t, err)
+	assert.Equal(t, `spec:
+  storage:
+    type: etcd
+    etcd:
+      peerAddress: 10.0.0.1
+  workerProfiles:
+  - name: small
+    values:
+      maxPods: 50
+  - name: big
+    values:
+      name: small
+      values:
+        maxPods: 50`, string(data))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "loop.yaml"), []byte("spec: !include loop.yaml\n"), 0600))
+	_, err = ExpandConfig([]byte("!include loop.yaml"), dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// FirewallBackendFirewalld opens the ports in a firewalld zone
+	FirewallBackendFirewalld = "firewalld"
+	// FirewallBackendNftables inserts accept rules into an nftables chain
+	FirewallBackendNftables = "nftables"
+	// DefaultFirewallChain is the nftables chain of the input filter of the common distributions
+	DefaultFirewallChain = "inet filter input"
+)
+
+// The ports of the node components which aren't configurable
+const (
+	kubeletPort       = 10250
+	bgpPort           = 179
+	wireguardPort     = 51820
+	wireguardIPv6Port = 51821
+)
+
+// Firewall opens the ports needed by k0s in the host firewall of the nodes and keeps them open
+type Firewall struct {
+	Enabled bool `yaml:"enabled"`
+	// Backend is firewalld or nftables, detected on each node when empty
+	Backend string `yaml:"backend,omitempty"`
+	// Zone is the firewalld zone the ports are opened in, the default zone when empty
+	Zone string `yaml:"zone,omitempty"`
+	// Chain is the nftables chain the accept rules are inserted into, "<family> <table> <chain>"
+	Chain string `yaml:"chain,omitempty"`
+	// ExtraPorts are opened on all the nodes in addition to the ports of k0s
+	ExtraPorts []FirewallPort `yaml:"extraPorts,omitempty"`
+}
+
+// FirewallPort is a port or a port range opened in the host firewall
+type FirewallPort struct {
+	Port int `yaml:"port"`
+	// EndPort is the last port of a port range
+	EndPort  int    `yaml:"endPort,omitempty"`
+	Protocol string `yaml:"protocol,omitempty"`
+	// Name tells what the port is for
+	Name string `yaml:"name,omitempty"`
+}
+
+// FirewallRules are the ports of a node and the firewall they're opened in, published to the workers
+type FirewallRules struct {
+	Backend string         `yaml:"backend,omitempty"`
+	Zone    string         `yaml:"zone,omitempty"`
+	Chain   string         `yaml:"chain,omitempty"`
+	Ports   []FirewallPort `yaml:"ports"`
+}
+
+// Validate validates the firewall settings
+func (f *Firewall) Validate() []error {
+	var errors []error
+	switch f.Backend {
+	case "", FirewallBackendFirewalld, FirewallBackendNftables:
+	default:
+		errors = append(errors, fmt.Errorf("spec.network.firewall.backend: %q is not supported, use %q or %q", f.Backend, FirewallBackendFirewalld, FirewallBackendNftables))
+	}
+	if f.Chain != "" && len(strings.Fields(f.Chain)) != 3 {
+		errors = append(errors, fmt.Errorf("spec.network.firewall.chain: %q must be given as \"<family> <table> <chain>\"", f.Chain))
+	}
+	for _, p := range f.ExtraPorts {
+		if err := p.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("spec.network.firewall.extraPorts: %w", err))
+		}
+	}
+	return errors
+}
+
+// Validate checks the port and the protocol
+func (p FirewallPort) Validate() error {
+	if p.Port < 1 || p.Port > 65535 {
+		return fmt.Errorf("%d is not a valid port", p.Port)
+	}
+	if p.EndPort != 0 && (p.EndPort < p.Port || p.EndPort > 65535) {
+		return fmt.Errorf("%d-%d is not a valid port range", p.Port, p.EndPort)
+	}
+	switch p.Protocol {
+	case "", "tcp", "udp", "sctp":
+	default:
+		return fmt.Errorf("protocol %q of port %d is not supported, use tcp, udp or sctp", p.Protocol, p.Port)
+	}
+	return nil
+}
+
+// String formats the port the way firewalld does, e.g. 6443/tcp or 30000-32767/tcp
+func (p FirewallPort) String() string {
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if p.EndPort > p.Port {
+		return fmt.Sprintf("%d-%d/%s", p.Port, p.EndPort, protocol)
+	}
+	return fmt.Sprintf("%d/%s", p.Port, protocol)
+}
+
+// FirewallRules returns the ports of a controller and/or a worker to open in the host firewall, nil if the firewall
+// isn't managed
+func (s *ClusterSpec) FirewallRules(controller, worker bool) *FirewallRules {
+	if s.Network == nil || s.Network.Firewall == nil || !s.Network.Firewall.Enabled {
+		return nil
+	}
+	f := s.Network.Firewall
+	rules := &FirewallRules{Backend: f.Backend, Zone: f.Zone, Chain: f.Chain}
+	if rules.Chain == "" {
+		rules.Chain = DefaultFirewallChain
+	}
+	var ports []FirewallPort
+	if controller {
+		ports = append(ports, s.controllerPorts()...)
+	}
+	if worker {
+		ports = append(ports, s.Network.workerPorts()...)
+	}
+	ports = append(ports, f.ExtraPorts...)
+
+	// the ports are listed once, sorted by the port
+	seen := map[string]bool{}
+	for _, p := range ports {
+		if !seen[p.String()] {
+			seen[p.String()] = true
+			rules.Ports = append(rules.Ports, p)
+		}
+	}
+	sort.SliceStable(rules.Ports, func(i, j int) bool { return rules.Ports[i].Port < rules.Ports[j].Port })
+	return rules
+}
+
+// controllerPorts are the ports the other controllers, the workers and the users connect to
+func (s *ClusterSpec) controllerPorts() []FirewallPort {
+	var ports []FirewallPort
+	if s.API != nil {
+		ports = append(ports, FirewallPort{Port: s.API.Port, Name: "kube-apiserver"})
+		if !s.Components.IsDisabled(ControlAPIComponent) {
+			ports = append(ports, FirewallPort{Port: s.API.K0sAPIPort, Name: "k0s-api"})
+		}
+		if s.API.HealthPort != 0 {
+			ports = append(ports, FirewallPort{Port: s.API.HealthPort, Name: "health"})
+		}
+	}
+	if s.Konnectivity != nil {
+		ports = append(ports, FirewallPort{Port: int(s.Konnectivity.AgentPort), Name: "konnectivity"})
+	}
+	if s.Storage != nil && s.Storage.Type == EtcdStorageType {
+		ports = append(ports, FirewallPort{Port: etcdPeerPort, Name: "etcd-peer"})
+	}
+	return ports
+}
+
+// workerPorts are the ports of the kubelet, the CNI and the NodePort services
+func (n *Network) workerPorts() []FirewallPort {
+	ports := []FirewallPort{{Port: kubeletPort, Name: "kubelet"}}
+	switch n.Provider {
+	case "kuberouter":
+		ports = append(ports, FirewallPort{Port: bgpPort, Name: "bgp"})
+	case "calico":
+		if n.Calico != nil {
+			switch n.Calico.Mode {
+			case "bird":
+				ports = append(ports, FirewallPort{Port: bgpPort, Name: "bgp"})
+			case "vxlan":
+				ports = append(ports, FirewallPort{Port: n.Calico.VxlanPort, Protocol: "udp", Name: "vxlan"})
+			}
+			if n.Calico.EnableWireguard {
+				ports = append(ports, FirewallPort{Port: wireguardPort, Protocol: "udp", Name: "wireguard"})
+				if n.DualStack.Enabled {
+					ports = append(ports, FirewallPort{Port: wireguardIPv6Port, Protocol: "udp", Name: "wireguard-ipv6"})
+				}
+			}
+		}
+	}
+	if first, last, err := n.NodePorts(); err == nil {
+		ports = append(ports,
+			FirewallPort{Port: first, EndPort: last, Name: "nodeports"},
+			FirewallPort{Port: first, EndPort: last, Protocol: "udp", Name: "nodeports"})
+	}
+	return ports
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func portStrings(rules *FirewallRules) []string {
+	var ports []string
+	for _, p := range rules.Ports {
+		ports = append(ports, p.String())
+	}
+	return ports
+}
+
+func TestFirewallRules(t *testing.T) {
+	spec := DefaultClusterConfig(constant.GetConfig("")).Spec
+	assert.Nil(t, spec.FirewallRules(true, true))
+
+	spec.Network.Firewall = &Firewall{Enabled: true, ExtraPorts: []FirewallPort{{Port: 443}, {Port: 6443}}}
+	controller := spec.FirewallRules(true, false)
+	assert.Equal(t, DefaultFirewallChain, controller.Chain)
+	assert.Equal(t, []string{"443/tcp", "2380/tcp", "6443/tcp", "8132/tcp", "9443/tcp"}, portStrings(controller))
+
+	assert.Equal(t, []string{"179/tcp", "443/tcp", "6443/tcp", "10250/tcp", "30000-32767/tcp", "30000-32767/udp"}, portStrings(spec.FirewallRules(false, true)))
+
+	spec.Network.Provider = "calico"
+	spec.Network.Calico = DefaultCalico()
+	spec.Network.Calico.EnableWireguard = true
+	assert.Equal(t, []string{"443/tcp", "4789/udp", "6443/tcp", "10250/tcp", "30000-32767/tcp", "30000-32767/udp", "51820/udp"}, portStrings(spec.FirewallRules(false, true)))
+}
+
+func TestFirewallValidate(t *testing.T) {
+	f := &Firewall{
+		Enabled:    true,
+		Backend:    "iptables",
+		Chain:      "input",
+		ExtraPorts: []FirewallPort{{Port: 80}, {Port: 0}, {Port: 100, EndPort: 50}, {Port: 53, Protocol: "icmp"}},
+	}
+	assert.Len(t, f.Validate(), 5)
+	assert.Empty(t, (&Firewall{Enabled: true, Backend: FirewallBackendNftables, Chain: "ip filter INPUT"}).Validate())
+}
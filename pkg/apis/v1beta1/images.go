@@ -19,6 +19,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/version"
+
 	"github.com/k0sproject/k0s/pkg/constant"
 )
 
@@ -30,6 +33,10 @@ type ImageSpec struct {
 
 // URI build image uri
 func (is ImageSpec) URI() string {
+	if strings.Contains(is.Version, ":") {
+		// a digest, like sha256:...
+		return fmt.Sprintf("%s@%s", is.Image, is.Version)
+	}
 	return fmt.Sprintf("%s:%s", is.Image, is.Version)
 }
 
@@ -39,6 +46,7 @@ type ClusterImages struct {
 	MetricsServer ImageSpec `yaml:"metricsserver"`
 	KubeProxy     ImageSpec `yaml:"kubeproxy"`
 	CoreDNS       ImageSpec `yaml:"coredns"`
+	Pause         ImageSpec `yaml:"pause"`
 
 	Calico     CalicoImageSpec     `yaml:"calico"`
 	KubeRouter KubeRouterImageSpec `yaml:"kuberouter"`
@@ -69,6 +77,7 @@ func (ci *ClusterImages) overrideImageRepositories() {
 	override(&ci.MetricsServer)
 	override(&ci.KubeProxy)
 	override(&ci.CoreDNS)
+	override(&ci.Pause)
 	override(&ci.Calico.CNI)
 	override(&ci.Calico.Node)
 	override(&ci.Calico.KubeControllers)
@@ -109,6 +118,10 @@ func DefaultClusterImages() *ClusterImages {
 			Image:   constant.CoreDNSImage,
 			Version: constant.CoreDNSImageVersion,
 		},
+		Pause: ImageSpec{
+			Image:   constant.KubePauseContainerImage,
+			Version: constant.KubePauseContainerImageVersion,
+		},
 		Calico: CalicoImageSpec{
 			CNI: ImageSpec{
 				Image:   constant.CalicoImage,
@@ -152,7 +165,65 @@ func overrideRepository(repository string, originalImage string) string {
 	return fmt.Sprintf("%s/%s", repository, originalImage)
 }
 
-// Validate stub for Validateable interface
-func (ci *ClusterImages) Validate() []error {
+// addonVersionRange is the range of the addon versions the manifests of k0s work with, from min up to but not
+// including max
+type addonVersionRange struct {
+	min string
+	max string
+}
+
+// The addon versions can be pinned in spec.images, so that upgrading k0s doesn't upgrade the addons at the same
+// time. The pinned versions must stay within the ranges the manifests of this k0s version are written for.
+var (
+	konnectivityVersions  = addonVersionRange{min: "0.0.15", max: "0.1.0"}
+	metricsServerVersions = addonVersionRange{min: "0.3.6", max: "0.4.0"}
+	coreDNSVersions       = addonVersionRange{min: "1.6.0", max: "1.9.0"}
+	calicoVersions        = addonVersionRange{min: "3.16.0", max: "3.20.0"}
+	kubeRouterVersions    = addonVersionRange{min: "1.1.0", max: "1.3.0"}
+)
+
+// validate checks that the version of the image is within the range. A version that isn't a semver tag,
+// like latest or a digest, can't be checked and only gets a warning.
+func (r addonVersionRange) validate(key string, spec ImageSpec) error {
+	v, err := version.ParseGeneric(spec.Version)
+	if err != nil {
+		logrus.Warnf("%s.version: %q can't be checked for compatibility with this k0s version: %v", key, spec.Version, err)
+		return nil
+	}
+	if v.LessThan(version.MustParseGeneric(r.min)) || !v.LessThan(version.MustParseGeneric(r.max)) {
+		return fmt.Errorf("%s.version: %s isn't compatible with this k0s version, use a version from %s up to but not including %s", key, spec.Version, r.min, r.max)
+	}
 	return nil
 }
+
+// Validate checks that the addon versions are compatible with the manifests of k0s
+func (ci *ClusterImages) Validate() []error {
+	if ci == nil {
+		return nil
+	}
+	var errors []error
+	validate := func(key string, spec ImageSpec, r addonVersionRange) {
+		if err := r.validate("spec.images."+key, spec); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	validate("konnectivity", ci.Konnectivity, konnectivityVersions)
+	validate("metricsserver", ci.MetricsServer, metricsServerVersions)
+	validate("coredns", ci.CoreDNS, coreDNSVersions)
+	validate("calico.cni", ci.Calico.CNI, calicoVersions)
+	validate("calico.node", ci.Calico.Node, calicoVersions)
+	validate("calico.kubecontrollers", ci.Calico.KubeControllers, calicoVersions)
+	validate("kuberouter.cni", ci.KubeRouter.CNI, kubeRouterVersions)
+
+	// the calico manifests are made of the components of a single release, which can only be told from the tags
+	if isVersionTag(ci.Calico.CNI.Version) && isVersionTag(ci.Calico.Node.Version) && isVersionTag(ci.Calico.KubeControllers.Version) &&
+		ci.Calico.Node.Version != ci.Calico.CNI.Version || ci.Calico.KubeControllers.Version != ci.Calico.CNI.Version {
+		errors = append(errors, fmt.Errorf("spec.images.calico: cni, node and kubecontrollers must have the same version, have %s, %s and %s", ci.Calico.CNI.Version, ci.Calico.Node.Version, ci.Calico.KubeControllers.Version))
+	}
+	return errors
+}
+
+func isVersionTag(v string) bool {
+	_, err := version.ParseGeneric(v)
+	return err == nil
+}
@@ -43,6 +43,7 @@ func TestImagesRepoOverrideInConfiguration(t *testing.T) {
 			require.Equal(t, fmt.Sprintf("my.repo/k8s-staging-metrics-server/metrics-server:%s", constant.MetricsImageVersion), testingConfig.Spec.Images.MetricsServer.URI())
 			require.Equal(t, fmt.Sprintf("my.repo/kube-proxy:%s", constant.KubeProxyImageVersion), testingConfig.Spec.Images.KubeProxy.URI())
 			require.Equal(t, fmt.Sprintf("my.repo/coredns/coredns:%s", constant.CoreDNSImageVersion), testingConfig.Spec.Images.CoreDNS.URI())
+			require.Equal(t, fmt.Sprintf("my.repo/pause:%s", constant.KubePauseContainerImageVersion), testingConfig.Spec.Images.Pause.URI())
 			require.Equal(t, fmt.Sprintf("my.repo/calico/cni:%s", constant.CalicoComponentImagesVersion), testingConfig.Spec.Images.Calico.CNI.URI())
 			require.Equal(t, fmt.Sprintf("my.repo/calico/node:%s", constant.CalicoComponentImagesVersion), testingConfig.Spec.Images.Calico.Node.URI())
 			require.Equal(t, fmt.Sprintf("my.repo/calico/kube-controllers:%s", constant.CalicoComponentImagesVersion), testingConfig.Spec.Images.Calico.KubeControllers.URI())
@@ -93,3 +94,47 @@ func TestOverrideFunction(t *testing.T) {
 		assert.Equal(t, tc.Output, overrideRepository(repository, tc.Input))
 	}
 }
+
+func TestImagesVersionPinning(t *testing.T) {
+	k0sVars := constant.GetConfig("")
+	t.Run("pinned_version_keeps_default_image", func(t *testing.T) {
+		c, err := configFromString(`
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  images:
+    coredns:
+      version: 1.6.9
+`, k0sVars)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s:1.6.9", constant.CoreDNSImage), c.Spec.Images.CoreDNS.URI())
+		assert.Empty(t, c.Spec.Images.Validate())
+	})
+	t.Run("defaults_are_compatible", func(t *testing.T) {
+		assert.Empty(t, DefaultClusterImages().Validate())
+	})
+	t.Run("incompatible_versions", func(t *testing.T) {
+		images := DefaultClusterImages()
+		images.MetricsServer.Version = "v0.5.0"
+		images.CoreDNS.Version = "latest"
+		images.KubeRouter.CNI.Version = "v0.9.0"
+		errors := images.Validate()
+		require.Len(t, errors, 2)
+		assert.Contains(t, errors[0].Error(), "spec.images.metricsserver.version: v0.5.0 isn't compatible")
+		assert.Contains(t, errors[1].Error(), "spec.images.kuberouter.cni.version: v0.9.0 isn't compatible")
+	})
+	t.Run("unchecked_versions_only_warn", func(t *testing.T) {
+		images := DefaultClusterImages()
+		images.CoreDNS.Version = "latest"
+		images.Calico.Node.Version = "sha256:3f8e1a6fd5ac7b5a6b4b2d0a5e1c8f2d5c9e6f1b4d9b1a0e7c1f2e3d4a5b6c7d"
+		assert.Empty(t, images.Validate())
+		assert.Equal(t, constant.CalicoNodeImage+"@"+images.Calico.Node.Version, images.Calico.Node.URI())
+	})
+	t.Run("calico_versions_must_match", func(t *testing.T) {
+		images := DefaultClusterImages()
+		images.Calico.Node.Version = "v3.17.0"
+		errors := images.Validate()
+		require.Len(t, errors, 1)
+		assert.Contains(t, errors[0].Error(), "spec.images.calico")
+	})
+}
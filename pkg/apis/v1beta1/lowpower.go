@@ -0,0 +1,70 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+)
+
+var _ Validateable = (*LowPowerSpec)(nil)
+
+const (
+	defaultIntervalMultiplier = 6
+	maxIntervalMultiplier     = 60
+)
+
+// LowPowerSpec configures the low-power mode of the controller, for the battery powered and fanless devices. The
+// reconcile loops run less often, the nonessential components are disabled and the manifests are applied in batches.
+type LowPowerSpec struct {
+	Enabled bool `yaml:"enabled"`
+	// IntervalMultiplier lengthens the intervals of the reconcile loops
+	IntervalMultiplier int `yaml:"intervalMultiplier,omitempty"`
+}
+
+// DefaultLowPowerSpec creates the disabled low-power config
+func DefaultLowPowerSpec() *LowPowerSpec {
+	return &LowPowerSpec{
+		IntervalMultiplier: defaultIntervalMultiplier,
+	}
+}
+
+// IsEnabled tells if the low-power mode is enabled
+func (l *LowPowerSpec) IsEnabled() bool {
+	return l != nil && l.Enabled
+}
+
+// Scale lengthens the interval in the low-power mode, it's returned as is otherwise
+func (l *LowPowerSpec) Scale(interval time.Duration) time.Duration {
+	if !l.IsEnabled() {
+		return interval
+	}
+	if l.IntervalMultiplier <= 0 {
+		return interval * defaultIntervalMultiplier
+	}
+	return interval * time.Duration(l.IntervalMultiplier)
+}
+
+// Validate validates the low-power config
+func (l *LowPowerSpec) Validate() []error {
+	if !l.IsEnabled() {
+		return nil
+	}
+	if l.IntervalMultiplier < 0 || l.IntervalMultiplier > maxIntervalMultiplier {
+		return []error{fmt.Errorf("spec.lowPower.intervalMultiplier: %d is not between 1 and %d", l.IntervalMultiplier, maxIntervalMultiplier)}
+	}
+	return nil
+}
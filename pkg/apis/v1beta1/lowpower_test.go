@@ -0,0 +1,37 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowPowerScale(t *testing.T) {
+	var unset *LowPowerSpec
+	assert.Equal(t, 10*time.Second, unset.Scale(10*time.Second))
+	assert.Equal(t, 10*time.Second, DefaultLowPowerSpec().Scale(10*time.Second))
+
+	spec := DefaultLowPowerSpec()
+	spec.Enabled = true
+	assert.Empty(t, spec.Validate())
+	assert.Equal(t, time.Minute, spec.Scale(10*time.Second))
+
+	spec.IntervalMultiplier = 120
+	assert.Len(t, spec.Validate(), 1)
+}
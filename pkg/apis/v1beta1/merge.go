@@ -0,0 +1,128 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// ConfigFromFiles reads the config from the given files and directories, merged in order so that the later
+// ones override the earlier ones. The directories contribute their *.yaml and *.yml files in lexical order.
+func ConfigFromFiles(paths []string, k0sVars constant.CfgVars) (*ClusterConfig, error) {
+	buf, err := MergeConfigFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	return configFromString(string(buf), k0sVars)
+}
+
+// MergeConfigFiles expands and merges the config files. The maps are merged key by key, the lists and the
+// scalars of the later files replace the earlier ones and a null value removes the key.
+// A single file is returned as expanded, without going through the merge.
+func MergeConfigFiles(paths []string) ([]byte, error) {
+	files, err := configFilePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config files found in %s", strings.Join(paths, ", "))
+	}
+
+	var merged interface{}
+	for _, file := range files {
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file at %s: %w", file, err)
+		}
+		buf, err = ExpandConfig(buf, filepath.Dir(file))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 1 {
+			return buf, nil
+		}
+		var doc interface{}
+		if err := yaml.Unmarshal(buf, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file at %s: %w", file, err)
+		}
+		merged = mergeValues(merged, doc)
+	}
+	return yaml.Marshal(merged)
+}
+
+// configFilePaths lists the files to merge, in order
+func configFilePaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file at %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config directory at %s: %w", path, err)
+		}
+		var names []string
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, filepath.Join(path, name))
+		}
+	}
+	return files, nil
+}
+
+// mergeValues merges the override into the base, the maps recursively
+func mergeValues(base, override interface{}) interface{} {
+	baseMap, ok := base.(map[interface{}]interface{})
+	if !ok {
+		return override
+	}
+	overrideMap, ok := override.(map[interface{}]interface{})
+	if !ok {
+		if override == nil {
+			// an empty document keeps the base
+			return base
+		}
+		return override
+	}
+	for k, v := range overrideMap {
+		if v == nil {
+			delete(baseMap, k)
+			continue
+		}
+		baseMap[k] = mergeValues(baseMap[k], v)
+	}
+	return baseMap
+}
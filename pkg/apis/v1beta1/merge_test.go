@@ -0,0 +1,75 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestConfigFromFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.yaml")
+	require.NoError(t, ioutil.WriteFile(base, []byte(`apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: fleet
+spec:
+  api:
+    port: 6443
+    sans:
+    - 10.0.0.1
+    - 10.0.0.2
+  network:
+    podCIDR: 10.244.0.0/16
+    serviceCIDR: 10.96.0.0/12
+  telemetry:
+    enabled: false
+`), 0600))
+
+	site := filepath.Join(dir, "site.d")
+	require.NoError(t, os.Mkdir(site, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(site, "20-network.yml"), []byte("spec:\n  network:\n    podCIDR: 10.100.0.0/16\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(site, "10-api.yaml"), []byte("spec:\n  api:\n    sans:\n    - 192.168.1.1\n  telemetry: null\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(site, "README.md"), []byte("not a config"), 0600))
+
+	c, err := ConfigFromFiles([]string{base, site}, constant.GetConfig(dir))
+	require.NoError(t, err)
+	assert.Equal(t, "fleet", c.Metadata.Name)
+	assert.Equal(t, 6443, c.Spec.API.Port)
+	assert.Equal(t, []string{"192.168.1.1"}, c.Spec.API.SANs)
+	assert.Equal(t, "10.100.0.0/16", c.Spec.Network.PodCIDR)
+	assert.Equal(t, "10.96.0.0/12", c.Spec.Network.ServiceCIDR)
+	assert.True(t, c.Spec.Telemetry.Enabled)
+
+	_, err = ConfigFromFiles([]string{filepath.Join(dir, "missing.yaml")}, constant.GetConfig(dir))
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.d")
+	require.NoError(t, os.Mkdir(empty, 0700))
+	_, err = ConfigFromFiles([]string{empty}, constant.GetConfig(dir))
+	assert.EqualError(t, err, "no config files found in "+empty)
+}
@@ -18,6 +18,9 @@ package v1beta1
 import (
 	"fmt"
 	"net"
+	"path"
+	"strconv"
+	"strings"
 
 	utilnet "k8s.io/utils/net"
 )
@@ -33,8 +36,22 @@ type Network struct {
 	KubeRouter  *KubeRouter `yaml:"kuberouter"`
 	DualStack   DualStack   `yaml:"dualStack,omitempty"`
 	KubeProxy   *KubeProxy  `yaml:"kubeProxy"`
+	CNIConfDir  string      `yaml:"cniConfDir,omitempty"`
+	CNIBinDir   string      `yaml:"cniBinDir,omitempty"`
+	// NodePortRange is the port range reserved for the NodePort services, e.g. 30000-32767
+	NodePortRange string `yaml:"nodePortRange,omitempty"`
+	// HostsSync keeps the names of the controllers and the nodes in /etc/hosts of the cluster members
+	HostsSync bool `yaml:"hostsSync,omitempty"`
+	// Firewall opens the ports of k0s in the host firewall of the nodes
+	Firewall *Firewall `yaml:"firewall,omitempty"`
 }
 
+const (
+	defaultCNIConfDir    = "/etc/cni/net.d"
+	defaultCNIBinDir     = "/opt/cni/bin"
+	defaultNodePortRange = "30000-32767"
+)
+
 // DefaultNetwork creates the Network config struct with sane default values
 func DefaultNetwork() *Network {
 	return &Network{
@@ -44,6 +61,10 @@ func DefaultNetwork() *Network {
 		KubeRouter:  DefaultKubeRouter(),
 		DualStack:   DefaultDualStack(),
 		KubeProxy:   DefaultKubeProxy(),
+		CNIConfDir:  defaultCNIConfDir,
+		CNIBinDir:   defaultCNIBinDir,
+
+		NodePortRange: defaultNodePortRange,
 	}
 }
 
@@ -80,10 +101,87 @@ func (n *Network) Validate() []error {
 			errors = append(errors, fmt.Errorf("dual-stack requires kube-proxy in ipvs mode"))
 		}
 	}
+	for _, dir := range []string{n.CNIConfDir, n.CNIBinDir} {
+		if !path.IsAbs(dir) {
+			errors = append(errors, fmt.Errorf("CNI directory %q must be an absolute path", dir))
+		}
+	}
+	if _, _, err := n.NodePorts(); err != nil {
+		errors = append(errors, err)
+	}
 	errors = append(errors, n.KubeProxy.Validate()...)
+	if n.Firewall != nil {
+		errors = append(errors, n.Firewall.Validate()...)
+	}
+	return errors
+}
+
+// NodePorts returns the first and the last port of the NodePort range
+func (n *Network) NodePorts() (int, int, error) {
+	bounds := strings.SplitN(n.NodePortRange, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid node port range %q, expected <first>-<last>", n.NodePortRange)
+	}
+	first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid node port range %q: %w", n.NodePortRange, err)
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid node port range %q: %w", n.NodePortRange, err)
+	}
+	if first < 1 || last > 65535 || first > last {
+		return 0, 0, fmt.Errorf("invalid node port range %q, the ports must be in 1-65535 with the first not above the last", n.NodePortRange)
+	}
+	return first, last, nil
+}
+
+// ValidateChange checks the network of a running cluster can be changed from previous to n: the pod CIDRs are fixed
+// while the service CIDRs may only be expanded keeping their base address, so the addresses of the kubernetes and
+// DNS services and the existing cluster IPs remain in the range. The NodePort range can be changed freely.
+func (n *Network) ValidateChange(previous *Network) []error {
+	var errors []error
+	if previous.PodCIDR != n.PodCIDR {
+		errors = append(errors, fmt.Errorf("cannot change the pod CIDR from %s to %s", previous.PodCIDR, n.PodCIDR))
+	}
+	if err := validateCIDRExpansion(previous.ServiceCIDR, n.ServiceCIDR); err != nil {
+		errors = append(errors, err)
+	} else if prevDNS, _ := previous.DNSAddress(); prevDNS != "" {
+		if dns, _ := n.DNSAddress(); dns != prevDNS {
+			errors = append(errors, fmt.Errorf("cannot change the service CIDR from %s to %s, it would move the DNS address %s to %s", previous.ServiceCIDR, n.ServiceCIDR, prevDNS, dns))
+		}
+	}
+	if previous.DualStack.Enabled && n.DualStack.Enabled {
+		if previous.DualStack.IPv6PodCIDR != n.DualStack.IPv6PodCIDR {
+			errors = append(errors, fmt.Errorf("cannot change the pod IPv6 CIDR from %s to %s", previous.DualStack.IPv6PodCIDR, n.DualStack.IPv6PodCIDR))
+		}
+		if err := validateCIDRExpansion(previous.DualStack.IPv6ServiceCIDR, n.DualStack.IPv6ServiceCIDR); err != nil {
+			errors = append(errors, err)
+		}
+	}
 	return errors
 }
 
+func validateCIDRExpansion(previous, current string) error {
+	if previous == current {
+		return nil
+	}
+	_, prevNet, err := net.ParseCIDR(previous)
+	if err != nil {
+		return fmt.Errorf("invalid previous service CIDR %s", previous)
+	}
+	_, curNet, err := net.ParseCIDR(current)
+	if err != nil {
+		return fmt.Errorf("invalid service CIDR %s", current)
+	}
+	prevSize, _ := prevNet.Mask.Size()
+	curSize, _ := curNet.Mask.Size()
+	if !prevNet.IP.Equal(curNet.IP) || curSize > prevSize {
+		return fmt.Errorf("cannot change the service CIDR from %s to %s, it can only be expanded to a larger CIDR starting at %s", previous, current, prevNet.IP)
+	}
+	return nil
+}
+
 // DNSAddress calculates the 10th address of configured service CIDR block.
 func (n *Network) DNSAddress() (string, error) {
 	_, ipnet, err := net.ParseCIDR(n.ServiceCIDR)
@@ -153,6 +251,16 @@ func (n *Network) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		n.KubeProxy = DefaultKubeProxy()
 	}
 
+	if n.CNIConfDir == "" {
+		n.CNIConfDir = defaultCNIConfDir
+	}
+	if n.CNIBinDir == "" {
+		n.CNIBinDir = defaultCNIBinDir
+	}
+	if n.NodePortRange == "" {
+		n.NodePortRange = defaultNodePortRange
+	}
+
 	return nil
 }
 
@@ -171,6 +279,12 @@ func (n *Network) BuildServiceCIDR(addr string) string {
 	return n.ServiceCIDR + "," + n.DualStack.IPv6ServiceCIDR
 }
 
+// NetworkCIDRs are the cluster CIDRs published to the workers for checking them against the host networks
+type NetworkCIDRs struct {
+	PodCIDRs     []string `yaml:"podCIDRs"`
+	ServiceCIDRs []string `yaml:"serviceCIDRs"`
+}
+
 // BuildPodCIDR returns actual argument value for pod cidr
 func (n *Network) BuildPodCIDR() string {
 	if n.DualStack.Enabled {
@@ -19,6 +19,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+
+	"github.com/k0sproject/k0s/pkg/constant"
 )
 
 type NetworkSuite struct {
@@ -176,6 +178,28 @@ spec:
 	s.True(p.Disabled)
 }
 
+func (s *NetworkSuite) TestCNIDirDefaults() {
+	yamlData := `
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: foobar
+spec:
+  network:
+    cniConfDir: /var/lib/k0s/cni/net.d
+`
+
+	c, err := configFromString(yamlData, k0sVars)
+	s.NoError(err)
+	n := c.Spec.Network
+
+	s.Equal("/var/lib/k0s/cni/net.d", n.CNIConfDir)
+	s.Equal("/opt/cni/bin", n.CNIBinDir)
+
+	n.CNIBinDir = "relative/bin"
+	s.Len(n.Validate(), 1)
+}
+
 func (s *NetworkSuite) TestValidation() {
 	s.T().Run("defaults_are_valid", func(t *testing.T) {
 		n := DefaultNetwork()
@@ -271,6 +295,71 @@ func (s *NetworkSuite) TestValidation() {
 	})
 }
 
+func (s *NetworkSuite) TestNodePortRange() {
+	s.T().Run("default", func(t *testing.T) {
+		first, last, err := DefaultNetwork().NodePorts()
+		s.NoError(err)
+		s.Equal(30000, first)
+		s.Equal(32767, last)
+	})
+	s.T().Run("invalid", func(t *testing.T) {
+		for _, r := range []string{"30000", "a-b", "32767-30000", "0-100", "30000-70000"} {
+			n := DefaultNetwork()
+			n.NodePortRange = r
+			errors := n.Validate()
+			s.Len(errors, 1, r)
+		}
+	})
+	s.T().Run("unmarshal_sets_default", func(t *testing.T) {
+		c, err := configFromString(`
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: foobar
+spec:
+  network:
+    provider: calico
+`, constant.GetConfig(""))
+		s.NoError(err)
+		s.Equal("30000-32767", c.Spec.Network.NodePortRange)
+	})
+}
+
+func (s *NetworkSuite) TestValidateChange() {
+	s.T().Run("expand_service_cidr", func(t *testing.T) {
+		n := DefaultNetwork()
+		n.ServiceCIDR = "10.96.0.0/11"
+		n.NodePortRange = "20000-32767"
+		s.Empty(n.ValidateChange(DefaultNetwork()))
+	})
+	s.T().Run("shrink_service_cidr", func(t *testing.T) {
+		n := DefaultNetwork()
+		n.ServiceCIDR = "10.96.0.0/13"
+		errors := n.ValidateChange(DefaultNetwork())
+		s.Len(errors, 1)
+		s.Contains(errors[0].Error(), "can only be expanded")
+	})
+	s.T().Run("move_service_cidr", func(t *testing.T) {
+		n := DefaultNetwork()
+		n.ServiceCIDR = "10.112.0.0/12"
+		s.Len(n.ValidateChange(DefaultNetwork()), 1)
+	})
+	s.T().Run("move_dns_address", func(t *testing.T) {
+		previous := DefaultNetwork()
+		previous.ServiceCIDR = "10.96.0.0/29"
+		n := DefaultNetwork()
+		n.ServiceCIDR = "10.96.0.0/28"
+		errors := n.ValidateChange(previous)
+		s.Len(errors, 1)
+		s.Contains(errors[0].Error(), "DNS address")
+	})
+	s.T().Run("change_pod_cidr", func(t *testing.T) {
+		n := DefaultNetwork()
+		n.PodCIDR = "10.245.0.0/16"
+		s.Len(n.ValidateChange(DefaultNetwork()), 1)
+	})
+}
+
 func TestNetworkSuite(t *testing.T) {
 	ns := &NetworkSuite{}
 
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var _ Validateable = (*NodeGCSpec)(nil)
+
+// NodeGCSpec configures the garbage collection of the node objects whose machines are gone
+type NodeGCSpec struct {
+	Enabled bool `yaml:"enabled"`
+	// UnreachableFor is how long a node has to be not ready before its machine is checked
+	UnreachableFor string `yaml:"unreachableFor,omitempty"`
+	// Webhook confirms that the machine of a node is gone
+	Webhook *NodeGCWebhook `yaml:"webhook,omitempty"`
+}
+
+// NodeGCWebhook is called with the unreachable nodes and tells if their machines are gone
+type NodeGCWebhook struct {
+	URL     string `yaml:"url"`
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// DefaultNodeGCSpec creates the disabled node garbage collection config
+func DefaultNodeGCSpec() *NodeGCSpec {
+	return &NodeGCSpec{
+		UnreachableFor: "1h",
+	}
+}
+
+// IsEnabled tells if the node garbage collection is enabled
+func (n *NodeGCSpec) IsEnabled() bool {
+	return n != nil && n.Enabled
+}
+
+// UnreachableForDuration returns the parsed unreachable period
+func (n *NodeGCSpec) UnreachableForDuration() time.Duration {
+	d, err := time.ParseDuration(n.UnreachableFor)
+	if err != nil || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// TimeoutDuration returns the parsed timeout of the webhook calls
+func (w *NodeGCWebhook) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(w.Timeout)
+	if err != nil || d <= 0 {
+		return 10 * time.Second
+	}
+	return d
+}
+
+// Validate validates the node garbage collection config, a check for the machines is required
+func (n *NodeGCSpec) Validate() []error {
+	if !n.IsEnabled() {
+		return nil
+	}
+	var errors []error
+	if n.UnreachableFor != "" {
+		if _, err := time.ParseDuration(n.UnreachableFor); err != nil {
+			errors = append(errors, fmt.Errorf("spec.nodeGC.unreachableFor: %w", err))
+		}
+	}
+	if n.Webhook == nil || n.Webhook.URL == "" {
+		errors = append(errors, fmt.Errorf("spec.nodeGC.webhook.url: a check for the machines of the nodes is required"))
+		return errors
+	}
+	if u, err := url.Parse(n.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		errors = append(errors, fmt.Errorf("spec.nodeGC.webhook.url: %q is not a valid http(s) url", n.Webhook.URL))
+	}
+	if n.Webhook.Timeout != "" {
+		if _, err := time.ParseDuration(n.Webhook.Timeout); err != nil {
+			errors = append(errors, fmt.Errorf("spec.nodeGC.webhook.timeout: %w", err))
+		}
+	}
+	return errors
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeGCValidate(t *testing.T) {
+	assert.Empty(t, DefaultNodeGCSpec().Validate())
+
+	// a check for the machines is required, unreachable nodes are never deleted on their own
+	assert.Len(t, (&NodeGCSpec{Enabled: true}).Validate(), 1)
+	assert.Len(t, (&NodeGCSpec{Enabled: true, Webhook: &NodeGCWebhook{URL: "ftp://example.com"}}).Validate(), 1)
+	assert.Len(t, (&NodeGCSpec{Enabled: true, UnreachableFor: "soon", Webhook: &NodeGCWebhook{URL: "https://example.com", Timeout: "1m"}}).Validate(), 1)
+
+	spec := &NodeGCSpec{Enabled: true, UnreachableFor: "30m", Webhook: &NodeGCWebhook{URL: "https://example.com/check"}}
+	assert.Empty(t, spec.Validate())
+	assert.Equal(t, 30*time.Minute, spec.UnreachableForDuration())
+	assert.Equal(t, 10*time.Second, spec.Webhook.TimeoutDuration())
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var _ Validateable = (*OIDCProviderSpec)(nil)
+
+// OIDCProviderSpec configures the OIDC identity provider embedded into the k0s API
+type OIDCProviderSpec struct {
+	Enabled  bool          `yaml:"enabled"`
+	ClientID string        `yaml:"clientID,omitempty"`
+	TokenTTL string        `yaml:"tokenTTL,omitempty"`
+	Users    []OIDCUser    `yaml:"users,omitempty"`
+	LDAP     *OIDCLDAPSpec `yaml:"ldap,omitempty"`
+}
+
+// OIDCUser is a static user of the OIDC provider
+type OIDCUser struct {
+	Username     string   `yaml:"username"`
+	PasswordHash string   `yaml:"passwordHash"`
+	Groups       []string `yaml:"groups,omitempty"`
+}
+
+// OIDCLDAPSpec authenticates the users of the OIDC provider against an LDAP server
+type OIDCLDAPSpec struct {
+	URL                string `yaml:"url"`
+	BindDNTemplate     string `yaml:"bindDNTemplate"`
+	GroupBaseDN        string `yaml:"groupBaseDN,omitempty"`
+	GroupMemberAttr    string `yaml:"groupMemberAttribute,omitempty"`
+	GroupNameAttr      string `yaml:"groupNameAttribute,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+}
+
+// DefaultOIDCProviderSpec creates the disabled OIDC provider config
+func DefaultOIDCProviderSpec() *OIDCProviderSpec {
+	return &OIDCProviderSpec{
+		ClientID: "kubernetes",
+		TokenTTL: "1h",
+	}
+}
+
+// IssuerURL returns the issuer URL of the provider served by the k0s API
+func (o *OIDCProviderSpec) IssuerURL(api *APISpec) string {
+	return api.K0sControlPlaneAPIAddress() + "/oidc"
+}
+
+// TokenTTLDuration returns the parsed lifetime of the issued tokens
+func (o *OIDCProviderSpec) TokenTTLDuration() time.Duration {
+	d, err := time.ParseDuration(o.TokenTTL)
+	if err != nil || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// Validate validates the users and the LDAP settings
+func (o *OIDCProviderSpec) Validate() []error {
+	if o == nil || !o.Enabled {
+		return nil
+	}
+	var errors []error
+	if o.ClientID == "" {
+		errors = append(errors, fmt.Errorf("spec.oidcProvider.clientID: must not be empty"))
+	}
+	if o.TokenTTL != "" {
+		if _, err := time.ParseDuration(o.TokenTTL); err != nil {
+			errors = append(errors, fmt.Errorf("spec.oidcProvider.tokenTTL: %w", err))
+		}
+	}
+	if len(o.Users) == 0 && o.LDAP == nil {
+		errors = append(errors, fmt.Errorf("spec.oidcProvider: either users or ldap must be configured"))
+	}
+	seen := map[string]bool{}
+	for _, u := range o.Users {
+		if u.Username == "" || seen[u.Username] {
+			errors = append(errors, fmt.Errorf("spec.oidcProvider.users: username %q is empty or not unique", u.Username))
+		}
+		seen[u.Username] = true
+		if !strings.HasPrefix(u.PasswordHash, "$2") {
+			errors = append(errors, fmt.Errorf("spec.oidcProvider.users: the password of %q must be a bcrypt hash", u.Username))
+		}
+	}
+	if o.LDAP != nil {
+		u, err := url.Parse(o.LDAP.URL)
+		if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			errors = append(errors, fmt.Errorf("spec.oidcProvider.ldap.url: %q is not a ldap:// or ldaps:// URL", o.LDAP.URL))
+		}
+		if strings.Count(o.LDAP.BindDNTemplate, "%s") != 1 {
+			errors = append(errors, fmt.Errorf("spec.oidcProvider.ldap.bindDNTemplate: must contain exactly one %%s for the username"))
+		}
+	}
+	return errors
+}
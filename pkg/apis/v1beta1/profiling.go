@@ -0,0 +1,39 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+// ProfilingSpec enables the pprof endpoints of k0s and the control plane components
+type ProfilingSpec struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// DefaultProfilingSpec creates the disabled profiling config
+func DefaultProfilingSpec() *ProfilingSpec {
+	return &ProfilingSpec{}
+}
+
+// IsEnabled tells if the profiling endpoints are enabled
+func (p *ProfilingSpec) IsEnabled() bool {
+	return p != nil && p.Enabled
+}
+
+// ProfilingArg returns the value of the profiling flags of the control plane components
+func (p *ProfilingSpec) ProfilingArg() string {
+	if p.IsEnabled() {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var _ Validateable = (*RemoteWriteSpec)(nil)
+
+// RemoteWriteSpec configures the push of a minimal set of fleet metrics to a Prometheus remote-write endpoint, for
+// the clusters where running a monitoring stack isn't feasible
+type RemoteWriteSpec struct {
+	Enabled bool   `yaml:"enabled"`
+	URL     string `yaml:"url,omitempty"`
+	// Interval is how often the metrics are collected and pushed
+	Interval string `yaml:"interval,omitempty"`
+	// BearerTokenFile holds the token sent to the endpoint, if any
+	BearerTokenFile string `yaml:"bearerTokenFile,omitempty"`
+	// CAFile holds the CA certificates the https endpoint is verified with, instead of the ones of the host
+	CAFile string `yaml:"caFile,omitempty"`
+	// Labels are added to every series, along with the cluster name
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// DefaultRemoteWriteSpec creates the disabled remote-write config
+func DefaultRemoteWriteSpec() *RemoteWriteSpec {
+	return &RemoteWriteSpec{
+		Interval: "5m",
+	}
+}
+
+// IsEnabled tells if the remote-write is enabled
+func (r *RemoteWriteSpec) IsEnabled() bool {
+	return r != nil && r.Enabled
+}
+
+// IntervalDuration returns the parsed push interval
+func (r *RemoteWriteSpec) IntervalDuration() time.Duration {
+	d, err := time.ParseDuration(r.Interval)
+	if err != nil || d <= 0 {
+		return 5 * time.Minute
+	}
+	return d
+}
+
+// Validate validates the remote-write config
+func (r *RemoteWriteSpec) Validate() []error {
+	if !r.IsEnabled() {
+		return nil
+	}
+	var errors []error
+	if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errors = append(errors, fmt.Errorf("spec.remoteWrite.url: %q is not a valid http(s) url", r.URL))
+	} else if r.CAFile != "" && u.Scheme != "https" {
+		errors = append(errors, fmt.Errorf("spec.remoteWrite.caFile: can only be used with an https url"))
+	}
+	if r.Interval != "" {
+		if d, err := time.ParseDuration(r.Interval); err != nil {
+			errors = append(errors, fmt.Errorf("spec.remoteWrite.interval: %w", err))
+		} else if d < time.Minute {
+			errors = append(errors, fmt.Errorf("spec.remoteWrite.interval: %s is shorter than the minimum of 1m", d))
+		}
+	}
+	for name := range r.Labels {
+		if name == "cluster" || name == "instance" || name == "__name__" {
+			errors = append(errors, fmt.Errorf("spec.remoteWrite.labels: %s is set by k0s", name))
+		}
+	}
+	return errors
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteWriteValidate(t *testing.T) {
+	assert.Empty(t, DefaultRemoteWriteSpec().Validate())
+
+	assert.Len(t, (&RemoteWriteSpec{Enabled: true}).Validate(), 1)
+	assert.Len(t, (&RemoteWriteSpec{Enabled: true, URL: "https://metrics.example.com/api/v1/write", Interval: "10s"}).Validate(), 1)
+	assert.Len(t, (&RemoteWriteSpec{Enabled: true, URL: "https://metrics.example.com/api/v1/write", Labels: map[string]string{"cluster": "edge"}}).Validate(), 1)
+	assert.Len(t, (&RemoteWriteSpec{Enabled: true, URL: "http://metrics.example.com/api/v1/write", CAFile: "/etc/k0s/metrics-ca.crt"}).Validate(), 1)
+
+	spec := &RemoteWriteSpec{Enabled: true, URL: "https://metrics.example.com/api/v1/write", CAFile: "/etc/k0s/metrics-ca.crt", Labels: map[string]string{"site": "store-42"}}
+	assert.Empty(t, spec.Validate())
+	assert.Equal(t, 5*time.Minute, spec.IntervalDuration())
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// The ports of the controller components which aren't configurable
+const (
+	etcdClientPort        = 2379
+	etcdPeerPort          = 2380
+	controllerManagerPort = 10257
+	schedulerPort         = 10259
+)
+
+// validateSemantics checks the parts of the config against each other: the networks must not overlap, the
+// controller components must not listen on the same ports and the features must be supported together
+func (c *ClusterConfig) validateSemantics() []error {
+	var errors []error
+	errors = append(errors, c.validateNetworkOverlaps()...)
+	errors = append(errors, c.validatePorts()...)
+	errors = append(errors, c.validateCombinations()...)
+	return errors
+}
+
+func (c *ClusterConfig) validateNetworkOverlaps() []error {
+	n := c.Spec.Network
+	if n == nil {
+		return nil
+	}
+	var errors []error
+	if err := cidrOverlap("spec.network.podCIDR", n.PodCIDR, "spec.network.serviceCIDR", n.ServiceCIDR); err != nil {
+		errors = append(errors, err)
+	}
+	if n.DualStack.Enabled {
+		if err := cidrOverlap("spec.network.dualStack.IPv6podCIDR", n.DualStack.IPv6PodCIDR, "spec.network.dualStack.IPv6serviceCIDR", n.DualStack.IPv6ServiceCIDR); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// cidrOverlap returns an error if the CIDRs overlap, the invalid CIDRs are left to the validation of the network
+func cidrOverlap(field1, cidr1, field2, cidr2 string) error {
+	_, net1, err := net.ParseCIDR(cidr1)
+	if err != nil {
+		return nil
+	}
+	_, net2, err := net.ParseCIDR(cidr2)
+	if err != nil {
+		return nil
+	}
+	if net1.Contains(net2.IP) || net2.Contains(net1.IP) {
+		return fmt.Errorf("%s: %s overlaps with %s %s, the pod and the service CIDRs must be separate", field1, cidr1, field2, cidr2)
+	}
+	return nil
+}
+
+func (c *ClusterConfig) validatePorts() []error {
+	ports := map[int][]string{
+		controllerManagerPort: {"kube-controller-manager"},
+		schedulerPort:         {"kube-scheduler"},
+	}
+	if c.Spec.Storage != nil && c.Spec.Storage.Type == EtcdStorageType {
+		ports[etcdClientPort] = append(ports[etcdClientPort], "etcd client")
+		ports[etcdPeerPort] = append(ports[etcdPeerPort], "etcd peer")
+	}
+
+	var errors []error
+	configured := func(field string, port int) {
+		if port == 0 {
+			// unset, the default is used
+			return
+		}
+		if port < 0 || port > 65535 {
+			errors = append(errors, fmt.Errorf("%s: %d is not a valid port", field, port))
+			return
+		}
+		ports[port] = append(ports[port], field)
+	}
+	if c.Spec.API != nil {
+		configured("spec.api.port", c.Spec.API.Port)
+		configured("spec.api.k0sApiPort", c.Spec.API.K0sAPIPort)
+	}
+	if c.Spec.Konnectivity != nil {
+		configured("spec.konnectivity.agentPort", int(c.Spec.Konnectivity.AgentPort))
+		configured("spec.konnectivity.adminPort", int(c.Spec.Konnectivity.AdminPort))
+	}
+
+	clashing := make([]int, 0, len(ports))
+	for port, users := range ports {
+		if len(users) > 1 {
+			clashing = append(clashing, port)
+		}
+	}
+	sort.Ints(clashing)
+	for _, port := range clashing {
+		errors = append(errors, fmt.Errorf("port %d is used by more than one component: %v, give each of them a port of its own", port, ports[port]))
+	}
+	return errors
+}
+
+func (c *ClusterConfig) validateCombinations() []error {
+	var errors []error
+	n := c.Spec.Network
+	if n != nil && n.DualStack.Enabled && n.Provider == "kuberouter" {
+		errors = append(errors, fmt.Errorf("spec.network.dualStack: dual-stack isn't supported with the kuberouter provider, use calico or a custom CNI"))
+	}
+	errors = append(errors, c.validateDisabledComponents()...)
+	return errors
+}
+
+// validateDisabledComponents checks that the config doesn't rely on the disabled components
+func (c *ClusterConfig) validateDisabledComponents() []error {
+	components := c.Spec.Components
+	var errors []error
+	if h := c.Spec.Extensions; components.IsDisabled(HelmComponent) && h != nil && h.Helm != nil && (len(h.Helm.Charts) > 0 || len(h.Helm.Repositories) > 0) {
+		errors = append(errors, fmt.Errorf("spec.components.disabled: %s is needed for spec.extensions.helm, remove the charts and the repositories or keep it enabled", HelmComponent))
+	}
+	if p := c.Spec.PodSecurityPolicy; components.IsDisabled(DefaultPSPComponent) && p != nil && p.DefaultPolicy != "" && p.DefaultPolicy != constant.DefaultPSP {
+		errors = append(errors, fmt.Errorf("spec.components.disabled: %s is needed for spec.podSecurityPolicy.defaultPolicy %s, remove it or keep the component enabled", DefaultPSPComponent, p.DefaultPolicy))
+	}
+	return errors
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSemantics(t *testing.T) {
+	assert.Empty(t, DefaultClusterConfig(k0sVars).validateSemantics())
+
+	t.Run("overlapping CIDRs", func(t *testing.T) {
+		c := DefaultClusterConfig(k0sVars)
+		c.Spec.Network.PodCIDR = "10.96.0.0/16"
+		errors := c.validateSemantics()
+		assert.Len(t, errors, 1)
+		assert.Contains(t, errors[0].Error(), "spec.network.podCIDR: 10.96.0.0/16 overlaps with spec.network.serviceCIDR 10.96.0.0/12")
+	})
+
+	t.Run("clashing ports", func(t *testing.T) {
+		c := DefaultClusterConfig(k0sVars)
+		c.Spec.API.K0sAPIPort = c.Spec.API.Port
+		c.Spec.Konnectivity.AdminPort = etcdPeerPort
+		errors := c.validateSemantics()
+		assert.Len(t, errors, 2)
+		assert.Contains(t, errors[0].Error(), "port 2380 is used by more than one component: [etcd peer spec.konnectivity.adminPort]")
+		assert.Contains(t, errors[1].Error(), "port 6443 is used by more than one component: [spec.api.port spec.api.k0sApiPort]")
+
+		// etcd isn't run with kine
+		c.Spec.Storage.Type = KineStorageType
+		assert.Len(t, c.validateSemantics(), 1)
+	})
+
+	t.Run("unsupported combinations", func(t *testing.T) {
+		c := DefaultClusterConfig(k0sVars)
+		c.Spec.Network.DualStack = DualStack{Enabled: true, IPv6PodCIDR: "fd00::/108", IPv6ServiceCIDR: "fd00::/108"}
+		assert.Len(t, c.validateSemantics(), 2)
+	})
+
+	t.Run("disabled components", func(t *testing.T) {
+		c := DefaultClusterConfig(k0sVars)
+		c.Spec.Components.Disabled = []string{HelmComponent, DefaultPSPComponent}
+		assert.Empty(t, c.validateSemantics())
+
+		c.Spec.Extensions = &ClusterExtensions{Helm: &HelmExtensions{Charts: []Chart{{Name: "prometheus"}}}}
+		c.Spec.PodSecurityPolicy.DefaultPolicy = "99-k0s-restricted"
+		errors := c.validateSemantics()
+		assert.Len(t, errors, 2)
+		assert.Contains(t, errors[0].Error(), "helm is needed for spec.extensions.helm")
+		assert.Contains(t, errors[1].Error(), "default-psp is needed for spec.podSecurityPolicy.defaultPolicy 99-k0s-restricted")
+	})
+}
@@ -16,12 +16,13 @@ limitations under the License.
 package v1beta1
 
 import (
-	"strings"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/kine"
 )
 
 // supported storage types
@@ -44,6 +45,21 @@ type KineConfig struct {
 	DataSource string `yaml:"dataSource"`
 }
 
+// Validate checks that the kine data source is usable by its driver
+func (k *KineConfig) Validate() []error {
+	if k == nil || k.DataSource == "" {
+		return []error{fmt.Errorf("spec.storage.kine.dataSource: a data source is required with the kine storage")}
+	}
+	driver, err := kine.Lookup(k.DataSource)
+	if err == nil {
+		_, err = driver.Endpoint(k.DataSource)
+	}
+	if err != nil {
+		return []error{fmt.Errorf("spec.storage.kine.dataSource: %w", err)}
+	}
+	return nil
+}
+
 // DefaultStorageSpec creates StorageSpec with sane defaults
 func DefaultStorageSpec(k0sVars constant.CfgVars) *StorageSpec {
 	if k0sVars.DefaultStorageType == KineStorageType {
@@ -63,20 +79,12 @@ func (s *StorageSpec) IsJoinable() bool {
 	if s.Type == EtcdStorageType {
 		return true
 	}
-
-	if strings.HasPrefix(s.Kine.DataSource, "sqlite://") {
+	if s.Kine == nil {
 		return false
 	}
 
-	if strings.HasPrefix(s.Kine.DataSource, "mysql://") {
-		return true
-	}
-
-	if strings.HasPrefix(s.Kine.DataSource, "postgres://") {
-		return true
-	}
-
-	return false
+	driver, err := kine.Lookup(s.Kine.DataSource)
+	return err == nil && driver.Joinable()
 }
 
 // UnmarshalYAML sets in some sane defaults when unmarshaling the data from yaml
@@ -95,12 +103,42 @@ func (s *StorageSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // Validate validates storage specs correctness
 func (s *StorageSpec) Validate() []error {
-	return nil
+	switch s.Type {
+	case EtcdStorageType:
+	case KineStorageType:
+		return s.Kine.Validate()
+	default:
+		return []error{fmt.Errorf("spec.storage.type: %q is not supported, use %q or %q", s.Type, EtcdStorageType, KineStorageType)}
+	}
+	if s.Etcd == nil {
+		return nil
+	}
+	var errors []error
+	if s.Etcd.Compaction != nil {
+		errors = append(errors, s.Etcd.Compaction.Validate()...)
+	}
+	if s.Etcd.Tuning != nil {
+		errors = append(errors, s.Etcd.Tuning.Validate()...)
+	}
+	if s.Etcd.Snapshots != nil {
+		errors = append(errors, s.Etcd.Snapshots.Validate()...)
+	}
+	return errors
 }
 
 // EtcdConfig defines etcd related config options
 type EtcdConfig struct {
 	PeerAddress string `yaml:"peerAddress"`
+	// Learner makes the controller join etcd as a non-voting member, its API server uses the voting members
+	Learner bool `yaml:"learner,omitempty"`
+	// ExternalClientAccess serves the etcd clients on the peer address too, needed on the voting members for learners
+	ExternalClientAccess bool `yaml:"externalClientAccess,omitempty"`
+	// Compaction enables the etcd auto compaction, the API server compacts etcd periodically regardless of it
+	Compaction *EtcdCompactionSpec `yaml:"compaction,omitempty"`
+	// Tuning overrides the timeouts and the quota tuned by the hardware class detected when etcd is started
+	Tuning *EtcdTuningSpec `yaml:"tuning,omitempty"`
+	// Snapshots enables the periodic etcd snapshots
+	Snapshots *EtcdSnapshotsSpec `yaml:"snapshots,omitempty"`
 }
 
 // DefaultEtcdConfig creates EtcdConfig with sane defaults
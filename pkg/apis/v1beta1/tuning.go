@@ -0,0 +1,210 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the watch caches are only scaled up for clusters with at least this many nodes
+const largeClusterNodes = 100
+
+// maxScaledWatchCacheSize caps the watch cache sizes scaled by the node count
+const maxScaledWatchCacheSize = 100000
+
+var watchCacheResourceRe = regexp.MustCompile(`^[a-z0-9]+(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// WatchCacheSpec defines the watch cache sizes of the API server
+type WatchCacheSpec struct {
+	// DefaultSize is the watch cache size of the resources without an explicit size, 0 scales it by the node count
+	DefaultSize int `yaml:"defaultSize,omitempty"`
+	// Sizes are the watch cache sizes per resource in the form of resource[.group]
+	Sizes map[string]int `yaml:"sizes,omitempty"`
+}
+
+// Validate validates the watch cache sizes
+func (w *WatchCacheSpec) Validate() []error {
+	var errors []error
+	if w.DefaultSize < 0 {
+		errors = append(errors, fmt.Errorf("spec.api.watchCache.defaultSize: must not be negative"))
+	}
+	for resource, size := range w.Sizes {
+		if !watchCacheResourceRe.MatchString(resource) {
+			errors = append(errors, fmt.Errorf("spec.api.watchCache.sizes: invalid resource %q, expected resource[.group]", resource))
+		}
+		if size < 0 {
+			errors = append(errors, fmt.Errorf("spec.api.watchCache.sizes: size of %s must not be negative", resource))
+		}
+	}
+	return errors
+}
+
+func scaledWatchCacheSize(size int) int {
+	if size > maxScaledWatchCacheSize {
+		return maxScaledWatchCacheSize
+	}
+	return size
+}
+
+// APIServerArgs returns the watch cache flags of the API server for a cluster with the given amount of nodes. The
+// API server defaults are kept for small clusters unless the sizes are configured.
+func (w *WatchCacheSpec) APIServerArgs(nodes int) map[string]string {
+	args := map[string]string{}
+	sizes := map[string]int{}
+	defaultSize := 0
+	if nodes >= largeClusterNodes {
+		defaultSize = scaledWatchCacheSize(5 * nodes)
+		sizes["nodes"] = scaledWatchCacheSize(5 * nodes)
+		sizes["pods"] = scaledWatchCacheSize(50 * nodes)
+	}
+
+	if w != nil {
+		if w.DefaultSize > 0 {
+			defaultSize = w.DefaultSize
+		}
+		for resource, size := range w.Sizes {
+			sizes[resource] = size
+		}
+	}
+
+	if defaultSize > 0 {
+		args["default-watch-cache-size"] = strconv.Itoa(defaultSize)
+	}
+	if len(sizes) > 0 {
+		resources := make([]string, 0, len(sizes))
+		for resource, size := range sizes {
+			resources = append(resources, fmt.Sprintf("%s#%d", resource, size))
+		}
+		sort.Strings(resources)
+		args["watch-cache-sizes"] = strings.Join(resources, ",")
+	}
+	return args
+}
+
+// supported etcd auto compaction modes
+const (
+	CompactionModePeriodic = "periodic"
+	CompactionModeRevision = "revision"
+)
+
+// EtcdCompactionSpec defines the etcd auto compaction
+type EtcdCompactionSpec struct {
+	// Mode is either periodic or revision
+	Mode string `yaml:"mode"`
+	// Retention is a duration such as 1h for the periodic mode and the amount of revisions to keep for the revision mode
+	Retention string `yaml:"retention"`
+}
+
+// DefaultEtcdCompactionSpec creates EtcdCompactionSpec with sane defaults
+func DefaultEtcdCompactionSpec() *EtcdCompactionSpec {
+	return &EtcdCompactionSpec{
+		Mode:      CompactionModePeriodic,
+		Retention: "1h",
+	}
+}
+
+// Validate validates the etcd auto compaction settings
+func (e *EtcdCompactionSpec) Validate() []error {
+	switch e.Mode {
+	case CompactionModePeriodic:
+		if _, err := time.ParseDuration(e.Retention); err != nil {
+			if hours, err := strconv.Atoi(e.Retention); err != nil || hours < 0 {
+				return []error{fmt.Errorf("spec.storage.etcd.compaction.retention: %q is not a duration", e.Retention)}
+			}
+		}
+	case CompactionModeRevision:
+		if revisions, err := strconv.ParseInt(e.Retention, 10, 64); err != nil || revisions <= 0 {
+			return []error{fmt.Errorf("spec.storage.etcd.compaction.retention: %q is not a positive amount of revisions", e.Retention)}
+		}
+	default:
+		return []error{fmt.Errorf("spec.storage.etcd.compaction.mode: unsupported mode %q, supported modes are %q and %q", e.Mode, CompactionModePeriodic, CompactionModeRevision)}
+	}
+	return nil
+}
+
+// EtcdArgs returns the auto compaction flags of etcd
+func (e *EtcdCompactionSpec) EtcdArgs() map[string]string {
+	return map[string]string{
+		"--auto-compaction-mode":      e.Mode,
+		"--auto-compaction-retention": e.Retention,
+	}
+}
+
+// UnmarshalYAML sets the defaults for the fields left out
+func (e *EtcdCompactionSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*e = *DefaultEtcdCompactionSpec()
+
+	type ycompaction EtcdCompactionSpec
+	yc := (*ycompaction)(e)
+	return unmarshal(yc)
+}
+
+// the bounds of the etcd tuning overrides, etcd itself refuses election timeouts above 50s. The election timeout is
+// at least five times the heartbeat interval, and ten times by default, which bounds the heartbeat interval.
+const (
+	minEtcdHeartbeatInterval = 10 * time.Millisecond
+	maxEtcdHeartbeatInterval = 5 * time.Second
+	maxEtcdElectionTimeout   = 50 * time.Second
+	maxEtcdQuotaBackendBytes = 8 * 1024 * 1024 * 1024
+)
+
+// EtcdTuningSpec overrides the etcd timeouts and quota that are otherwise tuned by the detected hardware class
+type EtcdTuningSpec struct {
+	// HeartbeatInterval is a duration such as 100ms
+	HeartbeatInterval string `yaml:"heartbeatInterval,omitempty"`
+	// ElectionTimeout is a duration such as 1s, at least five times the heartbeat interval
+	ElectionTimeout string `yaml:"electionTimeout,omitempty"`
+	// QuotaBackendBytes is the size limit of the etcd database
+	QuotaBackendBytes int64 `yaml:"quotaBackendBytes,omitempty"`
+}
+
+// Validate validates the etcd tuning overrides
+func (e *EtcdTuningSpec) Validate() []error {
+	var errors []error
+	heartbeat, err := parseOptionalDuration(e.HeartbeatInterval)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.heartbeatInterval: %q is not a duration", e.HeartbeatInterval))
+	} else if heartbeat != 0 && heartbeat < minEtcdHeartbeatInterval {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.heartbeatInterval: must be at least %s", minEtcdHeartbeatInterval))
+	} else if heartbeat > maxEtcdHeartbeatInterval {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.heartbeatInterval: must be at most %s", maxEtcdHeartbeatInterval))
+	}
+	election, err := parseOptionalDuration(e.ElectionTimeout)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.electionTimeout: %q is not a duration", e.ElectionTimeout))
+	} else if election > maxEtcdElectionTimeout {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.electionTimeout: must be at most %s", maxEtcdElectionTimeout))
+	}
+	if heartbeat != 0 && election != 0 && election < 5*heartbeat {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.electionTimeout: must be at least five times the heartbeat interval"))
+	}
+	if e.QuotaBackendBytes < 0 || e.QuotaBackendBytes > maxEtcdQuotaBackendBytes {
+		errors = append(errors, fmt.Errorf("spec.storage.etcd.tuning.quotaBackendBytes: must be between 0 and %d", int64(maxEtcdQuotaBackendBytes)))
+	}
+	return errors
+}
+
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -17,6 +17,13 @@ package v1beta1
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 var _ Validateable = (*WorkerProfiles)(nil)
@@ -37,8 +44,112 @@ func (wps WorkerProfiles) Validate() []error {
 
 // WorkerProfile worker profile
 type WorkerProfile struct {
-	Name   string                 `yaml:"name"`
-	Values map[string]interface{} `yaml:"values"`
+	Name    string                 `yaml:"name"`
+	Values  map[string]interface{} `yaml:"values"`
+	Seccomp *SeccompSpec           `yaml:"seccomp,omitempty"`
+	// ReadinessGate keeps the workers using the profile cordoned after a restart until the local health checks pass
+	ReadinessGate *ReadinessGateSpec `yaml:"readinessGate,omitempty"`
+	// Registries configures the image registries of the containerd run by k0s on the workers using the profile
+	Registries *RegistriesSpec `yaml:"registries,omitempty"`
+	// NodeLabels are set on the nodes of the workers using the profile when they register
+	NodeLabels map[string]string `yaml:"nodeLabels,omitempty"`
+	// NodeTaints are set on the nodes of the workers using the profile when they register, as key=value:Effect
+	NodeTaints []string `yaml:"nodeTaints,omitempty"`
+}
+
+// NodeTaint is a taint the kubelet registers its node with
+type NodeTaint struct {
+	Key    string `yaml:"key"`
+	Value  string `yaml:"value,omitempty"`
+	Effect string `yaml:"effect"`
+}
+
+// ParseNodeTaint parses a taint given as key=value:Effect or key:Effect
+func ParseNodeTaint(taint string) (NodeTaint, error) {
+	var t NodeTaint
+	i := strings.LastIndex(taint, ":")
+	if i < 0 {
+		return t, fmt.Errorf("invalid taint `%s`, expected key=value:Effect", taint)
+	}
+	t.Effect = taint[i+1:]
+	t.Key = taint[:i]
+	if j := strings.Index(t.Key, "="); j >= 0 {
+		t.Key, t.Value = t.Key[:j], t.Key[j+1:]
+	}
+	switch t.Effect {
+	case "NoSchedule", "PreferNoSchedule", "NoExecute":
+	default:
+		return t, fmt.Errorf("invalid effect `%s` of taint `%s`, use NoSchedule, PreferNoSchedule or NoExecute", t.Effect, taint)
+	}
+	if msgs := validation.IsQualifiedName(t.Key); len(msgs) > 0 {
+		return t, fmt.Errorf("invalid key of taint `%s`: %s", taint, strings.Join(msgs, ", "))
+	}
+	if msgs := validation.IsValidLabelValue(t.Value); len(msgs) > 0 {
+		return t, fmt.Errorf("invalid value of taint `%s`: %s", taint, strings.Join(msgs, ", "))
+	}
+	return t, nil
+}
+
+// String formats the taint as key=value:Effect
+func (t NodeTaint) String() string {
+	if t.Value == "" {
+		return t.Key + ":" + t.Effect
+	}
+	return t.Key + "=" + t.Value + ":" + t.Effect
+}
+
+// SeccompSpec defines the seccomp profiles distributed to the workers using the profile
+type SeccompSpec struct {
+	// Default is rejected: the SeccompDefault feature is only known to the kubelet from 1.22 on, k0s ships 1.21
+	Default bool `yaml:"default"`
+	// Profiles are the additional seccomp profiles, keyed by file name, written into the kubelet seccomp dir
+	Profiles map[string]string `yaml:"profiles,omitempty"`
+}
+
+// the health checks of the readiness gate
+const (
+	ReadinessCheckCRI = "cri"
+	ReadinessCheckCNI = "cni"
+	ReadinessCheckDNS = "dns"
+)
+
+// ReadinessGateSpec defines the health checks the worker runs before uncordoning its node
+type ReadinessGateSpec struct {
+	// Checks are the health checks to pass: cri, cni and dns. All of them are run if empty.
+	Checks []string `yaml:"checks,omitempty"`
+	// DNSName is the name resolved through the cluster DNS by the dns check (default: kubernetes.default.svc.<cluster domain>)
+	DNSName string `yaml:"dnsName,omitempty"`
+}
+
+// EnabledChecks returns the health checks to run
+func (r *ReadinessGateSpec) EnabledChecks() []string {
+	if len(r.Checks) == 0 {
+		return []string{ReadinessCheckCRI, ReadinessCheckCNI, ReadinessCheckDNS}
+	}
+	return r.Checks
+}
+
+// RegistriesSpec defines the registry mirrors and the registry TLS and auth settings, keyed by registry host
+type RegistriesSpec struct {
+	// Mirrors are the endpoints the images of the registry are pulled from, tried in order. The registry itself is
+	// tried last. An http:// endpoint makes an insecure registry.
+	Mirrors map[string][]string `yaml:"mirrors,omitempty"`
+	// Configs are the TLS and the auth settings of the registries and the mirror hosts
+	Configs map[string]RegistryConfig `yaml:"configs,omitempty"`
+}
+
+// RegistryConfig defines the TLS and the auth settings of a registry, the files are read on the worker
+type RegistryConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
+	CAFile             string `yaml:"caFile,omitempty"`
+	CertFile           string `yaml:"certFile,omitempty"`
+	KeyFile            string `yaml:"keyFile,omitempty"`
+	// Username and Password are published to the workers in the profile config map, use CredentialsFile to keep
+	// them out of the cluster
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// CredentialsFile is a file on the worker holding username:password
+	CredentialsFile string `yaml:"credentialsFile,omitempty"`
 }
 
 var lockedFields = map[string]struct{}{
@@ -55,5 +166,228 @@ func (wp *WorkerProfile) Validate() error {
 			return fmt.Errorf("field `%s` is prohibited to override in worker profile", field)
 		}
 	}
+	if err := validateKubeletValues(wp.Values); err != nil {
+		return fmt.Errorf("%w in worker profile %s", err, wp.Name)
+	}
+	if wp.Seccomp != nil {
+		if wp.Seccomp.Default {
+			return fmt.Errorf("`seccomp.default` is not supported in worker profile %s, the kubelet doesn't know the SeccompDefault feature before 1.22", wp.Name)
+		}
+		for name := range wp.Seccomp.Profiles {
+			if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+				return fmt.Errorf("invalid seccomp profile name `%s` in worker profile %s", name, wp.Name)
+			}
+		}
+	}
+	if wp.ReadinessGate != nil {
+		for _, check := range wp.ReadinessGate.Checks {
+			switch check {
+			case ReadinessCheckCRI, ReadinessCheckCNI, ReadinessCheckDNS:
+			default:
+				return fmt.Errorf("unknown readiness check `%s` in worker profile %s, supported checks: cri, cni, dns", check, wp.Name)
+			}
+		}
+	}
+	for key, value := range wp.NodeLabels {
+		if msgs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(value)...); len(msgs) > 0 {
+			return fmt.Errorf("invalid node label `%s=%s` in worker profile %s: %s", key, value, wp.Name, strings.Join(msgs, ", "))
+		}
+		if restrictedNodeLabel(key) {
+			return fmt.Errorf("node label `%s` in worker profile %s uses a prefix reserved for kubernetes, the kubelet only sets labels of the kubelet.kubernetes.io and node.kubernetes.io prefixes", key, wp.Name)
+		}
+	}
+	for _, taint := range wp.NodeTaints {
+		if _, err := ParseNodeTaint(taint); err != nil {
+			return fmt.Errorf("%w in worker profile %s", err, wp.Name)
+		}
+	}
+	if wp.Registries != nil {
+		return wp.Registries.validate(wp.Name)
+	}
+	return nil
+}
+
+// restrictedNodeLabel tells whether the kubelet refuses to register a node with the label,
+// which is the case for the kubernetes.io and k8s.io prefixes besides the kubelet and node ones
+func restrictedNodeLabel(key string) bool {
+	i := strings.Index(key, "/")
+	if i < 0 {
+		return false
+	}
+	prefix := key[:i]
+	for _, allowed := range []string{"kubelet.kubernetes.io", "node.kubernetes.io"} {
+		if prefix == allowed || strings.HasSuffix(prefix, "."+allowed) {
+			return false
+		}
+	}
+	for _, restricted := range []string{"kubernetes.io", "k8s.io"} {
+		if prefix == restricted || strings.HasSuffix(prefix, "."+restricted) {
+			return true
+		}
+	}
+	return false
+}
+
+// the kubelet cgroup drivers, the containerd run by k0s is configured with the driver of the profile
+const (
+	CgroupDriverCgroupfs = "cgroupfs"
+	CgroupDriverSystemd  = "systemd"
+)
+
+var (
+	evictionSignals = map[string]struct{}{
+		"memory.available":   {},
+		"nodefs.available":   {},
+		"nodefs.inodesFree":  {},
+		"imagefs.available":  {},
+		"imagefs.inodesFree": {},
+		"pid.available":      {},
+	}
+	reservedResources = map[string]struct{}{
+		"cpu":               {},
+		"memory":            {},
+		"ephemeral-storage": {},
+		"pid":               {},
+	}
+)
+
+// validateKubeletValues checks the kubelet settings commonly overridden in the profiles, so that a mistake fails the
+// validation of the cluster config instead of the kubelets of the workers. The other settings are passed on as is.
+func validateKubeletValues(values map[string]interface{}) error {
+	if v, found := values["maxPods"]; found {
+		if n, ok := toInt(v); !ok || n < 1 {
+			return fmt.Errorf("invalid maxPods `%v`, expected a positive integer", v)
+		}
+	}
+	if v, found := values["cgroupDriver"]; found {
+		if v != CgroupDriverCgroupfs && v != CgroupDriverSystemd {
+			return fmt.Errorf("invalid cgroupDriver `%v`, use cgroupfs or systemd", v)
+		}
+	}
+	if v, found := values["evictionPressureTransitionPeriod"]; found {
+		if _, err := time.ParseDuration(fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("invalid evictionPressureTransitionPeriod `%v`: %w", v, err)
+		}
+	}
+	for _, field := range []string{"evictionHard", "evictionSoft"} {
+		thresholds, err := stringMap(values, field)
+		if err != nil {
+			return err
+		}
+		for signal, threshold := range thresholds {
+			if _, found := evictionSignals[signal]; !found {
+				return fmt.Errorf("unknown eviction signal `%s` in %s", signal, field)
+			}
+			if err := validateThreshold(threshold); err != nil {
+				return fmt.Errorf("invalid %s threshold `%s` of %s: %w", field, threshold, signal, err)
+			}
+		}
+	}
+	soft, _ := stringMap(values, "evictionSoft")
+	gracePeriods, err := stringMap(values, "evictionSoftGracePeriod")
+	if err != nil {
+		return err
+	}
+	for signal := range soft {
+		if _, found := gracePeriods[signal]; !found {
+			return fmt.Errorf("no evictionSoftGracePeriod for the soft eviction threshold of %s", signal)
+		}
+	}
+	for signal, period := range gracePeriods {
+		if _, err := time.ParseDuration(period); err != nil {
+			return fmt.Errorf("invalid evictionSoftGracePeriod `%s` of %s: %w", period, signal, err)
+		}
+	}
+	for _, field := range []string{"kubeReserved", "systemReserved"} {
+		reserved, err := stringMap(values, field)
+		if err != nil {
+			return err
+		}
+		for name, quantity := range reserved {
+			if _, found := reservedResources[name]; !found {
+				return fmt.Errorf("unknown resource `%s` in %s, use cpu, memory, ephemeral-storage or pid", name, field)
+			}
+			if _, err := resource.ParseQuantity(quantity); err != nil {
+				return fmt.Errorf("invalid %s quantity `%s` of %s: %w", field, quantity, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateThreshold checks an eviction threshold, a quantity or a percentage
+func validateThreshold(threshold string) error {
+	if strings.HasSuffix(threshold, "%") {
+		percentage, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil || percentage < 0 || percentage > 100 {
+			return fmt.Errorf("expected a percentage between 0%% and 100%%")
+		}
+		return nil
+	}
+	_, err := resource.ParseQuantity(threshold)
+	return err
+}
+
+// stringMap returns the mapping of strings of the field, the YAML decoders have their own map types
+func stringMap(values map[string]interface{}, field string) (map[string]string, error) {
+	v, found := values[field]
+	if !found || v == nil {
+		return nil, nil
+	}
+	result := map[string]string{}
+	switch m := v.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			result[k] = fmt.Sprint(v)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range m {
+			result[fmt.Sprint(k)] = fmt.Sprint(v)
+		}
+	case map[string]string:
+		return m, nil
+	default:
+		return nil, fmt.Errorf("invalid %s, expected a mapping", field)
+	}
+	return result, nil
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	case float64:
+		return int64(n), n == float64(int64(n))
+	}
+	return 0, false
+}
+
+func (r *RegistriesSpec) validate(profile string) error {
+	for host, endpoints := range r.Mirrors {
+		if host == "" || strings.ContainsAny(host, `/"`) {
+			return fmt.Errorf("invalid registry host `%s` in the mirrors of worker profile %s", host, profile)
+		}
+		for _, endpoint := range endpoints {
+			u, err := url.Parse(endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid mirror endpoint `%s` of registry %s in worker profile %s, an http or https URL is needed", endpoint, host, profile)
+			}
+		}
+	}
+	for host, c := range r.Configs {
+		if host == "" || strings.ContainsAny(host, `/"`) {
+			return fmt.Errorf("invalid registry host `%s` in the configs of worker profile %s", host, profile)
+		}
+		if c.CredentialsFile != "" && (c.Username != "" || c.Password != "") {
+			return fmt.Errorf("registry %s in worker profile %s has both credentials and a credentials file, use one of them", host, profile)
+		}
+		if (c.CertFile == "") != (c.KeyFile == "") {
+			return fmt.Errorf("registry %s in worker profile %s needs both the certFile and the keyFile for the client certificate", host, profile)
+		}
+	}
 	return nil
 }
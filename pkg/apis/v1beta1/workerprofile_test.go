@@ -18,7 +18,7 @@ package v1beta1
 import (
 	"testing"
 
-	"github.com/magiconair/properties/assert"
+	"github.com/stretchr/testify/assert"
 )
 
 // TestWorkerProfile worker profile test suite
@@ -74,4 +74,133 @@ func TestWorkerProfile(t *testing.T) {
 			})
 		}
 	})
+	t.Run("seccomp_profile_names_validation", func(t *testing.T) {
+		cases := map[string]bool{
+			"audit.json":       true,
+			"../escape.json":   false,
+			"nested/file.json": false,
+			".hidden":          false,
+		}
+		for name, valid := range cases {
+			profile := WorkerProfile{
+				Seccomp: &SeccompSpec{Profiles: map[string]string{name: "{}"}},
+			}
+			assert.Equal(t, profile.Validate() == nil, valid, name)
+		}
+
+		profile := WorkerProfile{Seccomp: &SeccompSpec{Default: true}}
+		assert.Error(t, profile.Validate())
+	})
+	t.Run("readiness_gate_checks_validation", func(t *testing.T) {
+		profile := WorkerProfile{
+			ReadinessGate: &ReadinessGateSpec{Checks: []string{ReadinessCheckCRI, ReadinessCheckDNS}},
+		}
+		assert.NoError(t, profile.Validate())
+		assert.Equal(t, []string{"cri", "dns"}, profile.ReadinessGate.EnabledChecks())
+
+		profile.ReadinessGate.Checks = nil
+		assert.Equal(t, []string{"cri", "cni", "dns"}, profile.ReadinessGate.EnabledChecks())
+
+		profile.ReadinessGate.Checks = []string{"disk"}
+		assert.Error(t, profile.Validate())
+	})
+	t.Run("registries_validation", func(t *testing.T) {
+		profile := WorkerProfile{
+			Name: "edge",
+			Registries: &RegistriesSpec{
+				Mirrors: map[string][]string{"docker.io": {"https://mirror.local:5000", "http://10.0.0.1:5000"}},
+				Configs: map[string]RegistryConfig{"mirror.local:5000": {CAFile: "/etc/k0s/mirror-ca.crt", CredentialsFile: "/etc/k0s/mirror-auth"}},
+			},
+		}
+		assert.NoError(t, profile.Validate())
+
+		profile.Registries.Mirrors["docker.io"] = []string{"mirror.local:5000"}
+		assert.Error(t, profile.Validate())
+
+		profile.Registries.Mirrors["docker.io"] = nil
+		profile.Registries.Configs["mirror.local:5000"] = RegistryConfig{Username: "k0s", CredentialsFile: "/etc/k0s/mirror-auth"}
+		assert.Error(t, profile.Validate())
+	})
+	t.Run("node_labels_and_taints_validation", func(t *testing.T) {
+		profile := WorkerProfile{
+			Name:       "gpu",
+			NodeLabels: map[string]string{"example.com/gpu": "true"},
+			NodeTaints: []string{"example.com/gpu=true:NoSchedule", "dedicated:NoExecute"},
+		}
+		assert.NoError(t, profile.Validate())
+
+		profile.NodeTaints = []string{"example.com/gpu=true"}
+		assert.Error(t, profile.Validate())
+
+		profile.NodeTaints = nil
+		profile.NodeLabels = map[string]string{"example.com/gpu": "not valid"}
+		assert.Error(t, profile.Validate())
+		for _, key := range []string{"kubernetes.io/role", "node-role.kubernetes.io/gpu", "example.k8s.io/gpu"} {
+			profile.NodeLabels = map[string]string{key: "true"}
+			assert.Error(t, profile.Validate(), key)
+		}
+		for _, key := range []string{"node.kubernetes.io/gpu", "example.kubelet.kubernetes.io/gpu", "kubernetes.io.example.com/gpu"} {
+			profile.NodeLabels = map[string]string{key: "true"}
+			assert.NoError(t, profile.Validate(), key)
+		}
+	})
+	t.Run("parse_node_taint", func(t *testing.T) {
+		taint, err := ParseNodeTaint("example.com/gpu=true:NoSchedule")
+		assert.NoError(t, err)
+		assert.Equal(t, NodeTaint{Key: "example.com/gpu", Value: "true", Effect: "NoSchedule"}, taint)
+		assert.Equal(t, "example.com/gpu=true:NoSchedule", taint.String())
+
+		taint, err = ParseNodeTaint("dedicated:PreferNoSchedule")
+		assert.NoError(t, err)
+		assert.Equal(t, NodeTaint{Key: "dedicated", Effect: "PreferNoSchedule"}, taint)
+
+		_, err = ParseNodeTaint("dedicated=infra:Never")
+		assert.Error(t, err)
+	})
+	t.Run("kubelet_values_validation", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			values map[string]interface{}
+			valid  bool
+		}{
+			{"max pods", map[string]interface{}{"maxPods": 50}, true},
+			{"max pods from json", map[string]interface{}{"maxPods": float64(50)}, true},
+			{"zero max pods", map[string]interface{}{"maxPods": 0}, false},
+			{"max pods as string", map[string]interface{}{"maxPods": "many"}, false},
+			{"systemd cgroup driver", map[string]interface{}{"cgroupDriver": "systemd"}, true},
+			{"unknown cgroup driver", map[string]interface{}{"cgroupDriver": "cgroupv2"}, false},
+			{"hard eviction", map[string]interface{}{
+				"evictionHard": map[interface{}]interface{}{"memory.available": "500Mi", "nodefs.available": "10%"},
+			}, true},
+			{"unknown eviction signal", map[string]interface{}{
+				"evictionHard": map[string]interface{}{"memory.free": "500Mi"},
+			}, false},
+			{"invalid eviction percentage", map[string]interface{}{
+				"evictionHard": map[string]interface{}{"nodefs.available": "110%"},
+			}, false},
+			{"soft eviction", map[string]interface{}{
+				"evictionSoft":            map[string]interface{}{"memory.available": "1Gi"},
+				"evictionSoftGracePeriod": map[string]interface{}{"memory.available": "1m30s"},
+			}, true},
+			{"soft eviction without grace period", map[string]interface{}{
+				"evictionSoft": map[string]interface{}{"memory.available": "1Gi"},
+			}, false},
+			{"eviction thresholds as list", map[string]interface{}{"evictionHard": []string{"memory.available<500Mi"}}, false},
+			{"reserved resources", map[string]interface{}{
+				"kubeReserved":   map[string]interface{}{"cpu": "200m", "memory": "512Mi"},
+				"systemReserved": map[string]interface{}{"ephemeral-storage": "1Gi", "pid": 1000},
+			}, true},
+			{"unknown reserved resource", map[string]interface{}{
+				"kubeReserved": map[string]interface{}{"gpu": "1"},
+			}, false},
+			{"invalid reserved quantity", map[string]interface{}{
+				"systemReserved": map[string]interface{}{"memory": "half"},
+			}, false},
+			{"other values are passed on", map[string]interface{}{"serializeImagePulls": false}, true},
+		}
+		for _, tc := range cases {
+			profile := WorkerProfile{Name: "custom", Values: tc.values}
+			assert.Equal(t, tc.valid, profile.Validate() == nil, tc.name)
+		}
+	})
 }
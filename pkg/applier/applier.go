@@ -36,6 +36,8 @@ import (
 type Applier struct {
 	Name string
 	Dir  string
+	// Throttle rate limits the API requests of the applier, no limits are applied if nil
+	Throttle *Throttle
 
 	log             *logrus.Entry
 	clientFactory   kubernetes.ClientFactory
@@ -109,6 +111,7 @@ func (a *Applier) Apply() error {
 		Resources: resources,
 		Client:    a.client,
 		Discovery: a.discoveryClient,
+		Throttle:  a.Throttle,
 	}
 	a.log.Debug("applying stack")
 	err = stack.Apply(context.Background(), true)
@@ -133,6 +136,7 @@ func (a *Applier) Delete() error {
 		Resources: []*unstructured.Unstructured{},
 		Client:    a.client,
 		Discovery: a.discoveryClient,
+		Throttle:  a.Throttle,
 	}
 	logrus.Debugf("about to delete a stack %s with empty apply", a.Name)
 	err = stack.Apply(context.Background(), true)
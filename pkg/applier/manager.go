@@ -40,8 +40,11 @@ type Manager struct {
 	cancelWatcher context.CancelFunc
 	log           *logrus.Entry
 	stacks        map[string]*StackApplier
+	throttle      *Throttle
 
 	LeaderElector controller.LeaderElector
+	// LowPower applies the stacks one at a time, collecting their changes for longer
+	LowPower bool
 }
 
 // Init initializes the Manager
@@ -55,6 +58,12 @@ func (m *Manager) Init() error {
 	m.bundlePath = m.K0sVars.ManifestsDir
 
 	m.applier = NewApplier(m.K0sVars.ManifestsDir, m.KubeClientFactory)
+	if m.LowPower {
+		m.throttle = NewThrottle(DefaultApplyQPS, DefaultApplyBurst, 1, DefaultStartupJitter)
+		m.throttle.Debounce = LowPowerDebounce
+	} else {
+		m.throttle = NewThrottle(DefaultApplyQPS, DefaultApplyBurst, DefaultConcurrentStacks, DefaultStartupJitter)
+	}
 
 	m.LeaderElector.AddAcquiredLeaseCallback(func() {
 		ctx, cancel := context.WithCancel(context.Background())
@@ -146,7 +155,7 @@ func (m *Manager) createStack(name string) error {
 		return nil
 	}
 	m.log.WithField("stack", name).Info("registering new stack")
-	sa, err := NewStackApplier(name, m.KubeClientFactory)
+	sa, err := NewStackApplier(name, m.KubeClientFactory, m.throttle)
 	if err != nil {
 		return err
 	}
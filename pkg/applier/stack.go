@@ -16,14 +16,15 @@ limitations under the License.
 package applier
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
-	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/sirupsen/logrus"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -34,6 +35,7 @@ import (
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
 
 	"github.com/k0sproject/k0s/internal/util"
 )
@@ -47,6 +49,9 @@ const (
 
 	// LastConfigAnnotation defines the annotation to be used for last applied configs
 	LastConfigAnnotation = "k0s.k0sproject.io/last-applied-configuration"
+
+	// FieldManager is the field manager of the server-side applied resources
+	FieldManager = "k0s"
 )
 
 // Stack is a k8s resource bundle
@@ -56,6 +61,8 @@ type Stack struct {
 	keepResources []string
 	Client        dynamic.Interface
 	Discovery     discovery.CachedDiscoveryInterface
+	// Throttle rate limits the API requests, no limits are applied if nil
+	Throttle *Throttle
 
 	log *logrus.Entry
 }
@@ -91,30 +98,36 @@ func (s *Stack) Apply(ctx context.Context, prune bool) error {
 		} else {
 			drClient = s.Client.Resource(mapping.Resource)
 		}
+		if err := s.Throttle.Wait(ctx); err != nil {
+			return err
+		}
 		serverResource, err := drClient.Get(ctx, resource.GetName(), metav1.GetOptions{})
 		if apiErrors.IsNotFound(err) {
-			_, err := drClient.Create(ctx, resource, metav1.CreateOptions{})
+			if err := s.Throttle.Wait(ctx); err != nil {
+				return err
+			}
+			// the fields set by the create are migrated to the apply manager on the first update
+			_, err := drClient.Create(ctx, resource, metav1.CreateOptions{FieldManager: FieldManager})
+			if apiErrors.IsAlreadyExists(err) {
+				err = s.applyResource(ctx, drClient, resource)
+			}
 			if err != nil {
 				return fmt.Errorf("cannot create resource %s: %s", resource.GetName(), err)
 			}
 		} else if err != nil {
 			return fmt.Errorf("unknown api error: %s", err)
-		} else { // The resource already exists, we need to update/patch it
+		} else { // The resource already exists, we need to update it
 			localChecksum := resource.GetAnnotations()[ChecksumAnnotation]
 			if serverResource.GetAnnotations()[ChecksumAnnotation] == localChecksum {
 				s.log.Debug("resource checksums match, no need to update")
 				s.keepResource(resource)
 				continue
 			}
-			if serverResource.GetAnnotations()[LastConfigAnnotation] == "" {
-				s.log.Debug("doing plain update as no last-config label present")
-				resource.SetResourceVersion(serverResource.GetResourceVersion())
-				_, err = drClient.Update(ctx, resource, metav1.UpdateOptions{})
-			} else {
-				s.log.Debug("patching resource")
-				err = s.patchResource(ctx, drClient, serverResource, resource)
+			if err := s.migrateManagedFields(ctx, drClient, serverResource); err != nil {
+				return fmt.Errorf("can't migrate the managed fields of resource %s: %v", resource.GetName(), err)
 			}
-			if err != nil {
+			s.log.Debug("applying resource server-side")
+			if err := s.applyResource(ctx, drClient, resource); err != nil {
 				return fmt.Errorf("can't update resource:%v", err)
 			}
 		}
@@ -254,6 +267,9 @@ func (s *Stack) deleteResource(ctx context.Context, mapper *restmapper.DeferredD
 	if err != nil {
 		return fmt.Errorf("failed to get dynamic client for resource %s: %w", resource.GetSelfLink(), err)
 	}
+	if err := s.Throttle.Wait(ctx); err != nil {
+		return err
+	}
 	err = drClient.Delete(ctx, resource.GetName(), metav1.DeleteOptions{
 		PropagationPolicy: &propagationPolicy,
 	})
@@ -300,6 +316,9 @@ func (s *Stack) getPruneableResources(ctx context.Context, drClient dynamic.Reso
 	listOpts := metav1.ListOptions{
 		LabelSelector: fmt.Sprintf("%s=%s", NameLabel, s.Name),
 	}
+	if err := s.Throttle.Wait(ctx); err != nil {
+		return nil
+	}
 	resourceList, err := drClient.List(ctx, listOpts)
 	if err != nil {
 		// FIXME why no error propagation !??!
@@ -327,25 +346,93 @@ func (s *Stack) isInStack(resource unstructured.Unstructured) bool {
 	return false
 }
 
-func (s *Stack) patchResource(ctx context.Context, drClient dynamic.ResourceInterface, serverResource *unstructured.Unstructured, localResource *unstructured.Unstructured) error {
-	original := serverResource.GetAnnotations()[LastConfigAnnotation]
-	if original == "" {
-		return fmt.Errorf("%s does not have last-applied-configuration", localResource.GetSelfLink())
-	}
-	modified, _ := localResource.MarshalJSON()
-
-	patch, err := jsonpatch.CreateMergePatch([]byte(original), modified)
+// applyResource updates the resource with a server-side apply, which takes a single request regardless of the
+// state of the resource on the server
+func (s *Stack) applyResource(ctx context.Context, drClient dynamic.ResourceInterface, resource *unstructured.Unstructured) error {
+	data, err := resource.MarshalJSON()
 	if err != nil {
-		return fmt.Errorf("failed to create jsonpatch data: %w", err)
+		return fmt.Errorf("failed to marshal resource: %w", err)
 	}
-	_, err = drClient.Patch(ctx, localResource.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	if err := s.Throttle.Wait(ctx); err != nil {
+		return err
+	}
+	force := true
+	_, err = drClient.Patch(ctx, resource.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &force,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to patch resource: %w", err)
+		return fmt.Errorf("failed to apply resource: %w", err)
 	}
 
 	return nil
 }
 
+// migrateManagedFields hands the fields set by the earlier client-side creates and patches of k0s over to its
+// server-side apply manager. Otherwise they stay owned by the old manager too and the fields removed from the
+// manifests are never pruned by the server-side apply.
+func (s *Stack) migrateManagedFields(ctx context.Context, drClient dynamic.ResourceInterface, serverResource *unstructured.Unstructured) error {
+	managedFields, migrated, err := migratedManagedFields(serverResource.GetManagedFields(), serverResource.GetAPIVersion())
+	if err != nil || !migrated {
+		return err
+	}
+	s.log.Debugf("migrating the managed fields of %s to server-side apply", generateResourceID(*serverResource))
+	patch, err := json.Marshal([]map[string]interface{}{
+		// the managed fields are only replaced if nobody has changed the resource in the meantime
+		{"op": "test", "path": "/metadata/resourceVersion", "value": serverResource.GetResourceVersion()},
+		{"op": "replace", "path": "/metadata/managedFields", "value": managedFields},
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Throttle.Wait(ctx); err != nil {
+		return err
+	}
+	_, err = drClient.Patch(ctx, serverResource.GetName(), types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// migratedManagedFields merges the fields of the update entries of the k0s field manager into its apply entry.
+// It returns false if there's nothing to migrate.
+func migratedManagedFields(entries []metav1.ManagedFieldsEntry, apiVersion string) ([]metav1.ManagedFieldsEntry, bool, error) {
+	var managedFields []metav1.ManagedFieldsEntry
+	fields := &fieldpath.Set{}
+	migrated := false
+	for _, entry := range entries {
+		if entry.Manager != FieldManager {
+			managedFields = append(managedFields, entry)
+			continue
+		}
+		if entry.Operation == metav1.ManagedFieldsOperationUpdate {
+			migrated = true
+		}
+		if entry.FieldsV1 != nil {
+			set := &fieldpath.Set{}
+			if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+				return nil, false, fmt.Errorf("failed to parse the managed fields of %s: %w", entry.Manager, err)
+			}
+			fields = fields.Union(set)
+		}
+	}
+	if !migrated {
+		return entries, false, nil
+	}
+
+	raw, err := fields.ToJSON()
+	if err != nil {
+		return nil, false, err
+	}
+	now := metav1.Now()
+	return append(managedFields, metav1.ManagedFieldsEntry{
+		Manager:    FieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: apiVersion,
+		Time:       &now,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: raw},
+	}), true, nil
+}
+
 func (s *Stack) prepareResource(resource *unstructured.Unstructured) {
 	checksum := resourceChecksum(resource)
 	lastAppliedConfig, _ := resource.MarshalJSON()
@@ -0,0 +1,56 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package applier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMigratedManagedFields(t *testing.T) {
+	entry := func(manager string, op metav1.ManagedFieldsOperationType, fields string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager:    manager,
+			Operation:  op,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+		}
+	}
+	kubectl := entry("kubectl", metav1.ManagedFieldsOperationUpdate, `{"f:data":{"f:extra":{}}}`)
+
+	// the create and the merge patches of k0s are merged into its apply entry, the other managers are kept
+	managedFields, migrated, err := migratedManagedFields([]metav1.ManagedFieldsEntry{
+		entry(FieldManager, metav1.ManagedFieldsOperationUpdate, `{"f:data":{"f:old":{}}}`),
+		kubectl,
+		entry(FieldManager, metav1.ManagedFieldsOperationApply, `{"f:data":{"f:new":{}}}`),
+	}, "v1")
+	require.NoError(t, err)
+	assert.True(t, migrated)
+	require.Len(t, managedFields, 2)
+	assert.Equal(t, kubectl, managedFields[0])
+	assert.Equal(t, FieldManager, managedFields[1].Manager)
+	assert.Equal(t, metav1.ManagedFieldsOperationApply, managedFields[1].Operation)
+	assert.JSONEq(t, `{"f:data":{"f:new":{},"f:old":{}}}`, string(managedFields[1].FieldsV1.Raw))
+
+	// once migrated, there's nothing left to do
+	_, migrated, err = migratedManagedFields(managedFields, "v1")
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
@@ -32,12 +32,13 @@ type StackApplier struct {
 
 	fsWatcher *fsnotify.Watcher
 	applier   Applier
+	throttle  *Throttle
 	log       *logrus.Entry
 	done      chan bool
 }
 
-// NewStackApplier crates new stack applier to manage a stack
-func NewStackApplier(path string, kubeClientFactory kubernetes.ClientFactory) (*StackApplier, error) {
+// NewStackApplier crates new stack applier to manage a stack, the throttle is shared with the other stack appliers
+func NewStackApplier(path string, kubeClientFactory kubernetes.ClientFactory, throttle *Throttle) (*StackApplier, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
@@ -47,6 +48,7 @@ func NewStackApplier(path string, kubeClientFactory kubernetes.ClientFactory) (*
 		return nil, err
 	}
 	applier := NewApplier(path, kubeClientFactory)
+	applier.Throttle = throttle
 	log := logrus.WithField("component", "applier-"+applier.Name)
 	log.WithField("path", path).Debug("created stack applier")
 
@@ -54,6 +56,7 @@ func NewStackApplier(path string, kubeClientFactory kubernetes.ClientFactory) (*
 		Path:      path,
 		fsWatcher: watcher,
 		applier:   applier,
+		throttle:  throttle,
 		log:       log,
 		done:      make(chan bool, 1),
 	}, nil
@@ -61,8 +64,10 @@ func NewStackApplier(path string, kubeClientFactory kubernetes.ClientFactory) (*
 
 // Start both the initial apply and also the watch for a single stack
 func (s *StackApplier) Start() error {
-	debouncer := debounce.New(5*time.Second, s.fsWatcher.Events, func(arg fsnotify.Event) {
+	debouncer := debounce.New(s.throttle.debounce(), s.fsWatcher.Events, func(arg fsnotify.Event) {
 		s.log.Debug("debouncer triggering, applying...")
+		s.throttle.acquire()
+		defer s.throttle.release()
 		err := retry.OnError(retry.DefaultRetry, func(err error) bool {
 			return true
 		}, s.applier.Apply)
@@ -73,8 +78,20 @@ func (s *StackApplier) Start() error {
 	defer debouncer.Stop()
 	go debouncer.Start()
 
-	// apply all changes on start
-	s.fsWatcher.Events <- fsnotify.Event{}
+	// apply all changes on start, spread over the startup jitter so that the stacks aren't all applied at once
+	go func() {
+		timer := time.NewTimer(s.throttle.startupDelay())
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.done:
+			return
+		}
+		select {
+		case s.fsWatcher.Events <- fsnotify.Event{}:
+		case <-s.done:
+		}
+	}()
 
 	<-s.done
 
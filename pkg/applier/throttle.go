@@ -0,0 +1,98 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package applier
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// the default limits of the manifest application, low enough for the boot time apply of all the stacks not to trip
+// the API server priority and fairness on small controllers
+const (
+	DefaultApplyQPS   = 10
+	DefaultApplyBurst = 20
+	// DefaultConcurrentStacks is the amount of stacks applied at the same time
+	DefaultConcurrentStacks = 2
+	// DefaultStartupJitter spreads the initial apply of the stacks
+	DefaultStartupJitter = 10 * time.Second
+	// DefaultDebounce is how long the changes of a stack are collected before it's applied
+	DefaultDebounce = 5 * time.Second
+	// LowPowerDebounce batches the changes of the stacks in the low-power mode
+	LowPowerDebounce = 30 * time.Second
+)
+
+// Throttle is shared by the stack appliers to rate limit their API requests and the amount of stacks being applied
+// at the same time
+type Throttle struct {
+	StartupJitter time.Duration
+	// Debounce is how long the changes of a stack are collected before it's applied, DefaultDebounce if unset
+	Debounce time.Duration
+
+	requests flowcontrol.RateLimiter
+	stacks   chan struct{}
+}
+
+// NewThrottle creates a Throttle allowing qps API requests per second on average with the given burst, and
+// concurrentStacks stacks being applied at the same time
+func NewThrottle(qps float32, burst int, concurrentStacks int, startupJitter time.Duration) *Throttle {
+	return &Throttle{
+		StartupJitter: startupJitter,
+		requests:      flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		stacks:        make(chan struct{}, concurrentStacks),
+	}
+}
+
+// Wait blocks until the next API request is allowed
+func (t *Throttle) Wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.requests.Wait(ctx)
+}
+
+// acquire blocks until the stack can be applied
+func (t *Throttle) acquire() {
+	if t != nil {
+		t.stacks <- struct{}{}
+	}
+}
+
+// release allows the next stack to be applied
+func (t *Throttle) release() {
+	if t != nil {
+		<-t.stacks
+	}
+}
+
+// startupDelay returns a random delay for the initial apply of a stack
+func (t *Throttle) startupDelay() time.Duration {
+	if t == nil || t.StartupJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.StartupJitter)))
+}
+
+// debounce returns how long the changes of a stack are collected before it's applied
+func (t *Throttle) debounce() time.Duration {
+	if t == nil || t.Debounce <= 0 {
+		return DefaultDebounce
+	}
+	return t.Debounce
+}
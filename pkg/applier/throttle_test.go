@@ -0,0 +1,60 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package applier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleLimitsConcurrentStacks(t *testing.T) {
+	throttle := NewThrottle(DefaultApplyQPS, DefaultApplyBurst, 1, 0)
+	throttle.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		throttle.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second stack applied while the first one is still being applied")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	throttle.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second stack not applied after the first one was done")
+	}
+}
+
+func TestThrottleStartupDelay(t *testing.T) {
+	var throttle *Throttle
+	assert.Equal(t, time.Duration(0), throttle.startupDelay())
+	assert.NoError(t, throttle.Wait(context.Background()))
+
+	throttle = NewThrottle(DefaultApplyQPS, DefaultApplyBurst, DefaultConcurrentStacks, time.Second)
+	for i := 0; i < 100; i++ {
+		delay := throttle.startupDelay()
+		assert.True(t, delay >= 0 && delay < time.Second, "delay %s out of the jitter", delay)
+	}
+}
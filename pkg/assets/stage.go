@@ -22,6 +22,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
@@ -119,6 +121,27 @@ func Stage(dataDir string, name string, filemode os.FileMode) error {
 	return nil
 }
 
+// EmbeddedBinaries returns the names of the binaries embedded into the k0s executable
+func EmbeddedBinaries() []string {
+	var names []string
+	for gzname := range BinData {
+		names = append(names, strings.TrimSuffix(strings.TrimPrefix(gzname, "bin/"), ".gz"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StageAll stages all the embedded binaries into the given directory and returns their names
+func StageAll(dataDir string, filemode os.FileMode) ([]string, error) {
+	names := EmbeddedBinaries()
+	for _, name := range names {
+		if err := Stage(dataDir, name, filemode); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
 func copyTo(p string, gz io.Reader) error {
 	_ = os.Remove(p)
 	f, err := os.Create(p)
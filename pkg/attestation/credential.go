@@ -0,0 +1,82 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// credentialMagic and credentialVersion are the header of the credential files of tpm2-tools
+const (
+	credentialMagic   uint32 = 0xBADCC0DE
+	credentialVersion uint32 = 1
+)
+
+// kdfa is the key derivation function of the TPM 2.0 specification with SHA256 as the hash
+func kdfa(key []byte, label string, contextU, contextV []byte, bits int) []byte {
+	out := make([]byte, 0, bits/8)
+	for counter := uint32(1); len(out) < bits/8; counter++ {
+		h := hmac.New(sha256.New, key)
+		_ = binary.Write(h, binary.BigEndian, counter)
+		h.Write([]byte(label))
+		h.Write([]byte{0})
+		h.Write(contextU)
+		h.Write(contextV)
+		_ = binary.Write(h, binary.BigEndian, uint32(bits))
+		out = h.Sum(out)
+	}
+	return out[:bits/8]
+}
+
+// makeCredential protects the secret so that it can only be recovered by the TPM holding both the endorsement key
+// and the key with the given name, like TPM2_MakeCredential. The endorsement key is expected to use the default
+// AES-128 CFB symmetric parameters. It returns the credential in the file format of tpm2-tools.
+func makeCredential(ek *rsa.PublicKey, name []byte, secret []byte) ([]byte, error) {
+	seed := make([]byte, sha256.Size)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	encryptedSeed, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, ek, seed, []byte("IDENTITY\x00"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt the credential seed: %w", err)
+	}
+
+	block, err := aes.NewCipher(kdfa(seed, "STORAGE", name, nil, 128))
+	if err != nil {
+		return nil, err
+	}
+	identity := tpm2b(secret)
+	encIdentity := make([]byte, len(identity))
+	cipher.NewCFBEncrypter(block, make([]byte, aes.BlockSize)).XORKeyStream(encIdentity, identity)
+
+	mac := hmac.New(sha256.New, kdfa(seed, "INTEGRITY", nil, nil, sha256.Size*8))
+	mac.Write(encIdentity)
+	mac.Write(name)
+	idObject := append(tpm2b(mac.Sum(nil)), encIdentity...)
+
+	blob := make([]byte, 8)
+	binary.BigEndian.PutUint32(blob, credentialMagic)
+	binary.BigEndian.PutUint32(blob[4:], credentialVersion)
+	blob = append(blob, tpm2b(idObject)...)
+	return append(blob, tpm2b(encryptedSeed)...), nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EnrollmentConfigMap holds the enrolled endorsement keys in kube-system, keyed by their fingerprint
+const EnrollmentConfigMap = "k0s-tpm-enrollment"
+
+// Enrollment manages the endorsement keys of the workers allowed to join with TPM attestation
+type Enrollment struct {
+	client kubernetes.Interface
+}
+
+// NewEnrollment creates a new enrollment using the given client
+func NewEnrollment(client kubernetes.Interface) *Enrollment {
+	return &Enrollment{client: client}
+}
+
+// IsEnrolled checks if the endorsement key with the fingerprint is enrolled
+func (e *Enrollment) IsEnrolled(fingerprint string) (bool, error) {
+	keys, err := e.List()
+	if err != nil {
+		return false, err
+	}
+	_, found := keys[fingerprint]
+	return found, nil
+}
+
+// List returns the PEM encoded enrolled endorsement keys keyed by their fingerprint
+func (e *Enrollment) List() (map[string]string, error) {
+	cm, err := e.client.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), EnrollmentConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cm.Data, nil
+}
+
+// Enroll adds the PEM encoded endorsement key and returns its fingerprint
+func (e *Enrollment) Enroll(ekPEM []byte) (string, error) {
+	fingerprint, _, err := ParseEK(ekPEM)
+	if err != nil {
+		return "", err
+	}
+
+	configMaps := e.client.CoreV1().ConfigMaps("kube-system")
+	cm, err := configMaps.Get(context.TODO(), EnrollmentConfigMap, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: EnrollmentConfigMap, Namespace: "kube-system"},
+			Data:       map[string]string{fingerprint: string(ekPEM)},
+		}
+		_, err = configMaps.Create(context.TODO(), cm, metav1.CreateOptions{})
+		return fingerprint, err
+	}
+	if err != nil {
+		return "", err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[fingerprint] = string(ekPEM)
+	_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return fingerprint, err
+}
+
+// Remove removes the endorsement key with the fingerprint
+func (e *Enrollment) Remove(fingerprint string) error {
+	configMaps := e.client.CoreV1().ConfigMaps("kube-system")
+	cm, err := configMaps.Get(context.TODO(), EnrollmentConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if _, found := cm.Data[fingerprint]; !found {
+		return fmt.Errorf("endorsement key %s is not enrolled", fingerprint)
+	}
+	delete(cm.Data, fingerprint)
+	_, err = configMaps.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// the subset of the TPM 2.0 constants needed for verifying the attestation
+const (
+	algRSA    uint16 = 0x0001
+	algSHA256 uint16 = 0x000B
+	algNull   uint16 = 0x0010
+	algRSASSA uint16 = 0x0014
+	algRSAPSS uint16 = 0x0016
+
+	generatedValue uint32 = 0xff544347
+	stAttestQuote  uint16 = 0x8018
+
+	attrFixedTPM            uint32 = 1 << 1
+	attrFixedParent         uint32 = 1 << 4
+	attrSensitiveDataOrigin uint32 = 1 << 5
+	attrRestricted          uint32 = 1 << 16
+	attrSign                uint32 = 1 << 18
+
+	// an attestation key must be a restricted signing key generated by and bound to the TPM
+	akAttributes = attrFixedTPM | attrFixedParent | attrSensitiveDataOrigin | attrRestricted | attrSign
+)
+
+// reader decodes the big-endian TPM structures, the first error is kept and stops the decoding
+type reader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func newReader(data []byte) *reader {
+	return &reader{r: bytes.NewReader(data)}
+}
+
+func (r *reader) read(v interface{}) {
+	if r.err == nil {
+		r.err = binary.Read(r.r, binary.BigEndian, v)
+	}
+}
+
+func (r *reader) u16() uint16 {
+	var v uint16
+	r.read(&v)
+	return v
+}
+
+func (r *reader) u32() uint32 {
+	var v uint32
+	r.read(&v)
+	return v
+}
+
+// tpm2b reads a sized buffer
+func (r *reader) tpm2b() []byte {
+	size := int(r.u16())
+	if r.err != nil {
+		return nil
+	}
+	if size > r.r.Len() {
+		r.err = fmt.Errorf("buffer of %d bytes exceeds the remaining %d bytes", size, r.r.Len())
+		return nil
+	}
+	buf := make([]byte, size)
+	r.read(buf)
+	return buf
+}
+
+func (r *reader) skip(n int) {
+	if r.err == nil && n > r.r.Len() {
+		r.err = fmt.Errorf("can't skip %d bytes, only %d remaining", n, r.r.Len())
+		return
+	}
+	r.read(make([]byte, n))
+}
+
+// tpm2b encodes a sized buffer
+func tpm2b(data []byte) []byte {
+	buf := make([]byte, 2+len(data))
+	binary.BigEndian.PutUint16(buf, uint16(len(data)))
+	copy(buf[2:], data)
+	return buf
+}
+
+// akPublic is the decoded public area of an attestation key
+type akPublic struct {
+	// Name identifies the key within the TPM, it's the name algorithm followed by the digest of the public area
+	Name       []byte
+	Attributes uint32
+	Key        *rsa.PublicKey
+}
+
+// parseAKPublic decodes a TPM2B_PUBLIC of a RSA attestation key with a SHA256 name
+func parseAKPublic(data []byte) (*akPublic, error) {
+	outer := newReader(data)
+	area := outer.tpm2b()
+	if outer.err != nil {
+		return nil, fmt.Errorf("invalid TPM2B_PUBLIC: %w", outer.err)
+	}
+
+	r := newReader(area)
+	keyType := r.u16()
+	nameAlg := r.u16()
+	attributes := r.u32()
+	r.tpm2b() // authPolicy
+	if r.err == nil && keyType != algRSA {
+		return nil, fmt.Errorf("unsupported attestation key type 0x%04x, only RSA keys are supported", keyType)
+	}
+	if r.err == nil && nameAlg != algSHA256 {
+		return nil, fmt.Errorf("unsupported attestation key name algorithm 0x%04x, only SHA256 is supported", nameAlg)
+	}
+	if symmetric := r.u16(); symmetric != algNull {
+		r.skip(4) // keyBits and mode
+	}
+	if scheme := r.u16(); scheme != algNull {
+		r.skip(2) // hashAlg
+	}
+	r.u16() // keyBits
+	exponent := r.u32()
+	modulus := r.tpm2b()
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid TPMT_PUBLIC: %w", r.err)
+	}
+	if exponent == 0 {
+		exponent = 65537
+	}
+
+	digest := sha256.Sum256(area)
+	name := make([]byte, 2, 2+len(digest))
+	binary.BigEndian.PutUint16(name, nameAlg)
+	return &akPublic{
+		Name:       append(name, digest[:]...),
+		Attributes: attributes,
+		Key:        &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(exponent)},
+	}, nil
+}
+
+// quote is the decoded TPMS_ATTEST of a quote
+type quote struct {
+	ExtraData []byte
+	PCRDigest []byte
+}
+
+// parseQuote decodes a TPMS_ATTEST structure and checks that it was generated by a TPM for a quote
+func parseQuote(data []byte) (*quote, error) {
+	r := newReader(data)
+	magic := r.u32()
+	attestType := r.u16()
+	if r.err == nil && magic != generatedValue {
+		return nil, fmt.Errorf("quote was not generated by a TPM")
+	}
+	if r.err == nil && attestType != stAttestQuote {
+		return nil, fmt.Errorf("unexpected attestation type 0x%04x", attestType)
+	}
+	r.tpm2b() // qualifiedSigner
+	q := &quote{ExtraData: r.tpm2b()}
+	r.skip(17) // clockInfo
+	r.skip(8)  // firmwareVersion
+	// TPML_PCR_SELECTION
+	count := r.u32()
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		r.u16() // hash
+		size := make([]byte, 1)
+		r.read(size)
+		r.skip(int(size[0]))
+	}
+	q.PCRDigest = r.tpm2b()
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid TPMS_ATTEST: %w", r.err)
+	}
+	return q, nil
+}
+
+// verifySignature checks the TPMT_SIGNATURE of a RSA attestation key over the data
+func verifySignature(key *rsa.PublicKey, data []byte, signature []byte) error {
+	r := newReader(signature)
+	sigAlg := r.u16()
+	hashAlg := r.u16()
+	sig := r.tpm2b()
+	if r.err != nil {
+		return fmt.Errorf("invalid TPMT_SIGNATURE: %w", r.err)
+	}
+	if hashAlg != algSHA256 {
+		return fmt.Errorf("unsupported signature hash algorithm 0x%04x", hashAlg)
+	}
+
+	digest := sha256.Sum256(data)
+	switch sigAlg {
+	case algRSASSA:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+	case algRSAPSS:
+		return rsa.VerifyPSS(key, crypto.SHA256, digest[:], sig, nil)
+	default:
+		return fmt.Errorf("unsupported signature algorithm 0x%04x", sigAlg)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// QuotedPCRs are the PCRs included in the quote of a joining worker
+const QuotedPCRs = "sha256:0,1,2,3,4,5,6,7"
+
+// TPM runs the TPM operations of a joining worker with tpm2-tools
+type TPM struct {
+	dir string
+}
+
+// NewTPM creates a TPM client keeping its working files in the given directory
+func NewTPM(dir string) (*TPM, error) {
+	if _, err := exec.LookPath("tpm2_createek"); err != nil {
+		return nil, fmt.Errorf("tpm2-tools are needed for the TPM attestation: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &TPM{dir: dir}, nil
+}
+
+func (t *TPM) run(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = t.dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (t *TPM) read(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(t.dir, name))
+}
+
+// EKPublic creates the endorsement key from the default template and returns its PEM encoded public part
+func (t *TPM) EKPublic() ([]byte, error) {
+	if err := t.run("tpm2_createek", "-c", "ek.ctx", "-G", "rsa", "-u", "ek.pem", "-f", "pem"); err != nil {
+		return nil, err
+	}
+	return t.read("ek.pem")
+}
+
+// AKPublic creates an attestation key under the endorsement key and returns its TPM2B_PUBLIC
+func (t *TPM) AKPublic() ([]byte, error) {
+	if err := t.run("tpm2_createak", "-C", "ek.ctx", "-c", "ak.ctx", "-G", "rsa", "-g", "sha256", "-s", "rsassa", "-u", "ak.pub", "-n", "ak.name"); err != nil {
+		return nil, err
+	}
+	return t.read("ak.pub")
+}
+
+// ActivateCredential recovers the secret of the credential, which only works on the TPM holding both the keys
+func (t *TPM) ActivateCredential(credential []byte) ([]byte, error) {
+	if err := ioutil.WriteFile(filepath.Join(t.dir, "credential.blob"), credential, 0600); err != nil {
+		return nil, err
+	}
+	// the endorsement key can only be used within a policy session authorized by the endorsement hierarchy
+	if err := t.run("tpm2_startauthsession", "--policy-session", "-S", "session.ctx"); err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = t.run("tpm2_flushcontext", "session.ctx")
+	}()
+	if err := t.run("tpm2_policysecret", "-S", "session.ctx", "-c", "e"); err != nil {
+		return nil, err
+	}
+	if err := t.run("tpm2_activatecredential", "-c", "ak.ctx", "-C", "ek.ctx", "-i", "credential.blob", "-o", "secret", "-P", "session:session.ctx"); err != nil {
+		return nil, err
+	}
+	return t.read("secret")
+}
+
+// Quote signs the PCR values and the nonce with the attestation key, it returns the TPMS_ATTEST and the TPMT_SIGNATURE
+func (t *TPM) Quote(nonce []byte) ([]byte, []byte, error) {
+	if err := t.run("tpm2_quote", "-c", "ak.ctx", "-l", QuotedPCRs, "-q", hex.EncodeToString(nonce), "-g", "sha256", "-m", "quote.msg", "-s", "quote.sig"); err != nil {
+		return nil, nil, err
+	}
+	quote, err := t.read("quote.msg")
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := t.read("quote.sig")
+	return quote, signature, err
+}
+
+// Close removes the working files
+func (t *TPM) Close() error {
+	return os.RemoveAll(t.dir)
+}
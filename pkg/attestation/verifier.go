@@ -0,0 +1,239 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// SessionTTL is the time the joining worker has for answering the challenge
+const SessionTTL = 5 * time.Minute
+
+// EnrolledKeys tells which endorsement keys are allowed to join
+type EnrolledKeys interface {
+	IsEnrolled(fingerprint string) (bool, error)
+}
+
+// Verifier verifies the TPM attestation of the joining workers. The challenges are stateless so that the worker
+// may answer them on any controller sharing the same key.
+type Verifier struct {
+	key      []byte
+	enrolled EnrolledKeys
+	now      func() time.Time
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+type session struct {
+	Nonce       []byte    `json:"nonce"`
+	Fingerprint string    `json:"fingerprint"`
+	AKName      []byte    `json:"akName"`
+	Expires     time.Time `json:"expires"`
+}
+
+// NewVerifier creates a verifier signing the challenges with the given key
+func NewVerifier(key []byte, enrolled EnrolledKeys) *Verifier {
+	return &Verifier{
+		key:      key,
+		enrolled: enrolled,
+		now:      time.Now,
+		used:     map[string]time.Time{},
+	}
+}
+
+// ParseEK decodes the PEM encoded public endorsement key and returns its fingerprint
+func ParseEK(data []byte) (string, *rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", nil, fmt.Errorf("no PEM encoded endorsement key found")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse the endorsement key: %w", err)
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported endorsement key type %T, only RSA keys are supported", pub)
+	}
+	digest := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(digest[:]), key, nil
+}
+
+// Challenge checks that the endorsement key is enrolled and creates a challenge that can only be solved by the TPM
+// holding both the endorsement key and the attestation key
+func (v *Verifier) Challenge(req v1beta1.AttestationChallengeRequest) (*v1beta1.AttestationChallengeResponse, error) {
+	fingerprint, ek, ak, err := v.parseKeys(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s := session{
+		Nonce:       make([]byte, 32),
+		Fingerprint: fingerprint,
+		AKName:      ak.Name,
+		Expires:     v.now().Add(SessionTTL),
+	}
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return nil, err
+	}
+	token, err := v.encodeSession(s)
+	if err != nil {
+		return nil, err
+	}
+	credential, err := makeCredential(ek, ak.Name, v.secret(token))
+	if err != nil {
+		return nil, err
+	}
+	return &v1beta1.AttestationChallengeResponse{
+		Session:    token,
+		Nonce:      s.Nonce,
+		Credential: credential,
+	}, nil
+}
+
+// Verify checks the answer to the challenge and returns the fingerprint of the attested endorsement key
+func (v *Verifier) Verify(req v1beta1.AttestationQuoteRequest) (string, error) {
+	s, err := v.decodeSession(req.Session)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, _, ak, err := v.parseKeys(req.AttestationChallengeRequest)
+	if err != nil {
+		return "", err
+	}
+	if fingerprint != s.Fingerprint || !bytes.Equal(ak.Name, s.AKName) {
+		return "", fmt.Errorf("the keys don't match the attestation session")
+	}
+	if !hmac.Equal(req.Secret, v.secret(req.Session)) {
+		return "", fmt.Errorf("the credential was not activated by the TPM holding the endorsement key")
+	}
+
+	q, err := parseQuote(req.Quote)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(q.ExtraData, s.Nonce) {
+		return "", fmt.Errorf("the quote doesn't include the nonce of the attestation session")
+	}
+	if err := verifySignature(ak.Key, req.Quote, req.Signature); err != nil {
+		return "", fmt.Errorf("invalid quote signature: %w", err)
+	}
+
+	if err := v.markUsed(req.Session, s.Expires); err != nil {
+		return "", err
+	}
+	return fingerprint, nil
+}
+
+func (v *Verifier) parseKeys(req v1beta1.AttestationChallengeRequest) (string, *rsa.PublicKey, *akPublic, error) {
+	fingerprint, ek, err := ParseEK(req.EKPublic)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	enrolled, err := v.enrolled.IsEnrolled(fingerprint)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to check the endorsement key enrollment: %w", err)
+	}
+	if !enrolled {
+		return "", nil, nil, fmt.Errorf("endorsement key %s is not enrolled", fingerprint)
+	}
+
+	ak, err := parseAKPublic(req.AKPublic)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if ak.Attributes&akAttributes != akAttributes {
+		return "", nil, nil, fmt.Errorf("the attestation key must be a restricted signing key bound to the TPM")
+	}
+	return fingerprint, ek, ak, nil
+}
+
+// secret is the value protected by the credential, it's derived from the session so that it needn't be stored
+func (v *Verifier) secret(token string) []byte {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte("secret:"))
+	mac.Write([]byte(token))
+	return mac.Sum(nil)
+}
+
+func (v *Verifier) sign(data string) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte("session:"))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (v *Verifier) encodeSession(s session) (string, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + v.sign(payload), nil
+}
+
+func (v *Verifier) decodeSession(token string) (*session, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(v.sign(parts[0]))) {
+		return nil, fmt.Errorf("invalid attestation session")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation session: %w", err)
+	}
+	s := &session{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid attestation session: %w", err)
+	}
+	if v.now().After(s.Expires) {
+		return nil, fmt.Errorf("the attestation session has expired")
+	}
+	return s, nil
+}
+
+// markUsed keeps the sessions from being answered twice on this controller
+func (v *Verifier) markUsed(token string, expires time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	for t, e := range v.used {
+		if now.After(e) {
+			delete(v.used, t)
+		}
+	}
+	if _, found := v.used[token]; found {
+		return fmt.Errorf("the attestation session was already used")
+	}
+	v.used[token] = expires
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package attestation
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+type fakeEnrollment map[string]bool
+
+func (f fakeEnrollment) IsEnrolled(fingerprint string) (bool, error) {
+	return f[fingerprint], nil
+}
+
+func be(values ...interface{}) []byte {
+	buf := new(bytes.Buffer)
+	for _, v := range values {
+		_ = binary.Write(buf, binary.BigEndian, v)
+	}
+	return buf.Bytes()
+}
+
+// fakeTPM does what a TPM would do with its endorsement and attestation keys
+type fakeTPM struct {
+	ek       *rsa.PrivateKey
+	ak       *rsa.PrivateKey
+	ekPEM    []byte
+	akPublic []byte
+}
+
+func newFakeTPM(t *testing.T) *fakeTPM {
+	ek, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ak, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ek.PublicKey)
+	require.NoError(t, err)
+
+	area := be(algRSA, algSHA256, akAttributes)
+	area = append(area, tpm2b(nil)...)
+	area = append(area, be(algNull, algRSASSA, algSHA256, uint16(2048), uint32(0))...)
+	area = append(area, tpm2b(ak.PublicKey.N.Bytes())...)
+
+	return &fakeTPM{
+		ek:       ek,
+		ak:       ak,
+		ekPEM:    pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		akPublic: tpm2b(area),
+	}
+}
+
+func (f *fakeTPM) activate(t *testing.T, credential []byte, name []byte) []byte {
+	r := newReader(credential)
+	assert.Equal(t, credentialMagic, r.u32())
+	assert.Equal(t, credentialVersion, r.u32())
+	idObject := r.tpm2b()
+	encryptedSeed := r.tpm2b()
+	require.NoError(t, r.err)
+
+	seed, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, f.ek, encryptedSeed, []byte("IDENTITY\x00"))
+	require.NoError(t, err)
+
+	r = newReader(idObject)
+	integrity := r.tpm2b()
+	require.NoError(t, r.err)
+	encIdentity := idObject[2+len(integrity):]
+	mac := hmac.New(sha256.New, kdfa(seed, "INTEGRITY", nil, nil, 256))
+	mac.Write(encIdentity)
+	mac.Write(name)
+	require.True(t, hmac.Equal(integrity, mac.Sum(nil)), "credential integrity")
+
+	block, err := aes.NewCipher(kdfa(seed, "STORAGE", name, nil, 128))
+	require.NoError(t, err)
+	identity := make([]byte, len(encIdentity))
+	cipher.NewCFBDecrypter(block, make([]byte, aes.BlockSize)).XORKeyStream(identity, encIdentity)
+	secret := newReader(identity).tpm2b()
+	require.NotEmpty(t, secret)
+	return secret
+}
+
+func (f *fakeTPM) quote(t *testing.T, nonce []byte) ([]byte, []byte) {
+	attest := be(generatedValue, stAttestQuote)
+	attest = append(attest, tpm2b([]byte("signer"))...)
+	attest = append(attest, tpm2b(nonce)...)
+	attest = append(attest, make([]byte, 17+8)...)
+	attest = append(attest, be(uint32(1), algSHA256, uint8(3), uint8(0xff), uint8(0), uint8(0))...)
+	attest = append(attest, tpm2b(make([]byte, 32))...)
+
+	digest := sha256.Sum256(attest)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.ak, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return attest, append(be(algRSASSA, algSHA256), tpm2b(sig)...)
+}
+
+func (f *fakeTPM) attest(t *testing.T, v *Verifier) v1beta1.AttestationQuoteRequest {
+	keys := v1beta1.AttestationChallengeRequest{EKPublic: f.ekPEM, AKPublic: f.akPublic}
+	challenge, err := v.Challenge(keys)
+	require.NoError(t, err)
+
+	ak, err := parseAKPublic(f.akPublic)
+	require.NoError(t, err)
+	quote, signature := f.quote(t, challenge.Nonce)
+	return v1beta1.AttestationQuoteRequest{
+		AttestationChallengeRequest: keys,
+		Session:                     challenge.Session,
+		Secret:                      f.activate(t, challenge.Credential, ak.Name),
+		Quote:                       quote,
+		Signature:                   signature,
+	}
+}
+
+func TestAttestation(t *testing.T) {
+	tpm := newFakeTPM(t)
+	fingerprint, _, err := ParseEK(tpm.ekPEM)
+	require.NoError(t, err)
+	v := NewVerifier([]byte("key"), fakeEnrollment{fingerprint: true})
+
+	req := tpm.attest(t, v)
+	attested, err := v.Verify(req)
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, attested)
+
+	_, err = v.Verify(req)
+	assert.Error(t, err, "sessions can't be replayed")
+
+	// another controller sharing the key accepts the session too
+	req = tpm.attest(t, v)
+	_, err = NewVerifier([]byte("key"), fakeEnrollment{fingerprint: true}).Verify(req)
+	assert.NoError(t, err)
+	_, err = NewVerifier([]byte("other"), fakeEnrollment{fingerprint: true}).Verify(req)
+	assert.Error(t, err)
+}
+
+func TestAttestationNotEnrolled(t *testing.T) {
+	tpm := newFakeTPM(t)
+	v := NewVerifier([]byte("key"), fakeEnrollment{})
+	_, err := v.Challenge(v1beta1.AttestationChallengeRequest{EKPublic: tpm.ekPEM, AKPublic: tpm.akPublic})
+	assert.Error(t, err)
+}
+
+func TestAttestationInvalidAnswers(t *testing.T) {
+	tpm := newFakeTPM(t)
+	fingerprint, _, err := ParseEK(tpm.ekPEM)
+	require.NoError(t, err)
+	v := NewVerifier([]byte("key"), fakeEnrollment{fingerprint: true})
+
+	t.Run("secret", func(t *testing.T) {
+		req := tpm.attest(t, v)
+		req.Secret = make([]byte, len(req.Secret))
+		_, err := v.Verify(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("nonce", func(t *testing.T) {
+		req := tpm.attest(t, v)
+		req.Quote, req.Signature = tpm.quote(t, []byte("stale nonce"))
+		_, err := v.Verify(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		req := tpm.attest(t, v)
+		req.Signature[len(req.Signature)-1] ^= 0xff
+		_, err := v.Verify(req)
+		assert.Error(t, err)
+	})
+
+	t.Run("other attestation key", func(t *testing.T) {
+		req := tpm.attest(t, v)
+		req.AKPublic = newFakeTPM(t).akPublic
+		_, err := v.Verify(req)
+		assert.Error(t, err)
+	})
+}
@@ -4,23 +4,31 @@ package backup
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 )
 
 type configurationStep struct {
 	path               string
+	tmpDir             string
 	restoredConfigPath string
+	rewriter           *pathRewriter
 }
 
-func newConfigurationStep(path string, restoredConfigPath string) *configurationStep {
+func newConfigurationStep(path string, tmpDir string, restoredConfigPath string, rewriter *pathRewriter) *configurationStep {
 	return &configurationStep{
 		path:               path,
+		tmpDir:             tmpDir,
 		restoredConfigPath: restoredConfigPath,
+		rewriter:           rewriter,
 	}
 }
 
@@ -29,6 +37,18 @@ func (c configurationStep) Name() string {
 }
 
 func (c configurationStep) Backup() (StepResult, error) {
+	if paths := strings.Split(c.path, ","); len(paths) > 1 || util.IsDirectory(c.path) {
+		// the merged config is backed up, so that it restores as a single k0s.yaml
+		data, err := v1beta1.MergeConfigFiles(paths)
+		if err != nil {
+			return StepResult{}, fmt.Errorf("can't backup `%s`: %v", c.path, err)
+		}
+		merged := filepath.Join(c.tmpDir, "k0s.yaml")
+		if err := ioutil.WriteFile(merged, data, 0640); err != nil {
+			return StepResult{}, fmt.Errorf("can't backup `%s`: %v", c.path, err)
+		}
+		return StepResult{filesForBackup: []string{merged}}, nil
+	}
 	_, err := os.Stat(c.path)
 	if os.IsNotExist(err) {
 		logrus.Info("default k0s.yaml is used, do not back it up")
@@ -50,5 +70,12 @@ func (c configurationStep) Restore(restoreFrom, restoreTo string) error {
 	logrus.Infof("Previously used k0s.yaml saved under the data directory `%s`", restoreTo)
 
 	logrus.Infof("restoring from `%s` to `%s`", objectPathInArchive, c.restoredConfigPath)
-	return util.FileCopy(objectPathInArchive, c.restoredConfigPath)
+	if c.rewriter == nil {
+		return util.FileCopy(objectPathInArchive, c.restoredConfigPath)
+	}
+	data, err := ioutil.ReadFile(objectPathInArchive)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.restoredConfigPath, c.rewriter.rewrite(data), 0640)
 }
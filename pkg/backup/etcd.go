@@ -83,7 +83,7 @@ func (e etcdStep) Restore(restoreFrom, _ string) error {
 	if err != nil {
 		return err
 	}
-	peerURL := fmt.Sprintf("https://%s:2380", e.peerAddress)
+	peerURL := etcd.PeerURL(e.peerAddress)
 	restoreConfig := snapshot.RestoreConfig{
 		SnapshotPath:   snapshotPath,
 		OutputDataDir:  e.etcdDataDir,
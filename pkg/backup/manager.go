@@ -23,8 +23,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -36,13 +36,14 @@ import (
 
 // Manager hold configuration for particular backup-restore process
 type Manager struct {
-	steps   []Backuper
-	tmpDir  string
-	dataDir string
+	steps    []Backuper
+	tmpDir   string
+	dataDir  string
+	rewriter *pathRewriter
 }
 
-// RunBackup backups cluster
-func (bm *Manager) RunBackup(cfgPath string, clusterSpec *v1beta1.ClusterSpec, vars constant.CfgVars, savePathDir string) error {
+// RunBackup backups cluster into savePath, a local directory or the location of a registered Storage
+func (bm *Manager) RunBackup(cfgPath string, clusterSpec *v1beta1.ClusterSpec, vars constant.CfgVars, savePath string) error {
 	bm.discoverSteps(cfgPath, clusterSpec, vars, "backup", "")
 	defer os.RemoveAll(bm.tmpDir)
 	assets := make([]string, 0, len(bm.steps))
@@ -56,18 +57,34 @@ func (bm *Manager) RunBackup(cfgPath string, clusterSpec *v1beta1.ClusterSpec, v
 		}
 		assets = append(assets, result.filesForBackup...)
 	}
+	metadataPath, err := writeMetadata(bm.tmpDir, metadata{DataDir: vars.DataDir, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to write the backup metadata: %v", err)
+	}
+	assets = append(assets, metadataPath)
 	backupFileName := fmt.Sprintf("k0s_backup_%s.tar.gz", timeStamp())
-	if err := bm.save(backupFileName, assets); err != nil {
+	if clusterSpec.ClusterName != "" {
+		backupFileName = fmt.Sprintf("k0s_backup_%s_%s.tar.gz", clusterSpec.ClusterName, timeStamp())
+	}
+	storage, location, err := lookupStorage(savePath)
+	if err != nil {
+		return err
+	}
+	archive, err := storage.Create(location, backupFileName)
+	if err != nil {
 		return fmt.Errorf("failed to create archive `%s`: %v", backupFileName, err)
 	}
-	srcBackupFile := filepath.Join(bm.tmpDir, backupFileName)
-	destBackupFile := filepath.Join(savePathDir, backupFileName)
-	if err := util.FileCopy(srcBackupFile, destBackupFile); err != nil {
-		return fmt.Errorf("failed to rename temporary archive: %v", err)
+	if err := createArchive(archive, assets, bm.dataDir); err != nil {
+		if abortErr := archive.Abort(); abortErr != nil {
+			logrus.Warnf("failed to drop the partial archive %s: %v", archive.Location(), abortErr)
+		}
+		return fmt.Errorf("failed to create archive `%s`: %v", backupFileName, err)
+	}
+	if err := archive.Commit(); err != nil {
+		return fmt.Errorf("failed to store archive `%s`: %v", archive.Location(), err)
 	}
-	logrus.Infof("archive %s created successfully", destBackupFile)
+	logrus.Infof("archive %s created successfully", archive.Location())
 	return nil
-
 }
 
 func (bm *Manager) discoverSteps(cfgPath string, clusterSpec *v1beta1.ClusterSpec, vars constant.CfgVars, action string, restoredConfigPath string) {
@@ -85,13 +102,14 @@ func (bm *Manager) discoverSteps(cfgPath string, clusterSpec *v1beta1.ClusterSpe
 		vars.OCIBundleDir,
 		vars.HelmHome,
 		vars.HelmRepositoryConfig,
+		vars.ClusterMetadataPath,
 	} {
 		if action == "backup" {
 			logrus.Infof("adding `%s` path to the backup archive", path)
 		}
 		bm.Add(NewFilesystemStep(path))
 	}
-	bm.Add(newConfigurationStep(cfgPath, restoredConfigPath))
+	bm.Add(newConfigurationStep(cfgPath, bm.tmpDir, restoredConfigPath, bm.rewriter))
 }
 
 // Add adds backup step
@@ -103,38 +121,36 @@ func (bm *Manager) Add(step Backuper) {
 	bm.steps = append(bm.steps, step)
 }
 
-func (bm Manager) save(backupFileName string, assets []string) error {
-	archiveFile := filepath.Join(bm.tmpDir, backupFileName)
-	logrus.Debugf("creating temporary archive file: %v", archiveFile)
-	out, err := os.Create(archiveFile)
-	if err != nil {
-		return fmt.Errorf("error creating archive file: %v", err)
-	}
-	defer out.Close()
-	// Create the archive and write the output to the "out" Writer
-	err = createArchive(out, assets, bm.dataDir)
+// RunRestore restores cluster from archivePath, a local file or the location of a registered Storage
+func (bm *Manager) RunRestore(archivePath string, k0sVars constant.CfgVars, restoredConfigPath string) error {
+	storage, location, err := lookupStorage(archivePath)
 	if err != nil {
-		logrus.Fatalf("error creating archive: %v", err)
+		return err
 	}
-
-	destinationFile := filepath.Join(bm.tmpDir, backupFileName)
-	err = util.FileCopy(archiveFile, destinationFile)
+	archive, err := storage.Open(location)
 	if err != nil {
-		return fmt.Errorf("failed to copy archive file from temporary directory: %v", err)
+		return fmt.Errorf("failed to open backup archive `%s`: %v", archivePath, err)
 	}
-	return nil
-}
-
-// RunRestore restores cluster
-func (bm *Manager) RunRestore(archivePath string, k0sVars constant.CfgVars, restoredConfigPath string) error {
-	if err := util.ExtractArchive(archivePath, bm.tmpDir); err != nil {
+	defer archive.Close()
+	if err := util.ExtractArchiveFrom(archive, bm.tmpDir); err != nil {
 		return fmt.Errorf("failed to unpack backup archive `%s`: %v", archivePath, err)
 	}
 	defer os.RemoveAll(bm.tmpDir)
+	md, err := readMetadata(bm.tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to read the backup metadata, check the backup archive: %v", err)
+	}
+	if bm.rewriter = newPathRewriter(md, k0sVars.DataDir); bm.rewriter != nil {
+		logrus.Infof("the backup was taken with the data dir %s, rewriting the paths to %s", bm.rewriter.from, bm.rewriter.to)
+	}
 	cfg, err := bm.getConfigForRestore(k0sVars)
 	if err != nil {
 		return fmt.Errorf("failed to parse backed-up configuration file, check the backup archive: %v", err)
 	}
+	bm.rewriter.rewriteSpec(cfg.Spec)
+	if cfg.Spec.Storage.Type == v1beta1.EtcdStorageType && cfg.Spec.Storage.Etcd != nil && !isLocalAddress(cfg.Spec.Storage.Etcd.PeerAddress) {
+		logrus.Warnf("the etcd peer address %s is not an address of this node, update spec.storage.etcd.peerAddress of the restored k0s.yaml before starting k0s", cfg.Spec.Storage.Etcd.PeerAddress)
+	}
 	bm.discoverSteps(fmt.Sprintf("%s/k0s.yaml", bm.tmpDir), cfg.Spec, k0sVars, "restore", restoredConfigPath)
 	logrus.Info("Starting restore")
 
@@ -162,6 +178,14 @@ func (bm Manager) getConfigForRestore(k0sVars constant.CfgVars) (*v1beta1.Cluste
 	return cfg, nil
 }
 
+func isLocalAddress(address string) bool {
+	addresses, err := util.AllAddresses()
+	if err != nil {
+		return true
+	}
+	return address == "127.0.0.1" || address == "::1" || util.StringSliceContains(addresses, address)
+}
+
 // NewBackupManager builds new manager
 func NewBackupManager() (*Manager, error) {
 	tmpDir, err := ioutil.TempDir("", "k0s-backup")
@@ -0,0 +1,89 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+const metadataFile = "k0s-backup.json"
+
+// metadata describes the node the backup was taken on, for rewriting the paths when it's restored on another node
+type metadata struct {
+	DataDir   string    `json:"dataDir"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func writeMetadata(dir string, md metadata) (string, error) {
+	data, err := json.MarshalIndent(md, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, metadataFile)
+	return path, ioutil.WriteFile(path, data, 0600)
+}
+
+// readMetadata reads the metadata of the extracted archive, the archives of the older k0s versions have none
+func readMetadata(dir string) (*metadata, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, metadataFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	md := &metadata{}
+	return md, json.Unmarshal(data, md)
+}
+
+// pathRewriter moves the paths under the data dir of the backed-up node under the data dir of the restored one
+type pathRewriter struct {
+	from string
+	to   string
+}
+
+func newPathRewriter(md *metadata, dataDir string) *pathRewriter {
+	if md == nil || md.DataDir == "" || filepath.Clean(md.DataDir) == filepath.Clean(dataDir) {
+		return nil
+	}
+	return &pathRewriter{from: filepath.Clean(md.DataDir), to: filepath.Clean(dataDir)}
+}
+
+// rewrite replaces the data dir prefix of the paths in the text, e.g. in the kine data source of the k0s.yaml
+func (r *pathRewriter) rewrite(text []byte) []byte {
+	if r == nil {
+		return text
+	}
+	return bytes.ReplaceAll(text, []byte(r.from+"/"), []byte(r.to+"/"))
+}
+
+// rewriteSpec rewrites the paths of the cluster config that point into the data dir
+func (r *pathRewriter) rewriteSpec(spec *v1beta1.ClusterSpec) {
+	if r == nil || spec.Storage == nil || spec.Storage.Kine == nil {
+		return
+	}
+	spec.Storage.Kine.DataSource = string(r.rewrite([]byte(spec.Storage.Kine.DataSource)))
+}
@@ -0,0 +1,65 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-backup-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	md, err := readMetadata(dir)
+	require.NoError(t, err)
+	assert.Nil(t, md)
+
+	_, err = writeMetadata(dir, metadata{DataDir: "/var/lib/k0s", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	md, err = readMetadata(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/k0s", md.DataDir)
+}
+
+func TestPathRewriter(t *testing.T) {
+	assert.Nil(t, newPathRewriter(nil, "/var/lib/k0s"))
+	assert.Nil(t, newPathRewriter(&metadata{DataDir: "/var/lib/k0s/"}, "/var/lib/k0s"))
+
+	r := newPathRewriter(&metadata{DataDir: "/var/lib/k0s"}, "/opt/k0s")
+	require.NotNil(t, r)
+	assert.Equal(t, "dataSource: sqlite:///opt/k0s/db/state.db?mode=rwc\n",
+		string(r.rewrite([]byte("dataSource: sqlite:///var/lib/k0s/db/state.db?mode=rwc\n"))))
+	assert.Equal(t, "/var/lib/k0s-other/db", string(r.rewrite([]byte("/var/lib/k0s-other/db"))))
+
+	spec := &v1beta1.ClusterSpec{Storage: &v1beta1.StorageSpec{
+		Type: v1beta1.KineStorageType,
+		Kine: &v1beta1.KineConfig{DataSource: "sqlite:///var/lib/k0s/db/state.db"},
+	}}
+	r.rewriteSpec(spec)
+	assert.Equal(t, "sqlite:///opt/k0s/db/state.db", spec.Storage.Kine.DataSource)
+}
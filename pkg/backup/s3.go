@@ -0,0 +1,186 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// s3PartSize is the size of the parts the archive is uploaded in, the only part of the archive kept in memory
+const s3PartSize = 8 * 1024 * 1024
+
+var errArchiveAborted = errors.New("archive aborted")
+
+// s3Storage keeps the archives in an S3 compatible object store, e.g. s3://bucket/prefix?region=eu-west-1.
+// The credentials are taken from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables. The location accepts the query parameters:
+//  - region: the bucket region, AWS_REGION or us-east-1 by default
+//  - endpoint: the URL of the S3 compatible store, e.g. http://minio:9000, the bucket is addressed in the path then
+//  - sse: the server-side encryption, none (default), AES256 or aws:kms
+//  - kms-key-id: the KMS key for aws:kms
+type s3Storage struct{}
+
+func (s3Storage) Scheme() string { return "s3" }
+
+func (s3Storage) Create(location *url.URL, name string) (ArchiveWriter, error) {
+	c, err := newS3Client(location)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Trim(location.Path, "/")
+	if key != "" {
+		key += "/"
+	}
+	key += name
+
+	r, w := io.Pipe()
+	a := &s3Archive{PipeWriter: w, bucket: c.bucket, key: key, done: make(chan error, 1)}
+	go func() {
+		_, err := c.PutObject(context.Background(), c.bucket, key, r, -1, minio.PutObjectOptions{
+			ContentType:          "application/gzip",
+			ServerSideEncryption: c.sse,
+			PartSize:             s3PartSize,
+		})
+		// fails the writes once the upload is over
+		if err != nil {
+			r.CloseWithError(err)
+		} else {
+			r.Close()
+		}
+		a.done <- err
+	}()
+	return a, nil
+}
+
+func (s3Storage) Open(location *url.URL) (io.ReadCloser, error) {
+	c, err := newS3Client(location)
+	if err != nil {
+		return nil, err
+	}
+	object, err := c.GetObject(context.Background(), c.bucket, strings.Trim(location.Path, "/"), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// the object is fetched lazily, stat it to report the missing archive right away
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("failed to open s3://%s%s: %w", location.Host, location.Path, err)
+	}
+	return object, nil
+}
+
+type s3Client struct {
+	*minio.Client
+	bucket string
+	sse    encrypt.ServerSide
+}
+
+func newS3Client(location *url.URL) (*s3Client, error) {
+	if location.Host == "" {
+		return nil, fmt.Errorf("no bucket in the backup location %s", location)
+	}
+	query := location.Query()
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for storing the backups in S3")
+	}
+	region := query.Get("region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	c := &s3Client{bucket: location.Host}
+	switch sse := query.Get("sse"); sse {
+	case "", "none":
+	case "AES256":
+		c.sse = encrypt.NewSSE()
+	case "aws:kms":
+		var err error
+		if c.sse, err = encrypt.NewSSEKMS(query.Get("kms-key-id"), nil); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported server-side encryption %q, use none, AES256 or aws:kms", sse)
+	}
+
+	opts := &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN")),
+		Secure: true,
+		Region: region,
+	}
+	host := "s3.amazonaws.com"
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+		host = u.Host
+		opts.Secure = u.Scheme == "https"
+		opts.BucketLookup = minio.BucketLookupPath
+	}
+
+	var err error
+	if c.Client, err = minio.New(host, opts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// s3Archive streams the archive through the pipe into a multipart upload
+type s3Archive struct {
+	*io.PipeWriter
+	bucket string
+	key    string
+	done   chan error
+}
+
+func (a *s3Archive) Commit() error {
+	a.PipeWriter.Close()
+	if err := <-a.done; err != nil {
+		return fmt.Errorf("failed to upload %s: %w", a.Location(), err)
+	}
+	return nil
+}
+
+// Abort fails the upload, which drops the uploaded parts
+func (a *s3Archive) Abort() error {
+	a.PipeWriter.CloseWithError(errArchiveAborted)
+	if err := <-a.done; err != nil && !errors.Is(err, errArchiveAborted) {
+		return err
+	}
+	return nil
+}
+
+func (a *s3Archive) Location() string {
+	return fmt.Sprintf("s3://%s/%s", a.bucket, a.key)
+}
@@ -0,0 +1,168 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 stores the objects uploaded with single or multipart uploads
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	sse     map[string]string
+	parts   map[int][]byte
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Get("uploadId") != "":
+		var numbers []int
+		for n := range s.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var object []byte
+		for _, n := range numbers {
+			object = append(object, s.parts[n]...)
+		}
+		s.objects[r.URL.Path] = object
+		fmt.Fprint(w, "<CompleteMultipartUploadResult><Bucket>backups</Bucket></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodPost:
+		s.sse[r.URL.Path] = r.Header.Get("X-Amz-Server-Side-Encryption")
+		s.parts = map[int][]byte{}
+		fmt.Fprint(w, "<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == http.MethodPut && query.Get("uploadId") != "":
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.parts[n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+	case r.Method == http.MethodPut:
+		s.sse[r.URL.Path] = r.Header.Get("X-Amz-Server-Side-Encryption")
+		s.objects[r.URL.Path] = body
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		object, found := s.objects[r.URL.Path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 26 Apr 2021 19:51:57 GMT")
+		w.Header().Set("Content-Length", strconv.Itoa(len(object)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(object)
+		}
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	s3 := &fakeS3{objects: map[string][]byte{}, sse: map[string]string{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	for _, env := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		prev, set := os.LookupEnv(env)
+		defer func(env string) {
+			if set {
+				os.Setenv(env, prev)
+			} else {
+				os.Unsetenv(env)
+			}
+		}(env)
+	}
+	os.Setenv("AWS_ACCESS_KEY_ID", "key")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	location := "s3://backups/k0s?endpoint=" + url.QueryEscape(server.URL)
+	storage, u, err := lookupStorage(location)
+	require.NoError(t, err)
+
+	for name, size := range map[string]int{"small.tar.gz": 1024, "large.tar.gz": s3PartSize + 1024} {
+		data := bytes.Repeat([]byte{'x'}, size)
+		w, err := storage.Create(u, name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Commit())
+		assert.Equal(t, "s3://backups/k0s/"+name, w.Location())
+		assert.Empty(t, s3.sse["/backups/k0s/"+name], "the archives aren't encrypted by default")
+
+		_, archive, err := lookupStorage("s3://backups/k0s/" + name + "?endpoint=" + url.QueryEscape(server.URL))
+		require.NoError(t, err)
+		r, err := storage.Open(archive)
+		require.NoError(t, err)
+		restored, err := ioutil.ReadAll(r)
+		r.Close()
+		require.NoError(t, err)
+		assert.Equal(t, data, restored, name)
+	}
+
+	_, missing, err := lookupStorage("s3://backups/k0s/missing.tar.gz?endpoint=" + url.QueryEscape(server.URL))
+	require.NoError(t, err)
+	_, err = storage.Open(missing)
+	assert.Contains(t, err.Error(), "The specified key does not exist.")
+
+	_, encrypted, err := lookupStorage("s3://backups/k0s?sse=AES256&endpoint=" + url.QueryEscape(server.URL))
+	require.NoError(t, err)
+	w, err := storage.Create(encrypted, "encrypted.tar.gz")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("archive"))
+	require.NoError(t, err)
+	require.NoError(t, w.Commit())
+	assert.Equal(t, "AES256", s3.sse["/backups/k0s/encrypted.tar.gz"])
+
+	w, err = storage.Create(u, "aborted.tar.gz")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("partial"))
+	require.NoError(t, err)
+	require.NoError(t, w.Abort())
+	assert.NotContains(t, s3.objects, "/backups/k0s/aborted.tar.gz")
+}
+
+func TestLookupStorage(t *testing.T) {
+	storage, u, err := lookupStorage("/var/backups")
+	require.NoError(t, err)
+	assert.Equal(t, "file", storage.Scheme())
+	assert.Equal(t, "/var/backups", u.Path)
+
+	_, _, err = lookupStorage("gs://bucket")
+	assert.Error(t, err)
+}
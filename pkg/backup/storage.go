@@ -0,0 +1,129 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Storage keeps the backup archives. The storage is picked by the scheme of the location, e.g. "s3" for
+// s3://bucket/prefix, the locations without a scheme are local directories.
+type Storage interface {
+	// Scheme is the location scheme handled by the storage
+	Scheme() string
+	// Create starts storing the archive with the given name under the location
+	Create(location *url.URL, name string) (ArchiveWriter, error)
+	// Open returns the reader of the archive at the location
+	Open(location *url.URL) (io.ReadCloser, error)
+}
+
+// ArchiveWriter streams the archive into the storage
+type ArchiveWriter interface {
+	io.Writer
+	// Commit completes the archive, it's not visible in the storage before
+	Commit() error
+	// Abort drops the partially written archive
+	Abort() error
+	// Location is where the archive is stored
+	Location() string
+}
+
+var (
+	storagesMu sync.RWMutex
+	storages   = map[string]Storage{}
+)
+
+func init() {
+	RegisterStorage(localStorage{})
+	RegisterStorage(s3Storage{})
+}
+
+// RegisterStorage makes the storage available for the locations with its scheme, replacing the storage registered
+// for the same scheme earlier
+func RegisterStorage(storage Storage) {
+	storagesMu.Lock()
+	defer storagesMu.Unlock()
+	storages[storage.Scheme()] = storage
+}
+
+// IsLocal tells if the location is a local path
+func IsLocal(location string) bool {
+	return !strings.Contains(location, "://") || strings.HasPrefix(location, "file://")
+}
+
+func lookupStorage(location string) (Storage, *url.URL, error) {
+	u := &url.URL{Scheme: "file", Path: location}
+	if strings.Contains(location, "://") {
+		var err error
+		if u, err = url.Parse(location); err != nil {
+			return nil, nil, fmt.Errorf("invalid backup location %q: %w", location, err)
+		}
+	}
+
+	storagesMu.RLock()
+	defer storagesMu.RUnlock()
+	storage, found := storages[u.Scheme]
+	if !found {
+		return nil, nil, fmt.Errorf("unsupported backup location %q, no storage for the scheme %s", location, u.Scheme)
+	}
+	return storage, u, nil
+}
+
+// localStorage keeps the archives in a local directory
+type localStorage struct{}
+
+func (localStorage) Scheme() string { return "file" }
+
+func (localStorage) Create(location *url.URL, name string) (ArchiveWriter, error) {
+	path := filepath.Join(location.Path, name)
+	f, err := os.OpenFile(path+".partial", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &localArchive{File: f, path: path}, nil
+}
+
+func (localStorage) Open(location *url.URL) (io.ReadCloser, error) {
+	return os.Open(location.Path)
+}
+
+type localArchive struct {
+	*os.File
+	path string
+}
+
+func (a *localArchive) Commit() error {
+	if err := a.File.Close(); err != nil {
+		return err
+	}
+	return os.Rename(a.File.Name(), a.path)
+}
+
+func (a *localArchive) Abort() error {
+	a.File.Close()
+	return os.Remove(a.File.Name())
+}
+
+func (a *localArchive) Location() string { return a.path }
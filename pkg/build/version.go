@@ -9,3 +9,4 @@ var KubernetesVersion string
 var KineVersion string
 var EtcdVersion string
 var KonnectivityVersion string
+var CrictlVersion string
@@ -0,0 +1,389 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certificate
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/keyutil"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// CARotationPhase is the phase of the rotation of the cluster CA
+type CARotationPhase string
+
+const (
+	// CARotationNone means no rotation is in progress
+	CARotationNone CARotationPhase = "none"
+	// CARotationPrepared means the next CA is trusted next to the current one, but doesn't sign anything yet
+	CARotationPrepared CARotationPhase = "prepared"
+	// CARotationActivated means the next CA has replaced the previous one, which is still trusted
+	CARotationActivated CARotationPhase = "activated"
+)
+
+// the files of the CA rotation in the cert dir, next to ca.crt and ca.key
+const (
+	caNextCert     = "ca-next.crt"
+	caNextKey      = "ca-next.key"
+	caPreviousCert = "ca-previous.crt"
+	caPreviousKey  = "ca-previous.key"
+	// caCrossCert is the next CA signed by the current one, and the current one signed by the previous one once
+	// activated
+	caCrossCert = "ca-cross.crt"
+	caBundle    = "ca-bundle.crt"
+)
+
+// CABundlePath is the bundle of the CAs the cluster trusts. It's the cluster CA, along with the CA replacing it or
+// being replaced while the CA is rotated.
+func CABundlePath(certRootDir string) string {
+	if bundle := filepath.Join(certRootDir, caBundle); util.FileExists(bundle) {
+		return bundle
+	}
+	return filepath.Join(certRootDir, "ca.crt")
+}
+
+// GetCARotationPhase returns the phase of the CA rotation by the files in the cert dir. The next CA is removed last
+// on activation, an interrupted activation is still prepared.
+func GetCARotationPhase(certRootDir string) CARotationPhase {
+	switch {
+	case util.FileExists(filepath.Join(certRootDir, caNextCert)):
+		return CARotationPrepared
+	case util.FileExists(filepath.Join(certRootDir, caPreviousCert)):
+		return CARotationActivated
+	default:
+		return CARotationNone
+	}
+}
+
+// PrepareCARotation creates the next cluster CA and makes it trusted along with the current one. The next CA is
+// cross-signed with the current one. A next CA already in the cert dir, e.g. copied from the controller which
+// prepared the rotation first, is used as is.
+func (m *Manager) PrepareCARotation() error {
+	dir := m.K0sVars.CertRootDir
+	if phase := GetCARotationPhase(dir); phase == CARotationActivated {
+		return fmt.Errorf("the CA rotation is %s already, finish it before preparing a new one", phase)
+	}
+	if util.FileExists(filepath.Join(dir, caPreviousCert)) {
+		return fmt.Errorf("the activation of the CA rotation was interrupted, activate it again")
+	}
+	nextCertFile, nextKeyFile := filepath.Join(dir, caNextCert), filepath.Join(dir, caNextKey)
+	if !util.FileExists(nextCertFile) || !util.FileExists(nextKeyFile) {
+		cert, key, err := newCA("kubernetes-ca")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(nextKeyFile, key, constant.CertSecureMode); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(nextCertFile, cert, constant.CertMode); err != nil {
+			return err
+		}
+	}
+
+	current, err := readCertificate(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		return err
+	}
+	currentKey, err := readSigner(filepath.Join(dir, "ca.key"))
+	if err != nil {
+		return err
+	}
+	next, err := readCertificate(nextCertFile)
+	if err != nil {
+		return err
+	}
+	cross, err := crossSign(next, current, currentKey)
+	if err != nil {
+		return fmt.Errorf("failed to cross-sign the next CA: %w", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, caCrossCert), cross, constant.CertMode); err != nil {
+		return err
+	}
+	return writeBundle(dir, "ca.crt", caNextCert)
+}
+
+// ActivateCARotation makes the next CA the cluster CA, the certificates are issued by it from now on. The previous
+// CA stays trusted until the rotation is finished. Every step can be run again, an interrupted activation is
+// completed by activating again: the previous CA is kept only once, the next CA is removed last.
+func (m *Manager) ActivateCARotation() error {
+	dir := m.K0sVars.CertRootDir
+	if phase := GetCARotationPhase(dir); phase != CARotationPrepared {
+		return fmt.Errorf("the CA rotation is %s, it must be prepared to be activated", phase)
+	}
+	if !util.FileExists(filepath.Join(dir, caPreviousCert)) {
+		if err := copyFiles(dir, [][2]string{{"ca.key", caPreviousKey}, {"ca.crt", caPreviousCert}}); err != nil {
+			return fmt.Errorf("failed to keep the previous CA: %w", err)
+		}
+	}
+	if err := copyFiles(dir, [][2]string{{caNextKey, "ca.key"}, {caNextCert, "ca.crt"}}); err != nil {
+		return fmt.Errorf("failed to replace the CA: %w", err)
+	}
+	if err := writeBundle(dir, "ca.crt", caPreviousCert); err != nil {
+		return err
+	}
+	for _, name := range []string{caNextCert, caNextKey} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// FinishCARotation stops trusting the previous CA. It's refused while a kubelet still serves a certificate signed by
+// the previous CA, which the API server wouldn't trust anymore.
+func (m *Manager) FinishCARotation() error {
+	return m.finishCARotation(m.kubeletsServingPreviousCA)
+}
+
+func (m *Manager) finishCARotation(kubeletsServingPreviousCA func(previous *x509.Certificate) ([]string, error)) error {
+	dir := m.K0sVars.CertRootDir
+	if phase := GetCARotationPhase(dir); phase != CARotationActivated {
+		return fmt.Errorf("the CA rotation is %s, it must be activated to be finished", phase)
+	}
+	previous, err := readCertificate(filepath.Join(dir, caPreviousCert))
+	if err != nil {
+		return err
+	}
+	nodes, err := kubeletsServingPreviousCA(previous)
+	if err != nil {
+		return fmt.Errorf("failed to check the kubelet serving certificates: %w", err)
+	}
+	if len(nodes) > 0 {
+		return fmt.Errorf("the kubelets of %s serve certificates signed by the previous CA, the workers renew them within an hour of the activation, or right away when restarted", strings.Join(nodes, ", "))
+	}
+	for _, name := range []string{caPreviousCert, caPreviousKey, caCrossCert, caBundle} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// kubeletsServingPreviousCA lists the ready nodes whose kubelet serves a certificate signed by the previous CA, and
+// the ones whose certificate can't be fetched. The nodes not ready are skipped, their workers renew the certificate
+// when they come back.
+func (m *Manager) kubeletsServingPreviousCA(previous *x509.Certificate) ([]string, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", m.K0sVars.AdminKubeConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var serving []string
+	for _, node := range nodes.Items {
+		if !nodeReady(node) {
+			logrus.Warnf("skipping the kubelet serving certificate of %s, the node isn't ready", node.Name)
+			continue
+		}
+		cert, err := kubeletServingCertificate(node)
+		if err != nil {
+			serving = append(serving, fmt.Sprintf("%s (%v)", node.Name, err))
+		} else if cert.CheckSignatureFrom(previous) == nil {
+			serving = append(serving, node.Name)
+		}
+	}
+	return serving, nil
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// kubeletServingCertificate fetches the serving certificate of the kubelet of the node, the certificate is only
+// looked at, not verified
+func kubeletServingCertificate(node corev1.Node) (*x509.Certificate, error) {
+	var address string
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP || address == "" {
+			address = addr.Address
+		}
+	}
+	if address == "" {
+		return nil, fmt.Errorf("no address")
+	}
+	port := node.Status.DaemonEndpoints.KubeletEndpoint.Port
+	if port == 0 {
+		port = 10250
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(address, strconv.Itoa(int(port))), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no serving certificate")
+	}
+	return certs[0], nil
+}
+
+// ChainCrossSignedCA appends the cross-signed CA to the named serving certificate once the CA rotation is activated,
+// so that the clients trusting only the previous CA still verify it. Certificates not issued by the cluster CA are
+// left as they are.
+func (m *Manager) ChainCrossSignedCA(name string) error {
+	dir := m.K0sVars.CertRootDir
+	if GetCARotationPhase(dir) != CARotationActivated {
+		return nil
+	}
+	cross, err := ioutil.ReadFile(filepath.Join(dir, caCrossCert))
+	if err != nil {
+		return err
+	}
+	certFile := filepath.Join(dir, fmt.Sprintf("%s.crt", name))
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, cross) {
+		return nil
+	}
+	cert, err := ParseCertificatePEM(data)
+	if err != nil {
+		return err
+	}
+	ca, err := readCertificate(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		return err
+	}
+	if cert.CheckSignatureFrom(ca) != nil {
+		return nil
+	}
+	return ioutil.WriteFile(certFile, append(data, cross...), constant.CertMode)
+}
+
+// crossSign signs the CA certificate with the issuer, the cross-signed certificate has the subject and the key of
+// the CA and expires with the issuer at the latest
+func crossSign(cert, issuer *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	notAfter := cert.NotAfter
+	if issuer.NotAfter.Before(notAfter) {
+		notAfter = issuer.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               cert.Subject,
+		NotBefore:             cert.NotBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              cert.KeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            cert.MaxPathLen,
+		MaxPathLenZero:        cert.MaxPathLenZero,
+		SubjectKeyId:          cert.SubjectKeyId,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, cert.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// copyFiles copies the files in the dir atomically, in order
+func copyFiles(dir string, copies [][2]string) error {
+	for _, c := range copies {
+		src := filepath.Join(dir, c[0])
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err := util.WriteFileAtomic(filepath.Join(dir, c[1]), data, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBundle writes the CA bundle of the certificate files, the CA signing the certificates first
+func writeBundle(dir string, names ...string) error {
+	var bundle []byte
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		bundle = append(bundle, bytes.TrimSpace(data)...)
+		bundle = append(bundle, '\n')
+	}
+	return util.WriteFileAtomic(filepath.Join(dir, caBundle), bundle, constant.CertMode)
+}
+
+func readCertificate(path string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := ParseCertificatePEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cert, nil
+}
+
+func readSigner(path string) (crypto.Signer, error) {
+	key, err := keyutil.PrivateKeyFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("the key %s can't sign", path)
+	}
+	return signer, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestCARotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ca-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	m := &Manager{K0sVars: constant.CfgVars{CertRootDir: dir}}
+	require.NoError(t, m.EnsureCA("ca", "kubernetes-ca"))
+	previous, err := readCertificate(filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+
+	require.NoError(t, m.PrepareCARotation())
+	assert.Equal(t, CARotationPrepared, GetCARotationPhase(dir))
+	assert.Equal(t, filepath.Join(dir, "ca-bundle.crt"), CABundlePath(dir))
+	assert.Len(t, readCertificates(t, CABundlePath(dir)), 2)
+	// preparing again keeps the next CA
+	next, err := readCertificate(filepath.Join(dir, caNextCert))
+	require.NoError(t, err)
+	require.NoError(t, m.PrepareCARotation())
+	again, err := readCertificate(filepath.Join(dir, caNextCert))
+	require.NoError(t, err)
+	assert.Equal(t, next.Raw, again.Raw)
+
+	require.NoError(t, m.ActivateCARotation())
+	assert.Equal(t, CARotationActivated, GetCARotationPhase(dir))
+	assert.Error(t, m.PrepareCARotation())
+	current, err := readCertificate(filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, next.Raw, current.Raw)
+
+	_, err = m.EnsureCertificate(Request{
+		Name:      "server",
+		CN:        "kubernetes",
+		O:         "kubernetes",
+		CACert:    filepath.Join(dir, "ca.crt"),
+		CAKey:     filepath.Join(dir, "ca.key"),
+		Hostnames: []string{"localhost"},
+	}, "root")
+	require.NoError(t, err)
+	require.NoError(t, m.ChainCrossSignedCA("server"))
+	require.NoError(t, m.ChainCrossSignedCA("server"))
+	chain := readCertificates(t, filepath.Join(dir, "server.crt"))
+	require.Len(t, chain, 2)
+
+	t.Run("clients_of_the_previous_ca_verify_through_the_cross_signed_ca", func(t *testing.T) {
+		roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+		roots.AddCert(previous)
+		intermediates.AddCert(chain[1])
+		_, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: "localhost"})
+		assert.NoError(t, err)
+	})
+	t.Run("clients_of_the_bundle_verify", func(t *testing.T) {
+		roots := x509.NewCertPool()
+		for _, ca := range readCertificates(t, CABundlePath(dir)) {
+			roots.AddCert(ca)
+		}
+		_, err := chain[0].Verify(x509.VerifyOptions{Roots: roots, DNSName: "localhost"})
+		assert.NoError(t, err)
+	})
+
+	err = m.finishCARotation(func(ca *x509.Certificate) ([]string, error) {
+		assert.Equal(t, previous.Raw, ca.Raw)
+		return []string{"worker-1"}, nil
+	})
+	assert.EqualError(t, err, "the kubelets of worker-1 serve certificates signed by the previous CA, the workers renew them within an hour of the activation, or right away when restarted")
+	assert.Equal(t, CARotationActivated, GetCARotationPhase(dir))
+
+	require.NoError(t, m.finishCARotation(func(*x509.Certificate) ([]string, error) { return nil, nil }))
+	assert.Equal(t, CARotationNone, GetCARotationPhase(dir))
+	assert.Equal(t, filepath.Join(dir, "ca.crt"), CABundlePath(dir))
+	assert.Error(t, m.ActivateCARotation())
+}
+
+func TestInterruptedCAActivation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ca-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	m := &Manager{K0sVars: constant.CfgVars{CertRootDir: dir}}
+	require.NoError(t, m.EnsureCA("ca", "kubernetes-ca"))
+	previous, err := ioutil.ReadFile(filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+	require.NoError(t, m.PrepareCARotation())
+	next, err := ioutil.ReadFile(filepath.Join(dir, caNextCert))
+	require.NoError(t, err)
+
+	// interrupted once the previous CA is kept and the key is replaced
+	require.NoError(t, copyFiles(dir, [][2]string{{"ca.key", caPreviousKey}, {"ca.crt", caPreviousCert}, {caNextKey, "ca.key"}}))
+	assert.Equal(t, CARotationPrepared, GetCARotationPhase(dir))
+	assert.Error(t, m.PrepareCARotation())
+
+	require.NoError(t, m.ActivateCARotation())
+	assert.Equal(t, CARotationActivated, GetCARotationPhase(dir))
+	for name, expected := range map[string][]byte{"ca.crt": next, caPreviousCert: previous} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, expected, data, name)
+	}
+	assert.Len(t, readCertificates(t, CABundlePath(dir)), 2)
+	assert.NoFileExists(t, filepath.Join(dir, caNextKey))
+}
+
+func readCertificates(t *testing.T, path string) []*x509.Certificate {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		certs = append(certs, cert)
+	}
+	return certs
+}
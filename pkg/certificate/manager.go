@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cloudflare/cfssl/certinfo"
 	"github.com/cloudflare/cfssl/cli"
@@ -68,15 +69,7 @@ func (m *Manager) EnsureCA(name, cn string) error {
 		return nil
 	}
 
-	req := new(csr.CertificateRequest)
-	req.KeyRequest = csr.NewKeyRequest()
-	req.KeyRequest.A = "rsa"
-	req.KeyRequest.S = 2048
-	req.CN = cn
-	req.CA = &csr.CAConfig{
-		Expiry: "87600h",
-	}
-	cert, _, key, err := initca.New(req)
+	cert, key, err := newCA(cn)
 	if err != nil {
 		return err
 	}
@@ -94,6 +87,20 @@ func (m *Manager) EnsureCA(name, cn string) error {
 	return nil
 }
 
+// newCA creates a self-signed CA valid for ten years
+func newCA(cn string) ([]byte, []byte, error) {
+	req := new(csr.CertificateRequest)
+	req.KeyRequest = csr.NewKeyRequest()
+	req.KeyRequest.A = "rsa"
+	req.KeyRequest.S = 2048
+	req.CN = cn
+	req.CA = &csr.CAConfig{
+		Expiry: "87600h",
+	}
+	cert, _, key, err := initca.New(req)
+	return cert, key, err
+}
+
 // EnsureCertificate creates the specified certificate if it does not already exist
 func (m *Manager) EnsureCertificate(certReq Request, ownerName string) (Certificate, error) {
 
@@ -105,40 +112,7 @@ func (m *Manager) EnsureCertificate(certReq Request, ownerName string) (Certific
 	// if regenerateCert returns true, it means we need to create the certs
 	if m.regenerateCert(certReq, keyFile, certFile) {
 		logrus.Debugf("creating certificate %s", certFile)
-		req := csr.CertificateRequest{
-			KeyRequest: csr.NewKeyRequest(),
-			CN:         certReq.CN,
-			Names: []csr.Name{
-				{O: certReq.O},
-			},
-		}
-
-		req.KeyRequest.A = "rsa"
-		req.KeyRequest.S = 2048
-		req.Hosts = certReq.Hostnames
-
-		var key, csrBytes []byte
-		g := &csr.Generator{Validator: genkey.Validator}
-		csrBytes, key, err := g.ProcessRequest(&req)
-		if err != nil {
-			return Certificate{}, err
-		}
-		config := cli.Config{
-			CAFile:    certReq.CACert,
-			CAKeyFile: certReq.CAKey,
-		}
-		s, err := sign.SignerFromConfig(config)
-		if err != nil {
-			return Certificate{}, err
-		}
-
-		var cert []byte
-		signReq := signer.SignRequest{
-			Request: string(csrBytes),
-			Profile: "kubernetes",
-		}
-
-		cert, err = s.Sign(signReq)
+		key, cert, err := m.sign(certReq, time.Time{})
 		if err != nil {
 			return Certificate{}, err
 		}
@@ -187,6 +161,59 @@ func (m *Manager) EnsureCertificate(certReq Request, ownerName string) (Certific
 
 }
 
+// IssueCertificate signs a certificate valid for the given duration, without storing it into the cert dir
+func (m *Manager) IssueCertificate(certReq Request, ttl time.Duration) (Certificate, error) {
+	key, cert, err := m.sign(certReq, time.Now().Add(ttl))
+	if err != nil {
+		return Certificate{}, err
+	}
+	return Certificate{
+		Key:  string(key),
+		Cert: string(cert),
+	}, nil
+}
+
+// sign creates a new key and signs a certificate for it, a zero notAfter uses the expiry of the signing profile
+func (m *Manager) sign(certReq Request, notAfter time.Time) ([]byte, []byte, error) {
+	req := csr.CertificateRequest{
+		KeyRequest: csr.NewKeyRequest(),
+		CN:         certReq.CN,
+		Names: []csr.Name{
+			{O: certReq.O},
+		},
+	}
+
+	req.KeyRequest.A = "rsa"
+	req.KeyRequest.S = 2048
+	req.Hosts = certReq.Hostnames
+
+	g := &csr.Generator{Validator: genkey.Validator}
+	csrBytes, key, err := g.ProcessRequest(&req)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := cli.Config{
+		CAFile:    certReq.CACert,
+		CAKeyFile: certReq.CAKey,
+	}
+	s, err := sign.SignerFromConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signReq := signer.SignRequest{
+		Request:  string(csrBytes),
+		Profile:  "kubernetes",
+		NotAfter: notAfter,
+	}
+
+	cert, err := s.Sign(signReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
 // if regenerateCert does not need to do any changes, it will return false
 // if a change in SAN hosts is detected, if will return true, to re-generate certs
 func (m *Manager) regenerateCert(certReq Request, keyFile string, certFile string) bool {
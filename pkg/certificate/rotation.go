@@ -0,0 +1,43 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certificate
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// RenewalFraction is the part of the validity of a client certificate after which it's renewed. The kubelet renews
+// its certificate at 70-90% of the validity, the rotation of k0s steps in only if that hasn't happened.
+const RenewalFraction = 0.9
+
+// ParseCertificatePEM parses the first certificate of the PEM data, which may hold the key as well
+func ParseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+	return nil, fmt.Errorf("no certificate found")
+}
+
+// RenewalTime is the time the certificate is due for renewal, once RenewalFraction of its validity has passed
+func RenewalTime(cert *x509.Certificate) time.Time {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotBefore.Add(time.Duration(float64(validity) * RenewalFraction))
+}
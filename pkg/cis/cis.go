@@ -0,0 +1,205 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/supervisor"
+)
+
+// Check statuses
+const (
+	StatusPass = "PASS"
+	StatusFail = "FAIL"
+	StatusSkip = "SKIP"
+)
+
+// Result is the outcome of a single benchmark control
+type Result struct {
+	ID          string `json:"id" yaml:"id"`
+	Text        string `json:"text" yaml:"text"`
+	Status      string `json:"status" yaml:"status"`
+	Reason      string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Remediation string `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+}
+
+// errSkip marks controls that are not applicable on this node
+type errSkip struct{ reason string }
+
+func (e errSkip) Error() string { return e.reason }
+
+type control struct {
+	id          string
+	text        string
+	remediation string
+	test        func(n *node) (bool, string, error)
+}
+
+// node holds the lazily collected running state of the node
+type node struct {
+	k0sVars constant.CfgVars
+	args    map[string]map[string]string
+}
+
+func (n *node) processArgs(name string) (map[string]string, error) {
+	if args, found := n.args[name]; found {
+		return args, nil
+	}
+	args, err := supervisor.ProcessArgs(n.k0sVars.RunDir, name)
+	if err != nil {
+		return nil, errSkip{fmt.Sprintf("%s is not running on this node", name)}
+	}
+	n.args[name] = args
+	return args, nil
+}
+
+// Run evaluates the controls applicable to the components running on this node
+func Run(k0sVars constant.CfgVars) []Result {
+	n := &node{k0sVars: k0sVars, args: make(map[string]map[string]string)}
+	var results []Result
+	for _, c := range controls {
+		r := Result{ID: c.id, Text: c.text}
+		ok, actual, err := c.test(n)
+		switch {
+		case err != nil:
+			if _, isSkip := err.(errSkip); !isSkip {
+				err = fmt.Errorf("check failed: %w", err)
+			}
+			r.Status = StatusSkip
+			r.Reason = err.Error()
+		case ok:
+			r.Status = StatusPass
+		default:
+			r.Status = StatusFail
+			r.Reason = actual
+			r.Remediation = c.remediation
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func flagEquals(process, flag, want string) func(n *node) (bool, string, error) {
+	return func(n *node) (bool, string, error) {
+		args, err := n.processArgs(process)
+		if err != nil {
+			return false, "", err
+		}
+		value, found := args[flag]
+		if !found {
+			return false, fmt.Sprintf("--%s is not set", flag), nil
+		}
+		return value == want, fmt.Sprintf("--%s=%s", flag, value), nil
+	}
+}
+
+func flagSet(process, flag string) func(n *node) (bool, string, error) {
+	return func(n *node) (bool, string, error) {
+		args, err := n.processArgs(process)
+		if err != nil {
+			return false, "", err
+		}
+		_, found := args[flag]
+		return found, fmt.Sprintf("--%s is not set", flag), nil
+	}
+}
+
+func flagContains(process, flag, item string, want bool) func(n *node) (bool, string, error) {
+	return func(n *node) (bool, string, error) {
+		args, err := n.processArgs(process)
+		if err != nil {
+			return false, "", err
+		}
+		contains := false
+		for _, v := range strings.Split(args[flag], ",") {
+			if v == item {
+				contains = true
+			}
+		}
+		return contains == want, fmt.Sprintf("--%s=%s", flag, args[flag]), nil
+	}
+}
+
+func fileMode(path func(k0sVars constant.CfgVars) string, max os.FileMode) func(n *node) (bool, string, error) {
+	return func(n *node) (bool, string, error) {
+		p := path(n.k0sVars)
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			return false, "", errSkip{fmt.Sprintf("%s does not exist on this node", p)}
+		} else if err != nil {
+			return false, "", err
+		}
+		mode := info.Mode().Perm()
+		return mode&^max == 0, fmt.Sprintf("%s has permissions %04o", p, mode), nil
+	}
+}
+
+func keyFileModes(max os.FileMode) func(n *node) (bool, string, error) {
+	return func(n *node) (bool, string, error) {
+		keys, err := filepath.Glob(filepath.Join(n.k0sVars.CertRootDir, "*.key"))
+		if err != nil {
+			return false, "", err
+		}
+		if len(keys) == 0 {
+			return false, "", errSkip{"no private keys found on this node"}
+		}
+		for _, key := range keys {
+			info, err := os.Stat(key)
+			if err != nil {
+				return false, "", err
+			}
+			if mode := info.Mode().Perm(); mode&^max != 0 {
+				return false, fmt.Sprintf("%s has permissions %04o", key, mode), nil
+			}
+		}
+		return true, "", nil
+	}
+}
+
+// kubeletAnonymousAuth checks the anonymous auth setting of the kubelet config written by the worker
+func kubeletAnonymousAuth(n *node) (bool, string, error) {
+	if _, err := n.processArgs("kubelet"); err != nil {
+		return false, "", err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(n.k0sVars.DataDir, "kubelet-config.yaml"))
+	if err != nil {
+		return false, "", err
+	}
+	cfg := struct {
+		Authentication struct {
+			Anonymous struct {
+				Enabled *bool `yaml:"enabled"`
+			} `yaml:"anonymous"`
+		} `yaml:"authentication"`
+	}{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return false, "", err
+	}
+	enabled := cfg.Authentication.Anonymous.Enabled
+	// the kubelet defaults to anonymous auth being enabled
+	if enabled == nil || *enabled {
+		return false, "authentication.anonymous.enabled is not false", nil
+	}
+	return true, "", nil
+}
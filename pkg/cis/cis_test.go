@@ -0,0 +1,85 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestFlagChecks(t *testing.T) {
+	n := &node{args: map[string]map[string]string{
+		"kube-apiserver": {
+			"anonymous-auth":     "true",
+			"authorization-mode": "Node,RBAC",
+		},
+	}}
+
+	ok, actual, err := flagEquals("kube-apiserver", "anonymous-auth", "false")(n)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "--anonymous-auth=true", actual)
+
+	ok, _, err = flagContains("kube-apiserver", "authorization-mode", "RBAC", true)(n)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = flagContains("kube-apiserver", "authorization-mode", "AlwaysAllow", false)(n)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = flagSet("kube-apiserver", "tls-private-key-file")(n)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNotRunningComponentIsSkipped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-cis")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	results := Run(constant.CfgVars{RunDir: dir, DataDir: dir, CertRootDir: dir, EtcdDataDir: filepath.Join(dir, "etcd")})
+	require.Len(t, results, len(controls))
+	for _, r := range results {
+		assert.Equal(t, StatusSkip, r.Status, r.ID)
+	}
+}
+
+func TestFileModeCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-cis")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	n := &node{k0sVars: constant.CfgVars{CertRootDir: dir}}
+	key := filepath.Join(dir, "ca.key")
+	require.NoError(t, ioutil.WriteFile(key, []byte("key"), 0644))
+
+	ok, _, err := keyFileModes(0600)(n)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, os.Chmod(key, 0600))
+	ok, _, err = keyFileModes(0600)(n)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cis
+
+import (
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// controls lists the CIS Kubernetes benchmark controls that can be evaluated from the running k0s configuration.
+// The IDs follow the CIS Kubernetes benchmark 1.6 which the kube-bench config in docs/kube-bench is based on.
+var controls = []control{
+	{
+		id:          "1.1.11",
+		text:        "Ensure that the etcd data directory permissions are set to 700 or more restrictive",
+		remediation: "chmod 700 on the etcd data directory",
+		test:        fileMode(func(v constant.CfgVars) string { return v.EtcdDataDir }, constant.EtcdDataDirMode),
+	},
+	{
+		id:          "1.1.21",
+		text:        "Ensure that the Kubernetes PKI key file permissions are set to 600",
+		remediation: "chmod 600 on the private keys in the k0s pki directory",
+		test:        keyFileModes(0600),
+	},
+	{
+		id:          "1.2.1",
+		text:        "Ensure that the --anonymous-auth argument is set to false",
+		remediation: "set spec.api.extraArgs.anonymous-auth: \"false\" in the k0s config",
+		test:        flagEquals("kube-apiserver", "anonymous-auth", "false"),
+	},
+	{
+		id:          "1.2.7",
+		text:        "Ensure that the --authorization-mode argument is not set to AlwaysAllow",
+		remediation: "remove AlwaysAllow from spec.api.extraArgs.authorization-mode in the k0s config",
+		test:        flagContains("kube-apiserver", "authorization-mode", "AlwaysAllow", false),
+	},
+	{
+		id:          "1.2.8",
+		text:        "Ensure that the --authorization-mode argument includes Node",
+		remediation: "add Node to spec.api.extraArgs.authorization-mode in the k0s config",
+		test:        flagContains("kube-apiserver", "authorization-mode", "Node", true),
+	},
+	{
+		id:          "1.2.9",
+		text:        "Ensure that the --authorization-mode argument includes RBAC",
+		remediation: "add RBAC to spec.api.extraArgs.authorization-mode in the k0s config",
+		test:        flagContains("kube-apiserver", "authorization-mode", "RBAC", true),
+	},
+	{
+		id:          "1.2.16",
+		text:        "Ensure that the admission control plugin NodeRestriction is set",
+		remediation: "add NodeRestriction to spec.api.extraArgs.enable-admission-plugins in the k0s config",
+		test:        flagContains("kube-apiserver", "enable-admission-plugins", "NodeRestriction", true),
+	},
+	{
+		id:          "1.2.19",
+		text:        "Ensure that the --insecure-port argument is set to 0",
+		remediation: "set spec.api.extraArgs.insecure-port: \"0\" in the k0s config",
+		test:        flagEquals("kube-apiserver", "insecure-port", "0"),
+	},
+	{
+		id:          "1.2.21",
+		text:        "Ensure that the --profiling argument is set to false",
+		remediation: "set spec.api.extraArgs.profiling: \"false\" in the k0s config",
+		test:        flagEquals("kube-apiserver", "profiling", "false"),
+	},
+	{
+		id:          "1.2.30",
+		text:        "Ensure that the --tls-cert-file and --tls-private-key-file arguments are set as appropriate",
+		remediation: "do not override the kube-apiserver TLS flags in spec.api.extraArgs",
+		test:        flagSet("kube-apiserver", "tls-private-key-file"),
+	},
+	{
+		id:          "1.3.2",
+		text:        "Ensure that the controller manager --profiling argument is set to false",
+		remediation: "set spec.controllerManager.extraArgs.profiling: \"false\" in the k0s config",
+		test:        flagEquals("kube-controller-manager", "profiling", "false"),
+	},
+	{
+		id:          "1.3.7",
+		text:        "Ensure that the controller manager --bind-address argument is set to 127.0.0.1",
+		remediation: "set spec.controllerManager.extraArgs.bind-address: 127.0.0.1 in the k0s config",
+		test:        flagEquals("kube-controller-manager", "bind-address", "127.0.0.1"),
+	},
+	{
+		id:          "1.4.1",
+		text:        "Ensure that the scheduler --profiling argument is set to false",
+		remediation: "set spec.scheduler.extraArgs.profiling: \"false\" in the k0s config",
+		test:        flagEquals("kube-scheduler", "profiling", "false"),
+	},
+	{
+		id:          "1.4.2",
+		text:        "Ensure that the scheduler --bind-address argument is set to 127.0.0.1",
+		remediation: "set spec.scheduler.extraArgs.bind-address: 127.0.0.1 in the k0s config",
+		test:        flagEquals("kube-scheduler", "bind-address", "127.0.0.1"),
+	},
+	{
+		id:          "4.2.1",
+		text:        "Ensure that the kubelet anonymous auth is disabled",
+		remediation: "set authentication.anonymous.enabled: false in the kubelet config of the worker profile",
+		test:        kubeletAnonymousAuth,
+	},
+}
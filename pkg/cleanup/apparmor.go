@@ -0,0 +1,36 @@
+package cleanup
+
+import (
+	"context"
+
+	"github.com/k0sproject/k0s/pkg/install"
+)
+
+type apparmor struct{}
+
+// Name returns the name of the step
+func (a *apparmor) Name() string {
+	return "remove AppArmor profiles step"
+}
+
+// NeedsToRun checks if k0s AppArmor profiles are present on the host
+func (a *apparmor) NeedsToRun() bool {
+	return len(install.InstalledAppArmorProfiles()) > 0
+}
+
+// Run unloads and removes the k0s AppArmor profiles
+func (a *apparmor) Run(ctx context.Context, result *CleanupResult) error {
+	for _, profilePath := range install.InstalledAppArmorProfiles() {
+		result.record(ActionRemoveProfile, profilePath, install.RemoveAppArmorProfile(profilePath))
+	}
+	return nil
+}
+
+// Plan lists the AppArmor profiles to remove
+func (a *apparmor) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	for _, profilePath := range install.InstalledAppArmorProfiles() {
+		actions = append(actions, Action{Action: ActionRemoveProfile, Target: profilePath})
+	}
+	return actions, nil
+}
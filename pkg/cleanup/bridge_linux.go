@@ -1,6 +1,7 @@
 package cleanup
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 
@@ -40,10 +41,12 @@ func (b *bridge) NeedsToRun() bool {
 }
 
 // Run removes found kube-bridge leftovers
-func (b *bridge) Run() error {
-	err := netlink.LinkDel(b.link)
-	if err != nil {
-		return err
-	}
+func (b *bridge) Run(ctx context.Context, result *CleanupResult) error {
+	result.record(ActionDeleteLink, b.link.Attrs().Name, netlink.LinkDel(b.link))
 	return nil
 }
+
+// Plan lists the kube-bridge link to delete
+func (b *bridge) Plan(ctx context.Context) ([]Action, error) {
+	return []Action{{Action: ActionDeleteLink, Target: b.link.Attrs().Name}}, nil
+}
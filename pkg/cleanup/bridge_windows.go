@@ -1,5 +1,7 @@
 package cleanup
 
+import "context"
+
 type bridge struct {
 }
 
@@ -14,6 +16,11 @@ func (b *bridge) NeedsToRun() bool {
 }
 
 // Run removes found kube-bridge leftovers
-func (b *bridge) Run() error {
+func (b *bridge) Run(ctx context.Context, result *CleanupResult) error {
 	return nil
 }
+
+// Plan lists nothing, there are no kube-bridge leftovers on windows
+func (b *bridge) Plan(ctx context.Context) ([]Action, error) {
+	return nil, nil
+}
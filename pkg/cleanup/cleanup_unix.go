@@ -17,8 +17,13 @@ limitations under the License.
 package cleanup
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/k0sproject/k0s/pkg/component/worker"
 
@@ -28,6 +33,20 @@ import (
 )
 
 type Config struct {
+	// DrainGracePeriod enables the eviction of the pods through the API before the containers are force-stopped,
+	// giving them the period for terminating
+	DrainGracePeriod time.Duration
+	// Steps limits the clean-up to the named steps (see StepNames), all of them run if it's empty
+	Steps []string
+	// KeepFirewallRules leaves the iptables and ip6tables rules alone, for hosts with externally managed firewalls
+	KeepFirewallRules bool
+	// PreserveData keeps the data dir, and the users owning the files in it, for restarting k0s with the same
+	// cluster identity
+	PreserveData bool
+	// RemoveImages removes the images of the containers of the kubelet pods from a custom runtime too. They're kept by
+	// default, as a runtime shared with others may use them as well.
+	RemoveImages bool
+
 	cfgFile          string
 	containerd       *containerdConfig
 	containerRuntime runtime.ContainerRuntime
@@ -39,11 +58,12 @@ type Config struct {
 type containerdConfig struct {
 	binPath    string
 	cmd        *exec.Cmd
+	exited     chan struct{}
 	socketPath string
 }
 
 func NewConfig(k0sVars constant.CfgVars, cfgFile string, criSocketPath string) (*Config, error) {
-	runDir := "/run/k0s" // https://github.com/k0sproject/k0s/pull/591/commits/c3f932de85a0b209908ad39b817750efc4987395
+	runDir := k0sVars.RunDir
 
 	var err error
 	var containerdCfg *containerdConfig
@@ -73,39 +93,126 @@ func NewConfig(k0sVars constant.CfgVars, cfgFile string, criSocketPath string) (
 	}, nil
 }
 
-func (c *Config) Cleanup() error {
-	var msg []error
-	cleanupSteps := []Step{
-		&containers{Config: c},
-		&users{Config: c},
-		&services{Config: c},
-		&directories{Config: c},
-		&cni{Config: c},
-		&bridge{},
+// the names of the clean-up steps, as selected with Config.Steps
+const (
+	StepProcesses   = "processes"
+	StepMounts      = "mounts"
+	StepNetns       = "netns"
+	StepContainers  = "containers"
+	StepWinServices = "windows-services"
+	StepUsers       = "users"
+	StepServices    = "services"
+	StepAppArmor    = "apparmor"
+	StepDirectories = "directories"
+	StepCNI         = "cni"
+	StepBridge      = "bridge"
+	StepNetwork     = "network"
+	StepHNS         = "hns"
+)
+
+// StepNames lists the names of the clean-up steps in the order they run
+var StepNames = []string{StepProcesses, StepMounts, StepNetns, StepContainers, StepWinServices, StepUsers, StepServices, StepAppArmor, StepDirectories, StepCNI, StepBridge, StepNetwork, StepHNS}
+
+// ValidateSteps checks that the given step names are known
+func ValidateSteps(names []string) error {
+	for _, name := range names {
+		found := false
+		for _, known := range StepNames {
+			if name == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown clean-up step %q, supported steps: %s", name, strings.Join(StepNames, ", "))
+		}
+	}
+	return nil
+}
+
+// steps returns the selected steps in the order they run. The node is drained first if requested, whatever steps
+// are selected.
+func (c *Config) steps() []Step {
+	all := map[string]Step{
+		StepProcesses:   &processes{Config: c},
+		StepMounts:      &podMounts{name: "pod volume mounts step", dirs: c.kubeletPodDirs},
+		StepNetns:       &podMounts{name: "network namespaces step", dirs: c.netnsDirs},
+		StepContainers:  &containers{Config: c},
+		StepWinServices: &winServices{},
+		StepUsers:       &users{Config: c},
+		StepServices:    &services{Config: c},
+		StepAppArmor:    &apparmor{},
+		StepDirectories: &directories{Config: c},
+		StepCNI:         &cni{Config: c},
+		StepBridge:      &bridge{},
+		StepNetwork:     &network{Config: c},
+		StepHNS:         &hns{},
+	}
+
+	selected := StepNames
+	if len(c.Steps) > 0 {
+		selected = c.Steps
+	}
+	steps := []Step{&drain{Config: c}}
+	for _, name := range StepNames {
+		if name == StepUsers && c.PreserveData {
+			continue
+		}
+		for _, s := range selected {
+			if s == name {
+				steps = append(steps, all[name])
+				break
+			}
+		}
 	}
+	return steps
+}
 
-	for _, step := range cleanupSteps {
+// Cleanup runs the clean-up steps. The outcomes of their operations are recorded in the result, the error
+// summarizes the failed ones. Once ctx is done, the remaining steps are skipped.
+func (c *Config) Cleanup(ctx context.Context) (*CleanupResult, error) {
+	result := &CleanupResult{}
+	for _, step := range c.steps() {
 		if step.NeedsToRun() {
 			logrus.Info("* ", step.Name())
-			err := step.Run()
-			if err != nil {
+			result.step = step.Name()
+			if err := ctx.Err(); err != nil {
+				result.record(ActionRunStep, "", fmt.Errorf("skipped: %w", err))
+				continue
+			}
+			if err := step.Run(ctx, result); err != nil {
 				logrus.Debug(err)
-				msg = append(msg, err)
+				result.record(ActionRunStep, "", err)
 			}
 		}
 	}
-	if len(msg) > 0 {
-		return fmt.Errorf("errors received during clean-up: %v", msg)
-	}
-	return nil
+	return result, result.Err()
 }
 
 // Step interface is used to implement cleanup steps
 type Step interface {
 	// NeedsToRun checks if the step needs to run
 	NeedsToRun() bool
-	// Run impelements specific cleanup operations
-	Run() error
+	// Run impelements specific cleanup operations, recording the outcome of each of them in the result
+	Run(ctx context.Context, result *CleanupResult) error
+	// Plan lists the operations Run would do, without touching anything
+	Plan(ctx context.Context) ([]Action, error)
 	// Name returns name of the step for conveninece
 	Name() string
 }
+
+// kubeletPodDirs returns the pod dirs of the default kubelet root dir and of the one recorded by the worker
+func (c *Config) kubeletPodDirs() []string {
+	dirs := []string{filepath.Join(c.dataDir, "kubelet", "pods")}
+	if data, err := ioutil.ReadFile(c.k0sVars.KubeletRootDirPath); err == nil {
+		if rootDir := filepath.Clean(strings.TrimSpace(string(data))); rootDir != "/" && rootDir != "." {
+			dirs = append(dirs, filepath.Join(rootDir, "pods"))
+		}
+	}
+	return dirs
+}
+
+// netnsDirs returns the dir of the network namespaces of the pods in the k0s run dir
+func (c *Config) netnsDirs() []string {
+	return []string{filepath.Join(c.k0sVars.RunDir, "netns")}
+}
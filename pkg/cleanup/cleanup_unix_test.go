@@ -0,0 +1,67 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/mount-utils"
+)
+
+func TestSelectedSteps(t *testing.T) {
+	c := &Config{Steps: []string{StepCNI, StepContainers}}
+	var names []string
+	for _, step := range c.steps() {
+		names = append(names, step.Name())
+	}
+	// the steps run in their usual order, after draining the node
+	assert.Equal(t, []string{"drain node steps", "containers steps", "CNI leftovers cleanup step"}, names)
+
+	c.Steps = nil
+	assert.Len(t, c.steps(), len(StepNames)+1)
+}
+
+func TestValidateSteps(t *testing.T) {
+	assert.NoError(t, ValidateSteps(nil))
+	assert.NoError(t, ValidateSteps([]string{StepMounts, StepNetns}))
+	assert.Error(t, ValidateSteps([]string{"containers", "kubelet"}))
+}
+
+func TestPreserveDataKeepsUsers(t *testing.T) {
+	c := &Config{PreserveData: true}
+	for _, step := range c.steps() {
+		assert.NotEqual(t, "remove k0s users step:", step.Name())
+	}
+	assert.Len(t, c.steps(), len(StepNames))
+}
+
+func TestMountsUnder(t *testing.T) {
+	procMounts := []mount.MountPoint{
+		{Path: "/var/lib/k0s/kubelet/pods/1234/volumes/kubernetes.io~secret/token"},
+		{Path: "/var/lib/k0s/kubelet/pods"},
+		{Path: "/run/k0s/netns/cni-1234"},
+		// not owned by k0s
+		{Path: "/var/lib/kubelet/pods/5678/volumes/kubernetes.io~secret/token"},
+		{Path: "/run/netns/cni-5678"},
+		{Path: "/home/user/run/netns/test"},
+	}
+	var paths []string
+	for _, v := range mountsUnder(procMounts, []string{"/var/lib/k0s/kubelet/pods", "/run/k0s/netns"}) {
+		paths = append(paths, v.Path)
+	}
+	assert.Equal(t, []string{"/var/lib/k0s/kubelet/pods/1234/volumes/kubernetes.io~secret/token", "/run/k0s/netns/cni-1234"}, paths)
+}
@@ -1,16 +1,53 @@
 package cleanup
 
 import (
-	"fmt"
+	"context"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
 
-	"github.com/k0sproject/k0s/internal/util"
 	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/config"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// CNIProvider knows the leftovers of a network provider on the hosts
+type CNIProvider interface {
+	// Name is the network provider, as in spec.network.provider
+	Name() string
+	// Detect checks if the provider was used on the host, for the resets without a cluster config
+	Detect(confDir string) bool
+	// Leftovers lists the files and the directories the provider leaves behind
+	Leftovers(confDir string) (files []string, dirs []string)
+}
+
+var (
+	cniProvidersMu sync.RWMutex
+	cniProviders   = map[string]CNIProvider{}
 )
 
+// RegisterCNIProvider makes the clean-up of the network provider available, replacing the one registered for the
+// same provider earlier. Downstreams register their providers from init functions.
+func RegisterCNIProvider(provider CNIProvider) {
+	cniProvidersMu.Lock()
+	defer cniProvidersMu.Unlock()
+	cniProviders[provider.Name()] = provider
+}
+
+func init() {
+	RegisterCNIProvider(calicoProvider{})
+	RegisterCNIProvider(kubeRouterProvider{})
+	RegisterCNIProvider(customProvider{})
+}
+
 type cni struct {
 	Config   *Config
 	toRemove []string
+	dirs     []string
 }
 
 // Name returns the name of the step
@@ -20,38 +57,148 @@ func (c *cni) Name() string {
 
 // NeedsToRun checks if there are and CNI leftovers
 func (c *cni) NeedsToRun() bool {
-	files := []string{
-		"/etc/cni/net.d/10-calico.conflist",
-		"/etc/cni/net.d/calico-kubeconfig",
-		"/etc/cni/net.d/10-kuberouter.conflist",
+	c.toRemove, c.dirs = nil, nil
+	providers, confDir := c.providers()
+	for _, provider := range providers {
+		files, dirs := provider.Leftovers(confDir)
+		for _, file := range files {
+			if util.FileExists(file) {
+				c.toRemove = append(c.toRemove, file)
+			}
+		}
+		for _, dir := range dirs {
+			if util.DirExists(dir) {
+				c.dirs = append(c.dirs, dir)
+			}
+		}
 	}
+	return len(c.toRemove) > 0 || len(c.dirs) > 0
+}
 
-	for _, file := range files {
-		if util.FileExists(file) {
-			c.toRemove = append(c.toRemove, file)
+// providers returns the network provider of the cluster config and its CNI config dir. Without a cluster config,
+// e.g. on the workers, the providers are detected from the leftovers in the default CNI config dir.
+func (c *cni) providers() ([]CNIProvider, string) {
+	cniProvidersMu.RLock()
+	defer cniProvidersMu.RUnlock()
+
+	if c.Config.cfgFile != "" {
+		clusterConfig, err := config.GetYamlFromFile(c.Config.cfgFile, c.Config.k0sVars)
+		if err == nil && clusterConfig.Spec.Network != nil {
+			network := clusterConfig.Spec.Network
+			if provider, found := cniProviders[network.Provider]; found {
+				return []CNIProvider{provider}, network.CNIConfDir
+			}
+			logrus.Warnf("no CNI clean-up for the network provider %s, detecting the leftovers", network.Provider)
+		} else if err != nil {
+			logrus.Debugf("failed to read the cluster config, detecting the CNI leftovers: %v", err)
+		}
+	}
+
+	names := make([]string, 0, len(cniProviders))
+	for name := range cniProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var detected []CNIProvider
+	for _, name := range names {
+		if cniProviders[name].Detect(constant.CNIConfDirDefault) {
+			detected = append(detected, cniProviders[name])
 		}
 	}
-	return len(c.toRemove) > 0
+	return detected, constant.CNIConfDirDefault
 }
 
 // Run removes found CNI leftovers
-func (c *cni) Run() error {
-	return removeCNILeftovers(c.toRemove)
+func (c *cni) Run(ctx context.Context, result *CleanupResult) error {
+	for _, file := range c.toRemove {
+		if util.FileExists(file) {
+			result.record(ActionRemoveFile, file, os.Remove(file))
+		}
+	}
+	for _, dir := range c.dirs {
+		result.record(ActionDeleteDir, dir, os.RemoveAll(dir))
+	}
+	return nil
 }
 
-func removeCNILeftovers(files []string) error {
-	var msg []error
+// Plan lists the CNI leftovers to remove
+func (c *cni) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	for _, file := range c.toRemove {
+		actions = append(actions, Action{Action: ActionRemoveFile, Target: file})
+	}
+	for _, dir := range c.dirs {
+		actions = append(actions, Action{Action: ActionDeleteDir, Target: dir})
+	}
+	return actions, nil
+}
+
+type calicoProvider struct{}
+
+func (calicoProvider) Name() string {
+	return "calico"
+}
+
+func (p calicoProvider) Detect(confDir string) bool {
+	return anyExists(p.Leftovers(confDir))
+}
+
+func (calicoProvider) Leftovers(confDir string) ([]string, []string) {
+	if runtime.GOOS == "windows" {
+		// the files installed by the calico for windows bootstrap script
+		return []string{
+			filepath.Join(confDir, "10-calico.conf"),
+			`C:\bootstrap.ps1`,
+			`C:\calico-windows.zip`,
+			`C:\calico-kube-config`,
+		}, []string{`C:\CalicoWindows`}
+	}
+	// the node state is kept in the host paths mounted into calico-node
+	return []string{
+		filepath.Join(confDir, "10-calico.conflist"),
+		filepath.Join(confDir, "calico-kubeconfig"),
+	}, []string{"/var/lib/calico", "/var/run/calico"}
+}
+
+type kubeRouterProvider struct{}
+
+func (kubeRouterProvider) Name() string {
+	return "kuberouter"
+}
 
+func (p kubeRouterProvider) Detect(confDir string) bool {
+	return util.FileExists(filepath.Join(confDir, "10-kuberouter.conflist"))
+}
+
+func (kubeRouterProvider) Leftovers(confDir string) ([]string, []string) {
+	return []string{filepath.Join(confDir, "10-kuberouter.conflist")}, []string{"/var/lib/kube-router"}
+}
+
+// customProvider leaves the CNI set up by the users alone
+type customProvider struct{}
+
+func (customProvider) Name() string {
+	return "custom"
+}
+
+func (customProvider) Detect(confDir string) bool {
+	return false
+}
+
+func (customProvider) Leftovers(confDir string) ([]string, []string) {
+	return nil, nil
+}
+
+func anyExists(files []string, dirs []string) bool {
 	for _, file := range files {
 		if util.FileExists(file) {
-			if err := os.Remove(file); err != nil {
-				logrus.Debug("failed to remove", file, err)
-				msg = append(msg, err)
-			}
+			return true
 		}
 	}
-	if len(msg) > 0 {
-		return fmt.Errorf("error occured while removing CNI leftovers: %v", msg)
+	for _, dir := range dirs {
+		if util.DirExists(dir) {
+			return true
+		}
 	}
-	return nil
+	return false
 }
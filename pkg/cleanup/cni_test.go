@@ -0,0 +1,69 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestCNIProviderFromConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-cni")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	confDir := filepath.Join(dir, "net.d")
+	require.NoError(t, os.MkdirAll(confDir, 0755))
+	for _, file := range []string{"10-calico.conflist", "10-kuberouter.conflist", "99-other.conflist"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, file), []byte("{}"), 0644))
+	}
+	cfgFile := filepath.Join(dir, "k0s.yaml")
+	require.NoError(t, ioutil.WriteFile(cfgFile, []byte(fmt.Sprintf(`
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+metadata:
+  name: k0s
+spec:
+  network:
+    provider: kuberouter
+    cniConfDir: %s
+`, confDir)), 0644))
+
+	c := &cni{Config: &Config{cfgFile: cfgFile, k0sVars: constant.GetConfig(dir)}}
+	providers, providerConfDir := c.providers()
+	require.Len(t, providers, 1)
+	assert.Equal(t, "kuberouter", providers[0].Name())
+	assert.Equal(t, confDir, providerConfDir)
+
+	// only the files of the configured provider are removed
+	assert.True(t, c.NeedsToRun())
+	assert.Equal(t, []string{filepath.Join(confDir, "10-kuberouter.conflist")}, c.toRemove)
+}
+
+func TestCustomCNIProvider(t *testing.T) {
+	files, dirs := customProvider{}.Leftovers(constant.CNIConfDirDefault)
+	assert.Empty(t, files)
+	assert.Empty(t, dirs)
+	assert.False(t, customProvider{}.Detect(constant.CNIConfDirDefault))
+}
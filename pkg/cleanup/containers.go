@@ -1,7 +1,9 @@
 package cleanup
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,7 +11,16 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"k8s.io/mount-utils"
+
+	"github.com/k0sproject/k0s/pkg/container/runtime"
+)
+
+const (
+	// containerdStartTimeout bounds the wait for the embedded containerd to listen on its socket
+	containerdStartTimeout = 30 * time.Second
+	// containerdStopTimeout is the time the embedded containerd is given to exit before it's killed
+	containerdStopTimeout  = 5 * time.Second
+	containerdPollInterval = 100 * time.Millisecond
 )
 
 type containers struct {
@@ -33,60 +44,87 @@ func (c *containers) NeedsToRun() bool {
 	return true
 }
 
-// Run removes all the pods and mounts and stops containers afterwards
+// Run stops and removes all the pods
 // Run starts containerd if custom CRI is not configured
-func (c *containers) Run() error {
+func (c *containers) Run(ctx context.Context, result *CleanupResult) error {
 	if !c.isCustomCriUsed() {
-		if err := c.startContainerd(); err != nil {
+		if err := c.startContainerd(ctx); err != nil {
 			logrus.Debugf("error starting containerd: %v", err)
 			return err
 		}
 	}
 
-	time.Sleep(5 * time.Second)
-
-	if err := c.stopAllContainers(); err != nil {
-		logrus.Debugf("error stopping containers: %v", err)
+	// the images are found from the containers of the pods, so they're listed before the pods are removed
+	images, imagesErr := c.images(ctx)
+	err := c.stopAllContainers(ctx, result)
+	if err == nil {
+		err = imagesErr
+	}
+	if err == nil {
+		c.removeImages(ctx, images, result)
 	}
 
 	if !c.isCustomCriUsed() {
 		c.stopContainerd()
 	}
-	return nil
+	return err
 }
 
-func removeMount(path string) error {
-	var msg []string
+// Plan lists the pods to stop and remove. The running containerd of k0s is asked for them if there is one, the
+// embedded containerd isn't started for the plan: without it, all of its pods are reported as to be removed.
+func (c *containers) Plan(ctx context.Context) ([]Action, error) {
+	if !c.isCustomCriUsed() && !c.containerdListening() {
+		target := fmt.Sprintf("all the pods of %s", c.Config.containerd.socketPath)
+		return []Action{{Action: ActionStopPod, Target: target}, {Action: ActionRemovePod, Target: target}}, nil
+	}
 
-	mounter := mount.New("")
-	procMounts, err := mounter.List()
+	pods, err := c.Config.containerRuntime.ListContainers(ctx)
 	if err != nil {
-		return err
-	}
-	for _, v := range procMounts {
-		if strings.Contains(v.Path, path) {
-			logrus.Debugf("Unmounting: %s", v.Path)
-			if err = mounter.Unmount(v.Path); err != nil {
-				msg = append(msg, err.Error())
-			}
-
-			logrus.Debugf("Removing: %s", v.Path)
-			if err := os.RemoveAll(v.Path); err != nil {
-				msg = append(msg, err.Error())
-			}
-		}
+		return nil, fmt.Errorf("failed to list pods: %w", err)
 	}
-	if len(msg) > 0 {
-		return fmt.Errorf("%v", strings.Join(msg, "\n"))
+	var actions []Action
+	for _, pod := range pods {
+		actions = append(actions, Action{Action: ActionStopPod, Target: pod}, Action{Action: ActionRemovePod, Target: pod})
+	}
+	images, err := c.images(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		actions = append(actions, Action{Action: ActionRemoveImage, Target: image})
+	}
+	return actions, nil
+}
+
+// images lists the images to remove. The embedded containerd keeps its images in the data dir, they go along with it.
+// The images of a custom runtime, which may be shared with others, are only removed with RemoveImages. The runtime is
+// asked for the images of the containers in the pods created by the kubelet then.
+func (c *containers) images(ctx context.Context) ([]string, error) {
+	imageRuntime, ok := c.Config.containerRuntime.(runtime.ImageRuntime)
+	if !ok || !c.isCustomCriUsed() || !c.Config.RemoveImages {
+		return nil, nil
+	}
+	images, err := imageRuntime.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	return images, nil
+}
+
+// removeImages removes the images of the k0s workloads, the images that fail are recorded in the result
+func (c *containers) removeImages(ctx context.Context, images []string, result *CleanupResult) {
+	imageRuntime, _ := c.Config.containerRuntime.(runtime.ImageRuntime)
+	for _, image := range images {
+		result.record(ActionRemoveImage, image, imageRuntime.RemoveImage(ctx, image))
 	}
-	return nil
 }
 
 func (c *containers) isCustomCriUsed() bool {
 	return c.Config.containerd == nil
 }
 
-func (c *containers) startContainerd() error {
+// startContainerd starts the embedded containerd and waits until it listens on its socket
+func (c *containers) startContainerd(ctx context.Context) error {
 	logrus.Debugf("starting containerd")
 	args := []string{
 		fmt.Sprintf("--root=%s", filepath.Join(c.Config.dataDir, "containerd")),
@@ -99,75 +137,85 @@ func (c *containers) startContainerd() error {
 		return fmt.Errorf("failed to start containerd: %v", err)
 	}
 
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
 	c.Config.containerd.cmd = cmd
-	logrus.Debugf("started containerd successfully")
+	c.Config.containerd.exited = exited
+
+	ctx, cancel := context.WithTimeout(ctx, containerdStartTimeout)
+	defer cancel()
+	for {
+		if conn, err := net.Dial("unix", c.Config.containerd.socketPath); err == nil {
+			conn.Close()
+			logrus.Debugf("started containerd successfully")
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("containerd exited before listening on %s", c.Config.containerd.socketPath)
+		case <-ctx.Done():
+			c.stopContainerd()
+			return fmt.Errorf("containerd didn't start listening on %s: %w", c.Config.containerd.socketPath, ctx.Err())
+		case <-time.After(containerdPollInterval):
+		}
+	}
+}
 
-	return nil
+// containerdListening tells if the embedded containerd is already running, listening on its socket
+func (c *containers) containerdListening() bool {
+	conn, err := net.Dial("unix", c.Config.containerd.socketPath)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
 }
 
+// stopContainerd interrupts the embedded containerd, and kills it if it doesn't exit in time
 func (c *containers) stopContainerd() {
 	logrus.Debug("attempting to stop containerd")
 	logrus.Debugf("found containerd pid: %v", c.Config.containerd.cmd.Process.Pid)
 	if err := c.Config.containerd.cmd.Process.Signal(os.Interrupt); err != nil {
 		logrus.Errorf("failed to kill containerd: %v", err)
 	}
-	// if process, didn't exit, wait a few seconds and send SIGKILL
-	if c.Config.containerd.cmd.ProcessState.ExitCode() != -1 {
-		time.Sleep(5 * time.Second)
-
+	select {
+	case <-c.Config.containerd.exited:
+	case <-time.After(containerdStopTimeout):
+		logrus.Debug("containerd didn't exit in time, sending SIGKILL")
 		if err := c.Config.containerd.cmd.Process.Kill(); err != nil {
 			logrus.Errorf("failed to send SIGKILL to containerd: %v", err)
 		}
+		<-c.Config.containerd.exited
 	}
 	logrus.Debug("successfully stopped containerd")
 }
 
-func (c *containers) stopAllContainers() error {
-	var msg []error
+// stopAllContainers stops and removes the pods, the pods that fail are recorded in the result
+func (c *containers) stopAllContainers(ctx context.Context, result *CleanupResult) error {
 	logrus.Debugf("trying to list all pods")
-	pods, err := c.Config.containerRuntime.ListContainers()
+	pods, err := c.Config.containerRuntime.ListContainers(ctx)
 	if err != nil {
-		logrus.Debugf("failed at listing pods %v", err)
-		return err
+		return fmt.Errorf("failed to list pods: %w", err)
 	}
-	if len(pods) > 0 {
-		if err := removeMount("kubelet/pods"); err != nil {
-			msg = append(msg, err)
-		}
-		if err := removeMount("run/netns"); err != nil {
-			msg = append(msg, err)
-		}
-	}
-
 	for _, pod := range pods {
 		logrus.Debugf("stopping container: %v", pod)
-		err := c.Config.containerRuntime.StopContainer(pod)
-		if err != nil {
-			if strings.Contains(err.Error(), "443: connect: connection refused") {
-				// on a single node instance, we will see "connection refused" error. this is to be expected
-				// since we're deleting the API pod itself. so we're ignoring this error
-				logrus.Debugf("ignoring container stop err: %v", err.Error())
-
-			} else {
-				fmtError := fmt.Errorf("failed to stop running pod %v: err: %v", pod, err)
-				logrus.Debug(fmtError)
-				msg = append(msg, fmtError)
-			}
-		}
-		err = c.Config.containerRuntime.RemoveContainer(pod)
-		if err != nil {
-			msg = append(msg, fmt.Errorf("failed to remove pod %v: err: %v", pod, err))
-
+		err := c.Config.containerRuntime.StopContainer(ctx, pod)
+		if err != nil && strings.Contains(err.Error(), "443: connect: connection refused") {
+			// on a single node instance, we will see "connection refused" error. this is to be expected
+			// since we're deleting the API pod itself. so we're ignoring this error
+			logrus.Debugf("ignoring container stop err: %v", err.Error())
+			err = nil
 		}
+		result.record(ActionStopPod, pod, err)
+		result.record(ActionRemovePod, pod, c.Config.containerRuntime.RemoveContainer(ctx, pod))
 	}
 
-	pods, err = c.Config.containerRuntime.ListContainers()
+	pods, err = c.Config.containerRuntime.ListContainers(ctx)
 	if err == nil && len(pods) == 0 {
 		logrus.Info("successfully removed k0s containers!")
 	}
-
-	if len(msg) > 0 {
-		return fmt.Errorf("errors occurred while removing pods: %v", msg)
-	}
 	return nil
 }
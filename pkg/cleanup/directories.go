@@ -1,12 +1,17 @@
 package cleanup
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 	"k8s.io/mount-utils"
+
+	"github.com/k0sproject/k0s/pkg/constant"
 )
 
 type directories struct {
@@ -20,17 +25,16 @@ func (d *directories) Name() string {
 
 // NeedsToRun checks if dataDir and runDir are present on the host
 func (d *directories) NeedsToRun() bool {
-	if _, err := os.Stat(d.Config.dataDir); err == nil {
-		return true
-	}
-	if _, err := os.Stat(d.Config.runDir); err == nil {
-		return true
+	for _, dir := range d.dirs() {
+		if _, err := os.Stat(dir); err == nil {
+			return true
+		}
 	}
-	return false
+	return d.externalKubeletRootDir() != ""
 }
 
 // Run removes all kubelet mounts and deletes generated dataDir and runDir
-func (d *directories) Run() error {
+func (d *directories) Run(ctx context.Context, result *CleanupResult) error {
 	// unmount any leftover overlays (such as in alpine)
 	mounter := mount.New("")
 	procMounts, err := mounter.List()
@@ -38,30 +42,132 @@ func (d *directories) Run() error {
 		return err
 	}
 
-	// search and unmount kubelet volume mounts
+	// the kubelet root dir may be outside of the data dir, or bind-mounted from it
+	if err := d.removeKubeletRootDir(result, mounter, procMounts); err != nil {
+		return err
+	}
+
+	// search and unmount kubelet volume mounts, deleting the dirs with something still mounted would wipe the volumes
 	for _, v := range procMounts {
-		if strings.Compare(v.Path, fmt.Sprintf("%s/kubelet", d.Config.dataDir)) == 0 {
+		if d.isDataDirMount(v.Path) {
 			logrus.Debugf("%v is mounted! attempting to unmount...", v.Path)
-			if err = mounter.Unmount(v.Path); err != nil {
-				logrus.Warningf("failed to unmount %v", v.Path)
+			err := mounter.Unmount(v.Path)
+			result.record(ActionUnmount, v.Path, err)
+			if err != nil {
+				return fmt.Errorf("not deleting the k0s directories, %s is still mounted", v.Path)
 			}
-		} else if strings.Compare(v.Path, d.Config.dataDir) == 0 {
-			logrus.Debugf("%v is mounted! attempting to unmount...", v.Path)
-			if err = mounter.Unmount(v.Path); err != nil {
-				logrus.Warningf("failed to unmount %v", v.Path)
+		}
+	}
+
+	logrus.Debugf("deleting k0s generated dirs: %v", d.dirs())
+	for _, dir := range d.dirs() {
+		if _, err := os.Stat(dir); err == nil {
+			result.record(ActionDeleteDir, dir, os.RemoveAll(dir))
+		}
+	}
+
+	return nil
+}
+
+// dirs returns the directories to delete, the data dir is kept when the data is preserved
+func (d *directories) dirs() []string {
+	if d.Config.PreserveData {
+		return []string{d.Config.runDir}
+	}
+	return []string{d.Config.dataDir, d.Config.runDir}
+}
+
+// isDataDirMount checks if the mount point is the kubelet dir or, unless the data is preserved, the data dir itself
+func (d *directories) isDataDirMount(path string) bool {
+	if path == fmt.Sprintf("%s/kubelet", d.Config.dataDir) {
+		return true
+	}
+	return path == d.Config.dataDir && !d.Config.PreserveData
+}
+
+// Plan lists the mounts to unmount and the directories to delete
+func (d *directories) Plan(ctx context.Context) ([]Action, error) {
+	procMounts, err := mount.New("").List()
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []Action
+	if rootDir := d.externalKubeletRootDir(); rootDir != "" {
+		for i := len(procMounts) - 1; i >= 0; i-- {
+			if v := procMounts[i]; v.Path == rootDir || strings.HasPrefix(v.Path, rootDir+"/") {
+				actions = append(actions, Action{Action: ActionUnmount, Target: v.Path})
 			}
 		}
+		actions = append(actions, Action{Action: ActionDeleteDir, Target: rootDir})
+	}
+	for _, v := range procMounts {
+		if d.isDataDirMount(v.Path) {
+			actions = append(actions, Action{Action: ActionUnmount, Target: v.Path})
+		}
+	}
+	for _, dir := range d.dirs() {
+		if _, err := os.Stat(dir); err == nil {
+			actions = append(actions, Action{Action: ActionDeleteDir, Target: dir})
+		}
+	}
+	return actions, nil
+}
+
+// externalKubeletRootDir returns the kubelet root dir recorded by the worker, if it's not under the data dir and
+// it's owned by k0s. It's kept along with the data dir when the data is preserved.
+func (d *directories) externalKubeletRootDir() string {
+	if d.Config.PreserveData {
+		return ""
 	}
+	data, err := ioutil.ReadFile(d.Config.k0sVars.KubeletRootDirPath)
+	if err != nil {
+		return ""
+	}
+	rootDir := filepath.Clean(strings.TrimSpace(string(data)))
+	if rootDir == "/" || strings.HasPrefix(rootDir, d.Config.dataDir+"/") || strings.HasPrefix(d.Config.dataDir, rootDir+"/") {
+		return ""
+	}
+	if !isOwnedKubeletRootDir(rootDir) {
+		logrus.Debugf("the kubelet root dir %s hasn't been created by k0s, not removing it", rootDir)
+		return ""
+	}
+	return rootDir
+}
+
+// isOwnedKubeletRootDir checks if the kubelet root dir carries the marker written by the worker when creating it
+func isOwnedKubeletRootDir(rootDir string) bool {
+	_, err := os.Stat(filepath.Join(rootDir, constant.KubeletRootDirMarker))
+	return err == nil
+}
 
-	logrus.Debugf("deleting k0s generated data-dir (%v) and run-dir (%v)", d.Config.dataDir, d.Config.runDir)
-	if err := os.RemoveAll(d.Config.dataDir); err != nil {
-		fmtError := fmt.Errorf("failed to delete %v. err: %v", d.Config.dataDir, err)
-		return fmtError
+// removeKubeletRootDir unmounts and deletes the kubelet root dir recorded by the worker, if it's not under the data
+// dir and it's owned by k0s. Nothing is deleted if any of the mounts can't be unmounted.
+func (d *directories) removeKubeletRootDir(result *CleanupResult, mounter mount.Interface, procMounts []mount.MountPoint) error {
+	rootDir := d.externalKubeletRootDir()
+	if rootDir == "" {
+		return nil
 	}
-	if err := os.RemoveAll(d.Config.runDir); err != nil {
-		fmtError := fmt.Errorf("failed to delete %v. err: %v", d.Config.runDir, err)
-		return fmtError
+
+	// unmount the nested volume mounts before the root dir itself
+	for i := len(procMounts) - 1; i >= 0; i-- {
+		v := procMounts[i]
+		if v.Path == rootDir || strings.HasPrefix(v.Path, rootDir+"/") {
+			logrus.Debugf("%v is mounted! attempting to unmount...", v.Path)
+			err := mounter.Unmount(v.Path)
+			result.record(ActionUnmount, v.Path, err)
+			if err != nil {
+				return fmt.Errorf("not deleting the kubelet root dir %s, %s is still mounted", rootDir, v.Path)
+			}
+		}
 	}
 
+	// once unmounted, a bind-mounted root dir shows the mount point, which may have existed before k0s
+	if !isOwnedKubeletRootDir(rootDir) {
+		logrus.Debugf("the kubelet root dir mount point %s hasn't been created by k0s, not removing it", rootDir)
+		return nil
+	}
+	logrus.Debugf("deleting kubelet root dir (%v)", rootDir)
+	result.record(ActionDeleteDir, rootDir, os.RemoveAll(rootDir))
 	return nil
 }
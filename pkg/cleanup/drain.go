@@ -0,0 +1,103 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/component/worker"
+	"github.com/k0sproject/k0s/pkg/node"
+)
+
+// drainConnectTimeout bounds the cordoning of the node, which also tells whether the API is reachable
+const drainConnectTimeout = 10 * time.Second
+
+type drain struct {
+	Config *Config
+}
+
+// Name returns the name of the step
+func (d *drain) Name() string {
+	return "drain node steps"
+}
+
+// NeedsToRun checks if the drain was asked for and the node has joined a cluster
+func (d *drain) NeedsToRun() bool {
+	return d.Config.DrainGracePeriod > 0 && util.FileExists(d.Config.k0sVars.KubeletAuthConfigPath)
+}
+
+// Run cordons the node and evicts its pods through the API, so that the workloads get rescheduled and the pod
+// disruption budgets are honored before the containers are force-stopped. If the API isn't reachable, the drain is
+// skipped and the containers are force-stopped right away.
+func (d *drain) Run(ctx context.Context, result *CleanupResult) error {
+	nodeName, err := worker.NodeName(d.Config.k0sVars)
+	if err != nil {
+		return fmt.Errorf("failed to drain the node: %w", err)
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", d.Config.k0sVars.KubeletAuthConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load kubelet kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	result.record(ActionEvictPods, nodeName, drainNode(ctx, client, nodeName, d.Config.DrainGracePeriod))
+	return nil
+}
+
+func drainNode(ctx context.Context, client kubernetes.Interface, nodeName string, gracePeriod time.Duration) error {
+	// k0s isn't running while resetting, the evicted pods are deleted right after their eviction has been accepted,
+	// as their containers are force-stopped anyway
+	drainer := &node.Drainer{Client: client, GracePeriod: gracePeriod, Progress: logrus.Infof, KubeletStopped: true}
+
+	cordonCtx, cancel := context.WithTimeout(ctx, drainConnectTimeout)
+	defer cancel()
+	if err := drainer.Cordon(cordonCtx, nodeName); err != nil {
+		logrus.Warnf("%v, force-stopping the containers without draining", err)
+		return nil
+	}
+
+	evictCtx, cancel := context.WithTimeout(ctx, gracePeriod)
+	defer cancel()
+	remaining, err := drainer.Evict(evictCtx, nodeName)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		logrus.Warnf("%d pods of node %s haven't been evicted within %s, force-stopping the containers", remaining, nodeName, gracePeriod)
+	}
+	return nil
+}
+
+// Plan lists the drain of the node, the API isn't contacted
+func (d *drain) Plan(ctx context.Context) ([]Action, error) {
+	nodeName, err := worker.NodeName(d.Config.k0sVars)
+	if err != nil {
+		return nil, err
+	}
+	return []Action{
+		{Action: ActionCordonNode, Target: nodeName},
+		{Action: ActionEvictPods, Target: nodeName},
+	}, nil
+}
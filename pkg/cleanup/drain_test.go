@@ -0,0 +1,96 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/k0sproject/k0s/pkg/node"
+)
+
+func nodePod(name string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "worker-1"},
+	}
+	if owner != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+	}
+	return pod
+}
+
+func TestDrainNode(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		nodePod("web", "ReplicaSet"),
+		nodePod("proxy", "DaemonSet"),
+	)
+	retryInterval := node.RetryInterval
+	node.RetryInterval = 10 * time.Millisecond
+	defer func() { node.RetryInterval = retryInterval }()
+
+	// as the API does, an accepted eviction only marks the pod terminating, the kubelet would delete it
+	var evicted []string
+	podsResource := corev1.SchemeGroupVersion.WithResource("pods")
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		evicted = append(evicted, name)
+		obj, err := client.Tracker().Get(podsResource, "default", name)
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*corev1.Pod)
+		now := metav1.Now()
+		pod.DeletionTimestamp = &now
+		return true, nil, client.Tracker().Update(podsResource, pod, "default")
+	})
+	var deleted []string
+	client.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleted = append(deleted, action.(k8stesting.DeleteAction).GetName())
+		return false, nil, nil
+	})
+
+	require.NoError(t, drainNode(context.TODO(), client, "worker-1", time.Minute))
+	assert.Equal(t, []string{"web"}, evicted)
+	// the kubelet is stopped, the evicted pod is deleted by the drain
+	assert.Equal(t, []string{"web"}, deleted)
+	_, err := client.CoreV1().Pods("default").Get(context.TODO(), "web", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	cordoned, err := client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, cordoned.Spec.Unschedulable)
+}
+
+func TestDrainNodeUnreachable(t *testing.T) {
+	// the node can't be cordoned, the containers get force-stopped without draining
+	client := fake.NewSimpleClientset()
+	assert.NoError(t, drainNode(context.TODO(), client, "worker-1", time.Minute))
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import "context"
+
+type hns struct {
+}
+
+// Name returns the name of the step
+func (h *hns) Name() string {
+	return "HNS networks cleanup step"
+}
+
+// NeedsToRun checks if there are HNS networks left
+func (h *hns) NeedsToRun() bool {
+	return false
+}
+
+// Run deletes the HNS networks
+func (h *hns) Run(ctx context.Context, result *CleanupResult) error {
+	return nil
+}
+
+// Plan lists nothing, there is no HNS on linux
+func (h *hns) Plan(ctx context.Context) ([]Action, error) {
+	return nil, nil
+}
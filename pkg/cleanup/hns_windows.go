@@ -0,0 +1,80 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+
+	"github.com/Microsoft/hcsshim"
+)
+
+// the HNS networks created by calico for windows, kube-proxy uses the Calico one
+var hnsNetworkNames = []string{"Calico", "External"}
+
+type hns struct {
+	networks    []hcsshim.HNSNetwork
+	policyLists []hcsshim.PolicyList
+}
+
+// Name returns the name of the step
+func (h *hns) Name() string {
+	return "HNS networks cleanup step"
+}
+
+// NeedsToRun checks if there are calico networks or kube-proxy load balancer policy lists left
+func (h *hns) NeedsToRun() bool {
+	h.networks, h.policyLists = nil, nil
+	networks, err := hcsshim.HNSListNetworkRequest("GET", "", "")
+	if err != nil {
+		return false
+	}
+	for _, n := range networks {
+		for _, name := range hnsNetworkNames {
+			if n.Name == name {
+				h.networks = append(h.networks, n)
+			}
+		}
+	}
+	if policyLists, err := hcsshim.HNSListPolicyListRequest(); err == nil {
+		h.policyLists = policyLists
+	}
+	return len(h.networks) > 0 || len(h.policyLists) > 0
+}
+
+// Run deletes the load balancer policy lists and the networks, the endpoints are deleted with their network
+func (h *hns) Run(ctx context.Context, result *CleanupResult) error {
+	for i := range h.policyLists {
+		_, err := h.policyLists[i].Delete()
+		result.record(ActionDeletePolicyList, h.policyLists[i].ID, err)
+	}
+	for i := range h.networks {
+		_, err := h.networks[i].Delete()
+		result.record(ActionDeleteHNSNetwork, h.networks[i].Name, err)
+	}
+	return nil
+}
+
+// Plan lists the policy lists and networks to delete
+func (h *hns) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	for _, p := range h.policyLists {
+		actions = append(actions, Action{Action: ActionDeletePolicyList, Target: p.ID})
+	}
+	for _, n := range h.networks {
+		actions = append(actions, Action{Action: ActionDeleteHNSNetwork, Target: n.Name})
+	}
+	return actions, nil
+}
@@ -0,0 +1,90 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/mount-utils"
+)
+
+// podMounts unmounts and removes the mounts left behind by the pods, the ones under the given dirs
+type podMounts struct {
+	name string
+	dirs func() []string
+}
+
+// Name returns the name of the step
+func (p *podMounts) Name() string {
+	return p.name
+}
+
+// NeedsToRun checks if there are any matching mounts on the host
+func (p *podMounts) NeedsToRun() bool {
+	mounts, err := matchingMounts(p.dirs())
+	if err != nil {
+		logrus.Debugf("failed to list the mounts: %v", err)
+		return false
+	}
+	return len(mounts) > 0
+}
+
+// Run unmounts and removes the matching mounts
+func (p *podMounts) Run(ctx context.Context, result *CleanupResult) error {
+	return removeMount(result, p.dirs())
+}
+
+// Plan lists the mounts to unmount and remove
+func (p *podMounts) Plan(ctx context.Context) ([]Action, error) {
+	mounts, err := matchingMounts(p.dirs())
+	if err != nil {
+		return nil, err
+	}
+	var actions []Action
+	for _, v := range mounts {
+		actions = append(actions, Action{Action: ActionUnmount, Target: v.Path}, Action{Action: ActionDeleteDir, Target: v.Path})
+	}
+	return actions, nil
+}
+
+// matchingMounts lists the mounts under the given dirs
+func matchingMounts(dirs []string) ([]mount.MountPoint, error) {
+	procMounts, err := mount.New("").List()
+	if err != nil {
+		return nil, err
+	}
+	return mountsUnder(procMounts, dirs), nil
+}
+
+// mountsUnder filters the mounts under the given dirs, the dirs themselves aren't included
+func mountsUnder(procMounts []mount.MountPoint, dirs []string) []mount.MountPoint {
+	var mounts []mount.MountPoint
+	for _, v := range procMounts {
+		for _, dir := range dirs {
+			if strings.HasPrefix(v.Path, filepath.Clean(dir)+"/") {
+				mounts = append(mounts, v)
+				break
+			}
+		}
+	}
+	return mounts
+}
+
+func removeMount(result *CleanupResult, dirs []string) error {
+	mounter := mount.New("")
+	mounts, err := matchingMounts(dirs)
+	if err != nil {
+		return fmt.Errorf("failed to list the mounts: %w", err)
+	}
+	for _, v := range mounts {
+		logrus.Debugf("Unmounting: %s", v.Path)
+		result.record(ActionUnmount, v.Path, mounter.Unmount(v.Path))
+
+		logrus.Debugf("Removing: %s", v.Path)
+		result.record(ActionDeleteDir, v.Path, os.RemoveAll(v.Path))
+	}
+	return nil
+}
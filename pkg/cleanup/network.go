@@ -0,0 +1,154 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// the links created by the CNI plugins and kube-proxy, kube-bridge is removed by the bridge step
+var networkLinkNames = []string{"vxlan.calico", "vxlan-v6.calico", "kube-ipvs0", "kube-dummy-if"}
+
+// the prefixes of the iptables chains created by kube-proxy, kube-router, calico and the CNI plugins
+var firewallChainPrefixes = []string{"KUBE-", "CNI-", "cali-"}
+
+func isFirewallChain(name string) bool {
+	for _, prefix := range firewallChainPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterIptablesSave drops the k0s owned chains and the rules jumping to them from the iptables-save output,
+// the result can be fed to iptables-restore. The removed chains are returned per table.
+func filterIptablesSave(dump string) (string, map[string][]string) {
+	var kept []string
+	removed := map[string][]string{}
+	table := ""
+	for _, line := range strings.Split(dump, "\n") {
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case strings.HasPrefix(line, ":"):
+			chain := strings.Fields(line[1:])
+			if len(chain) > 0 && isFirewallChain(chain[0]) {
+				removed[table] = append(removed[table], chain[0])
+				continue
+			}
+		case strings.HasPrefix(line, "-A "):
+			if isFirewallRule(strings.Fields(line)) {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+// isFirewallRule checks if the rule is in a k0s owned chain or jumps to one
+func isFirewallRule(fields []string) bool {
+	if len(fields) > 1 && isFirewallChain(fields[1]) {
+		return true
+	}
+	for i := 2; i < len(fields)-1; i++ {
+		switch fields[i] {
+		case "-j", "--jump", "-g", "--goto":
+			if isFirewallChain(fields[i+1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ipvsService is a virtual service of the ipvs table
+type ipvsService struct {
+	protocol string
+	address  net.IP
+	port     int
+}
+
+func (s ipvsService) String() string {
+	return fmt.Sprintf("%s %s", s.protocol, net.JoinHostPort(s.address.String(), strconv.Itoa(s.port)))
+}
+
+// kubeProxyIPVSServices returns the virtual services of /proc/net/ip_vs created by kube-proxy: the ones of the
+// addresses bound to kube-ipvs0, which kube-proxy binds all the service IPs to, and the ones of the node ports
+func kubeProxyIPVSServices(table string, serviceIPs []net.IP, firstNodePort, lastNodePort int) []ipvsService {
+	var services []ipvsService
+	for _, line := range strings.Split(table, "\n") {
+		// the real servers of the services are indented, the firewall mark services have no address
+		fields := strings.Fields(line)
+		if strings.HasPrefix(line, " ") || len(fields) < 2 {
+			continue
+		}
+		var protocol string
+		switch fields[0] {
+		case "TCP", "UDP", "SCTP":
+			protocol = fields[0]
+		default:
+			continue
+		}
+		address, port, err := parseIPVSAddress(fields[1])
+		if err != nil {
+			continue
+		}
+		service := ipvsService{protocol: protocol, address: address, port: port}
+		if port >= firstNodePort && port <= lastNodePort {
+			services = append(services, service)
+			continue
+		}
+		for _, ip := range serviceIPs {
+			if ip.Equal(address) {
+				services = append(services, service)
+				break
+			}
+		}
+	}
+	return services
+}
+
+// parseIPVSAddress parses the hex encoded IPv4 addresses, e.g. 0A600001:01BB, and the bracketed IPv6 addresses,
+// e.g. [fd00:0000:0000:0000:0000:0000:0000:0001]:01BB, of /proc/net/ip_vs
+func parseIPVSAddress(s string) (net.IP, int, error) {
+	i := strings.LastIndex(s, ":")
+	if i == -1 {
+		return nil, 0, fmt.Errorf("no port in %q", s)
+	}
+	port, err := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+	host := s[:i]
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		ip := net.ParseIP(host[1 : len(host)-1])
+		if ip == nil {
+			return nil, 0, fmt.Errorf("invalid address %q", host)
+		}
+		return ip, int(port), nil
+	}
+	ip, err := hex.DecodeString(host)
+	if err != nil || len(ip) != net.IPv4len {
+		return nil, 0, fmt.Errorf("invalid address %q", host)
+	}
+	return net.IP(ip), int(port), nil
+}
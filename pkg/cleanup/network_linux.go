@@ -0,0 +1,223 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/config"
+)
+
+type network struct {
+	Config *Config
+
+	links        []netlink.Link
+	firewall     []firewallRules
+	ipvsServices []ipvsService
+}
+
+// firewallRules are the rules of iptables or ip6tables without the k0s owned chains
+type firewallRules struct {
+	bin      string
+	restore  string
+	filtered string
+	removed  map[string][]string
+}
+
+// Name returns the name of the step
+func (n *network) Name() string {
+	return "network leftovers cleanup step"
+}
+
+// NeedsToRun checks if there are CNI links, k0s owned firewall chains or kube-proxy ipvs services left
+func (n *network) NeedsToRun() bool {
+	n.links, n.firewall, n.ipvsServices = nil, nil, nil
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		logrus.Debugf("failed to list the network links: %v", err)
+	}
+	var serviceIPs []net.IP
+	for _, l := range links {
+		if isNetworkLink(l) {
+			n.links = append(n.links, l)
+		}
+		if l.Attrs().Name == "kube-ipvs0" {
+			addrs, err := netlink.AddrList(l, netlink.FAMILY_ALL)
+			if err != nil {
+				logrus.Debugf("failed to list the addresses of kube-ipvs0: %v", err)
+			}
+			for _, addr := range addrs {
+				serviceIPs = append(serviceIPs, addr.IP)
+			}
+		}
+	}
+
+	if !n.Config.KeepFirewallRules {
+		for _, bin := range []string{"iptables", "ip6tables"} {
+			rules, err := readFirewallRules(bin)
+			if err != nil {
+				logrus.Debugf("skipping the %s clean-up: %v", bin, err)
+				continue
+			}
+			if len(rules.removed) > 0 {
+				n.firewall = append(n.firewall, *rules)
+			}
+		}
+	}
+
+	if data, err := ioutil.ReadFile("/proc/net/ip_vs"); err == nil {
+		firstNodePort, lastNodePort := n.nodePorts()
+		n.ipvsServices = kubeProxyIPVSServices(string(data), serviceIPs, firstNodePort, lastNodePort)
+	}
+
+	return len(n.links) > 0 || len(n.firewall) > 0 || len(n.ipvsServices) > 0
+}
+
+// nodePorts returns the node port range of the cluster config, or the default one without a cluster config
+func (n *network) nodePorts() (int, int) {
+	spec := v1beta1.DefaultNetwork()
+	if n.Config.cfgFile != "" {
+		clusterConfig, err := config.GetYamlFromFile(n.Config.cfgFile, n.Config.k0sVars)
+		if err == nil && clusterConfig.Spec.Network != nil {
+			spec = clusterConfig.Spec.Network
+		} else if err != nil {
+			logrus.Debugf("failed to read the cluster config, using the default node port range: %v", err)
+		}
+	}
+	first, last, err := spec.NodePorts()
+	if err != nil {
+		first, last, _ = v1beta1.DefaultNetwork().NodePorts()
+	}
+	return first, last
+}
+
+func isNetworkLink(l netlink.Link) bool {
+	name := l.Attrs().Name
+	for _, linkName := range networkLinkNames {
+		if name == linkName {
+			return true
+		}
+	}
+	// the pod ends of the calico veths are gone with the network namespaces, the host ends may be left behind
+	return strings.HasPrefix(name, "cali") && l.Type() == "veth"
+}
+
+func readFirewallRules(bin string) (*firewallRules, error) {
+	save, err := exec.LookPath(bin + "-save")
+	if err != nil {
+		return nil, err
+	}
+	restore, err := exec.LookPath(bin + "-restore")
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command(save).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", save, err)
+	}
+	filtered, removed := filterIptablesSave(string(out))
+	return &firewallRules{bin: bin, restore: restore, filtered: filtered, removed: removed}, nil
+}
+
+// Run deletes the found links, removes the k0s owned firewall chains and deletes the kube-proxy ipvs services
+func (n *network) Run(ctx context.Context, result *CleanupResult) error {
+	for _, l := range n.links {
+		result.record(ActionDeleteLink, l.Attrs().Name, netlink.LinkDel(l))
+	}
+
+	for _, found := range n.firewall {
+		// the rules are saved again right before restoring them, so the rules changed since NeedsToRun aren't lost
+		rules, err := readFirewallRules(found.bin)
+		if err == nil {
+			cmd := exec.CommandContext(ctx, rules.restore)
+			cmd.Stdin = strings.NewReader(rules.filtered)
+			if out, restoreErr := cmd.CombinedOutput(); restoreErr != nil {
+				err = fmt.Errorf("%s failed: %w: %s", rules.restore, restoreErr, strings.TrimSpace(string(out)))
+			}
+		} else {
+			rules = &found
+		}
+		for _, target := range rules.targets() {
+			result.record(ActionRemoveChains, target, err)
+		}
+	}
+
+	if len(n.ipvsServices) > 0 {
+		ipvsadm, lookErr := exec.LookPath("ipvsadm")
+		for _, service := range n.ipvsServices {
+			err := fmt.Errorf("ipvsadm is needed for deleting the ipvs services: %w", lookErr)
+			if lookErr == nil {
+				err = deleteIPVSService(ctx, ipvsadm, service)
+			}
+			result.record(ActionDeleteIPVS, service.String(), err)
+		}
+	}
+	return nil
+}
+
+// deleteIPVSService deletes the virtual service with ipvsadm
+func deleteIPVSService(ctx context.Context, ipvsadm string, service ipvsService) error {
+	flag := map[string]string{"TCP": "--tcp-service", "UDP": "--udp-service", "SCTP": "--sctp-service"}[service.protocol]
+	address := net.JoinHostPort(service.address.String(), strconv.Itoa(service.port))
+	if out, err := exec.CommandContext(ctx, ipvsadm, "--delete-service", flag, address).CombinedOutput(); err != nil {
+		return fmt.Errorf("ipvsadm failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// targets describes the removed chains per table
+func (r firewallRules) targets() []string {
+	var tables []string
+	for table := range r.removed {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var targets []string
+	for _, table := range tables {
+		targets = append(targets, fmt.Sprintf("%s %s table (%d chains)", r.bin, table, len(r.removed[table])))
+	}
+	return targets
+}
+
+// Plan lists the links to delete, the firewall chains to remove and the ipvs services to clear
+func (n *network) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	for _, l := range n.links {
+		actions = append(actions, Action{Action: ActionDeleteLink, Target: l.Attrs().Name})
+	}
+	for _, rules := range n.firewall {
+		for _, target := range rules.targets() {
+			actions = append(actions, Action{Action: ActionRemoveChains, Target: target})
+		}
+	}
+	for _, service := range n.ipvsServices {
+		actions = append(actions, Action{Action: ActionDeleteIPVS, Target: service.String()})
+	}
+	return actions, nil
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const iptablesSave = `# Generated by iptables-save v1.8.4 on Mon Aug  2 10:00:00 2021
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+:KUBE-SERVICES - [0:0]
+:KUBE-POSTROUTING - [0:0]
+:CNI-HOSTPORT-DNAT - [0:0]
+:DOCKER - [0:0]
+-A PREROUTING -m comment --comment "kubernetes service portals" -j KUBE-SERVICES
+-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER
+-A POSTROUTING -j KUBE-POSTROUTING
+-A POSTROUTING -s 172.17.0.0/16 ! -o docker0 -j MASQUERADE
+-A KUBE-POSTROUTING -m mark ! --mark 0x4000/0x4000 -j RETURN
+COMMIT
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD DROP [0:0]
+:cali-FORWARD - [0:0]
+-A FORWARD -m comment --comment "cali:wUHhoiAYhphO9Mso" -g cali-FORWARD
+-A FORWARD -i eth0 -j ACCEPT
+COMMIT
+`
+
+func TestFilterIptablesSave(t *testing.T) {
+	filtered, removed := filterIptablesSave(iptablesSave)
+
+	assert.Equal(t, map[string][]string{
+		"nat":    {"KUBE-SERVICES", "KUBE-POSTROUTING", "CNI-HOSTPORT-DNAT"},
+		"filter": {"cali-FORWARD"},
+	}, removed)
+	assert.Equal(t, `# Generated by iptables-save v1.8.4 on Mon Aug  2 10:00:00 2021
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+:DOCKER - [0:0]
+-A PREROUTING -m addrtype --dst-type LOCAL -j DOCKER
+-A POSTROUTING -s 172.17.0.0/16 ! -o docker0 -j MASQUERADE
+COMMIT
+*filter
+:INPUT ACCEPT [0:0]
+:FORWARD DROP [0:0]
+-A FORWARD -i eth0 -j ACCEPT
+COMMIT
+`, filtered)
+
+	_, removed = filterIptablesSave("*nat\n:PREROUTING ACCEPT [0:0]\nCOMMIT\n")
+	assert.Empty(t, removed)
+}
+
+func TestKubeProxyIPVSServices(t *testing.T) {
+	table := `IP Virtual Server version 1.2.1 (size=4096)
+Prot LocalAddress:Port Scheduler Flags
+  -> RemoteAddress:Port Forward Weight ActiveConn InActConn
+TCP  0A600001:01BB rr
+  -> 0A000002:192B      Masq    1      0          0
+UDP  0A60000A:0035 rr
+TCP  C0A80105:7A12 rr
+TCP  C0A80105:0050 wlc
+TCP  [fd00:0000:0000:0000:0000:0000:0000:000a]:0035 rr
+FWM  00000001 rr
+`
+	serviceIPs := []net.IP{net.ParseIP("10.96.0.1"), net.ParseIP("10.96.0.10"), net.ParseIP("fd00::a")}
+	services := kubeProxyIPVSServices(table, serviceIPs, 30000, 32767)
+
+	var names []string
+	for _, s := range services {
+		names = append(names, s.String())
+	}
+	// the service on port 80 of the node address isn't one of kube-proxy
+	assert.Equal(t, []string{"TCP 10.96.0.1:443", "UDP 10.96.0.10:53", "TCP 192.168.1.5:31250", "TCP [fd00::a]:53"}, names)
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import "context"
+
+type network struct {
+	Config *Config
+}
+
+// Name returns the name of the step
+func (n *network) Name() string {
+	return "network leftovers cleanup step"
+}
+
+// NeedsToRun checks if there are network leftovers
+func (n *network) NeedsToRun() bool {
+	return false
+}
+
+// Run removes found network leftovers
+func (n *network) Run(ctx context.Context, result *CleanupResult) error {
+	return nil
+}
+
+// Plan lists nothing, there are no network leftovers on windows
+func (n *network) Plan(ctx context.Context) ([]Action, error) {
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"fmt"
+)
+
+// the operations of the clean-up steps, as listed by a dry run
+const (
+	ActionCordonNode       = "cordon node"
+	ActionEvictPods        = "evict pods of node"
+	ActionStopPod          = "stop pod"
+	ActionRemovePod        = "remove pod"
+	ActionRemoveImage      = "remove image"
+	ActionUnmount          = "unmount"
+	ActionDeleteDir        = "delete directory"
+	ActionRemoveFile       = "remove file"
+	ActionDeleteUser       = "delete user"
+	ActionUninstallService = "uninstall service"
+	ActionRemoveProfile    = "unload and remove AppArmor profile"
+	ActionDeleteLink       = "delete network link"
+	ActionRemoveChains     = "remove firewall chains"
+	ActionDeleteIPVS       = "delete ipvs service"
+	ActionStopService      = "stop service"
+	ActionDeleteHNSNetwork = "delete HNS network"
+	ActionDeletePolicyList = "delete HNS policy list"
+	ActionTerminateProcess = "terminate process"
+)
+
+// Action is an operation that a clean-up step does on the host
+type Action struct {
+	Step   string `json:"step"`
+	Action string `json:"action"`
+	Target string `json:"target"`
+}
+
+// String formats the action for humans
+func (a Action) String() string {
+	return fmt.Sprintf("%s %s", a.Action, a.Target)
+}
+
+// Plan lists the operations the clean-up would do, without touching anything. Listing the containers of the
+// embedded containerd needs it running, so it's started for the time of the listing.
+func (c *Config) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	var msg []error
+	for _, step := range c.steps() {
+		if !step.NeedsToRun() {
+			continue
+		}
+		stepActions, err := step.Plan(ctx)
+		if err != nil {
+			msg = append(msg, fmt.Errorf("%s: %w", step.Name(), err))
+		}
+		for _, a := range stepActions {
+			a.Step = step.Name()
+			actions = append(actions, a)
+		}
+	}
+	if len(msg) > 0 {
+		return actions, fmt.Errorf("errors received during clean-up dry run: %v", msg)
+	}
+	return actions, nil
+}
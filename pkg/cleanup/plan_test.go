@@ -0,0 +1,105 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestDirectoriesPlan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-cleanup-plan")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	k0sVars := constant.GetConfigWithRunDir(filepath.Join(dir, "data"), filepath.Join(dir, "run"))
+	require.NoError(t, os.MkdirAll(k0sVars.DataDir, 0755))
+	rootDir := filepath.Join(dir, "kubelet")
+	require.NoError(t, os.MkdirAll(rootDir, 0755))
+	require.NoError(t, ioutil.WriteFile(k0sVars.KubeletRootDirPath, []byte(rootDir), 0644))
+
+	// the kubelet root dir isn't touched unless it's been created by k0s
+	d := &directories{Config: &Config{dataDir: k0sVars.DataDir, runDir: k0sVars.RunDir, k0sVars: k0sVars}}
+	actions, err := d.Plan(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []Action{{Action: ActionDeleteDir, Target: k0sVars.DataDir}}, actions)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(rootDir, constant.KubeletRootDirMarker), nil, 0644))
+	actions, err = d.Plan(context.TODO())
+	require.NoError(t, err)
+	// the run dir doesn't exist, there's nothing to delete there
+	assert.Equal(t, []Action{
+		{Action: ActionDeleteDir, Target: rootDir},
+		{Action: ActionDeleteDir, Target: k0sVars.DataDir},
+	}, actions)
+
+	// the data dir and the kubelet root dir are kept when the data is preserved
+	d.Config.PreserveData = true
+	actions, err = d.Plan(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, actions)
+
+	// nothing has been touched
+	assert.DirExists(t, k0sVars.DataDir)
+	assert.FileExists(t, k0sVars.KubeletRootDirPath)
+}
+
+func TestCNIPlan(t *testing.T) {
+	c := &cni{toRemove: []string{"/etc/cni/net.d/10-kuberouter.conflist"}}
+	actions, err := c.Plan(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, "remove file /etc/cni/net.d/10-kuberouter.conflist", actions[0].String())
+}
+
+// fakeContainerd serves the pods and the images of the CRI namespace of a shared containerd
+type fakeContainerd struct {
+	pods, images []string
+}
+
+func (f *fakeContainerd) ListContainers(context.Context) ([]string, error) { return f.pods, nil }
+func (f *fakeContainerd) RemoveContainer(context.Context, string) error    { return nil }
+func (f *fakeContainerd) StopContainer(context.Context, string) error      { return nil }
+func (f *fakeContainerd) ListImages(context.Context) ([]string, error)     { return f.images, nil }
+func (f *fakeContainerd) RemoveImage(context.Context, string) error        { return nil }
+
+func TestContainersPlan(t *testing.T) {
+	rt := &fakeContainerd{pods: []string{"pod"}, images: []string{"docker.io/library/nginx:1.21"}}
+	c := &containers{Config: &Config{containerRuntime: rt}}
+	actions, err := c.Plan(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []Action{
+		{Action: ActionStopPod, Target: "pod"},
+		{Action: ActionRemovePod, Target: "pod"},
+	}, actions, "the images of a shared runtime are kept by default")
+
+	c.Config.RemoveImages = true
+	actions, err = c.Plan(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []Action{
+		{Action: ActionStopPod, Target: "pod"},
+		{Action: ActionRemovePod, Target: "pod"},
+		{Action: ActionRemoveImage, Target: "docker.io/library/nginx:1.21"},
+	}, actions)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/k0sproject/k0s/pkg/supervisor"
+)
+
+// process is a k0s managed process left running, e.g. after k0s crashed
+type process struct {
+	pid  int
+	name string
+}
+
+func (p process) String() string {
+	return fmt.Sprintf("%s (pid %d)", p.name, p.pid)
+}
+
+// isManagedProcess checks if the process runs a binary from the k0s data dir or was started by the k0s supervisor.
+// The containerd shims are left alone, the containers step stops the containers through them.
+func isManagedProcess(exe string, environ []byte, dataDir string) bool {
+	// the binaries replaced by an upgrade are still running
+	exe = strings.TrimSuffix(exe, " (deleted)")
+	if strings.HasPrefix(filepath.Base(exe), "containerd-shim") {
+		return false
+	}
+	if exe != "" && strings.HasPrefix(exe, filepath.Clean(dataDir)+string(filepath.Separator)) {
+		return true
+	}
+	for _, env := range strings.Split(string(environ), "\x00") {
+		if strings.HasPrefix(env, supervisor.ManagedEnv+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// processStopTimeout is the time the k0s managed processes are given to exit before they're killed
+	processStopTimeout  = 10 * time.Second
+	processPollInterval = 100 * time.Millisecond
+)
+
+type processes struct {
+	Config *Config
+
+	found []process
+}
+
+// Name returns the name of the step
+func (p *processes) Name() string {
+	return "orphaned processes step"
+}
+
+// NeedsToRun checks if there are k0s managed processes still running
+func (p *processes) NeedsToRun() bool {
+	found, err := findManagedProcesses(p.Config.dataDir)
+	if err != nil {
+		logrus.Debugf("failed to look for the k0s managed processes: %v", err)
+	}
+	p.found = found
+	return len(p.found) > 0
+}
+
+// Run terminates the k0s managed processes, the ones still running after processStopTimeout are killed
+func (p *processes) Run(ctx context.Context, result *CleanupResult) error {
+	for _, proc := range p.found {
+		logrus.Infof("terminating %s", proc)
+		if err := syscall.Kill(proc.pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+			logrus.Debugf("failed to terminate %s: %v", proc, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, processStopTimeout)
+	defer cancel()
+	remaining := stillRunning(p.found)
+	for len(remaining) > 0 && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-time.After(processPollInterval):
+		}
+		remaining = stillRunning(remaining)
+	}
+
+	killed := map[int]error{}
+	for _, proc := range remaining {
+		logrus.Warnf("%s didn't terminate, killing it", proc)
+		if err := syscall.Kill(proc.pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			killed[proc.pid] = err
+		}
+	}
+	for _, proc := range p.found {
+		result.record(ActionTerminateProcess, proc.String(), killed[proc.pid])
+	}
+	return nil
+}
+
+// Plan lists the k0s managed processes to terminate
+func (p *processes) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	for _, proc := range p.found {
+		actions = append(actions, Action{Action: ActionTerminateProcess, Target: proc.String()})
+	}
+	return actions, nil
+}
+
+// findManagedProcesses scans /proc for the k0s managed processes, other than the current one
+func findManagedProcesses(dataDir string) ([]process, error) {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var found []process
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		// the kernel threads have no exe, the processes may exit while they're inspected
+		exe, _ := os.Readlink(filepath.Join(dir, "exe"))
+		environ, _ := ioutil.ReadFile(filepath.Join(dir, "environ"))
+		if !isManagedProcess(exe, environ, dataDir) {
+			continue
+		}
+		comm, err := ioutil.ReadFile(filepath.Join(dir, "comm"))
+		if err != nil {
+			continue
+		}
+		found = append(found, process{pid: pid, name: strings.TrimSpace(string(comm))})
+	}
+	return found, nil
+}
+
+func stillRunning(procs []process) []process {
+	var running []process
+	for _, proc := range procs {
+		if err := syscall.Kill(proc.pid, 0); err == nil || err == syscall.EPERM {
+			if !isZombie(proc.pid) {
+				running = append(running, proc)
+			}
+		}
+	}
+	return running
+}
+
+// isZombie checks if the process has exited but not been reaped yet, its parent crashed along with k0s
+func isZombie(pid int) bool {
+	stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// the state follows the command name in parentheses, which may contain spaces
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsManagedProcess(t *testing.T) {
+	managedEnv := []byte("PATH=/var/lib/k0s/bin:/usr/bin\x00_KOS_MANAGED=yes\x00HOME=/root\x00")
+	otherEnv := []byte("PATH=/usr/bin\x00HOME=/root\x00")
+
+	assert.True(t, isManagedProcess("/var/lib/k0s/bin/kubelet", otherEnv, "/var/lib/k0s"))
+	assert.True(t, isManagedProcess("/var/lib/k0s/bin/etcd (deleted)", nil, "/var/lib/k0s/"))
+	assert.True(t, isManagedProcess("/usr/bin/iptables", managedEnv, "/var/lib/k0s"))
+	assert.False(t, isManagedProcess("/var/lib/k0s-other/bin/kubelet", otherEnv, "/var/lib/k0s"))
+	assert.False(t, isManagedProcess("/usr/sbin/sshd", otherEnv, "/var/lib/k0s"))
+	assert.False(t, isManagedProcess("", nil, "/var/lib/k0s"))
+	// the containers are stopped through the shims
+	assert.False(t, isManagedProcess("/var/lib/k0s/bin/containerd-shim-runc-v2", managedEnv, "/var/lib/k0s"))
+}
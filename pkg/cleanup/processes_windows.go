@@ -0,0 +1,42 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import "context"
+
+type processes struct {
+	Config *Config
+}
+
+// Name returns the name of the step
+func (p *processes) Name() string {
+	return "orphaned processes step"
+}
+
+// NeedsToRun checks if there are k0s managed processes still running
+func (p *processes) NeedsToRun() bool {
+	return false
+}
+
+// Run terminates the k0s managed processes
+func (p *processes) Run(ctx context.Context, result *CleanupResult) error {
+	return nil
+}
+
+// Plan lists nothing, the k0s managed processes are stopped with the windows services
+func (p *processes) Plan(ctx context.Context) ([]Action, error) {
+	return nil, nil
+}
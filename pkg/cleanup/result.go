@@ -0,0 +1,79 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ActionRunStep records the failures of the steps that aren't bound to a single item
+const ActionRunStep = "run step"
+
+// ItemResult is the outcome of a single operation of the clean-up
+type ItemResult struct {
+	Step   string `json:"step"`
+	Action string `json:"action"`
+	Target string `json:"target,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CleanupResult records the outcomes of the operations done by the clean-up steps
+type CleanupResult struct {
+	Items []ItemResult `json:"items"`
+
+	step string
+}
+
+// record adds the outcome of the action on the target, err is nil on success
+func (r *CleanupResult) record(action string, target string, err error) {
+	item := ItemResult{Step: r.step, Action: action, Target: target}
+	if err != nil {
+		logrus.Debugf("failed to %s %s: %v", action, target, err)
+		item.Error = err.Error()
+	}
+	r.Items = append(r.Items, item)
+}
+
+// Failed returns the items that failed to clean up
+func (r *CleanupResult) Failed() []ItemResult {
+	var failed []ItemResult
+	for _, item := range r.Items {
+		if item.Error != "" {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// Err summarizes the failed items, it's nil if everything got cleaned up
+func (r *CleanupResult) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	msg := make([]string, 0, len(failed))
+	for _, item := range failed {
+		if item.Target == "" {
+			msg = append(msg, fmt.Sprintf("%s: %s", item.Step, item.Error))
+		} else {
+			msg = append(msg, fmt.Sprintf("%s %s: %s", item.Action, item.Target, item.Error))
+		}
+	}
+	return fmt.Errorf("%d clean-up operations failed: %s", len(failed), strings.Join(msg, "; "))
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-cleanup-result")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	leftover := filepath.Join(dir, "10-kuberouter.conflist")
+	require.NoError(t, ioutil.WriteFile(leftover, []byte("{}"), 0644))
+
+	result := &CleanupResult{step: "CNI leftovers cleanup step"}
+	// the files that are gone already aren't recorded
+	c := &cni{toRemove: []string{leftover, filepath.Join(dir, "calico-kubeconfig")}}
+	require.NoError(t, c.Run(context.TODO(), result))
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, ItemResult{Step: "CNI leftovers cleanup step", Action: ActionRemoveFile, Target: leftover}, result.Items[0])
+	assert.NoFileExists(t, leftover)
+	assert.NoError(t, result.Err())
+
+	result.step = "remove directories step"
+	result.record(ActionUnmount, "/var/lib/k0s/kubelet", errors.New("device or resource busy"))
+	failed := result.Failed()
+	require.Len(t, failed, 1)
+	assert.Equal(t, ItemResult{Step: "remove directories step", Action: ActionUnmount, Target: "/var/lib/k0s/kubelet", Error: "device or resource busy"}, failed[0])
+	assert.EqualError(t, result.Err(), "1 clean-up operations failed: unmount /var/lib/k0s/kubelet: device or resource busy")
+}
+
+func TestCleanupResultStepFailure(t *testing.T) {
+	result := &CleanupResult{step: "containers steps"}
+	result.record(ActionRunStep, "", errors.New("failed to list pods"))
+	assert.EqualError(t, result.Err(), "1 clean-up operations failed: containers steps: failed to list pods")
+}
@@ -1,16 +1,18 @@
 package cleanup
 
 import (
-	"fmt"
-	"strings"
+	"context"
+	"os"
 
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/k0sproject/k0s/pkg/install"
-	"github.com/sirupsen/logrus"
 )
 
 type services struct {
-	Config *Config
-	roles  []string
+	Config   *Config
+	roles    []string
+	packaged []string
 }
 
 // Name returns the name of the step
@@ -27,22 +29,40 @@ func (s *services) NeedsToRun() bool {
 		if _, stub, err := install.GetSysInit(prole); err == nil && stub != "" {
 			s.roles = append(s.roles, prole)
 		}
+		if util.FileExists(install.PackagedServiceDropIn(prole)) {
+			s.packaged = append(s.packaged, prole)
+		}
 	}
 
-	return len(s.roles) > 0
+	return len(s.roles) > 0 || len(s.packaged) > 0 || util.FileExists(constant.InstallStatePath)
 }
 
 // Run uninstalls k0s services that are found on the host
-func (s *services) Run() error {
-	var msg []string
+func (s *services) Run(ctx context.Context, result *CleanupResult) error {
 	for _, role := range s.roles {
-		if err := install.UninstallService(role); err != nil {
-			logrus.Debugf("Tried removing service: %v", err)
-			msg = append(msg, err.Error())
-		}
+		result.record(ActionUninstallService, "k0s"+role, install.UninstallService(role))
+	}
+	// the units shipped by the distro packages are removed with the package, only the k0s drop-ins are removed here
+	for _, role := range s.packaged {
+		result.record(ActionRemoveFile, install.PackagedServiceDropIn(role), install.RemovePackagedService(role))
 	}
-	if len(msg) > 0 {
-		return fmt.Errorf("%v", strings.Join(msg, "\n"))
+	if util.FileExists(constant.InstallStatePath) {
+		result.record(ActionRemoveFile, constant.InstallStatePath, os.Remove(constant.InstallStatePath))
 	}
 	return nil
 }
+
+// Plan lists the k0s services and the install state to remove
+func (s *services) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	for _, role := range s.roles {
+		actions = append(actions, Action{Action: ActionUninstallService, Target: "k0s" + role})
+	}
+	for _, role := range s.packaged {
+		actions = append(actions, Action{Action: ActionRemoveFile, Target: install.PackagedServiceDropIn(role)})
+	}
+	if util.FileExists(constant.InstallStatePath) {
+		actions = append(actions, Action{Action: ActionRemoveFile, Target: constant.InstallStatePath})
+	}
+	return actions, nil
+}
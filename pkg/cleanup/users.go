@@ -1,10 +1,12 @@
 package cleanup
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/k0sproject/k0s/pkg/config"
 	"github.com/k0sproject/k0s/pkg/install"
-	"github.com/sirupsen/logrus"
 )
 
 type users struct {
@@ -33,15 +35,30 @@ func (u *users) NeedsToRun() bool {
 }
 
 // Run removes all controller users that are present on the host
-func (u *users) Run() error {
-	logger := logrus.New()
+func (u *users) Run(ctx context.Context, result *CleanupResult) error {
 	clusterConfig, err := config.GetYamlFromFile(u.Config.cfgFile, u.Config.k0sVars)
 	if err != nil {
-		logger.Errorf("failed to get cluster setup: %v", err)
+		return fmt.Errorf("failed to get cluster setup: %w", err)
 	}
-	if err := install.DeleteControllerUsers(clusterConfig); err != nil {
-		// don't fail, just notify on delete error
-		logger.Infof("failed to delete controller users: %v", err)
+	for _, user := range install.GetControllerUsers(clusterConfig) {
+		if exists, _ := util.CheckIfUserExists(user); exists {
+			result.record(ActionDeleteUser, user, install.DeleteUser(user))
+		}
 	}
 	return nil
 }
+
+// Plan lists the controller users present on the host
+func (u *users) Plan(ctx context.Context) ([]Action, error) {
+	clusterConfig, err := config.GetYamlFromFile(u.Config.cfgFile, u.Config.k0sVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster setup: %w", err)
+	}
+	var actions []Action
+	for _, user := range install.GetControllerUsers(clusterConfig) {
+		if exists, _ := util.CheckIfUserExists(user); exists {
+			actions = append(actions, Action{Action: ActionDeleteUser, Target: user})
+		}
+	}
+	return actions, nil
+}
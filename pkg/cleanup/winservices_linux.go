@@ -0,0 +1,41 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import "context"
+
+type winServices struct {
+}
+
+// Name returns the name of the step
+func (w *winServices) Name() string {
+	return "windows services step"
+}
+
+// NeedsToRun checks if there are windows services to stop
+func (w *winServices) NeedsToRun() bool {
+	return false
+}
+
+// Run stops the windows services
+func (w *winServices) Run(ctx context.Context, result *CleanupResult) error {
+	return nil
+}
+
+// Plan lists nothing, there are no windows services on linux
+func (w *winServices) Plan(ctx context.Context) ([]Action, error) {
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/k0sproject/k0s/internal/util"
+)
+
+// the services of the external runtime and kubernetes components installed alongside k0s, calico has an uninstall script of its own
+var windowsServiceNames = []string{"kubelet", "kube-proxy", "containerd"}
+
+const calicoUninstallScript = `C:\CalicoWindows\uninstall-calico.ps1`
+
+type winServices struct {
+	calico  bool
+	running []string
+}
+
+// Name returns the name of the step
+func (w *winServices) Name() string {
+	return "windows services step"
+}
+
+// NeedsToRun checks if calico for windows is installed or any of the services is running
+func (w *winServices) NeedsToRun() bool {
+	w.calico, w.running = util.FileExists(calicoUninstallScript), nil
+	for _, name := range windowsServiceNames {
+		status, err := powershell(context.Background(), fmt.Sprintf("(Get-Service -Name '%s' -ErrorAction Stop).Status", name))
+		if err == nil && status == "Running" {
+			w.running = append(w.running, name)
+		}
+	}
+	return w.calico || len(w.running) > 0
+}
+
+// Run uninstalls calico for windows and stops the services, the containers are removed before
+func (w *winServices) Run(ctx context.Context, result *CleanupResult) error {
+	if w.calico {
+		_, err := powershell(ctx, fmt.Sprintf("& '%s'", calicoUninstallScript))
+		result.record(ActionUninstallService, "Calico for Windows", err)
+	}
+	for _, name := range w.running {
+		_, err := powershell(ctx, fmt.Sprintf("Stop-Service -Name '%s' -Force -ErrorAction Stop", name))
+		result.record(ActionStopService, name, err)
+	}
+	return nil
+}
+
+// Plan lists calico for windows and the services to stop
+func (w *winServices) Plan(ctx context.Context) ([]Action, error) {
+	var actions []Action
+	if w.calico {
+		actions = append(actions, Action{Action: ActionUninstallService, Target: "Calico for Windows"})
+	}
+	for _, name := range w.running {
+		actions = append(actions, Action{Action: ActionStopService, Target: name})
+	}
+	return actions, nil
+}
+
+func powershell(ctx context.Context, command string) (string, error) {
+	out, err := exec.CommandContext(ctx, "powershell.exe", "-NoProfile", "-NonInteractive", "-Command", command).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", command, err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -71,7 +71,7 @@ func (a *APIEndpointReconciler) Init() error {
 func (a *APIEndpointReconciler) Run() error {
 
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(reconcileInterval(10 * time.Second))
 		defer ticker.Stop()
 		for {
 			select {
@@ -16,6 +16,7 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -23,14 +24,18 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
+	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/component"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+	"github.com/k0sproject/k0s/pkg/status"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -41,6 +46,7 @@ type APIServer struct {
 	LogLevel           string
 	Storage            component.Component
 	EnableKonnectivity bool
+	Maintenance        *Maintenance
 	gid                int
 	supervisor         supervisor.Supervisor
 	uid                int
@@ -79,17 +85,46 @@ func (a *APIServer) Init() error {
 	if err != nil {
 		logrus.Warning(fmt.Errorf("running kube-apiserver as root: %w", err))
 	}
+	if a.Maintenance != nil {
+		a.Maintenance.AddCallback(a.restartForMaintenance)
+	}
 	return assets.Stage(a.K0sVars.BinDir, "kube-apiserver", constant.BinDirMode)
 }
 
 // Run runs kube api
 func (a *APIServer) Run() error {
 	logrus.Info("Starting kube-apiserver")
+	if a.EnableKonnectivity {
+		if err := a.writeKonnectivityConfig(); err != nil {
+			return err
+		}
+	}
+	apiServerArgs, err := a.args()
+	if err != nil {
+		return err
+	}
+
+	a.supervisor = supervisor.Supervisor{
+		Name:     "kube-apiserver",
+		BinPath:  assets.BinPath("kube-apiserver", a.K0sVars.BinDir),
+		RunDir:   a.K0sVars.RunDir,
+		DataDir:  a.K0sVars.DataDir,
+		CrashDir: a.K0sVars.CrashDir,
+		ArgsDir:  a.K0sVars.ComponentArgsDir,
+		Args:     apiServerArgs,
+		UID:      a.uid,
+		GID:      a.gid,
+	}
+	return a.supervisor.Supervise()
+}
+
+// args renders the flags of kube-apiserver
+func (a *APIServer) args() ([]string, error) {
 	args := map[string]string{
 		"advertise-address":                a.ClusterConfig.Spec.API.Address,
 		"secure-port":                      fmt.Sprintf("%d", a.ClusterConfig.Spec.API.Port),
 		"authorization-mode":               "Node,RBAC",
-		"client-ca-file":                   path.Join(a.K0sVars.CertRootDir, "ca.crt"),
+		"client-ca-file":                   certificate.CABundlePath(a.K0sVars.CertRootDir),
 		"enable-bootstrap-token-auth":      "true",
 		"kubelet-client-certificate":       path.Join(a.K0sVars.CertRootDir, "apiserver-kubelet-client.crt"),
 		"kubelet-client-key":               path.Join(a.K0sVars.CertRootDir, "apiserver-kubelet-client.key"),
@@ -100,45 +135,60 @@ func (a *APIServer) Run() error {
 		"requestheader-client-ca-file":     path.Join(a.K0sVars.CertRootDir, "front-proxy-ca.crt"),
 		"service-account-key-file":         path.Join(a.K0sVars.CertRootDir, "sa.pub"),
 		"service-cluster-ip-range":         a.ClusterConfig.Spec.Network.BuildServiceCIDR(a.ClusterConfig.Spec.API.Address),
+		"service-node-port-range":          a.ClusterConfig.Spec.Network.NodePortRange,
 		"tls-cert-file":                    path.Join(a.K0sVars.CertRootDir, "server.crt"),
 		"tls-private-key-file":             path.Join(a.K0sVars.CertRootDir, "server.key"),
 		"service-account-signing-key-file": path.Join(a.K0sVars.CertRootDir, "sa.key"),
 		"service-account-issuer":           "https://kubernetes.default.svc",
 		"service-account-jwks-uri":         "https://kubernetes.default.svc/openid/v1/jwks",
 		"insecure-port":                    "0",
-		"profiling":                        "false",
+		"profiling":                        a.ClusterConfig.Spec.Profiling.ProfilingArg(),
 		"v":                                a.LogLevel,
-		"kubelet-certificate-authority":    path.Join(a.K0sVars.CertRootDir, "ca.crt"),
+		"kubelet-certificate-authority":    certificate.CABundlePath(a.K0sVars.CertRootDir),
 		"enable-admission-plugins":         "NodeRestriction,PodSecurityPolicy",
 	}
 
 	apiAudiences := []string{"https://kubernetes.default.svc"}
 
 	if a.EnableKonnectivity {
-		err := a.writeKonnectivityConfig()
-		if err != nil {
-			return err
-		}
 		args["egress-selector-config-file"] = path.Join(a.K0sVars.DataDir, "konnectivity.conf")
 		apiAudiences = append(apiAudiences, "system:konnectivity-server")
 	}
 
 	args["api-audiences"] = strings.Join(apiAudiences, ",")
 
+	if oidcSpec := a.ClusterConfig.Spec.OIDCProvider; oidcSpec != nil && oidcSpec.Enabled {
+		args["oidc-issuer-url"] = oidcSpec.IssuerURL(a.ClusterConfig.Spec.API)
+		args["oidc-client-id"] = oidcSpec.ClientID
+		args["oidc-ca-file"] = certificate.CABundlePath(a.K0sVars.CertRootDir)
+		args["oidc-username-claim"] = "sub"
+		args["oidc-username-prefix"] = "oidc:"
+		args["oidc-groups-claim"] = "groups"
+		args["oidc-groups-prefix"] = "oidc:"
+	}
+
 	for name, value := range a.ClusterConfig.Spec.API.ExtraArgs {
 		if args[name] != "" && name != "profiling" {
-			return fmt.Errorf("cannot override apiserver flag: %s", name)
+			return nil, fmt.Errorf("cannot override apiserver flag: %s", name)
 		}
 		args[name] = value
 	}
 	a.ClusterConfig.Spec.Network.DualStack.EnableDualStackFeatureGate(args)
 
+	// the watch cache flags can still be overridden with the extra args
+	for name, value := range a.ClusterConfig.Spec.API.WatchCache.APIServerArgs(a.nodeCount()) {
+		if args[name] == "" {
+			args[name] = value
+		}
+	}
+
 	for name, value := range apiDefaultArgs {
 		if args[name] == "" {
 			args[name] = value
 		}
 	}
-	if a.ClusterConfig.Spec.API.ExternalAddress != "" {
+	// in maintenance the API server stops renewing its endpoint lease, the other API servers drop it from the endpoints once the lease expires
+	if a.ClusterConfig.Spec.API.ExternalAddress != "" || a.Maintenance.Enabled() {
 		args["endpoint-reconciler-type"] = "none"
 	}
 	var apiServerArgs []string
@@ -146,29 +196,55 @@ func (a *APIServer) Run() error {
 		apiServerArgs = append(apiServerArgs, fmt.Sprintf("--%s=%s", name, value))
 	}
 
-	a.supervisor = supervisor.Supervisor{
-		Name:    "kube-apiserver",
-		BinPath: assets.BinPath("kube-apiserver", a.K0sVars.BinDir),
-		RunDir:  a.K0sVars.RunDir,
-		DataDir: a.K0sVars.DataDir,
-		Args:    apiServerArgs,
-		UID:     a.uid,
-		GID:     a.gid,
-	}
 	switch a.ClusterConfig.Spec.Storage.Type {
 	case config.KineStorageType:
-		a.supervisor.Args = append(a.supervisor.Args,
+		apiServerArgs = append(apiServerArgs,
 			fmt.Sprintf("--etcd-servers=unix://%s", a.K0sVars.KineSocketPath)) // kine endpoint
 	case config.EtcdStorageType:
-		a.supervisor.Args = append(a.supervisor.Args,
-			"--etcd-servers=https://127.0.0.1:2379",
+		etcdServers, err := a.etcdServers()
+		if err != nil {
+			return nil, err
+		}
+		apiServerArgs = append(apiServerArgs,
+			fmt.Sprintf("--etcd-servers=%s", etcdServers),
 			fmt.Sprintf("--etcd-cafile=%s", path.Join(a.K0sVars.CertRootDir, "etcd/ca.crt")),
 			fmt.Sprintf("--etcd-certfile=%s", path.Join(a.K0sVars.CertRootDir, "apiserver-etcd-client.crt")),
 			fmt.Sprintf("--etcd-keyfile=%s", path.Join(a.K0sVars.CertRootDir, "apiserver-etcd-client.key")))
 	default:
-		return fmt.Errorf("invalid storage type: %s", a.ClusterConfig.Spec.Storage.Type)
+		return nil, fmt.Errorf("invalid storage type: %s", a.ClusterConfig.Spec.Storage.Type)
 	}
-	return a.supervisor.Supervise()
+	return apiServerArgs, nil
+}
+
+// nodeCount returns the amount of nodes last seen by the controller, or 0 if the controller hasn't seen the cluster yet
+func (a *APIServer) nodeCount() int {
+	metadata, err := status.ReadClusterMetadata(a.K0sVars.ClusterMetadataPath)
+	if err != nil {
+		return 0
+	}
+	return metadata.NodeCount
+}
+
+// etcdServers returns the local etcd, or the voting members if the local etcd is a learner that can't serve the API server
+func (a *APIServer) etcdServers() (string, error) {
+	if !a.ClusterConfig.Spec.Storage.Etcd.Learner {
+		return "https://127.0.0.1:2379", nil
+	}
+	etcdClient, err := etcd.NewClient(a.K0sVars.CertRootDir, a.K0sVars.EtcdCertDir)
+	if err != nil {
+		return "", err
+	}
+	defer etcdClient.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	urls, err := etcdClient.VoterClientURLs(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(urls) == 0 {
+		return "", fmt.Errorf("no voting etcd members with externalClientAccess found for the API server of the learner")
+	}
+	return strings.Join(urls, ","), nil
 }
 
 func (a *APIServer) writeKonnectivityConfig() error {
@@ -188,6 +264,21 @@ func (a *APIServer) writeKonnectivityConfig() error {
 	return nil
 }
 
+// restartForMaintenance restarts the API server for toggling its endpoint reconciler
+func (a *APIServer) restartForMaintenance(enabled bool) {
+	if a.ClusterConfig.Spec.API.ExternalAddress != "" {
+		// the endpoints point to the external address, the load balancer takes care of the maintenance
+		return
+	}
+	logrus.Info("restarting kube-apiserver for toggling the maintenance mode")
+	if err := a.Stop(); err != nil {
+		logrus.Errorf("failed to stop kube-apiserver: %v", err)
+	}
+	if err := a.Run(); err != nil {
+		logrus.Errorf("failed to restart kube-apiserver: %v", err)
+	}
+}
+
 // Stop stops APIServer
 func (a *APIServer) Stop() error {
 	return a.supervisor.Stop()
@@ -63,6 +63,8 @@ type calicoConfig struct {
 	IPAutodetectionMethod      string
 	IPV6AutodetectionMethod    string
 	PullPolicy                 string
+	CNIConfDir                 string
+	CNIBinDir                  string
 }
 
 // NewCalico creates new Calico reconciler component
@@ -84,42 +86,14 @@ func (c *Calico) Init() error {
 // Run runs the calico reconciler
 func (c *Calico) Run() error {
 	c.tickerDone = make(chan struct{})
-	var emptyStruct struct{}
 
 	// Write the CRD definitions only at "boot", they do not change during runtime
-	crds, err := static.AssetDir("manifests/calico/CustomResourceDefinition")
-
-	if err != nil {
+	if err := c.writeCRDs(); err != nil {
 		return err
 	}
 
-	for _, filename := range crds {
-		manifestName := fmt.Sprintf("calico-crd-%s", filename)
-
-		output := bytes.NewBuffer([]byte{})
-
-		contents, err := static.Asset(fmt.Sprintf("manifests/calico/CustomResourceDefinition/%s", filename))
-
-		if err != nil {
-			return fmt.Errorf("failed to fetch crd %s: %w", filename, err)
-		}
-
-		tw := util.TemplateWriter{
-			Name:     fmt.Sprintf("calico-crd-%s", strings.TrimSuffix(filename, filepath.Ext(filename))),
-			Template: string(contents),
-			Data:     emptyStruct,
-		}
-		if err := tw.WriteToBuffer(output); err != nil {
-			return fmt.Errorf("failed to write calico crd manifests %s: %v", manifestName, err)
-		}
-
-		if err := c.crdSaver.Save(manifestName, output.Bytes()); err != nil {
-			return fmt.Errorf("failed to save calico crd manifest %s: %v", manifestName, err)
-		}
-	}
-
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(reconcileInterval(10 * time.Second))
 		defer ticker.Stop()
 		var previousConfig = calicoConfig{}
 		for {
@@ -150,11 +124,68 @@ func (c *Calico) processConfigChanges(previousConfig calicoConfig) *calicoConfig
 		return nil
 	}
 
+	if err := c.writeManifests(cfg); err != nil {
+		c.log.Errorf("error writing calico manifests: %s. will retry", err.Error())
+		return nil
+	}
+
+	return &cfg
+}
+
+// writeCRDs writes the CRD definitions of calico
+func (c *Calico) writeCRDs() error {
+	var emptyStruct struct{}
+
+	crds, err := static.AssetDir("manifests/calico/CustomResourceDefinition")
+
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range crds {
+		manifestName := fmt.Sprintf("calico-crd-%s", filename)
+
+		output := bytes.NewBuffer([]byte{})
+
+		contents, err := static.Asset(fmt.Sprintf("manifests/calico/CustomResourceDefinition/%s", filename))
+
+		if err != nil {
+			return fmt.Errorf("failed to fetch crd %s: %w", filename, err)
+		}
+
+		tw := util.TemplateWriter{
+			Name:     fmt.Sprintf("calico-crd-%s", strings.TrimSuffix(filename, filepath.Ext(filename))),
+			Template: string(contents),
+			Data:     emptyStruct,
+		}
+		if err := tw.WriteToBuffer(output); err != nil {
+			return fmt.Errorf("failed to write calico crd manifests %s: %v", manifestName, err)
+		}
+
+		if err := c.crdSaver.Save(manifestName, output.Bytes()); err != nil {
+			return fmt.Errorf("failed to save calico crd manifest %s: %v", manifestName, err)
+		}
+	}
+	return nil
+}
+
+// writeManifests writes the calico manifests other than the CRDs. A manifest failing to be written doesn't keep the
+// others from being written, the first failure is returned.
+func (c *Calico) writeManifests(cfg calicoConfig) error {
 	manifestDirectories, err := static.AssetDir("manifests/calico")
 
 	if err != nil {
-		c.log.Errorf("error retrieving calico manifests: %s. will retry", err.Error())
-		return nil
+		return err
+	}
+
+	var writeErr error
+	tryAndLog := func(name string, e error) {
+		if e != nil {
+			c.log.Errorf("failed to write manifest %s: %v, will re-try", name, e)
+			if writeErr == nil {
+				writeErr = e
+			}
+		}
 	}
 
 	for _, dir := range manifestDirectories {
@@ -164,14 +195,7 @@ func (c *Calico) processConfigChanges(previousConfig calicoConfig) *calicoConfig
 		}
 		manifestPaths, err := static.AssetDir(fmt.Sprintf("manifests/calico/%s", dir))
 		if err != nil {
-			c.log.Errorf("error retrieving calico manifests: %s. will retry", err.Error())
-			return nil
-		}
-
-		tryAndLog := func(name string, e error) {
-			if e != nil {
-				c.log.Errorf("failed to write manifest %s: %v, will re-try", name, e)
-			}
+			return err
 		}
 
 		for _, filename := range manifestPaths {
@@ -180,7 +204,7 @@ func (c *Calico) processConfigChanges(previousConfig calicoConfig) *calicoConfig
 			contents, err := static.Asset(fmt.Sprintf("manifests/calico/%s/%s", dir, filename))
 
 			if err != nil {
-				return nil
+				return err
 			}
 
 			tw := util.TemplateWriter{
@@ -193,7 +217,7 @@ func (c *Calico) processConfigChanges(previousConfig calicoConfig) *calicoConfig
 		}
 	}
 
-	return &cfg
+	return writeErr
 }
 
 func (c *Calico) getConfig() (calicoConfig, error) {
@@ -219,6 +243,8 @@ func (c *Calico) getConfig() (calicoConfig, error) {
 		IPAutodetectionMethod:      c.clusterConf.Spec.Network.Calico.IPAutodetectionMethod,
 		IPV6AutodetectionMethod:    ipv6AutoDetectionMethod,
 		PullPolicy:                 c.clusterConf.Spec.Images.DefaultPullPolicy,
+		CNIConfDir:                 c.clusterConf.Spec.Network.CNIConfDir,
+		CNIBinDir:                  c.clusterConf.Spec.Network.CNIBinDir,
 	}
 
 	return config, nil
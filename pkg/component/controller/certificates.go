@@ -79,9 +79,10 @@ func (c *Certificates) Init() error {
 		return err
 	}
 
-	// We need CA cert loaded to generate client configs
+	// We need CA cert loaded to generate client configs, the kubeconfigs trust the previous or the next CA too
+	// while the CA is rotated
 	logrus.Debugf("CA key and cert exists, loading")
-	cert, err := ioutil.ReadFile(caCertPath)
+	cert, err := ioutil.ReadFile(certificate.CABundlePath(c.K0sVars.CertRootDir))
 	if err != nil {
 		return fmt.Errorf("failed to read ca cert: %w", err)
 	}
@@ -128,6 +129,12 @@ func (c *Certificates) Init() error {
 		return c.CertManager.CreateKeyPair("sa", c.K0sVars, constant.ApiserverUser)
 	})
 
+	eg.Go(func() error {
+		// signing key of the embedded OIDC provider, created even if the provider is disabled so that it's
+		// distributed to the joining controllers
+		return c.CertManager.CreateKeyPair("oidc", c.K0sVars, "root")
+	})
+
 	eg.Go(func() error {
 		// konnectivity kubeconfig
 		konnectivityReq := certificate.Request{
@@ -220,9 +227,10 @@ func (c *Certificates) Init() error {
 			CAKey:     caCertKey,
 			Hostnames: hostnames,
 		}
-		_, err = c.CertManager.EnsureCertificate(serverReq, constant.ApiserverUser)
-
-		return err
+		if _, err := c.CertManager.EnsureCertificate(serverReq, constant.ApiserverUser); err != nil {
+			return err
+		}
+		return c.CertManager.ChainCrossSignedCA("server")
 	})
 
 	eg.Go(func() error {
@@ -235,8 +243,10 @@ func (c *Certificates) Init() error {
 			Hostnames: hostnames,
 		}
 		// TODO Not sure about the user...
-		_, err := c.CertManager.EnsureCertificate(apiReq, constant.ApiserverUser)
-		return err
+		if _, err := c.CertManager.EnsureCertificate(apiReq, constant.ApiserverUser); err != nil {
+			return err
+		}
+		return c.CertManager.ChainCrossSignedCA("k0s-api")
 	})
 
 	return eg.Wait()
@@ -0,0 +1,217 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/certificate/csr"
+	"k8s.io/client-go/util/keyutil"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/certificate"
+	"github.com/k0sproject/k0s/pkg/constant"
+	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+const (
+	// certRotationInterval is how often the client certificates of the internal kubeconfigs are checked
+	certRotationInterval = time.Hour
+	// certRequestTimeout bounds the wait for the controller manager to sign a renewed certificate
+	certRequestTimeout = 5 * time.Minute
+)
+
+// internalKubeconfig is a kubeconfig of the controller with a client certificate, and the component using it
+type internalKubeconfig struct {
+	path      string
+	owner     string
+	component string
+}
+
+// CertRotation renews the client certificates of the internal kubeconfigs of the controller through the CSR API
+// before they expire, so that a controller running for longer than the validity of the certificates keeps working.
+// The kubeconfigs are swapped atomically and the components using them are restarted. The certificates are issued
+// anew on every start of the controller as well.
+type CertRotation struct {
+	K0sVars           constant.CfgVars
+	KubeClientFactory kubeutil.ClientFactory
+	// Restart restarts the named component with its renewed kubeconfig
+	Restart func(name string) error
+
+	log    *logrus.Entry
+	cancel context.CancelFunc
+}
+
+// Init initializes the logger
+func (r *CertRotation) Init() error {
+	r.log = logrus.WithField("component", "cert-rotation")
+	return nil
+}
+
+// Run checks the certificates periodically
+func (r *CertRotation) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(certRotationInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.rotate(ctx, time.Now())
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the checks
+func (r *CertRotation) Stop() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	return nil
+}
+
+// Healthy is a no-op
+func (r *CertRotation) Healthy() error { return nil }
+
+func (r *CertRotation) kubeconfigs() []internalKubeconfig {
+	return []internalKubeconfig{
+		{path: r.K0sVars.AdminKubeConfigPath, owner: "root"},
+		{path: r.K0sVars.KonnectivityKubeConfigPath, owner: constant.KonnectivityServerUser, component: "Konnectivity"},
+		{path: filepath.Join(r.K0sVars.CertRootDir, "ccm.conf"), owner: constant.ApiserverUser, component: "Manager"},
+		{path: filepath.Join(r.K0sVars.CertRootDir, "scheduler.conf"), owner: constant.SchedulerUser, component: "Scheduler"},
+	}
+}
+
+func (r *CertRotation) rotate(ctx context.Context, now time.Time) {
+	for _, k := range r.kubeconfigs() {
+		if !util.FileExists(k.path) {
+			continue
+		}
+		renewed, err := r.renew(ctx, k, now)
+		if err != nil {
+			r.log.Errorf("failed to renew the client certificate of %s: %v", k.path, err)
+			continue
+		}
+		if !renewed || k.component == "" || r.Restart == nil {
+			continue
+		}
+		r.log.Infof("restarting %s with the renewed client certificate", k.component)
+		if err := r.Restart(k.component); err != nil {
+			r.log.Errorf("failed to restart %s: %v", k.component, err)
+		}
+	}
+}
+
+// renew requests a new client certificate for the kubeconfig if it's due for renewal, returns true if renewed
+func (r *CertRotation) renew(ctx context.Context, k internalKubeconfig, now time.Time) (bool, error) {
+	kubeconfig, err := clientcmd.LoadFromFile(k.path)
+	if err != nil {
+		return false, err
+	}
+	kubeCtx, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	if !ok {
+		return false, fmt.Errorf("no current context")
+	}
+	authInfo, ok := kubeconfig.AuthInfos[kubeCtx.AuthInfo]
+	if !ok {
+		return false, fmt.Errorf("no user %s", kubeCtx.AuthInfo)
+	}
+	current, err := certificate.ParseCertificatePEM(authInfo.ClientCertificateData)
+	if err != nil {
+		return false, err
+	}
+	if now.Before(certificate.RenewalTime(current)) {
+		return false, nil
+	}
+
+	r.log.Infof("renewing the client certificate of %s, which expires at %s", k.path, current.NotAfter.Format(time.RFC3339))
+	client, err := r.KubeClientFactory.GetClient()
+	if err != nil {
+		return false, err
+	}
+	certPEM, keyPEM, err := requestClientCertificate(ctx, client, current.Subject)
+	if err != nil {
+		return false, err
+	}
+	authInfo.ClientCertificateData, authInfo.ClientKeyData = certPEM, keyPEM
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return false, err
+	}
+	if err := util.WriteFileAtomic(k.path, data, constant.CertSecureMode); err != nil {
+		return false, err
+	}
+	return true, util.ChownFile(k.path, k.owner, constant.CertSecureMode)
+}
+
+// requestClientCertificate requests and approves a client certificate for the subject. It's signed by the
+// controller manager with the cluster CA, the same as the certificates issued on the start of the controller.
+func requestClientCertificate(ctx context.Context, client kubernetes.Interface, subject pkix.Name) ([]byte, []byte, error) {
+	keyPEM, err := keyutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	csrPEM, err := cert.MakeCSR(key, &pkix.Name{CommonName: subject.CommonName, Organization: subject.Organization}, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	usages := []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment, certificatesv1.UsageClientAuth}
+	reqName, reqUID, err := csr.RequestCertificate(client, csrPEM, "", certificatesv1.KubeAPIServerClientSignerName, usages, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to request the client certificate: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, certRequestTimeout)
+	defer cancel()
+	req, err := client.CertificatesV1().CertificateSigningRequests().Get(ctx, reqName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Status.Conditions = append(req.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  "K0sCertRotation",
+		Message: "renewal of an internal client certificate of k0s",
+	})
+	if _, err := client.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, reqName, req, metav1.UpdateOptions{}); err != nil {
+		return nil, nil, fmt.Errorf("failed to approve the client certificate request %s: %w", reqName, err)
+	}
+	certPEM, err := csr.WaitForCertificate(ctx, client, reqName, reqUID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client certificate request %s wasn't signed: %w", reqName, err)
+	}
+	return certPEM, keyPEM, nil
+}
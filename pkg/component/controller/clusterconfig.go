@@ -0,0 +1,226 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+// ClusterConfigResource is the resource keeping the cluster-wide part of the config in the API
+var ClusterConfigResource = schema.GroupVersionResource{Group: "k0s.k0sproject.io", Version: "v1beta1", Resource: "clusterconfigs"}
+
+const (
+	clusterConfigName      = "k0s"
+	clusterConfigNamespace = "kube-system"
+	clusterConfigInterval  = 10 * time.Second
+)
+
+// ClusterConfigReconciler keeps the cluster-wide part of the config in the ClusterConfig resource. The leader creates
+// the resource from the config file, after which every controller follows the changes of the resource and hands the
+// valid ones over to OnChange.
+type ClusterConfigReconciler struct {
+	L *logrus.Entry
+
+	clusterConfig     *config.ClusterConfig
+	onChange          func(*config.ClusterWideSpec) error
+	leaderElector     LeaderElector
+	kubeClientFactory k8sutil.ClientFactory
+	client            dynamic.Interface
+	stopCh            chan struct{}
+	heartbeat         *watchdog.Heartbeat
+
+	// applied and rejected are the last cluster-wide specs taken and refused, as yaml
+	applied  []byte
+	rejected []byte
+}
+
+// NewClusterConfigReconciler creates the ClusterConfigReconciler component, onChange is called from its loop
+func NewClusterConfigReconciler(clusterConfig *config.ClusterConfig, leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory, onChange func(*config.ClusterWideSpec) error) *ClusterConfigReconciler {
+	return &ClusterConfigReconciler{
+		clusterConfig:     clusterConfig,
+		onChange:          onChange,
+		leaderElector:     leaderElector,
+		kubeClientFactory: kubeClientFactory,
+		heartbeat:         watchdog.NewHeartbeat("ClusterConfig", reconcileInterval(clusterConfigInterval)),
+		L:                 logrus.WithFields(logrus.Fields{"component": "clusterconfig"}),
+	}
+}
+
+// Init initializes the kube client
+func (r *ClusterConfigReconciler) Init() error {
+	var err error
+	r.client, err = r.kubeClientFactory.GetDynamicClient()
+	if err != nil {
+		return fmt.Errorf("can't create kubernetes client for the cluster config: %w", err)
+	}
+	data, err := yaml.Marshal(r.clusterConfig.Spec.ClusterWide())
+	if err != nil {
+		return err
+	}
+	// normalized like the specs read from the resource, so the initial spec isn't taken for a change
+	_, r.applied, err = parseClusterWideSpec(data)
+	return err
+}
+
+// Run checks the ClusterConfig resource every ten seconds
+func (r *ClusterConfigReconciler) Run() error {
+	stopCh := make(chan struct{})
+	r.stopCh = stopCh
+	r.heartbeat.Start()
+
+	go func() {
+		ticker := time.NewTicker(reconcileInterval(clusterConfigInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.reconcile(context.TODO()); err != nil {
+					r.L.Warnf("cluster config reconcile failed: %v", err)
+				}
+				r.heartbeat.Beat()
+			case <-stopCh:
+				r.L.Info("cluster config reconciler done")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the reconciler
+func (r *ClusterConfigReconciler) Stop() error {
+	r.heartbeat.Stop()
+	if r.stopCh != nil {
+		close(r.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (r *ClusterConfigReconciler) Healthy() error { return nil }
+
+func (r *ClusterConfigReconciler) reconcile(ctx context.Context) error {
+	resources := r.client.Resource(ClusterConfigResource).Namespace(clusterConfigNamespace)
+	obj, err := resources.Get(ctx, clusterConfigName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if !r.leaderElector.IsLeader() {
+			return nil
+		}
+		// the CRD may not be applied yet, the creation is retried on the next round
+		obj, err = clusterConfigObject(r.applied)
+		if err != nil {
+			return err
+		}
+		if _, err := resources.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("can't create the cluster config resource: %w", err)
+		}
+		r.L.Infof("created the cluster config resource %s/%s from the config file", clusterConfigNamespace, clusterConfigName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't get the cluster config resource: %w", err)
+	}
+
+	spec, data, err := clusterWideSpec(obj)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, r.applied) || bytes.Equal(data, r.rejected) {
+		return nil
+	}
+	if errs := r.validate(spec); len(errs) > 0 {
+		r.rejected = data
+		return fmt.Errorf("rejected the cluster config change: %v", errs)
+	}
+
+	r.L.Info("applying the changed cluster config")
+	if err := r.onChange(spec); err != nil {
+		return fmt.Errorf("failed to apply the cluster config: %w", err)
+	}
+	r.applied = data
+	r.rejected = nil
+	return nil
+}
+
+// validate checks the config of the controller updated with the cluster-wide spec
+func (r *ClusterConfigReconciler) validate(spec *config.ClusterWideSpec) []error {
+	candidate := *r.clusterConfig
+	candidateSpec := *r.clusterConfig.Spec
+	candidateSpec.ApplyClusterWide(spec)
+	candidate.Spec = &candidateSpec
+	return append(spec.ValidateChange(r.clusterConfig.Spec), candidate.Validate()...)
+}
+
+// clusterConfigObject builds the ClusterConfig resource from the yaml of the cluster-wide spec
+func clusterConfigObject(specYaml []byte) (*unstructured.Unstructured, error) {
+	specJSON, err := k8syaml.ToJSON(specYaml)
+	if err != nil {
+		return nil, err
+	}
+	spec := map[string]interface{}{}
+	if err := json.Unmarshal(specJSON, &spec); err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetAPIVersion(ClusterConfigResource.GroupVersion().String())
+	obj.SetKind("ClusterConfig")
+	obj.SetName(clusterConfigName)
+	obj.SetNamespace(clusterConfigNamespace)
+	return obj, nil
+}
+
+// clusterWideSpec parses the spec of the ClusterConfig resource, and returns it along with its normalized yaml
+func clusterWideSpec(obj *unstructured.Unstructured) (*config.ClusterWideSpec, []byte, error) {
+	specJSON, err := json.Marshal(obj.Object["spec"])
+	if err != nil {
+		return nil, nil, err
+	}
+	spec, data, err := parseClusterWideSpec(specJSON)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cluster config resource: %w", err)
+	}
+	return spec, data, nil
+}
+
+// parseClusterWideSpec parses the yaml, or json, of the cluster-wide spec and marshals it again with the defaults
+func parseClusterWideSpec(data []byte) (*config.ClusterWideSpec, []byte, error) {
+	spec := &config.ClusterWideSpec{}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, nil, err
+	}
+	normalized, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	return spec, normalized, nil
+}
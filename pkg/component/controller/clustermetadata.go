@@ -0,0 +1,191 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/status"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+// ClusterNameLabel is stamped on every node of the cluster
+const ClusterNameLabel = "k0s.k0sproject.io/cluster-name"
+
+// clusterInfo exposes the cluster name and labels on the debug server under /debug/vars
+var clusterInfo = expvar.NewMap("k0s_cluster_info")
+
+// ClusterMetadata propagates the cluster name and labels into the node labels and the local status, which also
+// records the node count for scaling the API server watch caches
+type ClusterMetadata struct {
+	ClusterConfig *config.ClusterConfig
+	K0sVars       constant.CfgVars
+
+	leaderElector     LeaderElector
+	kubeClientFactory k8sutil.ClientFactory
+	metadata          status.ClusterMetadata
+	log               *logrus.Entry
+	stopCh            chan struct{}
+	heartbeat         *watchdog.Heartbeat
+}
+
+// NewClusterMetadata creates new cluster metadata reconciler
+func NewClusterMetadata(c *config.ClusterConfig, k0sVars constant.CfgVars, leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *ClusterMetadata {
+	return &ClusterMetadata{
+		ClusterConfig:     c,
+		K0sVars:           k0sVars,
+		leaderElector:     leaderElector,
+		kubeClientFactory: kubeClientFactory,
+		log:               logrus.WithField("component", "cluster-metadata"),
+		heartbeat:         watchdog.NewHeartbeat("ClusterMetadata", reconcileInterval(time.Minute)),
+	}
+}
+
+// Init stores the metadata for k0s status and publishes it as a metric
+func (m *ClusterMetadata) Init() error {
+	metadata := status.ClusterMetadata{
+		Name:   m.ClusterConfig.ClusterName(),
+		Labels: m.ClusterConfig.Spec.ClusterLabels,
+	}
+	if previous, err := status.ReadClusterMetadata(m.K0sVars.ClusterMetadataPath); err == nil {
+		metadata.NodeCount = previous.NodeCount
+	}
+	m.metadata = metadata
+	name := new(expvar.String)
+	name.Set(metadata.Name)
+	clusterInfo.Set("name", name)
+	labels := new(expvar.Map).Init()
+	for k, v := range metadata.Labels {
+		value := new(expvar.String)
+		value.Set(v)
+		labels.Set(k, value)
+	}
+	clusterInfo.Set("labels", labels)
+
+	if err := status.WriteClusterMetadata(m.K0sVars.ClusterMetadataPath, metadata); err != nil {
+		return fmt.Errorf("failed to write cluster metadata: %w", err)
+	}
+	return nil
+}
+
+// Run runs the main loop for labeling the nodes
+func (m *ClusterMetadata) Run() error {
+	stopCh := make(chan struct{})
+	m.stopCh = stopCh
+	m.heartbeat.Start()
+
+	go func() {
+		ticker := time.NewTicker(reconcileInterval(time.Minute))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.reconcile(); err != nil {
+					m.log.Warnf("failed to label nodes with the cluster metadata: %v", err)
+				}
+				m.heartbeat.Beat()
+			case <-stopCh:
+				m.log.Info("cluster metadata reconciler done")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the reconciler
+func (m *ClusterMetadata) Stop() error {
+	m.heartbeat.Stop()
+	if m.stopCh != nil {
+		close(m.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (m *ClusterMetadata) Healthy() error { return nil }
+
+// nodeLabels returns the labels every node of the cluster should carry
+func (m *ClusterMetadata) nodeLabels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range m.ClusterConfig.Spec.ClusterLabels {
+		labels[k] = v
+	}
+	if name := m.ClusterConfig.ClusterName(); name != "" {
+		labels[ClusterNameLabel] = name
+	}
+	return labels
+}
+
+func (m *ClusterMetadata) reconcile() error {
+	c, err := m.kubeClientFactory.GetClient()
+	if err != nil {
+		return err
+	}
+	nodes, err := c.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return err
+	}
+
+	if len(nodes.Items) != m.metadata.NodeCount {
+		m.metadata.NodeCount = len(nodes.Items)
+		if err := status.WriteClusterMetadata(m.K0sVars.ClusterMetadataPath, m.metadata); err != nil {
+			m.log.Warnf("failed to write cluster metadata: %v", err)
+		}
+	}
+
+	if !m.leaderElector.IsLeader() {
+		m.log.Debug("we're not the leader, not labeling the nodes")
+		return nil
+	}
+
+	want := m.nodeLabels()
+	for _, node := range nodes.Items {
+		missing := map[string]string{}
+		for k, v := range want {
+			if node.Labels[k] != v {
+				missing[k] = v
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": missing},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := c.CoreV1().Nodes().Patch(context.TODO(), node.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to label node %s: %w", node.Name, err)
+		}
+		m.log.Debugf("labeled node %s with the cluster metadata", node.Name)
+	}
+	return nil
+}
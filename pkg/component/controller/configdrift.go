@@ -0,0 +1,209 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"expvar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/status"
+	"github.com/k0sproject/k0s/pkg/supervisor"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+// configDriftItems exposes the amount of detected drift items on the debug server under /debug/vars
+var configDriftItems = expvar.NewInt("k0s_config_drift_items")
+
+var manifestImageRe = regexp.MustCompile(`(?m)^\s*(?:-\s*)?image:\s*["']?([^\s"']+)`)
+
+// ConfigDrift periodically compares the declared cluster config against the running state
+type ConfigDrift struct {
+	ClusterConfig *config.ClusterConfig
+	K0sVars       constant.CfgVars
+	Interval      time.Duration
+
+	log        *logrus.Entry
+	tickerDone chan struct{}
+	heartbeat  *watchdog.Heartbeat
+}
+
+// Init does nothing
+func (d *ConfigDrift) Init() error {
+	d.log = logrus.WithField("component", "config-drift")
+	if d.Interval == 0 {
+		d.Interval = 5 * time.Minute
+	}
+	d.heartbeat = watchdog.NewHeartbeat("ConfigDrift", d.Interval)
+	return nil
+}
+
+// Run starts the periodic drift checks
+func (d *ConfigDrift) Run() error {
+	tickerDone := make(chan struct{})
+	d.tickerDone = tickerDone
+	d.heartbeat.Start()
+
+	go func() {
+		ticker := time.NewTicker(d.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.check()
+				d.heartbeat.Beat()
+			case <-tickerDone:
+				d.log.Info("config drift detector done")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the drift checks
+func (d *ConfigDrift) Stop() error {
+	d.heartbeat.Stop()
+	if d.tickerDone != nil {
+		close(d.tickerDone)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (d *ConfigDrift) Healthy() error { return nil }
+
+func (d *ConfigDrift) check() {
+	var items []status.DriftItem
+	items = append(items, d.flagDrift()...)
+	items = append(items, d.imageDrift()...)
+	items = append(items, d.cniDrift()...)
+
+	for _, item := range items {
+		d.log.Warnf("config drift detected: %s", item)
+	}
+	configDriftItems.Set(int64(len(items)))
+
+	report := status.DriftReport{CheckedAt: time.Now(), Items: items}
+	if err := status.WriteDriftReport(d.K0sVars.ConfigDriftPath, report); err != nil {
+		d.log.Errorf("failed to write config drift report: %v", err)
+	}
+}
+
+// flagDrift compares the configured extra args against the command lines of the running components
+func (d *ConfigDrift) flagDrift() []status.DriftItem {
+	declared := map[string]map[string]string{
+		"kube-apiserver":          d.ClusterConfig.Spec.API.ExtraArgs,
+		"kube-controller-manager": d.ClusterConfig.Spec.ControllerManager.ExtraArgs,
+		"kube-scheduler":          d.ClusterConfig.Spec.Scheduler.ExtraArgs,
+	}
+
+	var items []status.DriftItem
+	for name, args := range declared {
+		if len(args) == 0 {
+			continue
+		}
+		running, err := supervisor.ProcessArgs(d.K0sVars.RunDir, name)
+		if err != nil {
+			d.log.Debugf("skipping flag drift check for %s: %v", name, err)
+			continue
+		}
+		for flag, value := range args {
+			actual, found := running[flag]
+			if !found {
+				actual = "<unset>"
+			}
+			if actual != value {
+				items = append(items, status.DriftItem{Component: name, Field: "--" + flag, Declared: value, Actual: actual})
+			}
+		}
+	}
+	return items
+}
+
+// imageDrift looks for addon images in the applied manifests that don't match the configured versions
+func (d *ConfigDrift) imageDrift() []status.DriftItem {
+	images := d.ClusterConfig.Spec.Images
+	declared := map[string]string{}
+	for _, spec := range []config.ImageSpec{
+		images.Konnectivity,
+		images.MetricsServer,
+		images.KubeProxy,
+		images.CoreDNS,
+		images.Calico.CNI,
+		images.Calico.Node,
+		images.Calico.KubeControllers,
+		images.KubeRouter.CNI,
+		images.KubeRouter.CNIInstaller,
+	} {
+		declared[spec.Image] = spec.Version
+	}
+
+	var items []status.DriftItem
+	_ = filepath.Walk(d.K0sVars.ManifestsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		for _, match := range manifestImageRe.FindAllStringSubmatch(string(content), -1) {
+			image, version := splitImage(match[1])
+			want, found := declared[image]
+			if found && version != want {
+				rel, _ := filepath.Rel(d.K0sVars.ManifestsDir, path)
+				items = append(items, status.DriftItem{Component: rel, Field: image, Declared: want, Actual: version})
+			}
+		}
+		return nil
+	})
+	return items
+}
+
+// splitImage splits the image reference into the image name and the tag
+func splitImage(ref string) (string, string) {
+	i := strings.LastIndex(ref, ":")
+	if i == -1 || strings.Contains(ref[i:], "/") {
+		return ref, ""
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// cniDrift checks that the applied CNI manifests belong to the configured provider
+func (d *ConfigDrift) cniDrift() []status.DriftItem {
+	declared := d.ClusterConfig.Spec.Network.Provider
+	var items []status.DriftItem
+	for _, provider := range []string{"calico", "kuberouter"} {
+		if provider == declared {
+			continue
+		}
+		if util.DirExists(filepath.Join(d.K0sVars.ManifestsDir, provider)) {
+			items = append(items, status.DriftItem{Component: "network", Field: "provider", Declared: declared, Actual: provider})
+		}
+	}
+	return items
+}
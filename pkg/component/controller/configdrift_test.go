@@ -0,0 +1,80 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+func TestSplitImage(t *testing.T) {
+	image, version := splitImage("docker.io/coredns/coredns:1.7.0")
+	assert.Equal(t, "docker.io/coredns/coredns", image)
+	assert.Equal(t, "1.7.0", version)
+
+	image, version = splitImage("localhost:5000/coredns")
+	assert.Equal(t, "localhost:5000/coredns", image)
+	assert.Equal(t, "", version)
+}
+
+func TestConfigDriftImagesAndCNI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-config-drift")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	k0sVars := constant.GetConfig(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(k0sVars.ManifestsDir, "coredns"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(k0sVars.ManifestsDir, "calico"), 0755))
+	manifest := `
+spec:
+  containers:
+  - name: coredns
+    image: docker.io/coredns/coredns:1.6.0
+`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(k0sVars.ManifestsDir, "coredns", "coredns.yaml"), []byte(manifest), 0644))
+
+	clusterCfg := v1beta1.DefaultClusterConfig(k0sVars)
+	clusterCfg.Spec.Network.Provider = "kuberouter"
+	d := &ConfigDrift{
+		ClusterConfig: clusterCfg,
+		K0sVars:       k0sVars,
+		log:           logrus.WithField("component", "config-drift"),
+	}
+
+	assert.Equal(t, []status.DriftItem{{
+		Component: "coredns/coredns.yaml",
+		Field:     constant.CoreDNSImage,
+		Declared:  constant.CoreDNSImageVersion,
+		Actual:    "1.6.0",
+	}}, d.imageDrift())
+
+	assert.Equal(t, []status.DriftItem{{
+		Component: "network",
+		Field:     "provider",
+		Declared:  "kuberouter",
+		Actual:    "calico",
+	}}, d.cniDrift())
+}
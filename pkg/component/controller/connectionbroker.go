@@ -0,0 +1,160 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// ConnectionBrokerConfig publishes the controller endpoints for the worker connection brokers
+type ConnectionBrokerConfig struct {
+	ClusterConfig *config.ClusterConfig
+	K0sVars       constant.CfgVars
+}
+
+// Init does nothing
+func (c *ConnectionBrokerConfig) Init() error {
+	return nil
+}
+
+// Run writes the config map with the endpoints, or removes it when the broker is disabled
+func (c *ConnectionBrokerConfig) Run() error {
+	dir := filepath.Join(c.K0sVars.ManifestsDir, "connectionbroker")
+	spec := c.ClusterConfig.Spec.ConnectionBroker
+	if spec == nil || !spec.Enabled {
+		return os.RemoveAll(dir)
+	}
+
+	endpoints, err := yaml.Marshal(spec.Endpoints)
+	if err != nil {
+		return err
+	}
+
+	if err := util.InitDirectory(dir, constant.ManifestsDirMode); err != nil {
+		return err
+	}
+	tw := util.TemplateWriter{
+		Name:     "connection-broker",
+		Template: connectionBrokerTemplate,
+		Data: struct {
+			Endpoints         string
+			DiscoverEndpoints bool
+			APIPort           int
+			KonnectivityPort  int64
+			ProbeInterval     string
+		}{
+			Endpoints:         string(endpoints),
+			DiscoverEndpoints: spec.DiscoverEndpoints,
+			APIPort:           c.ClusterConfig.Spec.API.Port,
+			KonnectivityPort:  c.ClusterConfig.Spec.Konnectivity.AgentPort,
+			ProbeInterval:     spec.ProbeIntervalDuration().String(),
+		},
+		Path: filepath.Join(dir, "connection-broker.yaml"),
+	}
+	if err := tw.Write(); err != nil {
+		return fmt.Errorf("failed to write connection broker manifests: %w", err)
+	}
+	return nil
+}
+
+// Stop does nothing
+func (c *ConnectionBrokerConfig) Stop() error {
+	return nil
+}
+
+// Healthy dummy implementation
+func (c *ConnectionBrokerConfig) Healthy() error { return nil }
+
+const connectionBrokerTemplate = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: k0s-connection-broker
+  namespace: kube-system
+data:
+  apiPort: "{{ .APIPort }}"
+  konnectivityPort: "{{ .KonnectivityPort }}"
+  probeInterval: "{{ .ProbeInterval }}"
+  discoverEndpoints: "{{ .DiscoverEndpoints }}"
+  endpoints: |
+{{ .Endpoints | nindent 4 }}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: system:bootstrappers:k0s-connection-broker
+  namespace: kube-system
+rules:
+- apiGroups: [""]
+  resources: ["configmaps"]
+  resourceNames: ["k0s-connection-broker"]
+  verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: system:bootstrappers:k0s-connection-broker
+  namespace: kube-system
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: system:bootstrappers:k0s-connection-broker
+subjects:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: Group
+    name: system:bootstrappers
+  - apiGroup: rbac.authorization.k8s.io
+    kind: Group
+    name: system:nodes
+{{- if .DiscoverEndpoints }}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: system:bootstrappers:k0s-connection-broker
+  namespace: default
+rules:
+- apiGroups: [""]
+  resources: ["endpoints"]
+  resourceNames: ["kubernetes"]
+  verbs: ["get"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: system:bootstrappers:k0s-connection-broker
+  namespace: default
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: system:bootstrappers:k0s-connection-broker
+subjects:
+  - apiGroup: rbac.authorization.k8s.io
+    kind: Group
+    name: system:bootstrappers
+  - apiGroup: rbac.authorization.k8s.io
+    kind: Group
+    name: system:nodes
+{{- end }}
+`
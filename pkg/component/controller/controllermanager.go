@@ -26,6 +26,7 @@ import (
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
+	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
@@ -36,6 +37,9 @@ type Manager struct {
 	gid           int
 	K0sVars       constant.CfgVars
 	LogLevel      string
+	// LeaderElector keeps the process on standby on the controllers other than the leader, nil runs it everywhere
+	LeaderElector LeaderElector
+	standby       *standby
 	supervisor    supervisor.Supervisor
 	uid           int
 }
@@ -64,33 +68,62 @@ func (a *Manager) Init() error {
 	if err := os.Chown(path.Join(a.K0sVars.CertRootDir, "ca.key"), a.uid, -1); err != nil && os.Geteuid() == 0 {
 		logrus.Warning(fmt.Errorf("failed to change permissions for the ca.key: %w", err))
 	}
+	if a.LeaderElector != nil {
+		a.standby = newStandby("kube-controller-manager", a.LeaderElector)
+	}
 	return assets.Stage(a.K0sVars.BinDir, "kube-controller-manager", constant.BinDirMode)
 }
 
 // Run runs kube Manager
 func (a *Manager) Run() error {
 	logrus.Info("Starting kube-controller-manager")
+	cmArgs, err := a.args()
+	if err != nil {
+		return err
+	}
+
+	sv := supervisor.Supervisor{
+		Name:     "kube-controller-manager",
+		BinPath:  assets.BinPath("kube-controller-manager", a.K0sVars.BinDir),
+		RunDir:   a.K0sVars.RunDir,
+		DataDir:  a.K0sVars.DataDir,
+		CrashDir: a.K0sVars.CrashDir,
+		ArgsDir:  a.K0sVars.ComponentArgsDir,
+		Args:     cmArgs,
+		UID:      a.uid,
+		GID:      a.gid,
+	}
+
+	if a.standby != nil {
+		return a.standby.run(sv)
+	}
+	a.supervisor = sv
+	return a.supervisor.Supervise()
+}
+
+// args renders the flags of kube-controller-manager
+func (a *Manager) args() ([]string, error) {
 	ccmAuthConf := filepath.Join(a.K0sVars.CertRootDir, "ccm.conf")
 	args := map[string]string{
 		"authentication-kubeconfig":        ccmAuthConf,
 		"authorization-kubeconfig":         ccmAuthConf,
 		"kubeconfig":                       ccmAuthConf,
-		"client-ca-file":                   path.Join(a.K0sVars.CertRootDir, "ca.crt"),
+		"client-ca-file":                   certificate.CABundlePath(a.K0sVars.CertRootDir),
 		"cluster-signing-cert-file":        path.Join(a.K0sVars.CertRootDir, "ca.crt"),
 		"cluster-signing-key-file":         path.Join(a.K0sVars.CertRootDir, "ca.key"),
 		"requestheader-client-ca-file":     path.Join(a.K0sVars.CertRootDir, "front-proxy-ca.crt"),
-		"root-ca-file":                     path.Join(a.K0sVars.CertRootDir, "ca.crt"),
+		"root-ca-file":                     certificate.CABundlePath(a.K0sVars.CertRootDir),
 		"service-account-private-key-file": path.Join(a.K0sVars.CertRootDir, "sa.key"),
 		"cluster-cidr":                     a.ClusterConfig.Spec.Network.BuildPodCIDR(),
 		"service-cluster-ip-range":         a.ClusterConfig.Spec.Network.BuildServiceCIDR(a.ClusterConfig.Spec.API.Address),
-		"profiling":                        "false",
+		"profiling":                        a.ClusterConfig.Spec.Profiling.ProfilingArg(),
 		"terminated-pod-gc-threshold":      "12500",
 		"v":                                a.LogLevel,
 	}
 
 	for name, value := range a.ClusterConfig.Spec.ControllerManager.ExtraArgs {
 		if args[name] != "" && name != "profiling" {
-			return fmt.Errorf("cannot override kube-controller-manager flag: %s", name)
+			return nil, fmt.Errorf("cannot override kube-controller-manager flag: %s", name)
 		}
 		args[name] = value
 	}
@@ -114,22 +147,14 @@ func (a *Manager) Run() error {
 	if a.ClusterConfig.Spec.API.ExternalAddress == "" {
 		cmArgs = append(cmArgs, "--leader-elect=false")
 	}
-
-	a.supervisor = supervisor.Supervisor{
-		Name:    "kube-controller-manager",
-		BinPath: assets.BinPath("kube-controller-manager", a.K0sVars.BinDir),
-		RunDir:  a.K0sVars.RunDir,
-		DataDir: a.K0sVars.DataDir,
-		Args:    cmArgs,
-		UID:     a.uid,
-		GID:     a.gid,
-	}
-
-	return a.supervisor.Supervise()
+	return cmArgs, nil
 }
 
 // Stop stops Manager
 func (a *Manager) Stop() error {
+	if a.standby != nil {
+		return a.standby.halt()
+	}
 	return a.supervisor.Stop()
 }
 
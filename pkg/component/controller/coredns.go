@@ -284,7 +284,7 @@ func (c *CoreDNS) Run() error {
 	c.tickerDone = make(chan struct{})
 
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(reconcileInterval(10 * time.Second))
 		defer ticker.Stop()
 		var previousConfig = coreDNSConfig{}
 		for {
@@ -299,14 +299,7 @@ func (c *CoreDNS) Run() error {
 					c.log.Infof("current cfg matches existing, not gonna do anything")
 					continue
 				}
-				tw := util.TemplateWriter{
-					Name:     "coredns",
-					Template: coreDNSTemplate,
-					Data:     cfg,
-					Path:     filepath.Join(corednsDir, "coredns.yaml"),
-				}
-				err = tw.Write()
-				if err != nil {
+				if err := c.write(corednsDir, cfg); err != nil {
 					c.log.Errorf("error writing coredns manifests: %s. will retry", err.Error())
 					continue
 				}
@@ -322,21 +315,22 @@ func (c *CoreDNS) Run() error {
 }
 
 func (c *CoreDNS) getConfig() (coreDNSConfig, error) {
-	dns, err := c.clusterConfig.Spec.Network.DNSAddress()
+	nodes, err := c.client.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{})
 	if err != nil {
 		return coreDNSConfig{}, err
 	}
+	return c.configFor(len(nodes.Items))
+}
 
-	nodes, err := c.client.CoreV1().Nodes().List(context.TODO(), v1.ListOptions{})
+// configFor returns the config for the given number of nodes
+func (c *CoreDNS) configFor(nodeCount int) (coreDNSConfig, error) {
+	dns, err := c.clusterConfig.Spec.Network.DNSAddress()
 	if err != nil {
 		return coreDNSConfig{}, err
 	}
 
-	nodeCount := len(nodes.Items)
-	replicas := replicaCount(nodeCount)
-
 	config := coreDNSConfig{
-		Replicas:      replicas,
+		Replicas:      replicaCount(nodeCount),
 		ClusterDomain: "cluster.local",
 		ClusterDNSIP:  dns,
 		Image:         c.clusterConfig.Spec.Images.CoreDNS.URI(),
@@ -346,6 +340,16 @@ func (c *CoreDNS) getConfig() (coreDNSConfig, error) {
 	return config, nil
 }
 
+func (c *CoreDNS) write(corednsDir string, cfg coreDNSConfig) error {
+	tw := util.TemplateWriter{
+		Name:     "coredns",
+		Template: coreDNSTemplate,
+		Data:     cfg,
+		Path:     filepath.Join(corednsDir, "coredns.yaml"),
+	}
+	return tw.Write()
+}
+
 // calculates an extra replica per 10 hosts
 func replicaCount(nodeCount int) int {
 	// always at least one so we get the coreDNS up-and running fast with the first node joining the cluster
@@ -24,20 +24,22 @@ import (
 
 // CRD unpacks bundled CRD definitions to the filesystem
 type CRD struct {
-	saver manifestsSaver
+	saver   manifestsSaver
+	bundles []string
 }
 
-// NewCRD build new CRD
-func NewCRD(s manifestsSaver) *CRD {
+// NewCRD build new CRD. The k0s CRDs are always unpacked, the helm ones only if helm is enabled.
+func NewCRD(s manifestsSaver, withHelm bool) *CRD {
+	bundles := []string{"v1beta1"}
+	if withHelm {
+		bundles = append([]string{"helm"}, bundles...)
+	}
 	return &CRD{
-		saver: s,
+		saver:   s,
+		bundles: bundles,
 	}
 }
 
-var bundles = []string{
-	"helm",
-}
-
 // Init  (c CRD) Init() error {
 func (c CRD) Init() error {
 	return nil
@@ -45,7 +47,7 @@ func (c CRD) Init() error {
 
 // Run unpacks manifests from bindata
 func (c CRD) Run() error {
-	for _, bundle := range bundles {
+	for _, bundle := range c.bundles {
 		crds, err := static.AssetDir(fmt.Sprintf("manifests/%s/CustomResourceDefinition", bundle))
 
 		if err != nil {
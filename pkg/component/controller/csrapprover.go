@@ -29,6 +29,7 @@ import (
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
 	kubeutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/watchdog"
 	"github.com/sirupsen/logrus"
 	authorization "k8s.io/api/authorization/v1"
 	v1 "k8s.io/api/certificates/v1"
@@ -50,8 +51,9 @@ type csrRecognizer struct {
 }
 
 type CSRApprover struct {
-	L      *logrus.Entry
-	stopCh chan struct{}
+	L         *logrus.Entry
+	stopCh    chan struct{}
+	heartbeat *watchdog.Heartbeat
 
 	ClusterConfig     *config.ClusterConfig
 	KubeClientFactory kubeutil.ClientFactory
@@ -66,7 +68,7 @@ func NewCSRApprover(c *k0sv1beta1.ClusterConfig, leaderElector LeaderElector, ku
 	return &CSRApprover{
 		ClusterConfig:     c,
 		leaderElector:     leaderElector,
-		stopCh:            make(chan struct{}),
+		heartbeat:         watchdog.NewHeartbeat("CSRApprover", reconcileInterval(10*time.Second)),
 		KubeClientFactory: kubeClientFactory,
 		L:                 logrus.WithFields(logrus.Fields{"component": "csrapprover"}),
 	}
@@ -76,7 +78,10 @@ func (a *CSRApprover) Healthy() error { return nil }
 
 // Stop stops the CSRApprover
 func (a *CSRApprover) Stop() error {
-	close(a.stopCh)
+	a.heartbeat.Stop()
+	if a.stopCh != nil {
+		close(a.stopCh)
+	}
 	return nil
 }
 
@@ -93,9 +98,12 @@ func (a *CSRApprover) Init() error {
 
 // Run every 10 seconds checks for newly issued CSRs and approves them
 func (a *CSRApprover) Run() error {
+	stopCh := make(chan struct{})
+	a.stopCh = stopCh
+	a.heartbeat.Start()
 
 	go func() {
-		ticker := time.NewTicker(10 * time.Second) // TODO: sometimes this should be refactored so it watches instead of polls for CSRs
+		ticker := time.NewTicker(reconcileInterval(10 * time.Second)) // TODO: sometimes this should be refactored so it watches instead of polls for CSRs
 		defer ticker.Stop()
 		for {
 			select {
@@ -104,7 +112,8 @@ func (a *CSRApprover) Run() error {
 				if err != nil {
 					a.L.Warnf("CSR approval failed: %s", err.Error())
 				}
-			case <-a.stopCh:
+				a.heartbeat.Beat()
+			case <-stopCh:
 				a.L.Info("CSR Approver done")
 				return
 			}
@@ -15,30 +15,44 @@ limitations under the License.
 */
 package controller
 
+// DummyLeaderElector is used when every controller runs the singleton reconcilers, it only steps down for maintenance
 type DummyLeaderElector struct {
-	Leader    bool
-	callbacks []func()
+	Leader      bool
+	Maintenance *Maintenance
+
+	callbacks     []func()
+	lostCallbacks []func()
+}
+
+func (l *DummyLeaderElector) Init() error {
+	if l.Leader && l.Maintenance != nil {
+		l.Maintenance.AddCallback(func(enabled bool) {
+			if enabled {
+				runCallbacks(l.lostCallbacks)
+			} else {
+				runCallbacks(l.callbacks)
+			}
+		})
+	}
+	return nil
 }
 
-func (l *DummyLeaderElector) Init() error    { return nil }
 func (l *DummyLeaderElector) Stop() error    { return nil }
-func (l *DummyLeaderElector) IsLeader() bool { return l.Leader }
+func (l *DummyLeaderElector) IsLeader() bool { return l.Leader && !l.Maintenance.Enabled() }
 func (l *DummyLeaderElector) Healthy() error { return nil }
 
 func (l *DummyLeaderElector) AddAcquiredLeaseCallback(fn func()) {
 	l.callbacks = append(l.callbacks, fn)
 }
 
-func (l *DummyLeaderElector) AddLostLeaseCallback(func()) {}
+func (l *DummyLeaderElector) AddLostLeaseCallback(fn func()) {
+	l.lostCallbacks = append(l.lostCallbacks, fn)
+}
 
 func (l *DummyLeaderElector) Run() error {
-	if !l.Leader {
+	if !l.IsLeader() {
 		return nil
 	}
-	for _, fn := range l.callbacks {
-		if fn != nil {
-			fn()
-		}
-	}
+	runCallbacks(l.callbacks)
 	return nil
 }
@@ -45,6 +45,7 @@ type Etcd struct {
 	JoinClient  *token.JoinClient
 	K0sVars     constant.CfgVars
 	LogLevel    string
+	Profiling   *config.ProfilingSpec
 
 	supervisor supervisor.Supervisor
 	uid        int
@@ -89,7 +90,7 @@ func (e *Etcd) syncEtcdConfig(peerURL, etcdCaCert, etcdCaCertKey string) ([]stri
 	var err error
 	for i := 0; i < 20; i++ {
 		logrus.Infof("trying to sync etcd config")
-		etcdResponse, err = e.JoinClient.JoinEtcd(peerURL)
+		etcdResponse, err = e.JoinClient.JoinEtcd(peerURL, e.Config.Learner)
 		if err == nil {
 			break
 		}
@@ -140,7 +141,7 @@ func (e *Etcd) Run() error {
 		return err
 	}
 
-	peerURL := fmt.Sprintf("https://%s:2380", e.Config.PeerAddress)
+	peerURL := etcd.PeerURL(e.Config.PeerAddress)
 
 	args := util.MappedArgs{
 		"--data-dir":                    e.K0sVars.EtcdDataDir,
@@ -158,11 +159,33 @@ func (e *Etcd) Run() error {
 		"--peer-cert-file":              etcdPeerCert,
 		"--log-level":                   e.LogLevel,
 		"--peer-client-cert-auth":       "true",
-		"--enable-pprof":                "false",
+		"--enable-pprof":                e.Profiling.ProfilingArg(),
+	}
+	if e.Config.Compaction != nil {
+		for name, value := range e.Config.Compaction.EtcdArgs() {
+			args[name] = value
+		}
+	}
+	hw, err := etcd.ProbeHardware(e.K0sVars.EtcdDataDir)
+	if err != nil {
+		logrus.Warnf("failed to detect the hardware class, not tuning etcd: %v", err)
+		hw = &etcd.Hardware{}
+	} else {
+		logrus.Infof("tuning etcd for the detected hardware: %s", hw)
+	}
+	for name, value := range etcd.TuningArgs(*hw, e.Config.Tuning) {
+		args[name] = value
+	}
+	if e.Config.ExternalClientAccess {
+		clientURL := etcd.ClientURL(e.Config.PeerAddress)
+		args["--listen-client-urls"] += "," + clientURL
+		args["--advertise-client-urls"] += "," + clientURL
 	}
 
 	if util.FileExists(filepath.Join(e.K0sVars.EtcdDataDir, "member", "snap", "db")) {
 		logrus.Warnf("etcd db file(s) already exist, not gonna run join process")
+	} else if e.Config.Learner && e.JoinClient == nil {
+		return fmt.Errorf("an etcd learner must join an existing cluster, a join token is needed")
 	} else if e.JoinClient != nil {
 		initialCluster, err := e.syncEtcdConfig(peerURL, etcdCaCert, etcdCaCertKey)
 		if err != nil {
@@ -185,13 +208,15 @@ func (e *Etcd) Run() error {
 	logrus.Infof("starting etcd with args: %v", args)
 
 	e.supervisor = supervisor.Supervisor{
-		Name:    "etcd",
-		BinPath: assets.BinPath("etcd", e.K0sVars.BinDir),
-		RunDir:  e.K0sVars.RunDir,
-		DataDir: e.K0sVars.DataDir,
-		Args:    args.ToArgs(),
-		UID:     e.uid,
-		GID:     e.gid,
+		Name:     "etcd",
+		BinPath:  assets.BinPath("etcd", e.K0sVars.BinDir),
+		RunDir:   e.K0sVars.RunDir,
+		DataDir:  e.K0sVars.DataDir,
+		CrashDir: e.K0sVars.CrashDir,
+		ArgsDir:  e.K0sVars.ComponentArgsDir,
+		Args:     args.ToArgs(),
+		UID:      e.uid,
+		GID:      e.gid,
 	}
 
 	return e.supervisor.Supervise()
@@ -231,16 +256,20 @@ func (e *Etcd) setupCerts() error {
 
 	eg.Go(func() error {
 		// etcd server cert
+		hostnames := []string{
+			"127.0.0.1",
+			"localhost",
+		}
+		if e.Config.ExternalClientAccess {
+			hostnames = append(hostnames, e.Config.PeerAddress)
+		}
 		etcdCertReq := certificate.Request{
-			Name:   filepath.Join("etcd", "server"),
-			CN:     "etcd-server",
-			O:      "etcd-server",
-			CACert: etcdCaCert,
-			CAKey:  etcdCaCertKey,
-			Hostnames: []string{
-				"127.0.0.1",
-				"localhost",
-			},
+			Name:      filepath.Join("etcd", "server"),
+			CN:        "etcd-server",
+			O:         "etcd-server",
+			CACert:    etcdCaCert,
+			CAKey:     etcdCaCertKey,
+			Hostnames: hostnames,
 		}
 		_, err := e.CertManager.EnsureCertificate(etcdCertReq, constant.EtcdUser)
 		return err
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"expvar"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+)
+
+// etcdHealth exposes the health metrics of the local etcd member on the debug server under /debug/vars
+var etcdHealth = expvar.NewMap("k0s_etcd")
+
+// EtcdMetrics periodically scrapes the health metrics of the local etcd member and warns about the ones past their thresholds
+type EtcdMetrics struct {
+	K0sVars  constant.CfgVars
+	Interval time.Duration
+
+	log        *logrus.Entry
+	client     *etcd.Client
+	prev       *etcd.Metrics
+	tickerDone chan struct{}
+}
+
+// Init sets the defaults
+func (e *EtcdMetrics) Init() error {
+	e.log = logrus.WithField("component", "etcd-metrics")
+	if e.Interval == 0 {
+		e.Interval = time.Minute
+	}
+	return nil
+}
+
+// Run starts scraping the metrics
+func (e *EtcdMetrics) Run() error {
+	tickerDone := make(chan struct{})
+	e.tickerDone = tickerDone
+
+	go func() {
+		ticker := time.NewTicker(e.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.scrape()
+			case <-tickerDone:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops scraping the metrics
+func (e *EtcdMetrics) Stop() error {
+	if e.tickerDone != nil {
+		close(e.tickerDone)
+	}
+	if e.client != nil {
+		e.client.Close()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (e *EtcdMetrics) Healthy() error { return nil }
+
+func (e *EtcdMetrics) scrape() {
+	if e.client == nil {
+		// the client certificates are created during the start up
+		client, err := etcd.NewClient(e.K0sVars.CertRootDir, e.K0sVars.EtcdCertDir)
+		if err != nil {
+			e.log.Warnf("failed to create the etcd client: %v", err)
+			return
+		}
+		e.client = client
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	m, err := e.client.Metrics(ctx)
+	if err != nil {
+		e.log.Warnf("failed to scrape the etcd metrics: %v", err)
+		return
+	}
+	// the latency since the member was started hides the recent trouble
+	m.FsyncP99Seconds = m.FsyncP99SecondsSince(e.prev)
+	if e.prev != nil && m.LeaderChanges > e.prev.LeaderChanges {
+		e.log.Warnf("etcd leader changed %d times in the last %s, frequent leader changes point to network or disk trouble", m.LeaderChanges-e.prev.LeaderChanges, e.Interval)
+	}
+	alerts := m.Alerts()
+	for _, alert := range alerts {
+		e.log.Warn(alert)
+	}
+	e.prev = m
+
+	hasLeader := int64(0)
+	if m.HasLeader {
+		hasLeader = 1
+	}
+	etcdHealth.Set("has_leader", expvarInt(hasLeader))
+	etcdHealth.Set("db_size_bytes", expvarInt(m.DBSizeBytes))
+	etcdHealth.Set("quota_backend_bytes", expvarInt(m.QuotaBytes))
+	etcdHealth.Set("leader_changes", expvarInt(m.LeaderChanges))
+	fsync := new(expvar.Float)
+	fsync.Set(m.FsyncP99Seconds)
+	etcdHealth.Set("wal_fsync_p99_seconds", fsync)
+	etcdHealth.Set("alerts", expvarInt(int64(len(alerts))))
+}
+
+func expvarInt(value int64) *expvar.Int {
+	v := new(expvar.Int)
+	v.Set(value)
+	return v
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/etcd/clientv3/snapshot"
+	"go.uber.org/zap"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+const (
+	snapshotPrefix = "etcd-snapshot-"
+	snapshotSuffix = ".db"
+	snapshotLayout = "20060102T150405Z"
+)
+
+// EtcdSnapshots takes the etcd snapshots on the configured schedule and keeps the configured amount of them
+type EtcdSnapshots struct {
+	Spec    *config.EtcdSnapshotsSpec
+	K0sVars constant.CfgVars
+
+	log      *logrus.Entry
+	schedule *util.Schedule
+	statusMu sync.Mutex
+	status   status.SnapshotStatus
+	stopCh   chan struct{}
+}
+
+// Init parses the schedule and picks up the snapshots taken before the restart
+func (e *EtcdSnapshots) Init() error {
+	e.log = logrus.WithField("component", "etcd-snapshots")
+	schedule, err := util.ParseSchedule(e.Spec.Schedule)
+	if err != nil {
+		return err
+	}
+	e.schedule = schedule
+	if err := util.InitDirectory(e.K0sVars.EtcdSnapshotDir, constant.EtcdDataDirMode); err != nil {
+		return err
+	}
+
+	next := schedule.Next(time.Now())
+	if next.IsZero() {
+		return fmt.Errorf("the etcd snapshot schedule %q never fires", e.Spec.Schedule)
+	}
+	e.status = status.SnapshotStatus{NextRun: next, StaleAfter: e.Spec.StaleAfterDuration(schedule.Next(next).Sub(next))}
+	if snapshots, err := e.snapshots(); err == nil && len(snapshots) > 0 {
+		e.status.Path = snapshots[len(snapshots)-1]
+		e.status.LastSnapshot, _ = snapshotTime(e.status.Path)
+	}
+
+	etcdHealth.Set("snapshot_age_seconds", expvar.Func(func() interface{} {
+		return int64(e.currentStatus().Age(time.Now()).Seconds())
+	}))
+	etcdHealth.Set("snapshot_stale", expvar.Func(func() interface{} {
+		return e.currentStatus().Stale(time.Now())
+	}))
+	return e.writeStatus()
+}
+
+func (e *EtcdSnapshots) currentStatus() status.SnapshotStatus {
+	e.statusMu.Lock()
+	defer e.statusMu.Unlock()
+	return e.status
+}
+
+// Run starts taking the snapshots
+func (e *EtcdSnapshots) Run() error {
+	stopCh := make(chan struct{})
+	e.stopCh = stopCh
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(e.currentStatus().NextRun))
+			select {
+			case <-timer.C:
+				e.takeSnapshot()
+				if err := e.writeStatus(); err != nil {
+					e.log.Warnf("failed to write the snapshot status: %v", err)
+				}
+			case <-stopCh:
+				timer.Stop()
+				e.log.Info("etcd snapshots done")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops taking the snapshots
+func (e *EtcdSnapshots) Stop() error {
+	if e.stopCh != nil {
+		close(e.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (e *EtcdSnapshots) Healthy() error { return nil }
+
+func (e *EtcdSnapshots) takeSnapshot() {
+	path, err := e.save(time.Now().UTC())
+
+	e.statusMu.Lock()
+	e.status.NextRun = e.schedule.Next(time.Now())
+	if err != nil {
+		e.status.LastError = err.Error()
+	} else {
+		e.status.LastError = ""
+		e.status.Path = path
+		e.status.LastSnapshot, _ = snapshotTime(path)
+	}
+	e.statusMu.Unlock()
+
+	if err != nil {
+		e.log.Errorf("failed to take the etcd snapshot: %v", err)
+		return
+	}
+	e.log.Infof("etcd snapshot saved to %s", path)
+	if err := e.rotate(); err != nil {
+		e.log.Warnf("failed to remove the old etcd snapshots: %v", err)
+	}
+}
+
+func (e *EtcdSnapshots) save(now time.Time) (string, error) {
+	client, err := etcd.NewClient(e.K0sVars.CertRootDir, e.K0sVars.EtcdCertDir)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	path := filepath.Join(e.K0sVars.EtcdSnapshotDir, snapshotPrefix+now.Format(snapshotLayout)+snapshotSuffix)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	// the snapshot is saved to a temporary file first, a partial snapshot is never picked up
+	if err := snapshot.NewV3(zap.NewNop()).Save(ctx, *client.Config, path+".part"); err != nil {
+		return "", err
+	}
+	return path, os.Rename(path+".part", path)
+}
+
+// rotate removes the oldest snapshots beyond the retention
+func (e *EtcdSnapshots) rotate() error {
+	snapshots, err := e.snapshots()
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > e.Spec.Keep() {
+		if err := os.Remove(snapshots[0]); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// snapshots lists the snapshots from the oldest to the newest
+func (e *EtcdSnapshots) snapshots() ([]string, error) {
+	files, err := ioutil.ReadDir(e.K0sVars.EtcdSnapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []string
+	for _, f := range files {
+		if f.Mode().IsRegular() && strings.HasPrefix(f.Name(), snapshotPrefix) && strings.HasSuffix(f.Name(), snapshotSuffix) {
+			snapshots = append(snapshots, filepath.Join(e.K0sVars.EtcdSnapshotDir, f.Name()))
+		}
+	}
+	sort.Strings(snapshots)
+	return snapshots, nil
+}
+
+func snapshotTime(path string) (time.Time, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), snapshotPrefix), snapshotSuffix)
+	t, err := time.Parse(snapshotLayout, name)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid snapshot name %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (e *EtcdSnapshots) writeStatus() error {
+	return status.WriteSnapshotStatus(e.K0sVars.EtcdSnapshotStatusPath, e.currentStatus())
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestEtcdSnapshotsRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "etcd-snapshots")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		name := snapshotPrefix + start.Add(time.Duration(i)*time.Hour).Format(snapshotLayout) + snapshotSuffix
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("snapshot"), 0600))
+	}
+	// partial snapshots and other files are left alone
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, snapshotPrefix+"20210501T050000Z.db.part"), nil, 0600))
+
+	e := &EtcdSnapshots{
+		Spec:    &config.EtcdSnapshotsSpec{Schedule: "@hourly", Retention: 2},
+		K0sVars: constant.CfgVars{EtcdSnapshotDir: dir},
+	}
+	require.NoError(t, e.rotate())
+
+	snapshots, err := e.snapshots()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "etcd-snapshot-20210501T020000Z.db"),
+		filepath.Join(dir, "etcd-snapshot-20210501T030000Z.db"),
+	}, snapshots)
+	assert.True(t, util.FileExists(filepath.Join(dir, snapshotPrefix+"20210501T050000Z.db.part")))
+
+	taken, err := snapshotTime(snapshots[1])
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(3*time.Hour), taken)
+}
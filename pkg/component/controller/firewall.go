@@ -0,0 +1,99 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/firewall"
+)
+
+// firewallSyncInterval is how often the ports are checked in the host firewall
+const firewallSyncInterval = time.Minute
+
+// Firewall opens the ports of the controller in the host firewall while spec.network.firewall is enabled, and opens
+// them again when they're closed, e.g. by a reload of the firewall. The ports are left open once disabled.
+type Firewall struct {
+	ClusterConfig *config.ClusterConfig
+	// Worker adds the ports of the worker, for the controllers running a worker
+	Worker bool
+
+	log    *logrus.Entry
+	cancel context.CancelFunc
+	synced bool
+}
+
+// Init initializes the logger
+func (f *Firewall) Init() error {
+	f.log = logrus.WithField("component", "firewall")
+	return nil
+}
+
+// Run syncs the ports right away, before the other controllers and the workers try to connect, and then periodically
+func (f *Firewall) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(firewallSyncInterval)
+		defer ticker.Stop()
+		for {
+			f.sync()
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the sync, the ports are left open
+func (f *Firewall) Stop() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (f *Firewall) Healthy() error { return nil }
+
+func (f *Firewall) sync() {
+	rules := f.ClusterConfig.Spec.FirewallRules(true, f.Worker)
+	if rules == nil {
+		f.synced = false
+		return
+	}
+	opened, err := firewall.Sync(rules)
+	switch {
+	case errors.Is(err, firewall.ErrNoFirewall):
+		f.log.Debug("no firewall found on the host, nothing to open")
+	case err != nil:
+		f.log.Warnf("failed to open the ports in the firewall: %v", err)
+	case len(opened) > 0 && f.synced:
+		f.log.Warnf("reopened %s, the ports were closed in the firewall", strings.Join(opened, " "))
+	case len(opened) > 0:
+		f.log.Infof("opened %s in the firewall", strings.Join(opened, " "))
+	}
+	f.synced = err == nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// healthCheckTimeout bounds a single check, a hanging check fails instead of holding up the results
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is a named check of the local control plane
+type HealthCheck struct {
+	Name  string
+	Check func() error
+}
+
+type healthResult struct {
+	name string
+	err  error
+}
+
+// HealthEndpoint serves the aggregate health of the local control plane for the load balancers in front of the
+// controllers, on spec.api.healthPort. /readyz answers 200 when all the checks pass and the controller isn't in
+// maintenance, 503 otherwise. The checks are run periodically so that frequent probes don't load the control plane.
+type HealthEndpoint struct {
+	Port        int
+	Checks      []HealthCheck
+	Maintenance *Maintenance
+	Interval    time.Duration
+
+	log     *logrus.Entry
+	server  *http.Server
+	stopCh  chan struct{}
+	mu      sync.Mutex
+	results []healthResult
+}
+
+// Init initializes the logger
+func (h *HealthEndpoint) Init() error {
+	h.log = logrus.WithField("component", "health-endpoint")
+	if h.Interval == 0 {
+		h.Interval = 2 * time.Second
+	}
+	return nil
+}
+
+// Run starts the checks and serves their results
+func (h *HealthEndpoint) Run() error {
+	stopCh := make(chan struct{})
+	h.stopCh = stopCh
+	go func() {
+		ticker := time.NewTicker(h.Interval)
+		defer ticker.Stop()
+		for {
+			h.check()
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/readyz", h.readyz)
+	h.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", h.Port),
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := h.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			h.log.Errorf("failed to serve the health endpoint: %v", err)
+		}
+	}()
+	h.log.Infof("serving the control plane health on :%d/readyz", h.Port)
+	return nil
+}
+
+// Stop stops serving first, so that the load balancers drop the controller while it's shutting down
+func (h *HealthEndpoint) Stop() error {
+	if h.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = h.server.Shutdown(ctx)
+	}
+	if h.stopCh != nil {
+		close(h.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (h *HealthEndpoint) Healthy() error { return nil }
+
+func (h *HealthEndpoint) check() {
+	results := make([]healthResult, len(h.Checks))
+	var wg sync.WaitGroup
+	for i, c := range h.Checks {
+		wg.Add(1)
+		go func(i int, c HealthCheck) {
+			defer wg.Done()
+			results[i] = healthResult{name: c.Name, err: runWithTimeout(c.Check, healthCheckTimeout)}
+		}(i, c)
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.results = results
+}
+
+func runWithTimeout(check func() error, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- check() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// status renders the results of the checks the way the readyz endpoint of the API server does
+func (h *HealthEndpoint) status() (bool, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.results == nil {
+		return false, "[-]checks not run yet\n"
+	}
+	ready := true
+	var b strings.Builder
+	for _, r := range h.results {
+		if r.err != nil {
+			ready = false
+			fmt.Fprintf(&b, "[-]%s failed: %v\n", r.name, r.err)
+		} else {
+			fmt.Fprintf(&b, "[+]%s ok\n", r.name)
+		}
+	}
+	if h.Maintenance.Enabled() {
+		ready = false
+		b.WriteString("[-]maintenance enabled\n")
+	} else {
+		b.WriteString("[+]maintenance disabled\n")
+	}
+	return ready, b.String()
+}
+
+func (h *HealthEndpoint) readyz(w http.ResponseWriter, r *http.Request) {
+	ready, body := h.status()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = fmt.Fprint(w, body)
+}
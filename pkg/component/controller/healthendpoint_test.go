@@ -0,0 +1,83 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestHealthEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-health")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.GetConfig(dir)
+
+	var etcdErr error
+	h := &HealthEndpoint{
+		Maintenance: NewMaintenance(k0sVars),
+		Checks: []HealthCheck{
+			{Name: "apiserver", Check: func() error { return nil }},
+			{Name: "etcd", Check: func() error { return etcdErr }},
+		},
+	}
+	get := func() (int, string) {
+		rec := httptest.NewRecorder()
+		h.readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	code, _ := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code, "not ready before the first checks")
+
+	h.check()
+	code, body := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[+]apiserver ok\n[+]etcd ok\n[+]maintenance disabled\n", body)
+
+	etcdErr = fmt.Errorf("no leader")
+	h.check()
+	code, body = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[-]etcd failed: no leader\n")
+
+	etcdErr = nil
+	h.check()
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	h.Maintenance.check()
+	code, body = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[-]maintenance enabled\n")
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	err := runWithTimeout(func() error {
+		time.Sleep(time.Second)
+		return nil
+	}, 10*time.Millisecond)
+	assert.Error(t, err)
+	assert.NoError(t, runWithTimeout(func() error { return nil }, time.Second))
+}
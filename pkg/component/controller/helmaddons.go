@@ -97,6 +97,11 @@ func (h *HelmAddons) initHelm() error {
 		}
 	}
 
+	return h.writeCharts()
+}
+
+// writeCharts writes the Chart resources of the addons
+func (h *HelmAddons) writeCharts() error {
 	for _, addon := range h.ClusterConfig.Spec.Extensions.Helm.Charts {
 		tw := util.TemplateWriter{
 			Name:     "addon_crd_manifest",
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/hosts"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+)
+
+// hostsSyncInterval is how often the addresses are published and the hosts file is updated
+const hostsSyncInterval = 30 * time.Second
+
+// HostsSync publishes the address of the controller for the other cluster members and keeps the names of the
+// controllers and the nodes in the hosts file of the controller, so that they resolve without an external DNS.
+// With spec.network.hostsSync disabled the published addresses are removed, which disables the sync on the workers.
+type HostsSync struct {
+	ClusterConfig     *config.ClusterConfig
+	KubeClientFactory k8sutil.ClientFactory
+	HostsPath         string
+
+	log         *logrus.Entry
+	cancel      context.CancelFunc
+	unpublished bool
+}
+
+// Init initializes the logger
+func (h *HostsSync) Init() error {
+	h.log = logrus.WithField("component", "hosts-sync")
+	if h.HostsPath == "" {
+		h.HostsPath = hosts.DefaultPath
+	}
+	return nil
+}
+
+// Run publishes the address and syncs the hosts file periodically
+func (h *HostsSync) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(hostsSyncInterval)
+		defer ticker.Stop()
+		for {
+			if err := h.sync(ctx); err != nil {
+				h.log.Warnf("failed to sync the hosts: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the sync, the hosts file is left as is
+func (h *HostsSync) Stop() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (h *HostsSync) Healthy() error { return nil }
+
+func (h *HostsSync) sync(ctx context.Context) error {
+	client, err := h.KubeClientFactory.GetClient()
+	if err != nil {
+		return err
+	}
+	if !h.ClusterConfig.Spec.Network.HostsSync {
+		if h.unpublished {
+			return nil
+		}
+		if err := hosts.Unpublish(ctx, client); err != nil {
+			return err
+		}
+		if _, err := hosts.Update(h.HostsPath, nil); err != nil {
+			return err
+		}
+		h.unpublished = true
+		return nil
+	}
+	h.unpublished = false
+
+	name, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	if err := hosts.Publish(ctx, client, name, h.ClusterConfig.Spec.API.Address); err != nil {
+		return err
+	}
+	entries, _, err := hosts.Lookup(ctx, client)
+	if err != nil {
+		return err
+	}
+	changed, err := hosts.Update(h.HostsPath, entries)
+	if changed {
+		h.log.Infof("updated %s with %d cluster members", h.HostsPath, len(entries))
+	}
+	return err
+}
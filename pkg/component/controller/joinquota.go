@@ -0,0 +1,339 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"crypto/x509"
+	"expvar"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	certificates "k8s.io/api/certificates/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/token"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+const bootstrapUserPrefix = "system:bootstrap:"
+
+var kubeletClientUsages = []certificates.KeyUsage{
+	certificates.UsageKeyEncipherment,
+	certificates.UsageDigitalSignature,
+	certificates.UsageClientAuth,
+}
+
+var (
+	// tokenJoins exposes the joins counted towards the quota of each token on the debug server under /debug/vars
+	tokenJoins = expvar.NewMap("k0s_token_joins")
+	// tokenJoinsDenied counts the node CSRs denied because the token quota was exhausted
+	tokenJoinsDenied = expvar.NewMap("k0s_token_joins_denied")
+)
+
+// JoinQuota approves the kubelet client CSRs of the worker tokens with a join quota, and of the bootstrap tokens not
+// created by k0s. The controller manager only approves the CSRs of the tokens in token.AutoApproveGroup, so the quota
+// is checked before the approval: the CSRs exceeding the quota are denied and the token is disabled for
+// authentication until the quota window allows new joins again. The new CSRs are handled as they are created, the
+// periodic check only catches up with the missed ones and re-enables the tokens.
+type JoinQuota struct {
+	L *logrus.Entry
+
+	leaderElector     LeaderElector
+	kubeClientFactory k8sutil.ClientFactory
+	clientset         clientset.Interface
+	mu                sync.Mutex
+	cancel            context.CancelFunc
+	heartbeat         *watchdog.Heartbeat
+}
+
+// NewJoinQuota creates the JoinQuota component
+func NewJoinQuota(leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *JoinQuota {
+	return &JoinQuota{
+		leaderElector:     leaderElector,
+		kubeClientFactory: kubeClientFactory,
+		heartbeat:         watchdog.NewHeartbeat("JoinQuota", reconcileInterval(2*time.Second)),
+		L:                 logrus.WithFields(logrus.Fields{"component": "joinquota"}),
+	}
+}
+
+// Init initializes the kube client
+func (q *JoinQuota) Init() error {
+	var err error
+	q.clientset, err = q.kubeClientFactory.GetClient()
+	if err != nil {
+		return fmt.Errorf("can't create kubernetes client for join quota checks: %w", err)
+	}
+	return nil
+}
+
+// Run handles the kubelet client CSRs as they are created, and checks all of them every two seconds
+func (q *JoinQuota) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+	q.heartbeat.Start()
+
+	created := make(chan struct{}, 1)
+	go q.watch(ctx, created)
+	go func() {
+		ticker := time.NewTicker(reconcileInterval(2 * time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.heartbeat.Beat()
+			case <-created:
+			case <-ctx.Done():
+				q.L.Info("join quota enforcer done")
+				return
+			}
+			if err := q.enforce(); err != nil {
+				q.L.Warnf("join quota enforcement failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// watch signals the creation of the kubelet client CSRs until ctx is done
+func (q *JoinQuota) watch(ctx context.Context, created chan<- struct{}) {
+	for ctx.Err() == nil {
+		w, err := q.clientset.CertificatesV1().CertificateSigningRequests().Watch(ctx, metav1.ListOptions{
+			FieldSelector: "spec.signerName=" + certificates.KubeAPIServerClientKubeletSignerName,
+		})
+		if err != nil {
+			q.L.Debugf("can't watch CSRs: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for event := range w.ResultChan() {
+			if event.Type != watch.Added {
+				continue
+			}
+			select {
+			case created <- struct{}{}:
+			default:
+			}
+		}
+		w.Stop()
+	}
+}
+
+// Stop stops the enforcement
+func (q *JoinQuota) Stop() error {
+	q.heartbeat.Stop()
+	if q.cancel != nil {
+		q.cancel()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (q *JoinQuota) Healthy() error { return nil }
+
+func (q *JoinQuota) enforce() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.leaderElector.IsLeader() {
+		q.L.Debug("not the leader, not enforcing join quotas")
+		return nil
+	}
+
+	secrets, err := q.clientset.CoreV1().Secrets("kube-system").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "type=bootstrap.kubernetes.io/token",
+	})
+	if err != nil {
+		return fmt.Errorf("can't list bootstrap tokens: %w", err)
+	}
+	tokens := map[string]*core.Secret{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		tokens[string(secret.Data["token-id"])] = secret
+	}
+
+	csrs, err := q.clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "spec.signerName=" + certificates.KubeAPIServerClientKubeletSignerName,
+	})
+	if err != nil {
+		return fmt.Errorf("can't fetch CSRs: %w", err)
+	}
+	requests := map[string]map[string]*certificates.CertificateSigningRequest{}
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		if !strings.HasPrefix(csr.Spec.Username, bootstrapUserPrefix) || hasGroup(csr.Spec.Groups, token.AutoApproveGroup) {
+			continue
+		}
+		if _, denied := getCertApprovalCondition(&csr.Status); denied {
+			continue
+		}
+		tokenID := strings.TrimPrefix(csr.Spec.Username, bootstrapUserPrefix)
+		if requests[tokenID] == nil {
+			requests[tokenID] = map[string]*certificates.CertificateSigningRequest{}
+		}
+		requests[tokenID][csr.Name] = csr
+	}
+
+	for tokenID, secret := range tokens {
+		if _, found := secret.Data[token.MaxJoinsKey]; !found && requests[tokenID] == nil {
+			continue
+		}
+		if err := q.enforceToken(tokenID, secret, requests[tokenID]); err != nil {
+			q.L.Warnf("failed to enforce the join quota of token %s: %v", tokenID, err)
+		}
+	}
+	return nil
+}
+
+func (q *JoinQuota) enforceToken(tokenID string, secret *core.Secret, requests map[string]*certificates.CertificateSigningRequest) error {
+	quota, err := token.QuotaFromData(secret.Data)
+	if err != nil {
+		return err
+	}
+	if !quota.Enabled() {
+		// not limited, e.g. a token not created by k0s
+		for _, csr := range requests {
+			if err := q.approve(csr); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	joins, err := token.JoinsFromAnnotation(secret.Annotations)
+	if err != nil {
+		return err
+	}
+
+	created := map[string]time.Time{}
+	for name, csr := range requests {
+		created[name] = csr.CreationTimestamp.Time
+	}
+	before := joins.Annotation()
+	_, denied := joins.Admit(quota, created, time.Now())
+
+	for _, name := range denied {
+		csr := requests[name]
+		if approved, _ := getCertApprovalCondition(&csr.Status); approved {
+			// approved by someone else, e.g. by a controller of an earlier version
+			joins[name] = created[name]
+			q.L.Warnf("csr %s of token %s exceeded the join quota but was already approved", name, tokenID)
+			continue
+		}
+		q.L.Warnf("denying csr %s, token %s has exhausted its join quota of %s", name, tokenID, quota)
+		csr.Status.Conditions = append(csr.Status.Conditions, certificates.CertificateSigningRequestCondition{
+			Type:    certificates.CertificateDenied,
+			Reason:  "JoinQuotaExceeded",
+			Message: fmt.Sprintf("token %s has exhausted its join quota of %s", tokenID, quota),
+			Status:  core.ConditionTrue,
+		})
+		if _, err := q.clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(context.TODO(), csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to deny csr %s: %w", csr.Name, err)
+		}
+		tokenJoinsDenied.Add(tokenID, 1)
+	}
+
+	joinCount := new(expvar.Int)
+	joinCount.Set(int64(len(joins)))
+	tokenJoins.Set(tokenID, joinCount)
+
+	exhausted := joins.Exhausted(quota)
+	_, disabled := secret.Annotations[token.QuotaExhaustedAnnotation]
+	if before != joins.Annotation() || exhausted != disabled {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[token.JoinsAnnotation] = joins.Annotation()
+		// disabling the authentication keeps further nodes from even creating CSRs with the token
+		if exhausted && !disabled {
+			q.L.Infof("token %s has exhausted its join quota of %s, disabling it", tokenID, quota)
+			secret.Annotations[token.QuotaExhaustedAnnotation] = "true"
+			secret.Data["usage-bootstrap-authentication"] = []byte("false")
+		} else if !exhausted && disabled {
+			q.L.Infof("token %s is within its join quota of %s again, enabling it", tokenID, quota)
+			delete(secret.Annotations, token.QuotaExhaustedAnnotation)
+			secret.Data["usage-bootstrap-authentication"] = []byte("true")
+		}
+		// the joins are recorded before approving, a conflicting update leaves the CSRs pending for the next check
+		if _, err := q.clientset.CoreV1().Secrets("kube-system").Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	for name, csr := range requests {
+		if _, admitted := joins[name]; admitted {
+			if err := q.approve(csr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// approve approves the pending CSR if it requests a kubelet client certificate
+func (q *JoinQuota) approve(csr *certificates.CertificateSigningRequest) error {
+	if approved, denied := getCertApprovalCondition(&csr.Status); approved || denied {
+		return nil
+	}
+	x509cr, err := parseCSR(csr)
+	if err != nil {
+		return fmt.Errorf("unable to parse csr %s: %w", csr.Name, err)
+	}
+	if !isNodeClientCert(csr, x509cr) {
+		q.L.Warnf("csr %s of %s doesn't request a kubelet client certificate, not approving it", csr.Name, csr.Spec.Username)
+		return nil
+	}
+	q.L.Infof("approving csr %s of %s", csr.Name, csr.Spec.Username)
+	appendApprovalCondition(csr, "Auto approving kubelet client certificate within the join quota of the token.")
+	if _, err := q.clientset.CertificatesV1().CertificateSigningRequests().UpdateApproval(context.TODO(), csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to approve csr %s: %w", csr.Name, err)
+	}
+	return nil
+}
+
+// isNodeClientCert checks the request the same way as the controller manager does before approving a kubelet client
+// certificate
+func isNodeClientCert(csr *certificates.CertificateSigningRequest, x509cr *x509.CertificateRequest) bool {
+	if !reflect.DeepEqual([]string{"system:nodes"}, x509cr.Subject.Organization) {
+		return false
+	}
+	if len(x509cr.DNSNames) > 0 || len(x509cr.EmailAddresses) > 0 || len(x509cr.IPAddresses) > 0 || len(x509cr.URIs) > 0 {
+		return false
+	}
+	if !strings.HasPrefix(x509cr.Subject.CommonName, "system:node:") {
+		return false
+	}
+	return hasExactUsages(csr, kubeletClientUsages) ||
+		hasExactUsages(csr, []certificates.KeyUsage{certificates.UsageDigitalSignature, certificates.UsageClientAuth})
+}
+
+func hasGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
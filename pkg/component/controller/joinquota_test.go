@@ -0,0 +1,104 @@
+/*
+Copyright 2020 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	certv1 "k8s.io/api/certificates/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k0sproject/k0s/internal/testutil"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+func bootstrapToken(id string, data map[string]string) *core.Secret {
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-" + id, Namespace: "kube-system"},
+		Type:       core.SecretTypeBootstrapToken,
+		Data:       map[string][]byte{"token-id": []byte(id)},
+	}
+	for key, value := range data {
+		secret.Data[key] = []byte(value)
+	}
+	return secret
+}
+
+func kubeletClientCSR(t *testing.T, name string, tokenID string, groups []string, created time.Time) *certv1.CertificateSigningRequest {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	request := pemWithTemplate(&x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "system:node:" + name, Organization: []string{"system:nodes"}},
+	}, key)
+	return &certv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Spec: certv1.CertificateSigningRequestSpec{
+			Request:    request,
+			SignerName: certv1.KubeAPIServerClientKubeletSignerName,
+			Usages:     kubeletClientUsages,
+			Username:   bootstrapUserPrefix + tokenID,
+			Groups:     append([]string{"system:bootstrappers"}, groups...),
+		},
+	}
+}
+
+func TestJoinQuotaApproval(t *testing.T) {
+	now := time.Now()
+	factory := testutil.NewFakeClientFactory(
+		bootstrapToken("quota1", map[string]string{token.MaxJoinsKey: "1"}),
+		bootstrapToken("noquot", nil),
+		bootstrapToken("autoap", map[string]string{"auth-extra-groups": token.AutoApproveGroup}),
+		kubeletClientCSR(t, "worker-1", "quota1", nil, now.Add(-time.Minute)),
+		kubeletClientCSR(t, "worker-2", "quota1", nil, now),
+		kubeletClientCSR(t, "worker-3", "noquot", nil, now),
+		kubeletClientCSR(t, "worker-4", "autoap", []string{token.AutoApproveGroup}, now),
+	)
+	q := NewJoinQuota(&DummyLeaderElector{Leader: true}, factory)
+	require.NoError(t, q.Init())
+	require.NoError(t, q.enforce())
+
+	ctx := context.TODO()
+	conditions := map[string][]certv1.RequestConditionType{}
+	csrs, err := factory.Client.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	for _, csr := range csrs.Items {
+		for _, c := range csr.Status.Conditions {
+			conditions[csr.Name] = append(conditions[csr.Name], c.Type)
+		}
+	}
+	assert.Equal(t, map[string][]certv1.RequestConditionType{
+		// the oldest CSR is within the quota
+		"worker-1": {certv1.CertificateApproved},
+		"worker-2": {certv1.CertificateDenied},
+		"worker-3": {certv1.CertificateApproved},
+		// left to the controller manager
+	}, conditions)
+
+	secret, err := factory.Client.CoreV1().Secrets("kube-system").Get(ctx, "bootstrap-token-quota1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, secret.Annotations[token.JoinsAnnotation], "worker-1")
+	assert.Equal(t, "true", secret.Annotations[token.QuotaExhaustedAnnotation])
+	assert.Equal(t, "false", string(secret.Data["usage-bootstrap-authentication"]))
+}
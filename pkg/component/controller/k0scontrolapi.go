@@ -47,10 +47,12 @@ func (m *K0SControlAPI) Run() error {
 		return err
 	}
 	m.supervisor = supervisor.Supervisor{
-		Name:    "k0s-control-api",
-		BinPath: selfExe,
-		RunDir:  m.K0sVars.RunDir,
-		DataDir: m.K0sVars.DataDir,
+		Name:     "k0s-control-api",
+		BinPath:  selfExe,
+		RunDir:   m.K0sVars.RunDir,
+		DataDir:  m.K0sVars.DataDir,
+		CrashDir: m.K0sVars.CrashDir,
+		ArgsDir:  m.K0sVars.ComponentArgsDir,
 		Args: []string{
 			"api",
 			fmt.Sprintf("--config=%s", m.ConfigPath),
@@ -17,7 +17,6 @@ package controller
 
 import (
 	"fmt"
-	"net/url"
 	"os"
 	"path/filepath"
 
@@ -26,6 +25,7 @@ import (
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/kine"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
@@ -36,6 +36,7 @@ type Kine struct {
 	Config     *config.KineConfig
 	gid        int
 	K0sVars    constant.CfgVars
+	driver     kine.Driver
 	supervisor supervisor.Supervisor
 	uid        int
 }
@@ -57,23 +58,12 @@ func (k *Kine) Init() error {
 		logrus.Warningf("failed to chown %s", kineSocketDir)
 	}
 
-	dsURL, err := url.Parse(k.Config.DataSource)
+	k.driver, err = kine.Lookup(k.Config.DataSource)
 	if err != nil {
 		return err
 	}
-	if dsURL.Scheme == "sqlite" {
-		// Make sure the db basedir exists
-		err = util.InitDirectory(filepath.Dir(dsURL.Path), constant.KineDBDirMode)
-		if err != nil {
-			return fmt.Errorf("failed to create dir %s: %w", filepath.Dir(dsURL.Path), err)
-		}
-		err = os.Chown(filepath.Dir(dsURL.Path), k.uid, k.gid)
-		if err != nil && os.Geteuid() == 0 {
-			return fmt.Errorf("failed to chown dir %s: %w", filepath.Dir(dsURL.Path), err)
-		}
-		if err := os.Chown(dsURL.Path, k.uid, k.gid); err != nil && os.Geteuid() == 0 {
-			logrus.Warningf("datasource file %s does not exist", dsURL.Path)
-		}
+	if err := k.driver.Prepare(k.Config.DataSource, k.uid, k.gid); err != nil {
+		return fmt.Errorf("failed to prepare the %s datastore: %w", k.driver.Scheme(), err)
 	}
 	return assets.Stage(k.K0sVars.BinDir, "kine", constant.BinDirMode)
 }
@@ -83,13 +73,24 @@ func (k *Kine) Run() error {
 	logrus.Info("Starting kine")
 	logrus.Debugf("datasource: %s", k.Config.DataSource)
 
+	endpoint, err := k.driver.Endpoint(k.Config.DataSource)
+	if err != nil {
+		return fmt.Errorf("failed to get the kine endpoint of the %s datastore: %w", k.driver.Scheme(), err)
+	}
+	binPath := assets.BinPath("kine", k.K0sVars.BinDir)
+	if driver, ok := k.driver.(kine.BinaryDriver); ok {
+		binPath = driver.BinPath(k.K0sVars)
+	}
+
 	k.supervisor = supervisor.Supervisor{
-		Name:    "kine",
-		BinPath: assets.BinPath("kine", k.K0sVars.BinDir),
-		DataDir: k.K0sVars.DataDir,
-		RunDir:  k.K0sVars.RunDir,
+		Name:     "kine",
+		BinPath:  binPath,
+		DataDir:  k.K0sVars.DataDir,
+		CrashDir: k.K0sVars.CrashDir,
+		ArgsDir:  k.K0sVars.ComponentArgsDir,
+		RunDir:   k.K0sVars.RunDir,
 		Args: []string{
-			fmt.Sprintf("--endpoint=%s", k.Config.DataSource),
+			fmt.Sprintf("--endpoint=%s", endpoint),
 			fmt.Sprintf("--listen-address=unix://%s", k.K0sVars.KineSocketPath),
 		},
 		UID: k.uid,
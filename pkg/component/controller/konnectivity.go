@@ -36,6 +36,15 @@ import (
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
+const (
+	// konnectivityServerCountGrace is how long a lower count of the controllers has to last before the
+	// konnectivity servers are restarted with it
+	konnectivityServerCountGrace = 10 * time.Minute
+	// konnectivityKeepalive is the keepalive of the agent connections, a controller gone without closing them is
+	// detected within it
+	konnectivityKeepalive = "30s"
+)
+
 // Konnectivity implements the component interface of konnectivity server
 type Konnectivity struct {
 	ClusterConfig *config.ClusterConfig
@@ -121,6 +130,7 @@ func (k *Konnectivity) defaultArgs() util.MappedArgs {
 		"--v":                       k.LogLevel,
 		"--enable-profiling":        "false",
 		"--server-id":               serverID,
+		"--keepalive-time":          konnectivityKeepalive,
 	}
 }
 
@@ -144,12 +154,14 @@ func (k *Konnectivity) runServer() {
 				args := k.defaultArgs()
 				args["--server-count"] = strconv.Itoa(count)
 				k.supervisor = &supervisor.Supervisor{
-					Name:    "konnectivity",
-					BinPath: assets.BinPath("konnectivity-server", k.K0sVars.BinDir),
-					DataDir: k.K0sVars.DataDir,
-					RunDir:  k.K0sVars.RunDir,
-					Args:    args.ToArgs(),
-					UID:     k.uid,
+					Name:     "konnectivity",
+					BinPath:  assets.BinPath("konnectivity-server", k.K0sVars.BinDir),
+					DataDir:  k.K0sVars.DataDir,
+					CrashDir: k.K0sVars.CrashDir,
+					ArgsDir:  k.K0sVars.ComponentArgsDir,
+					RunDir:   k.K0sVars.RunDir,
+					Args:     args.ToArgs(),
+					UID:      k.uid,
 				}
 				err := k.supervisor.Supervise()
 				if err != nil {
@@ -175,10 +187,20 @@ func (k *Konnectivity) Stop() error {
 }
 
 type konnectivityAgentConfig struct {
-	APIAddress string
-	AgentPort  int64
-	Image      string
-	PullPolicy string
+	Agents      []konnectivityAgent
+	AgentPort   int64
+	Image       string
+	PullPolicy  string
+	HostNetwork bool
+}
+
+// konnectivityAgent is a DaemonSet of agents connecting to the given address. With a public address the nodes
+// labeled with the public audience get agents of their own, which connect to the public address.
+type konnectivityAgent struct {
+	Name          string
+	APIAddress    string
+	Public        bool
+	ExcludePublic bool
 }
 
 func (k *Konnectivity) writeKonnectivityAgent() error {
@@ -188,16 +210,28 @@ func (k *Konnectivity) writeKonnectivityAgent() error {
 		return err
 	}
 
+	api := k.ClusterConfig.Spec.API
+	cfg := konnectivityAgentConfig{
+		Agents:     []konnectivityAgent{{Name: "konnectivity-agent", APIAddress: api.APIAddress()}},
+		AgentPort:  k.ClusterConfig.Spec.Konnectivity.AgentPort,
+		Image:      k.ClusterConfig.Spec.Images.Konnectivity.URI(),
+		PullPolicy: k.ClusterConfig.Spec.Images.DefaultPullPolicy,
+	}
+	// with the connection broker the agents connect through the broker listening on the node loopback
+	if broker := k.ClusterConfig.Spec.ConnectionBroker; broker != nil && broker.Enabled {
+		cfg.Agents[0].APIAddress = "127.0.0.1"
+		cfg.AgentPort = constant.ConnectionBrokerKonnectivityPort
+		cfg.HostNetwork = true
+	} else if api.PublicAddress != "" {
+		cfg.Agents[0].ExcludePublic = true
+		cfg.Agents = append(cfg.Agents, konnectivityAgent{Name: "konnectivity-agent-public", APIAddress: api.PublicAddress, Public: true})
+	}
+
 	tw := util.TemplateWriter{
 		Name:     "konnectivity-agent",
 		Template: konnectivityAgentTemplate,
-		Data: konnectivityAgentConfig{
-			APIAddress: k.ClusterConfig.Spec.API.APIAddress(),
-			AgentPort:  k.ClusterConfig.Spec.Konnectivity.AgentPort,
-			Image:      k.ClusterConfig.Spec.Images.Konnectivity.URI(),
-			PullPolicy: k.ClusterConfig.Spec.Images.DefaultPullPolicy,
-		},
-		Path: filepath.Join(konnectivityDir, "konnectivity-agent.yaml"),
+		Data:     cfg,
+		Path:     filepath.Join(konnectivityDir, "konnectivity-agent.yaml"),
 	}
 	err = tw.Write()
 	if err != nil {
@@ -208,9 +242,11 @@ func (k *Konnectivity) writeKonnectivityAgent() error {
 
 func (k *Konnectivity) runLeaseCounter() {
 
-	logrus.Infof("starting to count controller lease holders every 10 secs")
-	ticker := time.NewTicker(10 * time.Second)
+	interval := reconcileInterval(10 * time.Second)
+	logrus.Infof("starting to count controller lease holders every %s", interval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	tracker := serverCountTracker{grace: konnectivityServerCountGrace}
 	for {
 		select {
 		case <-k.stopCtx.Done():
@@ -221,11 +257,39 @@ func (k *Konnectivity) runLeaseCounter() {
 				logrus.Errorf("failed to count controller leases: %s", err)
 				continue
 			}
-			k.serverCountChan <- count
+			k.serverCountChan <- tracker.observe(count, time.Now())
 		}
 	}
 }
 
+// serverCountTracker smooths the count of the konnectivity servers. The agents connect to as many servers as the
+// count tells, and the servers are restarted whenever it changes. An increase is applied right away, so that the
+// agents connect to the new server as well. A decrease is applied only once it has lasted for the grace period, so
+// that a restarting controller doesn't restart the servers of all the other controllers, twice. Until then the
+// agents just keep trying to reach the missing server in the background, their connections to the other servers
+// keep working. How the agents and the servers share the load between the connections is up to konnectivity.
+type serverCountTracker struct {
+	grace    time.Duration
+	current  int
+	lowSince time.Time
+}
+
+// observe records the counted servers and returns the count to run the servers with
+func (t *serverCountTracker) observe(count int, now time.Time) int {
+	if count >= t.current {
+		t.current = count
+		t.lowSince = time.Time{}
+		return t.current
+	}
+	if t.lowSince.IsZero() {
+		t.lowSince = now
+	} else if now.Sub(t.lowSince) >= t.grace {
+		t.current = count
+		t.lowSince = time.Time{}
+	}
+	return t.current
+}
+
 func (k *Konnectivity) countLeaseHolders() (int, error) {
 	client, err := k.KubeClientFactory.GetClient()
 	if err != nil {
@@ -274,6 +338,7 @@ metadata:
   labels:
     kubernetes.io/cluster-service: "true"
     addonmanager.kubernetes.io/mode: Reconcile
+{{- range .Agents }}
 ---
 apiVersion: apps/v1
 # Alternatively, you can deploy the agents as Deployments. It is not necessary
@@ -282,27 +347,44 @@ kind: DaemonSet
 metadata:
   labels:
     addonmanager.kubernetes.io/mode: Reconcile
-    k8s-app: konnectivity-agent
+    k8s-app: {{ .Name }}
   namespace: kube-system
-  name: konnectivity-agent
+  name: {{ .Name }}
 spec:
   selector:
     matchLabels:
-      k8s-app: konnectivity-agent
+      k8s-app: {{ .Name }}
   template:
     metadata:
       labels:
-        k8s-app: konnectivity-agent
+        k8s-app: {{ .Name }}
     spec:
       nodeSelector:
         kubernetes.io/os: linux
+        {{- if .Public }}
+        ` + config.PublicAudienceLabel + `: public
+        {{- end }}
+      {{- if .ExcludePublic }}
+      affinity:
+        nodeAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            nodeSelectorTerms:
+              - matchExpressions:
+                  - key: ` + config.PublicAudienceLabel + `
+                    operator: NotIn
+                    values: ["public"]
+      {{- end }}
       priorityClassName: system-cluster-critical
       tolerations:
         - key: "CriticalAddonsOnly"
           operator: "Exists"
+      {{- if $.HostNetwork }}
+      hostNetwork: true
+      dnsPolicy: ClusterFirstWithHostNet
+      {{- end }}
       containers:
-        - image: {{ .Image }}
-          imagePullPolicy: {{ .PullPolicy }}
+        - image: {{ $.Image }}
+          imagePullPolicy: {{ $.PullPolicy }}
           name: konnectivity-agent
           command: ["/proxy-agent"]
           args: [
@@ -311,7 +393,10 @@ spec:
                   # Since the konnectivity server runs with hostNetwork=true,
                   # this is the IP address of the master machine.
                   "--proxy-server-host={{ .APIAddress }}",
-                  "--proxy-server-port={{ .AgentPort }}",
+                  "--proxy-server-port={{ $.AgentPort }}",
+                  # reconnect quickly to the servers lost, and notice the servers gone without closing the connections
+                  "--sync-interval-cap=5s",
+                  "--keepalive-time=` + konnectivityKeepalive + `",
                   "--service-account-token-path=/var/run/secrets/tokens/konnectivity-agent-token"
                   ]
           volumeMounts:
@@ -331,6 +416,7 @@ spec:
               - serviceAccountToken:
                   path: konnectivity-agent-token
                   audience: system:konnectivity-server
+{{- end }}
 `
 
 // Healthy is a no-op check
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerCountTracker(t *testing.T) {
+	now := time.Now()
+	tracker := serverCountTracker{grace: 10 * time.Minute}
+
+	assert.Equal(t, 1, tracker.observe(1, now))
+	// a new controller is picked up right away
+	assert.Equal(t, 3, tracker.observe(3, now))
+
+	// a restarting controller doesn't restart the others
+	assert.Equal(t, 3, tracker.observe(2, now.Add(time.Minute)))
+	assert.Equal(t, 3, tracker.observe(2, now.Add(5*time.Minute)))
+	assert.Equal(t, 3, tracker.observe(3, now.Add(6*time.Minute)))
+
+	// a removed controller is dropped once the grace period has passed
+	assert.Equal(t, 3, tracker.observe(2, now.Add(7*time.Minute)))
+	assert.Equal(t, 3, tracker.observe(2, now.Add(16*time.Minute)))
+	assert.Equal(t, 2, tracker.observe(2, now.Add(17*time.Minute)))
+}
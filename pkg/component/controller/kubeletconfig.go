@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"io"
 	"io/ioutil"
+	"os"
 
 	"github.com/imdario/mergo"
 	"github.com/sirupsen/logrus"
@@ -30,7 +32,9 @@ import (
 
 	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/hosts"
 )
 
 // KubeletConfig is the reconciler for generic kubelet configs
@@ -83,10 +87,10 @@ func (k *KubeletConfig) run(dnsAddress string) (*bytes.Buffer, error) {
 	manifest := bytes.NewBuffer([]byte{})
 	defaultProfile := getDefaultProfile(dnsAddress, k.clusterSpec.Network.DualStack.Enabled)
 	winDefaultProfile := getDefaultProfile(dnsAddress, k.clusterSpec.Network.DualStack.Enabled)
-	if err := k.writeConfigMapWithProfile(manifest, "default", defaultProfile); err != nil {
+	if err := k.writeConfigMapWithProfile(manifest, "default", defaultProfile, seccompProfiles(nil), nil, nil, nil, nil); err != nil {
 		return nil, fmt.Errorf("can't write manifest for default profile config map: %v", err)
 	}
-	if err := k.writeConfigMapWithProfile(manifest, "default-windows", winDefaultProfile); err != nil {
+	if err := k.writeConfigMapWithProfile(manifest, "default-windows", winDefaultProfile, nil, nil, nil, nil, nil); err != nil {
 		return nil, fmt.Errorf("can't write manifest for default profile config map: %v", err)
 	}
 	configMapNames := []string{
@@ -99,10 +103,14 @@ func (k *KubeletConfig) run(dnsAddress string) (*bytes.Buffer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("can't merge profile `%s` with default profile: %v", profile.Name, err)
 		}
-
 		if err := k.writeConfigMapWithProfile(manifest,
 			profile.Name,
-			merged); err != nil {
+			merged,
+			seccompProfiles(profile.Seccomp),
+			profile.ReadinessGate,
+			profile.Registries,
+			profile.NodeLabels,
+			profile.NodeTaints); err != nil {
 			return nil, fmt.Errorf("can't write manifest for profile config map: %v", err)
 		}
 		configMapNames = append(configMapNames, formatProfileName(profile.Name))
@@ -129,20 +137,97 @@ func (k *KubeletConfig) save(data []byte) error {
 
 type unstructuredYamlObject map[string]interface{}
 
-func (k *KubeletConfig) writeConfigMapWithProfile(w io.Writer, name string, profile unstructuredYamlObject) error {
+func (k *KubeletConfig) writeConfigMapWithProfile(w io.Writer, name string, profile unstructuredYamlObject, seccomp map[string]string, readinessGate *config.ReadinessGateSpec, registries *config.RegistriesSpec, nodeLabels map[string]string, nodeTaints []string) error {
 	profileYaml, err := yaml.Marshal(profile)
 	if err != nil {
 		return err
 	}
+	var seccompYaml []byte
+	if len(seccomp) > 0 {
+		seccompYaml, err = yaml.Marshal(seccomp)
+		if err != nil {
+			return err
+		}
+	}
+	var readinessGateYaml []byte
+	if readinessGate != nil {
+		readinessGateYaml, err = yaml.Marshal(readinessGate)
+		if err != nil {
+			return err
+		}
+	}
+	var registriesYaml []byte
+	if registries != nil {
+		registriesYaml, err = yaml.Marshal(registries)
+		if err != nil {
+			return err
+		}
+	}
+	var nodeLabelsYaml []byte
+	if len(nodeLabels) > 0 {
+		nodeLabelsYaml, err = yaml.Marshal(nodeLabels)
+		if err != nil {
+			return err
+		}
+	}
+	var nodeTaintsYaml []byte
+	if len(nodeTaints) > 0 {
+		nodeTaintsYaml, err = yaml.Marshal(nodeTaints)
+		if err != nil {
+			return err
+		}
+	}
+	networkYaml, err := yaml.Marshal(config.NetworkCIDRs{
+		PodCIDRs:     strings.Split(k.clusterSpec.Network.BuildPodCIDR(), ","),
+		ServiceCIDRs: strings.Split(k.clusterSpec.Network.BuildServiceCIDR(k.clusterSpec.API.Address), ","),
+	})
+	if err != nil {
+		return err
+	}
+	var firewallYaml []byte
+	if rules := k.clusterSpec.FirewallRules(false, true); rules != nil {
+		firewallYaml, err = yaml.Marshal(rules)
+		if err != nil {
+			return err
+		}
+	}
+	// the workers trust the CAs of the bundle, it changes while the CA is rotated
+	caBundle, err := ioutil.ReadFile(certificate.CABundlePath(k.k0sVars.CertRootDir))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// the windows nodes run a pause image of their own
+	var pauseImage string
+	if k.clusterSpec.Images != nil && name != "default-windows" {
+		pauseImage = k.clusterSpec.Images.Pause.URI()
+	}
 	tw := util.TemplateWriter{
 		Name:     "kubelet-config",
 		Template: kubeletConfigsManifestTemplate,
 		Data: struct {
-			Name              string
-			KubeletConfigYAML string
+			Name                string
+			KubeletConfigYAML   string
+			SeccompProfilesYAML string
+			ReadinessGateYAML   string
+			RegistriesYAML      string
+			NodeLabelsYAML      string
+			NodeTaintsYAML      string
+			NetworkYAML         string
+			FirewallYAML        string
+			PauseImage          string
+			CABundle            string
 		}{
-			Name:              formatProfileName(name),
-			KubeletConfigYAML: string(profileYaml),
+			Name:                formatProfileName(name),
+			KubeletConfigYAML:   string(profileYaml),
+			SeccompProfilesYAML: string(seccompYaml),
+			ReadinessGateYAML:   string(readinessGateYaml),
+			RegistriesYAML:      string(registriesYaml),
+			NodeLabelsYAML:      string(nodeLabelsYaml),
+			NodeTaintsYAML:      string(nodeTaintsYaml),
+			NetworkYAML:         string(networkYaml),
+			FirewallYAML:        string(firewallYaml),
+			PauseImage:          pauseImage,
+			CABundle:            string(caBundle),
 		},
 	}
 	return tw.WriteToBuffer(w)
@@ -230,6 +315,39 @@ metadata:
 data:
   kubelet: | 
 {{ .KubeletConfigYAML | nindent 4 }}
+{{- if .SeccompProfilesYAML }}
+  seccomp: |
+{{ .SeccompProfilesYAML | nindent 4 }}
+{{- end }}
+{{- if .ReadinessGateYAML }}
+  readinessGate: |
+{{ .ReadinessGateYAML | nindent 4 }}
+{{- end }}
+{{- if .RegistriesYAML }}
+  registries: |
+{{ .RegistriesYAML | nindent 4 }}
+{{- end }}
+{{- if .NodeLabelsYAML }}
+  nodeLabels: |
+{{ .NodeLabelsYAML | nindent 4 }}
+{{- end }}
+{{- if .NodeTaintsYAML }}
+  nodeTaints: |
+{{ .NodeTaintsYAML | nindent 4 }}
+{{- end }}
+  network: |
+{{ .NetworkYAML | nindent 4 }}
+{{- if .FirewallYAML }}
+  firewall: |
+{{ .FirewallYAML | nindent 4 }}
+{{- end }}
+{{- if .PauseImage }}
+  pauseImage: {{ .PauseImage }}
+{{- end }}
+{{- if .CABundle }}
+  caBundle: |
+{{ .CABundle | nindent 4 }}
+{{- end }}
 `
 
 const rbacRoleAndBindingsManifestTemplate = `---
@@ -246,6 +364,11 @@ rules:
     - "{{ . -}}"
 {{ end }}
   verbs: ["get"]
+# the hosts sync of the workers
+- apiGroups: [""]
+  resources: ["configmaps"]
+  resourceNames: ["` + hosts.ConfigMapName + `"]
+  verbs: ["get"]
 ---
 apiVersion: rbac.authorization.k8s.io/v1
 kind: RoleBinding
@@ -102,6 +102,99 @@ func Test_KubeletConfig(t *testing.T) {
 			require.YAMLEq(t, string(defaultWithChangesYYY), profileYYY.Data["kubelet"])
 		})
 	})
+	t.Run("with_seccomp_profiles", func(t *testing.T) {
+		k, err := NewKubeletConfig(config.DefaultClusterConfig(k0sVars).Spec, k0sVars)
+		require.NoError(t, err)
+		k.clusterSpec.WorkerProfiles = append(k.clusterSpec.WorkerProfiles, config.WorkerProfile{
+			Name: "hardened",
+			Seccomp: &config.SeccompSpec{
+				Profiles: map[string]string{"audit.json": `{"defaultAction": "SCMP_ACT_LOG"}`},
+			},
+		})
+		buf, err := k.run(dnsAddr)
+		require.NoError(t, err)
+		manifestYamls := strings.Split(strings.TrimSuffix(buf.String(), "---"), "---")[1:]
+
+		hardened := struct {
+			Data map[string]string `yaml:"data"`
+		}{}
+		require.NoError(t, yaml.Unmarshal([]byte(manifestYamls[2]), &hardened))
+
+		profiles := map[string]string{}
+		require.NoError(t, yaml.Unmarshal([]byte(hardened.Data["seccomp"]), &profiles))
+		require.Equal(t, defaultSeccompProfile, profiles[defaultSeccompProfileName])
+		require.Equal(t, `{"defaultAction": "SCMP_ACT_LOG"}`, profiles["audit.json"])
+	})
+	t.Run("with_readiness_gate", func(t *testing.T) {
+		k, err := NewKubeletConfig(config.DefaultClusterConfig(k0sVars).Spec, k0sVars)
+		require.NoError(t, err)
+		k.clusterSpec.WorkerProfiles = append(k.clusterSpec.WorkerProfiles, config.WorkerProfile{
+			Name:          "gated",
+			ReadinessGate: &config.ReadinessGateSpec{Checks: []string{config.ReadinessCheckCRI}},
+			Registries:    &config.RegistriesSpec{Mirrors: map[string][]string{"docker.io": {"https://mirror.local:5000"}}},
+		})
+		buf, err := k.run(dnsAddr)
+		require.NoError(t, err)
+		manifestYamls := strings.Split(strings.TrimSuffix(buf.String(), "---"), "---")[1:]
+
+		gated := struct {
+			Data map[string]string `yaml:"data"`
+		}{}
+		require.NoError(t, yaml.Unmarshal([]byte(manifestYamls[2]), &gated))
+		gate := config.ReadinessGateSpec{}
+		require.NoError(t, yaml.Unmarshal([]byte(gated.Data["readinessGate"]), &gate))
+		require.Equal(t, []string{"cri"}, gate.Checks)
+		registries := config.RegistriesSpec{}
+		require.NoError(t, yaml.Unmarshal([]byte(gated.Data["registries"]), &registries))
+		require.Equal(t, []string{"https://mirror.local:5000"}, registries.Mirrors["docker.io"])
+
+		// the default profile has no readiness gate
+		defaultProfile := struct {
+			Data map[string]string `yaml:"data"`
+		}{}
+		require.NoError(t, yaml.Unmarshal([]byte(manifestYamls[0]), &defaultProfile))
+		require.NotContains(t, defaultProfile.Data, "readinessGate")
+		require.NotContains(t, defaultProfile.Data, "registries")
+
+		// every profile publishes the cluster CIDRs for the host network preflight of the workers
+		network := config.NetworkCIDRs{}
+		require.NoError(t, yaml.Unmarshal([]byte(defaultProfile.Data["network"]), &network))
+		require.Equal(t, []string{"10.244.0.0/16"}, network.PodCIDRs)
+		require.Equal(t, []string{"10.96.0.0/12"}, network.ServiceCIDRs)
+
+		// the sandbox image is published for the workers to preload it, the windows nodes use their own
+		require.Equal(t, constant.KubePauseContainerImage+":"+constant.KubePauseContainerImageVersion, defaultProfile.Data["pauseImage"])
+		windowsProfile := struct {
+			Data map[string]string `yaml:"data"`
+		}{}
+		require.NoError(t, yaml.Unmarshal([]byte(manifestYamls[1]), &windowsProfile))
+		require.NotContains(t, windowsProfile.Data, "pauseImage")
+	})
+	t.Run("with_node_labels_and_taints", func(t *testing.T) {
+		k, err := NewKubeletConfig(config.DefaultClusterConfig(k0sVars).Spec, k0sVars)
+		require.NoError(t, err)
+		k.clusterSpec.WorkerProfiles = append(k.clusterSpec.WorkerProfiles, config.WorkerProfile{
+			Name:       "gpu",
+			NodeLabels: map[string]string{"example.com/gpu": "true"},
+			NodeTaints: []string{"example.com/gpu=true:NoSchedule"},
+		})
+		buf, err := k.run(dnsAddr)
+		require.NoError(t, err)
+		manifestYamls := strings.Split(strings.TrimSuffix(buf.String(), "---"), "---")[1:]
+
+		gpu := struct {
+			Data map[string]string `yaml:"data"`
+		}{}
+		require.NoError(t, yaml.Unmarshal([]byte(manifestYamls[2]), &gpu))
+		labels := map[string]string{}
+		require.NoError(t, yaml.Unmarshal([]byte(gpu.Data["nodeLabels"]), &labels))
+		require.Equal(t, map[string]string{"example.com/gpu": "true"}, labels)
+
+		var taints []string
+		require.NoError(t, yaml.Unmarshal([]byte(gpu.Data["nodeTaints"]), &taints))
+		require.Equal(t, []string{"example.com/gpu=true:NoSchedule"}, taints)
+		require.NotContains(t, gpu.Data["kubelet"], "registerWithTaints")
+	})
 }
 
 func defaultConfigWithUserProvidedProfiles(t *testing.T) *KubeletConfig {
@@ -68,7 +68,7 @@ func (k *KubeProxy) Run() error {
 	}
 
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(reconcileInterval(10 * time.Second))
 		defer ticker.Stop()
 		var previousConfig = proxyConfig{}
 		for {
@@ -83,14 +83,7 @@ func (k *KubeProxy) Run() error {
 					k.log.Infof("current cfg matches existing, not gonna do anything")
 					continue
 				}
-				tw := util.TemplateWriter{
-					Name:     "kube-proxy",
-					Template: proxyTemplate,
-					Data:     cfg,
-					Path:     filepath.Join(proxyDir, "kube-proxy.yaml"),
-				}
-				err = tw.Write()
-				if err != nil {
+				if err := k.write(proxyDir, cfg); err != nil {
 					k.log.Errorf("error writing kube-proxy manifests: %s. will retry", err.Error())
 					continue
 				}
@@ -120,6 +113,16 @@ func (k *KubeProxy) removeKubeProxy(manifestDir string) error {
 	return os.RemoveAll(manifestDir)
 }
 
+func (k *KubeProxy) write(proxyDir string, cfg proxyConfig) error {
+	tw := util.TemplateWriter{
+		Name:     "kube-proxy",
+		Template: proxyTemplate,
+		Data:     cfg,
+		Path:     filepath.Join(proxyDir, "kube-proxy.yaml"),
+	}
+	return tw.Write()
+}
+
 func (k *KubeProxy) getConfig() (proxyConfig, error) {
 	cfg := proxyConfig{
 		ClusterCIDR:          k.clusterConf.Spec.Network.BuildPodCIDR(),
@@ -40,6 +40,8 @@ type kubeRouterConfig struct {
 	PeerRouterIPs     string
 	PeerRouterASNs    string
 	PullPolicy        string
+	CNIConfDir        string
+	CNIBinDir         string
 }
 
 // NewKubeRouter creates new KubeRouter reconciler component
@@ -73,6 +75,8 @@ func (c *KubeRouter) Run() error {
 		CNIImage:          c.clusterConf.Spec.Images.KubeRouter.CNI.URI(),
 		CNIInstallerImage: c.clusterConf.Spec.Images.KubeRouter.CNIInstaller.URI(),
 		PullPolicy:        c.clusterConf.Spec.Images.DefaultPullPolicy,
+		CNIConfDir:        c.clusterConf.Spec.Network.CNIConfDir,
+		CNIBinDir:         c.clusterConf.Spec.Network.CNIBinDir,
 	}
 
 	output := bytes.NewBuffer([]byte{})
@@ -201,10 +205,10 @@ spec:
           path: /lib/modules
       - name: cni-conf-dir
         hostPath:
-          path: /etc/cni/net.d
+          path: {{ .CNIConfDir }}
       - name: cni-bin
         hostPath:
-          path: /opt/cni/bin
+          path: {{ .CNIBinDir }}
           type: DirectoryOrCreate
       - name: kube-router-cfg
         configMap:
@@ -25,6 +25,7 @@ func TestKubeRouterConfig(t *testing.T) {
 	cfg.Spec.Network.KubeRouter.MTU = 1450
 	cfg.Spec.Network.KubeRouter.PeerRouterASNs = "12345,67890"
 	cfg.Spec.Network.KubeRouter.PeerRouterIPs = "1.2.3.4,4.3.2.1"
+	cfg.Spec.Network.CNIConfDir = "/var/lib/k0s/cni/net.d"
 
 	saver := inMemorySaver{}
 	kr, err := NewKubeRouter(cfg, saver)
@@ -42,6 +43,14 @@ func TestKubeRouterConfig(t *testing.T) {
 	require.NotNil(t, ds)
 	require.Contains(t, ds.Spec.Template.Spec.Containers[0].Args, "--peer-router-ips=1.2.3.4,4.3.2.1")
 	require.Contains(t, ds.Spec.Template.Spec.Containers[0].Args, "--peer-router-asns=12345,67890")
+	for _, v := range ds.Spec.Template.Spec.Volumes {
+		switch v.Name {
+		case "cni-conf-dir":
+			require.Equal(t, "/var/lib/k0s/cni/net.d", v.HostPath.Path)
+		case "cni-bin":
+			require.Equal(t, "/opt/cni/bin", v.HostPath.Path)
+		}
+	}
 
 	cm, err := findConfig(resources)
 	require.NoError(t, err)
@@ -18,6 +18,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sync"
 	"sync/atomic"
 
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
@@ -44,62 +45,108 @@ type leaderElector struct {
 	stopCh            chan struct{}
 	leaderStatus      atomic.Value
 	kubeClientFactory kubeutil.ClientFactory
+	maintenance       *Maintenance
+	events            *leaderelection.LeaseEvents
+	mu                sync.Mutex
 	leaseCancel       context.CancelFunc
 
 	acquiredLeaseCallbacks []func()
 	lostLeaseCallbacks     []func()
 }
 
-// NewLeaderElector creates new leader elector
-func NewLeaderElector(c *k0sv1beta1.ClusterConfig, kubeClientFactory kubeutil.ClientFactory) LeaderElector {
+// NewLeaderElector creates new leader elector. The controller doesn't take part in the election while in maintenance.
+func NewLeaderElector(c *k0sv1beta1.ClusterConfig, kubeClientFactory kubeutil.ClientFactory, maintenance *Maintenance) LeaderElector {
 	d := atomic.Value{}
 	d.Store(false)
 	return &leaderElector{
 		ClusterConfig:     c,
 		stopCh:            make(chan struct{}),
 		kubeClientFactory: kubeClientFactory,
+		maintenance:       maintenance,
 		L:                 logrus.WithFields(logrus.Fields{"component": "endpointreconciler"}),
 		leaderStatus:      d,
 	}
 }
 
 func (l *leaderElector) Init() error {
+	if l.maintenance != nil {
+		l.maintenance.AddCallback(func(enabled bool) {
+			if enabled {
+				l.L.Info("releasing the leader lease for maintenance")
+				l.stopElection()
+				return
+			}
+			if err := l.startElection(); err != nil {
+				l.L.Errorf("failed to rejoin the leader election after maintenance: %v", err)
+			}
+		})
+	}
 	return nil
 }
 
 func (l *leaderElector) Run() error {
-	client, err := l.kubeClientFactory.GetClient()
-	if err != nil {
-		return fmt.Errorf("can't create kubernetes rest client for lease pool: %v", err)
-	}
-	leasePool, err := leaderelection.NewLeasePool(client, "k0s-endpoint-reconciler", leaderelection.WithLogger(l.L))
-
-	if err != nil {
-		return err
+	// the same channels are used for all the elections, a new lease pool is needed for rejoining after maintenance
+	l.events = &leaderelection.LeaseEvents{
+		AcquiredLease: make(chan struct{}),
+		LostLease:     make(chan struct{}),
 	}
-	events, cancel, err := leasePool.Watch()
-	if err != nil {
-		return err
-	}
-	l.leaseCancel = cancel
 
 	go func() {
 		for {
 			select {
-			case <-events.AcquiredLease:
+			case <-l.events.AcquiredLease:
 				l.L.Info("acquired leader lease")
 				l.leaderStatus.Store(true)
 				runCallbacks(l.acquiredLeaseCallbacks)
-			case <-events.LostLease:
+			case <-l.events.LostLease:
 				l.L.Info("lost leader lease")
 				l.leaderStatus.Store(false)
 				runCallbacks(l.lostLeaseCallbacks)
 			}
 		}
 	}()
+
+	if l.maintenance.Enabled() {
+		l.L.Info("not taking part in the leader election while in maintenance")
+		return nil
+	}
+	return l.startElection()
+}
+
+func (l *leaderElector) startElection() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leaseCancel != nil {
+		return nil
+	}
+
+	client, err := l.kubeClientFactory.GetClient()
+	if err != nil {
+		return fmt.Errorf("can't create kubernetes rest client for lease pool: %v", err)
+	}
+	leasePool, err := leaderelection.NewLeasePool(client, "k0s-endpoint-reconciler", leaderelection.WithLogger(l.L))
+
+	if err != nil {
+		return err
+	}
+	_, cancel, err := leasePool.Watch(leaderelection.WithOutputChannels(l.events))
+	if err != nil {
+		return err
+	}
+	l.leaseCancel = cancel
 	return nil
 }
 
+// stopElection releases the lease, the lost lease callbacks are run once the release is done
+func (l *leaderElector) stopElection() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leaseCancel != nil {
+		l.leaseCancel()
+		l.leaseCancel = nil
+	}
+}
+
 func runCallbacks(callbacks []func()) {
 	for _, fn := range callbacks {
 		if fn != nil {
@@ -117,9 +164,7 @@ func (l *leaderElector) AddLostLeaseCallback(fn func()) {
 }
 
 func (l *leaderElector) Stop() error {
-	if l.leaseCancel != nil {
-		l.leaseCancel()
-	}
+	l.stopElection()
 	return nil
 }
 
@@ -0,0 +1,35 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"time"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// lowPower lengthens the intervals of the reconcile loops of the components, it's set before they're created
+var lowPower *config.LowPowerSpec
+
+// SetLowPower sets the low-power mode of the components created afterwards
+func SetLowPower(spec *config.LowPowerSpec) {
+	lowPower = spec
+}
+
+// reconcileInterval returns the interval of a reconcile loop, lengthened in the low-power mode
+func reconcileInterval(interval time.Duration) time.Duration {
+	return lowPower.Scale(interval)
+}
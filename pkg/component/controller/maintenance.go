@@ -0,0 +1,149 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// Maintenance tracks the maintenance mode of the controller, toggled with `k0s controller maintenance`.
+// While in maintenance the controller drops out of the API endpoints and the leader election of the
+// singleton reconcilers, but keeps running its etcd member.
+type Maintenance struct {
+	K0sVars  constant.CfgVars
+	Interval time.Duration
+
+	log        *logrus.Entry
+	mu         sync.Mutex
+	enabled    bool
+	callbacks  []func(enabled bool)
+	tickerDone chan struct{}
+}
+
+// NewMaintenance creates the maintenance tracker, picking up the current state of the marker
+func NewMaintenance(k0sVars constant.CfgVars) *Maintenance {
+	return &Maintenance{
+		K0sVars:  k0sVars,
+		Interval: 5 * time.Second,
+		log:      logrus.WithField("component", "maintenance"),
+		enabled:  MaintenanceEnabled(k0sVars),
+	}
+}
+
+// MaintenanceEnabled checks if the controller using the given data dir is in maintenance mode
+func MaintenanceEnabled(k0sVars constant.CfgVars) bool {
+	return util.FileExists(k0sVars.MaintenancePath)
+}
+
+// SetMaintenance writes or removes the maintenance marker, the running controller picks the change up within seconds
+func SetMaintenance(k0sVars constant.CfgVars, enabled bool) error {
+	if !enabled {
+		if err := os.Remove(k0sVars.MaintenancePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove the maintenance marker: %w", err)
+		}
+		return nil
+	}
+	if err := ioutil.WriteFile(k0sVars.MaintenancePath, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write the maintenance marker: %w", err)
+	}
+	return nil
+}
+
+// Enabled tells if the controller is in maintenance mode
+func (m *Maintenance) Enabled() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enabled
+}
+
+// AddCallback registers a function called with the new state whenever the maintenance mode is toggled
+func (m *Maintenance) AddCallback(fn func(enabled bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.callbacks = append(m.callbacks, fn)
+}
+
+// Init logs the maintenance mode the controller starts in
+func (m *Maintenance) Init() error {
+	if m.Enabled() {
+		m.log.Warn("controller is in maintenance mode, run `k0s controller maintenance disable` to bring it back")
+	}
+	return nil
+}
+
+// Run starts watching the maintenance marker
+func (m *Maintenance) Run() error {
+	tickerDone := make(chan struct{})
+	m.tickerDone = tickerDone
+
+	go func() {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-tickerDone:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching the maintenance marker
+func (m *Maintenance) Stop() error {
+	if m.tickerDone != nil {
+		close(m.tickerDone)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (m *Maintenance) Healthy() error { return nil }
+
+func (m *Maintenance) check() {
+	enabled := MaintenanceEnabled(m.K0sVars)
+	m.mu.Lock()
+	if enabled == m.enabled {
+		m.mu.Unlock()
+		return
+	}
+	m.enabled = enabled
+	callbacks := append([]func(bool){}, m.callbacks...)
+	m.mu.Unlock()
+
+	if enabled {
+		m.log.Info("entering maintenance mode")
+	} else {
+		m.log.Info("leaving maintenance mode")
+	}
+	for _, fn := range callbacks {
+		fn(enabled)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestMaintenanceToggle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-maintenance")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.GetConfig(dir)
+
+	m := NewMaintenance(k0sVars)
+	assert.False(t, m.Enabled())
+
+	var toggles []bool
+	m.AddCallback(func(enabled bool) { toggles = append(toggles, enabled) })
+
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	m.check()
+	assert.True(t, m.Enabled())
+	// no callbacks without a change of the state
+	m.check()
+
+	require.NoError(t, SetMaintenance(k0sVars, false))
+	// disabling twice is fine
+	require.NoError(t, SetMaintenance(k0sVars, false))
+	m.check()
+	assert.False(t, m.Enabled())
+	assert.Equal(t, []bool{true, false}, toggles)
+
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	assert.True(t, NewMaintenance(k0sVars).Enabled())
+}
+
+func TestDummyLeaderElectorMaintenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-maintenance")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.GetConfig(dir)
+
+	m := NewMaintenance(k0sVars)
+	l := &DummyLeaderElector{Leader: true, Maintenance: m}
+	acquired, lost := 0, 0
+	l.AddAcquiredLeaseCallback(func() { acquired++ })
+	l.AddLostLeaseCallback(func() { lost++ })
+	require.NoError(t, l.Init())
+	require.NoError(t, l.Run())
+	assert.True(t, l.IsLeader())
+	assert.Equal(t, 1, acquired)
+
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	m.check()
+	assert.False(t, l.IsLeader())
+	assert.Equal(t, 1, lost)
+
+	require.NoError(t, SetMaintenance(k0sVars, false))
+	m.check()
+	assert.True(t, l.IsLeader())
+	assert.Equal(t, 2, acquired)
+}
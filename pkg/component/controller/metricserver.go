@@ -245,7 +245,7 @@ func (m *MetricServer) Run() error {
 	}
 
 	go func() {
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(reconcileInterval(10 * time.Second))
 		defer ticker.Stop()
 		var previousConfig = metricsConfig{}
 		for {
@@ -258,14 +258,7 @@ func (m *MetricServer) Run() error {
 				if previousConfig == newConfig {
 					continue
 				}
-				tw := util.TemplateWriter{
-					Name:     "metricServer",
-					Template: metricServerTemplate,
-					Data:     newConfig,
-					Path:     filepath.Join(msDir, "metric_server.yaml"),
-				}
-				err = tw.Write()
-				if err != nil {
+				if err := m.write(msDir, newConfig); err != nil {
 					m.log.Errorf("error writing metric server manifests: %s. will retry", err.Error())
 					continue
 				}
@@ -291,6 +284,16 @@ func (m *MetricServer) Stop() error {
 // Healthy is the health-check interface
 func (m *MetricServer) Healthy() error { return nil }
 
+func (m *MetricServer) write(msDir string, cfg metricsConfig) error {
+	tw := util.TemplateWriter{
+		Name:     "metricServer",
+		Template: metricServerTemplate,
+		Data:     cfg,
+		Path:     filepath.Join(msDir, "metric_server.yaml"),
+	}
+	return tw.Write()
+}
+
 // Mostly for calculating the resource needs based on node numbers. From https://github.com/kubernetes-sigs/metrics-server#scaling :
 // Starting from v0.5.0 Metrics Server comes with default resource requests that should guarantee good performance for most cluster configurations up to 100 nodes:
 // - 100m core of CPU
@@ -311,8 +314,17 @@ func (m *MetricServer) getConfig() (metricsConfig, error) {
 	if err != nil {
 		return cfg, err
 	}
+	return m.configFor(len(nodeList.Items)), nil
+}
+
+// configFor returns the config for the given number of nodes
+func (m *MetricServer) configFor(nodeCount int) metricsConfig {
+	cfg := metricsConfig{
+		Image:      m.clusterConfig.Spec.Images.MetricsServer.URI(),
+		PullPolicy: m.clusterConfig.Spec.Images.DefaultPullPolicy,
+	}
 
-	scale := math.Ceil(float64(len(nodeList.Items)) / 10.0)
+	scale := math.Ceil(float64(nodeCount) / 10.0)
 	if scale < 1 {
 		scale = 1
 	}
@@ -322,5 +334,5 @@ func (m *MetricServer) getConfig() (metricsConfig, error) {
 	cfg.MEMRequest = fmt.Sprintf("%dM", memRequest)
 	cfg.CPURequest = fmt.Sprintf("%dm", cpuRequest)
 
-	return cfg, nil
+	return cfg
 }
@@ -0,0 +1,215 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+// nodeGCDeleted counts the node objects deleted by the node garbage collection
+var nodeGCDeleted = expvar.NewInt("k0s_node_gc_deleted")
+
+const nodeGCInterval = time.Minute
+
+// MachineChecker confirms that the machine of a node is gone for good, so that its node object can be deleted
+type MachineChecker interface {
+	MachineGone(ctx context.Context, node *core.Node) (bool, error)
+}
+
+// NodeGC deletes the node objects that have been unreachable for the configured period, once a MachineChecker
+// confirms that their machines are gone. A node is never deleted only because it's unreachable.
+type NodeGC struct {
+	L *logrus.Entry
+
+	unreachableFor    time.Duration
+	checker           MachineChecker
+	leaderElector     LeaderElector
+	kubeClientFactory k8sutil.ClientFactory
+	clientset         clientset.Interface
+	stopCh            chan struct{}
+	heartbeat         *watchdog.Heartbeat
+}
+
+// NewNodeGC creates the NodeGC component, checking the machines with the configured webhook
+func NewNodeGC(spec *config.NodeGCSpec, leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *NodeGC {
+	return &NodeGC{
+		unreachableFor:    spec.UnreachableForDuration(),
+		checker:           &WebhookMachineChecker{URL: spec.Webhook.URL, Client: &http.Client{Timeout: spec.Webhook.TimeoutDuration()}},
+		leaderElector:     leaderElector,
+		kubeClientFactory: kubeClientFactory,
+		heartbeat:         watchdog.NewHeartbeat("NodeGC", reconcileInterval(nodeGCInterval)),
+		L:                 logrus.WithFields(logrus.Fields{"component": "nodegc"}),
+	}
+}
+
+// Init initializes the kube client
+func (n *NodeGC) Init() error {
+	var err error
+	n.clientset, err = n.kubeClientFactory.GetClient()
+	if err != nil {
+		return fmt.Errorf("can't create kubernetes client for node garbage collection: %w", err)
+	}
+	return nil
+}
+
+// Run checks the unreachable nodes every minute
+func (n *NodeGC) Run() error {
+	stopCh := make(chan struct{})
+	n.stopCh = stopCh
+	n.heartbeat.Start()
+
+	go func() {
+		ticker := time.NewTicker(reconcileInterval(nodeGCInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := n.collect(time.Now()); err != nil {
+					n.L.Warnf("node garbage collection failed: %v", err)
+				}
+				n.heartbeat.Beat()
+			case <-stopCh:
+				n.L.Info("node garbage collector done")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the garbage collection
+func (n *NodeGC) Stop() error {
+	n.heartbeat.Stop()
+	if n.stopCh != nil {
+		close(n.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (n *NodeGC) Healthy() error { return nil }
+
+func (n *NodeGC) collect(now time.Time) error {
+	if !n.leaderElector.IsLeader() {
+		n.L.Debug("not the leader, not collecting nodes")
+		return nil
+	}
+
+	nodes, err := n.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("can't list nodes: %w", err)
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		since, unreachable := unreachableSince(node)
+		if !unreachable || now.Sub(since) < n.unreachableFor {
+			continue
+		}
+
+		gone, err := n.checker.MachineGone(context.TODO(), node)
+		if err != nil {
+			n.L.Warnf("can't check the machine of node %s: %v", node.Name, err)
+			continue
+		}
+		if !gone {
+			n.L.Debugf("node %s is unreachable since %s, but its machine still exists", node.Name, since)
+			continue
+		}
+
+		n.L.Infof("deleting node %s, unreachable since %s and its machine is gone", node.Name, since)
+		err = n.clientset.CoreV1().Nodes().Delete(context.TODO(), node.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			n.L.Warnf("failed to delete node %s: %v", node.Name, err)
+			continue
+		}
+		nodeGCDeleted.Add(1)
+	}
+	return nil
+}
+
+// unreachableSince returns the time since when the node hasn't been ready
+func unreachableSince(node *core.Node) (time.Time, bool) {
+	for _, c := range node.Status.Conditions {
+		if c.Type == core.NodeReady {
+			return c.LastTransitionTime.Time, c.Status != core.ConditionTrue
+		}
+	}
+	return time.Time{}, false
+}
+
+// WebhookMachineChecker asks a webhook whether the machine of a node is gone. The webhook gets a POST with the
+// node name, provider ID and addresses, and answers with {"gone": true} once the machine no longer exists.
+type WebhookMachineChecker struct {
+	URL    string
+	Client *http.Client
+}
+
+type machineCheckRequest struct {
+	NodeName   string             `json:"nodeName"`
+	ProviderID string             `json:"providerID,omitempty"`
+	Addresses  []core.NodeAddress `json:"addresses,omitempty"`
+}
+
+type machineCheckResponse struct {
+	Gone bool `json:"gone"`
+}
+
+// MachineGone calls the webhook for the node
+func (w *WebhookMachineChecker) MachineGone(ctx context.Context, node *core.Node) (bool, error) {
+	body, err := json.Marshal(machineCheckRequest{
+		NodeName:   node.Name,
+		ProviderID: node.Spec.ProviderID,
+		Addresses:  node.Status.Addresses,
+	})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	var result machineCheckResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("invalid webhook response: %w", err)
+	}
+	return result.Gone, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type fakeMachineChecker map[string]bool
+
+func (f fakeMachineChecker) MachineGone(_ context.Context, node *core.Node) (bool, error) {
+	return f[node.Name], nil
+}
+
+func testNode(name string, ready core.ConditionStatus, since time.Time) *core.Node {
+	return &core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: core.NodeStatus{Conditions: []core.NodeCondition{
+			{Type: core.NodeReady, Status: ready, LastTransitionTime: metav1.NewTime(since)},
+		}},
+	}
+}
+
+func TestNodeGCCollect(t *testing.T) {
+	now := time.Now()
+	client := fake.NewSimpleClientset(
+		testNode("ready", core.ConditionTrue, now.Add(-48*time.Hour)),
+		testNode("gone", core.ConditionUnknown, now.Add(-2*time.Hour)),
+		testNode("recently-lost", core.ConditionUnknown, now.Add(-10*time.Minute)),
+		testNode("still-there", core.ConditionFalse, now.Add(-2*time.Hour)),
+	)
+	n := &NodeGC{
+		L:              logrus.WithField("component", "nodegc"),
+		unreachableFor: time.Hour,
+		checker:        fakeMachineChecker{"gone": true, "recently-lost": true, "ready": true},
+		leaderElector:  &DummyLeaderElector{Leader: true},
+		clientset:      client,
+	}
+	require.NoError(t, n.collect(now))
+
+	nodes, err := client.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, node := range nodes.Items {
+		names = append(names, node.Name)
+	}
+	assert.ElementsMatch(t, []string{"ready", "recently-lost", "still-there"}, names)
+}
+
+func TestWebhookMachineChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req machineCheckRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "aws:///eu-west-1a/i-0123", req.ProviderID)
+		_ = json.NewEncoder(w).Encode(machineCheckResponse{Gone: req.NodeName == "gone"})
+	}))
+	defer server.Close()
+
+	checker := &WebhookMachineChecker{URL: server.URL, Client: server.Client()}
+	for name, expected := range map[string]bool{"gone": true, "running": false} {
+		node := &core.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: core.NodeSpec{ProviderID: "aws:///eu-west-1a/i-0123"}}
+		gone, err := checker.MachineGone(context.TODO(), node)
+		require.NoError(t, err)
+		assert.Equal(t, expected, gone, name)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Profiling serves the pprof endpoints of k0s on a unix socket only accessible by root
+type Profiling struct {
+	SocketPath string
+
+	listener net.Listener
+}
+
+// Init removes the socket left behind by a previous run
+func (p *Profiling) Init() error {
+	if err := os.Remove(p.SocketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale profiling socket: %w", err)
+	}
+	return nil
+}
+
+// Run starts serving the pprof endpoints
+func (p *Profiling) Run() error {
+	var err error
+	p.listener, err = net.Listen("unix", p.SocketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on the profiling socket: %w", err)
+	}
+	if err := os.Chmod(p.SocketPath, 0600); err != nil {
+		p.listener.Close()
+		return fmt.Errorf("failed to restrict the profiling socket permissions: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		logrus.Infof("serving the k0s profiling endpoints on %s", p.SocketPath)
+		if err := http.Serve(p.listener, mux); err != nil {
+			logrus.Debugf("profiling server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops serving the pprof endpoints
+func (p *Profiling) Stop() error {
+	if p.listener != nil {
+		return p.listener.Close()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (p *Profiling) Healthy() error { return nil }
@@ -0,0 +1,321 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/build"
+	"github.com/k0sproject/k0s/pkg/constant"
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/remotewrite"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+// remoteWriteBacklog is the amount of unsent collections kept while the endpoint can't be reached
+const remoteWriteBacklog = 12
+
+// RemoteWrite pushes a minimal set of fleet metrics to a Prometheus remote-write endpoint. Every controller pushes
+// its own version, API server readiness and certificate expiry, the leader adds the cluster-wide node and component
+// metrics. The collections failing to be sent are kept and sent along with the next ones, unless the endpoint
+// rejected them with a 4xx status, as it would reject them again.
+type RemoteWrite struct {
+	L *logrus.Entry
+
+	spec              *config.RemoteWriteSpec
+	labels            map[string]string
+	certDir           string
+	leaderElector     LeaderElector
+	kubeClientFactory k8sutil.ClientFactory
+	clientset         clientset.Interface
+	client            *remotewrite.Client
+	pending           [][]remotewrite.Series
+	stopCh            chan struct{}
+	heartbeat         *watchdog.Heartbeat
+}
+
+// NewRemoteWrite creates the RemoteWrite component
+func NewRemoteWrite(clusterConfig *config.ClusterConfig, k0sVars constant.CfgVars, leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *RemoteWrite {
+	spec := clusterConfig.Spec.RemoteWrite
+	labels := map[string]string{"cluster": clusterConfig.ClusterName()}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+	return &RemoteWrite{
+		spec:              spec,
+		labels:            labels,
+		certDir:           k0sVars.CertRootDir,
+		leaderElector:     leaderElector,
+		kubeClientFactory: kubeClientFactory,
+		heartbeat:         watchdog.NewHeartbeat("RemoteWrite", spec.IntervalDuration()),
+		L:                 logrus.WithFields(logrus.Fields{"component": "remotewrite"}),
+	}
+}
+
+// Init initializes the kube client and the remote-write client
+func (r *RemoteWrite) Init() error {
+	var err error
+	r.clientset, err = r.kubeClientFactory.GetClient()
+	if err != nil {
+		return fmt.Errorf("can't create kubernetes client for the remote-write: %w", err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	r.labels["instance"] = hostname
+	r.client = &remotewrite.Client{
+		URL:       r.spec.URL,
+		UserAgent: "k0s/" + build.Version,
+	}
+	return nil
+}
+
+// Run pushes the metrics on start and then every interval
+func (r *RemoteWrite) Run() error {
+	stopCh := make(chan struct{})
+	r.stopCh = stopCh
+	r.heartbeat.Start()
+
+	go func() {
+		ticker := time.NewTicker(r.spec.IntervalDuration())
+		defer ticker.Stop()
+		for {
+			if err := r.push(time.Now()); err != nil {
+				r.L.Warnf("remote-write failed, %d collections pending: %v", len(r.pending), err)
+			}
+			r.heartbeat.Beat()
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				r.L.Info("remote-write done")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops pushing the metrics
+func (r *RemoteWrite) Stop() error {
+	r.heartbeat.Stop()
+	if r.stopCh != nil {
+		close(r.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (r *RemoteWrite) Healthy() error { return nil }
+
+func (r *RemoteWrite) push(now time.Time) error {
+	r.pending = append(r.pending, r.collect(now))
+	if len(r.pending) > remoteWriteBacklog {
+		r.pending = r.pending[len(r.pending)-remoteWriteBacklog:]
+	}
+
+	// the token and the CA are read on every push, so that they can be rotated
+	r.client.BearerToken = ""
+	if r.spec.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(r.spec.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("can't read the bearer token: %w", err)
+		}
+		r.client.BearerToken = strings.TrimSpace(string(token))
+	}
+	httpClient, err := remoteWriteHTTPClient(r.spec.CAFile)
+	if err != nil {
+		return err
+	}
+	r.client.HTTPClient = httpClient
+	defer httpClient.CloseIdleConnections()
+
+	var series []remotewrite.Series
+	for _, collected := range r.pending {
+		series = append(series, collected...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := r.client.Write(ctx, series); err != nil {
+		var statusErr *remotewrite.StatusError
+		if errors.As(err, &statusErr) && !statusErr.Recoverable() {
+			dropped := len(r.pending)
+			r.pending = nil
+			return fmt.Errorf("dropped %d collections: %w", dropped, err)
+		}
+		return err
+	}
+	r.pending = nil
+	return nil
+}
+
+// remoteWriteHTTPClient creates the client verifying the endpoint with the CA of the file, or the ones of the host
+func remoteWriteHTTPClient(caFile string) (*http.Client, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if caFile == "" {
+		return client, nil
+	}
+	caCert, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read the CA of the remote-write endpoint: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	client.Transport = transport
+	return client, nil
+}
+
+// collect gathers the metrics, the ones failing to be collected are left out
+func (r *RemoteWrite) collect(now time.Time) []remotewrite.Series {
+	series := []remotewrite.Series{
+		r.series("k0s_fleet_controller_info", map[string]string{"version": build.Version}, 1, now),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ready := 0.0
+	if _, err := r.clientset.Discovery().RESTClient().Get().AbsPath("/readyz").DoRaw(ctx); err == nil {
+		ready = 1
+	}
+	series = append(series, r.series("k0s_fleet_apiserver_ready", nil, ready, now))
+
+	expiries, err := certExpiries(r.certDir)
+	if err != nil {
+		r.L.Warnf("can't read the certificates: %v", err)
+	}
+	for cert, notAfter := range expiries {
+		series = append(series, r.series("k0s_fleet_cert_expiry_timestamp_seconds", map[string]string{"cert": cert}, float64(notAfter.Unix()), now))
+	}
+
+	if !r.leaderElector.IsLeader() {
+		return series
+	}
+	// the cluster-wide series are sent without the instance, so that they don't move with the leader
+	nodes, err := r.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		r.L.Warnf("can't list nodes: %v", err)
+	} else {
+		series = append(series, r.nodeSeries(nodes.Items, now)...)
+	}
+	statuses, err := r.clientset.CoreV1().ComponentStatuses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		r.L.Warnf("can't list component statuses: %v", err)
+	} else {
+		for _, cs := range statuses.Items {
+			healthy := 0.0
+			for _, c := range cs.Conditions {
+				if c.Type == core.ComponentHealthy && c.Status == core.ConditionTrue {
+					healthy = 1
+				}
+			}
+			series = append(series, r.clusterSeries("k0s_fleet_component_healthy", map[string]string{"component": cs.Name}, healthy, now))
+		}
+	}
+	return series
+}
+
+func (r *RemoteWrite) nodeSeries(nodes []core.Node, now time.Time) []remotewrite.Series {
+	ready := 0
+	versions := map[string]int{}
+	for i := range nodes {
+		for _, c := range nodes[i].Status.Conditions {
+			if c.Type == core.NodeReady && c.Status == core.ConditionTrue {
+				ready++
+			}
+		}
+		versions[nodes[i].Status.NodeInfo.KubeletVersion]++
+	}
+	series := []remotewrite.Series{
+		r.clusterSeries("k0s_fleet_nodes", nil, float64(len(nodes)), now),
+		r.clusterSeries("k0s_fleet_nodes_ready", nil, float64(ready), now),
+	}
+	for version, count := range versions {
+		series = append(series, r.clusterSeries("k0s_fleet_kubelet_version_nodes", map[string]string{"version": version}, float64(count), now))
+	}
+	return series
+}
+
+// series creates a series of this controller
+func (r *RemoteWrite) series(name string, labels map[string]string, value float64, now time.Time) remotewrite.Series {
+	l := map[string]string{}
+	for k, v := range r.labels {
+		l[k] = v
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return remotewrite.NewSeries(name, l, value, now)
+}
+
+// clusterSeries creates a series of the whole cluster
+func (r *RemoteWrite) clusterSeries(name string, labels map[string]string, value float64, now time.Time) remotewrite.Series {
+	s := r.series(name, labels, value, now)
+	delete(s.Labels, "instance")
+	return s
+}
+
+// certExpiries reads the expiry of the certificates in the cert dir, by their path relative to it
+func certExpiries(certDir string) (map[string]time.Time, error) {
+	expiries := map[string]time.Time{}
+	err := filepath.Walk(certDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".crt" {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(certDir, path)
+		if err != nil {
+			return err
+		}
+		expiries[filepath.ToSlash(rel)] = cert.NotAfter
+		return nil
+	})
+	return expiries, err
+}
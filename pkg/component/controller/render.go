@@ -0,0 +1,181 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// RenderedStacks are the dirs of the manifests dir written by the controllers, which RenderManifests replaces
+var RenderedStacks = []string{
+	"bootstraprbac",
+	"calico",
+	"calico_init",
+	"connectionbroker",
+	"coredns",
+	"defaultpsp",
+	"helm",
+	"konnectivity",
+	"kubelet",
+	"kubeproxy",
+	"kuberouter",
+	"metricserver",
+}
+
+// RenderOptions is the state of the cluster the manifests depend on, which isn't part of the config
+type RenderOptions struct {
+	// NodeCount scales CoreDNS and metrics-server the way the controllers do for the nodes of the cluster
+	NodeCount int
+	// SingleNode leaves out the konnectivity agents, as a single node controller does
+	SingleNode bool
+}
+
+// RenderManifests writes the manifests the controllers apply for the config into dir, laid out in stacks the same
+// way as in the manifests dir of the controllers. The stacks of a previous render are replaced, so that the
+// manifests of the disabled components don't linger. Given the same config, options and k0s version, the same
+// manifests are written.
+func RenderManifests(clusterConfig *config.ClusterConfig, k0sVars constant.CfgVars, dir string, opts RenderOptions) error {
+	for _, stack := range RenderedStacks {
+		if err := os.RemoveAll(filepath.Join(dir, stack)); err != nil {
+			return err
+		}
+	}
+	k0sVars.ManifestsDir = dir
+	spec := clusterConfig.Spec
+	log := logrus.WithField("component", "render")
+	stackDir := func(stack string) (string, error) {
+		d := filepath.Join(dir, stack)
+		return d, util.InitDirectory(d, constant.ManifestsDirMode)
+	}
+	saver := func(stack string) (*FsManifestsSaver, error) {
+		d, err := stackDir(stack)
+		return &FsManifestsSaver{dir: d}, err
+	}
+
+	renderers := []struct {
+		name    string
+		enabled bool
+		render  func() error
+	}{
+		{"bootstrap RBAC", true, func() error {
+			return (&SystemRBAC{manifestDir: dir}).Run()
+		}},
+		{"default PSP", !spec.Components.IsDisabled(config.DefaultPSPComponent), func() error {
+			return (&DefaultPSP{clusterSpec: spec, k0sVars: k0sVars}).Run()
+		}},
+		{"kubelet config", true, func() error {
+			return (&KubeletConfig{clusterSpec: spec, k0sVars: k0sVars, log: log}).Run()
+		}},
+		{"kube-proxy", !spec.Network.KubeProxy.Disabled, func() error {
+			k := &KubeProxy{clusterConf: clusterConfig, K0sVars: k0sVars, log: log}
+			cfg, err := k.getConfig()
+			if err != nil {
+				return err
+			}
+			d, err := stackDir("kubeproxy")
+			if err != nil {
+				return err
+			}
+			return k.write(d, cfg)
+		}},
+		{"CoreDNS", !spec.Components.IsDisabled(config.CoreDNSComponent), func() error {
+			c := &CoreDNS{clusterConfig: clusterConfig, K0sVars: k0sVars, log: log}
+			cfg, err := c.configFor(opts.NodeCount)
+			if err != nil {
+				return err
+			}
+			d, err := stackDir("coredns")
+			if err != nil {
+				return err
+			}
+			return c.write(d, cfg)
+		}},
+		{"metrics-server", !spec.Components.IsDisabled(config.MetricsServerComponent), func() error {
+			m := &MetricServer{clusterConfig: clusterConfig, K0sVars: k0sVars, log: log}
+			d, err := stackDir("metricserver")
+			if err != nil {
+				return err
+			}
+			return m.write(d, m.configFor(opts.NodeCount))
+		}},
+		{"calico", spec.Network.Provider == "calico", func() error {
+			crdSaver, err := saver("calico_init")
+			if err != nil {
+				return err
+			}
+			manifestsSaver, err := saver("calico")
+			if err != nil {
+				return err
+			}
+			c := &Calico{clusterConf: clusterConfig, crdSaver: crdSaver, saver: manifestsSaver, log: log}
+			if err := c.writeCRDs(); err != nil {
+				return err
+			}
+			cfg, err := c.getConfig()
+			if err != nil {
+				return err
+			}
+			return c.writeManifests(cfg)
+		}},
+		{"kube-router", spec.Network.Provider == "kuberouter", func() error {
+			s, err := saver("kuberouter")
+			if err != nil {
+				return err
+			}
+			return (&KubeRouter{clusterConf: clusterConfig, saver: s, log: log}).Run()
+		}},
+		{"CRD", true, func() error {
+			s, err := saver("helm")
+			if err != nil {
+				return err
+			}
+			return NewCRD(s, !spec.Components.IsDisabled(config.HelmComponent)).Run()
+		}},
+		{"helm", !spec.Components.IsDisabled(config.HelmComponent), func() error {
+			s, err := saver("helm")
+			if err != nil {
+				return err
+			}
+			if spec.Extensions == nil || spec.Extensions.Helm == nil {
+				return nil
+			}
+			return (&HelmAddons{ClusterConfig: clusterConfig, saver: s, L: log}).writeCharts()
+		}},
+		{"konnectivity agent", !opts.SingleNode && !spec.Components.IsDisabled(config.KonnectivityServerComponent), func() error {
+			return (&Konnectivity{ClusterConfig: clusterConfig, K0sVars: k0sVars}).writeKonnectivityAgent()
+		}},
+		{"connection broker", true, func() error {
+			return (&ConnectionBrokerConfig{ClusterConfig: clusterConfig, K0sVars: k0sVars}).Run()
+		}},
+	}
+	for _, r := range renderers {
+		if !r.enabled {
+			continue
+		}
+		if err := r.render(); err != nil {
+			return fmt.Errorf("failed to render the %s manifests: %w", r.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestRenderManifests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the bundled assets of calico and of helm are left out
+	cfg := v1beta1.DefaultClusterConfig(constant.CfgVars{})
+	cfg.Spec.Network.Calico = nil
+	cfg.Spec.Network.Provider = "kuberouter"
+	cfg.Spec.Network.KubeRouter = v1beta1.DefaultKubeRouter()
+	cfg.Spec.Components.Disabled = []string{v1beta1.HelmComponent}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("kept"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "calico"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "calico", "stale.yaml"), []byte("stale"), 0644))
+
+	render := func(opts RenderOptions) map[string]string {
+		require.NoError(t, RenderManifests(cfg, constant.CfgVars{}, dir, opts))
+		files := map[string]string{}
+		require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			data, err := ioutil.ReadFile(path)
+			rel, _ := filepath.Rel(dir, path)
+			files[rel] = string(data)
+			return err
+		}))
+		return files
+	}
+
+	files := render(RenderOptions{NodeCount: 1})
+	for _, f := range []string{
+		"bootstraprbac/bootstrap-rbac.yaml",
+		"coredns/coredns.yaml",
+		"defaultpsp/default-psp.yaml",
+		"konnectivity/konnectivity-agent.yaml",
+		"kubelet/kubelet-config.yaml",
+		"kubeproxy/kube-proxy.yaml",
+		"kuberouter/kube-router.yaml",
+		"metricserver/metric_server.yaml",
+	} {
+		assert.Contains(t, files, f)
+	}
+	assert.Equal(t, "kept", files["README.md"])
+	assert.NotContains(t, files, "calico/stale.yaml", "the stacks of the previous render are replaced")
+	assert.NotContains(t, files, "connectionbroker/connection-broker.yaml")
+
+	assert.Equal(t, files, render(RenderOptions{NodeCount: 1}), "the same config renders the same manifests")
+
+	scaled := render(RenderOptions{NodeCount: 50, SingleNode: true})
+	assert.NotEqual(t, files["coredns/coredns.yaml"], scaled["coredns/coredns.yaml"])
+	assert.NotContains(t, scaled, "konnectivity/konnectivity-agent.yaml")
+}
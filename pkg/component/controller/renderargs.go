@@ -0,0 +1,49 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// RenderArgs renders the flags of the kube control plane components for the config without starting them, keyed by
+// the component. The flags of the components depending on the state of the node, e.g. etcd, kine and konnectivity,
+// are only rendered when they start.
+func RenderArgs(clusterConfig *config.ClusterConfig, k0sVars constant.CfgVars, logLevels map[string]string, enableKonnectivity bool) (map[string][]string, error) {
+	apiServer := &APIServer{
+		ClusterConfig:      clusterConfig,
+		K0sVars:            k0sVars,
+		LogLevel:           logLevels["kube-apiserver"],
+		EnableKonnectivity: enableKonnectivity,
+	}
+	scheduler := &Scheduler{ClusterConfig: clusterConfig, K0sVars: k0sVars, LogLevel: logLevels["kube-scheduler"]}
+	manager := &Manager{ClusterConfig: clusterConfig, K0sVars: k0sVars, LogLevel: logLevels["kube-controller-manager"]}
+
+	rendered := map[string][]string{}
+	for name, render := range map[string]func() ([]string, error){
+		"kube-apiserver":          apiServer.args,
+		"kube-scheduler":          scheduler.args,
+		"kube-controller-manager": manager.args,
+	} {
+		args, err := render()
+		if err != nil {
+			return nil, err
+		}
+		rendered[name] = args
+	}
+	return rendered, nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestRenderArgs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "render-args")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.GetConfig(dir)
+	clusterConfig := v1beta1.DefaultClusterConfig(k0sVars)
+	clusterConfig.Spec.Scheduler.ExtraArgs = map[string]string{"feature-gates": "A=true"}
+
+	rendered, err := RenderArgs(clusterConfig, k0sVars, map[string]string{"kube-apiserver": "2"}, true)
+	require.NoError(t, err)
+	assert.Len(t, rendered, 3)
+	assert.Contains(t, rendered["kube-apiserver"], "--v=2")
+	assert.Contains(t, rendered["kube-scheduler"], "--feature-gates=A=true")
+	assert.NoFileExists(t, k0sVars.DataDir+"/konnectivity.conf", "nothing is written for the rendering")
+
+	clusterConfig.Spec.ControllerManager.ExtraArgs = map[string]string{"cluster-cidr": "10.0.0.0/16"}
+	_, err = RenderArgs(clusterConfig, k0sVars, nil, false)
+	assert.Error(t, err)
+}
@@ -34,6 +34,9 @@ type Scheduler struct {
 	gid           int
 	K0sVars       constant.CfgVars
 	LogLevel      string
+	// LeaderElector keeps the process on standby on the controllers other than the leader, nil runs it everywhere
+	LeaderElector LeaderElector
+	standby       *standby
 	supervisor    supervisor.Supervisor
 	uid           int
 }
@@ -45,12 +48,42 @@ func (a *Scheduler) Init() error {
 	if err != nil {
 		logrus.Warning(fmt.Errorf("running kube-scheduler as root: %w", err))
 	}
+	if a.LeaderElector != nil {
+		a.standby = newStandby("kube-scheduler", a.LeaderElector)
+	}
 	return assets.Stage(a.K0sVars.BinDir, "kube-scheduler", constant.BinDirMode)
 }
 
 // Run runs kube scheduler
 func (a *Scheduler) Run() error {
 	logrus.Info("Starting kube-scheduler")
+	schedulerArgs, err := a.args()
+	if err != nil {
+		return err
+	}
+
+	sv := supervisor.Supervisor{
+		Name:     "kube-scheduler",
+		BinPath:  assets.BinPath("kube-scheduler", a.K0sVars.BinDir),
+		RunDir:   a.K0sVars.RunDir,
+		DataDir:  a.K0sVars.DataDir,
+		CrashDir: a.K0sVars.CrashDir,
+		ArgsDir:  a.K0sVars.ComponentArgsDir,
+		Args:     schedulerArgs,
+		UID:      a.uid,
+		GID:      a.gid,
+	}
+	// TODO We need to dump the config file suited for k0s use
+
+	if a.standby != nil {
+		return a.standby.run(sv)
+	}
+	a.supervisor = sv
+	return a.supervisor.Supervise()
+}
+
+// args renders the flags of kube-scheduler
+func (a *Scheduler) args() ([]string, error) {
 	schedulerAuthConf := filepath.Join(a.K0sVars.CertRootDir, "scheduler.conf")
 	args := map[string]string{
 		"authentication-kubeconfig": schedulerAuthConf,
@@ -58,12 +91,12 @@ func (a *Scheduler) Run() error {
 		"kubeconfig":                schedulerAuthConf,
 		"bind-address":              "127.0.0.1",
 		"leader-elect":              "true",
-		"profiling":                 "false",
+		"profiling":                 a.ClusterConfig.Spec.Profiling.ProfilingArg(),
 		"v":                         a.LogLevel,
 	}
 	for name, value := range a.ClusterConfig.Spec.Scheduler.ExtraArgs {
 		if args[name] != "" && name != "profiling" {
-			return fmt.Errorf("cannot override kube-scheduler flag: %s", name)
+			return nil, fmt.Errorf("cannot override kube-scheduler flag: %s", name)
 		}
 		args[name] = value
 	}
@@ -74,23 +107,14 @@ func (a *Scheduler) Run() error {
 	if a.ClusterConfig.Spec.API.ExternalAddress == "" {
 		schedulerArgs = append(schedulerArgs, "--leader-elect=false")
 	}
-
-	a.supervisor = supervisor.Supervisor{
-		Name:    "kube-scheduler",
-		BinPath: assets.BinPath("kube-scheduler", a.K0sVars.BinDir),
-		RunDir:  a.K0sVars.RunDir,
-		DataDir: a.K0sVars.DataDir,
-		Args:    schedulerArgs,
-		UID:     a.uid,
-		GID:     a.gid,
-	}
-	// TODO We need to dump the config file suited for k0s use
-
-	return a.supervisor.Supervise()
+	return schedulerArgs, nil
 }
 
 // Stop stops Scheduler
 func (a *Scheduler) Stop() error {
+	if a.standby != nil {
+		return a.standby.halt()
+	}
 	return a.supervisor.Stop()
 }
 
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// defaultSeccompProfileName is the file name of the k0s shipped profile, referenced by pods as localhostProfile: k0s/default.json
+const defaultSeccompProfileName = "default.json"
+
+// seccompProfiles returns the seccomp profiles distributed to the workers using the given worker profile
+func seccompProfiles(spec *config.SeccompSpec) map[string]string {
+	profiles := map[string]string{
+		defaultSeccompProfileName: defaultSeccompProfile,
+	}
+	if spec != nil {
+		for name, content := range spec.Profiles {
+			profiles[name] = content
+		}
+	}
+	return profiles
+}
+
+// defaultSeccompProfile approximates the RuntimeDefault profile of containerd with a deny list of the syscalls that
+// profile keeps from unprivileged containers. It isn't an exact match: containerd allow lists the syscalls, grants
+// some of them by the capabilities of the container and filters some arguments, so the syscalls this profile doesn't
+// know about are allowed. It's meant as a base for custom profiles and for hosts without a runtime default.
+const defaultSeccompProfile = `{
+  "defaultAction": "SCMP_ACT_ALLOW",
+  "architectures": ["SCMP_ARCH_X86_64", "SCMP_ARCH_X86", "SCMP_ARCH_X32", "SCMP_ARCH_AARCH64", "SCMP_ARCH_ARM"],
+  "syscalls": [
+    {
+      "names": [
+        "acct", "add_key", "bpf", "clock_adjtime", "clock_settime", "create_module", "delete_module",
+        "finit_module", "get_kernel_syms", "get_mempolicy", "init_module", "ioperm", "iopl", "kcmp",
+        "kexec_file_load", "kexec_load", "keyctl", "lookup_dcookie", "mbind", "mount", "move_pages",
+        "name_to_handle_at", "nfsservctl", "open_by_handle_at", "perf_event_open", "personality",
+        "pivot_root", "process_vm_readv", "process_vm_writev", "ptrace", "query_module", "quotactl",
+        "reboot", "request_key", "set_mempolicy", "setns", "settimeofday", "stime", "swapon", "swapoff",
+        "sysfs", "_sysctl", "umount", "umount2", "unshare", "uselib", "userfaultfd", "ustat", "vm86", "vm86old"
+      ],
+      "action": "SCMP_ACT_ERRNO",
+      "errnoRet": 1
+    }
+  ]
+}
+`
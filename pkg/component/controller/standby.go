@@ -0,0 +1,105 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/supervisor"
+)
+
+// standby runs a control plane process only while the controller holds the leader lease. The binary is staged on
+// every controller, so that the process is started right away when the lease moves over on failover.
+type standby struct {
+	name          string
+	leaderElector LeaderElector
+
+	mu       sync.Mutex
+	template *supervisor.Supervisor
+	current  *supervisor.Supervisor
+}
+
+func newStandby(name string, leaderElector LeaderElector) *standby {
+	s := &standby{name: name, leaderElector: leaderElector}
+	leaderElector.AddAcquiredLeaseCallback(s.promote)
+	leaderElector.AddLostLeaseCallback(s.demote)
+	return s
+}
+
+// run supervises the process if the controller is the leader, otherwise it's started once the lease is acquired.
+// A supervisor can't be started again once stopped, each start gets a copy of the given one.
+func (s *standby) run(sv supervisor.Supervisor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.template = &sv
+	if !s.leaderElector.IsLeader() {
+		logrus.Infof("%s on standby until this controller is the leader", s.name)
+		return nil
+	}
+	return s.start()
+}
+
+// halt stops the process if it's running and keeps it from being started on the next lease
+func (s *standby) halt() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.template = nil
+	return s.stop()
+}
+
+func (s *standby) promote() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.template == nil || s.current != nil {
+		return
+	}
+	logrus.Infof("acquired the leader lease, starting %s", s.name)
+	if err := s.start(); err != nil {
+		logrus.Errorf("failed to start %s: %v", s.name, err)
+	}
+}
+
+func (s *standby) demote() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return
+	}
+	logrus.Infof("lost the leader lease, putting %s on standby", s.name)
+	if err := s.stop(); err != nil {
+		logrus.Errorf("failed to stop %s: %v", s.name, err)
+	}
+}
+
+func (s *standby) start() error {
+	sv := *s.template
+	if err := sv.Supervise(); err != nil {
+		return err
+	}
+	s.current = &sv
+	return nil
+}
+
+func (s *standby) stop() error {
+	if s.current == nil {
+		return nil
+	}
+	sv := s.current
+	s.current = nil
+	return sv.Stop()
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/supervisor"
+)
+
+func TestStandby(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-standby")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// the supervisor expects the dirs to be accessible to the other users
+	require.NoError(t, os.Chmod(dir, 0755))
+	k0sVars := constant.GetConfig(dir)
+
+	// the maintenance moves the lease of the dummy leader elector away and back
+	m := NewMaintenance(k0sVars)
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	m.check()
+	l := &DummyLeaderElector{Leader: true, Maintenance: m}
+	s := newStandby("sleep", l)
+	require.NoError(t, l.Init())
+
+	require.NoError(t, s.run(supervisor.Supervisor{
+		Name:    "sleep",
+		BinPath: "/bin/sh",
+		Args:    []string{"-c", "exec sleep 60"},
+		RunDir:  dir,
+		DataDir: dir,
+	}))
+	assert.Nil(t, s.current, "started without the lease")
+
+	require.NoError(t, SetMaintenance(k0sVars, false))
+	m.check()
+	assert.NotNil(t, s.current, "not started on the lease")
+
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	m.check()
+	assert.Nil(t, s.current, "not stopped on losing the lease")
+
+	require.NoError(t, SetMaintenance(k0sVars, false))
+	m.check()
+	assert.NotNil(t, s.current, "not started again on the lease")
+
+	require.NoError(t, s.halt())
+	assert.Nil(t, s.current)
+	require.NoError(t, SetMaintenance(k0sVars, true))
+	m.check()
+	require.NoError(t, SetMaintenance(k0sVars, false))
+	m.check()
+	assert.Nil(t, s.current, "started after the halt")
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	certificates "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+
+	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"github.com/k0sproject/k0s/pkg/token"
+	"github.com/k0sproject/k0s/pkg/watchdog"
+)
+
+// TokenUsage consumes the worker tokens with a usage limit. The nodes that joined with a token are recorded on the
+// token once they've registered, and the token is deleted once it has been used up, so that a leaked token can't be
+// replayed to join further nodes. The controller tokens are consumed by the k0s API as the controllers join.
+type TokenUsage struct {
+	L *logrus.Entry
+
+	leaderElector     LeaderElector
+	kubeClientFactory k8sutil.ClientFactory
+	clientset         clientset.Interface
+	tokens            *token.Manager
+	stopCh            chan struct{}
+	heartbeat         *watchdog.Heartbeat
+}
+
+// NewTokenUsage creates the TokenUsage component
+func NewTokenUsage(leaderElector LeaderElector, kubeClientFactory k8sutil.ClientFactory) *TokenUsage {
+	return &TokenUsage{
+		leaderElector:     leaderElector,
+		kubeClientFactory: kubeClientFactory,
+		heartbeat:         watchdog.NewHeartbeat("TokenUsage", reconcileInterval(5*time.Second)),
+		L:                 logrus.WithFields(logrus.Fields{"component": "tokenusage"}),
+	}
+}
+
+// Init initializes the kube client
+func (u *TokenUsage) Init() error {
+	var err error
+	u.clientset, err = u.kubeClientFactory.GetClient()
+	if err != nil {
+		return fmt.Errorf("can't create kubernetes client for token usage checks: %w", err)
+	}
+	u.tokens = token.NewManagerForClient(u.clientset)
+	return nil
+}
+
+// Run checks the nodes joined with the tokens every five seconds
+func (u *TokenUsage) Run() error {
+	stopCh := make(chan struct{})
+	u.stopCh = stopCh
+	u.heartbeat.Start()
+
+	go func() {
+		ticker := time.NewTicker(reconcileInterval(5 * time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := u.consume(); err != nil {
+					u.L.Warnf("token usage check failed: %v", err)
+				}
+				u.heartbeat.Beat()
+			case <-stopCh:
+				u.L.Info("token usage checks done")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the checks
+func (u *TokenUsage) Stop() error {
+	u.heartbeat.Stop()
+	if u.stopCh != nil {
+		close(u.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (u *TokenUsage) Healthy() error { return nil }
+
+func (u *TokenUsage) consume() error {
+	if !u.leaderElector.IsLeader() {
+		u.L.Debug("not the leader, not checking token usage")
+		return nil
+	}
+
+	secrets, err := u.clientset.CoreV1().Secrets("kube-system").List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "type=bootstrap.kubernetes.io/token",
+	})
+	if err != nil {
+		return fmt.Errorf("can't list bootstrap tokens: %w", err)
+	}
+	limited := map[string]token.Uses{}
+	for _, secret := range secrets.Items {
+		if _, found := secret.Data[token.UsageLimitKey]; !found || string(secret.Data["usage-controller-join"]) == "true" {
+			continue
+		}
+		uses, err := token.UsesFromAnnotation(secret.Annotations)
+		if err != nil {
+			u.L.Warnf("ignoring the recorded uses of token %s: %v", secret.Data["token-id"], err)
+		}
+		limited[string(secret.Data["token-id"])] = uses
+	}
+	if len(limited) == 0 {
+		return nil
+	}
+
+	csrs, err := u.clientset.CertificatesV1().CertificateSigningRequests().List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "spec.signerName=" + certificates.KubeAPIServerClientKubeletSignerName,
+	})
+	if err != nil {
+		return fmt.Errorf("can't fetch CSRs: %w", err)
+	}
+	for i := range csrs.Items {
+		csr := &csrs.Items[i]
+		tokenID := strings.TrimPrefix(csr.Spec.Username, bootstrapUserPrefix)
+		uses, found := limited[tokenID]
+		if !found || !strings.HasPrefix(csr.Spec.Username, bootstrapUserPrefix) || len(csr.Status.Certificate) == 0 {
+			continue
+		}
+		node, err := u.joinedNode(csr)
+		if err != nil {
+			u.L.Warnf("can't tell the node of csr %s: %v", csr.Name, err)
+			continue
+		}
+		if node == "" || !uses.Add(node) {
+			continue
+		}
+		if err := u.tokens.RecordUse(tokenID, node); err != nil {
+			u.L.Warnf("failed to record the use of token %s by %s: %v", tokenID, node, err)
+			continue
+		}
+		limited[tokenID] = uses
+	}
+	return nil
+}
+
+// joinedNode returns the node the kubelet client CSR was issued for, once the node has registered
+func (u *TokenUsage) joinedNode(csr *certificates.CertificateSigningRequest) (string, error) {
+	x509cr, err := parseCSR(csr)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimPrefix(x509cr.Subject.CommonName, "system:node:")
+	_, err = u.clientset.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	certificates "k8s.io/api/certificates/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+func nodeCSR(t *testing.T, name string, tokenID string, node string) *certificates.CertificateSigningRequest {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return &certificates.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: certificates.CertificateSigningRequestSpec{
+			Request:    pemWithTemplate(&x509.CertificateRequest{Subject: pkix.Name{CommonName: "system:node:" + node, Organization: []string{"system:nodes"}}}, key),
+			SignerName: certificates.KubeAPIServerClientKubeletSignerName,
+			Username:   bootstrapUserPrefix + tokenID,
+		},
+		Status: certificates.CertificateSigningRequestStatus{Certificate: []byte("issued")},
+	}
+}
+
+func TestTokenUsageConsume(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&core.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-abcdef", Namespace: "kube-system"},
+			Type:       core.SecretTypeBootstrapToken,
+			Data:       map[string][]byte{"token-id": []byte("abcdef"), token.UsageLimitKey: []byte("2")},
+		},
+		nodeCSR(t, "csr-1", "abcdef", "worker-1"),
+		nodeCSR(t, "csr-2", "abcdef", "worker-2"),
+		&core.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+	)
+	u := &TokenUsage{
+		L:             logrus.WithField("component", "tokenusage"),
+		leaderElector: &DummyLeaderElector{Leader: true},
+		clientset:     client,
+		tokens:        token.NewManagerForClient(client),
+	}
+	secrets := client.CoreV1().Secrets("kube-system")
+
+	// worker-2 hasn't registered yet
+	require.NoError(t, u.consume())
+	secret, err := secrets.Get(context.TODO(), "bootstrap-token-abcdef", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `["worker-1"]`, secret.Annotations[token.UsesAnnotation])
+
+	_, err = client.CoreV1().Nodes().Create(context.TODO(), &core.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-2"}}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, u.consume())
+	_, err = secrets.Get(context.TODO(), "bootstrap-token-abcdef", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err), "the used up token should be deleted")
+}
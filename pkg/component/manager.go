@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/k0sproject/k0s/pkg/performance"
+	"github.com/k0sproject/k0s/pkg/status"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 )
@@ -30,6 +31,16 @@ import (
 type Manager struct {
 	components []Component
 	sync       map[string]struct{}
+	// deps are the components that need to be healthy before the component is started
+	deps map[Component][]Component
+	// History records component health transitions, if set
+	History *status.History
+}
+
+// Named is implemented by the components which aren't named after their type, such as the plugin components. The
+// name is used in the logs, the status history and by Restart.
+type Named interface {
+	Name() string
 }
 
 // NewManager creates a manager
@@ -37,18 +48,26 @@ func NewManager() *Manager {
 	return &Manager{
 		components: []Component{},
 		sync:       map[string]struct{}{},
+		deps:       map[Component][]Component{},
 	}
 }
 
-// Add adds a component to the manager
+// Add adds a component to the manager, started once all the previously added components are healthy
 func (m *Manager) Add(component Component) {
+	m.AddAfter(component, m.components...)
+}
+
+// AddAfter adds a component to the manager, started once the given components are healthy. The components
+// which don't depend on each other are started in parallel.
+func (m *Manager) AddAfter(component Component, dependencies ...Component) {
+	m.deps[component] = append([]Component{}, dependencies...)
 	m.components = append(m.components, component)
 }
 
 // AddSync adds a component to the manager that should be initialized synchronously
 func (m *Manager) AddSync(component Component) {
-	m.components = append(m.components, component)
-	compName := reflect.TypeOf(component).Elem().Name()
+	m.Add(component)
+	compName := componentName(component)
 	m.sync[compName] = struct{}{}
 }
 
@@ -57,7 +76,7 @@ func (m *Manager) Init() error {
 	var g errgroup.Group
 
 	for _, comp := range m.components {
-		compName := reflect.TypeOf(comp).Elem().Name()
+		compName := componentName(comp)
 		logrus.Infof("initializing %v\n", compName)
 		c := comp
 		if _, found := m.sync[compName]; found {
@@ -73,41 +92,119 @@ func (m *Manager) Init() error {
 	return err
 }
 
-// Start starts all managed components
+// Start starts all managed components, each one as soon as its dependencies are healthy
 func (m *Manager) Start(ctx context.Context) error {
-
 	perfTimer := performance.NewTimer("component-start").Buffer().Start()
+	healthy := make(map[Component]chan struct{}, len(m.components))
 	for _, comp := range m.components {
-		compName := reflect.TypeOf(comp).Elem().Name()
-		perfTimer.Checkpoint(fmt.Sprintf("running-%s", compName))
-		logrus.Infof("starting %v", compName)
+		healthy[comp] = make(chan struct{})
+	}
 
-		if err := comp.Run(); err != nil {
-			return err
-		}
-		perfTimer.Checkpoint(fmt.Sprintf("running-%s-done", compName))
-		if err := waitForHealthy(ctx, comp, compName); err != nil {
-			return err
-		}
+	g, ctx := errgroup.WithContext(ctx)
+	for _, comp := range m.components {
+		comp := comp
+		g.Go(func() error {
+			compName := componentName(comp)
+			for _, dep := range m.deps[comp] {
+				depHealthy, found := healthy[dep]
+				if !found {
+					return fmt.Errorf("%s depends on %s, which is not managed", compName, componentName(dep))
+				}
+				select {
+				case <-depHealthy:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			perfTimer.Checkpoint(fmt.Sprintf("running-%s", compName))
+			logrus.Infof("starting %v", compName)
+			if err := comp.Run(); err != nil {
+				return err
+			}
+			perfTimer.Checkpoint(fmt.Sprintf("running-%s-done", compName))
+			if err := waitForHealthy(ctx, comp, compName); err != nil {
+				m.record(compName, status.EventUnhealthy, err.Error())
+				return err
+			}
+			m.record(compName, status.EventHealthy, "")
+			close(healthy[comp])
+			return nil
+		})
 	}
+	err := g.Wait()
 	perfTimer.Output()
-	return nil
+	return err
 }
 
-// Stop stops all managed components
+// Stop stops all managed components in the reverse order they were added, which stops the dependents of a
+// component before the component itself
 func (m *Manager) Stop() error {
 	var ret error = nil
 	for i := len(m.components) - 1; i >= 0; i-- {
+		compName := componentName(m.components[i])
 		if err := m.components[i].Stop(); err != nil {
 			logrus.Errorf("failed to stop component: %s", err.Error())
 			if ret == nil {
 				ret = fmt.Errorf("failed to stop components")
 			}
+			continue
 		}
+		m.record(compName, status.EventStopped, "")
 	}
 	return ret
 }
 
+// Restart stops and runs again the named component
+func (m *Manager) Restart(name string) error {
+	return m.RestartWith(name, nil)
+}
+
+// RestartWith restarts the named component like Restart, reconfigure is called with the component once it's stopped
+func (m *Manager) RestartWith(name string, reconfigure func(Component)) error {
+	for _, comp := range m.components {
+		if componentName(comp) != name {
+			continue
+		}
+		if err := comp.Stop(); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", name, err)
+		}
+		m.record(name, status.EventStopped, "restarting")
+		if reconfigure != nil {
+			reconfigure(comp)
+		}
+		if err := comp.Run(); err != nil {
+			return fmt.Errorf("failed to run %s: %w", name, err)
+		}
+		m.record(name, status.EventStarted, "")
+		return nil
+	}
+	return fmt.Errorf("component %s is not managed", name)
+}
+
+// WaitForHealthy waits until the named component is healthy, for two minutes at most
+func (m *Manager) WaitForHealthy(name string) error {
+	for _, comp := range m.components {
+		if componentName(comp) == name {
+			return waitForHealthy(context.Background(), comp, name)
+		}
+	}
+	return fmt.Errorf("component %s is not managed", name)
+}
+
+func componentName(comp Component) string {
+	if named, ok := comp.(Named); ok {
+		return named.Name()
+	}
+	return reflect.TypeOf(comp).Elem().Name()
+}
+
+func (m *Manager) record(compName, eventType, message string) {
+	if m.History != nil {
+		m.History.Record(compName, eventType, message)
+	}
+}
+
 // waitForHealthy waits until the component is healthy and returns true upon success. If a timeout occurs, it returns false
 func waitForHealthy(ctx context.Context, comp Component, name string) error {
 	ctx, cancelFunction := context.WithTimeout(ctx, 2*time.Minute)
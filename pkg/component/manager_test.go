@@ -0,0 +1,203 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package component
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeComponent struct {
+	name    string
+	started chan struct{}
+	// release the health check of the component
+	release chan struct{}
+	runErr  error
+	events  *events
+}
+
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, event)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.list...)
+}
+
+func newFake(name string, e *events) *fakeComponent {
+	return &fakeComponent{name: name, started: make(chan struct{}), release: make(chan struct{}), events: e}
+}
+
+func (f *fakeComponent) Init() error { return nil }
+func (f *fakeComponent) Run() error {
+	f.events.add("run " + f.name)
+	if f.runErr != nil {
+		return f.runErr
+	}
+	close(f.started)
+	return nil
+}
+func (f *fakeComponent) Stop() error {
+	f.events.add("stop " + f.name)
+	return nil
+}
+func (f *fakeComponent) Healthy() error {
+	select {
+	case <-f.release:
+		return nil
+	default:
+		return errors.New("not yet")
+	}
+}
+
+func waitStarted(t *testing.T, f *fakeComponent) {
+	select {
+	case <-f.started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s was not started", f.name)
+	}
+}
+
+func TestStartFollowsDependencies(t *testing.T) {
+	e := &events{}
+	storage := newFake("storage", e)
+	api := newFake("api", e)
+	scheduler := newFake("scheduler", e)
+	manager := newFake("manager", e)
+
+	m := NewManager()
+	m.AddAfter(storage)
+	m.AddAfter(api, storage)
+	m.AddAfter(scheduler, api)
+	m.AddAfter(manager, api)
+
+	done := make(chan error)
+	go func() { done <- m.Start(context.Background()) }()
+
+	waitStarted(t, storage)
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, []string{"run storage"}, e.get())
+
+	close(storage.release)
+	waitStarted(t, api)
+	close(api.release)
+
+	// the independent components are started without waiting for each other
+	waitStarted(t, scheduler)
+	waitStarted(t, manager)
+	close(scheduler.release)
+	close(manager.release)
+	require.NoError(t, <-done)
+
+	require.NoError(t, m.Stop())
+	assert.Equal(t, []string{"stop manager", "stop scheduler", "stop api", "stop storage"}, e.get()[4:])
+}
+
+func TestAddIsSequential(t *testing.T) {
+	e := &events{}
+	first := newFake("first", e)
+	second := newFake("second", e)
+
+	m := NewManager()
+	m.Add(first)
+	m.Add(second)
+	assert.Equal(t, []Component{first}, m.deps[second])
+
+	close(first.release)
+	close(second.release)
+	require.NoError(t, m.Start(context.Background()))
+	assert.Equal(t, []string{"run first", "run second"}, e.get())
+}
+
+func TestStartFailureStopsDependents(t *testing.T) {
+	e := &events{}
+	storage := newFake("storage", e)
+	storage.runErr = errors.New("no disk")
+	api := newFake("api", e)
+
+	m := NewManager()
+	m.AddAfter(storage)
+	m.AddAfter(api, storage)
+
+	err := m.Start(context.Background())
+	assert.EqualError(t, err, "no disk")
+	assert.Equal(t, []string{"run storage"}, e.get())
+}
+
+func TestUnmanagedDependency(t *testing.T) {
+	e := &events{}
+	api := newFake("api", e)
+
+	m := NewManager()
+	m.AddAfter(api, newFake("storage", e))
+
+	err := m.Start(context.Background())
+	assert.Contains(t, err.Error(), "which is not managed")
+}
+
+type namedFake struct {
+	*fakeComponent
+}
+
+func (n namedFake) Name() string { return "agent-" + n.name }
+
+func TestRestartNamed(t *testing.T) {
+	e := &events{}
+	m := NewManager()
+	m.Add(namedFake{newFake("first", e)})
+	m.Add(namedFake{newFake("second", e)})
+
+	require.NoError(t, m.Restart("agent-second"))
+	assert.Equal(t, []string{"stop second", "run second"}, e.get())
+	assert.EqualError(t, m.Restart("namedFake"), "component namedFake is not managed")
+}
+
+func TestRestartWith(t *testing.T) {
+	e := &events{}
+	m := NewManager()
+	m.Add(newFake("first", e))
+
+	require.NoError(t, m.RestartWith("fakeComponent", func(comp Component) {
+		e.add("reconfigure " + comp.(*fakeComponent).name)
+	}))
+	assert.Equal(t, []string{"stop first", "reconfigure first", "run first"}, e.get())
+}
+
+func TestWaitForHealthy(t *testing.T) {
+	e := &events{}
+	m := NewManager()
+	fake := newFake("first", e)
+	m.Add(namedFake{fake})
+
+	close(fake.release)
+	require.NoError(t, m.WaitForHealthy("agent-first"))
+	assert.EqualError(t, m.WaitForHealthy("namedFake"), "component namedFake is not managed")
+}
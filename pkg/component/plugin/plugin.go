@@ -0,0 +1,131 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package plugin runs the node agents of third parties as components of the k0s worker. An agent registers its
+// factory in the init function of a package, which is compiled into a k0s binary with a blank import:
+//
+//	import _ "example.com/agent/k0splugin"
+//
+// The worker adds the agents after its built-in components, so they're started once the kubelet is healthy and
+// stopped before it. They are health-checked on start and stopped the same as the built-in components, and log with
+// their name as the component. The component manager doesn't restart a failed agent on its own, only an explicit
+// Restart of the manager does, so an agent has to recover from its own failures.
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/component"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// Options are passed to the factories of the agents
+type Options struct {
+	K0sVars constant.CfgVars
+	// Profile is the worker profile of the node
+	Profile string
+	// Labels are the node labels given on the command line, as key=value pairs
+	Labels []string
+	// Log is the logger of the agent
+	Log *logrus.Entry
+}
+
+// Factory creates the component of an agent
+type Factory func(opts Options) (component.Component, error)
+
+var (
+	mu        sync.Mutex
+	factories = map[string]Factory{}
+
+	nameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+)
+
+// Register registers the factory of the named agent. It panics if the name is invalid or already registered, as it's
+// called from the init functions.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !nameRegexp.MatchString(name) {
+		panic(fmt.Sprintf("plugin: invalid agent name %q", name))
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("plugin: nil factory for agent %s", name))
+	}
+	if _, found := factories[name]; found {
+		panic(fmt.Sprintf("plugin: agent %s is registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Names returns the names of the registered agents, sorted
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Components creates the components of the registered agents, except the disabled ones. The components are named
+// plugin-<name> for the component manager.
+func Components(opts Options, disabled []string) ([]component.Component, error) {
+	names := Names()
+	skip := map[string]bool{}
+	for _, name := range disabled {
+		skip[name] = true
+		if i := sort.SearchStrings(names, name); i == len(names) || names[i] != name {
+			logrus.Warnf("can't disable the agent %s, it's not compiled into k0s", name)
+		}
+	}
+	var components []component.Component
+	for _, name := range names {
+		if skip[name] {
+			logrus.Infof("the agent %s is disabled", name)
+			continue
+		}
+		mu.Lock()
+		factory := factories[name]
+		mu.Unlock()
+
+		agentOpts := opts
+		agentOpts.Log = logrus.WithField("component", "plugin-"+name)
+		comp, err := factory(agentOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the agent %s: %w", name, err)
+		}
+		components = append(components, &agent{Component: comp, name: "plugin-" + name})
+	}
+	return components, nil
+}
+
+// agent names the component of an agent for the component manager
+type agent struct {
+	component.Component
+	name string
+}
+
+// Name returns the name of the agent component
+func (a *agent) Name() string {
+	return a.name
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/component"
+)
+
+type fakeAgent struct {
+	opts Options
+}
+
+func (f *fakeAgent) Init() error    { return nil }
+func (f *fakeAgent) Run() error     { return nil }
+func (f *fakeAgent) Stop() error    { return nil }
+func (f *fakeAgent) Healthy() error { return nil }
+
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	factories = map[string]Factory{}
+}
+
+func TestRegister(t *testing.T) {
+	defer reset()
+	factory := func(opts Options) (component.Component, error) { return &fakeAgent{opts}, nil }
+
+	Register("metrics", factory)
+	Register("backup-agent", factory)
+	assert.Equal(t, []string{"backup-agent", "metrics"}, Names())
+
+	assert.Panics(t, func() { Register("metrics", factory) })
+	assert.Panics(t, func() { Register("Metrics", factory) })
+	assert.Panics(t, func() { Register("agent-", factory) })
+	assert.Panics(t, func() { Register("other", nil) })
+}
+
+func TestComponents(t *testing.T) {
+	defer reset()
+	Register("metrics", func(opts Options) (component.Component, error) { return &fakeAgent{opts}, nil })
+	Register("backup-agent", func(opts Options) (component.Component, error) { return &fakeAgent{opts}, nil })
+
+	components, err := Components(Options{Profile: "edge"}, []string{"backup-agent"})
+	require.NoError(t, err)
+	require.Len(t, components, 1)
+
+	named, ok := components[0].(component.Named)
+	require.True(t, ok)
+	assert.Equal(t, "plugin-metrics", named.Name())
+	opts := components[0].(*agent).Component.(*fakeAgent).opts
+	assert.Equal(t, "edge", opts.Profile)
+	assert.Equal(t, "plugin-metrics", opts.Log.Data["component"])
+}
+
+func TestComponentsFactoryError(t *testing.T) {
+	defer reset()
+	Register("metrics", func(opts Options) (component.Component, error) { return nil, errors.New("no socket") })
+
+	_, err := Components(Options{}, nil)
+	assert.EqualError(t, err, "failed to create the agent metrics: no socket")
+}
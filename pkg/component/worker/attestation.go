@@ -0,0 +1,79 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/attestation"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+// AttestJoin attests the node with its TPM and exchanges the attestation token for a join token of the node
+func AttestJoin(encodedToken string, k0sVars constant.CfgVars) (string, error) {
+	joinClient, err := token.JoinClientFromToken(encodedToken)
+	if err != nil {
+		return "", err
+	}
+	tpm, err := attestation.NewTPM(filepath.Join(k0sVars.DataDir, "attestation"))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := tpm.Close(); err != nil {
+			logrus.Warnf("failed to remove the TPM attestation files: %v", err)
+		}
+	}()
+
+	ekPublic, err := tpm.EKPublic()
+	if err != nil {
+		return "", err
+	}
+	akPublic, err := tpm.AKPublic()
+	if err != nil {
+		return "", err
+	}
+	keys := v1beta1.AttestationChallengeRequest{EKPublic: ekPublic, AKPublic: akPublic}
+	challenge, err := joinClient.AttestationChallenge(keys)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := tpm.ActivateCredential(challenge.Credential)
+	if err != nil {
+		return "", err
+	}
+	quote, signature, err := tpm.Quote(challenge.Nonce)
+	if err != nil {
+		return "", err
+	}
+	attested, err := joinClient.Attest(v1beta1.AttestationQuoteRequest{
+		AttestationChallengeRequest: keys,
+		Session:                     challenge.Session,
+		Secret:                      secret,
+		Quote:                       quote,
+		Signature:                   signature,
+	})
+	if err != nil {
+		return "", err
+	}
+	logrus.Info("the node was attested successfully")
+	return attested.Token, nil
+}
@@ -0,0 +1,50 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/mount-utils"
+)
+
+// bindMount bind-mounts source onto the existing target with shared propagation, so the volumes mounted by the kubelet
+// and the CSI drivers are visible on both paths
+func bindMount(source, target string) error {
+	mounts, err := mount.New("").List()
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if m.Path == target {
+			logrus.Infof("%s is already mounted, not bind-mounting %s", target, source)
+			return nil
+		}
+	}
+
+	logrus.Infof("bind-mounting %s to %s", source, target)
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind-mount %s to %s: %w", source, target, err)
+	}
+	if err := syscall.Mount("", target, "", syscall.MS_SHARED|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to make %s a shared mount: %w", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// CheckNonRootPreflight verifies that a worker running as a non-root user has all the capabilities it needs
+func CheckNonRootPreflight(runAsUser string) error {
+	if runAsUser != "" {
+		current, err := user.Current()
+		if err != nil {
+			return fmt.Errorf("failed to get the current user: %w", err)
+		}
+		if current.Username != runAsUser {
+			return fmt.Errorf("worker is configured to run as %q but runs as %q", runAsUser, current.Username)
+		}
+	}
+	if os.Geteuid() == 0 {
+		return nil
+	}
+
+	effective, err := effectiveCapabilities()
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, c := range constant.WorkerCapabilities {
+		if effective&(1<<c.Bit) == 0 {
+			missing = append(missing, c.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("worker runs as non-root user but lacks the capabilities: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// effectiveCapabilities parses the effective capability set of the current process
+func effectiveCapabilities() (uint64, error) {
+	data, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "CapEff:" {
+			return strconv.ParseUint(fields[1], 16, 64)
+		}
+	}
+	return 0, fmt.Errorf("effective capabilities not found in /proc/self/status")
+}
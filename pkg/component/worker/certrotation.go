@@ -0,0 +1,315 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/certificate"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// certRotationInterval is how often the client certificate of the kubelet is checked
+const certRotationInterval = time.Hour
+
+// CertRotation renews the client certificate of the kubelet through the CSR API when the kubelet hasn't renewed it
+// itself, for example because it was down when the renewal was due. The new certificate is swapped in atomically
+// and the kubelet is restarted to use it.
+//
+// It also rolls out the CA bundle published by the controllers while the cluster CA is rotated. The bundle replaces
+// the CA of the worker and of its kubeconfigs, and the certificate is renewed once it's not signed by the CA signing
+// the certificates anymore.
+type CertRotation struct {
+	K0sVars             constant.CfgVars
+	KubeletConfigClient *KubeletConfigClient
+	Profile             string
+	// Restart restarts the named component, the kubelet is restarted with the renewed certificate
+	Restart func(name string) error
+
+	log    *logrus.Entry
+	cancel context.CancelFunc
+}
+
+// Init initializes the logger
+func (r *CertRotation) Init() error {
+	r.log = logrus.WithField("component", "cert-rotation")
+	return nil
+}
+
+// Run checks the certificate right away and then periodically
+func (r *CertRotation) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(certRotationInterval)
+		defer ticker.Stop()
+		for {
+			r.check(ctx, time.Now())
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the checks
+func (r *CertRotation) Stop() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	return nil
+}
+
+// Healthy is a no-op
+func (r *CertRotation) Healthy() error { return nil }
+
+func (r *CertRotation) check(ctx context.Context, now time.Time) {
+	caChanged, err := r.syncCABundle()
+	if err != nil {
+		r.log.Errorf("failed to update the CA bundle: %v", err)
+	}
+	renewed, err := r.rotate(ctx, now)
+	if err != nil {
+		r.log.Errorf("failed to renew the kubelet client certificate: %v", err)
+	}
+	dropped, err := r.dropOutdatedServingCert()
+	if err != nil {
+		r.log.Errorf("failed to check the kubelet serving certificate: %v", err)
+	}
+	if (!caChanged && !renewed && !dropped) || r.Restart == nil {
+		return
+	}
+	r.log.Info("restarting the kubelet")
+	if err := r.Restart("Kubelet"); err != nil {
+		r.log.Errorf("failed to restart the kubelet: %v", err)
+	}
+}
+
+// syncCABundle replaces the CA of the worker and of its kubeconfigs with the CA bundle published by the controllers,
+// returns true if it changed
+func (r *CertRotation) syncCABundle() (bool, error) {
+	// the controllers running a worker manage the CA themselves
+	if r.KubeletConfigClient == nil || util.FileExists(filepath.Join(r.K0sVars.CertRootDir, "ca.key")) {
+		return false, nil
+	}
+	bundle, err := r.KubeletConfigClient.CABundle(r.Profile)
+	if err != nil || bundle == "" {
+		return false, err
+	}
+	caPath := filepath.Join(r.K0sVars.CertRootDir, "ca.crt")
+	if current, err := ioutil.ReadFile(caPath); err == nil && bytes.Equal(current, []byte(bundle)) {
+		return false, nil
+	}
+	if _, err := certificate.ParseCertificatePEM([]byte(bundle)); err != nil {
+		return false, fmt.Errorf("failed to parse the CA bundle: %w", err)
+	}
+	r.log.Info("the CA bundle of the cluster changed, updating the CA of the worker")
+	if err := util.WriteFileAtomic(caPath, []byte(bundle), constant.CertMode); err != nil {
+		return false, err
+	}
+	for _, path := range []string{r.K0sVars.KubeletAuthConfigPath, r.K0sVars.KubeletBootstrapConfigPath} {
+		if !util.FileExists(path) {
+			continue
+		}
+		if err := setKubeconfigCA(path, []byte(bundle)); err != nil {
+			return true, fmt.Errorf("failed to update the CA of %s: %w", path, err)
+		}
+	}
+	return true, nil
+}
+
+// rotate renews the client certificate of the kubelet kubeconfig if it's due for renewal, or if it's not signed by
+// the CA signing the certificates during a CA rotation. Returns true if renewed.
+func (r *CertRotation) rotate(ctx context.Context, now time.Time) (bool, error) {
+	if !util.FileExists(r.K0sVars.KubeletAuthConfigPath) {
+		// not joined yet
+		return false, nil
+	}
+	kubeconfig, err := clientcmd.LoadFromFile(r.K0sVars.KubeletAuthConfigPath)
+	if err != nil {
+		return false, err
+	}
+	authInfo, err := currentAuthInfo(kubeconfig)
+	if err != nil {
+		return false, err
+	}
+	data := authInfo.ClientCertificateData
+	if authInfo.ClientCertificate != "" {
+		if data, err = ioutil.ReadFile(authInfo.ClientCertificate); err != nil {
+			return false, err
+		}
+	}
+	cert, err := certificate.ParseCertificatePEM(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the kubelet client certificate: %w", err)
+	}
+	renewal := certificate.RenewalTime(cert)
+	if now.Before(renewal) && !r.issuedByPreviousCA(cert) {
+		r.log.Debugf("the kubelet client certificate is due for renewal at %s", renewal.Format(time.RFC3339))
+		return false, nil
+	}
+
+	nodeName := strings.TrimPrefix(cert.Subject.CommonName, "system:node:")
+	r.log.Infof("renewing the kubelet client certificate of %s, which expires at %s", nodeName, cert.NotAfter.Format(time.RFC3339))
+	restConfig, err := clientcmd.BuildConfigFromFlags("", r.K0sVars.KubeletAuthConfigPath)
+	if err != nil {
+		return false, err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return false, err
+	}
+	renewed, err := requestNodeCertificate(ctx, client, nodeName)
+	if err != nil {
+		return false, err
+	}
+
+	if authInfo.ClientCertificate != "" {
+		err = swapCertificateFile(authInfo.ClientCertificate, renewed, now)
+	} else {
+		authInfo.ClientCertificateData, authInfo.ClientKeyData = renewed, renewed
+		err = writeKubeconfigAtomic(kubeconfig, r.K0sVars.KubeletAuthConfigPath)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store the renewed certificate: %w", err)
+	}
+	r.log.Info("renewed the kubelet client certificate")
+	return true, nil
+}
+
+// dropOutdatedServingCert removes the serving certificate of the kubelet once it's not signed by the CA signing the
+// certificates, e.g. after the CA rotation is activated. The restarted kubelet requests a new one from the cluster.
+// The self-signed certificates of the kubelets not bootstrapping their serving certificates are left alone.
+// Returns true if removed.
+func (r *CertRotation) dropOutdatedServingCert() (bool, error) {
+	path := filepath.Join(kubeletRootDir(r.K0sVars), kubeletCertDirName, "kubelet-server-current.pem")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	cert, err := certificate.ParseCertificatePEM(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the kubelet serving certificate: %w", err)
+	}
+	if cert.CheckSignatureFrom(cert) == nil {
+		return false, nil
+	}
+	caData, err := ioutil.ReadFile(certificate.CABundlePath(r.K0sVars.CertRootDir))
+	if err != nil {
+		return false, err
+	}
+	ca, err := certificate.ParseCertificatePEM(caData)
+	if err != nil {
+		return false, err
+	}
+	if cert.CheckSignatureFrom(ca) == nil {
+		return false, nil
+	}
+	r.log.Info("the kubelet serving certificate isn't signed by the cluster CA, requesting a new one")
+	return true, os.Remove(path)
+}
+
+// issuedByPreviousCA tells if the CA bundle of the worker holds more than one CA, as it does during a CA rotation, and
+// the certificate isn't signed by the first one, the CA signing the certificates
+func (r *CertRotation) issuedByPreviousCA(cert *x509.Certificate) bool {
+	data, err := ioutil.ReadFile(certificate.CABundlePath(r.K0sVars.CertRootDir))
+	if err != nil || bytes.Count(data, []byte("BEGIN CERTIFICATE")) < 2 {
+		return false
+	}
+	ca, err := certificate.ParseCertificatePEM(data)
+	if err != nil {
+		return false
+	}
+	return cert.CheckSignatureFrom(ca) != nil
+}
+
+func currentAuthInfo(kubeconfig *clientcmdapi.Config) (*clientcmdapi.AuthInfo, error) {
+	ctx, ok := kubeconfig.Contexts[kubeconfig.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("the kubeconfig has no current context")
+	}
+	authInfo, ok := kubeconfig.AuthInfos[ctx.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("the kubeconfig has no user %s", ctx.AuthInfo)
+	}
+	return authInfo, nil
+}
+
+// swapCertificateFile stores the certificate the way the kubelet does: the certificate file is a symlink to the
+// latest of the timestamped certificates, which is swapped with a rename. A regular file is replaced atomically.
+func swapCertificateFile(path string, data []byte, now time.Time) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return util.WriteFileAtomic(path, data, constant.CertSecureMode)
+	}
+	dir := filepath.Dir(path)
+	prefix := strings.TrimSuffix(filepath.Base(path), "-current.pem")
+	target := filepath.Join(dir, fmt.Sprintf("%s-%s.pem", prefix, now.Format("2006-01-02-15-04-05")))
+	if err := ioutil.WriteFile(target, data, constant.CertSecureMode); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// setKubeconfigCA replaces the CA data of the clusters of the kubeconfig
+func setKubeconfigCA(path string, ca []byte) error {
+	kubeconfig, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return err
+	}
+	for _, cluster := range kubeconfig.Clusters {
+		if len(cluster.CertificateAuthorityData) > 0 {
+			cluster.CertificateAuthorityData = ca
+		}
+	}
+	return writeKubeconfigAtomic(kubeconfig, path)
+}
+
+func writeKubeconfigAtomic(kubeconfig *clientcmdapi.Config, path string) error {
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, data, constant.CertSecureMode)
+}
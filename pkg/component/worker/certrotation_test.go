@@ -0,0 +1,137 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/certificate"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestRenewalTime(t *testing.T) {
+	notBefore := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(100 * 24 * time.Hour)}
+	assert.Equal(t, notBefore.Add(90*24*time.Hour), certificate.RenewalTime(cert))
+}
+
+func TestSwapCertificateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	now := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("symlink", func(t *testing.T) {
+		old := filepath.Join(dir, "kubelet-client-2020-07-01-10-00-00.pem")
+		require.NoError(t, ioutil.WriteFile(old, []byte("old"), 0600))
+		current := filepath.Join(dir, "kubelet-client-current.pem")
+		require.NoError(t, os.Symlink(old, current))
+
+		require.NoError(t, swapCertificateFile(current, []byte("renewed"), now))
+		target, err := os.Readlink(current)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "kubelet-client-2021-07-01-10-00-00.pem"), target)
+		data, err := ioutil.ReadFile(current)
+		require.NoError(t, err)
+		assert.Equal(t, "renewed", string(data))
+		assert.FileExists(t, old)
+	})
+
+	t.Run("regular file", func(t *testing.T) {
+		path := filepath.Join(dir, "client.pem")
+		require.NoError(t, ioutil.WriteFile(path, []byte("old"), 0600))
+
+		require.NoError(t, swapCertificateFile(path, []byte("renewed"), now))
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "renewed", string(data))
+		assert.NoFileExists(t, path+".tmp")
+	})
+}
+
+func TestDropOutdatedServingCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.CfgVars{DataDir: dir, CertRootDir: filepath.Join(dir, "pki")}
+	certDir := filepath.Join(dir, "kubelet", "pki")
+	require.NoError(t, os.MkdirAll(certDir, 0700))
+
+	// the previous CA signs the serving certificate, the cluster CA is in the cert dir
+	previous := &certificate.Manager{K0sVars: constant.CfgVars{CertRootDir: filepath.Join(dir, "previous")}}
+	require.NoError(t, os.MkdirAll(previous.K0sVars.CertRootDir, 0700))
+	require.NoError(t, previous.EnsureCA("ca", "kubernetes-ca"))
+	cert, err := previous.EnsureCertificate(certificate.Request{
+		Name:      "kubelet-server",
+		CN:        "system:node:worker",
+		O:         "system:nodes",
+		CACert:    filepath.Join(previous.K0sVars.CertRootDir, "ca.crt"),
+		CAKey:     filepath.Join(previous.K0sVars.CertRootDir, "ca.key"),
+		Hostnames: []string{"worker"},
+	}, "root")
+	require.NoError(t, err)
+	current := &certificate.Manager{K0sVars: k0sVars}
+	require.NoError(t, os.MkdirAll(k0sVars.CertRootDir, 0700))
+	require.NoError(t, current.EnsureCA("ca", "kubernetes-ca"))
+
+	servingCert := filepath.Join(certDir, "kubelet-server-current.pem")
+	r := &CertRotation{K0sVars: k0sVars}
+	require.NoError(t, r.Init())
+	dropped, err := r.dropOutdatedServingCert()
+	require.NoError(t, err)
+	assert.False(t, dropped, "no serving certificate yet")
+
+	require.NoError(t, ioutil.WriteFile(servingCert, []byte(cert.Cert), 0600))
+	require.NoError(t, util.FileCopy(filepath.Join(previous.K0sVars.CertRootDir, "ca.crt"), filepath.Join(k0sVars.CertRootDir, "ca-bundle.crt")))
+	dropped, err = r.dropOutdatedServingCert()
+	require.NoError(t, err)
+	assert.False(t, dropped, "signed by the CA signing the certificates")
+
+	require.NoError(t, os.Remove(filepath.Join(k0sVars.CertRootDir, "ca-bundle.crt")))
+	dropped, err = r.dropOutdatedServingCert()
+	require.NoError(t, err)
+	assert.True(t, dropped)
+	assert.NoFileExists(t, servingCert)
+}
+
+func TestSetKubeconfigCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert-rotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kubelet.conf")
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["k0s"] = &clientcmdapi.Cluster{Server: "https://10.0.0.1:6443", CertificateAuthorityData: []byte("previous")}
+	kubeconfig.Clusters["k0s-1"] = &clientcmdapi.Cluster{Server: "https://10.0.0.2:6443", CertificateAuthority: "/etc/ca.crt"}
+	require.NoError(t, clientcmd.WriteToFile(*kubeconfig, path))
+
+	require.NoError(t, setKubeconfigCA(path, []byte("bundle")))
+	updated, err := clientcmd.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "bundle", string(updated.Clusters["k0s"].CertificateAuthorityData))
+	assert.Empty(t, updated.Clusters["k0s-1"].CertificateAuthorityData)
+	assert.Equal(t, "/etc/ca.crt", updated.Clusters["k0s-1"].CertificateAuthority)
+}
@@ -0,0 +1,498 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+const (
+	regionLabel = "topology.kubernetes.io/region"
+	// discoveredCacheKey keeps the discovered endpoints in the cached config, so that a restarted worker can reach
+	// the controllers which joined after it
+	discoveredCacheKey = "discoveredEndpoints"
+)
+
+// ConnectionBroker forwards the apiserver and konnectivity traffic of the node to the nearest reachable controller
+type ConnectionBroker struct {
+	K0sVars             constant.CfgVars
+	KubeletConfigClient *KubeletConfigClient
+	Labels              []string
+
+	enabled          bool
+	region           string
+	endpoints        []config.ControllerEndpoint
+	discover         bool
+	discovered       []config.ControllerEndpoint
+	apiPort          int
+	konnectivityPort int
+	probeInterval    time.Duration
+	data             map[string]string
+
+	log         *logrus.Entry
+	client      *KubeletConfigClient
+	mu          sync.RWMutex
+	ranked      []string
+	next        int
+	connections map[string]map[net.Conn]struct{}
+	listeners   []net.Listener
+	tickerDone  chan struct{}
+}
+
+type probeResult struct {
+	endpoint  config.ControllerEndpoint
+	reachable bool
+	latency   time.Duration
+}
+
+// Init loads the broker config published by the controllers, falling back to the cached copy
+func (b *ConnectionBroker) Init() error {
+	b.log = logrus.WithField("component", "connection-broker")
+	b.client = b.KubeletConfigClient
+	b.connections = map[string]map[net.Conn]struct{}{}
+
+	cached, _ := b.readCache()
+	data, err := b.KubeletConfigClient.ConnectionBrokerConfig()
+	if err != nil {
+		b.log.Warnf("%v, using the cached connection broker config", err)
+		if cached == nil {
+			return nil
+		}
+		data = cached
+	}
+	if data == nil {
+		if err := b.writeCache(nil); err != nil {
+			b.log.Warnf("failed to remove the cached connection broker config: %v", err)
+		}
+		return nil
+	}
+
+	if err := b.parseConfig(data); err != nil {
+		return err
+	}
+	if b.discover {
+		b.discovered = parseAddresses(cached[discoveredCacheKey])
+		b.discovered = mergeEndpoints(b.discovered, kubeconfigEndpoints(b.K0sVars.KubeletBootstrapConfigPath, b.K0sVars.KubeletAuthConfigPath))
+	}
+	b.data = data
+	if err := b.writeCache(data); err != nil {
+		b.log.Warnf("failed to cache connection broker config: %v", err)
+	}
+	for _, l := range b.Labels {
+		if strings.HasPrefix(l, regionLabel+"=") {
+			b.region = strings.TrimPrefix(l, regionLabel+"=")
+		}
+	}
+	b.enabled = len(b.endpoints) > 0 || len(b.discovered) > 0
+	return nil
+}
+
+func (b *ConnectionBroker) parseConfig(data map[string]string) error {
+	var endpoints []config.ControllerEndpoint
+	if err := yaml.Unmarshal([]byte(data["endpoints"]), &endpoints); err != nil {
+		return fmt.Errorf("failed to parse connection broker endpoints: %w", err)
+	}
+	apiPort, err := strconv.Atoi(data["apiPort"])
+	if err != nil {
+		return fmt.Errorf("invalid connection broker api port: %w", err)
+	}
+	konnectivityPort, err := strconv.Atoi(data["konnectivityPort"])
+	if err != nil {
+		return fmt.Errorf("invalid connection broker konnectivity port: %w", err)
+	}
+	probeInterval, err := time.ParseDuration(data["probeInterval"])
+	if err != nil {
+		return fmt.Errorf("invalid connection broker probe interval: %w", err)
+	}
+	discover := false
+	if data["discoverEndpoints"] != "" {
+		if discover, err = strconv.ParseBool(data["discoverEndpoints"]); err != nil {
+			return fmt.Errorf("invalid connection broker discoverEndpoints: %w", err)
+		}
+	}
+	b.endpoints, b.apiPort, b.konnectivityPort, b.probeInterval = endpoints, apiPort, konnectivityPort, probeInterval
+	b.discover = discover
+	return nil
+}
+
+// refresh updates the endpoints from the config published by the controllers and the discovered controllers, the
+// ports require a restart
+func (b *ConnectionBroker) refresh() {
+	data, err := b.client.ConnectionBrokerConfig()
+	if err == nil && data != nil {
+		var endpoints []config.ControllerEndpoint
+		if err := yaml.Unmarshal([]byte(data["endpoints"]), &endpoints); err == nil && (len(endpoints) > 0 || b.discover) {
+			b.endpoints = endpoints
+			b.data = data
+		}
+	}
+	if b.discover {
+		addresses, err := b.client.ControllerAddresses()
+		if err != nil {
+			b.log.Debugf("failed to discover the controllers: %v", err)
+		} else if len(addresses) > 0 {
+			discovered := make([]config.ControllerEndpoint, 0, len(addresses))
+			for _, a := range addresses {
+				discovered = append(discovered, config.ControllerEndpoint{Address: a})
+			}
+			b.discovered = discovered
+		}
+	}
+	if err := b.writeCache(b.data); err != nil {
+		b.log.Warnf("failed to cache connection broker config: %v", err)
+	}
+}
+
+// candidates are the listed endpoints followed by the discovered ones which aren't listed
+func (b *ConnectionBroker) candidates() []config.ControllerEndpoint {
+	return mergeEndpoints(b.endpoints, b.discovered)
+}
+
+// mergeEndpoints appends the extra endpoints to the endpoints, skipping the addresses already there
+func mergeEndpoints(endpoints, extra []config.ControllerEndpoint) []config.ControllerEndpoint {
+	seen := map[string]bool{}
+	var merged []config.ControllerEndpoint
+	for _, e := range append(append([]config.ControllerEndpoint{}, endpoints...), extra...) {
+		if seen[e.Address] {
+			continue
+		}
+		seen[e.Address] = true
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// parseAddresses reads the comma separated addresses the discovered endpoints are cached as
+func parseAddresses(addresses string) []config.ControllerEndpoint {
+	var endpoints []config.ControllerEndpoint
+	for _, a := range strings.Split(addresses, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			endpoints = append(endpoints, config.ControllerEndpoint{Address: a})
+		}
+	}
+	return endpoints
+}
+
+// kubeconfigEndpoints are the controllers the kubeconfigs point to, before they're pointed to the broker
+func kubeconfigEndpoints(paths ...string) []config.ControllerEndpoint {
+	var endpoints []config.ControllerEndpoint
+	for _, path := range paths {
+		if !util.FileExists(path) {
+			continue
+		}
+		cfg, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			continue
+		}
+		for _, cluster := range cfg.Clusters {
+			u, err := url.Parse(cluster.Server)
+			if err != nil {
+				continue
+			}
+			if host := u.Hostname(); host != "" && host != "localhost" && host != "127.0.0.1" {
+				endpoints = mergeEndpoints(endpoints, []config.ControllerEndpoint{{Address: host}})
+			}
+		}
+	}
+	return endpoints
+}
+
+// Run probes the endpoints, starts the local listeners and points the kubelet kubeconfigs to them
+func (b *ConnectionBroker) Run() error {
+	if !b.enabled {
+		return nil
+	}
+	b.probe()
+
+	for port, upstreamPort := range map[int]int{
+		constant.ConnectionBrokerAPIPort:          b.apiPort,
+		constant.ConnectionBrokerKonnectivityPort: b.konnectivityPort,
+	} {
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			return fmt.Errorf("connection broker failed to listen on port %d: %w", port, err)
+		}
+		b.listeners = append(b.listeners, l)
+		go b.serve(l, upstreamPort)
+	}
+
+	server := fmt.Sprintf("https://localhost:%d", constant.ConnectionBrokerAPIPort)
+	for _, path := range []string{b.K0sVars.KubeletBootstrapConfigPath, b.K0sVars.KubeletAuthConfigPath} {
+		if err := rewriteKubeconfigServer(path, server); err != nil {
+			return err
+		}
+	}
+	// the config and the controllers are fetched through the broker as well, to keep up when a controller dies
+	if client, err := LoadKubeletConfigClient(b.K0sVars); err != nil {
+		b.log.Warnf("failed to create a client through the connection broker: %v", err)
+	} else {
+		b.client = client
+	}
+
+	b.tickerDone = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(b.probeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				b.refresh()
+				b.probe()
+			case <-b.tickerDone:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the probes and closes the listeners
+func (b *ConnectionBroker) Stop() error {
+	if b.tickerDone != nil {
+		close(b.tickerDone)
+	}
+	for _, l := range b.listeners {
+		_ = l.Close()
+	}
+	return nil
+}
+
+// Healthy checks that at least one controller endpoint is reachable
+func (b *ConnectionBroker) Healthy() error {
+	if !b.enabled {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.ranked) == 0 {
+		return fmt.Errorf("no controller endpoint is reachable")
+	}
+	return nil
+}
+
+func (b *ConnectionBroker) probe() {
+	var results []probeResult
+	for _, e := range b.candidates() {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(e.Address, strconv.Itoa(b.apiPort)), 5*time.Second)
+		r := probeResult{endpoint: e, latency: time.Since(start)}
+		if err == nil {
+			r.reachable = true
+			conn.Close()
+		} else {
+			b.log.Debugf("controller endpoint %s is not reachable: %v", e.Address, err)
+		}
+		results = append(results, r)
+	}
+
+	ranked := rankEndpoints(results, b.region)
+	b.mu.Lock()
+	if len(ranked) > 0 && (len(b.ranked) == 0 || b.ranked[0] != ranked[0]) {
+		b.log.Infof("preferring controller endpoint %s", ranked[0])
+	}
+	b.ranked = ranked
+	b.mu.Unlock()
+
+	// the connections to a dead controller may hang until the keepalives time out, closing them makes the clients
+	// reconnect through the broker to a live one right away
+	for _, r := range results {
+		if !r.reachable {
+			b.closeConnections(r.endpoint.Address)
+		}
+	}
+}
+
+func (b *ConnectionBroker) closeConnections(address string) {
+	b.mu.Lock()
+	conns := b.connections[address]
+	delete(b.connections, address)
+	b.mu.Unlock()
+	if len(conns) > 0 {
+		b.log.Infof("controller endpoint %s is not reachable, closing %d connections to it", address, len(conns))
+	}
+	for c := range conns {
+		_ = c.Close()
+	}
+}
+
+func (b *ConnectionBroker) track(address string, conn net.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.connections[address] == nil {
+		b.connections[address] = map[net.Conn]struct{}{}
+	}
+	b.connections[address][conn] = struct{}{}
+}
+
+func (b *ConnectionBroker) untrack(address string, conn net.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.connections[address], conn)
+}
+
+// rankEndpoints orders the reachable endpoints so that the ones in the given region come first, then by latency
+func rankEndpoints(results []probeResult, region string) []string {
+	reachable := []probeResult{}
+	for _, r := range results {
+		if r.reachable {
+			reachable = append(reachable, r)
+		}
+	}
+	sort.SliceStable(reachable, func(i, j int) bool {
+		iLocal := region != "" && reachable[i].endpoint.Region == region
+		jLocal := region != "" && reachable[j].endpoint.Region == region
+		if iLocal != jLocal {
+			return iLocal
+		}
+		return reachable[i].latency < reachable[j].latency
+	})
+	var addresses []string
+	for _, r := range reachable {
+		addresses = append(addresses, r.endpoint.Address)
+	}
+	return addresses
+}
+
+func (b *ConnectionBroker) serve(l net.Listener, upstreamPort int) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go b.forward(conn, upstreamPort)
+	}
+}
+
+// forward connects the client to the best ranked endpoint, failing over to the next ones. The konnectivity
+// connections are spread over all the endpoints instead, the agents need to reach every konnectivity server.
+func (b *ConnectionBroker) forward(conn net.Conn, upstreamPort int) {
+	defer conn.Close()
+
+	b.mu.Lock()
+	candidates := append([]string{}, b.ranked...)
+	if upstreamPort == b.konnectivityPort && len(candidates) > 0 {
+		candidates = rotate(candidates, b.next)
+		b.next++
+	}
+	b.mu.Unlock()
+
+	for _, address := range candidates {
+		upstream, err := net.DialTimeout("tcp", net.JoinHostPort(address, strconv.Itoa(upstreamPort)), 5*time.Second)
+		if err != nil {
+			b.log.Warnf("failed to connect to controller endpoint %s: %v", address, err)
+			continue
+		}
+		b.track(address, upstream)
+		defer b.untrack(address, upstream)
+		defer upstream.Close()
+		done := make(chan struct{}, 2)
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			done <- struct{}{}
+		}()
+		go func() {
+			_, _ = io.Copy(conn, upstream)
+			done <- struct{}{}
+		}()
+		<-done
+		return
+	}
+	b.log.Errorf("no controller endpoint available for port %d", upstreamPort)
+}
+
+// rotate starts the addresses from the nth one, wrapping around
+func rotate(addresses []string, n int) []string {
+	n %= len(addresses)
+	return append(append([]string{}, addresses[n:]...), addresses[:n]...)
+}
+
+func (b *ConnectionBroker) readCache() (map[string]string, error) {
+	content, err := ioutil.ReadFile(b.K0sVars.ConnectionBrokerConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (b *ConnectionBroker) writeCache(data map[string]string) error {
+	if data == nil {
+		if util.FileExists(b.K0sVars.ConnectionBrokerConfigPath) {
+			return os.Remove(b.K0sVars.ConnectionBrokerConfigPath)
+		}
+		return nil
+	}
+	if len(b.discovered) > 0 {
+		cached := map[string]string{}
+		for k, v := range data {
+			cached[k] = v
+		}
+		var addresses []string
+		for _, e := range b.discovered {
+			addresses = append(addresses, e.Address)
+		}
+		cached[discoveredCacheKey] = strings.Join(addresses, ",")
+		data = cached
+	}
+	content, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.K0sVars.ConnectionBrokerConfigPath, content, constant.CertSecureMode)
+}
+
+// rewriteKubeconfigServer points all the clusters of the kubeconfig to the given server
+func rewriteKubeconfigServer(path, server string) error {
+	if !util.FileExists(path) {
+		return nil
+	}
+	cfg, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig %s: %w", path, err)
+	}
+	changed := false
+	for _, cluster := range cfg.Clusters {
+		if cluster.Server != server {
+			cluster.Server = server
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return clientcmd.WriteToFile(*cfg, path)
+}
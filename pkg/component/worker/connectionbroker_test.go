@@ -0,0 +1,99 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestRankEndpoints(t *testing.T) {
+	results := []probeResult{
+		{endpoint: config.ControllerEndpoint{Address: "10.0.0.1", Region: "eu"}, reachable: true, latency: 80 * time.Millisecond},
+		{endpoint: config.ControllerEndpoint{Address: "10.0.0.2", Region: "us"}, reachable: true, latency: 5 * time.Millisecond},
+		{endpoint: config.ControllerEndpoint{Address: "10.0.0.3", Region: "us"}, reachable: false, latency: time.Millisecond},
+		{endpoint: config.ControllerEndpoint{Address: "10.0.0.4", Region: "eu"}, reachable: true, latency: 20 * time.Millisecond},
+	}
+
+	t.Run("same region first", func(t *testing.T) {
+		assert.Equal(t, []string{"10.0.0.4", "10.0.0.1", "10.0.0.2"}, rankEndpoints(results, "eu"))
+	})
+
+	t.Run("latency only without region", func(t *testing.T) {
+		assert.Equal(t, []string{"10.0.0.2", "10.0.0.4", "10.0.0.1"}, rankEndpoints(results, ""))
+	})
+
+	t.Run("nothing reachable", func(t *testing.T) {
+		assert.Empty(t, rankEndpoints(results[2:3], "us"))
+	})
+}
+
+func TestMergeEndpoints(t *testing.T) {
+	listed := []config.ControllerEndpoint{{Address: "10.0.0.1", Region: "eu"}, {Address: "10.0.0.2", Region: "us"}}
+	discovered := []config.ControllerEndpoint{{Address: "10.0.0.2"}, {Address: "10.0.0.3"}}
+
+	assert.Equal(t, []config.ControllerEndpoint{
+		{Address: "10.0.0.1", Region: "eu"},
+		{Address: "10.0.0.2", Region: "us"},
+		{Address: "10.0.0.3"},
+	}, mergeEndpoints(listed, discovered))
+}
+
+func TestRotate(t *testing.T) {
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	assert.Equal(t, addresses, rotate(addresses, 0))
+	assert.Equal(t, []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}, rotate(addresses, 2))
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3", "10.0.0.1"}, rotate(addresses, 4))
+}
+
+func TestKubeconfigEndpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "connection-broker")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	kubeconfig := func(name, server string) string {
+		path := filepath.Join(dir, name)
+		content := "apiVersion: v1\nkind: Config\nclusters:\n- name: k0s\n  cluster:\n    server: " + server + "\n"
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+		return path
+	}
+	bootstrap := kubeconfig("bootstrap.conf", "https://10.0.0.1:6443")
+	auth := kubeconfig("kubelet.conf", "https://localhost:7443")
+
+	assert.Equal(t, []config.ControllerEndpoint{{Address: "10.0.0.1"}}, kubeconfigEndpoints(bootstrap, auth, filepath.Join(dir, "missing.conf")))
+}
+
+func TestCloseConnections(t *testing.T) {
+	b := &ConnectionBroker{log: logrus.WithField("component", "connection-broker"), connections: map[string]map[net.Conn]struct{}{}}
+	client, server := net.Pipe()
+	defer server.Close()
+	b.track("10.0.0.1", client)
+
+	b.closeConnections("10.0.0.1")
+	_, err := client.Write([]byte("x"))
+	assert.Error(t, err)
+	assert.Empty(t, b.connections)
+}
@@ -16,14 +16,23 @@ limitations under the License.
 package worker
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
+	"gopkg.in/fsnotify.v1"
 
+	"github.com/k0sproject/k0s/internal/util"
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/debounce"
 	"github.com/k0sproject/k0s/pkg/supervisor"
 )
 
@@ -34,10 +43,30 @@ type ContainerD struct {
 	K0sVars    constant.CfgVars
 
 	OCIBundlePath string
+	CNIConfDir    string
+	CNIBinDir     string
+	// SandboxImage is the pause image of the pod sandboxes, the containerd default is used if it's empty
+	SandboxImage string
+	// Registries are the registry mirrors and credentials of the worker profile
+	Registries *config.RegistriesSpec
+	// Snapshotter is the snapshotter of the container images, the containerd default is used if it's empty
+	Snapshotter string
+	// CgroupDriver is the cgroup driver of the kubelet, runc uses the same one
+	CgroupDriver string
+
+	pluginSupervisor *supervisor.Supervisor
+
+	mu         sync.Mutex
+	configPath string
+	watcher    *fsnotify.Watcher
+	debouncer  debounce.Debouncer
 }
 
-// Init extracts the needed binaries
+// Init checks the snapshotter and extracts the needed binaries
 func (c *ContainerD) Init() error {
+	if err := c.checkSnapshotter(); err != nil {
+		return err
+	}
 	g := new(errgroup.Group)
 	for _, bin := range []string{"containerd", "containerd-shim", "containerd-shim-runc-v1", "containerd-shim-runc-v2", "runc"} {
 		b := bin
@@ -52,27 +81,222 @@ func (c *ContainerD) Init() error {
 // Run runs containerD
 func (c *ContainerD) Run() error {
 	logrus.Info("Starting containerD")
+	configPath, err := c.config()
+	if err != nil {
+		return err
+	}
+	c.configPath = configPath
+	if err := c.start(); err != nil {
+		return err
+	}
+	return c.watchDropIns()
+}
+
+// checkSnapshotter fails if the selected snapshotter isn't supported by the node, the default one only warns
+// as it may still work
+func (c *ContainerD) checkSnapshotter() error {
+	if err := validateSnapshotter(c.Snapshotter); err != nil {
+		return err
+	}
+	name := c.Snapshotter
+	if name == "" {
+		name = SnapshotterOverlayfs
+	}
+	err := checkSnapshotter(c.Snapshotter, snapshotterRoot(c.K0sVars, name))
+	if err != nil && c.Snapshotter == "" {
+		logrus.Warnf("the default containerd snapshotter may not work: %v", err)
+		return nil
+	}
+	return err
+}
+
+func (c *ContainerD) start() error {
+	if c.Snapshotter == SnapshotterFuseOverlayfs && c.pluginSupervisor == nil {
+		c.pluginSupervisor = &supervisor.Supervisor{
+			Name:     fuseOverlayfsPlugin,
+			BinPath:  fuseOverlayfsPlugin,
+			RunDir:   c.K0sVars.RunDir,
+			DataDir:  c.K0sVars.DataDir,
+			CrashDir: c.K0sVars.CrashDir,
+			ArgsDir:  c.K0sVars.ComponentArgsDir,
+			Args: []string{
+				fuseOverlayfsSocket(c.K0sVars),
+				snapshotterRoot(c.K0sVars, SnapshotterFuseOverlayfs),
+			},
+		}
+		if err := c.pluginSupervisor.Supervise(); err != nil {
+			return err
+		}
+	}
 	c.supervisor = supervisor.Supervisor{
-		Name:    "containerd",
-		BinPath: assets.BinPath("containerd", c.K0sVars.BinDir),
-		RunDir:  c.K0sVars.RunDir,
-		DataDir: c.K0sVars.DataDir,
+		Name:     "containerd",
+		BinPath:  assets.BinPath("containerd", c.K0sVars.BinDir),
+		RunDir:   c.K0sVars.RunDir,
+		DataDir:  c.K0sVars.DataDir,
+		CrashDir: c.K0sVars.CrashDir,
+		ArgsDir:  c.K0sVars.ComponentArgsDir,
 		Args: []string{
 			fmt.Sprintf("--root=%s", filepath.Join(c.K0sVars.DataDir, "containerd")),
 			fmt.Sprintf("--state=%s", filepath.Join(c.K0sVars.RunDir, "containerd")),
 			fmt.Sprintf("--address=%s", filepath.Join(c.K0sVars.RunDir, "containerd.sock")),
 			fmt.Sprintf("--log-level=%s", c.LogLevel),
-			"--config=/etc/k0s/containerd.toml",
+			fmt.Sprintf("--config=%s", c.configPath),
 		},
 	}
-	// TODO We need to dump the config file suited for k0s use
 
 	return c.supervisor.Supervise()
 }
 
+// watchDropIns restarts containerd when the drop-ins change. The containers keep running while containerd restarts.
+func (c *ContainerD) watchDropIns() error {
+	if c.configPath != c.K0sVars.ContainerdConfigPath || !util.DirExists(constant.ContainerdDropInDir) {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(constant.ContainerdDropInDir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch containerd drop-ins: %w", err)
+	}
+	c.watcher = watcher
+	c.debouncer = debounce.New(time.Second, watcher.Events, func(fsnotify.Event) { c.reload() })
+	go c.debouncer.Start()
+	return nil
+}
+
+func (c *ContainerD) reload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, _ := ioutil.ReadFile(c.configPath)
+	if _, err := c.config(); err != nil {
+		logrus.Errorf("keeping the current containerd config: %v", err)
+		return
+	}
+	current, _ := ioutil.ReadFile(c.configPath)
+	if bytes.Equal(previous, current) {
+		return
+	}
+	logrus.Infof("the drop-ins of %s changed, restarting containerd", constant.ContainerdDropInDir)
+	if err := c.supervisor.Stop(); err != nil {
+		logrus.Errorf("failed to stop containerd: %v", err)
+	}
+	if err := c.start(); err != nil {
+		logrus.Errorf("failed to restart containerd: %v", err)
+	}
+}
+
+// config returns the path of the containerd config. The config provided by the user takes precedence,
+// otherwise k0s writes one into the data dir so nothing needs to be written outside of it.
+func (c *ContainerD) config() (string, error) {
+	if util.FileExists(constant.ContainerdUserConfigPath) {
+		if c.CNIConfDir != constant.CNIConfDirDefault || c.CNIBinDir != constant.CNIBinDirDefault {
+			logrus.Warnf("using %s, the CNI dirs need to be configured in it", constant.ContainerdUserConfigPath)
+		}
+		if c.Registries != nil {
+			logrus.Warnf("using %s, the registries of the worker profile need to be configured in it", constant.ContainerdUserConfigPath)
+		}
+		if c.Snapshotter != "" {
+			logrus.Warnf("using %s, the snapshotter needs to be configured in it", constant.ContainerdUserConfigPath)
+		}
+		if c.CgroupDriver == config.CgroupDriverSystemd {
+			logrus.Warnf("using %s, the systemd cgroup driver of runc needs to be configured in it", constant.ContainerdUserConfigPath)
+		}
+		if dropIns, _ := containerdDropIns(constant.ContainerdDropInDir); len(dropIns) > 0 {
+			logrus.Warnf("using %s, the drop-ins of %s are ignored", constant.ContainerdUserConfigPath, constant.ContainerdDropInDir)
+		}
+		return constant.ContainerdUserConfigPath, nil
+	}
+
+	registries, err := registriesConfig(c.Registries)
+	if err != nil {
+		return "", err
+	}
+	tw := util.TemplateWriter{
+		Name:     "containerd-config",
+		Template: containerdConfigTemplate,
+		Data: struct {
+			CNIConfDir   string
+			CNIBinDir    string
+			SandboxImage string
+			Registries   string
+			Snapshotter  string
+			CgroupDriver string
+		}{
+			CNIConfDir:   c.CNIConfDir,
+			CNIBinDir:    c.CNIBinDir,
+			SandboxImage: c.SandboxImage,
+			Registries:   registries,
+			Snapshotter:  snapshotterConfig(c.K0sVars, c.Snapshotter),
+			CgroupDriver: cgroupDriverConfig(c.CgroupDriver),
+		},
+	}
+	var buf bytes.Buffer
+	if err := tw.WriteToBuffer(&buf); err != nil {
+		return "", fmt.Errorf("failed to write containerd config: %w", err)
+	}
+	data, err := mergeContainerdDropIns(buf.Bytes(), constant.ContainerdDropInDir)
+	if err != nil {
+		return "", err
+	}
+	// the registry credentials end up in the config, the mode of the config written by the earlier versions is
+	// tightened as well
+	if err := ioutil.WriteFile(c.K0sVars.ContainerdConfigPath, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write containerd config: %w", err)
+	}
+	if err := os.Chmod(c.K0sVars.ContainerdConfigPath, 0600); err != nil {
+		return "", fmt.Errorf("failed to write containerd config: %w", err)
+	}
+	return c.K0sVars.ContainerdConfigPath, nil
+}
+
+const containerdConfigTemplate = `# generated by k0s, use /etc/k0s/containerd.toml for a custom config or /etc/k0s/containerd.d for additions
+version = 2
+{{- if .SandboxImage }}
+
+[plugins."io.containerd.grpc.v1.cri"]
+  sandbox_image = "{{ .SandboxImage }}"
+{{- end }}
+
+[plugins."io.containerd.grpc.v1.cri".cni]
+  conf_dir = "{{ .CNIConfDir }}"
+  bin_dir = "{{ .CNIBinDir }}"
+{{- .Snapshotter }}
+{{- .Registries }}
+{{- .CgroupDriver }}
+`
+
+// cgroupDriverConfig renders the runc config using the systemd cgroup driver, runc uses cgroupfs by default
+func cgroupDriverConfig(driver string) string {
+	if driver != config.CgroupDriverSystemd {
+		return ""
+	}
+	return `
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
+  SystemdCgroup = true`
+}
+
 // Stop stops containerD
 func (c *ContainerD) Stop() error {
-	return c.supervisor.Stop()
+	if c.watcher != nil {
+		c.debouncer.Stop()
+		c.watcher.Close()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the snapshotter plugin is stopped even if containerd failed to stop
+	err := c.supervisor.Stop()
+	if c.pluginSupervisor != nil {
+		if pluginErr := c.pluginSupervisor.Stop(); err == nil {
+			err = pluginErr
+		}
+	}
+	return err
 }
 
 // Health-check interface
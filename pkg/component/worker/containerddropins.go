@@ -0,0 +1,81 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// containerdDropIns lists the TOML snippets of the dir, in the order they are merged
+func containerdDropIns(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergeContainerdDropIns merges the drop-ins of the dir into the config, in lexical order. The tables are merged
+// key by key, the other values of the drop-ins replace the ones of the config. The config is returned as is if
+// there are no drop-ins.
+func mergeContainerdDropIns(config []byte, dir string) ([]byte, error) {
+	files, err := containerdDropIns(dir)
+	if err != nil || len(files) == 0 {
+		return config, err
+	}
+
+	merged := map[string]interface{}{}
+	if _, err := toml.Decode(string(config), &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse the generated containerd config: %w", err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read containerd drop-in %s: %w", file, err)
+		}
+		dropIn := map[string]interface{}{}
+		if _, err := toml.Decode(string(data), &dropIn); err != nil {
+			return nil, fmt.Errorf("failed to parse containerd drop-in %s: %w", file, err)
+		}
+		mergeTables(merged, dropIn)
+	}
+
+	buf := bytes.NewBufferString("# generated by k0s from the drop-ins of " + dir + "\n")
+	if err := toml.NewEncoder(buf).Encode(merged); err != nil {
+		return nil, fmt.Errorf("failed to encode the containerd config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeTables merges the override into the base, the tables recursively
+func mergeTables(base, override map[string]interface{}) {
+	for k, v := range override {
+		baseTable, ok := base[k].(map[string]interface{})
+		overrideTable, isTable := v.(map[string]interface{})
+		if ok && isTable {
+			mergeTables(baseTable, overrideTable)
+			continue
+		}
+		base[k] = v
+	}
+}
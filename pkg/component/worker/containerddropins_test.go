@@ -0,0 +1,100 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeContainerdDropIns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-containerd-dropins-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := []byte(`version = 2
+
+[plugins."io.containerd.grpc.v1.cri"]
+  sandbox_image = "k8s.gcr.io/pause:3.2"
+
+[plugins."io.containerd.grpc.v1.cri".cni]
+  conf_dir = "/etc/cni/net.d"
+  bin_dir = "/opt/cni/bin"
+`)
+
+	t.Run("no_drop_ins", func(t *testing.T) {
+		merged, err := mergeContainerdDropIns(config, dir)
+		require.NoError(t, err)
+		assert.Equal(t, config, merged)
+	})
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "10-nvidia.toml"), []byte(`
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.nvidia]
+  runtime_type = "io.containerd.runc.v2"
+  [plugins."io.containerd.grpc.v1.cri".containerd.runtimes.nvidia.options]
+    BinaryName = "/usr/bin/nvidia-container-runtime"
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "20-cni.toml"), []byte(`
+[plugins."io.containerd.grpc.v1.cri".cni]
+  conf_dir = "/etc/k0s/cni"
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a drop-in"), 0644))
+
+	t.Run("merged_in_order", func(t *testing.T) {
+		merged, err := mergeContainerdDropIns(config, dir)
+		require.NoError(t, err)
+
+		var parsed struct {
+			Version int64
+			Plugins struct {
+				CRI struct {
+					SandboxImage string `toml:"sandbox_image"`
+					CNI          struct {
+						ConfDir string `toml:"conf_dir"`
+						BinDir  string `toml:"bin_dir"`
+					} `toml:"cni"`
+					Containerd struct {
+						Runtimes map[string]struct {
+							RuntimeType string                 `toml:"runtime_type"`
+							Options     map[string]interface{} `toml:"options"`
+						} `toml:"runtimes"`
+					} `toml:"containerd"`
+				} `toml:"io.containerd.grpc.v1.cri"`
+			} `toml:"plugins"`
+		}
+		_, err = toml.Decode(string(merged), &parsed)
+		require.NoError(t, err)
+		cri := parsed.Plugins.CRI
+		assert.Equal(t, int64(2), parsed.Version)
+		assert.Equal(t, "k8s.gcr.io/pause:3.2", cri.SandboxImage)
+		assert.Equal(t, "/etc/k0s/cni", cri.CNI.ConfDir)
+		assert.Equal(t, "/opt/cni/bin", cri.CNI.BinDir)
+		assert.Equal(t, "io.containerd.runc.v2", cri.Containerd.Runtimes["nvidia"].RuntimeType)
+		assert.Equal(t, "/usr/bin/nvidia-container-runtime", cri.Containerd.Runtimes["nvidia"].Options["BinaryName"])
+	})
+
+	t.Run("invalid_drop_in", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "30-broken.toml"), []byte("[plugins"), 0644))
+		_, err := mergeContainerdDropIns(config, dir)
+		assert.Error(t, err)
+	})
+}
@@ -8,15 +8,30 @@ import (
 type RuntimeType = string
 type RuntimeSocket = string
 
+// CRIOSocketDefault is the socket CRI-O listens on by default
+const CRIOSocketDefault = "unix:///var/run/crio/crio.sock"
+
 func SplitRuntimeConfig(rtConfig string) (RuntimeType, RuntimeSocket, error) {
+	// the CRI-O socket can be left out for the default one
+	if rtConfig == "crio" {
+		rtConfig = "crio:"
+	}
 	runtimeConfig := strings.SplitN(rtConfig, ":", 2)
 	if len(runtimeConfig) != 2 {
 		return "", "", fmt.Errorf("cannot parse CRI socket path")
 	}
 	runtimeType := runtimeConfig[0]
 	runtimeSocket := runtimeConfig[1]
-	if runtimeType != "docker" && runtimeType != "remote" {
-		return "", "", fmt.Errorf("unknown runtime type %s, must be either of remote or docker", runtimeType)
+	if runtimeType != "docker" && runtimeType != "remote" && runtimeType != "crio" {
+		return "", "", fmt.Errorf("unknown runtime type %s, must be either of remote, crio or docker", runtimeType)
+	}
+	if runtimeType == "crio" {
+		if runtimeSocket == "" {
+			runtimeSocket = CRIOSocketDefault
+		} else if !strings.Contains(runtimeSocket, "://") {
+			// gRPC dials plain paths over TCP
+			runtimeSocket = "unix://" + runtimeSocket
+		}
 	}
 
 	return runtimeType, runtimeSocket, nil
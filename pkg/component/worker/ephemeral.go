@@ -0,0 +1,113 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// deregisterTimeout bounds the deletion of the node object, so that a lost apiserver doesn't hold up the shutdown
+const deregisterTimeout = 30 * time.Second
+
+// EphemeralNode deregisters the node from the cluster when the worker shuts down gracefully: the node object is
+// deleted and the kubelet credentials are removed from the host, so a restarted worker joins again as a new node.
+// It needs to be stopped after the kubelet, so that the kubelet doesn't register the node again, and before the
+// connection broker, which may carry the apiserver traffic of the node.
+type EphemeralNode struct {
+	K0sVars constant.CfgVars
+
+	log *logrus.Entry
+}
+
+// Init does nothing
+func (e *EphemeralNode) Init() error {
+	e.log = logrus.WithField("component", "ephemeral-node")
+	return nil
+}
+
+// Run does nothing, the node is deregistered on stop
+func (e *EphemeralNode) Run() error {
+	return nil
+}
+
+// Stop deletes the node object and removes the kubelet credentials
+func (e *EphemeralNode) Stop() error {
+	nodeName, err := NodeName(e.K0sVars)
+	if err != nil {
+		return fmt.Errorf("failed to deregister the node: %w", err)
+	}
+	if err := e.deleteNode(nodeName); err != nil {
+		return fmt.Errorf("failed to deregister node %s: %w", nodeName, err)
+	}
+	e.log.Infof("deregistered node %s", nodeName)
+	return e.removeCredentials()
+}
+
+// Healthy dummy implementation
+func (e *EphemeralNode) Healthy() error { return nil }
+
+// deleteNode deletes the node object with the credentials of the kubelet, the NodeRestriction admission plugin only
+// lets it delete its own node
+func (e *EphemeralNode) deleteNode(nodeName string) error {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", e.K0sVars.KubeletAuthConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load kubelet kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancel()
+	err = client.CoreV1().Nodes().Delete(ctx, nodeName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// removeCredentials removes the kubeconfigs and the client certificates of the kubelet. The certificates remain valid
+// until they expire, removing them makes sure that the host can't act as the node anymore.
+func (e *EphemeralNode) removeCredentials() error {
+	certs, err := filepath.Glob(filepath.Join(kubeletRootDir(e.K0sVars), kubeletCertDirName, "kubelet-client-*.pem"))
+	if err != nil {
+		return err
+	}
+	paths := append([]string{
+		e.K0sVars.KubeletAuthConfigPath,
+		e.K0sVars.KubeletBootstrapConfigPath,
+		e.K0sVars.KubeletNodeNamePath,
+	}, certs...)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove the kubelet credentials: %w", err)
+		}
+	}
+	return nil
+}
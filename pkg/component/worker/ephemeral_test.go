@@ -0,0 +1,58 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestEphemeralNodeCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-ephemeral-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	k0sVars := constant.GetConfig(dir)
+	certDir := filepath.Join(k0sVars.DataDir, "kubelet", "pki")
+	require.NoError(t, os.MkdirAll(certDir, 0700))
+	clientCert := filepath.Join(certDir, "kubelet-client-current.pem")
+	rotatedCert := filepath.Join(certDir, "kubelet-client-2021-06-01-10-00-00.pem")
+	servingCert := filepath.Join(certDir, "kubelet.crt")
+	require.NoError(t, ioutil.WriteFile(clientCert, nodeClientCert(t, "system:node:spot-1"), 0600))
+	for _, path := range []string{rotatedCert, servingCert, k0sVars.KubeletAuthConfigPath, k0sVars.KubeletBootstrapConfigPath} {
+		require.NoError(t, ioutil.WriteFile(path, []byte("test"), 0600))
+	}
+
+	e := &EphemeralNode{K0sVars: k0sVars}
+	require.NoError(t, e.Init())
+	nodeName, err := NodeName(k0sVars)
+	require.NoError(t, err)
+	assert.Equal(t, "spot-1", nodeName)
+
+	require.NoError(t, e.removeCredentials())
+	for _, path := range []string{clientCert, rotatedCert, k0sVars.KubeletAuthConfigPath, k0sVars.KubeletBootstrapConfigPath} {
+		assert.NoFileExists(t, path)
+	}
+	// the serving certificate doesn't authenticate the node
+	assert.FileExists(t, servingCert)
+}
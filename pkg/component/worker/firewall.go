@@ -0,0 +1,102 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/firewall"
+)
+
+// firewallSyncInterval is how often the ports are checked in the host firewall
+const firewallSyncInterval = time.Minute
+
+// Firewall opens the ports of the worker in the host firewall while the firewall management is enabled in the cluster
+// config. The controllers publish the ports along with the kubelet config of the profile. The ports closed later on,
+// e.g. by a reload of the firewall, are opened again.
+type Firewall struct {
+	KubeletConfigClient *KubeletConfigClient
+	Profile             string
+
+	log    *logrus.Entry
+	cancel context.CancelFunc
+	synced bool
+}
+
+// Init initializes the logger
+func (f *Firewall) Init() error {
+	f.log = logrus.WithField("component", "firewall")
+	return nil
+}
+
+// Run syncs the ports right away and then periodically
+func (f *Firewall) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(firewallSyncInterval)
+		defer ticker.Stop()
+		for {
+			f.sync()
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the sync, the ports are left open
+func (f *Firewall) Stop() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (f *Firewall) Healthy() error { return nil }
+
+func (f *Firewall) sync() {
+	rules, err := f.KubeletConfigClient.FirewallRules(f.Profile)
+	if err != nil {
+		f.log.Warnf("failed to get the ports of the worker: %v", err)
+		return
+	}
+	if rules == nil {
+		f.synced = false
+		return
+	}
+	opened, err := firewall.Sync(rules)
+	switch {
+	case errors.Is(err, firewall.ErrNoFirewall):
+		f.log.Debug("no firewall found on the host, nothing to open")
+	case err != nil:
+		f.log.Warnf("failed to open the ports in the firewall: %v", err)
+	case len(opened) > 0 && f.synced:
+		f.log.Warnf("reopened %s, the ports were closed in the firewall", strings.Join(opened, " "))
+	case len(opened) > 0:
+		f.log.Infof("opened %s in the firewall", strings.Join(opened, " "))
+	}
+	f.synced = err == nil
+}
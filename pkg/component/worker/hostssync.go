@@ -0,0 +1,111 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/hosts"
+)
+
+// hostsSyncInterval is how often the hosts file is updated
+const hostsSyncInterval = 30 * time.Second
+
+// HostsSync keeps the names of the controllers and the nodes in the hosts file of the worker while the hosts sync
+// is enabled in the cluster config, so that they resolve without an external DNS. The managed block of the hosts
+// file is removed once the sync is disabled.
+type HostsSync struct {
+	K0sVars   constant.CfgVars
+	HostsPath string
+
+	log    *logrus.Entry
+	cancel context.CancelFunc
+}
+
+// Init initializes the logger
+func (h *HostsSync) Init() error {
+	h.log = logrus.WithField("component", "hosts-sync")
+	if h.HostsPath == "" {
+		h.HostsPath = hosts.DefaultPath
+	}
+	return nil
+}
+
+// Run syncs the hosts file periodically
+func (h *HostsSync) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(hostsSyncInterval)
+		defer ticker.Stop()
+		for {
+			if err := h.sync(ctx); err != nil {
+				h.log.Warnf("failed to sync the hosts: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the sync, the hosts file is left as is
+func (h *HostsSync) Stop() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (h *HostsSync) Healthy() error { return nil }
+
+func (h *HostsSync) sync(ctx context.Context) error {
+	if !util.FileExists(h.K0sVars.KubeletAuthConfigPath) {
+		// not joined yet
+		return nil
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", h.K0sVars.KubeletAuthConfigPath)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	entries, enabled, err := hosts.Lookup(ctx, client)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		entries = nil
+	}
+	changed, err := hosts.Update(h.HostsPath, entries)
+	if changed {
+		h.log.Infof("updated %s with %d cluster members", h.HostsPath, len(entries))
+	}
+	return err
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// the files of the identity archive, all at the top level
+const (
+	identityNodeName      = "node-name"
+	identityKubeconfig    = "kubelet.conf"
+	identityCACert        = "ca.crt"
+	identityClientCert    = "kubelet-client-current.pem"
+	identityServingCert   = "kubelet.crt"
+	identityServingKey    = "kubelet.key"
+	nodeCommonNamePrefix  = "system:node:"
+	kubeletCertDirName    = "pki"
+	kubeletDefaultRootDir = "kubelet"
+)
+
+// ExportIdentity writes the kubelet identity of the node (the kubeconfig, the kubelet certificates and the node name) into
+// a tar.gz written to w, so that it can be imported with ImportIdentity on replacement hardware. The archive contains the
+// private keys of the node and has to be handled like any other credentials.
+func ExportIdentity(k0sVars constant.CfgVars, w io.Writer) error {
+	if !util.FileExists(k0sVars.KubeletAuthConfigPath) {
+		return fmt.Errorf("%s does not exist, the node has not joined the cluster yet", k0sVars.KubeletAuthConfigPath)
+	}
+	certDir := filepath.Join(kubeletRootDir(k0sVars), kubeletCertDirName)
+
+	clientCert, err := ioutil.ReadFile(filepath.Join(certDir, identityClientCert))
+	if err != nil {
+		return fmt.Errorf("failed to read the kubelet client certificate: %w", err)
+	}
+	nodeName, err := identityNodeNameOf(k0sVars, clientCert)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name     string
+		path     string
+		optional bool
+	}{
+		{identityKubeconfig, k0sVars.KubeletAuthConfigPath, false},
+		{identityCACert, filepath.Join(k0sVars.CertRootDir, "ca.crt"), false},
+		{identityServingCert, filepath.Join(certDir, identityServingCert), true},
+		{identityServingKey, filepath.Join(certDir, identityServingKey), true},
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	if err := writeIdentityFile(tw, identityNodeName, []byte(nodeName)); err != nil {
+		return err
+	}
+	if err := writeIdentityFile(tw, identityClientCert, clientCert); err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f.path)
+		if err != nil {
+			if f.optional && os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to read %s: %w", f.path, err)
+		}
+		if err := writeIdentityFile(tw, f.name, data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// ImportIdentity places the kubelet identity exported with ExportIdentity on this node. The kubelet certificates are
+// written into the cert dir under kubeletRootDir, or under the default kubelet root dir if it's empty. The node then
+// starts as the node it replaces, without a join token.
+func ImportIdentity(k0sVars constant.CfgVars, archive string, kubeletRootDir string) error {
+	tmpDir, err := ioutil.TempDir("", "k0s-identity")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := util.ExtractArchive(archive, tmpDir); err != nil {
+		return fmt.Errorf("failed to extract the identity archive: %w", err)
+	}
+
+	nodeName, err := ioutil.ReadFile(filepath.Join(tmpDir, identityNodeName))
+	if err != nil {
+		return fmt.Errorf("invalid identity archive: %w", err)
+	}
+	kubeconfig, err := clientcmd.LoadFromFile(filepath.Join(tmpDir, identityKubeconfig))
+	if err != nil {
+		return fmt.Errorf("invalid identity archive: %w", err)
+	}
+
+	if kubeletRootDir == "" {
+		kubeletRootDir = filepath.Join(k0sVars.DataDir, kubeletDefaultRootDir)
+	}
+	certDir := filepath.Join(kubeletRootDir, kubeletCertDirName)
+	for _, dir := range []string{k0sVars.DataDir, kubeletRootDir} {
+		if err := util.InitDirectory(dir, constant.DataDirMode); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if err := util.InitDirectory(certDir, constant.CertRootDirMode); err != nil {
+		return fmt.Errorf("failed to create %s: %w", certDir, err)
+	}
+	if err := util.InitDirectory(k0sVars.CertRootDir, constant.CertRootDirMode); err != nil {
+		return fmt.Errorf("failed to create %s: %w", k0sVars.CertRootDir, err)
+	}
+
+	files := []struct {
+		name     string
+		path     string
+		mode     os.FileMode
+		optional bool
+	}{
+		{identityCACert, filepath.Join(k0sVars.CertRootDir, "ca.crt"), constant.CertMode, false},
+		{identityClientCert, filepath.Join(certDir, identityClientCert), constant.CertSecureMode, false},
+		{identityServingCert, filepath.Join(certDir, identityServingCert), constant.CertMode, true},
+		{identityServingKey, filepath.Join(certDir, identityServingKey), constant.CertSecureMode, true},
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join(tmpDir, f.name))
+		if err != nil {
+			if f.optional && os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("invalid identity archive: %w", err)
+		}
+		if err := ioutil.WriteFile(f.path, data, f.mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+	}
+
+	// the kubeconfig points to the client certificate of the old node, which may have used another root dir
+	clientCert := filepath.Join(certDir, identityClientCert)
+	for _, authInfo := range kubeconfig.AuthInfos {
+		if authInfo.ClientCertificate != "" {
+			authInfo.ClientCertificate = clientCert
+		}
+		if authInfo.ClientKey != "" {
+			authInfo.ClientKey = clientCert
+		}
+	}
+	if err := clientcmd.WriteToFile(*kubeconfig, k0sVars.KubeletAuthConfigPath); err != nil {
+		return fmt.Errorf("failed to write %s: %w", k0sVars.KubeletAuthConfigPath, err)
+	}
+	if err := os.Chmod(k0sVars.KubeletAuthConfigPath, constant.CertSecureMode); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(k0sVars.KubeletNodeNamePath, nodeName, constant.CertMode); err != nil {
+		return fmt.Errorf("failed to record the node name: %w", err)
+	}
+	logrus.Infof("imported the identity of node %s", nodeName)
+	return nil
+}
+
+// ImportedNodeName returns the node name imported with ImportIdentity, or an empty string if there's none
+func ImportedNodeName(k0sVars constant.CfgVars) string {
+	data, err := ioutil.ReadFile(k0sVars.KubeletNodeNamePath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// NodeName returns the name of the node the kubelet client certificate has been issued for, or the imported one
+func NodeName(k0sVars constant.CfgVars) (string, error) {
+	clientCert, err := ioutil.ReadFile(filepath.Join(kubeletRootDir(k0sVars), kubeletCertDirName, identityClientCert))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the kubelet client certificate: %w", err)
+	}
+	return identityNodeNameOf(k0sVars, clientCert)
+}
+
+// kubeletRootDir returns the kubelet root dir recorded by the kubelet component, or the default one
+func kubeletRootDir(k0sVars constant.CfgVars) string {
+	if data, err := ioutil.ReadFile(k0sVars.KubeletRootDirPath); err == nil {
+		if dir := strings.TrimSpace(string(data)); util.DirExists(filepath.Join(dir, kubeletCertDirName)) {
+			return dir
+		}
+	}
+	return filepath.Join(k0sVars.DataDir, kubeletDefaultRootDir)
+}
+
+// identityNodeNameOf returns the node name of the identity, preferring the imported one, then the one the
+// client certificate has been issued for
+func identityNodeNameOf(k0sVars constant.CfgVars, clientCert []byte) (string, error) {
+	if name := ImportedNodeName(k0sVars); name != "" {
+		return name, nil
+	}
+	for block, rest := pem.Decode(clientCert); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse the kubelet client certificate: %w", err)
+		}
+		if strings.HasPrefix(cert.Subject.CommonName, nodeCommonNamePrefix) {
+			return strings.TrimPrefix(cert.Subject.CommonName, nodeCommonNamePrefix), nil
+		}
+	}
+	return "", fmt.Errorf("the kubelet client certificate has not been issued for a node")
+}
+
+func writeIdentityFile(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write file header to archive: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+const identityTestKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: default-cluster
+  cluster:
+    server: https://10.0.0.1:6443
+    certificate-authority: /var/lib/k0s/pki/ca.crt
+contexts:
+- name: default-context
+  context:
+    cluster: default-cluster
+    user: default-auth
+current-context: default-context
+users:
+- name: default-auth
+  user:
+    client-certificate: /old/kubelet/pki/kubelet-client-current.pem
+    client-key: /old/kubelet/pki/kubelet-client-current.pem
+`
+
+func nodeClientCert(t *testing.T, commonName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"system:nodes"}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...)
+}
+
+func TestExportImportIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-identity-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldNode := constant.GetConfig(filepath.Join(dir, "old"))
+	oldCertDir := filepath.Join(oldNode.DataDir, "kubelet", "pki")
+	require.NoError(t, os.MkdirAll(oldCertDir, 0700))
+	require.NoError(t, os.MkdirAll(oldNode.CertRootDir, 0700))
+	clientCert := nodeClientCert(t, "system:node:node-1")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(oldCertDir, "kubelet-client-current.pem"), clientCert, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(oldNode.CertRootDir, "ca.crt"), []byte("ca"), 0600))
+	require.NoError(t, ioutil.WriteFile(oldNode.KubeletAuthConfigPath, []byte(identityTestKubeconfig), 0600))
+
+	var archive bytes.Buffer
+	require.NoError(t, ExportIdentity(oldNode, &archive))
+	archivePath := filepath.Join(dir, "identity.tar.gz")
+	require.NoError(t, ioutil.WriteFile(archivePath, archive.Bytes(), 0600))
+
+	newNode := constant.GetConfig(filepath.Join(dir, "new"))
+	newRootDir := filepath.Join(dir, "new-kubelet")
+	require.NoError(t, ImportIdentity(newNode, archivePath, newRootDir))
+
+	assert.Equal(t, "node-1", ImportedNodeName(newNode))
+	imported, err := ioutil.ReadFile(filepath.Join(newRootDir, "pki", "kubelet-client-current.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, clientCert, imported)
+	assert.FileExists(t, filepath.Join(newNode.CertRootDir, "ca.crt"))
+	assert.NoFileExists(t, filepath.Join(newRootDir, "pki", "kubelet.crt"))
+
+	kubeconfig, err := clientcmd.LoadFromFile(newNode.KubeletAuthConfigPath)
+	require.NoError(t, err)
+	authInfo := kubeconfig.AuthInfos["default-auth"]
+	require.NotNil(t, authInfo)
+	assert.Equal(t, filepath.Join(newRootDir, "pki", "kubelet-client-current.pem"), authInfo.ClientCertificate)
+	assert.Equal(t, filepath.Join(newRootDir, "pki", "kubelet-client-current.pem"), authInfo.ClientKey)
+	assert.Equal(t, "https://10.0.0.1:6443", kubeconfig.Clusters["default-cluster"].Server)
+
+	// the imported name is kept on the next export
+	archive.Reset()
+	require.NoError(t, os.MkdirAll(filepath.Join(newNode.DataDir, "kubelet", "pki"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(newNode.DataDir, "kubelet", "pki", "kubelet-client-current.pem"), nodeClientCert(t, "not-a-node"), 0600))
+	assert.NoError(t, ExportIdentity(newNode, &archive))
+}
+
+func TestExportIdentityNotJoined(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-identity-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Error(t, ExportIdentity(constant.GetConfig(dir), &bytes.Buffer{}))
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/pkg/exitcode"
+	"github.com/k0sproject/k0s/pkg/token"
+)
+
+// nat64Prefix is the well-known prefix of the IPv6 addresses synthesized by DNS64 for IPv4-only hosts
+var nat64Prefix = &net.IPNet{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}
+
+// CheckJoinAddressPreflight checks that the API address of the join token can be reached with the IP families
+// configured on the node. It catches the IPv4 literals in the tokens used on IPv6-only nodes, which DNS64/NAT64
+// can't translate.
+func CheckJoinAddressPreflight(encodedToken string) error {
+	kubeconfig, err := token.DecodeJoinToken(encodedToken)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Join, fmt.Errorf("failed to decode token: %w", err))
+	}
+	clientCfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Join, fmt.Errorf("failed to parse the join token: %w", err))
+	}
+	for _, cluster := range clientCfg.Clusters {
+		server, err := url.Parse(cluster.Server)
+		if err != nil {
+			return fmt.Errorf("invalid API address %q in the join token: %w", cluster.Server, err)
+		}
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			logrus.Warnf("failed to list the node addresses, skipping the join address check: %v", err)
+			return nil
+		}
+		var nodeIPs []net.IP
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				nodeIPs = append(nodeIPs, ipnet.IP)
+			}
+		}
+		if err := checkJoinAddress(server.Hostname(), nodeIPs, net.LookupIP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkJoinAddress(host string, nodeIPs []net.IP, lookup func(string) ([]net.IP, error)) error {
+	var hasIPv4, hasIPv6 bool
+	for _, ip := range nodeIPs {
+		// the loopback and link-local addresses don't route to the controllers
+		if !ip.IsGlobalUnicast() {
+			continue
+		}
+		if ip.To4() != nil {
+			hasIPv4 = true
+		} else {
+			hasIPv6 = true
+		}
+	}
+	if !hasIPv4 && !hasIPv6 {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil && !hasIPv4 {
+			return fmt.Errorf("the join address %s is an IPv4 address, it can't be reached from this IPv6-only node as DNS64/NAT64 doesn't translate IP literals. Use a DNS name or an IPv6 address as spec.api.externalAddress and create a new token", host)
+		}
+		if ip.To4() == nil && !hasIPv6 {
+			return fmt.Errorf("the join address %s is an IPv6 address, but the node has no global IPv6 address", host)
+		}
+		return nil
+	}
+
+	ips, err := lookup(host)
+	if err != nil {
+		// the name may resolve once the network is fully up, the join is retried anyway
+		logrus.Warnf("failed to resolve the join address %s: %v", host, err)
+		return nil
+	}
+	reachable := false
+	for _, ip := range ips {
+		if ip.To4() != nil && hasIPv4 || ip.To4() == nil && hasIPv6 {
+			reachable = true
+		}
+		if nat64Prefix.Contains(ip) {
+			logrus.Infof("the join address %s resolves to %s, the controllers are reached through NAT64", host, ip)
+		}
+	}
+	if !reachable && !hasIPv4 {
+		return fmt.Errorf("the join address %s only resolves to IPv4 addresses on this IPv6-only node, publish an AAAA record for it or set up DNS64/NAT64", host)
+	}
+	if !reachable {
+		return fmt.Errorf("the join address %s only resolves to IPv6 addresses, but the node has no global IPv6 address", host)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckJoinAddress(t *testing.T) {
+	ipv4Only := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("10.0.0.5")}
+	ipv6Only := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1"), net.ParseIP("fe80::1"), net.ParseIP("2001:db8::5")}
+	dualStack := append(ipv4Only, net.ParseIP("2001:db8::5"))
+
+	dns := map[string][]net.IP{
+		"v4.example.com":    {net.ParseIP("10.0.0.1")},
+		"v6.example.com":    {net.ParseIP("2001:db8::1")},
+		"nat64.example.com": {net.ParseIP("10.0.0.1"), net.ParseIP("64:ff9b::a00:1")},
+	}
+	lookup := func(host string) ([]net.IP, error) {
+		if ips, found := dns[host]; found {
+			return ips, nil
+		}
+		return nil, fmt.Errorf("no such host")
+	}
+
+	tests := []struct {
+		name    string
+		host    string
+		nodeIPs []net.IP
+		wantErr bool
+	}{
+		{"IPv4 literal on IPv4 node", "10.0.0.1", ipv4Only, false},
+		{"IPv4 literal on IPv6-only node", "10.0.0.1", ipv6Only, true},
+		{"IPv6 literal on IPv6-only node", "2001:db8::1", ipv6Only, false},
+		{"IPv6 literal on IPv4-only node", "2001:db8::1", ipv4Only, true},
+		{"IPv4 name on IPv6-only node", "v4.example.com", ipv6Only, true},
+		{"DNS64 name on IPv6-only node", "nat64.example.com", ipv6Only, false},
+		{"IPv6 name on dual-stack node", "v6.example.com", dualStack, false},
+		{"IPv6 name on IPv4-only node", "v6.example.com", ipv4Only, true},
+		{"unresolvable name", "missing.example.com", ipv6Only, false},
+		{"node without addresses", "10.0.0.1", []net.IP{net.ParseIP("127.0.0.1")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJoinAddress(tt.host, tt.nodeIPs, lookup)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
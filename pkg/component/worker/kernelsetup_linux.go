@@ -45,7 +45,7 @@ func hasFilesystem(filesystem string) bool {
 }
 
 func modprobe(module string) {
-	err := exec.Command("modprobe", module)
+	err := exec.Command("modprobe", module).Run()
 	if err != nil {
 		logrus.Warnf("failed to load %s kernel module: %s", module, err)
 	}
@@ -23,6 +23,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/assets"
 	"github.com/k0sproject/k0s/pkg/constant"
 	"github.com/k0sproject/k0s/pkg/supervisor"
@@ -48,7 +50,12 @@ type Kubelet struct {
 	supervisor          supervisor.Supervisor
 	ClusterDNS          string
 	Labels              []string
+	Taints              []string
 	ExtraArgs           string
+	RootDir             string
+	BindMountRootDir    bool
+	// PauseImage is the image of the pod sandboxes used by the runtime, the kubelet never garbage collects it
+	PauseImage string
 }
 
 type kubeletConfig struct {
@@ -67,15 +74,52 @@ func (k *Kubelet) Init() error {
 		return err
 	}
 
-	k.dataDir = filepath.Join(k.K0sVars.DataDir, "kubelet")
-	err = util.InitDirectory(k.dataDir, constant.DataDirMode)
-	if err != nil {
-		return fmt.Errorf("failed to create %s: %w", k.dataDir, err)
+	k.dataDir = k.RootDir
+	if k.dataDir == "" {
+		k.dataDir = filepath.Join(k.K0sVars.DataDir, "kubelet")
+	}
+	// the dir in the data dir is always owned by k0s, a custom one only if k0s creates it
+	if err := initKubeletRootDir(k.dataDir, k.RootDir == ""); err != nil {
+		return err
+	}
+
+	if k.BindMountRootDir && filepath.Clean(k.dataDir) != constant.KubeletDefaultRootDir {
+		// the marker has to be written before mounting over the dir, so reset finds it once it's unmounted
+		if err := initKubeletRootDir(constant.KubeletDefaultRootDir, false); err != nil {
+			return err
+		}
+		if err := bindMount(k.dataDir, constant.KubeletDefaultRootDir); err != nil {
+			return err
+		}
+		// the kubelet needs to use the default path, it's passed to the CSI drivers which only see that one
+		k.dataDir = constant.KubeletDefaultRootDir
+	}
+
+	// k0s reset needs to know where to look for the kubelet mounts
+	if err := ioutil.WriteFile(k.K0sVars.KubeletRootDirPath, []byte(k.dataDir), constant.CertMode); err != nil {
+		return fmt.Errorf("failed to record the kubelet root dir: %w", err)
 	}
 
 	return nil
 }
 
+// initKubeletRootDir creates the kubelet root dir and, if it's owned by k0s, marks it so k0s reset is allowed to
+// delete it. Existing dirs created by someone else, such as /var, are never marked.
+func initKubeletRootDir(dir string, owned bool) error {
+	if !util.DirExists(dir) {
+		owned = true
+	}
+	if err := util.InitDirectory(dir, constant.DataDirMode); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if owned {
+		if err := ioutil.WriteFile(filepath.Join(dir, constant.KubeletRootDirMarker), nil, constant.CertMode); err != nil {
+			return fmt.Errorf("failed to mark %s as owned by k0s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
 // Run runs kubelet
 func (k *Kubelet) Run() error {
 	cmd := "kubelet"
@@ -102,10 +146,6 @@ func (k *Kubelet) Run() error {
 		"--cert-dir":             filepath.Join(k.dataDir, "pki"),
 	}
 
-	if len(k.Labels) > 0 {
-		args["--node-labels"] = strings.Join(k.Labels, ",")
-	}
-
 	if runtime.GOOS == "windows" {
 		node, err := getNodeName()
 		if err != nil {
@@ -125,6 +165,10 @@ func (k *Kubelet) Run() error {
 	} else {
 		args["--cgroups-per-qos"] = "true"
 		args["--resolv-conf"] = resolvConfPath
+		// the node runs with the identity of the node it replaces
+		if name := ImportedNodeName(k.K0sVars); name != "" {
+			args["--hostname-override"] = name
+		}
 	}
 
 	if k.CRISocket != "" {
@@ -132,16 +176,18 @@ func (k *Kubelet) Run() error {
 		if err != nil {
 			return err
 		}
-		args["--container-runtime"] = rtType
 		shimPath := "unix:///var/run/dockershim.sock"
 		if runtime.GOOS == "windows" {
 			shimPath = "npipe:////./pipe/dockershim"
 		}
 		if rtType == "docker" {
+			args["--container-runtime"] = rtType
 			args["--docker-endpoint"] = rtSock
 			// this endpoint is actually pointing to the one kubelet itself creates as the cri shim between itself and docker
 			args["--container-runtime-endpoint"] = shimPath
 		} else {
+			// CRI-O is a remote runtime for the kubelet as well
+			args["--container-runtime"] = "remote"
 			args["--container-runtime-endpoint"] = rtSock
 		}
 	} else {
@@ -151,32 +197,29 @@ func (k *Kubelet) Run() error {
 		args["--containerd"] = sockPath
 	}
 
+	if k.PauseImage != "" {
+		args["--pod-infra-container-image"] = k.PauseImage
+	}
+
 	// We only support external providers
 	if k.EnableCloudProvider {
 		args["--cloud-provider"] = "external"
 	}
 
-	// Handle the extra args as last so they can be used to overrride some k0s "hardcodings"
-	if k.ExtraArgs != "" {
-		extras := util.SplitFlags(k.ExtraArgs)
-		args.Merge(extras)
-	}
-
-	logrus.Infof("starting kubelet with args: %v", args)
-	k.supervisor = supervisor.Supervisor{
-		Name:    cmd,
-		BinPath: assets.BinPath(cmd, k.K0sVars.BinDir),
-		RunDir:  k.K0sVars.RunDir,
-		DataDir: k.K0sVars.DataDir,
-		Args:    args.ToArgs(),
-	}
-
+	var profileLabels map[string]string
+	var profileTaints []string
 	err := retry.Do(func() error {
 		kubeletconfig, err := k.KubeletConfigClient.Get(k.Profile)
 		if err != nil {
 			logrus.Warnf("failed to get initial kubelet config with join token: %s", err.Error())
 			return err
 		}
+		if profileLabels, err = k.KubeletConfigClient.NodeLabels(k.Profile); err != nil {
+			return err
+		}
+		if profileTaints, err = k.KubeletConfigClient.NodeTaints(k.Profile); err != nil {
+			return err
+		}
 		tw := util.TemplateWriter{
 			Name:     "kubelet-config",
 			Template: kubeletconfig,
@@ -191,6 +234,9 @@ func (k *Kubelet) Run() error {
 			return fmt.Errorf("failed to write kubelet config: %w", err)
 		}
 
+		if runtime.GOOS != "windows" {
+			return k.writeSeccompProfiles()
+		}
 		return nil
 	},
 		retry.Delay(time.Millisecond*500),
@@ -199,9 +245,105 @@ func (k *Kubelet) Run() error {
 		return err
 	}
 
+	// the labels of the profile are set with the ones given to the worker, which take precedence
+	if labels := nodeLabels(profileLabels, k.Labels); labels != "" {
+		args["--node-labels"] = labels
+	}
+	// the taints given to the worker are added to the ones of the profile, replacing the ones with the same key and effect
+	if taints := nodeTaints(profileTaints, k.Taints); taints != "" {
+		args["--register-with-taints"] = taints
+	}
+	// Handle the extra args as last so they can be used to overrride some k0s "hardcodings"
+	if k.ExtraArgs != "" {
+		extras := util.SplitFlags(k.ExtraArgs)
+		args.Merge(extras)
+	}
+
+	logrus.Infof("starting kubelet with args: %v", args)
+	k.supervisor = supervisor.Supervisor{
+		Name:     cmd,
+		BinPath:  assets.BinPath(cmd, k.K0sVars.BinDir),
+		RunDir:   k.K0sVars.RunDir,
+		DataDir:  k.K0sVars.DataDir,
+		CrashDir: k.K0sVars.CrashDir,
+		ArgsDir:  k.K0sVars.ComponentArgsDir,
+		Args:     args.ToArgs(),
+	}
 	return k.supervisor.Supervise()
 }
 
+// nodeLabels joins the labels of the profile and the given key=value labels, sorted by the key
+func nodeLabels(profileLabels map[string]string, labels []string) string {
+	merged := map[string]string{}
+	for key, value := range profileLabels {
+		merged[key] = value
+	}
+	for _, label := range labels {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) == 2 {
+			merged[kv[0]] = kv[1]
+		} else {
+			merged[kv[0]] = ""
+		}
+	}
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+merged[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// nodeTaints joins the taints of the profile and the given taints, a given taint replaces the one of
+// the profile with the same key and effect
+func nodeTaints(profileTaints []string, taints []string) string {
+	index := map[string]int{}
+	joined := make([]string, 0, len(profileTaints)+len(taints))
+	for _, taint := range append(append([]string{}, profileTaints...), taints...) {
+		id := taint
+		if t, err := v1beta1.ParseNodeTaint(taint); err == nil {
+			id = t.Key + ":" + t.Effect
+		}
+		if i, found := index[id]; found {
+			joined[i] = taint
+			continue
+		}
+		index[id] = len(joined)
+		joined = append(joined, taint)
+	}
+	return strings.Join(joined, ",")
+}
+
+// writeSeccompProfiles replaces the k0s managed seccomp profiles in the kubelet seccomp dir
+// with the ones distributed with the worker profile
+func (k *Kubelet) writeSeccompProfiles() error {
+	profiles, err := k.KubeletConfigClient.SeccompProfiles(k.Profile)
+	if err != nil {
+		return err
+	}
+	// the kubelet resolves the localhost profiles relative to <root-dir>/seccomp
+	seccompDir := filepath.Join(k.dataDir, "seccomp", "k0s")
+	if err := os.RemoveAll(seccompDir); err != nil {
+		return fmt.Errorf("failed to clean up seccomp profiles: %w", err)
+	}
+	if len(profiles) == 0 {
+		return nil
+	}
+	if err := util.InitDirectory(seccompDir, constant.DataDirMode); err != nil {
+		return fmt.Errorf("failed to create %s: %w", seccompDir, err)
+	}
+	for name, content := range profiles {
+		if err := ioutil.WriteFile(filepath.Join(seccompDir, filepath.Base(name)), []byte(content), constant.CertMode); err != nil {
+			return fmt.Errorf("failed to write seccomp profile %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
 // Stop stops kubelet
 func (k *Kubelet) Stop() error {
 	return k.supervisor.Stop()
@@ -43,6 +43,20 @@ func TestCRISocketParsing(t *testing.T) {
 			expSocket: "unix:///var/run/mke/containerd.sock",
 			err:       false,
 		},
+		{
+			name:      "crio",
+			input:     "crio:/run/crio/crio.sock",
+			expType:   "crio",
+			expSocket: "unix:///run/crio/crio.sock",
+			err:       false,
+		},
+		{
+			name:      "crio-default",
+			input:     "crio",
+			expType:   "crio",
+			expSocket: "unix:///var/run/crio/crio.sock",
+			err:       false,
+		},
 		{
 			name:      "unknown-type",
 			input:     "foobar:unix:///var/run/mke/containerd.sock",
@@ -65,3 +79,18 @@ func TestCRISocketParsing(t *testing.T) {
 	}
 
 }
+
+func TestNodeLabels(t *testing.T) {
+	profileLabels := map[string]string{"example.com/gpu": "true", "tier": "batch"}
+	require.Equal(t, "example.com/gpu=true,tier=web", nodeLabels(profileLabels, []string{"tier=web"}))
+	require.Equal(t, "", nodeLabels(nil, nil))
+}
+
+func TestNodeTaints(t *testing.T) {
+	profileTaints := []string{"example.com/gpu=true:NoSchedule"}
+	require.Equal(t, "example.com/gpu=true:NoSchedule,dedicated:PreferNoSchedule",
+		nodeTaints(profileTaints, []string{"dedicated:PreferNoSchedule", "example.com/gpu=true:NoSchedule"}))
+	require.Equal(t, "example.com/gpu=false:NoSchedule,example.com/gpu:NoExecute",
+		nodeTaints(profileTaints, []string{"example.com/gpu=false:NoSchedule", "example.com/gpu:NoExecute"}))
+	require.Equal(t, "", nodeTaints(nil, nil))
+}
@@ -18,11 +18,16 @@ package worker
 import (
 	"context"
 	"fmt"
+	"sort"
 
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/constant"
 	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // KubeletConfigClient is the client used to fetch kubelet config from a common config map
@@ -42,9 +47,32 @@ func NewKubeletConfigClient(kubeconfigPath string) (*KubeletConfigClient, error)
 	}, nil
 }
 
+// newKubeletConfigClientFor creates a KubeletConfigClient talking to the given server with the credentials of the
+// kubeconfig. The requests time out, so that a dead server fails them instead of hanging them.
+func newKubeletConfigClientFor(kubeconfigPath, server string) (*KubeletConfigClient, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags(server, kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	restConfig.Timeout = apiServerTimeout
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	return &KubeletConfigClient{
+		kubeClient: kubeClient,
+	}, nil
+}
+
+// ping checks that the API server can be reached
+func (k *KubeletConfigClient) ping() error {
+	_, err := k.kubeClient.Discovery().ServerVersion()
+	return err
+}
+
 // Get reads the config from kube api
 func (k *KubeletConfigClient) Get(profile string) (string, error) {
-	cmName := fmt.Sprintf("kubelet-config-%s-%s", profile, constant.KubernetesMajorMinorVersion)
+	cmName := configMapName(profile)
 	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to get kubelet config from API: %w", err)
@@ -55,3 +83,195 @@ func (k *KubeletConfigClient) Get(profile string) (string, error) {
 	}
 	return config, nil
 }
+
+// SeccompProfiles reads the seccomp profiles, keyed by file name, distributed with the profile
+func (k *KubeletConfigClient) SeccompProfiles(profile string) (map[string]string, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	profiles := map[string]string{}
+	if err := yaml.Unmarshal([]byte(cm.Data["seccomp"]), &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse seccomp profiles in %s: %w", cmName, err)
+	}
+	return profiles, nil
+}
+
+// ReadinessGate reads the readiness gate of the profile, nil if the profile has none
+func (k *KubeletConfigClient) ReadinessGate(profile string) (*config.ReadinessGateSpec, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	data, found := cm.Data["readinessGate"]
+	if !found {
+		return nil, nil
+	}
+	spec := &config.ReadinessGateSpec{}
+	if err := yaml.Unmarshal([]byte(data), spec); err != nil {
+		return nil, fmt.Errorf("failed to parse readiness gate in %s: %w", cmName, err)
+	}
+	return spec, nil
+}
+
+// Registries reads the registry settings of the profile, nil if the profile has none
+func (k *KubeletConfigClient) Registries(profile string) (*config.RegistriesSpec, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	data, found := cm.Data["registries"]
+	if !found {
+		return nil, nil
+	}
+	spec := &config.RegistriesSpec{}
+	if err := yaml.Unmarshal([]byte(data), spec); err != nil {
+		return nil, fmt.Errorf("failed to parse the registries in %s: %w", cmName, err)
+	}
+	return spec, nil
+}
+
+// NetworkCIDRs reads the cluster CIDRs published with the profile, nil if the controllers don't publish them yet
+func (k *KubeletConfigClient) NetworkCIDRs(profile string) (*config.NetworkCIDRs, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	data, found := cm.Data["network"]
+	if !found {
+		return nil, nil
+	}
+	cidrs := &config.NetworkCIDRs{}
+	if err := yaml.Unmarshal([]byte(data), cidrs); err != nil {
+		return nil, fmt.Errorf("failed to parse the network CIDRs in %s: %w", cmName, err)
+	}
+	return cidrs, nil
+}
+
+// NodeLabels reads the node labels of the profile, nil if the profile has none
+func (k *KubeletConfigClient) NodeLabels(profile string) (map[string]string, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	data, found := cm.Data["nodeLabels"]
+	if !found {
+		return nil, nil
+	}
+	labels := map[string]string{}
+	if err := yaml.Unmarshal([]byte(data), &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse the node labels in %s: %w", cmName, err)
+	}
+	return labels, nil
+}
+
+// NodeTaints reads the node taints of the profile as key=value:Effect, nil if the profile has none
+func (k *KubeletConfigClient) NodeTaints(profile string) ([]string, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	data, found := cm.Data["nodeTaints"]
+	if !found {
+		return nil, nil
+	}
+	var taints []string
+	if err := yaml.Unmarshal([]byte(data), &taints); err != nil {
+		return nil, fmt.Errorf("failed to parse the node taints in %s: %w", cmName, err)
+	}
+	return taints, nil
+}
+
+// FirewallRules reads the ports of the worker published with the profile, nil if the firewall isn't managed
+func (k *KubeletConfigClient) FirewallRules(profile string) (*config.FirewallRules, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	data, found := cm.Data["firewall"]
+	if !found {
+		return nil, nil
+	}
+	rules := &config.FirewallRules{}
+	if err := yaml.Unmarshal([]byte(data), rules); err != nil {
+		return nil, fmt.Errorf("failed to parse the firewall rules in %s: %w", cmName, err)
+	}
+	return rules, nil
+}
+
+// PauseImage reads the sandbox image published with the profile, empty if the controllers don't publish it yet
+func (k *KubeletConfigClient) PauseImage(profile string) (string, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	return cm.Data["pauseImage"], nil
+}
+
+// CgroupDriver reads the cgroup driver of the kubelet config of the profile, cgroupfs if the profile doesn't set it
+func (k *KubeletConfigClient) CgroupDriver(profile string) (string, error) {
+	data, err := k.Get(profile)
+	if err != nil {
+		return "", err
+	}
+	kubeletConfig := struct {
+		CgroupDriver string `yaml:"cgroupDriver"`
+	}{}
+	if err := yaml.Unmarshal([]byte(data), &kubeletConfig); err != nil {
+		return "", fmt.Errorf("failed to parse the kubelet config of profile %s: %w", profile, err)
+	}
+	if kubeletConfig.CgroupDriver == "" {
+		return config.CgroupDriverCgroupfs, nil
+	}
+	return kubeletConfig.CgroupDriver, nil
+}
+
+// CABundle reads the CA bundle published with the profile, empty if the controllers don't publish it yet
+func (k *KubeletConfigClient) CABundle(profile string) (string, error) {
+	cmName := configMapName(profile)
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), cmName, v1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubelet config from API: %w", err)
+	}
+	return cm.Data["caBundle"], nil
+}
+
+// ConnectionBrokerConfig reads the connection broker config published by the controllers, nil if the broker is not enabled
+func (k *KubeletConfigClient) ConnectionBrokerConfig() (map[string]string, error) {
+	cm, err := k.kubeClient.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), "k0s-connection-broker", v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection broker config from API: %w", err)
+	}
+	return cm.Data, nil
+}
+
+// ControllerAddresses reads the addresses of the apiservers from the endpoints of the kubernetes service
+func (k *KubeletConfigClient) ControllerAddresses() ([]string, error) {
+	endpoints, err := k.kubeClient.CoreV1().Endpoints("default").Get(context.TODO(), "kubernetes", v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the kubernetes service endpoints from API: %w", err)
+	}
+	var addresses []string
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			addresses = append(addresses, address.IP)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses, nil
+}
+
+func configMapName(profile string) string {
+	return fmt.Sprintf("kubelet-config-%s-%s", profile, constant.KubernetesMajorMinorVersion)
+}
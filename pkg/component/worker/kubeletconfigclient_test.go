@@ -0,0 +1,61 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCgroupDriver(t *testing.T) {
+	profileConfigMap := func(profile, kubeletConfig string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: configMapName(profile), Namespace: "kube-system"},
+			Data:       map[string]string{"kubelet": kubeletConfig},
+		}
+	}
+	client := &KubeletConfigClient{kubeClient: fake.NewSimpleClientset(
+		profileConfigMap("default", "kind: KubeletConfiguration\nmaxPods: 110\n"),
+		profileConfigMap("systemd", "kind: KubeletConfiguration\ncgroupDriver: systemd\n"),
+	)}
+
+	driver, err := client.CgroupDriver("default")
+	require.NoError(t, err)
+	assert.Equal(t, "cgroupfs", driver)
+
+	driver, err = client.CgroupDriver("systemd")
+	require.NoError(t, err)
+	assert.Equal(t, "systemd", driver)
+
+	_, err = client.CgroupDriver("missing")
+	assert.Error(t, err)
+}
+
+func TestCgroupDriverConfig(t *testing.T) {
+	assert.Empty(t, cgroupDriverConfig("cgroupfs"))
+	assert.Equal(t, `
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
+  SystemdCgroup = true`, cgroupDriverConfig("systemd"))
+}
@@ -44,11 +44,13 @@ func (k KubeProxy) Run() error {
 		"--feature-gates=WinOverlay=true",
 	}
 	k.supervisor = supervisor.Supervisor{
-		Name:    cmd,
-		BinPath: assets.BinPath(cmd, k.K0sVars.BinDir),
-		RunDir:  k.K0sVars.RunDir,
-		DataDir: k.K0sVars.DataDir,
-		Args:    args,
+		Name:     cmd,
+		BinPath:  assets.BinPath(cmd, k.K0sVars.BinDir),
+		RunDir:   k.K0sVars.RunDir,
+		DataDir:  k.K0sVars.DataDir,
+		CrashDir: k.K0sVars.CrashDir,
+		ArgsDir:  k.K0sVars.ComponentArgsDir,
+		Args:     args,
 	}
 	k.supervisor.Supervise()
 	return nil
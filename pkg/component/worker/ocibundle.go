@@ -1,30 +1,44 @@
 package worker
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"github.com/avast/retry-go"
 	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/reference/docker"
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/diskspace"
 	"github.com/sirupsen/logrus"
+	"io"
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 )
 
 // OCIBundleReconciler tries to import OCI bundle into the running containerd instance
 type OCIBundleReconciler struct {
-	k0sVars constant.CfgVars
-	log     *logrus.Entry
+	k0sVars     constant.CfgVars
+	socket      string
+	pauseImage  string
+	diskMonitor *diskspace.Monitor
+	log         *logrus.Entry
 }
 
-// NewOCIBundleReconciler builds new reconciler
-func NewOCIBundleReconciler(vars constant.CfgVars) *OCIBundleReconciler {
+// NewOCIBundleReconciler builds new reconciler importing into the containerd on the given socket, the imports are
+// skipped while the disk monitor is degraded. The pause image is pulled after the imports if no bundle had it, so
+// the kubelet doesn't start before the pod sandboxes can be created.
+func NewOCIBundleReconciler(vars constant.CfgVars, socket string, pauseImage string, diskMonitor *diskspace.Monitor) *OCIBundleReconciler {
 	return &OCIBundleReconciler{
-		k0sVars: vars,
-		log:     logrus.WithField("component", "OCIBundleReconciler"),
+		k0sVars:     vars,
+		socket:      socket,
+		pauseImage:  pauseImage,
+		diskMonitor: diskMonitor,
+		log:         logrus.WithField("component", "OCIBundleReconciler"),
 	}
 }
 
@@ -37,13 +51,17 @@ func (a *OCIBundleReconciler) Run() error {
 	if err != nil {
 		return fmt.Errorf("can't read bundles directory")
 	}
-	if len(files) == 0 {
+	if len(files) == 0 && a.pauseImage == "" {
+		return nil
+	}
+	if a.diskMonitor.Degraded() {
+		a.log.Warn("data dir is running out of disk space, skipping the OCI bundle imports")
 		return nil
 	}
 	var client *containerd.Client
-	sock := filepath.Join(a.k0sVars.RunDir, "containerd.sock")
+	sock := a.socket
 	err = retry.Do(func() error {
-		client, err = containerd.New(sock, containerd.WithDefaultNamespace("k8s.io"))
+		client, err = containerd.New(sock, containerd.WithDefaultNamespace(constant.ContainerdNamespace))
 		if err != nil {
 			logrus.WithError(err).Errorf("can't connect to containerd socket %s", sock)
 			return err
@@ -61,21 +79,67 @@ func (a *OCIBundleReconciler) Run() error {
 	defer client.Close()
 
 	for _, file := range files {
+		// the bundles are tarballs, the directories and hidden files are left alone
+		if !file.Mode().IsRegular() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
 		if err := a.unpackBundle(client, a.k0sVars.OCIBundleDir+"/"+file.Name()); err != nil {
 			logrus.WithError(err).Errorf("can't unpack bundle %s", file.Name())
 			return fmt.Errorf("can't unpack bundle %s: %w", file.Name(), err)
 		}
 	}
+	a.ensurePauseImage(client)
 	return nil
 }
 
+// ensurePauseImage pulls the pause image unless it's already in containerd. The failures are only logged, the
+// registry may become reachable later on and the kubelet pulls the image then.
+func (a *OCIBundleReconciler) ensurePauseImage(client *containerd.Client) {
+	if a.pauseImage == "" {
+		return
+	}
+	ref, err := docker.ParseDockerRef(a.pauseImage)
+	if err != nil {
+		a.log.WithError(err).Errorf("invalid pause image %s", a.pauseImage)
+		return
+	}
+	ctx := context.Background()
+	_, err = client.GetImage(ctx, ref.String())
+	if err == nil {
+		return
+	}
+	if !errdefs.IsNotFound(err) {
+		a.log.WithError(err).Errorf("can't look up the pause image %s", ref)
+		return
+	}
+
+	a.log.Infof("pulling the pause image %s", ref)
+	if _, err := client.Pull(ctx, ref.String(), containerd.WithPullUnpack); err != nil {
+		a.log.WithError(err).Errorf("pause image %s is not available, the pods can't be started until it's pulled. "+
+			"Nodes without registry access need it in an image bundle in %s", ref, a.k0sVars.OCIBundleDir)
+		return
+	}
+	a.log.Infof("pulled the pause image %s", ref)
+}
+
 func (a OCIBundleReconciler) unpackBundle(client *containerd.Client, bundlePath string) error {
 	r, err := os.Open(bundlePath)
 	if err != nil {
 		return fmt.Errorf("can't open bundle file %s: %v", bundlePath, err)
 	}
 	defer r.Close()
-	images, err := client.Import(context.Background(), r)
+	// gzipped bundles, as saved by docker save | gzip, are imported as well
+	br := bufio.NewReader(r)
+	var bundle io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("can't decompress bundle file %s: %v", bundlePath, err)
+		}
+		defer gr.Close()
+		bundle = gr
+	}
+	images, err := client.Import(context.Background(), bundle)
 	if err != nil {
 		return fmt.Errorf("can't import bundle: %v", err)
 	}
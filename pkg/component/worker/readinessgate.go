@@ -0,0 +1,303 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/container/runtime"
+)
+
+// readinessGateAnnotation marks the nodes cordoned by the readiness gate, the nodes cordoned by the admins are
+// never uncordoned by it
+const readinessGateAnnotation = "k0sproject.io/readiness-gate"
+
+const (
+	readinessGateInterval = 5 * time.Second
+	readinessGateTimeout  = 10 * time.Second
+)
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context, node *corev1.Node) error
+}
+
+// ReadinessGate keeps the node cordoned after a restart of the worker until the local health checks of the worker
+// profile pass. The node is cordoned when the worker shuts down gracefully, and when it starts, in case the worker
+// didn't shut down gracefully. It needs to be stopped before the kubelet, so that the node can still be cordoned.
+type ReadinessGate struct {
+	CRISocket           string
+	K0sVars             constant.CfgVars
+	KubeletConfigClient *KubeletConfigClient
+	Profile             string
+
+	log      *logrus.Entry
+	spec     *config.ReadinessGateSpec
+	cordoned bool
+	failing  string
+	stopCh   chan struct{}
+}
+
+// Init does nothing
+func (g *ReadinessGate) Init() error {
+	g.log = logrus.WithField("component", "readiness-gate")
+	return nil
+}
+
+// Run starts gating the node if the worker profile has a readiness gate
+func (g *ReadinessGate) Run() error {
+	spec, err := g.KubeletConfigClient.ReadinessGate(g.Profile)
+	if err != nil {
+		return err
+	}
+	if spec == nil {
+		return nil
+	}
+	g.spec = spec
+
+	checks, err := g.checks()
+	if err != nil {
+		return err
+	}
+	g.stopCh = make(chan struct{})
+	go g.gate(checks)
+	return nil
+}
+
+// Stop cordons the node, it's uncordoned once the health checks pass again after the restart
+func (g *ReadinessGate) Stop() error {
+	if g.spec == nil {
+		return nil
+	}
+	close(g.stopCh)
+
+	client, nodeName, err := g.kubeClient()
+	if err != nil {
+		return fmt.Errorf("failed to cordon the node: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readinessGateTimeout)
+	defer cancel()
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to cordon node %s: %w", nodeName, err)
+	}
+	if _, err := g.cordon(ctx, client, node); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (g *ReadinessGate) Healthy() error { return nil }
+
+func (g *ReadinessGate) gate(checks []readinessCheck) {
+	ticker := time.NewTicker(readinessGateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			client, nodeName, err := g.kubeClient()
+			if err != nil {
+				// the kubelet hasn't bootstrapped its client certificate yet
+				g.log.Debugf("readiness gate waiting for the kubelet credentials: %v", err)
+				continue
+			}
+			done, err := g.reconcile(client, nodeName, checks)
+			if err != nil {
+				g.log.Warnf("readiness gate: %v", err)
+			}
+			if done {
+				return
+			}
+		case <-g.stopCh:
+			return
+		}
+	}
+}
+
+// reconcile cordons the node if needed, and uncordons it once all the checks pass. It returns true once there's
+// nothing left to do.
+func (g *ReadinessGate) reconcile(client kubernetes.Interface, nodeName string, checks []readinessCheck) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), readinessGateTimeout)
+	defer cancel()
+
+	node, err := client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// not registered yet
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+	if !g.cordoned {
+		cordoned, err := g.cordon(ctx, client, node)
+		if err != nil {
+			return false, err
+		}
+		if !cordoned {
+			g.log.Infof("node %s has been cordoned by someone else, leaving it cordoned", nodeName)
+			return true, nil
+		}
+		g.cordoned = true
+	}
+
+	for _, c := range checks {
+		if err := c.check(ctx, node); err != nil {
+			if g.failing != c.name {
+				g.log.Infof("keeping node %s cordoned, %s check failed: %v", nodeName, c.name, err)
+				g.failing = c.name
+			}
+			return false, nil
+		}
+	}
+
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null}},"spec":{"unschedulable":false}}`, readinessGateAnnotation)
+	if _, err := client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return false, fmt.Errorf("failed to uncordon node %s: %w", nodeName, err)
+	}
+	g.log.Infof("health checks passed, uncordoned node %s", nodeName)
+	return true, nil
+}
+
+// cordon cordons the node on behalf of the readiness gate. It returns false if the node has been cordoned by
+// someone else.
+func (g *ReadinessGate) cordon(ctx context.Context, client kubernetes.Interface, node *corev1.Node) (bool, error) {
+	if _, gated := node.Annotations[readinessGateAnnotation]; gated {
+		return true, nil
+	}
+	if node.Spec.Unschedulable {
+		return false, nil
+	}
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"cordoned"}},"spec":{"unschedulable":true}}`, readinessGateAnnotation)
+	if _, err := client.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+		return false, fmt.Errorf("failed to cordon node %s: %w", node.Name, err)
+	}
+	g.log.Infof("cordoned node %s until the health checks pass", node.Name)
+	return true, nil
+}
+
+// kubeClient returns a client with the credentials of the kubelet, along with the node name
+func (g *ReadinessGate) kubeClient() (kubernetes.Interface, string, error) {
+	nodeName, err := NodeName(g.K0sVars)
+	if err != nil {
+		return nil, "", err
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", g.K0sVars.KubeletAuthConfigPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubelet kubeconfig: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, nodeName, nil
+}
+
+// checks returns the health checks enabled in the readiness gate
+func (g *ReadinessGate) checks() ([]readinessCheck, error) {
+	var checks []readinessCheck
+	for _, name := range g.spec.EnabledChecks() {
+		switch name {
+		case config.ReadinessCheckCRI:
+			checks = append(checks, readinessCheck{name: name, check: g.checkCRI})
+		case config.ReadinessCheckCNI:
+			checks = append(checks, readinessCheck{name: name, check: checkNetworkReady})
+		case config.ReadinessCheckDNS:
+			checks = append(checks, readinessCheck{name: name, check: g.checkDNS})
+		default:
+			return nil, fmt.Errorf("unknown readiness check %s", name)
+		}
+	}
+	return checks, nil
+}
+
+// checkCRI checks that the container runtime responds
+func (g *ReadinessGate) checkCRI(ctx context.Context, _ *corev1.Node) error {
+	rtType, rtSock := "cri", fmt.Sprintf("unix://%s", filepath.Join(g.K0sVars.RunDir, "containerd.sock"))
+	if g.CRISocket != "" {
+		var err error
+		if rtType, rtSock, err = SplitRuntimeConfig(g.CRISocket); err != nil {
+			return err
+		}
+	}
+	_, err := runtime.NewContainerRuntime(rtType, rtSock).ListContainers(ctx)
+	return err
+}
+
+// checkNetworkReady checks that the node is ready, the kubelet reports the node not ready until the CNI plugin
+// has been initialized
+func checkNetworkReady(_ context.Context, node *corev1.Node) error {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			if c.Status != corev1.ConditionTrue {
+				return fmt.Errorf("node is not ready: %s", c.Message)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("node has no ready condition yet")
+}
+
+// checkDNS resolves the DNS name of the readiness gate through the cluster DNS configured for the kubelet
+func (g *ReadinessGate) checkDNS(ctx context.Context, _ *corev1.Node) error {
+	data, err := ioutil.ReadFile(filepath.Join(g.K0sVars.DataDir, "kubelet-config.yaml"))
+	if err != nil {
+		return err
+	}
+	kubeletConfig := struct {
+		ClusterDNS    []string `yaml:"clusterDNS"`
+		ClusterDomain string   `yaml:"clusterDomain"`
+	}{}
+	if err := yaml.Unmarshal(data, &kubeletConfig); err != nil {
+		return fmt.Errorf("failed to parse the kubelet config: %w", err)
+	}
+	if len(kubeletConfig.ClusterDNS) == 0 {
+		return fmt.Errorf("no cluster DNS configured for the kubelet")
+	}
+
+	name := g.spec.DNSName
+	if name == "" {
+		name = "kubernetes.default.svc." + kubeletConfig.ClusterDomain
+	}
+	server := net.JoinHostPort(kubeletConfig.ClusterDNS[0], "53")
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	if _, err := resolver.LookupHost(ctx, name); err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReadinessGate(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+	g := &ReadinessGate{}
+	require.NoError(t, g.Init())
+
+	checkErr := errors.New("not ready")
+	checks := []readinessCheck{{name: "cni", check: func(context.Context, *corev1.Node) error { return checkErr }}}
+
+	done, err := g.reconcile(client, "worker-1", checks)
+	require.NoError(t, err)
+	assert.False(t, done)
+	node, err := client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+	assert.Contains(t, node.Annotations, readinessGateAnnotation)
+
+	checkErr = nil
+	done, err = g.reconcile(client, "worker-1", checks)
+	require.NoError(t, err)
+	assert.True(t, done)
+	node, err = client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+	assert.NotContains(t, node.Annotations, readinessGateAnnotation)
+}
+
+func TestReadinessGateLeavesCordonedNodes(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	})
+	g := &ReadinessGate{}
+	require.NoError(t, g.Init())
+
+	done, err := g.reconcile(client, "worker-1", nil)
+	require.NoError(t, err)
+	assert.True(t, done)
+	node, err := client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+}
+
+func TestReadinessGateWaitsForRegistration(t *testing.T) {
+	g := &ReadinessGate{}
+	require.NoError(t, g.Init())
+	done, err := g.reconcile(fake.NewSimpleClientset(), "worker-1", nil)
+	assert.NoError(t, err)
+	assert.False(t, done)
+}
+
+func TestCheckNetworkReady(t *testing.T) {
+	node := &corev1.Node{}
+	assert.Error(t, checkNetworkReady(context.TODO(), node))
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Message: "network plugin is not ready"}}
+	assert.Error(t, checkNetworkReady(context.TODO(), node))
+	node.Status.Conditions[0].Status = corev1.ConditionTrue
+	assert.NoError(t, checkNetworkReady(context.TODO(), node))
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+const criRegistryTable = `plugins."io.containerd.grpc.v1.cri".registry`
+
+// registriesConfig renders the registry settings as the tables of the CRI plugin of the containerd config. The
+// credentials files are read here, so that the credentials only end up in the containerd config on the worker.
+func registriesConfig(spec *config.RegistriesSpec) (string, error) {
+	if spec == nil {
+		return "", nil
+	}
+	var b strings.Builder
+	mirrors := make([]string, 0, len(spec.Mirrors))
+	for host := range spec.Mirrors {
+		mirrors = append(mirrors, host)
+	}
+	sort.Strings(mirrors)
+	for _, host := range mirrors {
+		endpoints := make([]string, 0, len(spec.Mirrors[host]))
+		for _, endpoint := range spec.Mirrors[host] {
+			endpoints = append(endpoints, tomlQuote(endpoint))
+		}
+		fmt.Fprintf(&b, "\n[%s.mirrors.%s]\n", criRegistryTable, tomlQuote(host))
+		fmt.Fprintf(&b, "  endpoint = [%s]\n", strings.Join(endpoints, ", "))
+	}
+
+	hosts := make([]string, 0, len(spec.Configs))
+	for host := range spec.Configs {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		c := spec.Configs[host]
+		var tls []string
+		if c.InsecureSkipVerify {
+			tls = append(tls, "insecure_skip_verify = true")
+		}
+		for key, path := range map[string]string{"ca_file": c.CAFile, "cert_file": c.CertFile, "key_file": c.KeyFile} {
+			if path != "" {
+				tls = append(tls, key+" = "+tomlQuote(path))
+			}
+		}
+		sort.Strings(tls)
+		writeTable(&b, fmt.Sprintf("%s.configs.%s.tls", criRegistryTable, tomlQuote(host)), tls)
+
+		username, password := c.Username, c.Password
+		if c.CredentialsFile != "" {
+			data, err := ioutil.ReadFile(c.CredentialsFile)
+			if err != nil {
+				return "", fmt.Errorf("can't read the credentials of registry %s: %w", host, err)
+			}
+			var found bool
+			username, password, found = cut(strings.TrimSpace(string(data)), ":")
+			if !found {
+				return "", fmt.Errorf("the credentials file of registry %s must hold username:password", host)
+			}
+		}
+		var auth []string
+		if username != "" || password != "" {
+			auth = append(auth, "username = "+tomlQuote(username), "password = "+tomlQuote(password))
+		}
+		writeTable(&b, fmt.Sprintf("%s.configs.%s.auth", criRegistryTable, tomlQuote(host)), auth)
+	}
+	return b.String(), nil
+}
+
+func writeTable(b *strings.Builder, name string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n[%s]\n", name)
+	for _, line := range lines {
+		fmt.Fprintf(b, "  %s\n", line)
+	}
+}
+
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// tomlQuote quotes the string as a TOML basic string. The control characters are escaped as \uXXXX, TOML doesn't know
+// the \xXX escapes of Go, and the other characters are kept as UTF-8.
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			// invalid UTF-8 is written as the replacement character, TOML doesn't allow it either
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
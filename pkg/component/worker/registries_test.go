@@ -0,0 +1,97 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestRegistriesConfig(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		out, err := registriesConfig(nil)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("mirrors_and_configs", func(t *testing.T) {
+		out, err := registriesConfig(&config.RegistriesSpec{
+			Mirrors: map[string][]string{
+				"docker.io":        {"https://mirror.example.com", "https://registry-1.docker.io"},
+				"registry.example": {"http://10.0.0.5:5000"},
+			},
+			Configs: map[string]config.RegistryConfig{
+				"mirror.example.com": {CAFile: "/etc/ssl/mirror-ca.crt", Username: "k0s", Password: `se"cret`},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+  endpoint = ["https://mirror.example.com", "https://registry-1.docker.io"]
+
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors."registry.example"]
+  endpoint = ["http://10.0.0.5:5000"]
+
+[plugins."io.containerd.grpc.v1.cri".registry.configs."mirror.example.com".tls]
+  ca_file = "/etc/ssl/mirror-ca.crt"
+
+[plugins."io.containerd.grpc.v1.cri".registry.configs."mirror.example.com".auth]
+  username = "k0s"
+  password = "se\"cret"
+`, out)
+	})
+
+	t.Run("credentials_file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "k0s-registries-test")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		credentials := filepath.Join(dir, "credentials")
+		require.NoError(t, ioutil.WriteFile(credentials, []byte("robot:t0ken:with:colons\n"), 0600))
+
+		out, err := registriesConfig(&config.RegistriesSpec{
+			Configs: map[string]config.RegistryConfig{
+				"registry.example": {CredentialsFile: credentials, InsecureSkipVerify: true},
+			},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, out, "  insecure_skip_verify = true\n")
+		assert.Contains(t, out, "  username = \"robot\"\n  password = \"t0ken:with:colons\"\n")
+
+		require.NoError(t, ioutil.WriteFile(credentials, []byte("no-password"), 0600))
+		_, err = registriesConfig(&config.RegistriesSpec{
+			Configs: map[string]config.RegistryConfig{"registry.example": {CredentialsFile: credentials}},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestTOMLQuote(t *testing.T) {
+	for _, s := range []string{"", "k0s", `se"cr\et`, "tab\tnew\nline", "bell\x07del\x7f", "päss"} {
+		var decoded struct{ Value string }
+		_, err := toml.Decode("value = "+tomlQuote(s), &decoded)
+		require.NoError(t, err, s)
+		assert.Equal(t, s, decoded.Value)
+	}
+	assert.Equal(t, `"bell\u0007"`, tomlQuote("bell\x07"))
+}
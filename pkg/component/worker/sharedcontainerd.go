@@ -0,0 +1,87 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/container/runtime"
+)
+
+// sharedContainerdTimeout bounds the detection of the containerd behind a remote CRI socket
+const sharedContainerdTimeout = 10 * time.Second
+
+// SharedContainerd is a containerd of the host used through a remote CRI socket, e.g. the containerd of Docker. k0s
+// doesn't manage it, nor touch its config.
+type SharedContainerd struct {
+	// Address is the socket of the containerd API
+	Address string
+	// SandboxImage is the pause image of the pod sandboxes configured in containerd
+	SandboxImage string
+}
+
+// DetectSharedContainerd checks the containerd behind a remote CRI socket. Its CRI plugin must be enabled, the
+// containerd shipped with Docker disables it. nil is returned if the socket isn't served by containerd.
+func DetectSharedContainerd(criSocket string) (*SharedContainerd, error) {
+	if criSocket == "" {
+		return nil, nil
+	}
+	rtType, rtSock, err := SplitRuntimeConfig(criSocket)
+	if err != nil || rtType != "remote" || !strings.HasPrefix(rtSock, "unix://") {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sharedContainerdTimeout)
+	defer cancel()
+	address := strings.TrimPrefix(rtSock, "unix://")
+	client, err := containerd.New(address, containerd.WithDefaultNamespace(constant.ContainerdNamespace), containerd.WithTimeout(sharedContainerdTimeout))
+	if err != nil {
+		logrus.Debugf("%s isn't served by containerd: %v", rtSock, err)
+		return nil, nil
+	}
+	defer client.Close()
+	version, err := client.Version(ctx)
+	if err != nil {
+		logrus.Debugf("%s isn't served by containerd: %v", rtSock, err)
+		return nil, nil
+	}
+
+	plugins, err := client.IntrospectionService().Plugins(ctx, []string{`type=="io.containerd.grpc.v1",id=="cri"`})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the plugins of containerd at %s: %w", address, err)
+	}
+	if len(plugins.Plugins) == 0 {
+		return nil, fmt.Errorf("the CRI plugin of containerd at %s is disabled, remove \"cri\" from disabled_plugins in its config and restart it", address)
+	}
+	if initErr := plugins.Plugins[0].InitErr; initErr != nil {
+		return nil, fmt.Errorf("the CRI plugin of containerd at %s failed to start: %s", address, initErr.Message)
+	}
+
+	shared := &SharedContainerd{Address: address}
+	cri := runtime.NewContainerRuntime(rtType, rtSock).(*runtime.ContainerdRuntime)
+	if shared.SandboxImage, err = cri.SandboxImage(ctx); err != nil {
+		logrus.Warnf("failed to get the sandbox image of containerd at %s: %v", address, err)
+	}
+	logrus.Infof("using the containerd %s at %s, sandbox image %s", version.Version, address, shared.SandboxImage)
+	return shared, nil
+}
@@ -0,0 +1,475 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/certificate/csr"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/utils/pointer"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/build"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// SimulatedNodeLabel marks the nodes registered by a worker in simulation mode
+const SimulatedNodeLabel = "k0sproject.io/simulated"
+
+const (
+	simulationLeaseDuration  = 40
+	simulationLeaseInterval  = 10 * time.Second
+	simulationStatusInterval = time.Minute
+	simulationPodInterval    = 5 * time.Second
+	simulationTimeout        = 10 * time.Second
+	// certificateRequestTimeout bounds the wait for the client certificate, which the controllers approve
+	certificateRequestTimeout = 5 * time.Minute
+	// simulationConcurrentJoins keeps the nodes from flooding the controllers with CSRs
+	simulationConcurrentJoins = 10
+)
+
+// simulatedCapacity is the capacity of each simulated node
+var simulatedCapacity = corev1.ResourceList{
+	corev1.ResourceCPU:    resource.MustParse("32"),
+	corev1.ResourceMemory: resource.MustParse("256Gi"),
+	corev1.ResourcePods:   resource.MustParse("110"),
+}
+
+// SimulatedNodes registers hollow nodes for the scale testing of the control plane. Each node joins the cluster
+// through the bootstrap flow of the kubelet, with a client certificate of its own, keeps its lease and its status
+// up to date and reports the pods scheduled to it as running, without running anything.
+type SimulatedNodes struct {
+	K0sVars   constant.CfgVars
+	Count     int
+	Labels    []string
+	Ephemeral bool
+
+	log    *logrus.Entry
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	nodes []*hollowNode
+}
+
+type hollowNode struct {
+	name    string
+	labels  map[string]string
+	client  kubernetes.Interface
+	log     *logrus.Entry
+	podCIDR *net.IPNet
+	nextIP  int
+}
+
+// Init checks the count
+func (s *SimulatedNodes) Init() error {
+	s.log = logrus.WithField("component", "simulated-nodes")
+	if s.Count <= 0 {
+		return fmt.Errorf("the number of simulated nodes needs to be positive, got %d", s.Count)
+	}
+	return nil
+}
+
+// Run joins the nodes in the background and keeps them alive
+func (s *SimulatedNodes) Run() error {
+	bootstrapConfig, err := clientcmd.BuildConfigFromFlags("", s.K0sVars.KubeletBootstrapConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to load the kubelet bootstrap kubeconfig: %w", err)
+	}
+	bootstrapClient, err := kubernetes.NewForConfig(bootstrapConfig)
+	if err != nil {
+		return err
+	}
+	prefix, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	labels := simulatedNodeLabels(s.Labels)
+	if err := util.InitDirectory(s.credentialsDir(), constant.CertRootDirMode); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	joins := make(chan struct{}, simulationConcurrentJoins)
+	for i := 0; i < s.Count; i++ {
+		name := fmt.Sprintf("%s-sim-%d", strings.ToLower(prefix), i)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			joins <- struct{}{}
+			node, err := s.join(ctx, bootstrapConfig, bootstrapClient, name, labels)
+			<-joins
+			if err != nil {
+				s.log.Errorf("failed to join simulated node %s: %v", name, err)
+				return
+			}
+			s.mu.Lock()
+			s.nodes = append(s.nodes, node)
+			s.mu.Unlock()
+			node.run(ctx)
+		}()
+	}
+	s.log.Infof("joining %d simulated nodes", s.Count)
+	return nil
+}
+
+// Stop stops the heartbeats of the nodes, which become NotReady, or deletes them if they are ephemeral
+func (s *SimulatedNodes) Stop() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	s.wg.Wait()
+	if !s.Ephemeral {
+		return nil
+	}
+	for _, node := range s.nodes {
+		ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+		err := node.client.CoreV1().Nodes().Delete(ctx, node.name, metav1.DeleteOptions{})
+		cancel()
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to deregister simulated node %s: %w", node.name, err)
+		}
+	}
+	s.log.Infof("deregistered %d simulated nodes", len(s.nodes))
+	return os.RemoveAll(s.credentialsDir())
+}
+
+// Healthy dummy implementation
+func (s *SimulatedNodes) Healthy() error { return nil }
+
+func (s *SimulatedNodes) credentialsDir() string {
+	return filepath.Join(s.K0sVars.DataDir, "simulated-nodes")
+}
+
+// join returns the node with its client certificate, which is requested with the bootstrap token unless the one
+// of a previous run is still valid
+func (s *SimulatedNodes) join(ctx context.Context, bootstrapConfig *rest.Config, bootstrapClient kubernetes.Interface, name string, labels map[string]string) (*hollowNode, error) {
+	path := filepath.Join(s.credentialsDir(), name+".pem")
+	data, err := ioutil.ReadFile(path)
+	if err != nil || !certValid(data, time.Now().Add(24*time.Hour)) {
+		if data, err = requestNodeCertificate(ctx, bootstrapClient, name); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(path, data, constant.CertSecureMode); err != nil {
+			return nil, err
+		}
+	}
+
+	config := rest.AnonymousClientConfig(bootstrapConfig)
+	config.CertData = data
+	config.KeyData = data
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &hollowNode{
+		name:   name,
+		labels: labels,
+		client: client,
+		log:    s.log.WithField("node", name),
+	}, nil
+}
+
+// requestNodeCertificate requests the client certificate of the node the way the kubelet does, the CSR is approved
+// by the controllers as the bootstrap token and the nodes themselves are allowed to request node client certificates
+func requestNodeCertificate(ctx context.Context, client kubernetes.Interface, name string) ([]byte, error) {
+	keyPEM, err := keyutil.MakeEllipticPrivateKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	key, err := keyutil.ParsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	csrPEM, err := cert.MakeCSR(key, &pkix.Name{CommonName: "system:node:" + name, Organization: []string{"system:nodes"}}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	usages := []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageKeyEncipherment, certificatesv1.UsageClientAuth}
+	reqName, reqUID, err := csr.RequestCertificate(client, csrPEM, "", certificatesv1.KubeAPIServerClientKubeletSignerName, usages, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request the client certificate: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, certificateRequestTimeout)
+	defer cancel()
+	certPEM, err := csr.WaitForCertificate(ctx, client, reqName, reqUID)
+	if err != nil {
+		return nil, fmt.Errorf("client certificate request %s wasn't approved: %w", reqName, err)
+	}
+	return append(certPEM, keyPEM...), nil
+}
+
+// certValid returns true if the first certificate of the PEM data is valid until the given time
+func certValid(data []byte, until time.Time) bool {
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		return err == nil && until.Before(c.NotAfter)
+	}
+	return false
+}
+
+// simulatedNodeLabels returns the labels of the nodes, the given ones being key=value pairs
+func simulatedNodeLabels(extra []string) map[string]string {
+	labels := map[string]string{
+		"kubernetes.io/os":   "linux",
+		"kubernetes.io/arch": goruntime.GOARCH,
+		SimulatedNodeLabel:   "true",
+	}
+	for _, l := range extra {
+		if i := strings.Index(l, "="); i > 0 {
+			labels[l[:i]] = l[i+1:]
+		}
+	}
+	return labels
+}
+
+func (n *hollowNode) run(ctx context.Context) {
+	if err := n.register(ctx); err != nil {
+		n.log.Errorf("failed to register: %v", err)
+		return
+	}
+	n.log.Info("registered simulated node")
+
+	leaseTicker := time.NewTicker(simulationLeaseInterval)
+	defer leaseTicker.Stop()
+	statusTicker := time.NewTicker(simulationStatusInterval)
+	defer statusTicker.Stop()
+	podTicker := time.NewTicker(simulationPodInterval)
+	defer podTicker.Stop()
+	n.renewLease(ctx)
+	for {
+		select {
+		case <-leaseTicker.C:
+			n.renewLease(ctx)
+		case <-statusTicker.C:
+			if err := n.updateStatus(ctx); err != nil {
+				n.log.Warnf("failed to update the node status: %v", err)
+			}
+		case <-podTicker.C:
+			if err := n.syncPods(ctx); err != nil {
+				n.log.Warnf("failed to sync the pods: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// register creates the node object, the NodeRestriction admission plugin only lets the node create itself
+func (n *hollowNode) register(ctx context.Context) error {
+	labels := map[string]string{"kubernetes.io/hostname": n.name}
+	for k, v := range n.labels {
+		labels[k] = v
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: n.name, Labels: labels}}
+	reqCtx, cancel := context.WithTimeout(ctx, simulationTimeout)
+	defer cancel()
+	if _, err := n.client.CoreV1().Nodes().Create(reqCtx, node, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return n.updateStatus(ctx)
+}
+
+func (n *hollowNode) updateStatus(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, simulationTimeout)
+	defer cancel()
+	node, err := n.client.CoreV1().Nodes().Get(ctx, n.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if node.Spec.PodCIDR != "" {
+		_, n.podCIDR, _ = net.ParseCIDR(node.Spec.PodCIDR)
+	}
+	node.Status = simulatedNodeStatus(node.Status, metav1.Now())
+	_, err = n.client.CoreV1().Nodes().UpdateStatus(ctx, node, metav1.UpdateOptions{})
+	return err
+}
+
+// simulatedNodeStatus returns the status of a healthy node, the transition times of the conditions are kept
+func simulatedNodeStatus(current corev1.NodeStatus, now metav1.Time) corev1.NodeStatus {
+	kubeletVersion := "v" + build.KubernetesVersion
+	if build.KubernetesVersion == "" {
+		kubeletVersion = "v" + constant.KubernetesMajorMinorVersion + ".0"
+	}
+	conditions := []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue, Reason: "KubeletReady", Message: "simulated node is ready"},
+		{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse, Reason: "KubeletHasSufficientMemory"},
+		{Type: corev1.NodeDiskPressure, Status: corev1.ConditionFalse, Reason: "KubeletHasNoDiskPressure"},
+		{Type: corev1.NodePIDPressure, Status: corev1.ConditionFalse, Reason: "KubeletHasSufficientPID"},
+	}
+	for i := range conditions {
+		conditions[i].LastHeartbeatTime = now
+		conditions[i].LastTransitionTime = now
+		for _, c := range current.Conditions {
+			if c.Type == conditions[i].Type && c.Status == conditions[i].Status {
+				conditions[i].LastTransitionTime = c.LastTransitionTime
+			}
+		}
+	}
+	return corev1.NodeStatus{
+		Capacity:    simulatedCapacity,
+		Allocatable: simulatedCapacity,
+		Phase:       corev1.NodeRunning,
+		Conditions:  conditions,
+		NodeInfo: corev1.NodeSystemInfo{
+			KubeletVersion:          kubeletVersion,
+			KubeProxyVersion:        kubeletVersion,
+			ContainerRuntimeVersion: "simulated://" + build.Version,
+			OperatingSystem:         "linux",
+			Architecture:            goruntime.GOARCH,
+		},
+		Addresses: current.Addresses,
+	}
+}
+
+// renewLease keeps the node alive for the node lifecycle controller
+func (n *hollowNode) renewLease(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, simulationTimeout)
+	defer cancel()
+	leases := n.client.CoordinationV1().Leases(corev1.NamespaceNodeLease)
+	now := metav1.NewMicroTime(time.Now())
+	lease, err := leases.Get(ctx, n.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: n.name, Namespace: corev1.NamespaceNodeLease},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.StringPtr(n.name),
+				LeaseDurationSeconds: pointer.Int32Ptr(simulationLeaseDuration),
+				RenewTime:            &now,
+			},
+		}
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+	} else if err == nil {
+		lease.Spec.RenewTime = &now
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		n.log.Warnf("failed to renew the node lease: %v", err)
+	}
+}
+
+// syncPods reports the pods scheduled to the node as running, and removes the deleted ones right away
+func (n *hollowNode) syncPods(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, simulationTimeout)
+	defer cancel()
+	pods, err := n.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", n.name).String(),
+	})
+	if err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		switch {
+		case pod.DeletionTimestamp != nil:
+			err = n.client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: pointer.Int64Ptr(0)})
+			if apierrors.IsNotFound(err) {
+				err = nil
+			}
+		case pod.Status.Phase == corev1.PodPending:
+			pod.Status = simulatedPodStatus(pod, n.podIP(pod), metav1.Now())
+			_, err = n.client.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			n.log.Warnf("failed to sync pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// podIP hands out the addresses of the pod CIDR of the node, the host network pods and the nodes without a pod
+// CIDR get none
+func (n *hollowNode) podIP(pod *corev1.Pod) string {
+	if pod.Spec.HostNetwork || n.podCIDR == nil {
+		return ""
+	}
+	ones, bits := n.podCIDR.Mask.Size()
+	size := 1 << uint(bits-ones)
+	if bits-ones > 16 {
+		size = 1 << 16
+	}
+	if size < 4 {
+		return ""
+	}
+	// skip the network and the broadcast addresses
+	n.nextIP = n.nextIP%(size-2) + 1
+	ip := make(net.IP, len(n.podCIDR.IP))
+	copy(ip, n.podCIDR.IP)
+	for i, carry := len(ip)-1, n.nextIP; i >= 0 && carry > 0; i-- {
+		sum := int(ip[i]) + carry
+		ip[i] = byte(sum)
+		carry = sum >> 8
+	}
+	return ip.String()
+}
+
+// simulatedPodStatus returns the status of a pod whose containers are all running and ready
+func simulatedPodStatus(pod *corev1.Pod, podIP string, now metav1.Time) corev1.PodStatus {
+	status := corev1.PodStatus{
+		Phase:     corev1.PodRunning,
+		PodIP:     podIP,
+		StartTime: &now,
+		QOSClass:  pod.Status.QOSClass,
+	}
+	if podIP != "" {
+		status.PodIPs = []corev1.PodIP{{IP: podIP}}
+	}
+	for _, t := range []corev1.PodConditionType{corev1.PodScheduled, corev1.PodInitialized, corev1.ContainersReady, corev1.PodReady} {
+		status.Conditions = append(status.Conditions, corev1.PodCondition{Type: t, Status: corev1.ConditionTrue, LastTransitionTime: now})
+	}
+	for _, c := range pod.Spec.Containers {
+		status.ContainerStatuses = append(status.ContainerStatuses, corev1.ContainerStatus{
+			Name:        c.Name,
+			Image:       c.Image,
+			ImageID:     c.Image,
+			ContainerID: fmt.Sprintf("simulated://%s-%s", pod.UID, c.Name),
+			Ready:       true,
+			Started:     pointer.BoolPtr(true),
+			State:       corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: now}},
+		})
+	}
+	return status
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+func TestSimulatedNodeLabels(t *testing.T) {
+	labels := simulatedNodeLabels([]string{"tier=test", "invalid", "k0sproject.io/simulated=yes"})
+	assert.Equal(t, "test", labels["tier"])
+	assert.Equal(t, "yes", labels[SimulatedNodeLabel])
+	assert.Equal(t, "linux", labels["kubernetes.io/os"])
+	assert.NotContains(t, labels, "invalid")
+}
+
+func TestCertValid(t *testing.T) {
+	key, err := keyutil.MakeEllipticPrivateKeyPEM()
+	require.NoError(t, err)
+	certPEM, _, err := cert.GenerateSelfSignedCertKey("system:node:test", nil, nil)
+	require.NoError(t, err)
+
+	assert.True(t, certValid(append(key, certPEM...), time.Now().Add(24*time.Hour)))
+	assert.False(t, certValid(certPEM, time.Now().Add(10*365*24*time.Hour)))
+	assert.False(t, certValid(key, time.Now()))
+}
+
+func TestSimulatedNodeStatus(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.Now()
+	status := simulatedNodeStatus(corev1.NodeStatus{
+		Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: before},
+			{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, LastTransitionTime: before},
+		},
+	}, now)
+
+	require.Len(t, status.Conditions, 4)
+	for _, c := range status.Conditions {
+		assert.Equal(t, now, c.LastHeartbeatTime)
+		switch c.Type {
+		case corev1.NodeReady:
+			assert.Equal(t, before, c.LastTransitionTime, "the node stayed ready")
+		case corev1.NodeDiskPressure:
+			assert.Equal(t, corev1.ConditionFalse, c.Status)
+			assert.Equal(t, now, c.LastTransitionTime, "the disk pressure went away")
+		}
+	}
+	assert.Equal(t, simulatedCapacity, status.Allocatable)
+}
+
+func TestHollowNodePodIP(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("10.244.1.0/30")
+	n := &hollowNode{podCIDR: cidr}
+	pod := &corev1.Pod{}
+	assert.Equal(t, "10.244.1.1", n.podIP(pod))
+	assert.Equal(t, "10.244.1.2", n.podIP(pod))
+	assert.Equal(t, "10.244.1.1", n.podIP(pod), "the addresses are reused once the CIDR is exhausted")
+
+	pod.Spec.HostNetwork = true
+	assert.Empty(t, n.podIP(pod))
+	assert.Empty(t, (&hollowNode{}).podIP(&corev1.Pod{}))
+}
+
+func TestHollowNodeSyncPods(t *testing.T) {
+	deleted := metav1.Now()
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "sim-0", Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default", DeletionTimestamp: &deleted},
+			Spec:       corev1.PodSpec{NodeName: "sim-0"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	)
+	n := &hollowNode{name: "sim-0", client: client, log: logrus.WithField("node", "sim-0")}
+	require.NoError(t, n.syncPods(context.Background()))
+
+	pod, err := client.CoreV1().Pods("default").Get(context.Background(), "pending", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, corev1.PodRunning, pod.Status.Phase)
+	require.Len(t, pod.Status.ContainerStatuses, 1)
+	assert.True(t, pod.Status.ContainerStatuses[0].Ready)
+	assert.NotNil(t, pod.Status.ContainerStatuses[0].State.Running)
+
+	_, err = client.CoreV1().Pods("default").Get(context.Background(), "deleted", metav1.GetOptions{})
+	assert.Error(t, err)
+}
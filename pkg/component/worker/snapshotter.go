@@ -0,0 +1,76 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// The containerd snapshotters which can be selected for the embedded containerd
+const (
+	SnapshotterOverlayfs     = "overlayfs"
+	SnapshotterNative        = "native"
+	SnapshotterFuseOverlayfs = "fuse-overlayfs"
+	SnapshotterZFS           = "zfs"
+	SnapshotterBtrfs         = "btrfs"
+)
+
+// Snapshotters lists the snapshotters in the order they are suggested when the selected one isn't supported
+var Snapshotters = []string{SnapshotterOverlayfs, SnapshotterFuseOverlayfs, SnapshotterZFS, SnapshotterBtrfs, SnapshotterNative}
+
+// fuseOverlayfsPlugin is the binary of the proxy plugin serving the fuse-overlayfs snapshots to containerd
+const fuseOverlayfsPlugin = "containerd-fuse-overlayfs-grpc"
+
+// validateSnapshotter checks that the snapshotter is known, the empty one being the containerd default
+func validateSnapshotter(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, s := range Snapshotters {
+		if s == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown containerd snapshotter %q, use one of %s", name, strings.Join(Snapshotters, ", "))
+}
+
+// snapshotterRoot is the dir of the snapshots taken by the given snapshotter
+func snapshotterRoot(k0sVars constant.CfgVars, name string) string {
+	return filepath.Join(k0sVars.DataDir, "containerd", "io.containerd.snapshotter.v1."+name)
+}
+
+// fuseOverlayfsSocket is the socket the fuse-overlayfs proxy plugin listens on
+func fuseOverlayfsSocket(k0sVars constant.CfgVars) string {
+	return filepath.Join(k0sVars.RunDir, "containerd-fuse-overlayfs.sock")
+}
+
+// snapshotterConfig renders the containerd config selecting the snapshotter, fuse-overlayfs being served by a
+// proxy plugin as containerd doesn't have it built in
+func snapshotterConfig(k0sVars constant.CfgVars, name string) string {
+	if name == "" {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n[plugins.\"io.containerd.grpc.v1.cri\".containerd]\n  snapshotter = %q", name)
+	if name == SnapshotterFuseOverlayfs {
+		fmt.Fprintf(&b, "\n\n[proxy_plugins.%q]\n  type = \"snapshot\"\n  address = %q", name, fuseOverlayfsSocket(k0sVars))
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/k0sproject/k0s/internal/util"
+)
+
+// The statfs magic numbers of the filesystems the snapshotters depend on
+const (
+	btrfsSuperMagic = 0x9123683e
+	zfsSuperMagic   = 0x2fc12fc1
+)
+
+// checkSnapshotter checks that the kernel and the filesystem of the snapshot dir support the snapshotter
+func checkSnapshotter(name string, root string) error {
+	switch name {
+	case "", SnapshotterOverlayfs:
+		if !loadFilesystem("overlay") {
+			return fmt.Errorf("the kernel doesn't support overlayfs, load the overlay module or use the fuse-overlayfs or native snapshotter")
+		}
+		if magic, path, err := fsMagic(root); err == nil && magic == zfsSuperMagic {
+			return fmt.Errorf("%s is on zfs, which doesn't support overlayfs on top of it, use the zfs or fuse-overlayfs snapshotter", path)
+		}
+	case SnapshotterFuseOverlayfs:
+		if !util.FileExists("/dev/fuse") {
+			return fmt.Errorf("/dev/fuse is missing, load the fuse module to use the fuse-overlayfs snapshotter")
+		}
+		for _, bin := range []string{"fuse-overlayfs", fuseOverlayfsPlugin} {
+			if _, err := exec.LookPath(bin); err != nil {
+				return fmt.Errorf("%s is needed in the PATH for the fuse-overlayfs snapshotter", bin)
+			}
+		}
+	case SnapshotterZFS:
+		if !loadFilesystem("zfs") {
+			return fmt.Errorf("the kernel doesn't support zfs, load the zfs module to use the zfs snapshotter")
+		}
+		if magic, _, err := fsMagic(root); err != nil || magic != zfsSuperMagic {
+			return fmt.Errorf("%s isn't a zfs dataset, create one mounted there to use the zfs snapshotter", root)
+		}
+	case SnapshotterBtrfs:
+		if !loadFilesystem("btrfs") {
+			return fmt.Errorf("the kernel doesn't support btrfs, load the btrfs module to use the btrfs snapshotter")
+		}
+		if magic, path, err := fsMagic(root); err != nil || magic != btrfsSuperMagic {
+			return fmt.Errorf("%s isn't on btrfs, which the btrfs snapshotter needs", path)
+		}
+	}
+	return nil
+}
+
+// loadFilesystem tells if the kernel supports the filesystem, its module is loaded if it isn't listed yet
+func loadFilesystem(name string) bool {
+	if hasFilesystem(name) {
+		return true
+	}
+	modprobe(name)
+	return hasFilesystem(name)
+}
+
+// fsMagic returns the filesystem type of the path or of its nearest existing parent, which is returned as well
+func fsMagic(path string) (int64, string, error) {
+	for {
+		var stat syscall.Statfs_t
+		err := syscall.Statfs(path, &stat)
+		if err == nil {
+			return int64(stat.Type), path, nil
+		}
+		parent := filepath.Dir(path)
+		if !os.IsNotExist(err) || parent == path {
+			return 0, path, err
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,29 @@
+// +build !linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import "fmt"
+
+// checkSnapshotter only allows the default snapshotter, the others are linux only
+func checkSnapshotter(name string, root string) error {
+	if name != "" {
+		return fmt.Errorf("the %s snapshotter is only supported on linux", name)
+	}
+	return nil
+}
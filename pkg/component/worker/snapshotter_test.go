@@ -0,0 +1,61 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestValidateSnapshotter(t *testing.T) {
+	for _, name := range append([]string{""}, Snapshotters...) {
+		assert.NoError(t, validateSnapshotter(name), name)
+	}
+	assert.Error(t, validateSnapshotter("aufs"))
+}
+
+func TestSnapshotterConfig(t *testing.T) {
+	k0sVars := constant.CfgVars{DataDir: "/var/lib/k0s", RunDir: "/run/k0s"}
+
+	t.Run("default", func(t *testing.T) {
+		assert.Empty(t, snapshotterConfig(k0sVars, ""))
+	})
+
+	t.Run("zfs", func(t *testing.T) {
+		assert.Equal(t, `
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  snapshotter = "zfs"`, snapshotterConfig(k0sVars, SnapshotterZFS))
+	})
+
+	t.Run("fuse-overlayfs is served by the proxy plugin", func(t *testing.T) {
+		assert.Equal(t, `
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  snapshotter = "fuse-overlayfs"
+
+[proxy_plugins."fuse-overlayfs"]
+  type = "snapshot"
+  address = "/run/k0s/containerd-fuse-overlayfs.sock"`, snapshotterConfig(k0sVars, SnapshotterFuseOverlayfs))
+	})
+
+	t.Run("native needs no support", func(t *testing.T) {
+		assert.NoError(t, checkSnapshotter(SnapshotterNative, snapshotterRoot(k0sVars, SnapshotterNative)))
+	})
+}
@@ -4,24 +4,40 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path"
+	"sort"
+	"time"
 
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/k0sproject/k0s/internal/util"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/exitcode"
 	"github.com/k0sproject/k0s/pkg/token"
 )
 
 func HandleKubeletBootstrapToken(encodedToken string, k0sVars constant.CfgVars) error {
+	attest, err := token.RequiresAttestation(encodedToken)
+	if err != nil {
+		return exitcode.Wrap(exitcode.Join, err)
+	}
+	if attest {
+		if encodedToken, err = AttestJoin(encodedToken, k0sVars); err != nil {
+			return fmt.Errorf("failed to exchange the join token with TPM attestation: %w", err)
+		}
+	}
+
 	kubeconfig, err := token.DecodeJoinToken(encodedToken)
 	if err != nil {
-		return fmt.Errorf("failed to decode token: %w", err)
+		return exitcode.Wrap(exitcode.Join, fmt.Errorf("failed to decode token: %w", err))
 	}
 
 	// Load the bootstrap kubeconfig to validate it
 	clientCfg, err := clientcmd.Load(kubeconfig)
 	if err != nil {
-		return fmt.Errorf("failed to parse kubelet bootstrap auth from token: %w", err)
+		return exitcode.Wrap(exitcode.Join, fmt.Errorf("failed to parse kubelet bootstrap auth from token: %w", err))
 	}
 	kubeletCAPath := path.Join(k0sVars.CertRootDir, "ca.crt")
 	if !util.FileExists(kubeletCAPath) {
@@ -41,6 +57,21 @@ func HandleKubeletBootstrapToken(encodedToken string, k0sVars constant.CfgVars)
 	return nil
 }
 
+// apiServerBackoff is how long LoadKubeletConfigClient tries the API servers of the kubeconfigs, about a minute
+var apiServerBackoff = wait.Backoff{
+	Steps:    6,
+	Duration: time.Second,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+// apiServerTimeout is the timeout of the requests to the API servers when failing over between them
+const apiServerTimeout = 10 * time.Second
+
+// LoadKubeletConfigClient creates the client of the kubelet config. When the bootstrap kubeconfig lists more than one
+// API server, as the tokens created with the additional API endpoints do, the client connects to the first reachable
+// one, retrying with backoff. If the node is still joining, the bootstrap kubeconfig is switched to that server too,
+// so that the kubelet joins through it.
 func LoadKubeletConfigClient(k0svars constant.CfgVars) (*KubeletConfigClient, error) {
 	var kubeletConfigClient *KubeletConfigClient
 	// Prefer to load client config from kubelet auth, fallback to bootstrap token auth
@@ -49,9 +80,86 @@ func LoadKubeletConfigClient(k0svars constant.CfgVars) (*KubeletConfigClient, er
 		clientConfigPath = k0svars.KubeletAuthConfigPath
 	}
 
+	servers := kubeconfigServers(clientConfigPath, k0svars.KubeletBootstrapConfigPath)
+	if len(servers) > 1 {
+		var server string
+		err := retry.OnError(apiServerBackoff, func(error) bool { return true }, func() error {
+			for _, s := range servers {
+				client, err := newKubeletConfigClientFor(clientConfigPath, s)
+				if err == nil {
+					err = client.ping()
+				}
+				if err != nil {
+					logrus.Warnf("API server %s is not reachable: %v", s, err)
+					continue
+				}
+				kubeletConfigClient, server = client, s
+				return nil
+			}
+			return fmt.Errorf("none of the API servers %v is reachable", servers)
+		})
+		if err == nil {
+			if server != servers[0] && clientConfigPath == k0svars.KubeletBootstrapConfigPath {
+				if err := useKubeconfigServer(clientConfigPath, server); err != nil {
+					logrus.Warnf("failed to switch the bootstrap kubeconfig to %s: %v", server, err)
+				}
+			}
+			return kubeletConfigClient, nil
+		}
+		logrus.Warnf("%v, continuing with %s", err, servers[0])
+	}
+
 	kubeletConfigClient, err := NewKubeletConfigClient(clientConfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start kubelet config client: %v", err)
 	}
 	return kubeletConfigClient, nil
 }
+
+// kubeconfigServers lists the API servers of the kubeconfigs, the server of the current context of the first one first
+func kubeconfigServers(paths ...string) []string {
+	var servers []string
+	add := func(server string) {
+		if server != "" && !util.StringSliceContains(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+	for _, path := range paths {
+		if !util.FileExists(path) {
+			continue
+		}
+		cfg, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			continue
+		}
+		if ctx, ok := cfg.Contexts[cfg.CurrentContext]; ok {
+			if cluster, ok := cfg.Clusters[ctx.Cluster]; ok {
+				add(cluster.Server)
+			}
+		}
+		names := make([]string, 0, len(cfg.Clusters))
+		for name := range cfg.Clusters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(cfg.Clusters[name].Server)
+		}
+	}
+	return servers
+}
+
+// useKubeconfigServer switches the current context of the kubeconfig to the one of the given server
+func useKubeconfigServer(path, server string) error {
+	cfg, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return err
+	}
+	for name, ctx := range cfg.Contexts {
+		if cluster, ok := cfg.Clusters[ctx.Cluster]; ok && cluster.Server == server {
+			cfg.CurrentContext = name
+			return clientcmd.WriteToFile(*cfg, path)
+		}
+	}
+	return fmt.Errorf("no context for %s", server)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package worker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const multiServerKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: k0s
+  cluster:
+    server: https://10.0.0.1:6443
+- name: k0s-1
+  cluster:
+    server: https://10.0.0.2:6443
+contexts:
+- name: k0s
+  context:
+    cluster: k0s
+    user: kubelet-bootstrap
+- name: k0s-1
+  context:
+    cluster: k0s-1
+    user: kubelet-bootstrap
+current-context: k0s
+users:
+- name: kubelet-bootstrap
+  user:
+    token: abcdef.0123456789abcdef
+`
+
+func TestKubeconfigServers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig-servers")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bootstrap := filepath.Join(dir, "bootstrap-kubelet.conf")
+	require.NoError(t, ioutil.WriteFile(bootstrap, []byte(multiServerKubeconfig), 0600))
+	auth := filepath.Join(dir, "kubelet.conf")
+	require.NoError(t, ioutil.WriteFile(auth, []byte("apiVersion: v1\nkind: Config\nclusters:\n- name: default-cluster\n  cluster:\n    server: https://10.0.0.2:6443\n"), 0600))
+
+	assert.Equal(t, []string{"https://10.0.0.1:6443", "https://10.0.0.2:6443"}, kubeconfigServers(bootstrap))
+	assert.Equal(t, []string{"https://10.0.0.2:6443", "https://10.0.0.1:6443"}, kubeconfigServers(auth, bootstrap))
+
+	require.NoError(t, useKubeconfigServer(bootstrap, "https://10.0.0.2:6443"))
+	cfg, err := clientcmd.LoadFromFile(bootstrap)
+	require.NoError(t, err)
+	assert.Equal(t, "k0s-1", cfg.CurrentContext)
+	assert.Equal(t, []string{"https://10.0.0.2:6443", "https://10.0.0.1:6443"}, kubeconfigServers(bootstrap))
+
+	assert.Error(t, useKubeconfigServer(bootstrap, "https://10.0.0.3:6443"))
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package config
 
 import (
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -24,16 +25,20 @@ import (
 
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/crypt"
 )
 
 var (
 	CfgFile        string
+	cfgFiles       []string
 	DataDir        string
+	RunDir         string
 	Debug          bool
 	DebugListenOn  string
 	K0sVars        constant.CfgVars
 	workerOpts     WorkerOptions
 	controllerOpts ControllerOptions
+	watchdogOpts   WatchdogOptions
 )
 
 // This struct holds all the CLI options & settings required by the
@@ -41,6 +46,7 @@ var (
 type CLIOptions struct {
 	WorkerOptions
 	ControllerOptions
+	WatchdogOptions
 	CfgFile          string
 	ClusterConfig    *v1beta1.ClusterConfig
 	Debug            bool
@@ -53,12 +59,25 @@ type CLIOptions struct {
 
 // Shared controller cli flags
 type ControllerOptions struct {
-	EnableWorker bool
-	SingleNode   bool
+	EnableWorker              bool
+	SingleNode                bool
+	EnableDynamicConfig       bool
+	EnableControlPlaneStandby bool
 
 	EnableK0sCloudProvider          bool
 	K0sCloudProviderUpdateFrequency time.Duration
 	K0sCloudProviderPort            int
+
+	DataDirEncryptionDevice    string
+	DataDirEncryptionKeyFile   string
+	DataDirEncryptionImageSize string
+}
+
+// Shared watchdog cli flags of the controller and the worker
+type WatchdogOptions struct {
+	WatchdogMaxGoroutines int
+	WatchdogRestart       bool
+	CollectCoreDumps      bool
 }
 
 // Shared worker cli flags
@@ -66,13 +85,26 @@ type WorkerOptions struct {
 	APIServer        string
 	CIDRRange        string
 	CloudProvider    bool
+	CNIBinDir        string
+	CNIConfDir       string
 	ClusterDNS       string
 	CmdLogLevels     map[string]string
 	CriSocket        string
+	DisablePlugins   []string
+	Snapshotter      string
+	Ephemeral        bool
+	IgnoreNetOverlap bool
+	KubeletBindMount bool
 	KubeletExtraArgs string
+	KubeletRootDir   string
 	Labels           []string
+	Taints           []string
+	ProvisioningPath string
+	RunAsUser        string
+	Simulate         int
 	TokenFile        string
 	TokenArg         string
+	TokenFromMeta    string
 	WorkerProfile    string
 }
 
@@ -91,9 +123,10 @@ func DefaultLogLevels() map[string]string {
 
 func GetPersistentFlagSet() *pflag.FlagSet {
 	flagset := &pflag.FlagSet{}
-	flagset.StringVarP(&CfgFile, "config", "c", "", "config file, use '-' to read the config from stdin")
+	flagset.StringSliceVarP(&cfgFiles, "config", "c", nil, "config file or directory, use '-' to read the config from stdin. Repeat the flag to merge several of them, the later ones overriding the earlier ones")
 	flagset.BoolVarP(&Debug, "debug", "d", false, "Debug logging (default: false)")
 	flagset.StringVar(&DataDir, "data-dir", "", "Data Directory for k0s (default: /var/lib/k0s). DO NOT CHANGE for an existing setup, things will break!")
+	flagset.StringVar(&RunDir, "run-dir", "", "Run Directory for the k0s sockets and runtime state (default: /run/k0s)")
 	flagset.StringVar(&DebugListenOn, "debugListenOn", ":6060", "Http listenOn for Debug pprof handler")
 	return flagset
 }
@@ -103,13 +136,45 @@ func GetPersistentFlagSet() *pflag.FlagSet {
 func GetKubeCtlFlagSet() *pflag.FlagSet {
 	flagset := &pflag.FlagSet{}
 	flagset.StringVar(&DataDir, "data-dir", "", "Data Directory for k0s (default: /var/lib/k0s). DO NOT CHANGE for an existing setup, things will break!")
+	flagset.StringVar(&RunDir, "run-dir", "", "Run Directory for the k0s sockets and runtime state (default: /run/k0s)")
 	flagset.BoolVar(&Debug, "debug", false, "Debug logging (default: false)")
 	return flagset
 }
 
 func GetCriSocketFlag() *pflag.FlagSet {
 	flagset := &pflag.FlagSet{}
-	flagset.StringVar(&workerOpts.CriSocket, "cri-socket", "", "container runtime socket to use, default to internal containerd. Format: [remote|docker]:[path-to-socket]")
+	flagset.StringVar(&workerOpts.CriSocket, "cri-socket", "", "container runtime socket to use, default to internal containerd. Format: [remote|crio|docker]:[path-to-socket]")
+	return flagset
+}
+
+// GetNetworkOverlapFlag returns the flag for starting despite the cluster networks overlapping the host networks
+func GetNetworkOverlapFlag() *pflag.FlagSet {
+	flagset := &pflag.FlagSet{}
+	flagset.BoolVar(&workerOpts.IgnoreNetOverlap, "ignore-network-overlap", false, "only warn when the pod or service CIDRs overlap the networks of the host instead of refusing to start")
+	return flagset
+}
+
+// GetWatchdogFlags returns the flags of the watchdog detecting the stalled loops of the k0s process
+func GetWatchdogFlags() *pflag.FlagSet {
+	flagset := &pflag.FlagSet{}
+	flagset.IntVar(&watchdogOpts.WatchdogMaxGoroutines, "watchdog-max-goroutines", 10000, "dump the goroutine stacks when the k0s process runs more goroutines than this")
+	flagset.BoolVar(&watchdogOpts.WatchdogRestart, "watchdog-restart", false, "restart the k0s subsystems whose reconcile loops stall")
+	flagset.BoolVar(&watchdogOpts.CollectCoreDumps, "collect-core-dumps", false, "enable the core dumps of the supervised components and keep them with their crashes")
+	return flagset
+}
+
+// GetCNIDirFlags returns the flags for relocating the CNI dirs used by the embedded containerd
+func GetCNIDirFlags() *pflag.FlagSet {
+	flagset := &pflag.FlagSet{}
+	flagset.StringVar(&workerOpts.CNIConfDir, "cni-conf-dir", constant.CNIConfDirDefault, "directory of the CNI network configs, must match spec.network.cniConfDir")
+	flagset.StringVar(&workerOpts.CNIBinDir, "cni-bin-dir", constant.CNIBinDirDefault, "directory of the CNI plugin binaries, must match spec.network.cniBinDir")
+	return flagset
+}
+
+// GetSnapshotterFlag returns the flag selecting the snapshotter of the embedded containerd
+func GetSnapshotterFlag() *pflag.FlagSet {
+	flagset := &pflag.FlagSet{}
+	flagset.StringVar(&workerOpts.Snapshotter, "containerd-snapshotter", "", "snapshotter of the embedded containerd: overlayfs, fuse-overlayfs, zfs, btrfs or native (default: the containerd default, overlayfs)")
 	return flagset
 }
 
@@ -121,11 +186,24 @@ func GetWorkerFlags() *pflag.FlagSet {
 	flagset.StringVar(&workerOpts.CIDRRange, "cidr-range", "10.96.0.0/12", "HACK: cidr range for the windows worker node")
 	flagset.StringVar(&workerOpts.ClusterDNS, "cluster-dns", "10.96.0.10", "HACK: cluster dns for the windows worker node")
 	flagset.BoolVar(&workerOpts.CloudProvider, "enable-cloud-provider", false, "Whether or not to enable cloud provider support in kubelet")
-	flagset.StringVar(&workerOpts.TokenFile, "token-file", "", "Path to the file containing token.")
+	flagset.StringVar(&workerOpts.TokenFile, "token-file", "", "Path to the file containing token, or an http(s) URL to fetch it from on the first start.")
+	flagset.StringVar(&workerOpts.TokenFromMeta, "token-from-metadata", "", "fetch the join token on the first start from the instance metadata of the cloud provider: aws, gcp or azure")
 	flagset.StringToStringVarP(&workerOpts.CmdLogLevels, "logging", "l", DefaultLogLevels(), "Logging Levels for the different components")
 	flagset.StringSliceVarP(&workerOpts.Labels, "labels", "", []string{}, "Node labels, list of key=value pairs")
+	flagset.StringSliceVarP(&workerOpts.Taints, "taints", "", []string{}, "Node taints the node registers with, list of key=value:Effect")
 	flagset.StringVar(&workerOpts.KubeletExtraArgs, "kubelet-extra-args", "", "extra args for kubelet")
+	flagset.StringVar(&workerOpts.KubeletRootDir, "kubelet-root-dir", "", "directory for the kubelet state (default: <data-dir>/kubelet)")
+	flagset.BoolVar(&workerOpts.KubeletBindMount, "kubelet-bind-mount", false, "bind-mount the kubelet root dir to "+constant.KubeletDefaultRootDir+" for CSI drivers expecting the default path (linux only)")
+	flagset.StringVar(&workerOpts.ProvisioningPath, "provisioning-path", "", "wait at first boot for the join token (and k0s.yaml for controllers) to appear in the given directory or file, e.g. on removable media. The files are securely deleted once used")
+	flagset.BoolVar(&workerOpts.Ephemeral, "ephemeral", false, "deregister the node from the cluster and remove its credentials on graceful shutdown, for spot and preemptible instances")
+	flagset.StringVar(&workerOpts.RunAsUser, "run-as-user", "", "run the worker as the given non-root user with ambient capabilities (linux/systemd only)")
+	flagset.StringSliceVar(&workerOpts.DisablePlugins, "disable-plugins", []string{}, "node agents compiled into k0s that are not run on the node")
+	flagset.IntVar(&workerOpts.Simulate, "simulate", 0, "join the given number of simulated nodes instead of running the worker, for the scale testing of the control plane. Nothing runs on the simulated nodes")
 	flagset.AddFlagSet(GetCriSocketFlag())
+	flagset.AddFlagSet(GetCNIDirFlags())
+	flagset.AddFlagSet(GetSnapshotterFlag())
+	flagset.AddFlagSet(GetNetworkOverlapFlag())
+	flagset.AddFlagSet(GetWatchdogFlags())
 
 	return flagset
 }
@@ -138,20 +216,32 @@ func GetControllerFlags() *pflag.FlagSet {
 	flagset.StringVar(&workerOpts.TokenFile, "token-file", "", "Path to the file containing join-token.")
 	flagset.StringToStringVarP(&workerOpts.CmdLogLevels, "logging", "l", DefaultLogLevels(), "Logging Levels for the different components")
 	flagset.BoolVar(&controllerOpts.SingleNode, "single", false, "enable single node (implies --enable-worker, default false)")
+	flagset.BoolVar(&controllerOpts.EnableDynamicConfig, "enable-dynamic-config", false, "keep the cluster-wide config in the ClusterConfig resource and reload it on changes (default false)")
+	flagset.BoolVar(&controllerOpts.EnableControlPlaneStandby, "enable-control-plane-standby", false, "run kube-scheduler and kube-controller-manager only on the leader controller, the others start them on failover (default false)")
 	flagset.BoolVar(&controllerOpts.EnableK0sCloudProvider, "enable-k0s-cloud-provider", false, "enables the k0s-cloud-provider (default false)")
 	flagset.DurationVar(&controllerOpts.K0sCloudProviderUpdateFrequency, "k0s-cloud-provider-update-frequency", 2*time.Minute, "the frequency of k0s-cloud-provider node updates")
 	flagset.IntVar(&controllerOpts.K0sCloudProviderPort, "k0s-cloud-provider-port", cloudprovider.CloudControllerManagerPort, "the port that k0s-cloud-provider binds on")
+	flagset.StringVar(&controllerOpts.DataDirEncryptionDevice, "data-dir-encryption-device", "", "encrypt the data dir with dm-crypt into the given LUKS block device or image file (linux only)")
+	flagset.StringVar(&controllerOpts.DataDirEncryptionKeyFile, "data-dir-encryption-key-file", "", "file holding the key of the encrypted data dir, e.g. unsealed from the TPM at boot")
+	flagset.StringVar(&controllerOpts.DataDirEncryptionImageSize, "data-dir-encryption-image-size", crypt.DefaultImageSize, "size of the image file created for the encrypted data dir")
 	flagset.AddFlagSet(GetCriSocketFlag())
+	flagset.AddFlagSet(GetSnapshotterFlag())
+	flagset.AddFlagSet(GetNetworkOverlapFlag())
+	flagset.AddFlagSet(GetWatchdogFlags())
 
 	return flagset
 }
 
 func GetCmdOpts() CLIOptions {
-	K0sVars = constant.GetConfig(DataDir)
+	if len(cfgFiles) > 0 {
+		CfgFile = strings.Join(cfgFiles, ",")
+	}
+	K0sVars = constant.GetConfigWithRunDir(DataDir, ResolveRunDir())
 
 	opts := CLIOptions{
 		ControllerOptions: controllerOpts,
 		WorkerOptions:     workerOpts,
+		WatchdogOptions:   watchdogOpts,
 
 		CfgFile:          CfgFile,
 		Debug:            Debug,
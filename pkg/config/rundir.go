@@ -0,0 +1,63 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"gopkg.in/yaml.v2"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/install"
+)
+
+// ResolveRunDir returns the run dir given with --run-dir. Without the flag, the installConfig.runDir of the
+// config file is used, and then the run dir recorded by k0s install, so that the commands run without
+// the flags, such as reset and status, find the runtime state of the installed service.
+// An empty result keeps the default run dir.
+func ResolveRunDir() string {
+	if RunDir != "" {
+		return RunDir
+	}
+	if runDir := configRunDir(CfgFile); runDir != "" {
+		return runDir
+	}
+	if state, err := install.ReadInstallState(constant.InstallStatePath); err == nil {
+		return state.RunDir
+	}
+	return ""
+}
+
+// configRunDir reads installConfig.runDir from the given config files, the config from stdin is not peeked at
+func configRunDir(cfgFile string) string {
+	if cfgFile == "" || cfgFile == "-" {
+		return ""
+	}
+	data, err := v1beta1.MergeConfigFiles(CfgFilePaths(cfgFile))
+	if err != nil {
+		return ""
+	}
+	cfg := struct {
+		Spec struct {
+			Install struct {
+				RunDir string `yaml:"runDir"`
+			} `yaml:"installConfig"`
+		} `yaml:"spec"`
+	}{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return ""
+	}
+	return cfg.Spec.Install.RunDir
+}
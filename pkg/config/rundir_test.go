@@ -0,0 +1,50 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigRunDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfgFile := filepath.Join(dir, "k0s.yaml")
+	require.NoError(t, ioutil.WriteFile(cfgFile, []byte(`
+apiVersion: k0s.k0sproject.io/v1beta1
+kind: Cluster
+spec:
+  installConfig:
+    runDir: /var/run/k0s
+`), 0644))
+
+	assert.Equal(t, "/var/run/k0s", configRunDir(cfgFile))
+
+	override := filepath.Join(dir, "site.yaml")
+	require.NoError(t, ioutil.WriteFile(override, []byte("spec:\n  installConfig:\n    runDir: /run/k0s-site\n"), 0644))
+	assert.Equal(t, "/run/k0s-site", configRunDir(cfgFile+","+override))
+	assert.Equal(t, "", configRunDir(filepath.Join(dir, "missing.yaml")))
+	assert.Equal(t, "", configRunDir("-"))
+	assert.Equal(t, "", configRunDir(""))
+}
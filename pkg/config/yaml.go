@@ -21,6 +21,7 @@ import (
 
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
 	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/exitcode"
 
 	"github.com/sirupsen/logrus"
 )
@@ -30,21 +31,34 @@ func GetYamlFromFile(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v
 		// no config file exists, using defaults
 		logrus.Info("no config file given, using defaults")
 	}
-	cfg, err := ValidateYaml(cfgPath, k0sVars)
+	return ValidateYaml(cfgPath, k0sVars)
+}
+
+// CfgFilePaths splits the config files and directories given with --config, in the order they are merged
+func CfgFilePaths(cfgFile string) []string {
+	if cfgFile == "" {
+		return nil
+	}
+	return strings.Split(cfgFile, ",")
+}
+
+// ValidateYaml reads and validates the cluster config, its errors are of the config category of pkg/exitcode
+func ValidateYaml(cfgPath string, k0sVars constant.CfgVars) (*v1beta1.ClusterConfig, error) {
+	clusterConfig, err := readAndValidate(cfgPath, k0sVars)
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.Config, err)
 	}
-	return cfg, nil
+	return clusterConfig, nil
 }
 
-func ValidateYaml(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v1beta1.ClusterConfig, err error) {
+func readAndValidate(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v1beta1.ClusterConfig, err error) {
 	switch cfgPath {
 	case "-":
 		clusterConfig, err = v1beta1.ConfigFromStdin(k0sVars)
 	case "":
 		clusterConfig = v1beta1.DefaultClusterConfig(k0sVars)
 	default:
-		clusterConfig, err = v1beta1.ConfigFromFile(cfgPath, k0sVars)
+		clusterConfig, err = v1beta1.ConfigFromFiles(CfgFilePaths(cfgPath), k0sVars)
 	}
 	if err != nil {
 		return nil, err
@@ -59,11 +73,11 @@ func ValidateYaml(cfgPath string, k0sVars constant.CfgVars) (clusterConfig *v1be
 
 	errors := clusterConfig.Validate()
 	if len(errors) > 0 {
-		messages := make([]string, len(errors))
+		messages := make([]string, 0, len(errors))
 		for _, e := range errors {
-			messages = append(messages, e.Error())
+			messages = append(messages, "  - "+e.Error())
 		}
-		return nil, fmt.Errorf(strings.Join(messages, "\n"))
+		return nil, fmt.Errorf("%s", strings.Join(messages, "\n"))
 	}
 	return clusterConfig, nil
 }
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package constant
+
+// Capability is a linux capability name together with its bit number
+type Capability struct {
+	Name string
+	Bit  uint
+}
+
+// WorkerCapabilities lists the capabilities a non-root worker needs as ambient capabilities:
+//   - runc can only grant the containers the capabilities it holds, so the default capability set of the containers
+//     is needed: CAP_CHOWN, CAP_DAC_OVERRIDE, CAP_FOWNER, CAP_FSETID, CAP_KILL, CAP_SETGID, CAP_SETUID, CAP_SETPCAP,
+//     CAP_NET_BIND_SERVICE, CAP_NET_RAW, CAP_SYS_CHROOT, CAP_MKNOD, CAP_AUDIT_WRITE and CAP_SETFCAP
+//   - CAP_SYS_ADMIN for the namespaces and the mounts of the containers and the kubelet volume mounts
+//   - CAP_NET_ADMIN for the network namespaces and the pod networking
+//   - CAP_SYS_RESOURCE for the OOM score and the resource limits set by the kubelet and containerd
+//
+// CAP_SYS_ADMIN and CAP_NET_ADMIN are equivalent to root: the non-root worker keeps the files and the processes of
+// k0s away from root, but it's not a security boundary.
+var WorkerCapabilities = []Capability{
+	{"CAP_CHOWN", 0},
+	{"CAP_DAC_OVERRIDE", 1},
+	{"CAP_FOWNER", 3},
+	{"CAP_FSETID", 4},
+	{"CAP_KILL", 5},
+	{"CAP_SETGID", 6},
+	{"CAP_SETUID", 7},
+	{"CAP_SETPCAP", 8},
+	{"CAP_NET_BIND_SERVICE", 10},
+	{"CAP_NET_ADMIN", 12},
+	{"CAP_NET_RAW", 13},
+	{"CAP_SYS_CHROOT", 18},
+	{"CAP_SYS_ADMIN", 21},
+	{"CAP_SYS_RESOURCE", 24},
+	{"CAP_MKNOD", 27},
+	{"CAP_AUDIT_WRITE", 29},
+	{"CAP_SETFCAP", 31},
+}
@@ -22,11 +22,26 @@ import "fmt"
 
 const (
 	// DataDirDefault is the default data directory containing k0s state
-	DataDirDefault                 = "/var/lib/k0s"
+	DataDirDefault = "/var/lib/k0s"
+	// RunDirDefault is the default run directory of k0s when running as root
+	RunDirDefault                  = "/run/k0s"
 	KubeletVolumePluginDir         = "/usr/libexec/k0s/kubelet-plugins/volume/exec"
 	KineSocket                     = "kine/kine.sock:2379"
 	KubePauseContainerImage        = "k8s.gcr.io/pause"
 	KubePauseContainerImageVersion = "3.2"
+
+	// CNIConfDirDefault is the default location of the CNI network configs on the hosts
+	CNIConfDirDefault = "/etc/cni/net.d"
+	// CNIBinDirDefault is the default location of the CNI plugin binaries on the hosts
+	CNIBinDirDefault = "/opt/cni/bin"
+	// ContainerdUserConfigPath is the location of the containerd config provided by the user, if any
+	ContainerdUserConfigPath = "/etc/k0s/containerd.toml"
+	// ContainerdDropInDir holds the TOML snippets merged into the containerd config generated by k0s
+	ContainerdDropInDir = "/etc/k0s/containerd.d"
+	// KubeletDefaultRootDir is the upstream default kubelet root dir, which many CSI drivers expect
+	KubeletDefaultRootDir = "/var/lib/kubelet"
+	// InstallStatePath is the location of the state recorded by k0s install, outside of the data dir so the package scripts find it
+	InstallStatePath = "/etc/k0s/install-state.json"
 )
 
 func formatPath(dir string, file string) string {
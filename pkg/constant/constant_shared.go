@@ -37,6 +37,16 @@ const (
 	// KineDBDirMode is the expected directory permissions for the Kine DB
 	KineDBDirMode = 0750
 
+	// KubeletRootDirMarker is the file marking a kubelet root dir outside of the data dir as created by k0s, k0s reset
+	// only deletes the ones carrying it
+	KubeletRootDirMarker = ".k0s-kubelet-root"
+
+	// ContainerdNamespace is the containerd namespace of the CRI plugin, which runs the pods of the kubelet. The images
+	// imported by k0s are put there too, the kubelet wouldn't see them elsewhere, and the CRI plugin has no setting for
+	// another namespace. It's not dedicated to k0s: on a shared containerd, other CRI clients use it as well, so the
+	// clean-up only touches the pods created by the kubelet, and their images only when asked to.
+	ContainerdNamespace = "k8s.io"
+
 	// User accounts for services
 
 	// EtcdUser defines the user to use for running etcd process
@@ -51,6 +61,10 @@ const (
 	KonnectivityServerUser = "konnectivity-server"
 	// KubernetesMajorMinorVersion defines the current embedded major.minor version info
 	KubernetesMajorMinorVersion = "1.21"
+	// ConnectionBrokerAPIPort is the local port the worker connection broker forwards to the nearest kube-apiserver
+	ConnectionBrokerAPIPort = 7443
+	// ConnectionBrokerKonnectivityPort is the local port the worker connection broker forwards to the nearest konnectivity-server
+	ConnectionBrokerKonnectivityPort = 7132
 	// DefaultPSP defines the system level default PSP to apply
 	DefaultPSP = "00-k0s-privileged"
 	// Image Constants
@@ -91,6 +105,24 @@ type CfgVars struct {
 	KonnectivityKubeConfigPath string // location for konnectivity kubeconfig
 	OCIBundleDir               string // location for OCI bundles
 	DefaultStorageType         string // Default backend storage
+	StatusHistoryPath          string // location of the status history database
+	ConfigDriftPath            string // location of the latest config drift report
+	ClusterMetadataPath        string // location of the cluster name and labels of the running controller
+	ContainerdConfigPath       string // location of the containerd config generated by k0s
+	KubeletRootDirPath         string // location of the file recording the kubelet root dir in use
+	KubeletNodeNamePath        string // location of the node name imported with the kubelet identity of a replaced node
+	ConnectionBrokerConfigPath string // location of the cached connection broker config on workers
+	ProvisionedTokenPath       string // location of the join token taken from the provisioning path, until the node has joined
+	ProvisionedConfigPath      string // location of the cluster config taken from the provisioning path
+	ProfilingSocketPath        string // location of the unix socket serving the pprof endpoints of k0s
+	LogDir                     string // location of the goroutine stack dumps taken by the watchdog
+	MaintenancePath            string // location of the marker keeping the controller in maintenance mode
+	NetworkStatePath           string // location of the network settings the controller last ran with
+	EtcdSnapshotDir            string // location of the periodic etcd snapshots
+	EtcdSnapshotStatusPath     string // location of the status of the periodic etcd snapshots
+	CrashDir                   string // location of the crash artifacts of the supervised components
+	UpgradeDir                 string // location of the previous binary and the backup kept for rolling back an upgrade
+	ComponentArgsDir           string // location of the flags the supervised components were last started with
 
 	// Helm config
 	HelmHome             string
@@ -100,6 +132,12 @@ type CfgVars struct {
 
 // GetConfig returns the pointer to a Config struct
 func GetConfig(dataDir string) CfgVars {
+	return GetConfigWithRunDir(dataDir, "")
+}
+
+// GetConfigWithRunDir returns the config with the sockets and runtime state placed in runDir.
+// An empty runDir keeps the default: /run/k0s for root, <dataDir>/run otherwise.
+func GetConfigWithRunDir(dataDir string, runDir string) CfgVars {
 	if dataDir == "" {
 		switch runtime.GOOS {
 		case "windows":
@@ -112,10 +150,12 @@ func GetConfig(dataDir string) CfgVars {
 	// fetch absolute path for dataDir
 	dataDir, _ = filepath.Abs(dataDir)
 
-	var runDir string
-	if os.Geteuid() == 0 {
-		runDir = "/run/k0s"
-	} else {
+	switch {
+	case runDir != "":
+		runDir, _ = filepath.Abs(runDir)
+	case os.Geteuid() == 0:
+		runDir = RunDirDefault
+	default:
 		runDir = formatPath(dataDir, "run")
 	}
 	certDir := formatPath(dataDir, "pki")
@@ -139,6 +179,24 @@ func GetConfig(dataDir string) CfgVars {
 		ManifestsDir:               formatPath(dataDir, "manifests"),
 		RunDir:                     runDir,
 		KonnectivityKubeConfigPath: formatPath(certDir, "konnectivity.conf"),
+		StatusHistoryPath:          formatPath(dataDir, "status-history.db"),
+		ConfigDriftPath:            formatPath(runDir, "config-drift.json"),
+		ClusterMetadataPath:        formatPath(dataDir, "cluster-metadata.json"),
+		ContainerdConfigPath:       formatPath(dataDir, "containerd.toml"),
+		KubeletRootDirPath:         formatPath(dataDir, "kubelet-root-dir"),
+		KubeletNodeNamePath:        formatPath(dataDir, "kubelet-node-name"),
+		ConnectionBrokerConfigPath: formatPath(dataDir, "connection-broker.yaml"),
+		ProvisionedTokenPath:       formatPath(dataDir, "provisioned-token"),
+		ProvisionedConfigPath:      formatPath(dataDir, "provisioned-k0s.yaml"),
+		ProfilingSocketPath:        formatPath(runDir, "k0s-pprof.sock"),
+		LogDir:                     formatPath(dataDir, "logs"),
+		MaintenancePath:            formatPath(dataDir, "maintenance"),
+		NetworkStatePath:           formatPath(dataDir, "network-state.json"),
+		EtcdSnapshotDir:            formatPath(dataDir, "etcd-snapshots"),
+		EtcdSnapshotStatusPath:     formatPath(runDir, "etcd-snapshot.json"),
+		CrashDir:                   formatPath(dataDir, "crashes"),
+		UpgradeDir:                 formatPath(dataDir, "upgrade"),
+		ComponentArgsDir:           formatPath(dataDir, "component-args"),
 
 		// Helm Config
 		HelmHome:             helmHome,
@@ -18,7 +18,10 @@ package constant
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -56,3 +59,17 @@ include ../../embedded-bins/Makefile.variables
 print-%:
 	@echo $($*)
 `
+
+func TestGetConfigWithRunDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-run-dir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	k0sVars := GetConfigWithRunDir(dir, filepath.Join(dir, "custom-run"))
+	assert.Equal(t, filepath.Join(dir, "custom-run"), k0sVars.RunDir)
+	assert.Equal(t, filepath.Join(dir, "custom-run", KineSocket), k0sVars.KineSocketPath)
+	assert.Equal(t, filepath.Join(dir, "custom-run", "k0s-pprof.sock"), k0sVars.ProfilingSocketPath)
+
+	// an empty run dir keeps the default
+	assert.Equal(t, GetConfig(dir).RunDir, GetConfigWithRunDir(dir, "").RunDir)
+}
@@ -29,10 +29,24 @@ const (
 	BinDir = "C:\\var\\lib\\k0s\\bin"
 	// RunDir run directory
 	RunDir = "C:\\run\\k0s"
+	// RunDirDefault is the default run directory of k0s when running as root
+	RunDirDefault = RunDir
 	// ManifestsDir stack applier directory
 	ManifestsDir = "C:\\var\\lib\\k0s\\manifests"
 	// KubeletVolumePluginDir defines the location for kubelet plugins volume executables
 	KubeletVolumePluginDir = "C:\\usr\\libexec\\k0s\\kubelet-plugins\\volume\\exec"
+	// CNIConfDirDefault is the default location of the CNI network configs on the hosts
+	CNIConfDirDefault = "C:\\k\\cni\\config"
+	// CNIBinDirDefault is the default location of the CNI plugin binaries on the hosts
+	CNIBinDirDefault = "C:\\k\\cni"
+	// ContainerdUserConfigPath is the location of the containerd config provided by the user, if any
+	ContainerdUserConfigPath = "C:\\etc\\k0s\\containerd.toml"
+	// ContainerdDropInDir holds the TOML snippets merged into the containerd config generated by k0s
+	ContainerdDropInDir = "C:\\etc\\k0s\\containerd.d"
+	// KubeletDefaultRootDir is the upstream default kubelet root dir, which many CSI drivers expect
+	KubeletDefaultRootDir = "C:\\var\\lib\\kubelet"
+	// InstallStatePath is the location of the state recorded by k0s install, outside of the data dir so the package scripts find it
+	InstallStatePath = "C:\\etc\\k0s\\install-state.json"
 
 	KineSocket                     = "kine\\kine.sock:2379"
 	KubePauseContainerImage        = "mcr.microsoft.com/oss/kubernetes/pause"
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+var _ ContainerRuntime = &ContainerdRuntime{}
+var _ ImageRuntime = &ContainerdRuntime{}
+
+// ContainerdRuntime manages the pods of a remote CRI runtime over CRI, typically a containerd shared with others such
+// as the containerd of Docker. Only the pod sandboxes created by the kubelet, and the images of their containers, are
+// touched. The CRI plugin of containerd keeps them in its own namespace, the other namespaces aren't visible to it.
+type ContainerdRuntime struct {
+	CRIRuntime
+}
+
+func (c *ContainerdRuntime) ListContainers(ctx context.Context) ([]string, error) {
+	return c.listKubeletPodSandboxes(ctx)
+}
+
+// ListImages lists the images of the containers in the pods created by the kubelet
+func (c *ContainerdRuntime) ListImages(ctx context.Context) ([]string, error) {
+	pods, err := c.listKubeletPodSandboxes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kubeletPods := map[string]bool{}
+	for _, id := range pods {
+		kubeletPods[id] = true
+	}
+
+	var images []string
+	err = c.call(ctx, func(ctx context.Context, client pb.RuntimeServiceClient) error {
+		r, err := client.ListContainers(ctx, &pb.ListContainersRequest{})
+		if err != nil {
+			return err
+		}
+		seen := map[string]bool{}
+		for _, container := range r.GetContainers() {
+			image := container.GetImageRef()
+			if image == "" {
+				image = container.GetImage().GetImage()
+			}
+			if !kubeletPods[container.GetPodSandboxId()] || image == "" || seen[image] {
+				continue
+			}
+			seen[image] = true
+			images = append(images, image)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	return images, nil
+}
+
+// RemoveImage removes the image, the images that are already gone are not errors
+func (c *ContainerdRuntime) RemoveImage(ctx context.Context, name string) error {
+	err := c.callImages(ctx, func(ctx context.Context, client pb.ImageServiceClient) error {
+		_, err := client.RemoveImage(ctx, &pb.RemoveImageRequest{Image: &pb.ImageSpec{Image: name}})
+		return err
+	})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to remove image %s: %w", name, err)
+	}
+	logrus.Debugf("Removed image %s", name)
+	return nil
+}
+
+// SandboxImage returns the pause image the CRI plugin of containerd runs the pod sandboxes with, it's empty if the
+// runtime doesn't tell
+func (c *ContainerdRuntime) SandboxImage(ctx context.Context) (string, error) {
+	var info map[string]string
+	err := c.call(ctx, func(ctx context.Context, client pb.RuntimeServiceClient) error {
+		r, err := client.Status(ctx, &pb.StatusRequest{Verbose: true})
+		info = r.GetInfo()
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the runtime status: %w", err)
+	}
+	config := struct {
+		SandboxImage string `json:"sandboxImage"`
+	}{}
+	if raw, found := info["config"]; found {
+		if err := json.Unmarshal([]byte(raw), &config); err != nil {
+			return "", fmt.Errorf("failed to parse the runtime config: %w", err)
+		}
+	}
+	return config.SandboxImage, nil
+}
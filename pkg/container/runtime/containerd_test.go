@@ -0,0 +1,98 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// fakeContainerd serves the CRI calls of a containerd shared with another CRI client
+type fakeContainerd struct {
+	pb.UnimplementedRuntimeServiceServer
+	pb.UnimplementedImageServiceServer
+	pods       map[string]map[string]string
+	containers []*pb.Container
+	images     map[string]bool
+}
+
+func (f *fakeContainerd) ListPodSandbox(_ context.Context, _ *pb.ListPodSandboxRequest) (*pb.ListPodSandboxResponse, error) {
+	resp := &pb.ListPodSandboxResponse{}
+	for id, labels := range f.pods {
+		resp.Items = append(resp.Items, &pb.PodSandbox{Id: id, Labels: labels})
+	}
+	return resp, nil
+}
+
+func (f *fakeContainerd) ListContainers(_ context.Context, _ *pb.ListContainersRequest) (*pb.ListContainersResponse, error) {
+	return &pb.ListContainersResponse{Containers: f.containers}, nil
+}
+
+func (f *fakeContainerd) Status(_ context.Context, _ *pb.StatusRequest) (*pb.StatusResponse, error) {
+	return &pb.StatusResponse{Info: map[string]string{"config": `{"sandboxImage":"registry.example.com/pause:3.2"}`}}, nil
+}
+
+func (f *fakeContainerd) RemoveImage(_ context.Context, req *pb.RemoveImageRequest) (*pb.RemoveImageResponse, error) {
+	if !f.images[req.Image.Image] {
+		return nil, status.Errorf(codes.NotFound, "image %q not found", req.Image.Image)
+	}
+	delete(f.images, req.Image.Image)
+	return &pb.RemoveImageResponse{}, nil
+}
+
+func TestContainerdRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-containerd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "containerd.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	fake := &fakeContainerd{
+		pods: map[string]map[string]string{
+			"kubelet-pod": {kubeletPodUIDLabel: "74b1dc6c-1a1f-4d1e-8a5e-8f1b2c6a0e2b"},
+			"other-pod":   {"app": "not-from-kubelet"},
+		},
+		containers: []*pb.Container{
+			{Id: "app", PodSandboxId: "kubelet-pod", ImageRef: "sha256:0a1b"},
+			{Id: "sidecar", PodSandboxId: "kubelet-pod", ImageRef: "sha256:0a1b"},
+			{Id: "other", PodSandboxId: "other-pod", ImageRef: "sha256:9f8e"},
+		},
+		images: map[string]bool{"sha256:0a1b": true, "sha256:9f8e": true},
+	}
+	server := grpc.NewServer()
+	pb.RegisterRuntimeServiceServer(server, fake)
+	pb.RegisterImageServiceServer(server, fake)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	rt := NewContainerRuntime("remote", "unix://"+socket)
+	require.IsType(t, &ContainerdRuntime{}, rt)
+	containerd := rt.(*ContainerdRuntime)
+
+	pods, err := containerd.ListContainers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kubelet-pod"}, pods)
+
+	// only the images of the kubelet pods are removed
+	images, err := containerd.ListImages(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sha256:0a1b"}, images)
+	assert.NoError(t, containerd.RemoveImage(context.Background(), "sha256:0a1b"))
+	assert.NoError(t, containerd.RemoveImage(context.Background(), "sha256:0a1b"))
+	assert.Equal(t, map[string]bool{"sha256:9f8e": true}, fake.images)
+
+	sandboxImage, err := containerd.SandboxImage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com/pause:3.2", sandboxImage)
+}
@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
@@ -11,96 +12,118 @@ import (
 
 var _ ContainerRuntime = &CRIRuntime{}
 
+// criCallTimeout bounds the connection and each of the calls to the CRI runtime service, so that a hung runtime
+// doesn't block the cleanup forever even if the context of the call has no deadline
+var criCallTimeout = 30 * time.Second
+
+// CRIRuntime manages the pod sandboxes of a runtime over the CRI gRPC API
 type CRIRuntime struct {
 	criSocketPath string
 }
 
-func (cri *CRIRuntime) ListContainers() ([]string, error) {
-	client, conn, err := getRuntimeClient(cri.criSocketPath)
-	defer closeConnection(conn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create CRI runtime client: %w", err)
-	}
-	if client == nil {
-		return nil, fmt.Errorf("failed to create CRI runtime client: %w", err)
-	}
-	request := &pb.ListPodSandboxRequest{}
-	logrus.Debugf("ListPodSandboxRequest: %v", request)
-	r, err := client.ListPodSandbox(context.Background(), request)
-	logrus.Debugf("ListPodSandboxResponse: %v", r)
+func (cri *CRIRuntime) ListContainers(ctx context.Context) ([]string, error) {
+	items, err := cri.listPodSandboxes(ctx)
 	if err != nil {
 		return nil, err
 	}
 	var pods []string
-	for _, p := range r.GetItems() {
+	for _, p := range items {
 		pods = append(pods, p.Id)
 	}
 	return pods, nil
 }
 
-func (cri *CRIRuntime) RemoveContainer(id string) error {
-	client, conn, err := getRuntimeClient(cri.criSocketPath)
-	defer closeConnection(conn)
-	if err != nil {
-		return fmt.Errorf("failed to create CRI runtime client: %w", err)
-	}
-	if client == nil {
-		return fmt.Errorf("failed to create CRI runtime client")
-	}
-	request := &pb.RemovePodSandboxRequest{PodSandboxId: id}
-	logrus.Debugf("RemovePodSandboxRequest: %v", request)
-	r, err := client.RemovePodSandbox(context.Background(), request)
-	logrus.Debugf("RemovePodSandboxResponse: %v", r)
-	if err != nil {
+func (cri *CRIRuntime) RemoveContainer(ctx context.Context, id string) error {
+	err := cri.call(ctx, func(ctx context.Context, client pb.RuntimeServiceClient) error {
+		request := &pb.RemovePodSandboxRequest{PodSandboxId: id}
+		logrus.Debugf("RemovePodSandboxRequest: %v", request)
+		r, err := client.RemovePodSandbox(ctx, request)
+		logrus.Debugf("RemovePodSandboxResponse: %v", r)
 		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove pod sandbox %s: %w", id, err)
 	}
-	logrus.Debugf("Removed pod sandbox %s\n", id)
+	logrus.Debugf("Removed pod sandbox %s", id)
 	return nil
 }
 
-func (cri *CRIRuntime) StopContainer(id string) error {
-	client, conn, err := getRuntimeClient(cri.criSocketPath)
-	defer closeConnection(conn)
-	if err != nil {
-		return fmt.Errorf("failed to create CRI runtime client: %w", err)
-	}
-	if client == nil {
-		return fmt.Errorf("failed to create CRI runtime client")
-	}
-	request := &pb.StopPodSandboxRequest{PodSandboxId: id}
-	logrus.Debugf("StopPodSandboxRequest: %v", request)
-	r, err := client.StopPodSandbox(context.Background(), request)
-	logrus.Debugf("StopPodSandboxResponse: %v", r)
+func (cri *CRIRuntime) StopContainer(ctx context.Context, id string) error {
+	err := cri.call(ctx, func(ctx context.Context, client pb.RuntimeServiceClient) error {
+		request := &pb.StopPodSandboxRequest{PodSandboxId: id}
+		logrus.Debugf("StopPodSandboxRequest: %v", request)
+		r, err := client.StopPodSandbox(ctx, request)
+		logrus.Debugf("StopPodSandboxResponse: %v", r)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to stop pod sandbox: %w", err)
+		return fmt.Errorf("failed to stop pod sandbox %s: %w", id, err)
 	}
-	logrus.Debugf("Stopped pod sandbox %s\n", id)
+	logrus.Debugf("Stopped pod sandbox %s", id)
 	return nil
 }
 
-func getRuntimeClient(addr string) (pb.RuntimeServiceClient, *grpc.ClientConn, error) {
-	conn, err := getRuntimeClientConnection(addr)
+func (cri *CRIRuntime) listPodSandboxes(ctx context.Context) ([]*pb.PodSandbox, error) {
+	var items []*pb.PodSandbox
+	err := cri.call(ctx, func(ctx context.Context, client pb.RuntimeServiceClient) error {
+		request := &pb.ListPodSandboxRequest{}
+		logrus.Debugf("ListPodSandboxRequest: %v", request)
+		r, err := client.ListPodSandbox(ctx, request)
+		logrus.Debugf("ListPodSandboxResponse: %v", r)
+		if err != nil {
+			return err
+		}
+		items = r.GetItems()
+		return nil
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("connect: %w", err)
+		return nil, fmt.Errorf("failed to list pod sandboxes: %w", err)
 	}
-	runtimeClient := pb.NewRuntimeServiceClient(conn)
-	return runtimeClient, conn, nil
+	return items, nil
 }
 
-func getRuntimeClientConnection(addr string) (*grpc.ClientConn, error) {
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+// listKubeletPodSandboxes lists the ids of the pod sandboxes created by the kubelet, for the runtimes shared with others
+func (cri *CRIRuntime) listKubeletPodSandboxes(ctx context.Context) ([]string, error) {
+	items, err := cri.listPodSandboxes(ctx)
 	if err != nil {
-		errMsg := fmt.Errorf("connect endpoint %s, make sure you are running as root and the endpoint has been started: %w", addr, err)
-		logrus.Error(errMsg)
-	} else {
-		logrus.Debugf("connected successfully using endpoint: %s", addr)
+		return nil, err
+	}
+	var pods []string
+	for _, p := range items {
+		if _, managed := p.GetLabels()[kubeletPodUIDLabel]; !managed {
+			logrus.Debugf("skipping pod sandbox %s not created by the kubelet", p.Id)
+			continue
+		}
+		pods = append(pods, p.Id)
 	}
-	return conn, nil
+	return pods, nil
+}
+
+// call connects to the CRI runtime service and runs fn with ctx, further bounded by criCallTimeout
+func (cri *CRIRuntime) call(ctx context.Context, fn func(ctx context.Context, client pb.RuntimeServiceClient) error) error {
+	return cri.dial(ctx, func(ctx context.Context, conn *grpc.ClientConn) error {
+		return fn(ctx, pb.NewRuntimeServiceClient(conn))
+	})
+}
+
+// callImages connects to the CRI image service and runs fn with ctx, further bounded by criCallTimeout
+func (cri *CRIRuntime) callImages(ctx context.Context, fn func(ctx context.Context, client pb.ImageServiceClient) error) error {
+	return cri.dial(ctx, func(ctx context.Context, conn *grpc.ClientConn) error {
+		return fn(ctx, pb.NewImageServiceClient(conn))
+	})
 }
 
-func closeConnection(conn *grpc.ClientConn) {
-	if conn == nil {
-		return
+func (cri *CRIRuntime) dial(ctx context.Context, fn func(ctx context.Context, conn *grpc.ClientConn) error) error {
+	ctx, cancel := context.WithTimeout(ctx, criCallTimeout)
+	defer cancel()
+
+	opts := append([]grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock()}, criDialOptions()...)
+	conn, err := grpc.DialContext(ctx, cri.criSocketPath, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the CRI endpoint %s, make sure you are running as root and the runtime has been started: %w", cri.criSocketPath, err)
 	}
-	conn.Close()
+	defer conn.Close()
+	logrus.Debugf("connected successfully using endpoint: %s", cri.criSocketPath)
+
+	return fn(ctx, conn)
 }
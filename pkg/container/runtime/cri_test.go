@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// hangingCRI never answers the list calls, like a runtime that is stuck
+type hangingCRI struct {
+	pb.UnimplementedRuntimeServiceServer
+}
+
+func (h *hangingCRI) ListPodSandbox(ctx context.Context, _ *pb.ListPodSandboxRequest) (*pb.ListPodSandboxResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestCRIRuntimeTimeouts(t *testing.T) {
+	defer func(timeout time.Duration) { criCallTimeout = timeout }(criCallTimeout)
+	criCallTimeout = 200 * time.Millisecond
+
+	dir, err := ioutil.TempDir("", "k0s-cri")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("missing socket", func(t *testing.T) {
+		rt := NewContainerRuntime("cri", "unix://"+filepath.Join(dir, "missing.sock"))
+		_, err := rt.ListContainers(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("hanging runtime", func(t *testing.T) {
+		socket := filepath.Join(dir, "hanging.sock")
+		listener, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		server := grpc.NewServer()
+		pb.RegisterRuntimeServiceServer(server, &hangingCRI{})
+		go func() { _ = server.Serve(listener) }()
+		defer server.Stop()
+
+		rt := NewContainerRuntime("cri", "unix://"+socket)
+		_, err = rt.ListContainers(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(unwrapAll(err)))
+
+		// the calls the fake doesn't implement come back as structured errors
+		err = rt.StopContainer(context.Background(), "some-pod")
+		require.Error(t, err)
+		assert.Equal(t, codes.Unimplemented, status.Code(unwrapAll(err)))
+
+		// the deadline of the caller applies when it's the shorter one
+		criCallTimeout = time.Minute
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = rt.ListContainers(ctx)
+		require.Error(t, err)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(unwrapAll(err)))
+		assert.Less(t, int64(time.Since(start)), int64(10*time.Second))
+	})
+}
+
+func unwrapAll(err error) error {
+	for {
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return err
+		}
+		err = u.Unwrap()
+	}
+}
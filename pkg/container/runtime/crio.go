@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ ContainerRuntime = &CRIORuntime{}
+
+// kubeletPodUIDLabel is set by the kubelet on all the pod sandboxes it creates
+const kubeletPodUIDLabel = "io.kubernetes.pod.uid"
+
+// CRIORuntime manages the pods of a CRI-O runtime over CRI. CRI-O may also run pods that were not created by the kubelet,
+// so only the pod sandboxes labeled by the kubelet are listed. Pods that are already gone are not treated as errors,
+// as CRI-O removes the sandboxes of the exited pods on its own.
+type CRIORuntime struct {
+	CRIRuntime
+}
+
+func (c *CRIORuntime) ListContainers(ctx context.Context) ([]string, error) {
+	return c.listKubeletPodSandboxes(ctx)
+}
+
+func (c *CRIORuntime) StopContainer(ctx context.Context, id string) error {
+	if err := c.CRIRuntime.StopContainer(ctx, id); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *CRIORuntime) RemoveContainer(ctx context.Context, id string) error {
+	if err := c.CRIRuntime.RemoveContainer(ctx, id); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	for err != nil {
+		if s, ok := status.FromError(err); ok {
+			return s.Code() == codes.NotFound
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// fakeCRIO serves the pod sandbox calls of the CRI runtime service the way CRI-O does
+type fakeCRIO struct {
+	pb.UnimplementedRuntimeServiceServer
+	pods map[string]map[string]string
+}
+
+func (f *fakeCRIO) ListPodSandbox(_ context.Context, _ *pb.ListPodSandboxRequest) (*pb.ListPodSandboxResponse, error) {
+	resp := &pb.ListPodSandboxResponse{}
+	for id, labels := range f.pods {
+		resp.Items = append(resp.Items, &pb.PodSandbox{Id: id, Labels: labels})
+	}
+	return resp, nil
+}
+
+func (f *fakeCRIO) StopPodSandbox(_ context.Context, req *pb.StopPodSandboxRequest) (*pb.StopPodSandboxResponse, error) {
+	if _, found := f.pods[req.PodSandboxId]; !found {
+		return nil, status.Errorf(codes.NotFound, "could not find pod %q", req.PodSandboxId)
+	}
+	return &pb.StopPodSandboxResponse{}, nil
+}
+
+func (f *fakeCRIO) RemovePodSandbox(_ context.Context, req *pb.RemovePodSandboxRequest) (*pb.RemovePodSandboxResponse, error) {
+	if _, found := f.pods[req.PodSandboxId]; !found {
+		return nil, status.Errorf(codes.NotFound, "could not find pod %q", req.PodSandboxId)
+	}
+	delete(f.pods, req.PodSandboxId)
+	return &pb.RemovePodSandboxResponse{}, nil
+}
+
+func TestCRIORuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-crio")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "crio.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	fake := &fakeCRIO{pods: map[string]map[string]string{
+		"kubelet-pod": {kubeletPodUIDLabel: "74b1dc6c-1a1f-4d1e-8a5e-8f1b2c6a0e2b"},
+		"other-pod":   {"app": "not-from-kubelet"},
+	}}
+	server := grpc.NewServer()
+	pb.RegisterRuntimeServiceServer(server, fake)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	rt := NewContainerRuntime("crio", "unix://"+socket)
+	require.IsType(t, &CRIORuntime{}, rt)
+
+	pods, err := rt.ListContainers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kubelet-pod"}, pods)
+
+	assert.NoError(t, rt.StopContainer(context.Background(), "kubelet-pod"))
+	assert.NoError(t, rt.RemoveContainer(context.Background(), "kubelet-pod"))
+	// the pods CRI-O has already cleaned up are not errors
+	assert.NoError(t, rt.StopContainer(context.Background(), "kubelet-pod"))
+	assert.NoError(t, rt.RemoveContainer(context.Background(), "kubelet-pod"))
+
+	pods, err = rt.ListContainers(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pods)
+}
@@ -1,9 +1,14 @@
 package runtime
 
 import (
-	"github.com/pkg/errors"
-	"os/exec"
+	"context"
+	"fmt"
 	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
 )
 
 var _ ContainerRuntime = &DockerRuntime{}
@@ -12,26 +17,73 @@ type DockerRuntime struct {
 	criSocketPath string
 }
 
-func (d *DockerRuntime) ListContainers() ([]string, error) {
-	out, err := exec.Command("docker", "--host", d.criSocketPath, "ps", "-a", "--filter", "name=k8s_", "-q").CombinedOutput()
+func (d *DockerRuntime) ListContainers(ctx context.Context) ([]string, error) {
+	cli, err := d.client()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to list containers: output: %s, error", string(out))
+		return nil, err
+	}
+	defer cli.Close()
+
+	// the containers managed by dockershim are named k8s_<container>_<pod>_<namespace>_<uid>_<attempt>
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", "k8s_")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	var ids []string
+	for _, c := range containers {
+		ids = append(ids, c.ID)
 	}
-	return strings.Fields(string(out)), nil
+	return ids, nil
 }
 
-func (d *DockerRuntime) RemoveContainer(id string) error {
-	out, err := exec.Command("docker", "--host", d.criSocketPath, "rm", "--volumes", id).CombinedOutput()
+func (d *DockerRuntime) RemoveContainer(ctx context.Context, id string) error {
+	cli, err := d.client()
 	if err != nil {
-		return errors.Wrapf(err, "failed to remove container %s: output: %s, error", id, string(out))
+		return err
 	}
+	defer cli.Close()
+
+	if err := cli.ContainerRemove(ctx, id, types.ContainerRemoveOptions{RemoveVolumes: true}); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", id, err)
+	}
+	logrus.Debugf("Removed container %s", id)
 	return nil
 }
 
-func (d *DockerRuntime) StopContainer(id string) error {
-	out, err := exec.Command("docker", "--host", d.criSocketPath, "stop", id).CombinedOutput()
+func (d *DockerRuntime) StopContainer(ctx context.Context, id string) error {
+	cli, err := d.client()
 	if err != nil {
-		return errors.Wrapf(err, "failed to stop running container %s: output: %s, error", id, string(out))
+		return err
+	}
+	defer cli.Close()
+
+	// a nil timeout uses the stop timeout of the container, as docker stop does
+	if err := cli.ContainerStop(ctx, id, nil); err != nil {
+		return fmt.Errorf("failed to stop running container %s: %w", id, err)
 	}
+	logrus.Debugf("Stopped container %s", id)
 	return nil
 }
+
+func (d *DockerRuntime) client() (*client.Client, error) {
+	cli, err := client.NewClientWithOpts(client.WithHost(dockerHost(d.criSocketPath)), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return cli, nil
+}
+
+// dockerHost converts the socket given in the CRI socket flag, e.g. docker:/var/run/docker.sock, into a docker host address.
+// Plain paths are unix sockets, addresses with a scheme such as unix://, tcp:// or npipe:// are used as is.
+func dockerHost(socket string) string {
+	if socket == "" {
+		return client.DefaultDockerHost
+	}
+	if strings.Contains(socket, "://") {
+		return socket
+	}
+	return "unix://" + socket
+}
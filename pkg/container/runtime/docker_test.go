@@ -0,0 +1,16 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerHost(t *testing.T) {
+	assert.Equal(t, client.DefaultDockerHost, dockerHost(""))
+	assert.Equal(t, "unix:///var/run/docker.sock", dockerHost("/var/run/docker.sock"))
+	assert.Equal(t, "unix:///var/run/docker.sock", dockerHost("unix:///var/run/docker.sock"))
+	assert.Equal(t, "tcp://127.0.0.1:2375", dockerHost("tcp://127.0.0.1:2375"))
+	assert.Equal(t, "npipe:////./pipe/docker_engine", dockerHost("npipe:////./pipe/docker_engine"))
+}
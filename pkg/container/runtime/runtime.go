@@ -1,14 +1,28 @@
 package runtime
 
+import "context"
+
+// ContainerRuntime manages the pods of a container runtime. The calls give up once ctx is done.
 type ContainerRuntime interface {
-	ListContainers() ([]string, error)
-	RemoveContainer(id string) error
-	StopContainer(id string) error
+	ListContainers(ctx context.Context) ([]string, error)
+	RemoveContainer(ctx context.Context, id string) error
+	StopContainer(ctx context.Context, id string) error
+}
+
+// ImageRuntime is implemented by the runtimes whose images are removed along with the pods
+type ImageRuntime interface {
+	ListImages(ctx context.Context) ([]string, error)
+	RemoveImage(ctx context.Context, name string) error
 }
 
 func NewContainerRuntime(runtimeType string, criSocketPath string) ContainerRuntime {
-	if runtimeType == "docker" {
+	switch runtimeType {
+	case "docker":
 		return &DockerRuntime{criSocketPath}
+	case "crio":
+		return &CRIORuntime{CRIRuntime{criSocketPath}}
+	case "remote":
+		return &ContainerdRuntime{CRIRuntime{criSocketPath}}
 	}
 	return &CRIRuntime{criSocketPath}
 }
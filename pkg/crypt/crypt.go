@@ -0,0 +1,93 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package crypt
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MapperName is the name of the dm-crypt mapping of the encrypted data dir, under /dev/mapper
+const MapperName = "k0s-data"
+
+// DefaultImageSize is the size of the image file created for the encrypted data dir
+const DefaultImageSize = "20Gi"
+
+// Volume is a LUKS encrypted block device, or image file, mounted at the data dir of k0s
+type Volume struct {
+	// Device is the block device or image file holding the encrypted volume
+	Device string
+	// KeyFile holds the key of the volume, it's expected to be provided at boot, e.g. unsealed from the TPM
+	KeyFile string
+	// ImageSize is the size of the image file, when the Device doesn't exist yet
+	ImageSize string
+	// MountPoint is the data dir the volume is mounted at
+	MountPoint string
+
+	loopDevice string
+	mounted    bool
+}
+
+// createImage creates a sparse image file of the given size, so that the disk space is taken as the data dir grows
+func createImage(path string, size string) error {
+	q, err := resource.ParseQuantity(size)
+	if err != nil {
+		return fmt.Errorf("invalid image size %s: %w", size, err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(q.Value())
+}
+
+// dirEmpty returns true if the directory is missing or has no entries
+func dirEmpty(path string) (bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// checkKeyFile makes sure the key file exists, and warns when it can be read by others than root
+func checkKeyFile(path string) error {
+	if path == "" {
+		return fmt.Errorf("the key file of the encrypted data dir is not given")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the key file of the encrypted data dir: %w", err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		logrus.Warnf("the key file %s of the encrypted data dir can be read by other users", path)
+	}
+	return nil
+}
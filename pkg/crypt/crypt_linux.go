@@ -0,0 +1,165 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package crypt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/mount-utils"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// Open unlocks the volume with the key file and mounts it at the data dir. A missing image file is created,
+// and a block device or an image without a LUKS header is formatted, as long as it doesn't hold any other
+// filesystem. The data dir has to be empty before the volume is first mounted.
+func (v *Volume) Open() error {
+	if err := checkKeyFile(v.KeyFile); err != nil {
+		return err
+	}
+	for _, bin := range []string{"cryptsetup", "blkid", "mkfs.ext4"} {
+		if _, err := util.GetExecPath(bin); err != nil {
+			return fmt.Errorf("%s is needed for the encrypted data dir: %w", bin, err)
+		}
+	}
+
+	mounter := mount.New("")
+	if notMounted, err := mounter.IsLikelyNotMountPoint(v.MountPoint); err == nil && !notMounted {
+		logrus.Infof("%s is already mounted, not mounting the encrypted data dir", v.MountPoint)
+		return nil
+	}
+	if empty, err := dirEmpty(v.MountPoint); err != nil {
+		return err
+	} else if !empty {
+		return fmt.Errorf("the data dir %s is not empty, move its content into the encrypted volume %s first", v.MountPoint, v.Device)
+	}
+
+	mapped := "/dev/mapper/" + MapperName
+	fresh := false
+	// the mapping is left open when k0s didn't exit cleanly
+	if !util.FileExists(mapped) {
+		device, err := v.attach()
+		if err != nil {
+			return err
+		}
+		if err := run("cryptsetup", "isLuks", device); err != nil {
+			// blkid exits with 2 when it finds no signature on the device
+			if err := exec.Command("blkid", "-p", device).Run(); err == nil || !isExitCode(err, 2) {
+				v.detach()
+				return fmt.Errorf("%s is not a LUKS volume and already holds data, not formatting it", v.Device)
+			}
+			logrus.Infof("formatting %s as a LUKS volume", v.Device)
+			if err := run("cryptsetup", "luksFormat", "--batch-mode", "--type", "luks2", "--key-file", v.KeyFile, device); err != nil {
+				v.detach()
+				return err
+			}
+			fresh = true
+		}
+		if err := run("cryptsetup", "open", "--key-file", v.KeyFile, device, MapperName); err != nil {
+			v.detach()
+			return err
+		}
+	}
+	if fresh {
+		if err := run("mkfs.ext4", "-q", mapped); err != nil {
+			v.Close()
+			return err
+		}
+	}
+
+	if err := util.InitDirectory(v.MountPoint, constant.DataDirMode); err != nil {
+		v.Close()
+		return err
+	}
+	logrus.Infof("mounting the encrypted data dir %s", v.MountPoint)
+	if err := mounter.Mount(mapped, v.MountPoint, "ext4", nil); err != nil {
+		v.Close()
+		return fmt.Errorf("failed to mount the encrypted data dir: %w", err)
+	}
+	v.mounted = true
+	return os.Chmod(v.MountPoint, constant.DataDirMode)
+}
+
+// Close unmounts the data dir and locks the volume
+func (v *Volume) Close() {
+	if v.mounted {
+		if err := mount.New("").Unmount(v.MountPoint); err != nil {
+			logrus.Errorf("failed to unmount the encrypted data dir: %v", err)
+			return
+		}
+		v.mounted = false
+	}
+	if util.FileExists("/dev/mapper/" + MapperName) {
+		if err := run("cryptsetup", "close", MapperName); err != nil {
+			logrus.Errorf("failed to close the encrypted data dir: %v", err)
+			return
+		}
+	}
+	v.detach()
+}
+
+// attach returns the block device of the volume, the image files are set up as loop devices
+func (v *Volume) attach() (string, error) {
+	if !util.FileExists(v.Device) {
+		logrus.Infof("creating the image file %s for the encrypted data dir", v.Device)
+		if err := createImage(v.Device, v.ImageSize); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", v.Device, err)
+		}
+	}
+	info, err := os.Stat(v.Device)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return v.Device, nil
+	}
+	out, err := exec.Command("losetup", "--find", "--show", v.Device).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to set up a loop device for %s: %w", v.Device, err)
+	}
+	v.loopDevice = strings.TrimSpace(string(out))
+	return v.loopDevice, nil
+}
+
+func (v *Volume) detach() {
+	if v.loopDevice == "" {
+		return
+	}
+	if err := run("losetup", "--detach", v.loopDevice); err != nil {
+		logrus.Errorf("failed to detach %s: %v", v.loopDevice, err)
+	}
+	v.loopDevice = ""
+}
+
+func run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func isExitCode(err error, code int) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	return ok && exitErr.ExitCode() == code
+}
@@ -0,0 +1,28 @@
+// +build !linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package crypt
+
+import "fmt"
+
+// Open fails, dm-crypt is only available on linux
+func (v *Volume) Open() error {
+	return fmt.Errorf("the encrypted data dir is only supported on linux")
+}
+
+// Close does nothing
+func (v *Volume) Close() {}
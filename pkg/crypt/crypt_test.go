@@ -0,0 +1,74 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package crypt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-crypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	empty, err := dirEmpty(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	empty, err = dirEmpty(dir)
+	require.NoError(t, err)
+	assert.True(t, empty)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "state.db"), []byte("data"), 0600))
+	empty, err = dirEmpty(dir)
+	require.NoError(t, err)
+	assert.False(t, empty)
+}
+
+func TestCreateImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-crypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	image := filepath.Join(dir, "data.img")
+	require.NoError(t, createImage(image, "16Mi"))
+	info, err := os.Stat(image)
+	require.NoError(t, err)
+	assert.Equal(t, int64(16*1024*1024), info.Size())
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// an existing image is never overwritten
+	assert.Error(t, createImage(image, "16Mi"))
+	assert.Error(t, createImage(filepath.Join(dir, "other.img"), "lots"))
+}
+
+func TestCheckKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-crypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "key")
+	assert.Error(t, checkKeyFile(""))
+	assert.Error(t, checkKeyFile(keyFile))
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("secret"), 0400))
+	assert.NoError(t, checkKeyFile(keyFile))
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// Level describes how close the data dir mount is to running out of space
+type Level int
+
+const (
+	// LevelOK means there's enough free space and inodes
+	LevelOK Level = iota
+	// LevelWarning means the free space or inodes are getting low
+	LevelWarning
+	// LevelCritical means the free space or inodes are so low that k0s degrades to protect its state
+	LevelCritical
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelWarning:
+		return "warning"
+	case LevelCritical:
+		return "critical"
+	default:
+		return "ok"
+	}
+}
+
+// errNotSupported is returned by GetUsage on platforms without statfs
+var errNotSupported = errors.New("disk usage is not supported on this platform")
+
+// Usage holds the capacity and the free space of a mount
+type Usage struct {
+	TotalBytes  uint64
+	FreeBytes   uint64
+	TotalInodes uint64
+	FreeInodes  uint64
+}
+
+// FreeBytesPercent returns the free space as a percentage of the total
+func (u Usage) FreeBytesPercent() float64 {
+	return percent(u.FreeBytes, u.TotalBytes)
+}
+
+// FreeInodesPercent returns the free inodes as a percentage of the total
+func (u Usage) FreeInodesPercent() float64 {
+	return percent(u.FreeInodes, u.TotalInodes)
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%.1f%% space (%d MiB) and %.1f%% inodes free", u.FreeBytesPercent(), u.FreeBytes/1024/1024, u.FreeInodesPercent())
+}
+
+func percent(free, total uint64) float64 {
+	// some filesystems, e.g. btrfs, don't report any inodes
+	if total == 0 {
+		return 100
+	}
+	return float64(free) / float64(total) * 100
+}
+
+// Thresholds are the free space and inode percentages under which the levels are triggered
+type Thresholds struct {
+	WarningPercent  float64
+	CriticalPercent float64
+}
+
+// DefaultThresholds are used unless configured otherwise
+var DefaultThresholds = Thresholds{
+	WarningPercent:  15,
+	CriticalPercent: 5,
+}
+
+// Evaluate returns the level the usage falls into
+func (t Thresholds) Evaluate(u Usage) Level {
+	free := u.FreeBytesPercent()
+	if inodes := u.FreeInodesPercent(); inodes < free {
+		free = inodes
+	}
+	switch {
+	case free < t.CriticalPercent:
+		return LevelCritical
+	case free < t.WarningPercent:
+		return LevelWarning
+	default:
+		return LevelOK
+	}
+}
+
+// Preflight verifies that the data dir is writable and its mount has enough free space and inodes to start
+func Preflight(dataDir string, thresholds Thresholds) error {
+	f, err := ioutil.TempFile(dataDir, ".k0s-preflight")
+	if err != nil {
+		return fmt.Errorf("data dir %s is not writable: %w", dataDir, err)
+	}
+	f.Close()
+	_ = os.Remove(f.Name())
+
+	usage, err := GetUsage(dataDir)
+	if errors.Is(err, errNotSupported) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get the disk usage of %s: %w", dataDir, err)
+	}
+	switch thresholds.Evaluate(*usage) {
+	case LevelCritical:
+		return fmt.Errorf("data dir %s is running out of disk space: %s", dataDir, usage)
+	case LevelWarning:
+		fmt.Fprintf(os.Stderr, "WARNING: data dir %s is running low on disk space: %s\n", dataDir, usage)
+	}
+	return nil
+}
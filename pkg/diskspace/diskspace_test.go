@@ -0,0 +1,50 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package diskspace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdsEvaluate(t *testing.T) {
+	cases := []struct {
+		name  string
+		usage Usage
+		level Level
+	}{
+		{"plenty of space", Usage{TotalBytes: 100, FreeBytes: 50, TotalInodes: 100, FreeInodes: 50}, LevelOK},
+		{"low space", Usage{TotalBytes: 100, FreeBytes: 10, TotalInodes: 100, FreeInodes: 50}, LevelWarning},
+		{"no space", Usage{TotalBytes: 100, FreeBytes: 1, TotalInodes: 100, FreeInodes: 50}, LevelCritical},
+		{"no inodes", Usage{TotalBytes: 100, FreeBytes: 50, TotalInodes: 100, FreeInodes: 2}, LevelCritical},
+		{"inodes not reported", Usage{TotalBytes: 100, FreeBytes: 50}, LevelOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.level, DefaultThresholds.Evaluate(tc.usage))
+		})
+	}
+}
+
+func TestMonitorDegraded(t *testing.T) {
+	var m *Monitor
+	assert.False(t, m.Degraded())
+
+	m = &Monitor{level: int32(LevelCritical)}
+	assert.True(t, m.Degraded())
+	assert.Error(t, m.Healthy())
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package diskspace
+
+import (
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+// dataDirFreePercent exposes the free space of the data dir mount on the debug server under /debug/vars
+var dataDirFreePercent = expvar.NewFloat("k0s_data_dir_free_percent")
+
+// Monitor periodically checks the free space and inodes of the data dir mount. When the level gets
+// critical, Degraded reports true so the components can stop writing non-essential data, e.g. importing the image
+// bundles. The image pulls of the kubelet aren't affected.
+type Monitor struct {
+	Path       string
+	Thresholds Thresholds
+	Interval   time.Duration
+	History    *status.History
+
+	level      int32
+	log        *logrus.Entry
+	tickerDone chan struct{}
+}
+
+// Init sets the defaults
+func (m *Monitor) Init() error {
+	m.log = logrus.WithField("component", "disk-monitor")
+	if m.Interval == 0 {
+		m.Interval = 30 * time.Second
+	}
+	if m.Thresholds == (Thresholds{}) {
+		m.Thresholds = DefaultThresholds
+	}
+	return nil
+}
+
+// Run does the first check synchronously and then keeps checking periodically
+func (m *Monitor) Run() error {
+	m.check()
+	m.tickerDone = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.check()
+			case <-m.tickerDone:
+				m.log.Info("disk monitor done")
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the checks
+func (m *Monitor) Stop() error {
+	if m.tickerDone != nil {
+		close(m.tickerDone)
+	}
+	return nil
+}
+
+// Healthy reports an error while the free space is critical
+func (m *Monitor) Healthy() error {
+	if m.Degraded() {
+		return fmt.Errorf("data dir %s is running out of disk space", m.Path)
+	}
+	return nil
+}
+
+// Degraded returns true while the free space or inodes of the data dir mount are critical
+func (m *Monitor) Degraded() bool {
+	return m != nil && Level(atomic.LoadInt32(&m.level)) == LevelCritical
+}
+
+func (m *Monitor) check() {
+	usage, err := GetUsage(m.Path)
+	if err != nil {
+		m.log.Debugf("failed to get the disk usage of %s: %v", m.Path, err)
+		return
+	}
+	dataDirFreePercent.Set(usage.FreeBytesPercent())
+
+	level := m.Thresholds.Evaluate(*usage)
+	previous := Level(atomic.SwapInt32(&m.level, int32(level)))
+	switch level {
+	case LevelCritical:
+		m.log.Errorf("data dir %s is running out of disk space, pausing image imports: %s", m.Path, usage)
+	case LevelWarning:
+		m.log.Warnf("data dir %s is running low on disk space: %s", m.Path, usage)
+	}
+	if level == previous {
+		return
+	}
+	if level == LevelCritical {
+		m.record(status.EventUnhealthy, usage.String())
+	} else if previous == LevelCritical {
+		m.log.Infof("data dir %s has enough disk space again: %s", m.Path, usage)
+		m.record(status.EventHealthy, usage.String())
+	}
+}
+
+func (m *Monitor) record(eventType string, message string) {
+	if m.History != nil {
+		m.History.Record("DiskMonitor", eventType, message)
+	}
+}
@@ -0,0 +1,34 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package diskspace
+
+import "syscall"
+
+// GetUsage returns the usage of the mount the given path is on
+func GetUsage(path string) (*Usage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	return &Usage{
+		TotalBytes:  stat.Blocks * uint64(stat.Bsize),
+		FreeBytes:   stat.Bavail * uint64(stat.Bsize),
+		TotalInodes: stat.Files,
+		FreeInodes:  stat.Ffree,
+	}, nil
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package doctor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/diskspace"
+	"github.com/k0sproject/k0s/pkg/platform"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+// crashLoopCount is the amount of crashes within the period making a component crash-looping
+const crashLoopCount = 3
+
+// logPatterns match the output of the crashed components to the probable cause, the first match wins
+var logPatterns = []struct {
+	re         *regexp.Regexp
+	suggestion string
+}{
+	{regexp.MustCompile(`(?i)address already in use`), "Another process listens on a port of the component, find it with `ss -ltnp` and stop it or change the port in the k0s config"},
+	{regexp.MustCompile(`(?i)no space left on device`), "The disk is full, free space on the file system of the data dir"},
+	{regexp.MustCompile(`(?i)x509: certificate has expired or is not yet valid`), "The clock of the node is off or a certificate has expired, check the time with `timedatectl` and keep it in sync with NTP"},
+	{regexp.MustCompile(`(?i)database space exceeded`), "The etcd database reached its quota, defragment it or raise the quota with `quota-backend-bytes` in spec.storage.etcd.extraArgs"},
+	{regexp.MustCompile(`(?i)cannot allocate memory|out of memory`), "The node is out of memory, stop the workloads using it or add memory"},
+	{regexp.MustCompile(`(?i)permission denied`), "A file of the component isn't accessible to its user, check the ownership of the data dir, `k0s install` sets it up"},
+	{regexp.MustCompile(`(?i)connection refused`), "A dependency of the component isn't reachable, check the components it connects to, such as the API server or etcd"},
+}
+
+func notRunning(n *Node) []Finding {
+	if n.Role != "" {
+		return nil
+	}
+	f := Finding{
+		ID:         "not-running",
+		Severity:   SeverityCritical,
+		Problem:    "k0s isn't running on this node",
+		Suggestion: "Start k0s with `k0s start`, the logs of the service tell why it stopped: `journalctl -u k0scontroller -u k0sworker` with systemd, /var/log/k0s*.log with OpenRC",
+	}
+	for _, e := range n.events() {
+		if e.Type == status.EventStopped {
+			f.Evidence = []string{fmt.Sprintf("last stopped at %s: %s", e.Timestamp.Format("2006-01-02 15:04:05"), e.Message)}
+		}
+	}
+	return []Finding{f}
+}
+
+func platformSupport(n *Node) []Finding {
+	var findings []Finding
+	for _, role := range strings.Split(n.Role, "+") {
+		if role == "" {
+			continue
+		}
+		if err := platform.Preflight(role); err != nil {
+			findings = append(findings, Finding{
+				ID:         "unsupported-platform",
+				Severity:   SeverityCritical,
+				Problem:    err.Error(),
+				Suggestion: "Run the " + role + " on a supported architecture, see the system requirements",
+			})
+		}
+	}
+	return findings
+}
+
+func diskSpace(n *Node) []Finding {
+	usage, err := diskspace.GetUsage(n.K0sVars.DataDir)
+	if err != nil {
+		return nil
+	}
+	severity := SeverityWarning
+	switch diskspace.DefaultThresholds.Evaluate(*usage) {
+	case diskspace.LevelOK:
+		return nil
+	case diskspace.LevelCritical:
+		severity = SeverityCritical
+	}
+	f := Finding{
+		ID:         "disk-space",
+		Severity:   severity,
+		Problem:    fmt.Sprintf("the data dir %s is running out of disk space", n.K0sVars.DataDir),
+		Evidence:   []string{usage.String()},
+		Suggestion: "Free space on the file system of the data dir, `k0s crictl rmi --prune` removes the images no container uses",
+	}
+	var cores []string
+	for _, c := range n.crashes() {
+		if c.CoreFile != "" && util.FileExists(c.CoreFile) {
+			cores = append(cores, c.CoreFile)
+		}
+	}
+	if len(cores) > 0 {
+		f.AutoFix = fmt.Sprintf("remove the %d core dumps kept in %s", len(cores), n.K0sVars.CrashDir)
+		f.fix = func() error {
+			for _, core := range cores {
+				if err := os.Remove(core); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return []Finding{f}
+}
+
+func crashes(n *Node) []Finding {
+	byComponent := map[string][]status.Crash{}
+	var components []string
+	for _, c := range n.crashes() {
+		if c.Timestamp.Before(n.Now.Add(-n.Since)) {
+			continue
+		}
+		if _, found := byComponent[c.Component]; !found {
+			components = append(components, c.Component)
+		}
+		byComponent[c.Component] = append(byComponent[c.Component], c)
+	}
+	sort.Strings(components)
+
+	var findings []Finding
+	for _, component := range components {
+		crashes := byComponent[component]
+		last := crashes[len(crashes)-1]
+		f := Finding{
+			ID:         "crash-" + component,
+			Severity:   SeverityWarning,
+			Problem:    fmt.Sprintf("%s crashed %d times in the last %s", component, len(crashes), n.Since),
+			Evidence:   []string{last.String()},
+			Suggestion: fmt.Sprintf("Check the output of the component kept in %s", filepath.Join(last.Dir, "output.log")),
+		}
+		if len(crashes) >= crashLoopCount {
+			f.Severity = SeverityCritical
+			f.Problem = fmt.Sprintf("%s is crash-looping, %d crashes in the last %s", component, len(crashes), n.Since)
+		}
+		if line, suggestion := correlateOutput(last); suggestion != "" {
+			f.Evidence = append(f.Evidence, line)
+			f.Suggestion = suggestion
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// correlateOutput finds the probable cause of the crash in the output of the component
+func correlateOutput(c status.Crash) (string, string) {
+	if c.Signal == "killed" {
+		return "killed by SIGKILL", "The component was killed, most likely by the OOM killer, `dmesg | grep -i oom` tells"
+	}
+	output, err := c.Output()
+	if err != nil {
+		return "", ""
+	}
+	// the last lines are the closest to the crash
+	for i := len(output) - 1; i >= 0; i-- {
+		for _, p := range logPatterns {
+			if p.re.MatchString(output[i]) {
+				return strings.TrimSpace(output[i]), p.suggestion
+			}
+		}
+	}
+	return "", ""
+}
+
+func unhealthyComponents(n *Node) []Finding {
+	last := map[string]status.Event{}
+	unhealthy := map[string]int{}
+	for _, e := range n.events() {
+		switch e.Type {
+		case status.EventHealthy:
+			last[e.Component] = e
+		case status.EventUnhealthy:
+			last[e.Component] = e
+			unhealthy[e.Component]++
+		}
+	}
+	components := make([]string, 0, len(last))
+	for component := range last {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	var findings []Finding
+	for _, component := range components {
+		e := last[component]
+		switch {
+		case e.Type == status.EventUnhealthy:
+			findings = append(findings, Finding{
+				ID:         "unhealthy-" + component,
+				Severity:   SeverityWarning,
+				Problem:    fmt.Sprintf("%s is unhealthy since %s", component, e.Timestamp.Format("2006-01-02 15:04:05")),
+				Evidence:   []string{e.Message},
+				Suggestion: "Check the health of the component with `k0s status` and its logs",
+			})
+		case unhealthy[component] >= crashLoopCount:
+			findings = append(findings, Finding{
+				ID:         "flapping-" + component,
+				Severity:   SeverityInfo,
+				Problem:    fmt.Sprintf("%s turned unhealthy %d times in the last %s", component, unhealthy[component], n.Since),
+				Suggestion: "The component recovers but keeps failing, `k0s status history` shows when",
+			})
+		}
+	}
+	return findings
+}
+
+func configDrift(n *Node) []Finding {
+	report, err := status.ReadDriftReport(n.K0sVars.ConfigDriftPath)
+	if err != nil || len(report.Items) == 0 {
+		return nil
+	}
+	f := Finding{
+		ID:         "config-drift",
+		Severity:   SeverityWarning,
+		Problem:    "the components don't run with the declared config",
+		Suggestion: "Restart k0s so that the components pick up the declared config, or find what changed them",
+	}
+	for _, item := range report.Items {
+		f.Evidence = append(f.Evidence, item.String())
+	}
+	return []Finding{f}
+}
+
+func etcdSnapshot(n *Node) []Finding {
+	snapshot, err := status.ReadSnapshotStatus(n.K0sVars.EtcdSnapshotStatusPath)
+	if err != nil || !snapshot.Stale(n.Now) {
+		return nil
+	}
+	return []Finding{{
+		ID:         "etcd-snapshot",
+		Severity:   SeverityWarning,
+		Problem:    "the periodic etcd snapshots are failing",
+		Evidence:   []string{snapshot.String()},
+		Suggestion: "Check the free space and the permissions of the snapshot dir, the cluster can't be restored without a recent snapshot",
+	}}
+}
+
+func kernelNetworking(n *Node) []Finding {
+	if runtime.GOOS != "linux" || !strings.Contains(n.Role, "worker") {
+		return nil
+	}
+	var findings []Finding
+	if !util.DirExists(filepath.Join(n.procRoot, "sys/net/bridge")) {
+		findings = append(findings, Finding{
+			ID:         "br-netfilter",
+			Severity:   SeverityCritical,
+			Problem:    "the br_netfilter kernel module isn't loaded, the service traffic between the pods of the node isn't routed",
+			Suggestion: "Load the module with `modprobe br_netfilter` and add it to /etc/modules-load.d so that it's loaded on boot",
+			AutoFix:    "modprobe br_netfilter",
+			fix:        func() error { return exec.Command("modprobe", "br_netfilter").Run() },
+		})
+	}
+	ipForward := filepath.Join(n.procRoot, "sys/net/ipv4/ip_forward")
+	if value, err := ioutil.ReadFile(ipForward); err == nil && strings.TrimSpace(string(value)) != "1" {
+		findings = append(findings, Finding{
+			ID:         "ip-forward",
+			Severity:   SeverityCritical,
+			Problem:    "IPv4 forwarding is disabled, the pods can't reach the other nodes",
+			Suggestion: "Enable it with `sysctl -w net.ipv4.ip_forward=1` and set it in /etc/sysctl.d so that it's kept on boot",
+			AutoFix:    "sysctl -w net.ipv4.ip_forward=1",
+			fix:        func() error { return ioutil.WriteFile(ipForward, []byte("1"), 0644) },
+		})
+	}
+	return findings
+}
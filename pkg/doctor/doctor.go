@@ -0,0 +1,132 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package doctor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+// Finding severities, the findings are ranked in this order
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityWarning  = "WARNING"
+	SeverityInfo     = "INFO"
+)
+
+var severityRank = map[string]int{SeverityCritical: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+// Finding is a probable problem of the node along with the suggested fix
+type Finding struct {
+	ID         string   `json:"id" yaml:"id"`
+	Severity   string   `json:"severity" yaml:"severity"`
+	Problem    string   `json:"problem" yaml:"problem"`
+	Evidence   []string `json:"evidence,omitempty" yaml:"evidence,omitempty"`
+	Suggestion string   `json:"suggestion" yaml:"suggestion"`
+	// AutoFix describes the fix applied by Fix, only the fixes which can't harm the node are automated
+	AutoFix string `json:"autoFix,omitempty" yaml:"autoFix,omitempty"`
+	Fixed   bool   `json:"fixed,omitempty" yaml:"fixed,omitempty"`
+
+	fix func() error
+}
+
+// Node is the state of the node the diagnoses are made from
+type Node struct {
+	K0sVars constant.CfgVars
+	// Role is the role of the running k0s, empty if k0s isn't running
+	Role string
+	// Since is how far back the crashes and the events are taken into account
+	Since time.Duration
+	Now   time.Time
+
+	procRoot    string
+	eventList   []status.Event
+	crashList   []status.Crash
+	crashesRead bool
+}
+
+// events reads the history events of the period, oldest first
+func (n *Node) events() []status.Event {
+	if n.eventList == nil {
+		n.eventList = []status.Event{}
+		if util.FileExists(n.K0sVars.StatusHistoryPath) {
+			events, err := status.NewHistory(n.K0sVars.StatusHistoryPath, status.DefaultHistoryRetention).List(n.Now.Add(-n.Since))
+			if err == nil {
+				n.eventList = events
+			}
+		}
+	}
+	return n.eventList
+}
+
+// crashes reads all the kept crashes, oldest first
+func (n *Node) crashes() []status.Crash {
+	if !n.crashesRead {
+		n.crashList, _ = status.ListCrashes(n.K0sVars.CrashDir)
+		n.crashesRead = true
+	}
+	return n.crashList
+}
+
+type diagnosis func(n *Node) []Finding
+
+var diagnoses = []diagnosis{
+	notRunning,
+	platformSupport,
+	diskSpace,
+	crashes,
+	unhealthyComponents,
+	configDrift,
+	etcdSnapshot,
+	kernelNetworking,
+}
+
+// Diagnose correlates the status, the history, the preflight checks and the output of the crashed components
+// into the probable problems of the node, the most severe first
+func Diagnose(n Node) []Finding {
+	if n.procRoot == "" {
+		n.procRoot = "/proc"
+	}
+	var findings []Finding
+	for _, d := range diagnoses {
+		findings = append(findings, d(&n)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank[findings[i].Severity] < severityRank[findings[j].Severity]
+	})
+	return findings
+}
+
+// Fix applies the automated fixes of the findings, the ones failing are returned by their ID
+func Fix(findings []Finding) map[string]error {
+	failed := map[string]error{}
+	for i := range findings {
+		f := &findings[i]
+		if f.fix == nil {
+			continue
+		}
+		if err := f.fix(); err != nil {
+			failed[f.ID] = err
+			continue
+		}
+		f.Fixed = true
+	}
+	return failed
+}
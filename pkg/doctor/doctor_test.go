@@ -0,0 +1,112 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package doctor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+func TestDiagnose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-doctor-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	k0sVars := constant.GetConfig(dir)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := status.WriteCrash(k0sVars.CrashDir, status.Crash{Component: "etcd", Timestamp: now.Add(-time.Duration(i) * time.Minute), ExitCode: 1},
+			[]string{"starting etcd", "listen tcp 127.0.0.1:2380: bind: address already in use", "exiting"}, status.DefaultCrashRetention)
+		require.NoError(t, err)
+	}
+	_, err = status.WriteCrash(k0sVars.CrashDir, status.Crash{Component: "kube-apiserver", Timestamp: now, ExitCode: -1, Signal: "killed"}, nil, status.DefaultCrashRetention)
+	require.NoError(t, err)
+	// too old to be taken into account
+	_, err = status.WriteCrash(k0sVars.CrashDir, status.Crash{Component: "konnectivity", Timestamp: now.Add(-2 * time.Hour), ExitCode: 1}, nil, status.DefaultCrashRetention)
+	require.NoError(t, err)
+
+	findings := Diagnose(Node{K0sVars: k0sVars, Role: "controller", Since: time.Hour, Now: now})
+
+	byID := map[string]Finding{}
+	for i, f := range findings {
+		byID[f.ID] = f
+		if i > 0 {
+			assert.LessOrEqual(t, severityRank[findings[i-1].Severity], severityRank[f.Severity], "findings must be ranked by severity")
+		}
+	}
+	require.Contains(t, byID, "crash-etcd")
+	assert.Equal(t, SeverityCritical, byID["crash-etcd"].Severity)
+	assert.Contains(t, byID["crash-etcd"].Evidence, "listen tcp 127.0.0.1:2380: bind: address already in use")
+	assert.Contains(t, byID["crash-etcd"].Suggestion, "ss -ltnp")
+
+	require.Contains(t, byID, "crash-kube-apiserver")
+	assert.Equal(t, SeverityWarning, byID["crash-kube-apiserver"].Severity)
+	assert.Contains(t, byID["crash-kube-apiserver"].Suggestion, "OOM killer")
+
+	assert.NotContains(t, byID, "crash-konnectivity")
+	assert.NotContains(t, byID, "not-running")
+}
+
+func TestDiagnoseNotRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-doctor-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	findings := Diagnose(Node{K0sVars: constant.GetConfig(dir), Since: time.Hour, Now: time.Now()})
+	require.NotEmpty(t, findings)
+	assert.Equal(t, "not-running", findings[0].ID)
+	assert.Equal(t, SeverityCritical, findings[0].Severity)
+}
+
+func TestKernelNetworking(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only checked on linux")
+	}
+	procRoot, err := ioutil.TempDir("", "k0s-doctor-proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "sys/net/ipv4"), 0755))
+	ipForward := filepath.Join(procRoot, "sys/net/ipv4/ip_forward")
+	require.NoError(t, ioutil.WriteFile(ipForward, []byte("0\n"), 0644))
+
+	n := &Node{Role: "controller"}
+	n.procRoot = procRoot
+	assert.Empty(t, kernelNetworking(n), "only checked on the workers")
+
+	n.Role = "controller+worker"
+	findings := kernelNetworking(n)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "br-netfilter", findings[0].ID)
+	assert.Equal(t, "ip-forward", findings[1].ID)
+
+	// only the ip_forward fix is applied, modprobe isn't run in the tests
+	findings[0].fix = nil
+	assert.Empty(t, Fix(findings))
+	assert.True(t, findings[1].Fixed)
+	value, err := ioutil.ReadFile(ipForward)
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(value))
+}
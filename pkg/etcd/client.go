@@ -18,18 +18,36 @@ package etcd
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/pkg/transport"
 )
 
+// PeerURL returns the peer URL of the etcd member on the given address, the IPv6 addresses are bracketed
+func PeerURL(address string) string {
+	return "https://" + net.JoinHostPort(address, "2380")
+}
+
+// ClientURL returns the client URL of the etcd member on the given address
+func ClientURL(address string) string {
+	return "https://" + net.JoinHostPort(address, "2379")
+}
+
 // Client is our internal helper to access some of the etcd APIs
 type Client struct {
 	Config  *clientv3.Config
 	client  *clientv3.Client
 	tlsInfo transport.TLSInfo
+	// metricsClient scrapes the metrics endpoint, created on the first scrape
+	metricsClient *http.Client
 }
 
 // NewClient creates new Client
@@ -71,10 +89,14 @@ func (c *Client) ListMembers(ctx context.Context) (map[string]string, error) {
 	return memberList, nil
 }
 
-// AddMember add new member to etcd cluster
-func (c *Client) AddMember(ctx context.Context, name, peerAddress string) ([]string, error) {
+// AddMember add new member to etcd cluster, learners are added as non-voting members
+func (c *Client) AddMember(ctx context.Context, name, peerAddress string, learner bool) ([]string, error) {
 
-	addResp, err := c.client.MemberAdd(ctx, []string{peerAddress})
+	add := c.client.MemberAdd
+	if learner {
+		add = c.client.MemberAddAsLearner
+	}
+	addResp, err := add(ctx, []string{peerAddress})
 	if err != nil {
 		// TODO we should try to detect possible double add for a peer
 		// Not sure though if we can return correct initial-cluster as the order
@@ -112,6 +134,65 @@ func (c *Client) GetPeerIDByAddress(ctx context.Context, peerAddress string) (ui
 	return 0, fmt.Errorf("peer not found: %s", peerAddress)
 }
 
+// FindMember looks up the peer id of the member given by its name, peer address, peer URL or hex ID
+func (c *Client) FindMember(ctx context.Context, peer string) (uint64, error) {
+	resp, err := c.client.MemberList(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("etcd member list failed: %w", err)
+	}
+	return findMember(resp.Members, peer)
+}
+
+func findMember(members []*etcdserverpb.Member, peer string) (uint64, error) {
+	var found []uint64
+	for _, m := range members {
+		// the members that haven't started yet have no name
+		match := (m.Name != "" && m.Name == peer) || strconv.FormatUint(m.ID, 16) == strings.ToLower(peer)
+		for _, peerURL := range m.PeerURLs {
+			if peerURL == peer || peerURL == PeerURL(peer) {
+				match = true
+			}
+		}
+		if match {
+			found = append(found, m.ID)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return 0, fmt.Errorf("peer not found: %s", peer)
+	case 1:
+		return found[0], nil
+	default:
+		return 0, fmt.Errorf("%s matches %d members, use the peer URL or the member ID", peer, len(found))
+	}
+}
+
+// VoterClientURLs returns the non-loopback client URLs of the voting members
+func (c *Client) VoterClientURLs(ctx context.Context) ([]string, error) {
+	resp, err := c.client.MemberList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("etcd member list failed: %w", err)
+	}
+	var urls []string
+	for _, m := range resp.Members {
+		if m.IsLearner {
+			continue
+		}
+		for _, clientURL := range m.ClientURLs {
+			if u, err := url.Parse(clientURL); err == nil && !net.ParseIP(u.Hostname()).IsLoopback() && u.Hostname() != "localhost" {
+				urls = append(urls, clientURL)
+			}
+		}
+	}
+	return urls, nil
+}
+
+// PromoteMember promotes the learner to a voting member
+func (c *Client) PromoteMember(ctx context.Context, peerID uint64) error {
+	_, err := c.client.MemberPromote(ctx, peerID)
+	return err
+}
+
 // DeleteMember deletes member by peer name
 func (c *Client) DeleteMember(ctx context.Context, peerID uint64) error {
 	_, err := c.client.MemberRemove(ctx, peerID)
@@ -121,12 +202,19 @@ func (c *Client) DeleteMember(ctx context.Context, peerID uint64) error {
 // Close closes the etcd client
 func (c *Client) Close() {
 	c.client.Close()
+	if c.metricsClient != nil {
+		c.metricsClient.CloseIdleConnections()
+	}
 }
 
 // Health return err if the etcd peer is not reported as healthy
 // ref: https://github.com/etcd-io/etcd/blob/3ead91ca3edf66112d56c453169343515bba71c3/etcdctl/ctlv3/command/ep_command.go#L89
 func (c *Client) Health(ctx context.Context) error {
 	_, err := c.client.Get(ctx, "health")
+	if isLearnerError(err) {
+		// learners only serve serializable reads
+		_, err = c.client.Get(ctx, "health", clientv3.WithSerializable())
+	}
 
 	// permission denied is OK since proposal goes through consensus to get it
 	if err == nil || err == rpctypes.ErrPermissionDenied {
@@ -136,3 +224,9 @@ func (c *Client) Health(ctx context.Context) error {
 	return err
 
 }
+
+// isLearnerError tells if the request was refused as the member is a learner. The client turns the gRPC status into
+// an EtcdError, so the errors are compared by the description.
+func isLearnerError(err error) bool {
+	return err != nil && rpctypes.ErrorDesc(err) == rpctypes.ErrorDesc(rpctypes.ErrGPRCNotSupportedForLearner)
+}
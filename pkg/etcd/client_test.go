@@ -0,0 +1,67 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
+)
+
+func TestFindMember(t *testing.T) {
+	members := []*etcdserverpb.Member{
+		{ID: 0x8e9e05c52164694d, Name: "controller-1", PeerURLs: []string{"https://10.0.0.1:2380"}},
+		{ID: 0x91bc3c398fb3c146, Name: "controller-2", PeerURLs: []string{"https://[fd00::2]:2380"}},
+		// a member added but not started yet has no name
+		{ID: 0xfd422379fda50e48, PeerURLs: []string{"https://10.0.0.3:2380"}},
+	}
+
+	tests := []struct {
+		peer string
+		id   uint64
+		err  bool
+	}{
+		{peer: "controller-1", id: 0x8e9e05c52164694d},
+		{peer: "10.0.0.1", id: 0x8e9e05c52164694d},
+		{peer: "fd00::2", id: 0x91bc3c398fb3c146},
+		{peer: "https://[fd00::2]:2380", id: 0x91bc3c398fb3c146},
+		{peer: "FD422379FDA50E48", id: 0xfd422379fda50e48},
+		{peer: "10.0.0.3", id: 0xfd422379fda50e48},
+		{peer: "10.0.0.4", err: true},
+		{peer: "", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.peer, func(t *testing.T) {
+			id, err := findMember(members, tc.peer)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.id, id)
+		})
+	}
+}
+
+func TestIsLearnerError(t *testing.T) {
+	// the client returns the gRPC errors converted into EtcdErrors
+	assert.True(t, isLearnerError(rpctypes.Error(rpctypes.ErrGPRCNotSupportedForLearner)))
+	assert.True(t, isLearnerError(rpctypes.ErrGPRCNotSupportedForLearner))
+	assert.False(t, isLearnerError(rpctypes.Error(rpctypes.ErrGRPCPermissionDenied)))
+	assert.False(t, isLearnerError(nil))
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the etcd metrics telling about the trouble before etcd fails
+const (
+	metricDBSize        = "etcd_mvcc_db_total_size_in_bytes"
+	metricQuota         = "etcd_server_quota_backend_bytes"
+	metricHasLeader     = "etcd_server_has_leader"
+	metricLeaderChanges = "etcd_server_leader_changes_seen_total"
+	metricFsyncBucket   = "etcd_disk_wal_fsync_duration_seconds_bucket"
+)
+
+// the alert thresholds, the fsync latency is the one recommended by the etcd hardware guide
+const (
+	DBSizeAlertRatio    = 0.8
+	FsyncAlertP99Second = 0.01
+)
+
+// Metrics are the health metrics of the local etcd member
+type Metrics struct {
+	DBSizeBytes   int64 `json:"dbSizeBytes" yaml:"dbSizeBytes"`
+	QuotaBytes    int64 `json:"quotaBytes" yaml:"quotaBytes"`
+	HasLeader     bool  `json:"hasLeader" yaml:"hasLeader"`
+	LeaderChanges int64 `json:"leaderChanges" yaml:"leaderChanges"`
+	// FsyncP99Seconds is the 99th percentile of the WAL fsync latency since the member was started
+	FsyncP99Seconds float64 `json:"fsyncP99Seconds" yaml:"fsyncP99Seconds"`
+
+	fsyncBuckets []bucket
+}
+
+// bucket is a cumulative histogram bucket
+type bucket struct {
+	upperBound float64
+	count      float64
+}
+
+// Metrics scrapes the metrics endpoint of the local etcd member
+func (c *Client) Metrics(ctx context.Context) (*Metrics, error) {
+	if c.metricsClient == nil {
+		// the client is kept for the next scrapes, its idle connections are closed along with the etcd client
+		tlsConfig, err := c.tlsInfo.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		c.metricsClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Config.Endpoints[0]+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.metricsClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape the etcd metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to scrape the etcd metrics: %s", resp.Status)
+	}
+	return parseMetrics(resp.Body)
+}
+
+// parseMetrics picks the health metrics from the prometheus text format
+func parseMetrics(r io.Reader) (*Metrics, error) {
+	m := &Metrics{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		if i == -1 {
+			continue
+		}
+		name, labels := line[:i], ""
+		if j := strings.Index(name, "{"); j != -1 {
+			name, labels = name[:j], name[j:]
+		}
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			continue
+		}
+
+		switch name {
+		case metricDBSize:
+			m.DBSizeBytes = int64(value)
+		case metricQuota:
+			m.QuotaBytes = int64(value)
+		case metricHasLeader:
+			m.HasLeader = value == 1
+		case metricLeaderChanges:
+			m.LeaderChanges = int64(value)
+		case metricFsyncBucket:
+			upperBound, err := parseUpperBound(labels)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s bucket %q: %w", metricFsyncBucket, labels, err)
+			}
+			m.fsyncBuckets = append(m.fsyncBuckets, bucket{upperBound: upperBound, count: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(m.fsyncBuckets, func(i, j int) bool { return m.fsyncBuckets[i].upperBound < m.fsyncBuckets[j].upperBound })
+	m.FsyncP99Seconds = quantile(0.99, m.fsyncBuckets)
+	return m, nil
+}
+
+func parseUpperBound(labels string) (float64, error) {
+	const le = `le="`
+	i := strings.Index(labels, le)
+	if i == -1 {
+		return 0, fmt.Errorf("no le label")
+	}
+	value := labels[i+len(le):]
+	end := strings.Index(value, `"`)
+	if end == -1 {
+		return 0, fmt.Errorf("unterminated le label")
+	}
+	value = value[:end]
+	if value == "+Inf" {
+		return math.Inf(1), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// quantile estimates the quantile of the histogram by interpolating linearly within the bucket, as prometheus does
+func quantile(q float64, buckets []bucket) float64 {
+	if len(buckets) == 0 {
+		return 0
+	}
+	total := buckets[len(buckets)-1].count
+	if total == 0 {
+		return 0
+	}
+	rank := q * total
+	lowerBound, lowerCount := 0.0, 0.0
+	for _, b := range buckets {
+		if b.count >= rank {
+			if math.IsInf(b.upperBound, 1) {
+				// the quantile is above the highest finite bucket
+				return lowerBound
+			}
+			return lowerBound + (b.upperBound-lowerBound)*(rank-lowerCount)/(b.count-lowerCount)
+		}
+		lowerBound, lowerCount = b.upperBound, b.count
+	}
+	return lowerBound
+}
+
+// FsyncP99SecondsSince is the 99th percentile of the WAL fsync latency since the previous scrape
+func (m *Metrics) FsyncP99SecondsSince(prev *Metrics) float64 {
+	if prev == nil || len(prev.fsyncBuckets) != len(m.fsyncBuckets) {
+		return m.FsyncP99Seconds
+	}
+	buckets := make([]bucket, len(m.fsyncBuckets))
+	for i, b := range m.fsyncBuckets {
+		buckets[i] = bucket{upperBound: b.upperBound, count: b.count - prev.fsyncBuckets[i].count}
+		if buckets[i].count < 0 {
+			// etcd was restarted in between
+			return m.FsyncP99Seconds
+		}
+	}
+	return quantile(0.99, buckets)
+}
+
+// DBSizeRatio is the share of the backend quota used by the database
+func (m *Metrics) DBSizeRatio() float64 {
+	if m.QuotaBytes == 0 {
+		return 0
+	}
+	return float64(m.DBSizeBytes) / float64(m.QuotaBytes)
+}
+
+// Alerts explains the metrics that are past their thresholds
+func (m *Metrics) Alerts() []string {
+	var alerts []string
+	if !m.HasLeader {
+		alerts = append(alerts, "the etcd member has no leader")
+	}
+	if ratio := m.DBSizeRatio(); ratio >= DBSizeAlertRatio {
+		alerts = append(alerts, fmt.Sprintf("the etcd database uses %.0f%% of its %d bytes quota, compact and defragment it or raise --quota-backend-bytes", ratio*100, m.QuotaBytes))
+	}
+	if m.FsyncP99Seconds >= FsyncAlertP99Second {
+		alerts = append(alerts, fmt.Sprintf("the etcd WAL fsync p99 latency is %.1fms, above the recommended %.0fms, the disk is too slow for etcd", m.FsyncP99Seconds*1000, FsyncAlertP99Second*1000))
+	}
+	return alerts
+}
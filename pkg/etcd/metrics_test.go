@@ -0,0 +1,82 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const etcdMetrics = `# HELP etcd_disk_wal_fsync_duration_seconds The latency distributions of fsync called by WAL.
+# TYPE etcd_disk_wal_fsync_duration_seconds histogram
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.001"} 0
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.002"} 50
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.004"} 90
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.008"} 98
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.016"} 100
+etcd_disk_wal_fsync_duration_seconds_bucket{le="+Inf"} 100
+etcd_disk_wal_fsync_duration_seconds_sum 0.3
+etcd_disk_wal_fsync_duration_seconds_count 100
+# HELP etcd_mvcc_db_total_size_in_bytes Total size of the underlying database physically allocated in bytes.
+# TYPE etcd_mvcc_db_total_size_in_bytes gauge
+etcd_mvcc_db_total_size_in_bytes 1.8e+09
+etcd_server_has_leader 1
+etcd_server_leader_changes_seen_total 3
+etcd_server_quota_backend_bytes 2.147483648e+09
+`
+
+func TestParseMetrics(t *testing.T) {
+	m, err := parseMetrics(strings.NewReader(etcdMetrics))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1800000000), m.DBSizeBytes)
+	assert.Equal(t, int64(2147483648), m.QuotaBytes)
+	assert.True(t, m.HasLeader)
+	assert.Equal(t, int64(3), m.LeaderChanges)
+	// the 99th sample is half way in the 8-16ms bucket
+	assert.InDelta(t, 0.012, m.FsyncP99Seconds, 0.0001)
+
+	alerts := m.Alerts()
+	require.Len(t, alerts, 2)
+	assert.Contains(t, alerts[0], "84% of its 2147483648 bytes quota")
+	assert.Contains(t, alerts[1], "12.0ms")
+}
+
+func TestFsyncP99SecondsSince(t *testing.T) {
+	prev, err := parseMetrics(strings.NewReader(etcdMetrics))
+	require.NoError(t, err)
+	// all of the 100 new fsyncs took less than 2ms
+	m, err := parseMetrics(strings.NewReader(strings.NewReplacer(
+		`le="0.001"} 0`, `le="0.001"} 0`,
+		`le="0.002"} 50`, `le="0.002"} 150`,
+		`le="0.004"} 90`, `le="0.004"} 190`,
+		`le="0.008"} 98`, `le="0.008"} 198`,
+		`le="0.016"} 100`, `le="0.016"} 200`,
+		`le="+Inf"} 100`, `le="+Inf"} 200`,
+	).Replace(etcdMetrics)))
+	require.NoError(t, err)
+
+	assert.InDelta(t, 0.00199, m.FsyncP99SecondsSince(prev), 0.00001)
+	assert.Equal(t, m.FsyncP99Seconds, m.FsyncP99SecondsSince(nil))
+}
+
+func TestMetricsAlertsNoLeader(t *testing.T) {
+	m := &Metrics{}
+	assert.Equal(t, []string{"the etcd member has no leader"}, m.Alerts())
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// HardwareClass tells how etcd is tuned for the machine it runs on
+type HardwareClass string
+
+const (
+	// HardwareStandard keeps the etcd defaults
+	HardwareStandard HardwareClass = "standard"
+	// HardwareSlowDisk is a disk too slow for the default timeouts, e.g. an SD card
+	HardwareSlowDisk HardwareClass = "slow-disk"
+)
+
+// the probe thresholds, the fsync latency is the one recommended by the etcd hardware guide
+const (
+	slowFsyncLatency = time.Duration(FsyncAlertP99Second * float64(time.Second))
+	lowMemoryBytes   = 2 * 1024 * 1024 * 1024
+	fsyncProbeWrites = 10
+)
+
+// the timeouts and quota bounds, etcd wants the election timeout to be at least five times the heartbeat interval
+const (
+	slowDiskHeartbeatInterval = 500 * time.Millisecond
+	slowDiskElectionTimeout   = 5 * time.Second
+	maxElectionTimeout        = 50 * time.Second
+	minQuotaBackendBytes      = 512 * 1024 * 1024
+)
+
+// Hardware is what's detected of the machine when etcd is started
+type Hardware struct {
+	// MemoryBytes is the total memory, 0 when unknown
+	MemoryBytes uint64
+	// FsyncLatency is the average latency of a small write and fsync in the data dir
+	FsyncLatency time.Duration
+	// DBSizeBytes is the size of the existing etcd database, 0 for a new member
+	DBSizeBytes int64
+}
+
+// Class returns the hardware class of the detected disk latency
+func (h Hardware) Class() HardwareClass {
+	if h.FsyncLatency > slowFsyncLatency {
+		return HardwareSlowDisk
+	}
+	return HardwareStandard
+}
+
+// LowMemory tells if the etcd database quota needs to be reduced to avoid swapping
+func (h Hardware) LowMemory() bool {
+	return h.MemoryBytes > 0 && h.MemoryBytes < lowMemoryBytes
+}
+
+func (h Hardware) String() string {
+	return fmt.Sprintf("%s, %d MiB memory, %s fsync latency", h.Class(), h.MemoryBytes/1024/1024, h.FsyncLatency)
+}
+
+// ProbeHardware measures the fsync latency in the given data dir, reads the total memory and the size of the existing
+// database
+func ProbeHardware(dataDir string) (*Hardware, error) {
+	memory, err := totalMemory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the total memory: %w", err)
+	}
+	latency, err := fsyncLatency(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure the fsync latency: %w", err)
+	}
+	hw := &Hardware{MemoryBytes: memory, FsyncLatency: latency}
+	if info, err := os.Stat(filepath.Join(dataDir, "member", "snap", "db")); err == nil {
+		hw.DBSizeBytes = info.Size()
+	}
+	return hw, nil
+}
+
+func fsyncLatency(dir string) (time.Duration, error) {
+	f, err := ioutil.TempFile(dir, ".fsync-probe")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	block := make([]byte, 4096)
+	start := time.Now()
+	for i := 0; i < fsyncProbeWrites; i++ {
+		if _, err := f.Write(block); err != nil {
+			return 0, err
+		}
+		if err := f.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	return time.Since(start) / fsyncProbeWrites, nil
+}
+
+// TuningArgs returns the etcd flags for the detected hardware, the values set in the tuning spec take precedence.
+// The etcd defaults are kept on standard hardware without overrides.
+func TuningArgs(hw Hardware, spec *v1beta1.EtcdTuningSpec) map[string]string {
+	var heartbeat, election time.Duration
+	var quota int64
+	if hw.Class() == HardwareSlowDisk {
+		heartbeat, election = slowDiskHeartbeatInterval, slowDiskElectionTimeout
+	}
+	if hw.LowMemory() {
+		quota = int64(hw.MemoryBytes / 2)
+		if quota < minQuotaBackendBytes {
+			quota = minQuotaBackendBytes
+		}
+		// an existing database larger than that would go straight into NOSPACE, it's given a quarter more room
+		if withHeadroom := hw.DBSizeBytes + hw.DBSizeBytes/4; quota < withHeadroom {
+			quota = withHeadroom
+		}
+	}
+
+	// the spec is validated already, a heartbeat override keeps the tuned election timeout in bounds
+	if spec != nil {
+		if d, err := time.ParseDuration(spec.HeartbeatInterval); err == nil {
+			heartbeat = d
+			if election < 5*heartbeat {
+				election = 10 * heartbeat
+			}
+			if election > maxElectionTimeout {
+				election = maxElectionTimeout
+			}
+		}
+		if d, err := time.ParseDuration(spec.ElectionTimeout); err == nil {
+			election = d
+		}
+		if spec.QuotaBackendBytes > 0 {
+			quota = spec.QuotaBackendBytes
+		}
+	}
+
+	args := map[string]string{}
+	if heartbeat > 0 {
+		args["--heartbeat-interval"] = milliseconds(heartbeat)
+	}
+	if election > 0 {
+		args["--election-timeout"] = milliseconds(election)
+	}
+	if quota > 0 {
+		args["--quota-backend-bytes"] = strconv.FormatInt(quota, 10)
+	}
+	return args
+}
+
+func milliseconds(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package etcd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestTuningArgs(t *testing.T) {
+	standard := Hardware{MemoryBytes: 8 * 1024 * 1024 * 1024, FsyncLatency: time.Millisecond}
+	sdCard := Hardware{MemoryBytes: 1024 * 1024 * 1024, FsyncLatency: 40 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		hw   Hardware
+		spec *v1beta1.EtcdTuningSpec
+		want map[string]string
+	}{
+		{
+			name: "standard keeps the etcd defaults",
+			hw:   standard,
+			want: map[string]string{},
+		},
+		{
+			name: "slow disk and low memory",
+			hw:   sdCard,
+			want: map[string]string{
+				"--heartbeat-interval":  "500",
+				"--election-timeout":    "5000",
+				"--quota-backend-bytes": "536870912",
+			},
+		},
+		{
+			name: "low memory keeps room for the existing database",
+			hw:   Hardware{MemoryBytes: 1024 * 1024 * 1024, FsyncLatency: time.Millisecond, DBSizeBytes: 800 * 1024 * 1024},
+			want: map[string]string{
+				"--quota-backend-bytes": "1048576000",
+			},
+		},
+		{
+			name: "the scaled election timeout stays within the etcd maximum",
+			hw:   standard,
+			spec: &v1beta1.EtcdTuningSpec{HeartbeatInterval: "5s"},
+			want: map[string]string{
+				"--heartbeat-interval": "5000",
+				"--election-timeout":   "50000",
+			},
+		},
+		{
+			name: "heartbeat override scales the election timeout",
+			hw:   standard,
+			spec: &v1beta1.EtcdTuningSpec{HeartbeatInterval: "200ms"},
+			want: map[string]string{
+				"--heartbeat-interval": "200",
+				"--election-timeout":   "2000",
+			},
+		},
+		{
+			name: "overrides take precedence",
+			hw:   sdCard,
+			spec: &v1beta1.EtcdTuningSpec{HeartbeatInterval: "300ms", ElectionTimeout: "10s", QuotaBackendBytes: 1024 * 1024 * 1024},
+			want: map[string]string{
+				"--heartbeat-interval":  "300",
+				"--election-timeout":    "10000",
+				"--quota-backend-bytes": "1073741824",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, TuningArgs(tt.hw, tt.spec))
+		})
+	}
+}
+
+func TestProbeHardware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-etcd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hw, err := ProbeHardware(dir)
+	require.NoError(t, err)
+	assert.NotZero(t, hw.FsyncLatency)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package exitcode defines the categories of the errors the k0s commands fail with, and their exit codes. The codes
+// and the category names are part of the command line interface, so that the provisioning tools can tell the
+// failures apart without parsing the messages. They must not change.
+package exitcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Category is the machine-readable kind of an error
+type Category string
+
+const (
+	// Runtime is a failure without a category of its own, exit code 1
+	Runtime Category = "runtime"
+	// Usage is an invalid flag or argument, exit code 2
+	Usage Category = "usage"
+	// Config is a cluster config which can't be read or doesn't pass the validation, exit code 3
+	Config Category = "config"
+	// Preflight is a host not meeting the requirements of k0s, exit code 4
+	Preflight Category = "preflight"
+	// Join is a join token which is invalid or was refused by the controllers, exit code 5
+	Join Category = "join"
+)
+
+var codes = map[Category]int{
+	Runtime:   1,
+	Usage:     2,
+	Config:    3,
+	Preflight: 4,
+	Join:      5,
+}
+
+// Error is an error of a category
+type Error struct {
+	Category Category
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns the error with the category, nil for nil. The innermost category of an error wins, so wrapping an
+// error of a category again doesn't change it.
+func Wrap(category Category, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Category: category, Err: err}
+}
+
+// CategoryOf returns the category of the error, runtime if it has none
+func CategoryOf(err error) Category {
+	var category Category
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			break
+		}
+		category, err = e.Category, e.Err
+	}
+	if category == "" {
+		return Runtime
+	}
+	return category
+}
+
+// Code returns the exit code of the error, 0 for nil
+func Code(err error) int {
+	if err == nil {
+		return 0
+	}
+	return codes[CategoryOf(err)]
+}
+
+// Print writes the error for the users, or as a JSON object with the category and the exit code for the format json
+func Print(w io.Writer, err error, format string) {
+	if format == "json" {
+		_ = json.NewEncoder(w).Encode(struct {
+			Error    string   `json:"error"`
+			Category Category `json:"category"`
+			ExitCode int      `json:"exitCode"`
+		}{err.Error(), CategoryOf(err), Code(err)})
+		return
+	}
+	fmt.Fprintf(w, "Error: %v\n", err)
+}
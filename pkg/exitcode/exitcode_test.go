@@ -0,0 +1,53 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package exitcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategories(t *testing.T) {
+	assert.Equal(t, 0, Code(nil))
+	assert.Nil(t, Wrap(Join, nil))
+
+	err := errors.New("failed")
+	assert.Equal(t, Runtime, CategoryOf(err))
+	assert.Equal(t, 1, Code(err))
+
+	preflight := fmt.Errorf("failed to start: %w", Wrap(Preflight, err))
+	assert.Equal(t, Preflight, CategoryOf(preflight))
+	assert.Equal(t, 4, Code(preflight))
+	assert.True(t, errors.Is(preflight, err))
+
+	// the innermost category wins
+	assert.Equal(t, Join, CategoryOf(Wrap(Config, fmt.Errorf("invalid: %w", Wrap(Join, err)))))
+}
+
+func TestPrint(t *testing.T) {
+	err := Wrap(Config, errors.New("spec.api.port: 0 is not a valid port"))
+	var buf bytes.Buffer
+	Print(&buf, err, "text")
+	assert.Equal(t, "Error: spec.api.port: 0 is not a valid port\n", buf.String())
+
+	buf.Reset()
+	Print(&buf, err, "json")
+	assert.JSONEq(t, `{"error": "spec.api.port: 0 is not a valid port", "category": "config", "exitCode": 3}`, buf.String())
+}
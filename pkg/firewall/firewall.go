@@ -0,0 +1,179 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package firewall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// ruleComment marks the nftables rules managed by k0s
+const ruleComment = "k0s"
+
+// ErrNoFirewall is returned when neither a running firewalld nor nftables is found on the host
+var ErrNoFirewall = errors.New("no supported firewall found")
+
+// runFunc runs the command with the input on stdin and returns its output
+type runFunc func(input []byte, name string, args ...string) ([]byte, error)
+
+// Sync opens the ports of the rules in the host firewall which aren't open, with the backend of the rules or the one
+// detected on the host. Returns the ports it opened.
+func Sync(rules *config.FirewallRules) ([]string, error) {
+	return syncWith(rules, execRun)
+}
+
+func syncWith(rules *config.FirewallRules, run runFunc) ([]string, error) {
+	backend := rules.Backend
+	if backend == "" {
+		backend = detect(run)
+	}
+	switch backend {
+	case config.FirewallBackendFirewalld:
+		return syncFirewalld(run, rules.Zone, rules.Ports)
+	case config.FirewallBackendNftables:
+		chain := rules.Chain
+		if chain == "" {
+			chain = config.DefaultFirewallChain
+		}
+		return syncNftables(run, chain, rules.Ports)
+	}
+	return nil, ErrNoFirewall
+}
+
+// detect prefers firewalld when it's running, as it overwrites the nftables rules it doesn't know of on reloads
+func detect(run runFunc) string {
+	if _, err := run(nil, "firewall-cmd", "--state"); err == nil {
+		return config.FirewallBackendFirewalld
+	}
+	if _, err := exec.LookPath("nft"); err == nil {
+		return config.FirewallBackendNftables
+	}
+	return ""
+}
+
+// syncFirewalld adds the missing ports to both the runtime and the permanent config of the zone
+func syncFirewalld(run runFunc, zone string, ports []config.FirewallPort) ([]string, error) {
+	var zoneArgs []string
+	if zone != "" {
+		zoneArgs = []string{"--zone=" + zone}
+	}
+	var opened []string
+	for _, permanent := range []bool{false, true} {
+		args := append([]string{}, zoneArgs...)
+		if permanent {
+			args = append([]string{"--permanent"}, args...)
+		}
+		out, err := run(nil, "firewall-cmd", append(args, "--list-ports")...)
+		if err != nil {
+			return opened, fmt.Errorf("failed to list the ports of firewalld: %w: %s", err, out)
+		}
+		open := map[string]bool{}
+		for _, p := range strings.Fields(string(out)) {
+			open[p] = true
+		}
+		var missing []string
+		for _, p := range ports {
+			if !open[p.String()] {
+				missing = append(missing, p.String())
+				open[p.String()] = true
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		addArgs := append([]string{}, args...)
+		for _, p := range missing {
+			addArgs = append(addArgs, "--add-port="+p)
+		}
+		if out, err := run(nil, "firewall-cmd", addArgs...); err != nil {
+			return opened, fmt.Errorf("failed to open %s in firewalld: %w: %s", strings.Join(missing, " "), err, out)
+		}
+		if !permanent {
+			opened = missing
+		}
+	}
+	return opened, nil
+}
+
+var nftRule = regexp.MustCompile(`^(.+) comment "` + ruleComment + `" # handle (\d+)$`)
+
+// syncNftables keeps an accept rule for each port at the start of the chain. The rules of k0s are marked with a
+// comment, they're replaced in a single transaction when they don't match the ports.
+func syncNftables(run runFunc, chain string, ports []config.FirewallPort) ([]string, error) {
+	out, err := run(nil, "nft", append([]string{"-a", "list", "chain"}, strings.Fields(chain)...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the nftables chain %s: %w: %s", chain, err, bytes.TrimSpace(out))
+	}
+	current := map[string]string{}
+	var handles []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := nftRule.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			current[m[1]] = m[2]
+			handles = append(handles, m[2])
+		}
+	}
+
+	var rules, opened []string
+	for _, p := range ports {
+		rule := nftablesRule(p)
+		rules = append(rules, rule)
+		if _, ok := current[rule]; !ok {
+			opened = append(opened, p.String())
+		}
+	}
+	if len(opened) == 0 && len(handles) == len(rules) {
+		return nil, nil
+	}
+
+	var script strings.Builder
+	for _, handle := range handles {
+		fmt.Fprintf(&script, "delete rule %s handle %s\n", chain, handle)
+	}
+	// inserted in reverse, as each rule goes to the start of the chain
+	for i := len(rules) - 1; i >= 0; i-- {
+		fmt.Fprintf(&script, "insert rule %s %s comment \"%s\"\n", chain, rules[i], ruleComment)
+	}
+	if out, err := run([]byte(script.String()), "nft", "-f", "-"); err != nil {
+		return nil, fmt.Errorf("failed to update the nftables chain %s: %w: %s", chain, err, bytes.TrimSpace(out))
+	}
+	return opened, nil
+}
+
+// nftablesRule formats the port the way nft lists it
+func nftablesRule(p config.FirewallPort) string {
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if p.EndPort > p.Port {
+		return fmt.Sprintf("%s dport %d-%d accept", protocol, p.Port, p.EndPort)
+	}
+	return fmt.Sprintf("%s dport %d accept", protocol, p.Port)
+}
+
+func execRun(input []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	return cmd.CombinedOutput()
+}
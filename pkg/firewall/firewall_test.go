@@ -0,0 +1,121 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package firewall
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// fakeRunner records the commands and answers them from the outputs by the command line
+type fakeRunner struct {
+	outputs  map[string]string
+	failing  map[string]bool
+	commands []string
+	inputs   []string
+}
+
+func (f *fakeRunner) run(input []byte, name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	f.commands = append(f.commands, cmd)
+	if input != nil {
+		f.inputs = append(f.inputs, string(input))
+	}
+	if f.failing[cmd] {
+		return []byte("failed"), fmt.Errorf("exit status 1")
+	}
+	return []byte(f.outputs[cmd]), nil
+}
+
+var testPorts = []config.FirewallPort{
+	{Port: 6443, Name: "kube-apiserver"},
+	{Port: 30000, EndPort: 32767, Protocol: "udp", Name: "nodeports"},
+}
+
+func TestSyncFirewalld(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"firewall-cmd --zone=public --list-ports":             "22/tcp 6443/tcp\n",
+		"firewall-cmd --permanent --zone=public --list-ports": "22/tcp 6443/tcp 30000-32767/udp\n",
+	}}
+	rules := &config.FirewallRules{Backend: config.FirewallBackendFirewalld, Zone: "public", Ports: testPorts}
+	opened, err := syncWith(rules, runner.run)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"30000-32767/udp"}, opened)
+	assert.Equal(t, []string{
+		"firewall-cmd --zone=public --list-ports",
+		"firewall-cmd --zone=public --add-port=30000-32767/udp",
+		"firewall-cmd --permanent --zone=public --list-ports",
+	}, runner.commands)
+}
+
+func TestSyncNftables(t *testing.T) {
+	list := "nft -a list chain inet filter input"
+	runner := &fakeRunner{outputs: map[string]string{list: `table inet filter {
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		tcp dport 6443 accept comment "k0s" # handle 7
+		tcp dport 10250 accept comment "k0s" # handle 8
+		ct state established,related accept # handle 3
+	}
+}
+`}}
+	rules := &config.FirewallRules{Backend: config.FirewallBackendNftables, Ports: testPorts}
+	opened, err := syncWith(rules, runner.run)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"30000-32767/udp"}, opened)
+	require.Len(t, runner.inputs, 1)
+	assert.Equal(t, `delete rule inet filter input handle 7
+delete rule inet filter input handle 8
+insert rule inet filter input udp dport 30000-32767 accept comment "k0s"
+insert rule inet filter input tcp dport 6443 accept comment "k0s"
+`, runner.inputs[0])
+
+	// nothing to do once the rules match
+	runner.outputs[list] = `table inet filter {
+	chain input { # handle 1
+		tcp dport 6443 accept comment "k0s" # handle 9
+		udp dport 30000-32767 accept comment "k0s" # handle 10
+	}
+}
+`
+	runner.inputs = nil
+	opened, err = syncWith(rules, runner.run)
+	require.NoError(t, err)
+	assert.Empty(t, opened)
+	assert.Empty(t, runner.inputs)
+
+	runner.failing = map[string]bool{list: true}
+	_, err = syncWith(rules, runner.run)
+	assert.Error(t, err)
+}
+
+func TestSyncDetectsFirewalld(t *testing.T) {
+	runner := &fakeRunner{outputs: map[string]string{
+		"firewall-cmd --state":                  "running",
+		"firewall-cmd --list-ports":             "6443/tcp 30000-32767/udp",
+		"firewall-cmd --permanent --list-ports": "6443/tcp 30000-32767/udp",
+	}}
+	opened, err := syncWith(&config.FirewallRules{Ports: testPorts}, runner.run)
+	require.NoError(t, err)
+	assert.Empty(t, opened)
+	assert.Len(t, runner.commands, 3)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hostnetwork
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Network is a network the host is attached to, through an interface address or a route
+type Network struct {
+	Net    *net.IPNet
+	Source string // e.g. "address of eth0" or "route via wg0"
+}
+
+// Overlap is a host network overlapping one of the cluster CIDRs
+type Overlap struct {
+	CIDR string
+	Network
+}
+
+func (o Overlap) String() string {
+	return fmt.Sprintf("%s overlaps %s (%s)", o.CIDR, o.Net, o.Source)
+}
+
+// the interfaces the CNIs and kube-proxy create for the cluster networks themselves. On Windows only the host vNIC of
+// the Calico_ep endpoint which the Calico installer of k0s creates is matched, the other vEthernet interfaces are the
+// vNICs of the host networks, e.g. "vEthernet (Ethernet)" once the HNS network is bound to the host adapter.
+var clusterInterfacePrefixes = []string{"kube-bridge", "kube-dummy-if", "kube-ipvs0", "cali", "tunl0", "vxlan.calico", "cni0", "vEthernet (Calico_ep)"}
+
+func isClusterInterface(name string) bool {
+	for _, prefix := range clusterInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Preflight checks the cluster CIDRs against the host interfaces and routes, a pod or service CIDR overlapping a
+// network the host is attached to makes the hosts there unreachable from the pods and the node. With warnOnly the
+// overlaps are only logged.
+func Preflight(podCIDRs, serviceCIDRs []string, warnOnly bool) error {
+	err := check(podCIDRs, serviceCIDRs)
+	if err != nil && warnOnly {
+		logrus.Warnf("%v, starting anyway as requested", err)
+		return nil
+	}
+	return err
+}
+
+func check(podCIDRs, serviceCIDRs []string) error {
+	networks, err := hostNetworks()
+	if err != nil {
+		logrus.Warnf("failed to list the host networks, skipping the network overlap check: %v", err)
+		return nil
+	}
+	overlaps, err := findOverlaps(podCIDRs, serviceCIDRs, networks)
+	if err != nil {
+		return err
+	}
+	if len(overlaps) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(overlaps))
+	for i, o := range overlaps {
+		msgs[i] = o.String()
+	}
+	return fmt.Errorf("the cluster networks overlap the host networks: %s. Change the pod and service CIDRs or use --ignore-network-overlap", strings.Join(msgs, "; "))
+}
+
+// findOverlaps returns the host networks overlapping the cluster CIDRs. Once the CNI has set the node up, it has
+// routed the pod subnets of the other nodes, so the routes within the pod CIDRs are expected then.
+func findOverlaps(podCIDRs, serviceCIDRs []string, networks []hostNetwork) ([]Overlap, error) {
+	cniSetUp := false
+	for _, n := range networks {
+		if n.cluster {
+			cniSetUp = true
+		}
+	}
+
+	var overlaps []Overlap
+	for i, cidr := range append(append([]string{}, podCIDRs...), serviceCIDRs...) {
+		_, clusterNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster CIDR %s: %w", cidr, err)
+		}
+		isPodCIDR := i < len(podCIDRs)
+		for _, n := range networks {
+			if n.cluster || !overlap(clusterNet, n.Net) {
+				continue
+			}
+			if n.route && cniSetUp && isPodCIDR && contains(clusterNet, n.Net) {
+				continue
+			}
+			overlaps = append(overlaps, Overlap{CIDR: cidr, Network: n.Network})
+		}
+	}
+	return overlaps, nil
+}
+
+// hostNetwork is a host network along with how it was found
+type hostNetwork struct {
+	Network
+	route   bool
+	cluster bool
+}
+
+func hostNetworks() ([]hostNetwork, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var networks []hostNetwork
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || !ipnet.IP.IsGlobalUnicast() {
+				continue
+			}
+			networks = append(networks, hostNetwork{
+				Network: Network{Net: &net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}, Source: "address of " + iface.Name},
+				cluster: isClusterInterface(iface.Name),
+			})
+		}
+	}
+
+	routes, err := routes()
+	if err != nil {
+		return nil, err
+	}
+	return append(networks, routes...), nil
+}
+
+func overlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// contains tells if inner lies entirely within outer
+func contains(outer, inner *net.IPNet) bool {
+	outerSize, _ := outer.Mask.Size()
+	innerSize, _ := inner.Mask.Size()
+	return outer.Contains(inner.IP) && innerSize >= outerSize
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hostnetwork
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func network(cidr, source string, route, cluster bool) hostNetwork {
+	_, ipnet, _ := net.ParseCIDR(cidr)
+	return hostNetwork{Network: Network{Net: ipnet, Source: source}, route: route, cluster: cluster}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	pod := []string{"10.244.0.0/16"}
+	svc := []string{"10.96.0.0/12"}
+
+	tests := []struct {
+		name     string
+		networks []hostNetwork
+		want     []string
+	}{
+		{
+			name:     "no overlap",
+			networks: []hostNetwork{network("192.168.1.0/24", "address of eth0", false, false)},
+		},
+		{
+			name:     "lan in the pod CIDR",
+			networks: []hostNetwork{network("10.244.5.0/24", "address of eth0", false, false)},
+			want:     []string{"10.244.0.0/16 overlaps 10.244.5.0/24 (address of eth0)"},
+		},
+		{
+			name:     "vpn route covering the cluster CIDRs",
+			networks: []hostNetwork{network("10.0.0.0/8", "route via wg0", true, false)},
+			want: []string{
+				"10.244.0.0/16 overlaps 10.0.0.0/8 (route via wg0)",
+				"10.96.0.0/12 overlaps 10.0.0.0/8 (route via wg0)",
+			},
+		},
+		{
+			name:     "routes of a fresh node",
+			networks: []hostNetwork{network("10.244.1.0/24", "route via eth0", true, false)},
+			want:     []string{"10.244.0.0/16 overlaps 10.244.1.0/24 (route via eth0)"},
+		},
+		{
+			name: "pod subnet routes set up by the CNI",
+			networks: []hostNetwork{
+				network("10.244.0.0/24", "address of kube-bridge", false, true),
+				network("10.244.1.0/24", "route via eth0", true, false),
+				network("10.96.0.1/32", "address of kube-ipvs0", false, true),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overlaps, err := findOverlaps(pod, svc, tt.networks)
+			require.NoError(t, err)
+			var got []string
+			for _, o := range overlaps {
+				got = append(got, o.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIsClusterInterface(t *testing.T) {
+	for name, cluster := range map[string]bool{
+		"kube-bridge":           true,
+		"cali1234":              true,
+		"vEthernet (Calico_ep)": true,
+		"vEthernet (Ethernet)":  false,
+		"vEthernet (nat)":       false,
+		"eth0":                  false,
+	} {
+		assert.Equal(t, cluster, isClusterInterface(name), name)
+	}
+}
@@ -0,0 +1,100 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hostnetwork
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	routePath     = "/proc/net/route"
+	ipv6RoutePath = "/proc/net/ipv6_route"
+)
+
+// routes lists the IPv4 and IPv6 routes of the host, but the default ones
+func routes() ([]hostNetwork, error) {
+	var networks []hostNetwork
+	data, err := ioutil.ReadFile(routePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	networks = append(networks, parseRoutes(string(data))...)
+
+	data, err = ioutil.ReadFile(ipv6RoutePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return append(networks, parseIPv6Routes(string(data))...), nil
+}
+
+// parseRoutes parses /proc/net/route, the addresses are hex encoded in the host byte order
+func parseRoutes(data string) []hostNetwork {
+	var networks []hostNetwork
+	for _, line := range strings.Split(data, "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		dest, err1 := strconv.ParseUint(fields[1], 16, 32)
+		mask, err2 := strconv.ParseUint(fields[7], 16, 32)
+		if err1 != nil || err2 != nil || mask == 0 {
+			continue
+		}
+		ipnet := &net.IPNet{IP: make(net.IP, 4), Mask: make(net.IPMask, 4)}
+		binary.LittleEndian.PutUint32(ipnet.IP, uint32(dest))
+		binary.LittleEndian.PutUint32(ipnet.Mask, uint32(mask))
+		networks = append(networks, routeNetwork(ipnet, fields[0]))
+	}
+	return networks
+}
+
+// parseIPv6Routes parses /proc/net/ipv6_route, the link-local and multicast routes are skipped
+func parseIPv6Routes(data string) []hostNetwork {
+	var networks []hostNetwork
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+		dest, err1 := hex.DecodeString(fields[0])
+		prefixLen, err2 := strconv.ParseUint(fields[1], 16, 8)
+		if err1 != nil || err2 != nil || len(dest) != net.IPv6len || prefixLen == 0 {
+			continue
+		}
+		ip := net.IP(dest)
+		if !ip.IsGlobalUnicast() {
+			continue
+		}
+		networks = append(networks, routeNetwork(&net.IPNet{IP: ip, Mask: net.CIDRMask(int(prefixLen), 128)}, fields[9]))
+	}
+	return networks
+}
+
+func routeNetwork(ipnet *net.IPNet, iface string) hostNetwork {
+	return hostNetwork{
+		Network: Network{Net: ipnet, Source: "route via " + iface},
+		route:   true,
+		cluster: isClusterInterface(iface),
+	}
+}
@@ -0,0 +1,47 @@
+// +build linux
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hostnetwork
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes := parseRoutes(`Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+kube-bridge	0000F40A	00000000	0001	0	0	0	00FFFFFF	0	0	0
+`)
+	if assert.Len(t, routes, 2) {
+		assert.Equal(t, "192.168.1.0/24", routes[0].Net.String())
+		assert.Equal(t, "route via eth0", routes[0].Source)
+		assert.False(t, routes[0].cluster)
+		assert.Equal(t, "10.244.0.0/24", routes[1].Net.String())
+		assert.True(t, routes[1].cluster)
+	}
+
+	routes = parseIPv6Routes(`fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+`)
+	if assert.Len(t, routes, 1) {
+		assert.Equal(t, "fd00::/64", routes[0].Net.String())
+	}
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hosts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// ConfigMapName is the config map the controllers publish their addresses in, keyed by the host name. Its
+	// presence enables the hosts sync on the workers.
+	ConfigMapName = "k0s-hosts"
+	// DefaultPath is the hosts file kept in sync
+	DefaultPath = "/etc/hosts"
+
+	// StaleAfter is how long the address of a controller is kept after it was last published
+	StaleAfter = 15 * time.Minute
+
+	beginMarker = "# BEGIN k0s managed hosts, changes are overwritten"
+	endMarker   = "# END k0s managed hosts"
+	// seenAnnotation records when the controllers last published their addresses, as JSON of the names and times
+	seenAnnotation = "k0sproject.io/hosts-seen"
+	// seenRefresh is how old the record of a controller gets before it's refreshed, the config map isn't updated
+	// on every publish
+	seenRefresh = StaleAfter / 3
+)
+
+// Entries maps the host names of the cluster members to their addresses
+type Entries map[string]string
+
+// Render formats the entries as the managed block of the hosts file, sorted by name
+func (e Entries) Render() string {
+	if len(e) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(beginMarker + "\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s\t%s\n", e[name], name)
+	}
+	b.WriteString(endMarker + "\n")
+	return b.String()
+}
+
+// Apply replaces the managed block of the hosts file content with the entries, the rest of the file is kept as is.
+// Without entries the managed block is removed.
+func Apply(content []byte, entries Entries) []byte {
+	var kept []string
+	inBlock := false
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		switch strings.TrimSpace(line) {
+		case beginMarker:
+			inBlock = true
+			continue
+		case endMarker:
+			inBlock = false
+			continue
+		}
+		if !inBlock && line != "" {
+			kept = append(kept, line)
+		}
+	}
+	out := strings.Join(kept, "")
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return []byte(out + entries.Render())
+}
+
+// Update writes the entries into the managed block of the hosts file, returns true if the file changed. The file is
+// replaced through a temporary file, so that the resolvers never read it half written.
+func Update(path string, entries Entries) (bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if len(entries) == 0 && !bytes.Contains(content, []byte(beginMarker)) {
+		return false, nil
+	}
+	updated := Apply(content, entries)
+	if bytes.Equal(content, updated) {
+		return false, nil
+	}
+	return true, write(path, updated)
+}
+
+// write replaces the file with the content through a temporary file. A bind mounted file, as /etc/hosts often is in
+// containers, can't be replaced with a rename and is written in place instead: the new content is written over the
+// old one before the file is truncated to its length, so the file is never empty.
+func write(path string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return writeInPlace(path, content, mode)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), mode)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return writeInPlace(path, content, mode)
+	}
+	return nil
+}
+
+func writeInPlace(path string, content []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, mode)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteAt(content, 0)
+	if err == nil {
+		err = f.Truncate(int64(len(content)))
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Lookup collects the entries of the controllers published in the config map and of the nodes. The controllers take
+// precedence, a node named like a controller is left out. Returns false if the hosts sync isn't enabled in the
+// cluster.
+func Lookup(ctx context.Context, client kubernetes.Interface) (Entries, bool, error) {
+	cm, err := client.CoreV1().ConfigMaps("kube-system").Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	entries := Entries{}
+	for name, address := range cm.Data {
+		if net.ParseIP(address) != nil {
+			entries[name] = address
+		}
+	}
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, true, err
+	}
+	for _, node := range nodes.Items {
+		if _, controller := cm.Data[node.Name]; controller {
+			continue
+		}
+		if address := nodeAddress(&node); address != "" {
+			entries[node.Name] = address
+		}
+	}
+	return entries, true, nil
+}
+
+// nodeAddress picks the internal address of the node, or the external one if it has none
+func nodeAddress(node *core.Node) string {
+	external := ""
+	for _, a := range node.Status.Addresses {
+		switch a.Type {
+		case core.NodeInternalIP:
+			return a.Address
+		case core.NodeExternalIP:
+			if external == "" {
+				external = a.Address
+			}
+		}
+	}
+	return external
+}
+
+// Publish records the address of the controller in the config map, creating it if needed. The controllers which
+// haven't published their addresses for StaleAfter, e.g. the removed ones, are dropped from the config map.
+func Publish(ctx context.Context, client kubernetes.Interface, name string, address string) error {
+	configMaps := client.CoreV1().ConfigMaps("kube-system")
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		now := time.Now().UTC()
+		cm, err := configMaps.Get(ctx, ConfigMapName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &core.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "kube-system"}}
+			if !publish(cm, name, address, now) {
+				return nil
+			}
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// created by another controller meanwhile, retried as a conflict
+				return apierrors.NewConflict(core.Resource("configmaps"), ConfigMapName, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if !publish(cm, name, address, now) {
+			return nil
+		}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// publish sets the address of the controller in the config map and drops the stale controllers, returns true if the
+// config map changed. The controllers without a record, e.g. published by an earlier version, are recorded as seen
+// now.
+func publish(cm *core.ConfigMap, name string, address string, now time.Time) bool {
+	seen := map[string]time.Time{}
+	if data := cm.Annotations[seenAnnotation]; data != "" {
+		// a broken record is replaced
+		_ = json.Unmarshal([]byte(data), &seen)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+
+	changed := false
+	for controller := range cm.Data {
+		at, recorded := seen[controller]
+		switch {
+		case controller == name:
+		case !recorded:
+			seen[controller] = now
+			changed = true
+		case now.Sub(at) > StaleAfter:
+			delete(cm.Data, controller)
+			delete(seen, controller)
+			changed = true
+		}
+	}
+	for controller := range seen {
+		if _, found := cm.Data[controller]; !found {
+			delete(seen, controller)
+			changed = true
+		}
+	}
+	if cm.Data[name] != address {
+		cm.Data[name] = address
+		changed = true
+	}
+	if now.Sub(seen[name]) > seenRefresh {
+		seen[name] = now
+		changed = true
+	}
+	if !changed {
+		return false
+	}
+
+	data, _ := json.Marshal(seen)
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[seenAnnotation] = string(data)
+	return true
+}
+
+// Unpublish deletes the config map, which disables the hosts sync of the workers
+func Unpublish(ctx context.Context, client kubernetes.Interface) error {
+	err := client.CoreV1().ConfigMaps("kube-system").Delete(ctx, ConfigMapName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package hosts
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApply(t *testing.T) {
+	original := "127.0.0.1\tlocalhost\n\n# custom\n10.0.0.9 db\n"
+	entries := Entries{"worker-1": "10.0.0.11", "controller-1": "10.0.0.1"}
+
+	applied := Apply([]byte(original), entries)
+	assert.Equal(t, original+beginMarker+"\n10.0.0.1\tcontroller-1\n10.0.0.11\tworker-1\n"+endMarker+"\n", string(applied))
+
+	// the block is replaced, not appended again
+	entries["worker-2"] = "10.0.0.12"
+	reapplied := Apply(applied, entries)
+	assert.Equal(t, string(Apply([]byte(original), entries)), string(reapplied))
+
+	assert.Equal(t, original, string(Apply(reapplied, nil)))
+	assert.Equal(t, "127.0.0.1 localhost\n", string(Apply([]byte("127.0.0.1 localhost"), nil)))
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-hosts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts")
+	require.NoError(t, ioutil.WriteFile(path, []byte("127.0.0.1 localhost\n"), 0644))
+
+	changed, err := Update(path, Entries{"worker-1": "10.0.0.11"})
+	require.NoError(t, err)
+	assert.True(t, changed)
+	changed, err = Update(path, Entries{"worker-1": "10.0.0.11"})
+	require.NoError(t, err)
+	assert.False(t, changed)
+	changed, err = Update(path, nil)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n", string(content))
+}
+
+func TestWriteInPlace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-hosts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts")
+	require.NoError(t, ioutil.WriteFile(path, []byte("127.0.0.1 localhost\n10.0.0.11 worker-1\n"), 0644))
+
+	require.NoError(t, writeInPlace(path, []byte("127.0.0.1 localhost\n"), 0644))
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n", string(content))
+}
+
+func TestPublishAndLookup(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset(&core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-1"},
+		Status: core.NodeStatus{Addresses: []core.NodeAddress{
+			{Type: core.NodeHostName, Address: "worker-1"},
+			{Type: core.NodeExternalIP, Address: "203.0.113.11"},
+			{Type: core.NodeInternalIP, Address: "10.0.0.11"},
+		}},
+	})
+
+	_, enabled, err := Lookup(ctx, client)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+
+	require.NoError(t, Publish(ctx, client, "controller-1", "10.0.0.1"))
+	require.NoError(t, Publish(ctx, client, "controller-2", "10.0.0.2"))
+	entries, enabled, err := Lookup(ctx, client)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, Entries{"controller-1": "10.0.0.1", "controller-2": "10.0.0.2", "worker-1": "10.0.0.11"}, entries)
+
+	require.NoError(t, Unpublish(ctx, client))
+	require.NoError(t, Unpublish(ctx, client))
+	_, enabled, err = Lookup(ctx, client)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestLookupPrefersControllers(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset(&core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "controller-1"},
+		Status: core.NodeStatus{Addresses: []core.NodeAddress{
+			{Type: core.NodeInternalIP, Address: "10.0.0.66"},
+		}},
+	})
+
+	require.NoError(t, Publish(ctx, client, "controller-1", "10.0.0.1"))
+	entries, _, err := Lookup(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, Entries{"controller-1": "10.0.0.1"}, entries)
+}
+
+func TestPublishDropsStaleControllers(t *testing.T) {
+	ctx := context.TODO()
+	client := fake.NewSimpleClientset()
+	require.NoError(t, Publish(ctx, client, "controller-1", "10.0.0.1"))
+	require.NoError(t, Publish(ctx, client, "controller-2", "10.0.0.2"))
+
+	// controller-2 was removed a while ago
+	configMaps := client.CoreV1().ConfigMaps("kube-system")
+	cm, err := configMaps.Get(ctx, ConfigMapName, metav1.GetOptions{})
+	require.NoError(t, err)
+	stale := time.Now().UTC().Add(-2 * StaleAfter).Format(time.RFC3339)
+	cm.Annotations[seenAnnotation] = `{"controller-1":"` + time.Now().UTC().Format(time.RFC3339) + `","controller-2":"` + stale + `"}`
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, Publish(ctx, client, "controller-1", "10.0.0.1"))
+	entries, _, err := Lookup(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, Entries{"controller-1": "10.0.0.1"}, entries)
+}
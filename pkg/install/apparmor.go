@@ -0,0 +1,212 @@
+/*
+Copyright 2021 k0s Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+const (
+	appArmorProfileDir     = "/etc/apparmor.d"
+	appArmorEnabledPath    = "/sys/module/apparmor/parameters/enabled"
+	appArmorLoadedProfiles = "/sys/kernel/security/apparmor/profiles"
+)
+
+type appArmorProfile struct {
+	Name    string
+	BinPath string
+	// ManagesHost is set for k0s itself, which loads the kernel modules and the AppArmor profiles
+	ManagesHost bool
+}
+
+// AppArmorProfileNames lists the AppArmor profiles managed by k0s
+var AppArmorProfileNames = []string{"k0s", "k0s-containerd", "k0s-kubelet"}
+
+func appArmorProfiles(k0sVars constant.CfgVars) ([]appArmorProfile, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return []appArmorProfile{
+		{Name: "k0s", BinPath: exe, ManagesHost: true},
+		{Name: "k0s-containerd", BinPath: filepath.Join(k0sVars.BinDir, "containerd")},
+		{Name: "k0s-kubelet", BinPath: filepath.Join(k0sVars.BinDir, "kubelet")},
+	}, nil
+}
+
+// AppArmorEnabled checks if AppArmor is enabled in the running kernel
+func AppArmorEnabled() bool {
+	data, err := ioutil.ReadFile(appArmorEnabledPath)
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(data)) == "Y"
+}
+
+// InstallAppArmorProfiles writes the AppArmor profiles for the k0s managed components and loads them into the kernel
+func InstallAppArmorProfiles(k0sVars constant.CfgVars) error {
+	if !AppArmorEnabled() {
+		return fmt.Errorf("AppArmor is not enabled on this host")
+	}
+	parser, err := util.GetExecPath("apparmor_parser")
+	if err != nil {
+		return fmt.Errorf("apparmor_parser not found: %w", err)
+	}
+
+	profiles, err := appArmorProfiles(k0sVars)
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		profilePath := filepath.Join(appArmorProfileDir, p.Name)
+		tw := util.TemplateWriter{
+			Name:     p.Name,
+			Template: appArmorProfileTemplate,
+			Data:     p,
+			Path:     profilePath,
+		}
+		if err := tw.Write(); err != nil {
+			return fmt.Errorf("failed to write AppArmor profile %s: %w", p.Name, err)
+		}
+		logrus.Infof("loading AppArmor profile %s", p.Name)
+		if err := execCmd(exec.Command(*parser, "--replace", "--write-cache", profilePath)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstalledAppArmorProfiles returns the paths of the k0s AppArmor profiles written on the host
+func InstalledAppArmorProfiles() []string {
+	var paths []string
+	for _, name := range AppArmorProfileNames {
+		profilePath := filepath.Join(appArmorProfileDir, name)
+		if util.FileExists(profilePath) {
+			paths = append(paths, profilePath)
+		}
+	}
+	return paths
+}
+
+// RemoveAppArmorProfiles unloads and removes the k0s AppArmor profiles
+func RemoveAppArmorProfiles() error {
+	var messages []string
+	for _, profilePath := range InstalledAppArmorProfiles() {
+		if err := RemoveAppArmorProfile(profilePath); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// RemoveAppArmorProfile unloads the AppArmor profile at the given path, if AppArmor is enabled, and removes it
+func RemoveAppArmorProfile(profilePath string) error {
+	var messages []string
+	if parser, _ := util.GetExecPath("apparmor_parser"); parser != nil && AppArmorEnabled() {
+		if err := execCmd(exec.Command(*parser, "--remove", profilePath)); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if err := os.Remove(profilePath); err != nil {
+		messages = append(messages, err.Error())
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// AppArmorStatus returns the enforcement mode of each of the loaded k0s AppArmor profiles
+func AppArmorStatus() (map[string]string, error) {
+	data, err := ioutil.ReadFile(appArmorLoadedProfiles)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		// lines are in the form of "profile-name (enforce)"
+		i := strings.LastIndex(line, " (")
+		if i == -1 {
+			continue
+		}
+		loaded[line[:i]] = strings.TrimSuffix(line[i+2:], ")")
+	}
+
+	status := make(map[string]string)
+	for _, name := range AppArmorProfileNames {
+		if mode, found := loaded[name]; found {
+			status[name] = mode
+		}
+	}
+	return status, nil
+}
+
+// the profiles deny access to the most sensitive kernel interfaces and firmware, the boot files and rebooting.
+// change_profile is needed for applying the profiles of the containers. containerd and the kubelet are also
+// denied loading kernel modules, changing the kernel's core dump and modprobe helpers and touching the AppArmor
+// policy, which only k0s needs: it loads the modules of the worker and (un)loads its own profiles, also on reset.
+const appArmorProfileTemplate = `# managed by k0s, do not edit
+#include <tunables/global>
+
+profile {{ .Name }} {{ .BinPath }} flags=(attach_disconnected,mediate_deleted) {
+  #include <abstractions/base>
+
+  capability,
+  network,
+  mount,
+  remount,
+  umount,
+  pivot_root,
+  ptrace,
+  signal,
+  unix,
+  dbus,
+  file,
+  change_profile -> **,
+
+  deny capability sys_boot,
+  deny @{PROC}/sysrq-trigger rwklx,
+  deny @{PROC}/kcore rwklx,
+  deny /dev/mem rwklx,
+  deny /dev/kmem rwklx,
+  deny /dev/port rwklx,
+  deny /sys/firmware/** rwklx,
+  deny /boot/** wl,
+{{- if not .ManagesHost }}
+
+  deny capability sys_module,
+  deny capability mac_admin,
+  deny capability mac_override,
+  deny @{PROC}/sys/kernel/{modprobe,core_pattern} w,
+  deny /sys/kernel/security/** wklx,
+  deny /etc/apparmor.d/** wl,
+{{- end }}
+}
+`
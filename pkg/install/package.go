@@ -0,0 +1,168 @@
+/*
+Copyright 2021 k0s Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+)
+
+// InstallState is recorded by k0s install, so that the package scripts can repeat the install on upgrades
+type InstallState struct {
+	Role        string    `json:"role"`
+	Args        []string  `json:"args"`
+	CfgFile     string    `json:"cfgFile,omitempty"`
+	DataDir     string    `json:"dataDir,omitempty"`
+	RunDir      string    `json:"runDir,omitempty"`
+	UserName    string    `json:"userName,omitempty"`
+	AppArmor    bool      `json:"appArmor,omitempty"`
+	PackageMode bool      `json:"packageMode"`
+	Version     string    `json:"version"`
+	InstalledAt time.Time `json:"installedAt"`
+}
+
+// SameInstall returns true if the other state installs the same service
+func (s *InstallState) SameInstall(other *InstallState) bool {
+	if s == nil || other == nil {
+		return false
+	}
+	return s.Role == other.Role && s.UserName == other.UserName && reflect.DeepEqual(s.Args, other.Args)
+}
+
+// WriteInstallState stores the install state into the given file
+func WriteInstallState(path string, state InstallState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ReadInstallState reads the state previously stored with WriteInstallState
+func ReadInstallState(path string) (*InstallState, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &InstallState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse install state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+var (
+	// packageUnitDirs are the directories where the distro packages ship their systemd units
+	packageUnitDirs = []string{"/usr/lib/systemd/system", "/lib/systemd/system"}
+	// dropInDir is where the drop-ins configuring the packaged units are written
+	dropInDir = "/etc/systemd/system"
+)
+
+const dropInName = "10-k0s-install.conf"
+
+// PackagedUnit returns the path of the systemd unit for the role shipped by a distro package, or an empty string if there's none
+func PackagedUnit(role string) string {
+	for _, dir := range packageUnitDirs {
+		unit := filepath.Join(dir, fmt.Sprintf("k0s%s.service", role))
+		if util.FileExists(unit) {
+			return unit
+		}
+	}
+	return ""
+}
+
+// PackagedServiceDropIn returns the path of the drop-in configuring the packaged unit for the role
+func PackagedServiceDropIn(role string) string {
+	return filepath.Join(dropInDir, fmt.Sprintf("k0s%s.service.d", role), dropInName)
+}
+
+type dropInData struct {
+	ExecStart    string
+	UserName     string
+	Capabilities string
+}
+
+// EnsurePackagedService configures the unit shipped by the distro package with a drop-in,
+// which runs k0s with the given arguments. The unit itself is left to the package manager.
+func EnsurePackagedService(role string, args []string, userName string) error {
+	if err := writeDropIn(role, args, userName); err != nil {
+		return err
+	}
+	systemctl, err := util.GetExecPath("systemctl")
+	if err != nil {
+		return fmt.Errorf("systemctl not found: %w", err)
+	}
+	return execCmd(exec.Command(*systemctl, "daemon-reload"))
+}
+
+func writeDropIn(role string, args []string, userName string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	path := PackagedServiceDropIn(role)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create dir %s: %w", filepath.Dir(path), err)
+	}
+	logrus.Infof("Configuring the packaged k0s service with %s", path)
+	tw := util.TemplateWriter{
+		Name:     "k0s-install-dropin",
+		Template: dropInTemplate,
+		Data: dropInData{
+			ExecStart:    strings.Join(append([]string{exe}, args...), " "),
+			UserName:     userName,
+			Capabilities: workerCapabilities,
+		},
+		Path: path,
+	}
+	return tw.Write()
+}
+
+// RemovePackagedService removes the drop-in configuring the packaged unit, the unit itself belongs to the package
+func RemovePackagedService(role string) error {
+	path := PackagedServiceDropIn(role)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// the drop-in dir is only removed if nothing else is in there
+	_ = os.Remove(filepath.Dir(path))
+	return nil
+}
+
+const dropInTemplate = `# managed by k0s install, do not edit
+[Service]
+ExecStart=
+ExecStart={{ .ExecStart }}
+{{- if .UserName }}
+User={{ .UserName }}
+AmbientCapabilities={{ .Capabilities }}
+CapabilityBoundingSet={{ .Capabilities }}
+{{- end }}
+`
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 k0s Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-install-state")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "etc", "install-state.json")
+	state := InstallState{Role: "worker", Args: []string{"worker", "--token-file=/etc/k0s/token"}, UserName: "k0s", Version: "v1.21.2+k0s.0"}
+	require.NoError(t, WriteInstallState(path, state))
+
+	read, err := ReadInstallState(path)
+	require.NoError(t, err)
+	assert.Equal(t, "worker", read.Role)
+	assert.True(t, read.SameInstall(&state))
+
+	// the version doesn't matter, upgrades keep the same install
+	state.Version = "v1.21.3+k0s.0"
+	assert.True(t, read.SameInstall(&state))
+	state.Args = append(state.Args, "--labels=foo=bar")
+	assert.False(t, read.SameInstall(&state))
+
+	var missing *InstallState
+	assert.False(t, missing.SameInstall(&state))
+}
+
+func TestPackagedServiceDropIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-packaged-service")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(dirs []string, d string) { packageUnitDirs, dropInDir = dirs, d }(packageUnitDirs, dropInDir)
+	packageUnitDirs = []string{filepath.Join(dir, "lib")}
+	dropInDir = filepath.Join(dir, "etc")
+
+	assert.Empty(t, PackagedUnit("worker"))
+	require.NoError(t, os.MkdirAll(packageUnitDirs[0], 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(packageUnitDirs[0], "k0sworker.service"), []byte("[Service]\n"), 0644))
+	assert.Equal(t, filepath.Join(packageUnitDirs[0], "k0sworker.service"), PackagedUnit("worker"))
+
+	require.NoError(t, writeDropIn("worker", []string{"worker", "--token-file=/etc/k0s/token"}, "k0s"))
+	content, err := ioutil.ReadFile(PackagedServiceDropIn("worker"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "ExecStart=\n")
+	assert.Contains(t, string(content), " worker --token-file=/etc/k0s/token\n")
+	assert.Contains(t, string(content), "User=k0s\n")
+
+	require.NoError(t, RemovePackagedService("worker"))
+	assert.NoFileExists(t, PackagedServiceDropIn("worker"))
+	assert.NoDirExists(t, filepath.Dir(PackagedServiceDropIn("worker")))
+}
@@ -21,9 +21,11 @@ import (
 	"io/ioutil"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/status"
 	"github.com/mitchellh/go-ps"
 	"gopkg.in/yaml.v2"
 )
@@ -36,6 +38,13 @@ type K0sStatus struct {
 	SysInit  string
 	StubFile string
 	Output   string
+
+	ClusterName   string                 `json:",omitempty" yaml:",omitempty"`
+	ClusterLabels map[string]string      `json:",omitempty" yaml:",omitempty"`
+	ConfigDrift   []status.DriftItem     `json:",omitempty" yaml:",omitempty"`
+	AppArmor      map[string]string      `json:",omitempty" yaml:",omitempty"`
+	EtcdSnapshot  *status.SnapshotStatus `json:",omitempty" yaml:",omitempty"`
+	Crashes       []status.Crash         `json:",omitempty" yaml:",omitempty"`
 }
 
 func GetPid() (status *K0sStatus, err error) {
@@ -124,6 +133,12 @@ func (s K0sStatus) String() {
 		fmt.Println("Process ID:", s.Pid)
 		fmt.Println("Parent Process ID:", s.PPid)
 		fmt.Println("Role:", s.Role)
+		if s.ClusterName != "" {
+			fmt.Println("Cluster name:", s.ClusterName)
+		}
+		for _, k := range sortedKeys(s.ClusterLabels) {
+			fmt.Printf("Cluster label %s: %s\n", k, s.ClusterLabels[k])
+		}
 
 		if s.SysInit != "" {
 			fmt.Println("Init System:", s.SysInit)
@@ -132,6 +147,26 @@ func (s K0sStatus) String() {
 		if s.StubFile != "" {
 			fmt.Println("Service file:", s.StubFile)
 		}
+		for _, name := range AppArmorProfileNames {
+			if mode, found := s.AppArmor[name]; found {
+				fmt.Printf("AppArmor profile %s: %s\n", name, mode)
+			}
+		}
+		if s.EtcdSnapshot != nil {
+			fmt.Println("Last etcd snapshot:", s.EtcdSnapshot)
+		}
+		if len(s.Crashes) > 0 {
+			fmt.Println("Recent crashes:")
+			for _, crash := range s.Crashes {
+				fmt.Println("  -", crash)
+			}
+		}
+		if len(s.ConfigDrift) > 0 {
+			fmt.Println("Config drift:")
+			for _, d := range s.ConfigDrift {
+				fmt.Println("  -", d)
+			}
+		}
 	}
 }
 
@@ -148,3 +183,12 @@ func GetRoleByStagedKubelet(binPath string) string {
 		return "worker"
 	}
 }
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -18,9 +18,12 @@ package install
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/kardianos/service"
 	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/pkg/constant"
 )
 
 var (
@@ -64,8 +67,9 @@ func InstalledService() (service.Service, error) {
 	return s, fmt.Errorf("k0s has not been installed as a service")
 }
 
-// EnsureService installs the k0s service, per the given arguments, and the detected platform
-func EnsureService(args []string) error {
+// EnsureService installs the k0s service, per the given arguments, and the detected platform.
+// If userName is given, the service runs as that user with the worker capabilities as ambient capabilities.
+func EnsureService(args []string, userName string) error {
 	var deps []string
 	var svcConfig *service.Config
 
@@ -95,6 +99,12 @@ func EnsureService(args []string) error {
 		}
 	default:
 	}
+	if userName != "" {
+		if svcType != "linux-systemd" {
+			return fmt.Errorf("running k0s as a non-root user is only supported with systemd, detected %s", svcType)
+		}
+		svcConfig.UserName = userName
+	}
 
 	svcConfig.Dependencies = deps
 	svcConfig.Arguments = args
@@ -173,9 +183,20 @@ func GetServiceConfig(role string) *service.Config {
 	}
 }
 
+// workerCapabilities holds the ambient capabilities given to a non-root k0s service
+var workerCapabilities = capabilityNames()
+
+func capabilityNames() string {
+	var names []string
+	for _, c := range constant.WorkerCapabilities {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, " ")
+}
+
 // Upstream kardianos/service does not support all the options we want to set to the systemd unit, hence we override the template
 // Currently mostly for KillMode=process so we get systemd to only send the sigterm to the main process
-const systemdScript = `[Unit]
+var systemdScript = `[Unit]
 Description={{.Description}}
 Documentation=https://docs.k0sproject.io
 ConditionFileIsExecutable={{.Path|cmdEscape}}
@@ -197,7 +218,11 @@ TimeoutStartSec=0
 {{- if .ChRoot}}RootDirectory={{.ChRoot|cmd}}{{- end}}
 
 {{- if .WorkingDirectory}}WorkingDirectory={{.WorkingDirectory|cmdEscape}}{{- end}}
-{{- if .UserName}}User={{.UserName}}{{end}}
+{{- if .UserName}}
+User={{.UserName}}
+AmbientCapabilities=` + workerCapabilities + `
+CapabilityBoundingSet=` + workerCapabilities + `
+{{- end}}
 {{- if .ReloadSignal}}ExecReload=/bin/kill -{{.ReloadSignal}} "$MAINPID"{{- end}}
 {{- if .PIDFile}}PIDFile={{.PIDFile|cmd}}{{- end}}
 {{- if and .LogOutput .HasOutputFileSupport -}}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kine
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func init() {
+	Register(sqliteDriver{})
+	Register(sqlDriver("mysql"))
+	Register(sqlDriver("postgres"))
+}
+
+// sqliteDriver keeps the state in a local database file, so it can't be shared by several controllers
+type sqliteDriver struct{}
+
+func (sqliteDriver) Scheme() string { return "sqlite" }
+func (sqliteDriver) Joinable() bool { return false }
+
+// Prepare makes sure the database dir exists and is owned by the kine user
+func (sqliteDriver) Prepare(dataSource string, uid, gid int) error {
+	dsURL, err := url.Parse(dataSource)
+	if err != nil {
+		return err
+	}
+	dbDir := filepath.Dir(dsURL.Path)
+	if err := util.InitDirectory(dbDir, constant.KineDBDirMode); err != nil {
+		return fmt.Errorf("failed to create dir %s: %w", dbDir, err)
+	}
+	if err := os.Chown(dbDir, uid, gid); err != nil && os.Geteuid() == 0 {
+		return fmt.Errorf("failed to chown dir %s: %w", dbDir, err)
+	}
+	if err := os.Chown(dsURL.Path, uid, gid); err != nil && os.Geteuid() == 0 {
+		logrus.Warningf("datasource file %s does not exist", dsURL.Path)
+	}
+	return nil
+}
+
+func (sqliteDriver) Endpoint(dataSource string) (string, error) { return dataSource, nil }
+
+// sqlDriver is used for the SQL servers supported by kine out of the box
+type sqlDriver string
+
+func (d sqlDriver) Scheme() string                             { return string(d) }
+func (d sqlDriver) Joinable() bool                             { return true }
+func (d sqlDriver) Prepare(string, int, int) error             { return nil }
+func (d sqlDriver) Endpoint(dataSource string) (string, error) { return dataSource, nil }
@@ -17,7 +17,9 @@ package kubernetes
 
 import (
 	"fmt"
+	"os"
 	"sync"
+	"time"
 
 	"github.com/k0sproject/k0s/pkg/constant"
 	"k8s.io/client-go/discovery"
@@ -52,13 +54,27 @@ type clientFactory struct {
 	dynamicClient   dynamic.Interface
 	discoveryClient discovery.CachedDiscoveryInterface
 	restConfig      *rest.Config
+	modTime         time.Time
 
 	mutex sync.Mutex
 }
 
+// reload drops the cached clients when the kubeconfig has changed, so that the renewed client certificates are used
+func (c *clientFactory) reload() {
+	info, err := os.Stat(c.configPath)
+	if err != nil || info.ModTime().Equal(c.modTime) {
+		return
+	}
+	if !c.modTime.IsZero() {
+		c.restConfig, c.client, c.dynamicClient, c.discoveryClient = nil, nil, nil, nil
+	}
+	c.modTime = info.ModTime()
+}
+
 func (c *clientFactory) GetClient() (kubernetes.Interface, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.reload()
 	var err error
 
 	if c.restConfig == nil {
@@ -91,6 +107,7 @@ func (c *clientFactory) GetClient() (kubernetes.Interface, error) {
 func (c *clientFactory) GetDynamicClient() (dynamic.Interface, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.reload()
 	var err error
 	if c.restConfig == nil {
 		c.restConfig, err = clientcmd.BuildConfigFromFlags("", c.configPath)
@@ -121,6 +138,7 @@ func (c *clientFactory) GetDynamicClient() (dynamic.Interface, error) {
 func (c *clientFactory) GetDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	c.reload()
 	var err error
 	if c.restConfig == nil {
 		c.restConfig, err = clientcmd.BuildConfigFromFlags("", c.configPath)
@@ -0,0 +1,190 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// RetryInterval is the interval of the evictions retries and of the progress checks
+var RetryInterval = 2 * time.Second
+
+// Drainer cordons the nodes and evicts their pods through the API
+type Drainer struct {
+	Client kubernetes.Interface
+	// GracePeriod is given to the evicted pods for terminating
+	GracePeriod time.Duration
+	// Progress reports the progress of the drain, if set
+	Progress func(format string, args ...interface{})
+	// KubeletStopped tells that the kubelet of the node isn't running, nothing else would delete the evicted pods,
+	// so they are deleted right away once their eviction has been accepted
+	KubeletStopped bool
+}
+
+// Cordon marks the node unschedulable
+func (d *Drainer) Cordon(ctx context.Context, nodeName string) error {
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	if _, err := d.Client.CoreV1().Nodes().Patch(ctx, nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to cordon node %s: %w", nodeName, err)
+	}
+	d.progress("cordoned node %s", nodeName)
+	return nil
+}
+
+// Evict evicts the pods of the node until all of them are gone, or ctx is done. The evictions blocked by a pod
+// disruption budget are retried. It returns the number of the pods that are still on the node, including the
+// terminating ones.
+func (d *Drainer) Evict(ctx context.Context, nodeName string) (int, error) {
+	gracePeriodSeconds := int64(d.GracePeriod.Seconds())
+	forceDelete := int64(0)
+	for {
+		pods, err := d.pods(ctx, nodeName, true)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list the pods of node %s: %w", nodeName, err)
+		}
+		if len(pods) == 0 {
+			d.progress("evicted all the pods of node %s", nodeName)
+			return 0, nil
+		}
+		for _, pod := range pods {
+			if pod.DeletionTimestamp != nil {
+				if d.KubeletStopped {
+					err := d.Client.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &forceDelete})
+					if err != nil && !apierrors.IsNotFound(err) {
+						d.progress("failed to delete pod %s/%s: %v", pod.Namespace, pod.Name, err)
+					}
+				}
+				continue
+			}
+			eviction := &policyv1beta1.Eviction{
+				ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+				DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds},
+			}
+			err := d.Client.PolicyV1beta1().Evictions(pod.Namespace).Evict(ctx, eviction)
+			switch {
+			case err == nil:
+				d.progress("evicting pod %s/%s", pod.Namespace, pod.Name)
+			case apierrors.IsNotFound(err):
+			case apierrors.IsTooManyRequests(err):
+				d.progress("eviction of pod %s/%s is blocked by a disruption budget, retrying", pod.Namespace, pod.Name)
+			default:
+				d.progress("failed to evict pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return len(pods), nil
+		case <-time.After(RetryInterval):
+		}
+	}
+}
+
+// WaitForPods waits until the evicted pods of the node have terminated
+func (d *Drainer) WaitForPods(ctx context.Context, nodeName string) error {
+	return d.waitFor(ctx, "pods to terminate", func() (int, error) {
+		pods, err := d.pods(ctx, nodeName, true)
+		return len(pods), err
+	})
+}
+
+// WaitForVolumeDetach waits until the volumes of the node have been detached
+func (d *Drainer) WaitForVolumeDetach(ctx context.Context, nodeName string) error {
+	return d.waitFor(ctx, "volumes to detach", func() (int, error) {
+		attachments, err := d.Client.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return 0, err
+		}
+		attached := 0
+		for _, a := range attachments.Items {
+			if a.Spec.NodeName == nodeName {
+				attached++
+			}
+		}
+		node, err := d.Client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		if n := len(node.Status.VolumesAttached); n > attached {
+			attached = n
+		}
+		return attached, nil
+	})
+}
+
+func (d *Drainer) waitFor(ctx context.Context, what string, remaining func() (int, error)) error {
+	last := -1
+	for {
+		n, err := remaining()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if n != last {
+			d.progress("waiting for %d %s", n, what)
+			last = n
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %d %s", n, what)
+		case <-time.After(RetryInterval):
+		}
+	}
+}
+
+// pods lists the pods of the node to evict. The DaemonSet pods would get recreated on the node right away, and the
+// mirror pods can't be evicted, so they are left alone.
+func (d *Drainer) pods(ctx context.Context, nodeName string, terminating bool) ([]corev1.Pod, error) {
+	list, err := d.Client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var pods []corev1.Pod
+	for _, pod := range list.Items {
+		if pod.DeletionTimestamp != nil && !terminating {
+			continue
+		}
+		if _, mirror := pod.Annotations[mirrorPodAnnotation]; mirror {
+			continue
+		}
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+func (d *Drainer) progress(format string, args ...interface{}) {
+	if d.Progress != nil {
+		d.Progress(format, args...)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package node
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Remove decommissions the node: it's cordoned, its pods are evicted and the node object is deleted once the pods
+// have terminated and the volumes of the node have been detached. Unless force is set, the node is left cordoned
+// if that doesn't happen before ctx is done.
+func (d *Drainer) Remove(ctx context.Context, nodeName string, force bool) error {
+	if _, err := d.Client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+	if err := d.Cordon(ctx, nodeName); err != nil {
+		return err
+	}
+
+	err := d.drain(ctx, nodeName)
+	if err != nil && !force {
+		return fmt.Errorf("node %s has been left cordoned: %w", nodeName, err)
+	}
+	if err != nil {
+		d.progress("%v, deleting node %s anyway", err, nodeName)
+	}
+
+	// the deletion isn't bound by ctx, it may be done already when forcing
+	err = d.Client.CoreV1().Nodes().Delete(context.Background(), nodeName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete node %s: %w", nodeName, err)
+	}
+	d.progress("deleted node %s", nodeName)
+	return nil
+}
+
+func (d *Drainer) drain(ctx context.Context, nodeName string) error {
+	remaining, err := d.Evict(ctx, nodeName)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%d pods haven't been evicted", remaining)
+	}
+	if err := d.WaitForPods(ctx, nodeName); err != nil {
+		return err
+	}
+	return d.WaitForVolumeDetach(ctx, nodeName)
+}
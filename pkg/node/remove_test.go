@@ -0,0 +1,127 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package node
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func init() {
+	RetryInterval = 10 * time.Millisecond
+}
+
+func workerPod(name string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "worker-1"},
+	}
+	if owner != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+	}
+	return pod
+}
+
+// evictDeletes makes the evictions delete the pods right away
+func evictDeletes(client *fake.Clientset, evicted *[]string) {
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		*evicted = append(*evicted, name)
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "default", name)
+	})
+}
+
+func TestRemove(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		workerPod("web", "ReplicaSet"),
+		workerPod("proxy", "DaemonSet"),
+	)
+	var evicted []string
+	evictDeletes(client, &evicted)
+
+	var progress []string
+	d := &Drainer{Client: client, GracePeriod: time.Second, Progress: func(format string, args ...interface{}) {
+		progress = append(progress, format)
+	}}
+	require.NoError(t, d.Remove(context.Background(), "worker-1", false))
+	assert.Equal(t, []string{"web"}, evicted)
+	assert.NotEmpty(t, progress)
+
+	_, err := client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestRemoveWaitsForVolumeDetach(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		&storagev1.VolumeAttachment{
+			ObjectMeta: metav1.ObjectMeta{Name: "csi-1"},
+			Spec:       storagev1.VolumeAttachmentSpec{NodeName: "worker-1"},
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	d := &Drainer{Client: client}
+	assert.Error(t, d.Remove(ctx, "worker-1", false))
+
+	// the node is left cordoned
+	node, err := client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+
+	assert.NoError(t, d.Remove(ctx, "worker-1", true))
+	_, err = client.CoreV1().Nodes().Get(context.TODO(), "worker-1", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestRemoveRetriesBlockedEvictions(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}},
+		workerPod("db", "StatefulSet"),
+	)
+	attempts := 0
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		attempts++
+		if attempts < 3 {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 0)
+		}
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "default", "db")
+	})
+
+	d := &Drainer{Client: client}
+	require.NoError(t, d.Remove(context.Background(), "worker-1", false))
+	assert.Equal(t, 3, attempts)
+}
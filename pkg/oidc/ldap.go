@@ -0,0 +1,209 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oidc
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+const (
+	ldapTimeout = 10 * time.Second
+	// ldapMaxMessageSize caps the length of the messages read from the server, the group entries are way shorter
+	ldapMaxMessageSize = 1 << 20
+)
+
+// ldapUsernameRe restricts the usernames to the characters that don't need escaping in DNs and filters
+var ldapUsernameRe = regexp.MustCompile(`^[a-zA-Z0-9._@-]+$`)
+
+// ldapAuthenticator authenticates the users with a simple bind and looks up their groups
+type ldapAuthenticator struct {
+	spec *config.OIDCLDAPSpec
+}
+
+func (l *ldapAuthenticator) Authenticate(username, password string) ([]string, error) {
+	// an empty password would be an anonymous bind, which most servers accept
+	if password == "" || !ldapUsernameRe.MatchString(username) {
+		return nil, errInvalidCredentials
+	}
+
+	conn, err := l.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetTimeout(ldapTimeout)
+
+	userDN := fmt.Sprintf(l.spec.BindDNTemplate, username)
+	if err := conn.Bind(userDN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errInvalidCredentials
+		}
+		return nil, err
+	}
+	return l.groups(conn, userDN)
+}
+
+func (l *ldapAuthenticator) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(l.spec.URL)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: ldapTimeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldaps":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, ldap.DefaultLdapsPort), &tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: l.spec.InsecureSkipVerify,
+		})
+	case "ldap":
+		conn, err = dialer.Dial("tcp", hostPort(u, ldap.DefaultLdapPort))
+	default:
+		return nil, fmt.Errorf("unsupported LDAP URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the limit is kept to this connection, ber.MaxPacketLengthBytes would apply to every BER decoder of the process
+	c := ldap.NewConn(&messageLimitConn{Conn: conn, limit: ldapMaxMessageSize}, u.Scheme == "ldaps")
+	c.Start()
+	return c, nil
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// messageLimitConn fails the reads of the messages longer than the limit before the BER decoder allocates them by
+// their length prefix, which it trusts up to 2GiB. It follows the headers of the top-level BER elements of the stream.
+type messageLimitConn struct {
+	net.Conn
+	limit int64
+	// remaining is the length of the content of the current message still to read
+	remaining int64
+	// header is the part of the header of the next message read so far
+	header []byte
+}
+
+func (c *messageLimitConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if checkErr := c.check(b[:n]); checkErr != nil {
+		return 0, checkErr
+	}
+	return n, err
+}
+
+func (c *messageLimitConn) check(data []byte) error {
+	for len(data) > 0 {
+		if c.remaining > 0 {
+			skip := int64(len(data))
+			if skip > c.remaining {
+				skip = c.remaining
+			}
+			c.remaining -= skip
+			data = data[skip:]
+			continue
+		}
+		c.header = append(c.header, data[0])
+		data = data[1:]
+		length, complete, err := berLength(c.header)
+		if err != nil {
+			return err
+		}
+		if !complete {
+			continue
+		}
+		if length > c.limit {
+			return fmt.Errorf("LDAP message of %d bytes exceeds the limit of %d bytes", length, c.limit)
+		}
+		c.remaining = length
+		c.header = c.header[:0]
+	}
+	return nil
+}
+
+// berLength decodes the content length of the BER element header, complete is false if the header needs more bytes
+func berLength(header []byte) (length int64, complete bool, err error) {
+	i := 1
+	if header[0]&0x1f == 0x1f {
+		// the high tag number form continues while the high bit is set
+		for i < len(header) && header[i]&0x80 != 0 {
+			i++
+		}
+		i++
+	}
+	if i >= len(header) {
+		return 0, false, nil
+	}
+	first := header[i]
+	switch {
+	case first < 0x80:
+		return int64(first), true, nil
+	case first == 0x80:
+		return 0, false, fmt.Errorf("indefinite length LDAP messages aren't allowed")
+	}
+	octets := int(first & 0x7f)
+	if octets > 7 {
+		return 0, false, fmt.Errorf("LDAP message length of %d bytes is too long", octets)
+	}
+	if len(header) < i+1+octets {
+		return 0, false, nil
+	}
+	for _, b := range header[i+1 : i+1+octets] {
+		length = length<<8 | int64(b)
+	}
+	return length, true, nil
+}
+
+// groups returns the names of the groups under the group base DN having the user as a member
+func (l *ldapAuthenticator) groups(conn ldap.Client, userDN string) ([]string, error) {
+	if l.spec.GroupBaseDN == "" {
+		return nil, nil
+	}
+	memberAttr := l.spec.GroupMemberAttr
+	if memberAttr == "" {
+		memberAttr = "member"
+	}
+	nameAttr := l.spec.GroupNameAttr
+	if nameAttr == "" {
+		nameAttr = "cn"
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.spec.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout.Seconds()), false,
+		fmt.Sprintf("(%s=%s)", ldap.EscapeFilter(memberAttr), ldap.EscapeFilter(userDN)),
+		[]string{nameAttr}, nil))
+	if err != nil {
+		return nil, fmt.Errorf("LDAP group search failed: %w", err)
+	}
+	var groups []string
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.GetAttributeValues(nameAttr)...)
+	}
+	return groups, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oidc
+
+import (
+	"net"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// fakeLDAPServer answers the requests read from conn with the operations returned by respond, in order
+func fakeLDAPServer(conn net.Conn, respond ...func() []*ber.Packet) {
+	for _, r := range respond {
+		request, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		for _, op := range r() {
+			msg := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+			msg.AppendChild(request.Children[0])
+			msg.AppendChild(op)
+			if _, err := conn.Write(msg.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func ldapResult(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return op
+}
+
+func ldapEntry(dn, attr, value string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, ""))
+	values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+	values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+	attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, attr, ""))
+	attribute.AppendChild(values)
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	attributes.AppendChild(attribute)
+	op.AppendChild(attributes)
+	return op
+}
+
+func TestLDAPBindAndSearch(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go fakeLDAPServer(server,
+		func() []*ber.Packet {
+			return []*ber.Packet{ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess)}
+		},
+		func() []*ber.Packet {
+			return []*ber.Packet{
+				ldapEntry("cn=devs,ou=groups", "cn", "devs"),
+				ldapEntry("cn=ops,ou=groups", "cn", "ops"),
+				ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess),
+			}
+		})
+
+	conn := ldap.NewConn(client, false)
+	conn.Start()
+	defer conn.Close()
+
+	l := &ldapAuthenticator{spec: &config.OIDCLDAPSpec{GroupBaseDN: "ou=groups"}}
+	require.NoError(t, conn.Bind("uid=alice,ou=people", "secret"))
+	groups, err := l.groups(conn, "uid=alice,ou=people")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"devs", "ops"}, groups)
+}
+
+func TestLDAPRejectsOversizedMessages(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		if _, err := ber.ReadPacket(server); err != nil {
+			return
+		}
+		// a message with a message ID claiming to be 2GiB long
+		_, _ = server.Write([]byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff, 0x02, 0x84, 0x7f, 0xff, 0xff, 0xf0})
+	}()
+
+	conn := ldap.NewConn(&messageLimitConn{Conn: client, limit: ldapMaxMessageSize}, false)
+	conn.Start()
+	defer conn.Close()
+	assert.Error(t, conn.Bind("uid=alice,ou=people", "secret"))
+}
+
+func TestMessageLimitConn(t *testing.T) {
+	c := &messageLimitConn{limit: 4}
+	// two messages, the second one with its header split over the reads
+	assert.NoError(t, c.check([]byte{0x30, 0x02, 0x01, 0x02, 0x30}))
+	assert.NoError(t, c.check([]byte{0x04, 0x01, 0x02}))
+	assert.NoError(t, c.check([]byte{0x03, 0x04}))
+	// long form length and high tag number form
+	assert.NoError(t, c.check([]byte{0x7f, 0x81, 0x01, 0x81, 0x03, 0x01, 0x02, 0x03}))
+	assert.Error(t, c.check([]byte{0x30, 0x82, 0x01, 0x00}))
+
+	assert.Error(t, (&messageLimitConn{limit: 4}).check([]byte{0x30, 0x80}), "indefinite length")
+}
+
+func TestLDAPRejectsUnsafeInput(t *testing.T) {
+	l := &ldapAuthenticator{}
+	_, err := l.Authenticate("alice", "")
+	assert.Equal(t, errInvalidCredentials, err)
+	_, err = l.Authenticate("alice,ou=admins", "secret")
+	assert.Equal(t, errInvalidCredentials, err)
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oidc
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// loginQPS and loginBurst limit the login attempts of a client, against guessing the passwords of the static
+	// users and against hammering the LDAP server with binds
+	loginQPS   = 0.2
+	loginBurst = 5
+	// loginLimiterIdle is how long the limiter of an idle client is kept
+	loginLimiterIdle = 10 * time.Minute
+)
+
+// loginLimiter limits the login attempts per client address
+type loginLimiter struct {
+	mu       sync.Mutex
+	clients  map[string]*clientLimiter
+	pruned   time.Time
+	now      func() time.Time
+	newLimit func() flowcontrol.RateLimiter
+}
+
+type clientLimiter struct {
+	limiter  flowcontrol.RateLimiter
+	lastSeen time.Time
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{
+		clients: map[string]*clientLimiter{},
+		now:     time.Now,
+		newLimit: func() flowcontrol.RateLimiter {
+			return flowcontrol.NewTokenBucketRateLimiter(loginQPS, loginBurst)
+		},
+	}
+}
+
+// allow tells if the client of the remote address may try to log in now
+func (l *loginLimiter) allow(remoteAddr string) bool {
+	client, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		client = remoteAddr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.pruned) > time.Minute {
+		for addr, c := range l.clients {
+			if now.Sub(c.lastSeen) > loginLimiterIdle {
+				delete(l.clients, addr)
+			}
+		}
+		l.pruned = now
+	}
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: l.newLimit()}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+	return c.limiter.TryAccept()
+}
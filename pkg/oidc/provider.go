@@ -0,0 +1,212 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oidc
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+var (
+	// errInvalidCredentials is returned by the authenticators for wrong passwords, and for unknown users by the ones
+	// which can't tell them apart
+	errInvalidCredentials = errors.New("invalid credentials")
+	// errUnknownUser is returned by the authenticators for the users they don't know
+	errUnknownUser = fmt.Errorf("unknown user: %w", errInvalidCredentials)
+)
+
+// Authenticator verifies the password of a user and returns the groups of the user
+type Authenticator interface {
+	Authenticate(username, password string) ([]string, error)
+}
+
+// Provider is a minimal OIDC identity provider issuing ID tokens with the resource owner password grant
+type Provider struct {
+	Issuer        string
+	ClientID      string
+	TokenTTL      time.Duration
+	Authenticator Authenticator
+
+	key     *rsa.PrivateKey
+	keyID   string
+	signer  jose.Signer
+	now     func() time.Time
+	limiter *loginLimiter
+}
+
+// NewProvider creates the provider for the given spec, signing the tokens with the key in keyPath
+func NewProvider(spec *config.OIDCProviderSpec, issuer string, keyPath string) (*Provider, error) {
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OIDC signing key: %w", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", keyPath)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC signing key: %w", err)
+	}
+
+	var authenticators multiAuthenticator
+	if len(spec.Users) > 0 {
+		authenticators = append(authenticators, staticAuthenticator(spec.Users))
+	}
+	if spec.LDAP != nil {
+		authenticators = append(authenticators, &ldapAuthenticator{spec: spec.LDAP})
+	}
+
+	return newProvider(issuer, spec.ClientID, spec.TokenTTLDuration(), authenticators, key)
+}
+
+func newProvider(issuer, clientID string, ttl time.Duration, authenticator Authenticator, key *rsa.PrivateKey) (*Provider, error) {
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(pub)
+	keyID := base64.RawURLEncoding.EncodeToString(sum[:12])
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: keyID}},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Issuer:        issuer,
+		ClientID:      clientID,
+		TokenTTL:      ttl,
+		Authenticator: authenticator,
+		key:           key,
+		keyID:         keyID,
+		signer:        signer,
+		now:           time.Now,
+		limiter:       newLoginLimiter(),
+	}, nil
+}
+
+// Register mounts the discovery, key set and token endpoints under the given prefix
+func (p *Provider) Register(router *mux.Router, prefix string) {
+	router.Path(prefix + "/.well-known/openid-configuration").Methods("GET").HandlerFunc(p.discoveryHandler)
+	router.Path(prefix + "/keys").Methods("GET").HandlerFunc(p.keysHandler)
+	router.Path(prefix + "/token").Methods("POST").HandlerFunc(p.tokenHandler)
+}
+
+func (p *Provider) discoveryHandler(resp http.ResponseWriter, req *http.Request) {
+	writeJSON(resp, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"jwks_uri":                              p.Issuer + "/keys",
+		"token_endpoint":                        p.Issuer + "/token",
+		"grant_types_supported":                 []string{"password"},
+		"response_types_supported":              []string{"id_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "groups"},
+		"claims_supported":                      []string{"iss", "sub", "aud", "exp", "iat", "name", "groups"},
+	})
+}
+
+func (p *Provider) keysHandler(resp http.ResponseWriter, req *http.Request) {
+	writeJSON(resp, http.StatusOK, jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{Key: &p.key.PublicKey, KeyID: p.keyID, Algorithm: string(jose.RS256), Use: "sig"}},
+	})
+}
+
+func (p *Provider) tokenHandler(resp http.ResponseWriter, req *http.Request) {
+	if err := req.ParseForm(); err != nil {
+		oauthError(resp, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	clientID := req.PostForm.Get("client_id")
+	if id, _, ok := req.BasicAuth(); ok {
+		clientID = id
+	}
+	if clientID != p.ClientID {
+		oauthError(resp, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if req.PostForm.Get("grant_type") != "password" {
+		oauthError(resp, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	if !p.limiter.allow(req.RemoteAddr) {
+		resp.Header().Set("retry-after", "5")
+		oauthError(resp, http.StatusTooManyRequests, "slow_down")
+		return
+	}
+
+	username := req.PostForm.Get("username")
+	groups, err := p.Authenticator.Authenticate(username, req.PostForm.Get("password"))
+	if err != nil {
+		if !errors.Is(err, errInvalidCredentials) {
+			logrus.Errorf("OIDC authentication of %s failed: %v", username, err)
+		}
+		oauthError(resp, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := p.now()
+	token, err := jwt.Signed(p.signer).Claims(jwt.Claims{
+		Issuer:   p.Issuer,
+		Subject:  username,
+		Audience: jwt.Audience{p.ClientID},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(p.TokenTTL)),
+	}).Claims(map[string]interface{}{
+		"name":   username,
+		"groups": groups,
+	}).CompactSerialize()
+	if err != nil {
+		logrus.Errorf("failed to sign OIDC token: %v", err)
+		oauthError(resp, http.StatusInternalServerError, "server_error")
+		return
+	}
+	writeJSON(resp, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"id_token":     token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(p.TokenTTL.Seconds()),
+	})
+}
+
+func oauthError(resp http.ResponseWriter, status int, code string) {
+	writeJSON(resp, status, map[string]string{"error": code})
+}
+
+func writeJSON(resp http.ResponseWriter, status int, body interface{}) {
+	resp.Header().Set("content-type", "application/json")
+	resp.Header().Set("cache-control", "no-store")
+	resp.WriteHeader(status)
+	_ = json.NewEncoder(resp).Encode(body)
+}
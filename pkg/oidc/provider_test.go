@@ -0,0 +1,171 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+func TestProviderTokenFlow(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	users := staticAuthenticator{{Username: "alice", PasswordHash: string(hash), Groups: []string{"devs"}}}
+	p, err := newProvider("https://k0s.example.com:9443/oidc", "kubernetes", time.Hour, users, key)
+	require.NoError(t, err)
+	router := mux.NewRouter()
+	p.Register(router, "/oidc")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	token := func(form url.Values) *http.Response {
+		resp, err := http.PostForm(srv.URL+"/oidc/token", form)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("discovery", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/oidc/.well-known/openid-configuration")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		discovery := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&discovery))
+		assert.Equal(t, "https://k0s.example.com:9443/oidc", discovery["issuer"])
+		assert.Equal(t, "https://k0s.example.com:9443/oidc/keys", discovery["jwks_uri"])
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		resp := token(url.Values{"grant_type": {"password"}, "client_id": {"kubernetes"}, "username": {"alice"}, "password": {"wrong"}})
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("wrong client", func(t *testing.T) {
+		resp := token(url.Values{"grant_type": {"password"}, "client_id": {"other"}, "username": {"alice"}, "password": {"secret"}})
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("valid credentials", func(t *testing.T) {
+		resp := token(url.Values{"grant_type": {"password"}, "client_id": {"kubernetes"}, "username": {"alice"}, "password": {"secret"}})
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+		token, err := jwt.ParseSigned(body["id_token"].(string))
+		require.NoError(t, err)
+		require.Len(t, token.Headers, 1)
+		assert.Equal(t, p.keyID, token.Headers[0].KeyID)
+
+		claims := jwt.Claims{}
+		custom := struct {
+			Groups []string `json:"groups"`
+		}{}
+		require.NoError(t, token.Claims(&key.PublicKey, &claims, &custom))
+		assert.NoError(t, claims.Validate(jwt.Expected{Issuer: "https://k0s.example.com:9443/oidc", Audience: jwt.Audience{"kubernetes"}}))
+		assert.Equal(t, "alice", claims.Subject)
+		assert.Equal(t, []string{"devs"}, custom.Groups)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		// two of the attempts of the client were used above
+		for i := 2; i < loginBurst; i++ {
+			resp := token(url.Values{"grant_type": {"password"}, "client_id": {"kubernetes"}, "username": {"alice"}, "password": {"wrong"}})
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		}
+		resp := token(url.Values{"grant_type": {"password"}, "client_id": {"kubernetes"}, "username": {"alice"}, "password": {"secret"}})
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/oidc/keys")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		keys := jose.JSONWebKeySet{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+		require.Len(t, keys.Key(p.keyID), 1)
+		assert.Equal(t, key.PublicKey, *keys.Key(p.keyID)[0].Key.(*rsa.PublicKey))
+	})
+}
+
+func TestMultiAuthenticator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	m := multiAuthenticator{
+		staticAuthenticator{{Username: "alice", PasswordHash: string(hash)}},
+		staticAuthenticator([]config.OIDCUser{{Username: "bob", PasswordHash: string(hash), Groups: []string{"ops"}}}),
+	}
+	groups, err := m.Authenticate("bob", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ops"}, groups)
+
+	_, err = m.Authenticate("carol", "secret")
+	assert.Equal(t, errInvalidCredentials, err)
+
+	// a wrong password of a static user isn't tried against the next authenticator, e.g. LDAP
+	m = multiAuthenticator{
+		staticAuthenticator{{Username: "alice", PasswordHash: string(hash)}},
+		authenticatorFunc(func(username, password string) ([]string, error) { return []string{"ldap"}, nil }),
+	}
+	_, err = m.Authenticate("alice", "wrong")
+	assert.Equal(t, errInvalidCredentials, err)
+	groups, err = m.Authenticate("carol", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ldap"}, groups)
+}
+
+type authenticatorFunc func(username, password string) ([]string, error)
+
+func (f authenticatorFunc) Authenticate(username, password string) ([]string, error) {
+	return f(username, password)
+}
+
+func TestLoginLimiter(t *testing.T) {
+	l := newLoginLimiter()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	for i := 0; i < loginBurst; i++ {
+		assert.True(t, l.allow("10.0.0.1:1234"))
+	}
+	assert.False(t, l.allow("10.0.0.1:5678"), "the port doesn't make another client")
+	assert.True(t, l.allow("10.0.0.2:1234"))
+
+	// the idle clients are forgotten
+	now = now.Add(loginLimiterIdle + time.Minute)
+	assert.True(t, l.allow("10.0.0.2:1234"))
+	assert.NotContains(t, l.clients, "10.0.0.1")
+}
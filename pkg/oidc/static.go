@@ -0,0 +1,61 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package oidc
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+
+	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+)
+
+// staticAuthenticator authenticates the users listed in the cluster config
+type staticAuthenticator []config.OIDCUser
+
+func (s staticAuthenticator) Authenticate(username, password string) ([]string, error) {
+	for _, u := range s {
+		if u.Username != username {
+			continue
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+			return nil, errInvalidCredentials
+		}
+		return u.Groups, nil
+	}
+	return nil, errUnknownUser
+}
+
+// multiAuthenticator tries the authenticators in order, the first one knowing the user decides. A wrong password of a
+// user known to an authenticator fails the authentication, the user isn't looked up in the next ones.
+type multiAuthenticator []Authenticator
+
+func (m multiAuthenticator) Authenticate(username, password string) ([]string, error) {
+	for _, a := range m {
+		groups, err := a.Authenticate(username, password)
+		switch {
+		case err == nil:
+			return groups, nil
+		case errors.Is(err, errUnknownUser):
+		case errors.Is(err, errInvalidCredentials):
+			return nil, errInvalidCredentials
+		default:
+			logrus.Warnf("OIDC authenticator failed: %v", err)
+		}
+	}
+	return nil, errInvalidCredentials
+}
@@ -17,6 +17,7 @@ package performance
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -31,6 +32,7 @@ type Timer struct {
 	bufferOutput bool
 	startedAt    time.Time
 	buffer       []checkpoint
+	mu           sync.Mutex
 }
 
 type checkpoint struct {
@@ -62,6 +64,9 @@ func (t *Timer) Start() *Timer {
 
 // Checkpoint records the time since the timer was started
 func (t *Timer) Checkpoint(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	// if the timer was never started, we'll record an errored checkpoint that Output can recognise
 	if t.startedAt.IsZero() {
 		t.buffer = append(t.buffer, checkpoint{
@@ -77,12 +82,18 @@ func (t *Timer) Checkpoint(name string) {
 	})
 
 	if !t.bufferOutput {
-		t.Output()
+		t.output()
 	}
 }
 
 // Output will loop through the message buffer and output all messages in order.
 func (t *Timer) Output() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.output()
+}
+
+func (t *Timer) output() {
 	for {
 		if len(t.buffer) == 0 {
 			return
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package platform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"runtime"
+	"strconv"
+)
+
+const (
+	// RoleController is the role of the nodes running the control plane
+	RoleController = "controller"
+	// RoleWorker is the role of the nodes running the workloads
+	RoleWorker = "worker"
+)
+
+// the architectures k0s is built for and the roles supported on each of them
+var supportedRoles = map[string][]string{
+	"amd64": {RoleController, RoleWorker},
+	"arm64": {RoleController, RoleWorker},
+	"arm":   {RoleController, RoleWorker},
+}
+
+// the calico images are only published for these architectures
+var calicoArchs = map[string]bool{
+	"amd64": true,
+	"arm64": true,
+}
+
+// minimum ARM architecture version for the arm builds, the embedded binaries are built with GOARM=7
+const minARMVersion = 7
+
+var (
+	cpuArchRe  = regexp.MustCompile(`(?m)^CPU architecture\s*:\s*(\d+)`)
+	cpuModelRe = regexp.MustCompile(`(?m)^model name\s*:.*ARMv(\d+)`)
+)
+
+var cpuInfoPath = "/proc/cpuinfo"
+
+// CalicoSupported returns true if the calico images are available for the given architecture
+func CalicoSupported(arch string) bool {
+	return calicoArchs[arch]
+}
+
+// Preflight verifies that the node platform supports running k0s with the given role
+func Preflight(role string) error {
+	return checkPlatform(runtime.GOARCH, role)
+}
+
+func checkPlatform(arch string, role string) error {
+	roles, found := supportedRoles[arch]
+	if !found {
+		return fmt.Errorf("unsupported architecture %s", arch)
+	}
+	if !contains(roles, role) {
+		return fmt.Errorf("running a %s on %s is not supported", role, arch)
+	}
+	if arch != "arm" {
+		return nil
+	}
+
+	cpuInfo, err := ioutil.ReadFile(cpuInfoPath)
+	if err != nil {
+		// not being able to tell the CPU version is not a reason to refuse to start
+		return nil
+	}
+	version := armVersion(string(cpuInfo))
+	if version > 0 && version < minARMVersion {
+		return fmt.Errorf("ARMv%d CPUs are not supported, k0s requires at least ARMv%d", version, minARMVersion)
+	}
+	return nil
+}
+
+// armVersion parses the ARM architecture version from the /proc/cpuinfo content, returns 0 if it cannot be found.
+// ARMv6 CPUs such as the ARM1176 report themselves as architecture 7, so the model name takes precedence.
+func armVersion(cpuInfo string) int {
+	for _, re := range []*regexp.Regexp{cpuModelRe, cpuArchRe} {
+		if match := re.FindStringSubmatch(cpuInfo); match != nil {
+			version, err := strconv.Atoi(match[1])
+			if err == nil {
+				return version
+			}
+		}
+	}
+	return 0
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
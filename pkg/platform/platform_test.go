@@ -0,0 +1,58 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPlatform(t *testing.T) {
+	assert.NoError(t, checkPlatform("amd64", RoleController))
+	assert.NoError(t, checkPlatform("arm64", RoleWorker))
+	assert.NoError(t, checkPlatform("arm", RoleController))
+	assert.Error(t, checkPlatform("riscv64", RoleWorker))
+	assert.Error(t, checkPlatform("mips", RoleWorker))
+}
+
+func TestCheckARMVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-platform")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(path string) { cpuInfoPath = path }(cpuInfoPath)
+	cpuInfoPath = filepath.Join(dir, "cpuinfo")
+
+	// a missing cpuinfo doesn't fail the check
+	assert.NoError(t, checkPlatform("arm", RoleWorker))
+
+	armv7 := "processor\t: 0\nmodel name\t: ARMv7 Processor rev 4 (v7l)\nCPU architecture: 7\n"
+	assert.NoError(t, ioutil.WriteFile(cpuInfoPath, []byte(armv7), 0644))
+	assert.NoError(t, checkPlatform("arm", RoleWorker))
+
+	armv6 := "processor\t: 0\nmodel name\t: ARMv6-compatible processor rev 7 (v6l)\nCPU architecture: 7\n"
+	assert.NoError(t, ioutil.WriteFile(cpuInfoPath, []byte(armv6), 0644))
+	assert.Error(t, checkPlatform("arm", RoleWorker))
+}
+
+func TestArmVersion(t *testing.T) {
+	assert.Equal(t, 8, armVersion("processor\t: 0\nCPU architecture: 8\n"))
+	assert.Equal(t, 0, armVersion("processor\t: 0\n"))
+}
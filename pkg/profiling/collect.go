@@ -0,0 +1,106 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package profiling
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Target is the pprof endpoint of a single component
+type Target struct {
+	Component string
+	// URL is the base URL of the pprof endpoints, e.g. https://127.0.0.1:2379/debug/pprof
+	URL    string
+	Client *http.Client
+}
+
+type profile struct {
+	file  string
+	query string
+}
+
+// profiles are collected after the CPU profile, so that they cover the same period
+var profiles = []profile{
+	{"heap.pb.gz", "heap"},
+	{"allocs.pb.gz", "allocs"},
+	{"block.pb.gz", "block"},
+	{"mutex.pb.gz", "mutex"},
+	{"goroutine.txt", "goroutine?debug=2"},
+}
+
+// Collect takes a CPU profile of the given length and a snapshot of the other profiles of the target, and
+// bundles them into a tar.gz written to w
+func Collect(ctx context.Context, t Target, seconds int, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	all := append([]profile{{"profile.pb.gz", fmt.Sprintf("profile?seconds=%d", seconds)}}, profiles...)
+	for _, p := range all {
+		data, err := t.fetch(ctx, p.query)
+		if err != nil {
+			return fmt.Errorf("failed to collect %s of %s: %w", p.file, t.Component, err)
+		}
+		header := &tar.Header{
+			Name:    path.Join(t.Component, p.file),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write file header to archive: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s to archive: %w", p.file, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func (t Target) fetch(ctx context.Context, query string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, t.URL+"/"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ioutil.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("profiling is not enabled, set spec.profiling.enabled in the k0s config")
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("not authorized to access the profiling endpoints (%s)", resp.Status)
+	default:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected response %s: %s", resp.Status, body)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package profiling
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollect(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RequestURI())
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	target := Target{Component: "etcd", URL: server.URL + "/debug/pprof", Client: server.Client()}
+	require.NoError(t, Collect(context.Background(), target, 5, &buf))
+	assert.Equal(t, "/debug/pprof/profile?seconds=5", queries[0])
+	assert.Len(t, queries, len(profiles)+1)
+
+	gr, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(data)
+	}
+	assert.Equal(t, "/debug/pprof/profile", files["etcd/profile.pb.gz"])
+	assert.Equal(t, "/debug/pprof/heap", files["etcd/heap.pb.gz"])
+	assert.Equal(t, "/debug/pprof/goroutine", files["etcd/goroutine.txt"])
+}
+
+func TestCollectDisabled(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	target := Target{Component: "kube-scheduler", URL: server.URL + "/debug/pprof", Client: server.Client()}
+	err := Collect(context.Background(), target, 1, ioutil.Discard)
+	assert.Contains(t, err.Error(), "profiling is not enabled")
+}
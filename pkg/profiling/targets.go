@@ -0,0 +1,100 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package profiling
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// Components lists the components that can be profiled
+var Components = []string{"k0s", "kube-apiserver", "kube-controller-manager", "kube-scheduler", "etcd", "kubelet"}
+
+// NewTarget creates the target for profiling the given component on the local controller. The kubelet of the
+// given node is reached through the API server node proxy.
+func NewTarget(component string, k0sVars constant.CfgVars, apiPort int, node string) (*Target, error) {
+	certDir := k0sVars.CertRootDir
+	switch component {
+	case "k0s":
+		socket := k0sVars.ProfilingSocketPath
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+		return &Target{Component: component, URL: "http://k0s/debug/pprof", Client: client}, nil
+	case "kube-apiserver", "kubelet":
+		client, err := tlsClient(filepath.Join(certDir, "ca.crt"), filepath.Join(certDir, "admin.crt"), filepath.Join(certDir, "admin.key"))
+		if err != nil {
+			return nil, err
+		}
+		url := fmt.Sprintf("https://localhost:%d/debug/pprof", apiPort)
+		if component == "kubelet" {
+			if node == "" {
+				return nil, fmt.Errorf("the node name is required for profiling the kubelet")
+			}
+			url = fmt.Sprintf("https://localhost:%d/api/v1/nodes/%s/proxy/debug/pprof", apiPort, node)
+		}
+		return &Target{Component: component, URL: url, Client: client}, nil
+	case "kube-controller-manager", "kube-scheduler":
+		// the serving certificates are self-signed, but the components only listen on the loopback address
+		client, err := tlsClient("", filepath.Join(certDir, "admin.crt"), filepath.Join(certDir, "admin.key"))
+		if err != nil {
+			return nil, err
+		}
+		port := 10257
+		if component == "kube-scheduler" {
+			port = 10259
+		}
+		return &Target{Component: component, URL: fmt.Sprintf("https://127.0.0.1:%d/debug/pprof", port), Client: client}, nil
+	case "etcd":
+		client, err := tlsClient(filepath.Join(k0sVars.EtcdCertDir, "ca.crt"), filepath.Join(certDir, "apiserver-etcd-client.crt"), filepath.Join(certDir, "apiserver-etcd-client.key"))
+		if err != nil {
+			return nil, err
+		}
+		return &Target{Component: component, URL: "https://127.0.0.1:2379/debug/pprof", Client: client}, nil
+	}
+	return nil, fmt.Errorf("unsupported component %q, supported components are %v", component, Components)
+}
+
+// tlsClient creates a client authenticating with the given certificate, the server certificate is not verified
+// if caFile is empty
+func tlsClient(caFile, certFile, keyFile string) (*http.Client, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the client certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if caFile == "" {
+		tlsConfig.InsecureSkipVerify = true
+	} else {
+		caCert, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(caCert)
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package provisioning
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+const (
+	// TokenFileName is the name of the join token file in a provisioning dir
+	TokenFileName = "token"
+	// ConfigFileName is the name of the cluster config file in a provisioning dir
+	ConfigFileName = "k0s.yaml"
+	// DefaultInterval is how often the provisioning path is checked
+	DefaultInterval = 5 * time.Second
+)
+
+// Bundle is the provisioning data found in the provisioning path
+type Bundle struct {
+	Token  string
+	Config []byte
+
+	files []string
+}
+
+// Wait blocks until the provisioning path contains a token or a config, and the files have stopped changing.
+// The path is either a dir, e.g. the mount point of removable media, or the token file itself.
+func Wait(path string, interval time.Duration) (*Bundle, error) {
+	logrus.Infof("waiting for the provisioning data in %s", path)
+	var previous *Bundle
+	for {
+		bundle, err := read(path)
+		if err != nil {
+			return nil, err
+		}
+		// the files need to be unchanged over two reads, so that partially copied files aren't used
+		if bundle != nil && previous != nil && bundle.equal(previous) {
+			logrus.Infof("found the provisioning data in %s", path)
+			return bundle, nil
+		}
+		previous = bundle
+		time.Sleep(interval)
+	}
+}
+
+// read returns the bundle in the path, nil if there's none yet
+func read(path string) (*Bundle, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{}
+	tokenPath := path
+	if info.IsDir() {
+		tokenPath = filepath.Join(path, TokenFileName)
+		configPath := filepath.Join(path, ConfigFileName)
+		if util.FileExists(configPath) {
+			if bundle.Config, err = ioutil.ReadFile(configPath); err != nil {
+				return nil, fmt.Errorf("failed to read provisioned config: %w", err)
+			}
+			bundle.files = append(bundle.files, configPath)
+		}
+	}
+	if util.FileExists(tokenPath) {
+		token, err := ioutil.ReadFile(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read provisioned token: %w", err)
+		}
+		bundle.Token = strings.TrimSpace(string(token))
+		bundle.files = append(bundle.files, tokenPath)
+	}
+	if bundle.Token == "" && bundle.Config == nil {
+		return nil, nil
+	}
+	return bundle, nil
+}
+
+func (b *Bundle) equal(other *Bundle) bool {
+	return b.Token == other.Token && bytes.Equal(b.Config, other.Config)
+}
+
+// Persist stores the bundle into the data dir, so that it survives restarts before the node has joined
+func (b *Bundle) Persist(k0sVars constant.CfgVars) error {
+	if err := util.InitDirectory(k0sVars.DataDir, constant.DataDirMode); err != nil {
+		return err
+	}
+	if b.Token != "" {
+		if err := ioutil.WriteFile(k0sVars.ProvisionedTokenPath, []byte(b.Token), 0600); err != nil {
+			return fmt.Errorf("failed to store provisioned token: %w", err)
+		}
+	}
+	if b.Config != nil {
+		if err := ioutil.WriteFile(k0sVars.ProvisionedConfigPath, b.Config, 0600); err != nil {
+			return fmt.Errorf("failed to store provisioned config: %w", err)
+		}
+	}
+	return nil
+}
+
+// Consume securely deletes the files of the bundle from the provisioning path
+func (b *Bundle) Consume() error {
+	for _, f := range b.files {
+		if err := SecureDelete(f); err != nil {
+			return fmt.Errorf("failed to delete provisioning file %s: %w", f, err)
+		}
+	}
+	return nil
+}
+
+// SecureDelete overwrites the file with zeros before removing it. Flash media may still keep copies of
+// the data in remapped blocks.
+func SecureDelete(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(make([]byte, info.Size())); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
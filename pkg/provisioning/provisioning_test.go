@@ -0,0 +1,77 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package provisioning
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestWaitAndConsume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-provisioning")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	media := filepath.Join(dir, "media")
+
+	// the media appears atomically with both of the files
+	staging := filepath.Join(dir, "staging")
+	require.NoError(t, os.Mkdir(staging, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(staging, TokenFileName), []byte("join-token\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(staging, ConfigFileName), []byte("spec: {}"), 0600))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = os.Rename(staging, media)
+	}()
+
+	bundle, err := Wait(media, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "join-token", bundle.Token)
+	assert.Equal(t, "spec: {}", string(bundle.Config))
+
+	k0sVars := constant.GetConfig(filepath.Join(dir, "data"))
+	require.NoError(t, bundle.Persist(k0sVars))
+	token, err := ioutil.ReadFile(k0sVars.ProvisionedTokenPath)
+	require.NoError(t, err)
+	assert.Equal(t, "join-token", string(token))
+
+	require.NoError(t, bundle.Consume())
+	assert.NoFileExists(t, filepath.Join(media, TokenFileName))
+	assert.NoFileExists(t, filepath.Join(media, ConfigFileName))
+}
+
+func TestReadTokenFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-provisioning")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bundle, err := read(filepath.Join(dir, "token"))
+	require.NoError(t, err)
+	assert.Nil(t, bundle)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("join-token"), 0600))
+	bundle, err = read(filepath.Join(dir, "token"))
+	require.NoError(t, err)
+	assert.Equal(t, "join-token", bundle.Token)
+	assert.Nil(t, bundle.Config)
+}
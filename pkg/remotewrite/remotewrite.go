@@ -0,0 +1,170 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// Series is a metric series with its samples, the metric name is given as the __name__ label
+type Series struct {
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// Sample is a value of a series at a point in time
+type Sample struct {
+	Value     float64
+	Timestamp time.Time
+}
+
+// NewSeries creates a series with a single sample
+func NewSeries(name string, labels map[string]string, value float64, ts time.Time) Series {
+	l := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		l[k] = v
+	}
+	l["__name__"] = name
+	return Series{Labels: l, Samples: []Sample{{Value: value, Timestamp: ts}}}
+}
+
+// Client pushes series to a Prometheus remote-write endpoint
+type Client struct {
+	URL         string
+	BearerToken string
+	UserAgent   string
+	HTTPClient  *http.Client
+}
+
+// Write sends the series in a single snappy compressed WriteRequest
+func (c *Client) Write(ctx context.Context, series []Series) error {
+	body := snappy.Encode(nil, EncodeWriteRequest(series))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(bytes.TrimSpace(msg))}
+	}
+	return nil
+}
+
+// StatusError is returned when the endpoint answers with another status than 2xx
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("remote write returned %s: %s", e.Status, e.Message)
+}
+
+// Recoverable tells if the same series may be accepted when sent again. As in the remote-write protocol, only 5xx
+// and 429 are retried, the endpoint rejects the other 4xx again.
+func (e *StatusError) Recoverable() bool {
+	return e.StatusCode/100 == 5 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// EncodeWriteRequest encodes the series as the protobuf WriteRequest of the remote-write protocol. The few messages
+// involved are encoded by hand rather than pulling the prometheus module in.
+func EncodeWriteRequest(series []Series) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		names := make([]string, 0, len(s.Labels))
+		for name := range s.Labels {
+			names = append(names, name)
+		}
+		// the labels are required to be sorted by name
+		sort.Strings(names)
+		for _, name := range names {
+			var label []byte
+			label = appendBytes(label, 1, []byte(name))
+			label = appendBytes(label, 2, []byte(s.Labels[name]))
+			ts = appendBytes(ts, 1, label)
+		}
+		for _, sample := range s.Samples {
+			var sm []byte
+			sm = appendTag(sm, 1, wireFixed64)
+			sm = appendFixed64(sm, math.Float64bits(sample.Value))
+			sm = appendTag(sm, 2, wireVarint)
+			sm = appendVarint(sm, uint64(sample.Timestamp.UnixNano()/int64(time.Millisecond)))
+			ts = appendBytes(ts, 2, sm)
+		}
+		req = appendBytes(req, 1, ts)
+	}
+	return req
+}
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package remotewrite
+
+import (
+	"errors"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeWriteRequest(t *testing.T) {
+	series := []Series{NewSeries("up", nil, 1, time.Unix(1, 0))}
+
+	expected := []byte{
+		0x0a, 0x1e, // timeseries
+		0x0a, 0x0e, // label
+		0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+		0x12, 0x02, 'u', 'p',
+		0x12, 0x0c, // sample
+		0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f,
+		0x10, 0xe8, 0x07,
+	}
+	assert.Equal(t, expected, EncodeWriteRequest(series))
+}
+
+func TestWrite(t *testing.T) {
+	series := []Series{
+		NewSeries("k0s_fleet_nodes", map[string]string{"cluster": "edge-1"}, 3, time.Now()),
+		NewSeries("k0s_fleet_nodes_ready", map[string]string{"cluster": "edge-1"}, 2, time.Now()),
+	}
+
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		received, err = snappy.Decode(nil, body)
+		assert.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL, BearerToken: "secret"}
+	require.NoError(t, c.Write(context.Background(), series))
+	assert.Equal(t, EncodeWriteRequest(series), received)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	c.URL = failing.URL
+	err := c.Write(context.Background(), series)
+	assert.EqualError(t, err, "remote write returned 400 Bad Request: out of order sample")
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.False(t, statusErr.Recoverable())
+	assert.True(t, (&StatusError{StatusCode: http.StatusTooManyRequests}).Recoverable())
+	assert.True(t, (&StatusError{StatusCode: http.StatusServiceUnavailable}).Recoverable())
+}
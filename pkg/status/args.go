@@ -0,0 +1,106 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/k0sproject/k0s/internal/util"
+)
+
+// ArgChange is a difference of the flags or of the config file of a component between two k0s versions
+type ArgChange struct {
+	Field    string `json:"field" yaml:"field"`
+	Previous string `json:"previous" yaml:"previous"`
+	Current  string `json:"current" yaml:"current"`
+}
+
+// String formats the change for humans
+func (c ArgChange) String() string {
+	return fmt.Sprintf("%s: %q -> %q", c.Field, c.Previous, c.Current)
+}
+
+// ComponentArgs are the flags and the config file a supervised component was last started with
+type ComponentArgs struct {
+	Component string    `json:"component" yaml:"component"`
+	Version   string    `json:"version" yaml:"version"`
+	Args      []string  `json:"args" yaml:"args"`
+	Config    string    `json:"config,omitempty" yaml:"config,omitempty"`
+	StartedAt time.Time `json:"startedAt" yaml:"startedAt"`
+	// PreviousVersion and Changes are the differences to the last start with another k0s version
+	PreviousVersion string      `json:"previousVersion,omitempty" yaml:"previousVersion,omitempty"`
+	Changes         []ArgChange `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// WriteComponentArgs stores the args of the component into the dir, the config files may hold credentials
+func WriteComponentArgs(dir string, args ComponentArgs) error {
+	if err := util.InitDirectory(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(filepath.Join(dir, args.Component+".json"), data, 0600)
+}
+
+// ReadComponentArgs reads the args of the component stored with WriteComponentArgs, nil if there are none
+func ReadComponentArgs(dir, component string) (*ComponentArgs, error) {
+	path := filepath.Join(dir, component+".json")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	args := &ComponentArgs{}
+	if err := json.Unmarshal(data, args); err != nil {
+		return nil, fmt.Errorf("failed to parse the component args %s: %w", path, err)
+	}
+	return args, nil
+}
+
+// ListComponentArgs reads the args of all the components in the dir, sorted by component
+func ListComponentArgs(dir string) ([]ComponentArgs, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []ComponentArgs
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		args, err := ReadComponentArgs(dir, strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *args)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Component < list[j].Component })
+	return list, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ClusterMetadata identifies the cluster the node belongs to
+type ClusterMetadata struct {
+	Name   string            `json:"name" yaml:"name"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// NodeCount is the amount of nodes last seen by the controller
+	NodeCount int `json:"nodeCount,omitempty" yaml:"nodeCount,omitempty"`
+}
+
+// WriteClusterMetadata stores the metadata into the given file
+func WriteClusterMetadata(path string, metadata ClusterMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ReadClusterMetadata reads the metadata previously stored with WriteClusterMetadata
+func ReadClusterMetadata(path string) (*ClusterMetadata, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &ClusterMetadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster metadata %s: %w", path, err)
+	}
+	return metadata, nil
+}
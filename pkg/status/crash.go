@@ -0,0 +1,153 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCrashRetention is the amount of crashes kept per component, the oldest are removed
+const DefaultCrashRetention = 5
+
+const (
+	crashInfoFile = "crash.json"
+	crashLogFile  = "output.log"
+	crashDirMode  = 0700
+)
+
+// Crash is an unexpected exit of a supervised component. Each crash is kept in a dir of its own in the crash dir,
+// along with the last output lines of the component and the core dump if one was collected.
+type Crash struct {
+	Component string    `json:"component" yaml:"component"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Pid       int       `json:"pid" yaml:"pid"`
+	// ExitCode is -1 when the process was killed by a signal
+	ExitCode   int    `json:"exitCode" yaml:"exitCode"`
+	Signal     string `json:"signal,omitempty" yaml:"signal,omitempty"`
+	CoreDumped bool   `json:"coreDumped,omitempty" yaml:"coreDumped,omitempty"`
+	CoreFile   string `json:"coreFile,omitempty" yaml:"coreFile,omitempty"`
+	Dir        string `json:"-" yaml:"dir"`
+}
+
+// String formats the crash for humans
+func (c Crash) String() string {
+	reason := fmt.Sprintf("exit code %d", c.ExitCode)
+	if c.Signal != "" {
+		reason = "signal: " + c.Signal
+	}
+	if c.CoreDumped {
+		reason += ", core dumped"
+	}
+	return fmt.Sprintf("%s %s ago (%s), %s", c.Component, time.Since(c.Timestamp).Round(time.Second), reason, c.Dir)
+}
+
+// WriteCrash stores the crash and the output lines into a new dir in the crash dir. The core file is moved into it
+// when it's on the same file system. Only the latest retention crashes of the component are kept.
+func WriteCrash(crashDir string, crash Crash, output []string, retention int) (*Crash, error) {
+	crash.Dir = filepath.Join(crashDir, fmt.Sprintf("%s-%s", crash.Component, crash.Timestamp.UTC().Format("20060102T150405.000Z")))
+	if err := os.MkdirAll(crash.Dir, crashDirMode); err != nil {
+		return nil, err
+	}
+	if crash.CoreFile != "" {
+		coreFile := filepath.Join(crash.Dir, filepath.Base(crash.CoreFile))
+		if err := os.Rename(crash.CoreFile, coreFile); err == nil {
+			crash.CoreFile = coreFile
+		}
+	}
+
+	log := strings.Join(output, "\n")
+	if log != "" {
+		log += "\n"
+	}
+	if err := ioutil.WriteFile(filepath.Join(crash.Dir, crashLogFile), []byte(log), 0600); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(crash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(crash.Dir, crashInfoFile), data, 0600); err != nil {
+		return nil, err
+	}
+	return &crash, pruneCrashes(crashDir, crash.Component, retention)
+}
+
+// Output reads the last output lines of the component kept with the crash
+func (c Crash) Output() ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(c.Dir, crashLogFile))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n"), nil
+}
+
+// ListCrashes reads the crashes kept in the crash dir, oldest first
+func ListCrashes(crashDir string) ([]Crash, error) {
+	entries, err := ioutil.ReadDir(crashDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var crashes []Crash
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(crashDir, entry.Name())
+		data, err := ioutil.ReadFile(filepath.Join(dir, crashInfoFile))
+		if err != nil {
+			// being written, or not a crash
+			continue
+		}
+		crash := Crash{}
+		if err := json.Unmarshal(data, &crash); err != nil {
+			return nil, fmt.Errorf("failed to parse crash %s: %w", dir, err)
+		}
+		crash.Dir = dir
+		crashes = append(crashes, crash)
+	}
+	sort.SliceStable(crashes, func(i, j int) bool { return crashes[i].Timestamp.Before(crashes[j].Timestamp) })
+	return crashes, nil
+}
+
+func pruneCrashes(crashDir, component string, retention int) error {
+	crashes, err := ListCrashes(crashDir)
+	if err != nil {
+		return err
+	}
+	var kept []Crash
+	for _, crash := range crashes {
+		if crash.Component == component {
+			kept = append(kept, crash)
+		}
+	}
+	for len(kept) > retention {
+		if err := os.RemoveAll(kept[0].Dir); err != nil {
+			return err
+		}
+		kept = kept[1:]
+	}
+	return nil
+}
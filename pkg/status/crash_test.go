@@ -0,0 +1,70 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-crashes")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	core := filepath.Join(dir, "core.4242")
+	require.NoError(t, ioutil.WriteFile(core, []byte("core"), 0600))
+
+	start := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	crash, err := WriteCrash(dir, Crash{
+		Component:  "kube-apiserver",
+		Timestamp:  start,
+		Pid:        4242,
+		ExitCode:   -1,
+		Signal:     "segmentation fault",
+		CoreDumped: true,
+		CoreFile:   core,
+	}, []string{"first line", "last line"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(crash.Dir, "core.4242"), crash.CoreFile)
+	assert.FileExists(t, crash.CoreFile)
+	log, err := ioutil.ReadFile(filepath.Join(crash.Dir, crashLogFile))
+	require.NoError(t, err)
+	assert.Equal(t, "first line\nlast line\n", string(log))
+
+	// only the latest crashes of each component are kept
+	for i := 1; i <= 2; i++ {
+		_, err := WriteCrash(dir, Crash{Component: "kube-apiserver", Timestamp: start.Add(time.Duration(i) * time.Minute), ExitCode: 1}, nil, 2)
+		require.NoError(t, err)
+	}
+	_, err = WriteCrash(dir, Crash{Component: "etcd", Timestamp: start, ExitCode: 2}, nil, 2)
+	require.NoError(t, err)
+
+	crashes, err := ListCrashes(dir)
+	require.NoError(t, err)
+	require.Len(t, crashes, 3)
+	assert.Equal(t, "etcd", crashes[0].Component)
+	assert.Equal(t, "kube-apiserver", crashes[1].Component)
+	assert.Equal(t, start.Add(time.Minute), crashes[1].Timestamp)
+	assert.Equal(t, start.Add(2*time.Minute), crashes[2].Timestamp)
+	assert.NoFileExists(t, crash.Dir)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// DriftItem describes a single difference between the declared and the running state
+type DriftItem struct {
+	Component string `json:"component" yaml:"component"`
+	Field     string `json:"field" yaml:"field"`
+	Declared  string `json:"declared" yaml:"declared"`
+	Actual    string `json:"actual" yaml:"actual"`
+}
+
+// String formats the drift item for humans
+func (d DriftItem) String() string {
+	return fmt.Sprintf("%s %s: declared %q, running %q", d.Component, d.Field, d.Declared, d.Actual)
+}
+
+// DriftReport is the outcome of the latest config drift check
+type DriftReport struct {
+	CheckedAt time.Time   `json:"checkedAt" yaml:"checkedAt"`
+	Items     []DriftItem `json:"items" yaml:"items"`
+}
+
+// WriteDriftReport stores the report into the given file
+func WriteDriftReport(path string, report DriftReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ReadDriftReport reads a report previously stored with WriteDriftReport
+func ReadDriftReport(path string) (*DriftReport, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &DriftReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to parse config drift report %s: %w", path, err)
+	}
+	return report, nil
+}
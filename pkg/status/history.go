@@ -0,0 +1,173 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultHistoryRetention defines how long history events are kept
+const DefaultHistoryRetention = 7 * 24 * time.Hour
+
+var eventsBucket = []byte("events")
+
+// Event types recorded into the history
+const (
+	EventHealthy   = "Healthy"
+	EventUnhealthy = "Unhealthy"
+	EventStarted   = "Started"
+	EventStopped   = "Stopped"
+)
+
+// Event is a single recorded component health transition or node lifecycle event
+type Event struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Component string    `json:"component" yaml:"component"`
+	Type      string    `json:"type" yaml:"type"`
+	Message   string    `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// History persists status events into a bolt database under the data dir.
+// The database is opened only for the duration of a single operation, so that
+// `k0s status history` can read it while k0s is running.
+type History struct {
+	path      string
+	retention time.Duration
+
+	mu   sync.Mutex
+	last map[string]string
+}
+
+// NewHistory creates a new history store backed by the given file
+func NewHistory(path string, retention time.Duration) *History {
+	return &History{
+		path:      path,
+		retention: retention,
+		last:      make(map[string]string),
+	}
+}
+
+// Record stores the event, unless it repeats the previous event type of the same component
+func (h *History) Record(component, eventType, message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.last[component] == eventType {
+		return
+	}
+	h.last[component] = eventType
+
+	event := Event{
+		Timestamp: time.Now(),
+		Component: component,
+		Type:      eventType,
+		Message:   message,
+	}
+	if err := h.write(event); err != nil {
+		logrus.Warnf("failed to record status history event for %s: %v", component, err)
+	}
+}
+
+func (h *History) write(event Event) error {
+	db, err := bolt.Open(h.path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(eventsBucket)
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := b.Put(eventKey(event.Timestamp, seq), data); err != nil {
+			return err
+		}
+		return prune(b, time.Now().Add(-h.retention))
+	})
+}
+
+// List returns all the recorded events newer than since, oldest first
+func (h *History) List(since time.Time) ([]Event, error) {
+	db, err := bolt.Open(h.path, 0600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open status history %s: %w", h.path, err)
+	}
+	defer db.Close()
+
+	var events []Event
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket)
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		k, v := c.First()
+		if !since.IsZero() {
+			k, v = c.Seek(eventKey(since, 0))
+		}
+		for ; k != nil; k, v = c.Next() {
+			var e Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return nil
+	})
+	return events, err
+}
+
+// prune removes all events older than the given cut-off time
+func prune(b *bolt.Bucket, before time.Time) error {
+	var expired [][]byte
+	c := b.Cursor()
+	cutoff := eventKey(before, 0)
+	for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+		expired = append(expired, append([]byte(nil), k...))
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventKey builds a sortable key out of the event timestamp and a sequence number
+func eventKey(ts time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(ts.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
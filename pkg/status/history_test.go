@@ -0,0 +1,69 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryRecordAndList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-status-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := NewHistory(filepath.Join(dir, "history.db"), DefaultHistoryRetention)
+	h.Record("Etcd", EventHealthy, "")
+	// repeated event types for the same component are not recorded twice
+	h.Record("Etcd", EventHealthy, "")
+	h.Record("Etcd", EventUnhealthy, "connection refused")
+	h.Record("APIServer", EventHealthy, "")
+
+	events, err := h.List(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "Etcd", events[0].Component)
+	assert.Equal(t, EventHealthy, events[0].Type)
+	assert.Equal(t, EventUnhealthy, events[1].Type)
+	assert.Equal(t, "connection refused", events[1].Message)
+	assert.Equal(t, "APIServer", events[2].Component)
+
+	events, err = h.List(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestHistoryRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-status-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h := NewHistory(filepath.Join(dir, "history.db"), time.Millisecond)
+	h.Record("Etcd", EventHealthy, "")
+	time.Sleep(10 * time.Millisecond)
+	h.Record("Etcd", EventStopped, "")
+
+	events, err := h.List(time.Time{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventStopped, events[0].Type)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// SnapshotStatus is the state of the periodic etcd snapshots of the controller
+type SnapshotStatus struct {
+	LastSnapshot time.Time `json:"lastSnapshot,omitempty" yaml:"lastSnapshot,omitempty"`
+	Path         string    `json:"path,omitempty" yaml:"path,omitempty"`
+	LastError    string    `json:"lastError,omitempty" yaml:"lastError,omitempty"`
+	NextRun      time.Time `json:"nextRun" yaml:"nextRun"`
+	// StaleAfter is the snapshot age after which a snapshot has been missed
+	StaleAfter time.Duration `json:"staleAfter" yaml:"staleAfter"`
+}
+
+// Age returns the age of the last snapshot, zero if none has been taken yet
+func (s SnapshotStatus) Age(now time.Time) time.Duration {
+	if s.LastSnapshot.IsZero() {
+		return 0
+	}
+	return now.Sub(s.LastSnapshot)
+}
+
+// Stale tells if the scheduled snapshots have been missed
+func (s SnapshotStatus) Stale(now time.Time) bool {
+	return s.LastError != "" || (!s.LastSnapshot.IsZero() && s.Age(now) > s.StaleAfter)
+}
+
+// String formats the snapshot status for humans
+func (s SnapshotStatus) String() string {
+	now := time.Now()
+	msg := "none taken yet"
+	if !s.LastSnapshot.IsZero() {
+		msg = fmt.Sprintf("%s ago (%s)", s.Age(now).Round(time.Second), s.Path)
+	}
+	if s.LastError != "" {
+		msg += ", last attempt failed: " + s.LastError
+	}
+	if s.Stale(now) {
+		msg += ", STALE"
+	} else if s.StaleAfter > 0 {
+		msg += fmt.Sprintf(", stale after %s", s.StaleAfter)
+	}
+	return msg
+}
+
+// WriteSnapshotStatus stores the status into the given file
+func WriteSnapshotStatus(path string, s SnapshotStatus) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ReadSnapshotStatus reads a status previously stored with WriteSnapshotStatus
+func ReadSnapshotStatus(path string) (*SnapshotStatus, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &SnapshotStatus{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse etcd snapshot status %s: %w", path, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,40 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package supervisor
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// EnableCoreDumps raises the core size limit of k0s to its hard limit, the supervised processes inherit it. Where the
+// cores are written is up to the core_pattern of the host.
+func EnableCoreDumps() error {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &limit); err != nil {
+		return fmt.Errorf("failed to get the core size limit: %w", err)
+	}
+	if limit.Max == 0 {
+		return fmt.Errorf("the hard core size limit is zero, e.g. LimitCORE of the k0s service needs to be raised")
+	}
+	limit.Cur = limit.Max
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &limit); err != nil {
+		return fmt.Errorf("failed to raise the core size limit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package supervisor
+
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+// crashLogLines is the amount of output lines kept with a crash
+const crashLogLines = 100
+
+// crashLogLineLength caps the kept length of each line, so that a process writing without newlines doesn't grow the
+// tail without bounds
+const crashLogLineLength = 4096
+
+// outputTail keeps the last lines written by the supervised process
+type outputTail struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newOutputTail(max int) *outputTail {
+	return &outputTail{max: max}
+}
+
+// Write keeps the lines of the output, the lines longer than crashLogLineLength are truncated
+func (o *outputTail) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		chunk := p
+		if i >= 0 {
+			chunk = p[:i]
+		}
+		if room := crashLogLineLength - len(o.partial); room > 0 {
+			if len(chunk) > room {
+				chunk = chunk[:room]
+			}
+			o.partial = append(o.partial, chunk...)
+		}
+		if i < 0 {
+			break
+		}
+		o.lines = append(o.lines, string(o.partial))
+		o.partial = o.partial[:0]
+		p = p[i+1:]
+	}
+	if len(o.lines) > o.max {
+		o.lines = append([]string(nil), o.lines[len(o.lines)-o.max:]...)
+	}
+	return n, nil
+}
+
+// Lines returns the kept lines, the unterminated last line included
+func (o *outputTail) Lines() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	lines := append([]string(nil), o.lines...)
+	if len(o.partial) > 0 {
+		lines = append(lines, string(o.partial))
+	}
+	return lines
+}
+
+// recordCrash keeps the exit status and the last output of the process in the crash dir, unless it exited cleanly
+func (s *Supervisor) recordCrash(output *outputTail) {
+	if s.CrashDir == "" {
+		return
+	}
+	state := s.cmd.ProcessState
+	crash := status.Crash{
+		Component: s.Name,
+		Timestamp: time.Now(),
+		Pid:       state.Pid(),
+		ExitCode:  state.ExitCode(),
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		crash.Signal = ws.Signal().String()
+		crash.CoreDumped = ws.CoreDump()
+	}
+	if crash.ExitCode == 0 && crash.Signal == "" {
+		return
+	}
+	if crash.CoreDumped {
+		crash.CoreFile = findCoreFile(s.cmd.Dir, crash.Pid)
+	}
+
+	saved, err := status.WriteCrash(s.CrashDir, crash, output.Lines(), status.DefaultCrashRetention)
+	if err != nil {
+		s.log.Warnf("failed to keep the crash: %v", err)
+		return
+	}
+	s.log.Warnf("crash kept in %s", saved.Dir)
+}
+
+// findCoreFile looks for the core file in the working dir of the process, where the kernel writes it with the
+// default core_pattern. Empty is returned if the core was handed to e.g. systemd-coredump.
+func findCoreFile(dir string, pid int) string {
+	for _, name := range []string{"core." + strconv.Itoa(pid), "core"} {
+		if path := filepath.Join(dir, name); util.FileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
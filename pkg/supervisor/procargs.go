@@ -0,0 +1,59 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package supervisor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ProcessArgs returns the flags of the running supervised process with the given name
+func ProcessArgs(runDir string, name string) (map[string]string, error) {
+	pidFile := path.Join(runDir, name) + ".pid"
+	pidData, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidData)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pid file %s: %w", pidFile, err)
+	}
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseArgs(strings.Split(string(cmdline), "\x00")), nil
+}
+
+// parseArgs collects the --flag=value style flags into a map
+func parseArgs(cmdline []string) map[string]string {
+	args := make(map[string]string)
+	for _, arg := range cmdline {
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)
+		if len(kv) == 2 {
+			args[kv[0]] = kv[1]
+		} else {
+			args[kv[0]] = "true"
+		}
+	}
+	return args
+}
@@ -17,6 +17,7 @@ package supervisor
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -32,6 +33,10 @@ import (
 	"github.com/k0sproject/k0s/pkg/constant"
 )
 
+// ManagedEnv is set in the environment of the supervised processes, so that they, and their children, can be told
+// apart from other processes, e.g. by k0s reset after k0s crashed
+const ManagedEnv = "_KOS_MANAGED"
+
 // Supervisor is dead simple and stupid process supervisor, just tries to keep the process running in a while-true loop
 type Supervisor struct {
 	Name           string
@@ -44,6 +49,11 @@ type Supervisor struct {
 	GID            int
 	TimeoutStop    time.Duration
 	TimeoutRespawn time.Duration
+	// CrashDir keeps the exit status and the last output of the unexpected exits, nothing is kept if it's empty
+	CrashDir string
+	// ArgsDir keeps the flags the process was last started with, to report their changes on upgrades. Nothing is
+	// kept if it's empty.
+	ArgsDir string
 
 	cmd  *exec.Cmd
 	quit chan bool
@@ -53,7 +63,7 @@ type Supervisor struct {
 
 // processWaitQuit waits for a process to exit or a shut down signal
 // returns true if shutdown is requested
-func (s *Supervisor) processWaitQuit() bool {
+func (s *Supervisor) processWaitQuit(output *outputTail) bool {
 	waitresult := make(chan error)
 	go func() {
 		waitresult <- s.cmd.Wait()
@@ -87,6 +97,7 @@ func (s *Supervisor) processWaitQuit() bool {
 		} else {
 			s.log.Warnf("Process exited with code: %d", s.cmd.ProcessState.ExitCode())
 		}
+		s.recordCrash(output)
 	}
 	return false
 }
@@ -106,6 +117,7 @@ func (s *Supervisor) Supervise() error {
 	if s.TimeoutRespawn == 0 {
 		s.TimeoutRespawn = 5 * time.Second
 	}
+	s.recordArgs()
 
 	started := make(chan error)
 	go func() {
@@ -119,8 +131,9 @@ func (s *Supervisor) Supervise() error {
 			// get signals sent directly to parent.
 			s.cmd.SysProcAttr = DetachAttr(s.UID, s.GID)
 
-			s.cmd.Stdout = s.log.Writer()
-			s.cmd.Stderr = s.log.Writer()
+			output := newOutputTail(crashLogLines)
+			s.cmd.Stdout = io.MultiWriter(s.log.Writer(), output)
+			s.cmd.Stderr = io.MultiWriter(s.log.Writer(), output)
 
 			err := s.cmd.Start()
 			if err != nil {
@@ -141,7 +154,7 @@ func (s *Supervisor) Supervise() error {
 				} else {
 					s.log.Info("Restarted")
 				}
-				if s.processWaitQuit() {
+				if s.processWaitQuit(output) {
 					return
 				}
 			}
@@ -178,5 +191,5 @@ func getEnv(dataDir string) []string {
 			env[i] = fmt.Sprintf("PATH=%s:%s", path.Join(dataDir, "bin"), os.Getenv("PATH"))
 		}
 	}
-	return env
+	return append(env, ManagedEnv+"=yes")
 }
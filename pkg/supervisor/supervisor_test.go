@@ -1,6 +1,9 @@
 package supervisor
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 type SupervisorTest struct {
 	shouldFail bool
@@ -57,3 +60,29 @@ func TestSupervisor(t *testing.T) {
 		}
 	}
 }
+
+func TestOutputTail(t *testing.T) {
+	output := newOutputTail(2)
+	for _, data := range []string{"first\nsec", "ond\nthird\n", "unterminated"} {
+		n, err := output.Write([]byte(data))
+		if err != nil || n != len(data) {
+			t.Fatalf("failed to write %q: %d, %v", data, n, err)
+		}
+	}
+	lines := output.Lines()
+	expected := []string{"second", "third", "unterminated"}
+	if strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	// the lines are capped, a process writing without newlines doesn't grow the tail
+	output = newOutputTail(2)
+	for i := 0; i < 3; i++ {
+		_, _ = output.Write([]byte(strings.Repeat("x", crashLogLineLength)))
+	}
+	_, _ = output.Write([]byte("\nnext\n"))
+	lines = output.Lines()
+	if len(lines) != 2 || len(lines[0]) != crashLogLineLength || lines[1] != "next" {
+		t.Errorf("expected a capped line and next, got %d lines", len(lines))
+	}
+}
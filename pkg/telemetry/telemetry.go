@@ -17,6 +17,8 @@ import (
 type telemetryData struct {
 	StorageType            string
 	ClusterID              string
+	ClusterName            string
+	ClusterLabels          map[string]string
 	WorkerNodesCount       int
 	ControlPlaneNodesCount int
 	WorkerData             []workerData
@@ -36,6 +38,8 @@ func (td telemetryData) asProperties() analytics.Properties {
 	return analytics.Properties{
 		"storageType":            td.StorageType,
 		"clusterID":              td.ClusterID,
+		"clusterName":            td.ClusterName,
+		"clusterLabels":          td.ClusterLabels,
 		"workerNodesCount":       td.WorkerNodesCount,
 		"controlPlaneNodesCount": td.ControlPlaneNodesCount,
 		"workerData":             td.WorkerData,
@@ -54,6 +58,8 @@ func (c Component) collectTelemetry() (telemetryData, error) {
 	if err != nil {
 		return data, fmt.Errorf("can't collect cluster ID: %v", err)
 	}
+	data.ClusterName = c.ClusterConfig.ClusterName()
+	data.ClusterLabels = c.ClusterConfig.Spec.ClusterLabels
 	wds, sums, err := c.getWorkerData()
 	if err != nil {
 		return data, fmt.Errorf("can't collect workers count: %v", err)
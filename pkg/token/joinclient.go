@@ -3,7 +3,9 @@ Copyright 2021 k0s authors
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
 You may obtain a copy of the License at
+
     http://www.apache.org/licenses/LICENSE-2.0
+
 Unless required by applicable law or agreed to in writing, software
 distributed under the License is distributed on an "AS IS" BASIS,
 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
@@ -23,6 +25,7 @@ import (
 	"os"
 
 	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/exitcode"
 	"github.com/sirupsen/logrus"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -38,16 +41,16 @@ type JoinClient struct {
 func JoinClientFromToken(encodedToken string) (*JoinClient, error) {
 	tokenBytes, err := DecodeJoinToken(encodedToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode token: %w", err)
+		return nil, exitcode.Wrap(exitcode.Join, fmt.Errorf("failed to decode token: %w", err))
 	}
 
 	clientConfig, err := clientcmd.NewClientConfigFromBytes(tokenBytes)
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.Join, err)
 	}
 	config, err := clientConfig.ClientConfig()
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.Join, err)
 	}
 
 	ca := x509.NewCertPool()
@@ -82,7 +85,7 @@ func (j *JoinClient) GetCA() (v1beta1.CaResponse, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return caData, fmt.Errorf("unexpected response status: %s", resp.Status)
+		return caData, statusError(resp, fmt.Errorf("unexpected response status: %s", resp.Status))
 	}
 	logrus.Info("got valid CA response")
 	if resp.Body == nil {
@@ -100,10 +103,11 @@ func (j *JoinClient) GetCA() (v1beta1.CaResponse, error) {
 }
 
 // JoinEtcd calls the etcd join API
-func (j *JoinClient) JoinEtcd(peerAddress string) (v1beta1.EtcdResponse, error) {
+func (j *JoinClient) JoinEtcd(peerAddress string, learner bool) (v1beta1.EtcdResponse, error) {
 	var etcdResponse v1beta1.EtcdResponse
 	etcdRequest := v1beta1.EtcdRequest{
 		PeerAddress: peerAddress,
+		Learner:     learner,
 	}
 	name, err := os.Hostname()
 	if err != nil {
@@ -127,7 +131,7 @@ func (j *JoinClient) JoinEtcd(peerAddress string) (v1beta1.EtcdResponse, error)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return etcdResponse, fmt.Errorf("unexpected response status when trying to join etcd cluster: %s", resp.Status)
+		return etcdResponse, statusError(resp, fmt.Errorf("unexpected response status when trying to join etcd cluster: %s", resp.Status))
 	}
 
 	b, err := ioutil.ReadAll(resp.Body)
@@ -141,3 +145,52 @@ func (j *JoinClient) JoinEtcd(peerAddress string) (v1beta1.EtcdResponse, error)
 
 	return etcdResponse, nil
 }
+
+// AttestationChallenge starts the TPM attestation
+func (j *JoinClient) AttestationChallenge(challengeRequest v1beta1.AttestationChallengeRequest) (v1beta1.AttestationChallengeResponse, error) {
+	var challenge v1beta1.AttestationChallengeResponse
+	err := j.post("/v1beta1/attestation/challenge", challengeRequest, &challenge)
+	return challenge, err
+}
+
+// Attest answers the TPM attestation challenge and returns the join token issued for the attested node
+func (j *JoinClient) Attest(quoteRequest v1beta1.AttestationQuoteRequest) (v1beta1.AttestationResponse, error) {
+	var attestation v1beta1.AttestationResponse
+	err := j.post("/v1beta1/attestation/quote", quoteRequest, &attestation)
+	return attestation, err
+}
+
+func (j *JoinClient) post(path string, request interface{}, response interface{}) error {
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(request); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, j.joinAddress+path, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", j.bearerToken))
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp, fmt.Errorf("unexpected response status: %s: %s", resp.Status, bytes.TrimSpace(b)))
+	}
+	return json.Unmarshal(b, response)
+}
+
+// statusError marks the error of a response refusing the token as a rejected join
+func statusError(resp *http.Response, err error) error {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return exitcode.Wrap(exitcode.Join, err)
+	}
+	return err
+}
@@ -21,10 +21,16 @@ import (
 	"fmt"
 	"html/template"
 	"io/ioutil"
+	"net"
 	"path/filepath"
+	"strconv"
 	"time"
 
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/k0sproject/k0s/internal/util"
 	config "github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/certificate"
 	"github.com/k0sproject/k0s/pkg/constant"
 )
 
@@ -37,15 +43,19 @@ var (
 	kubeconfigTemplate = template.Must(template.New("kubeconfig").Parse(`
 apiVersion: v1
 clusters:
+{{- range $i, $server := .Servers }}
 - cluster:
-    server: {{.JoinURL}}
-    certificate-authority-data: {{.CACert}}
-  name: k0s
+    server: {{ $server }}
+    certificate-authority-data: {{ $.CACert }}
+  name: k0s{{ if $i }}-{{ $i }}{{ end }}
+{{- end }}
 contexts:
+{{- range $i, $server := .Servers }}
 - context:
-    cluster: k0s
-    user: {{.User}}
-  name: k0s
+    cluster: k0s{{ if $i }}-{{ $i }}{{ end }}
+    user: {{ $.User }}
+  name: k0s{{ if $i }}-{{ $i }}{{ end }}
+{{- end }}
 current-context: k0s
 kind: Config
 preferences: {}
@@ -56,8 +66,20 @@ users:
 `))
 )
 
-func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars constant.CfgVars, role string, expiry time.Duration) (string, error) {
-	crtFile := filepath.Join(k0sVars.CertRootDir, "ca.crt")
+// AttestationUser is the user of the join tokens that must be exchanged for a bootstrap token with TPM attestation
+const AttestationUser = "kubelet-attestation"
+
+func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars constant.CfgVars, role string, expiry time.Duration, opts CreateOptions) (string, error) {
+	var extraServers []string
+	for _, endpoint := range opts.APIEndpoints {
+		server, err := apiEndpointURL(endpoint, clusterConfig.Spec.API.Port)
+		if err != nil {
+			return "", err
+		}
+		extraServers = append(extraServers, server)
+	}
+	// the token trusts the previous or the next CA too while the CA is rotated
+	crtFile := certificate.CABundlePath(k0sVars.CertRootDir)
 	caCert, err := ioutil.ReadFile(crtFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to read cluster ca certificate from %s: %w. check if the control plane is initialized on this node", crtFile, err)
@@ -66,7 +88,7 @@ func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars c
 	if err != nil {
 		return "", err
 	}
-	tokenString, err := manager.Create(expiry, role)
+	tokenString, err := manager.Create(expiry, role, opts)
 	if err != nil {
 		return "", err
 	}
@@ -74,18 +96,31 @@ func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars c
 		CACert  string
 		Token   string
 		User    string
-		JoinURL string
-		APIUrl  string
+		Servers []string
 	}{
 		CACert: base64.StdEncoding.EncodeToString(caCert),
 		Token:  tokenString,
 	}
-	if role == workerRole {
+	api := clusterConfig.Spec.API
+	apiURL, k0sAPIURL := api.APIAddressURL(), api.K0sControlPlaneAPIAddress()
+	if opts.Public {
+		apiURL, k0sAPIURL = api.PublicAPIAddressURL(), api.PublicK0sControlPlaneAPIAddress()
+	}
+	if role == workerRole && opts.Attestation {
+		// the token is only valid for the attestation on the k0s API
+		data.User = AttestationUser
+		data.Servers = []string{k0sAPIURL}
+	} else if role == workerRole {
 		data.User = "kubelet-bootstrap"
-		data.JoinURL = clusterConfig.Spec.API.APIAddressURL()
+		data.Servers = []string{apiURL}
+		for _, server := range extraServers {
+			if !util.StringSliceContains(data.Servers, server) {
+				data.Servers = append(data.Servers, server)
+			}
+		}
 	} else if role == controllerRole {
 		data.User = "controller-bootstrap"
-		data.JoinURL = clusterConfig.Spec.API.K0sControlPlaneAPIAddress()
+		data.Servers = []string{k0sAPIURL}
 	} else {
 		return "", fmt.Errorf("unsupported role %s only supported roles are %q and %q", role, controllerRole, workerRole)
 	}
@@ -98,3 +133,29 @@ func CreateKubeletBootstrapConfig(clusterConfig *config.ClusterConfig, k0sVars c
 	}
 	return JoinEncode(&buf)
 }
+
+// apiEndpointURL is the URL of an additional API server given as host or host:port, the port defaults to the API port
+func apiEndpointURL(endpoint string, port int) (string, error) {
+	host, p := endpoint, strconv.Itoa(port)
+	if h, hp, err := net.SplitHostPort(endpoint); err == nil {
+		host, p = h, hp
+	}
+	if _, err := strconv.ParseUint(p, 10, 16); err != nil || host == "" {
+		return "", fmt.Errorf("invalid API endpoint %q, expected host or host:port", endpoint)
+	}
+	return "https://" + net.JoinHostPort(host, p), nil
+}
+
+// RequiresAttestation tells if the join token must be exchanged for a bootstrap token with TPM attestation
+func RequiresAttestation(encodedToken string) (bool, error) {
+	kubeconfig, err := DecodeJoinToken(encodedToken)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode token: %w", err)
+	}
+	clientCfg, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse the join token: %w", err)
+	}
+	_, found := clientCfg.AuthInfos[AttestationUser]
+	return found, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestAPIEndpointURL(t *testing.T) {
+	for endpoint, expected := range map[string]string{
+		"10.0.0.2":         "https://10.0.0.2:6443",
+		"10.0.0.2:7443":    "https://10.0.0.2:7443",
+		"fd00::2":          "https://[fd00::2]:6443",
+		"[fd00::2]:7443":   "https://[fd00::2]:7443",
+		"controller-2.k0s": "https://controller-2.k0s:6443",
+	} {
+		url, err := apiEndpointURL(endpoint, 6443)
+		require.NoError(t, err, endpoint)
+		assert.Equal(t, expected, url)
+	}
+
+	_, err := apiEndpointURL("10.0.0.2:port", 6443)
+	assert.Error(t, err)
+}
+
+func TestKubeconfigTemplateServers(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, kubeconfigTemplate.Execute(&buf, struct {
+		CACert  string
+		Token   string
+		User    string
+		Servers []string
+	}{
+		CACert:  "Y2E=",
+		Token:   "abcdef.0123456789abcdef",
+		User:    "kubelet-bootstrap",
+		Servers: []string{"https://10.0.0.1:6443", "https://10.0.0.2:6443"},
+	}))
+
+	cfg, err := clientcmd.Load(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "k0s", cfg.CurrentContext)
+	assert.Equal(t, "https://10.0.0.1:6443", cfg.Clusters["k0s"].Server)
+	assert.Equal(t, "https://10.0.0.2:6443", cfg.Clusters["k0s-1"].Server)
+	assert.Equal(t, "k0s-1", cfg.Contexts["k0s-1"].Cluster)
+	assert.Equal(t, "kubelet-bootstrap", cfg.Contexts["k0s-1"].AuthInfo)
+}
@@ -17,7 +17,9 @@ package token
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -30,14 +32,30 @@ import (
 	k8sutil "github.com/k0sproject/k0s/pkg/kubernetes"
 )
 
+// ErrTokenNotFound is returned when removing a token that doesn't exist
+var ErrTokenNotFound = goerrors.New("token not found")
+
 type Token struct {
 	ID     string
 	Role   string
 	Expiry string
+	Quota  JoinQuota
+	Joins  int
+	// UsageLimit is the amount of nodes that may join with the token before it's deleted, 0 means unlimited
+	UsageLimit int
+	Uses       int
 }
 
 func (t Token) ToArray() []string {
-	return []string{t.ID, t.Role, t.Expiry}
+	quota := ""
+	if t.Quota.Enabled() {
+		quota = fmt.Sprintf("%d/%s", t.Joins, t.Quota)
+	}
+	uses := ""
+	if t.UsageLimit > 0 {
+		uses = fmt.Sprintf("%d/%d", t.Uses, t.UsageLimit)
+	}
+	return []string{t.ID, t.Role, t.Expiry, quota, uses}
 }
 
 // NewManager creates a new token manager using given kubeconfig
@@ -52,13 +70,33 @@ func NewManager(kubeconfig string) (*Manager, error) {
 	}, nil
 }
 
+// NewManagerForClient creates a new token manager using the given kube client
+func NewManagerForClient(client kubernetes.Interface) *Manager {
+	return &Manager{client: client}
+}
+
 // Manager is responsible to manage the join tokens in kube API as secrets in kube-system namespace
 type Manager struct {
 	client kubernetes.Interface
 }
 
-// Create creates a new bootstrap token
-func (m *Manager) Create(valid time.Duration, role string) (string, error) {
+// CreateOptions are the optional restrictions of the tokens
+type CreateOptions struct {
+	// UsageLimit is the amount of nodes that may join with the token before it's deleted, 0 means unlimited
+	UsageLimit int
+	// Quota limits the amount of nodes joining with the token
+	Quota JoinQuota
+	// Attestation limits the token to the TPM attestation, the attested nodes get a bootstrap token of their own
+	Attestation bool
+	// Public points the token to the public address of the control plane, for the nodes outside of the cluster network
+	Public bool
+	// APIEndpoints are the additional API servers, as host or host:port, the workers fail over to while joining
+	APIEndpoints []string
+}
+
+// Create creates a new bootstrap token. The usage limit applies to both roles, the other options are only applied to
+// worker tokens.
+func (m *Manager) Create(valid time.Duration, role string, opts CreateOptions) (string, error) {
 	tokenID := util.RandomString(6)
 	tokenSecret := util.RandomString(16)
 
@@ -78,16 +116,31 @@ func (m *Manager) Create(valid time.Duration, role string) (string, error) {
 	// windows workers during the join step
 	data["usage-bootstrap-api-auth"] = "true"
 
-	if role == "worker" {
+	if role == "worker" && opts.Attestation {
+		data["description"] = "Worker attestation token generated by k0s"
+		data["usage-bootstrap-authentication"] = "false"
+		data["usage-bootstrap-api-attestation"] = "true"
+	} else if role == "worker" {
 		data["description"] = "Worker bootstrap token generated by k0s"
 		data["usage-bootstrap-authentication"] = "true"
 		data["usage-bootstrap-api-worker-calls"] = "true"
+		// the quota keeps more nodes than the limit from joining while the token isn't deleted yet
+		if opts.UsageLimit > 0 && !opts.Quota.Enabled() {
+			opts.Quota = JoinQuota{MaxJoins: opts.UsageLimit}
+		}
+		opts.Quota.toData(data)
+		if !opts.Quota.Enabled() {
+			data["auth-extra-groups"] = AutoApproveGroup
+		}
 	} else {
 		data["description"] = "Controller bootstrap token generated by k0s"
 		data["usage-bootstrap-authentication"] = "false"
 		data["usage-bootstrap-signing"] = "false"
 		data["usage-controller-join"] = "true"
 	}
+	if opts.UsageLimit > 0 && !opts.Attestation {
+		data[UsageLimitKey] = strconv.Itoa(opts.UsageLimit)
+	}
 
 	secret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -122,20 +175,34 @@ func (m *Manager) List(role string) ([]Token, error) {
 			r = "controller"
 		}
 		if r == role || role == "" {
-			tokens = append(tokens, Token{
+			token := Token{
 				ID:     string(t.Data["token-id"]),
 				Role:   r,
 				Expiry: string(t.Data["expiration"]),
-			})
+			}
+			if quota, err := QuotaFromData(t.Data); err == nil {
+				token.Quota = quota
+			}
+			if joins, err := JoinsFromAnnotation(t.Annotations); err == nil {
+				token.Joins = len(joins)
+			}
+			if limit, err := UsageLimitFromData(t.Data); err == nil {
+				token.UsageLimit = limit
+			}
+			if uses, err := UsesFromAnnotation(t.Annotations); err == nil {
+				token.Uses = len(uses)
+			}
+			tokens = append(tokens, token)
 		}
 	}
 	return tokens, nil
 }
 
+// Remove deletes the join token, a token that doesn't exist is reported with ErrTokenNotFound
 func (m *Manager) Remove(tokenID string) error {
 	err := m.client.CoreV1().Secrets("kube-system").Delete(context.TODO(), fmt.Sprintf("bootstrap-token-%s", tokenID), metav1.DeleteOptions{})
 	if errors.IsNotFound(err) {
-		return nil
+		return ErrTokenNotFound
 	}
 	return err
 }
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func bootstrapSecret(id string, data map[string]string) *v1.Secret {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-" + id, Namespace: "kube-system"},
+		Type:       v1.SecretTypeBootstrapToken,
+		Data:       map[string][]byte{"token-id": []byte(id)},
+	}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+	return secret
+}
+
+func TestListAndRemove(t *testing.T) {
+	m := NewManagerForClient(fake.NewSimpleClientset(
+		bootstrapSecret("abcdef", map[string]string{"expiration": "2021-10-01T00:00:00Z", MaxJoinsKey: "3"}),
+		bootstrapSecret("ghijkl", map[string]string{"usage-controller-join": "true"}),
+	))
+
+	tokens, err := m.List("")
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+
+	tokens, err = m.List("worker")
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, []string{"abcdef", "worker", "2021-10-01T00:00:00Z", "0/3 joins", ""}, tokens[0].ToArray())
+
+	require.NoError(t, m.Remove("abcdef"))
+	assert.ErrorIs(t, m.Remove("abcdef"), ErrTokenNotFound)
+
+	tokens, err = m.List("")
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "controller", tokens[0].Role)
+}
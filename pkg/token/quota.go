@@ -0,0 +1,155 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// MaxJoinsKey is the bootstrap token secret key holding the amount of nodes the token may join
+	MaxJoinsKey = "k0s-max-joins"
+	// JoinWindowKey is the bootstrap token secret key holding the window the join quota applies to
+	JoinWindowKey = "k0s-join-window"
+	// JoinsAnnotation records the CSRs created with the token, keyed by the CSR name
+	JoinsAnnotation = "k0s.k0sproject.io/joins"
+	// QuotaExhaustedAnnotation marks tokens that were disabled by k0s because the quota was used up
+	QuotaExhaustedAnnotation = "k0s.k0sproject.io/join-quota-exhausted"
+	// AutoApproveGroup is the group of the worker tokens without a join quota, the controller manager approves the
+	// kubelet client CSRs of its members. k0s approves the other ones after checking their quota.
+	AutoApproveGroup = "system:bootstrappers:k0s-autoapprove"
+)
+
+// JoinQuota limits how many nodes can join with a single token
+type JoinQuota struct {
+	// MaxJoins is the amount of nodes allowed to join within the window, 0 means unlimited
+	MaxJoins int
+	// Window is the sliding window the joins are counted in, 0 means the whole lifetime of the token
+	Window time.Duration
+}
+
+// Enabled tells if the quota limits the joins at all
+func (q JoinQuota) Enabled() bool {
+	return q.MaxJoins > 0
+}
+
+// String formats the quota for humans
+func (q JoinQuota) String() string {
+	if !q.Enabled() {
+		return ""
+	}
+	if q.Window == 0 {
+		return fmt.Sprintf("%d joins", q.MaxJoins)
+	}
+	return fmt.Sprintf("%d joins per %s", q.MaxJoins, q.Window)
+}
+
+func (q JoinQuota) toData(data map[string]string) {
+	if !q.Enabled() {
+		return
+	}
+	data[MaxJoinsKey] = strconv.Itoa(q.MaxJoins)
+	if q.Window != 0 {
+		data[JoinWindowKey] = q.Window.String()
+	}
+}
+
+// QuotaFromData reads the join quota from the bootstrap token secret data
+func QuotaFromData(data map[string][]byte) (JoinQuota, error) {
+	q := JoinQuota{}
+	if max, found := data[MaxJoinsKey]; found {
+		n, err := strconv.Atoi(string(max))
+		if err != nil {
+			return q, fmt.Errorf("invalid %s: %w", MaxJoinsKey, err)
+		}
+		q.MaxJoins = n
+	}
+	if window, found := data[JoinWindowKey]; found {
+		d, err := time.ParseDuration(string(window))
+		if err != nil {
+			return q, fmt.Errorf("invalid %s: %w", JoinWindowKey, err)
+		}
+		q.Window = d
+	}
+	return q, nil
+}
+
+// Joins are the CSRs created with a token, mapped to their creation time
+type Joins map[string]time.Time
+
+// JoinsFromAnnotation decodes the joins recorded in the token secret annotations
+func JoinsFromAnnotation(annotations map[string]string) (Joins, error) {
+	joins := Joins{}
+	value, found := annotations[JoinsAnnotation]
+	if !found || value == "" {
+		return joins, nil
+	}
+	if err := json.Unmarshal([]byte(value), &joins); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", JoinsAnnotation, err)
+	}
+	return joins, nil
+}
+
+// Annotation encodes the joins for storing in the token secret annotations
+func (j Joins) Annotation() string {
+	data, _ := json.Marshal(j)
+	return string(data)
+}
+
+// Admit forgets the joins outside of the quota window and decides which of the new CSRs are still within
+// the quota. The admitted CSRs are recorded into the joins, the remaining ones are returned as denied.
+func (j Joins) Admit(quota JoinQuota, csrs map[string]time.Time, now time.Time) (admitted []string, denied []string) {
+	if quota.Window != 0 {
+		for name, created := range j {
+			if now.Sub(created) > quota.Window {
+				delete(j, name)
+			}
+		}
+	}
+
+	// admit the oldest requests first so that retries of a denied node can't starve the earlier ones
+	names := make([]string, 0, len(csrs))
+	for name := range csrs {
+		if _, found := j[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(a, b int) bool {
+		if csrs[names[a]].Equal(csrs[names[b]]) {
+			return names[a] < names[b]
+		}
+		return csrs[names[a]].Before(csrs[names[b]])
+	})
+
+	for _, name := range names {
+		if !quota.Enabled() || len(j) < quota.MaxJoins {
+			j[name] = csrs[name]
+			admitted = append(admitted, name)
+		} else {
+			denied = append(denied, name)
+		}
+	}
+	return admitted, denied
+}
+
+// Exhausted tells if the quota allows no further joins
+func (j Joins) Exhausted(quota JoinQuota) bool {
+	return quota.Enabled() && len(j) >= quota.MaxJoins
+}
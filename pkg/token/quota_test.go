@@ -0,0 +1,72 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaData(t *testing.T) {
+	data := map[string]string{}
+	JoinQuota{MaxJoins: 3, Window: time.Hour}.toData(data)
+	assert.Equal(t, "3", data[MaxJoinsKey])
+	assert.Equal(t, "1h0m0s", data[JoinWindowKey])
+
+	quota, err := QuotaFromData(map[string][]byte{MaxJoinsKey: []byte(data[MaxJoinsKey]), JoinWindowKey: []byte(data[JoinWindowKey])})
+	require.NoError(t, err)
+	assert.Equal(t, JoinQuota{MaxJoins: 3, Window: time.Hour}, quota)
+
+	_, err = QuotaFromData(map[string][]byte{MaxJoinsKey: []byte("many")})
+	assert.Error(t, err)
+}
+
+func TestJoinsAdmit(t *testing.T) {
+	now := time.Now()
+	quota := JoinQuota{MaxJoins: 2, Window: time.Hour}
+	joins := Joins{"old": now.Add(-2 * time.Hour), "recent": now.Add(-time.Minute)}
+
+	admitted, denied := joins.Admit(quota, map[string]time.Time{
+		"recent": now.Add(-time.Minute),
+		"second": now.Add(-10 * time.Second),
+		"first":  now.Add(-20 * time.Second),
+	}, now)
+	assert.Equal(t, []string{"first"}, admitted)
+	assert.Equal(t, []string{"second"}, denied)
+	assert.Len(t, joins, 2)
+	assert.True(t, joins.Exhausted(quota))
+
+	decoded, err := JoinsFromAnnotation(map[string]string{JoinsAnnotation: joins.Annotation()})
+	require.NoError(t, err)
+	assert.Len(t, decoded, 2)
+
+	// once the window has passed the token may be used again
+	admitted, denied = decoded.Admit(quota, map[string]time.Time{"third": now.Add(2 * time.Hour)}, now.Add(2*time.Hour))
+	assert.Equal(t, []string{"third"}, admitted)
+	assert.Empty(t, denied)
+	assert.False(t, decoded.Exhausted(quota))
+}
+
+func TestJoinsAdmitUnlimited(t *testing.T) {
+	joins := Joins{}
+	admitted, denied := joins.Admit(JoinQuota{}, map[string]time.Time{"a": time.Now(), "b": time.Now()}, time.Now())
+	assert.Len(t, admitted, 2)
+	assert.Empty(t, denied)
+	assert.False(t, joins.Exhausted(JoinQuota{}))
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// UserDataTokenVar is the variable the join token is given with in the user data of the instances, when the user data
+// holds more than the token, e.g. a cloud-init script
+const UserDataTokenVar = "K0S_JOIN_TOKEN"
+
+// MetadataProviders are the instance metadata services the join token can be fetched from
+var MetadataProviders = []string{"aws", "gcp", "azure"}
+
+// metadataEndpoints are the addresses of the instance metadata services, overridden in the tests
+var metadataEndpoints = map[string]string{
+	"aws":   "http://169.254.169.254",
+	"gcp":   "http://metadata.google.internal",
+	"azure": "http://169.254.169.254",
+}
+
+// fetchBackoff retries the fetch for about five minutes, the network of a booting instance may not be up yet
+var fetchBackoff = wait.Backoff{
+	Steps:    12,
+	Duration: time.Second,
+	Factor:   2,
+	Cap:      30 * time.Second,
+	Jitter:   0.1,
+}
+
+var fetchClient = &http.Client{Timeout: 10 * time.Second, CheckRedirect: refuseInsecureRedirect}
+
+// refuseInsecureRedirect keeps the token fetch from being redirected to plain http, where the token could be read
+// or replaced on the way
+func refuseInsecureRedirect(req *http.Request, via []*http.Request) error {
+	if req.URL.Scheme != "https" {
+		return fmt.Errorf("refusing the redirect to %s, the join token must be fetched over https", req.URL.Scheme+"://"+req.URL.Host)
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return nil
+}
+
+// IsURL tells if the token file is given as an http or https URL
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// CheckURL checks that the join token is fetched over https, the token grants joining the cluster and mustn't be
+// readable or replaceable on the way. Only the metadata endpoints of the cloud providers are read over http.
+func CheckURL(url string) error {
+	if !strings.HasPrefix(url, "https://") {
+		return fmt.Errorf("the join token must be fetched over https, got %s", url)
+	}
+	return nil
+}
+
+// FetchFromURL fetches the join token from the https URL, retrying with backoff
+func FetchFromURL(ctx context.Context, url string) (string, error) {
+	if err := CheckURL(url); err != nil {
+		return "", err
+	}
+	return fetch(ctx, url, func(ctx context.Context) ([]byte, error) {
+		return get(ctx, url, nil)
+	})
+}
+
+// FetchFromMetadata fetches the join token from the instance metadata of the cloud provider, retrying with backoff.
+// On AWS and Azure the token is read from the user data of the instance, on GCP from the k0s-join-token attribute
+// of the instance.
+func FetchFromMetadata(ctx context.Context, provider string) (string, error) {
+	endpoint, found := metadataEndpoints[provider]
+	if !found {
+		return "", fmt.Errorf("unsupported metadata provider %q, the supported providers are %s", provider, strings.Join(MetadataProviders, ", "))
+	}
+	var read func(ctx context.Context) ([]byte, error)
+	switch provider {
+	case "aws":
+		read = func(ctx context.Context) ([]byte, error) {
+			// IMDSv2, the session token is required when IMDSv1 is disabled on the instance
+			session, err := request(ctx, http.MethodPut, endpoint+"/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+			if err != nil {
+				return nil, err
+			}
+			return get(ctx, endpoint+"/latest/user-data", map[string]string{"X-aws-ec2-metadata-token": string(session)})
+		}
+	case "gcp":
+		read = func(ctx context.Context) ([]byte, error) {
+			return get(ctx, endpoint+"/computeMetadata/v1/instance/attributes/k0s-join-token", map[string]string{"Metadata-Flavor": "Google"})
+		}
+	case "azure":
+		read = func(ctx context.Context) ([]byte, error) {
+			data, err := get(ctx, endpoint+"/metadata/instance/compute/userData?api-version=2021-01-01&format=text", map[string]string{"Metadata": "true"})
+			if err != nil {
+				return nil, err
+			}
+			return base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		}
+	}
+	return fetch(ctx, provider+" instance metadata", read)
+}
+
+// fetch retries reading the token until it's found, the last error is returned once the retries are used up
+func fetch(ctx context.Context, source string, read func(ctx context.Context) ([]byte, error)) (string, error) {
+	var token string
+	err := retry.OnError(fetchBackoff, func(err error) bool {
+		logrus.Warnf("failed to fetch the join token from %s, retrying: %v", source, err)
+		return ctx.Err() == nil
+	}, func() error {
+		data, err := read(ctx)
+		if err != nil {
+			return err
+		}
+		token, err = ExtractToken(data)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the join token from %s: %w", source, err)
+	}
+	return token, nil
+}
+
+// ExtractToken finds the join token in the fetched data, which is either the token itself or holds it in a
+// K0S_JOIN_TOKEN=<token> line
+func ExtractToken(data []byte) (string, error) {
+	if token := strings.TrimSpace(string(data)); isJoinToken(token) {
+		return token, nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export ")
+		if !strings.HasPrefix(line, UserDataTokenVar+"=") {
+			continue
+		}
+		token := strings.Trim(strings.TrimPrefix(line, UserDataTokenVar+"="), `"'`)
+		if isJoinToken(token) {
+			return token, nil
+		}
+	}
+	return "", fmt.Errorf("no join token found")
+}
+
+func isJoinToken(token string) bool {
+	_, err := DecodeJoinToken(token)
+	return token != "" && err == nil
+}
+
+func get(ctx context.Context, url string, headers map[string]string) ([]byte, error) {
+	return request(ctx, http.MethodGet, url, headers)
+}
+
+func request(ctx context.Context, method string, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", method, req.URL.Path, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
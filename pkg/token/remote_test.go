@@ -0,0 +1,157 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func testJoinToken(t *testing.T) string {
+	token, err := JoinEncode(bytes.NewBufferString("apiVersion: v1\nkind: Config\n"))
+	require.NoError(t, err)
+	return token
+}
+
+func withFastBackoff(t *testing.T) {
+	saved := fetchBackoff
+	fetchBackoff = wait.Backoff{Steps: 3, Duration: time.Millisecond}
+	t.Cleanup(func() { fetchBackoff = saved })
+}
+
+func TestExtractToken(t *testing.T) {
+	token := testJoinToken(t)
+
+	extracted, err := ExtractToken([]byte(token + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, token, extracted)
+
+	extracted, err = ExtractToken([]byte("#!/bin/sh\nexport K0S_JOIN_TOKEN=\"" + token + "\"\nk0s install worker\n"))
+	require.NoError(t, err)
+	assert.Equal(t, token, extracted)
+
+	_, err = ExtractToken([]byte("<html>not found</html>"))
+	assert.Error(t, err)
+}
+
+func TestFetchFromURL(t *testing.T) {
+	withFastBackoff(t)
+	token := testJoinToken(t)
+	calls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		// the first attempt fails, as if the network wasn't up yet
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(token))
+	}))
+	defer server.Close()
+	client := fetchClient
+	fetchClient = server.Client()
+	fetchClient.CheckRedirect = refuseInsecureRedirect
+	defer func() { fetchClient = client }()
+
+	assert.True(t, IsURL(server.URL))
+	fetched, err := FetchFromURL(context.TODO(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, token, fetched)
+	assert.Equal(t, 2, calls)
+
+	// the token isn't fetched over plain http
+	plainURL := "http://" + strings.TrimPrefix(server.URL, "https://")
+	assert.True(t, IsURL(plainURL))
+	_, err = FetchFromURL(context.TODO(), plainURL)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestFetchFromURLRefusesInsecureRedirect(t *testing.T) {
+	withFastBackoff(t)
+	plainCalls := 0
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plainCalls++
+		_, _ = w.Write([]byte(testJoinToken(t)))
+	}))
+	defer plain.Close()
+	server := httptest.NewTLSServer(http.RedirectHandler(plain.URL, http.StatusFound))
+	defer server.Close()
+	client := fetchClient
+	fetchClient = server.Client()
+	fetchClient.CheckRedirect = refuseInsecureRedirect
+	defer func() { fetchClient = client }()
+
+	_, err := FetchFromURL(context.TODO(), server.URL)
+	assert.Error(t, err)
+	assert.Zero(t, plainCalls)
+}
+
+func TestFetchFromMetadata(t *testing.T) {
+	withFastBackoff(t)
+	token := testJoinToken(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		_, _ = w.Write([]byte("session"))
+	})
+	mux.HandleFunc("/latest/user-data", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("K0S_JOIN_TOKEN=" + token + "\n"))
+	})
+	mux.HandleFunc("/computeMetadata/v1/instance/attributes/k0s-join-token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(token))
+	})
+	mux.HandleFunc("/metadata/instance/compute/userData", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString([]byte(token))))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	saved := metadataEndpoints
+	metadataEndpoints = map[string]string{"aws": server.URL, "gcp": server.URL, "azure": server.URL}
+	defer func() { metadataEndpoints = saved }()
+
+	for _, provider := range MetadataProviders {
+		fetched, err := FetchFromMetadata(context.TODO(), provider)
+		require.NoError(t, err, provider)
+		assert.Equal(t, token, fetched, provider)
+	}
+
+	_, err := FetchFromMetadata(context.TODO(), "openstack")
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	// EnvVar holds the join token when it's given neither as an argument nor as a token file
+	EnvVar = "K0S_TOKEN"
+	// Stdin as the token file reads the join token from the standard input
+	Stdin = "-"
+)
+
+var stdin io.Reader = os.Stdin
+
+// ReadFile reads the join token from the file, or from the standard input for "-"
+func ReadFile(path string) (string, error) {
+	var data []byte
+	var err error
+	source := path
+	if path == Stdin {
+		source = "the standard input"
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("no join token in %s", source)
+	}
+	return token, nil
+}
+
+// FromEnv returns the join token of K0S_TOKEN and removes the variable, so that the processes started by k0s don't
+// inherit it
+func FromEnv() string {
+	token := strings.TrimSpace(os.Getenv(EnvVar))
+	_ = os.Unsetenv(EnvVar)
+	return token
+}
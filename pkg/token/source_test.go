@@ -0,0 +1,58 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(path, []byte("abc\n"), 0600))
+	token, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token)
+
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = strings.NewReader("def\n")
+	token, err = ReadFile(Stdin)
+	require.NoError(t, err)
+	assert.Equal(t, "def", token)
+
+	stdin = strings.NewReader("")
+	_, err = ReadFile(Stdin)
+	assert.Error(t, err)
+}
+
+func TestFromEnv(t *testing.T) {
+	require.NoError(t, os.Setenv(EnvVar, "abc"))
+	assert.Equal(t, "abc", FromEnv())
+	_, found := os.LookupEnv(EnvVar)
+	assert.False(t, found)
+	assert.Equal(t, "", FromEnv())
+}
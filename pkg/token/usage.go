@@ -0,0 +1,142 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// UsageLimitKey is the bootstrap token secret key holding the amount of nodes that may join with the token
+	// before it's consumed
+	UsageLimitKey = "k0s-usage-limit"
+	// UsesAnnotation records the nodes that joined with the token
+	UsesAnnotation = "k0s.k0sproject.io/uses"
+)
+
+// ErrTokenConsumed is returned when a token has been used up to its usage limit already
+var ErrTokenConsumed = goerrors.New("token has been used up")
+
+// UsageLimitFromData reads the usage limit from the bootstrap token secret data, 0 means unlimited
+func UsageLimitFromData(data map[string][]byte) (int, error) {
+	limit, found := data[UsageLimitKey]
+	if !found {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(string(limit))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", UsageLimitKey, err)
+	}
+	return n, nil
+}
+
+// Uses are the names of the nodes that joined with a token
+type Uses []string
+
+// UsesFromAnnotation decodes the uses recorded in the token secret annotations
+func UsesFromAnnotation(annotations map[string]string) (Uses, error) {
+	uses := Uses{}
+	value, found := annotations[UsesAnnotation]
+	if !found || value == "" {
+		return uses, nil
+	}
+	if err := json.Unmarshal([]byte(value), &uses); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", UsesAnnotation, err)
+	}
+	return uses, nil
+}
+
+// Annotation encodes the uses for storing in the token secret annotations
+func (u Uses) Annotation() string {
+	data, _ := json.Marshal(u)
+	return string(data)
+}
+
+// Add records the node, returns false if it was recorded already
+func (u *Uses) Add(node string) bool {
+	for _, n := range *u {
+		if n == node {
+			return false
+		}
+	}
+	*u = append(*u, node)
+	return true
+}
+
+// Consumed tells if the token has been used up
+func (u Uses) Consumed(limit int) bool {
+	return limit > 0 && len(u) >= limit
+}
+
+// RecordUse records that the node joined with the token. Once the token has been used up to its usage limit it's
+// deleted, so that it can't be replayed to join further nodes. Tokens without a usage limit are left as is.
+// The update and the delete are guarded by the resourceVersion of the secret, so of the nodes joining at the
+// same time only as many as the limit allows get a nil error, the others get ErrTokenConsumed. The use is thus
+// to be recorded before the join is answered.
+func (m *Manager) RecordUse(tokenID string, node string) error {
+	secrets := m.client.CoreV1().Secrets("kube-system")
+	name := fmt.Sprintf("bootstrap-token-%s", tokenID)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(context.TODO(), name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			// deleted by the join which used it up
+			return ErrTokenConsumed
+		}
+		if err != nil {
+			return err
+		}
+		limit, err := UsageLimitFromData(secret.Data)
+		if err != nil || limit == 0 {
+			return err
+		}
+		uses, err := UsesFromAnnotation(secret.Annotations)
+		if err != nil {
+			return err
+		}
+		consumed := uses.Consumed(limit)
+		if !uses.Add(node) {
+			return nil
+		}
+		if consumed {
+			return ErrTokenConsumed
+		}
+		if uses.Consumed(limit) {
+			logrus.Infof("token %s was used by %s and has reached its usage limit of %d, deleting it", tokenID, node, limit)
+			err := secrets.Delete(context.TODO(), name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &secret.ResourceVersion}})
+			if errors.IsNotFound(err) {
+				return ErrTokenConsumed
+			}
+			return err
+		}
+		logrus.Infof("token %s was used by %s, %d of %d uses", tokenID, node, len(uses), limit)
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[UsesAnnotation] = uses.Annotation()
+		// the secret keeps the resourceVersion it was read with, a concurrent use makes the update conflict
+		_, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+}
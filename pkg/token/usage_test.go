@@ -0,0 +1,102 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUses(t *testing.T) {
+	uses := Uses{}
+	assert.True(t, uses.Add("node-1"))
+	assert.False(t, uses.Add("node-1"))
+	assert.False(t, uses.Consumed(2))
+	assert.True(t, uses.Add("node-2"))
+	assert.True(t, uses.Consumed(2))
+	assert.False(t, uses.Consumed(0))
+
+	decoded, err := UsesFromAnnotation(map[string]string{UsesAnnotation: uses.Annotation()})
+	require.NoError(t, err)
+	assert.Equal(t, uses, decoded)
+
+	_, err = UsageLimitFromData(map[string][]byte{UsageLimitKey: []byte("once")})
+	assert.Error(t, err)
+}
+
+func TestCreateWithUsageLimit(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	m := NewManagerForClient(client)
+	tok, err := m.Create(time.Hour, "worker", CreateOptions{UsageLimit: 2})
+	require.NoError(t, err)
+
+	secret, err := client.CoreV1().Secrets("kube-system").Get(context.TODO(), "bootstrap-token-"+tok[:6], metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2", secret.StringData[UsageLimitKey])
+	// the join quota keeps more nodes from joining than the token may be used for
+	assert.Equal(t, "2", secret.StringData[MaxJoinsKey])
+}
+
+func TestRecordUse(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-abcdef", Namespace: "kube-system"},
+			Type:       v1.SecretTypeBootstrapToken,
+			Data:       map[string][]byte{"token-id": []byte("abcdef"), UsageLimitKey: []byte("2")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-ghijkl", Namespace: "kube-system"},
+			Type:       v1.SecretTypeBootstrapToken,
+			Data:       map[string][]byte{"token-id": []byte("ghijkl")},
+		},
+	)
+	m := NewManagerForClient(client)
+	secrets := client.CoreV1().Secrets("kube-system")
+
+	require.NoError(t, m.RecordUse("abcdef", "controller-2"))
+	require.NoError(t, m.RecordUse("abcdef", "controller-2"))
+	secret, err := secrets.Get(context.TODO(), "bootstrap-token-abcdef", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `["controller-2"]`, secret.Annotations[UsesAnnotation])
+
+	tokens, err := m.List("")
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	for _, tok := range tokens {
+		if tok.ID == "abcdef" {
+			assert.Equal(t, []string{"abcdef", "worker", "", "", "1/2"}, tok.ToArray())
+		}
+	}
+
+	require.NoError(t, m.RecordUse("abcdef", "controller-3"))
+	_, err = secrets.Get(context.TODO(), "bootstrap-token-abcdef", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err), "the used up token should be deleted")
+	// the token has been used up, further joins are refused
+	assert.ErrorIs(t, m.RecordUse("abcdef", "controller-4"), ErrTokenConsumed)
+
+	require.NoError(t, m.RecordUse("ghijkl", "controller-2"))
+	secret, err = secrets.Get(context.TODO(), "bootstrap-token-ghijkl", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, secret.Annotations[UsesAnnotation])
+}
@@ -0,0 +1,170 @@
+// +build !windows
+
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/apis/v1beta1"
+	"github.com/k0sproject/k0s/pkg/backup"
+	"github.com/k0sproject/k0s/pkg/build"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/etcd"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+// Prepare keeps the running binary and a backup of the controller in the upgrade dir, replacing the ones of a
+// previous upgrade. It's run with the version being upgraded from, while the controller is running.
+func Prepare(cfgPath string, clusterSpec *v1beta1.ClusterSpec, k0sVars constant.CfgVars, window time.Duration) (*State, error) {
+	if err := os.RemoveAll(k0sVars.UpgradeDir); err != nil {
+		return nil, err
+	}
+	if err := util.InitDirectory(k0sVars.UpgradeDir, constant.DataDirMode); err != nil {
+		return nil, err
+	}
+
+	exe, err := executable()
+	if err != nil {
+		return nil, err
+	}
+	binary := filepath.Join(k0sVars.UpgradeDir, "k0s-"+build.Version)
+	logrus.Infof("keeping %s as %s", exe, binary)
+	if err := util.FileCopy(exe, binary); err != nil {
+		return nil, fmt.Errorf("failed to keep the k0s binary: %w", err)
+	}
+
+	mgr, err := backup.NewBackupManager()
+	if err != nil {
+		return nil, err
+	}
+	if err := mgr.RunBackup(cfgPath, clusterSpec, k0sVars, k0sVars.UpgradeDir); err != nil {
+		return nil, fmt.Errorf("failed to back up the controller: %w", err)
+	}
+	archives, err := filepath.Glob(filepath.Join(k0sVars.UpgradeDir, "k0s_backup_*.tar.gz"))
+	if err != nil || len(archives) != 1 {
+		return nil, fmt.Errorf("failed to find the backup archive in %s", k0sVars.UpgradeDir)
+	}
+
+	state := &State{
+		PreviousVersion: build.Version,
+		PreviousBinary:  binary,
+		Backup:          archives[0],
+		PreparedAt:      time.Now(),
+		Window:          window,
+	}
+	if clusterSpec.Storage != nil && clusterSpec.Storage.Type == v1beta1.EtcdStorageType {
+		if state.EtcdMembers, err = etcdMembers(k0sVars); err != nil {
+			return nil, fmt.Errorf("failed to list the etcd members: %w", err)
+		}
+	}
+	return state, state.Save(k0sVars)
+}
+
+func etcdMembers(k0sVars constant.CfgVars) (int, error) {
+	client, err := etcd.NewClient(k0sVars.CertRootDir, k0sVars.EtcdCertDir)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	members, err := client.ListMembers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return len(members), nil
+}
+
+// Rollback restores the binary and the backup kept for the upgrade, if the post-upgrade health checks failed or
+// if forced. It's run with k0s stopped. The etcd data dir of the upgraded version is moved aside, as etcd restores
+// only into a new data dir. The backup isn't restored if etcd had other members, the restored controller would
+// start a single-member etcd cluster of its own, binaryOnly restores only the binary then. The upgrade dir is
+// removed once rolled back.
+func Rollback(k0sVars constant.CfgVars, restoredConfigPath string, force bool, binaryOnly bool) error {
+	state, err := LoadState(k0sVars)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no upgrade has been prepared, run `k0s upgrade prepare` before upgrading")
+	}
+	events, err := status.NewHistory(k0sVars.StatusHistoryPath, status.DefaultHistoryRetention).List(time.Time{})
+	if err != nil {
+		return err
+	}
+	health, reason := state.Check(events, time.Now())
+	switch {
+	case health == HealthFailed:
+		logrus.Infof("the upgrade to %s failed the health checks: %s", state.Version, reason)
+	case !force:
+		return fmt.Errorf("the health checks of the upgrade are %s, use --force to roll back anyway", health)
+	}
+
+	if state.EtcdMembers > 1 && !binaryOnly {
+		return fmt.Errorf("etcd had %d members when the upgrade was prepared, restoring the backup would split the controller off into an etcd cluster of its own, use --binary-only to restore only the k0s binary", state.EtcdMembers)
+	}
+
+	exe, err := executable()
+	if err != nil {
+		return err
+	}
+	if binaryOnly {
+		logrus.Info("keeping the controller state, restoring only the k0s binary")
+	} else {
+		if util.DirExists(k0sVars.EtcdDataDir) {
+			aside := fmt.Sprintf("%s.rollback-%d", k0sVars.EtcdDataDir, time.Now().Unix())
+			logrus.Infof("moving the etcd data dir to %s", aside)
+			if err := os.Rename(k0sVars.EtcdDataDir, aside); err != nil {
+				return err
+			}
+		}
+		mgr, err := backup.NewBackupManager()
+		if err != nil {
+			return err
+		}
+		if err := mgr.RunRestore(state.Backup, k0sVars, restoredConfigPath); err != nil {
+			return err
+		}
+	}
+
+	// the binary is replaced with a rename, as the running binary can't be written to
+	logrus.Infof("restoring k0s %s to %s", state.PreviousVersion, exe)
+	tmp := exe + ".rollback"
+	if err := util.FileCopy(state.PreviousBinary, tmp); err != nil {
+		return fmt.Errorf("failed to restore the k0s binary: %w", err)
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		return fmt.Errorf("failed to restore the k0s binary: %w", err)
+	}
+	return os.RemoveAll(k0sVars.UpgradeDir)
+}
+
+func executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the k0s binary: %w", err)
+	}
+	return filepath.EvalSymlinks(exe)
+}
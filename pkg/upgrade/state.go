@@ -0,0 +1,161 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package upgrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+// DefaultWindow is how long the controller has to pass the health checks after an upgrade
+const DefaultWindow = 10 * time.Minute
+
+const (
+	stateFile = "state.json"
+	// controllerComponent is the name the controller records its starts with in the status history
+	controllerComponent = "controller"
+)
+
+// State is the upgrade prepared on the controller: the binary and the backup of the version being upgraded from,
+// and once the controller has started with a new version, the version and the time of the upgrade
+type State struct {
+	PreviousVersion string        `json:"previousVersion"`
+	PreviousBinary  string        `json:"previousBinary"`
+	Backup          string        `json:"backup"`
+	PreparedAt      time.Time     `json:"preparedAt"`
+	Window          time.Duration `json:"window"`
+	Version         string        `json:"version,omitempty"`
+	UpgradedAt      *time.Time    `json:"upgradedAt,omitempty"`
+	// EtcdMembers is the size of the etcd cluster when the upgrade was prepared, 0 without etcd
+	EtcdMembers int `json:"etcdMembers,omitempty"`
+}
+
+// Health is the verdict of the post-upgrade health checks
+type Health string
+
+const (
+	// HealthNotUpgraded means the controller hasn't started with a new version since the upgrade was prepared
+	HealthNotUpgraded Health = "NotUpgraded"
+	// HealthPending means the health checks haven't failed so far and the window is still open
+	HealthPending Health = "Pending"
+	// HealthPassed means the health checks didn't fail within the window
+	HealthPassed Health = "Passed"
+	// HealthFailed means a component became unhealthy within the window, or didn't become healthy again
+	HealthFailed Health = "Failed"
+)
+
+// LoadState reads the prepared upgrade, nil if there is none
+func LoadState(k0sVars constant.CfgVars) (*State, error) {
+	data, err := ioutil.ReadFile(filepath.Join(k0sVars.UpgradeDir, stateFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse the upgrade state: %w", err)
+	}
+	return state, nil
+}
+
+// Save writes the state into the upgrade dir
+func (s *State) Save(k0sVars constant.CfgVars) error {
+	if err := util.InitDirectory(k0sVars.UpgradeDir, constant.DataDirMode); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(k0sVars.UpgradeDir, stateFile), data, 0600)
+}
+
+// MarkStarted records the upgrade when the controller starts with another version than the prepared one. The
+// window of the health checks starts from then. It's called before the controller records its start.
+func MarkStarted(k0sVars constant.CfgVars, version string, now time.Time) error {
+	state, err := LoadState(k0sVars)
+	if err != nil || state == nil {
+		return err
+	}
+	if state.UpgradedAt != nil || state.PreviousVersion == version {
+		return nil
+	}
+	state.Version = version
+	state.UpgradedAt = &now
+	return state.Save(k0sVars)
+}
+
+// Check evaluates the post-upgrade health checks from the component health recorded in the status history. The
+// upgrade fails if a component is recorded unhealthy within the window, or if a component healthy in the last run
+// before the upgrade isn't healthy again by the end of the window. The reason is given for the failed checks.
+func (s *State) Check(events []status.Event, now time.Time) (Health, string) {
+	if s.UpgradedAt == nil {
+		return HealthNotUpgraded, ""
+	}
+	window := s.Window
+	if window == 0 {
+		window = DefaultWindow
+	}
+	deadline := s.UpgradedAt.Add(window)
+
+	var lastRun time.Time
+	for _, e := range events {
+		if e.Timestamp.Before(*s.UpgradedAt) && e.Component == controllerComponent && e.Type == status.EventStarted {
+			lastRun = e.Timestamp
+		}
+	}
+	before := map[string]bool{}
+	after := map[string]bool{}
+	for _, e := range events {
+		switch {
+		case e.Timestamp.Before(lastRun) || e.Timestamp.After(deadline):
+			continue
+		case e.Timestamp.Before(*s.UpgradedAt):
+			if e.Type == status.EventHealthy {
+				before[e.Component] = true
+			}
+		case e.Type == status.EventUnhealthy:
+			return HealthFailed, fmt.Sprintf("%s became unhealthy at %s: %s", e.Component, e.Timestamp.Format(time.RFC3339), e.Message)
+		case e.Type == status.EventHealthy:
+			after[e.Component] = true
+		}
+	}
+	if now.Before(deadline) {
+		return HealthPending, ""
+	}
+	var missing []string
+	for component := range before {
+		if !after[component] {
+			missing = append(missing, component)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return HealthFailed, fmt.Sprintf("%v didn't become healthy within %s of the upgrade", missing, window)
+	}
+	return HealthPassed, ""
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package upgrade
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+	"github.com/k0sproject/k0s/pkg/status"
+)
+
+func TestMarkStarted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.GetConfig(dir)
+
+	// nothing prepared
+	require.NoError(t, MarkStarted(k0sVars, "v1.21.3+k0s.0", time.Now()))
+	state, err := LoadState(k0sVars)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	prepared := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, (&State{PreviousVersion: "v1.21.2+k0s.0", PreparedAt: prepared}).Save(k0sVars))
+
+	// restarted with the prepared version
+	require.NoError(t, MarkStarted(k0sVars, "v1.21.2+k0s.0", prepared.Add(time.Minute)))
+	state, err = LoadState(k0sVars)
+	require.NoError(t, err)
+	assert.Nil(t, state.UpgradedAt)
+
+	upgraded := prepared.Add(time.Hour)
+	require.NoError(t, MarkStarted(k0sVars, "v1.21.3+k0s.0", upgraded))
+	// the later starts keep the time of the upgrade
+	require.NoError(t, MarkStarted(k0sVars, "v1.21.3+k0s.0", upgraded.Add(time.Hour)))
+	state, err = LoadState(k0sVars)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.21.3+k0s.0", state.Version)
+	assert.True(t, upgraded.Equal(*state.UpgradedAt))
+}
+
+func TestCheck(t *testing.T) {
+	prepared := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	upgraded := prepared.Add(time.Hour)
+	at := func(d time.Duration) time.Time { return upgraded.Add(d) }
+	state := &State{PreviousVersion: "v1.21.2+k0s.0", PreparedAt: prepared, Window: 10 * time.Minute, Version: "v1.21.3+k0s.0", UpgradedAt: &upgraded}
+	previousRun := []status.Event{
+		{Timestamp: at(-3 * time.Hour), Component: "controller", Type: status.EventStarted},
+		{Timestamp: at(-3 * time.Hour), Component: "Kine", Type: status.EventHealthy},
+		{Timestamp: at(-2 * time.Hour), Component: "controller", Type: status.EventStarted},
+		{Timestamp: at(-2 * time.Hour), Component: "Etcd", Type: status.EventHealthy},
+		{Timestamp: at(-2 * time.Hour), Component: "APIServer", Type: status.EventHealthy},
+		{Timestamp: at(-time.Minute), Component: "APIServer", Type: status.EventStopped},
+	}
+
+	t.Run("not upgraded", func(t *testing.T) {
+		health, _ := (&State{PreviousVersion: "v1.21.2+k0s.0", PreparedAt: prepared}).Check(previousRun, at(0))
+		assert.Equal(t, HealthNotUpgraded, health)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		events := append(previousRun, status.Event{Timestamp: at(time.Minute), Component: "Etcd", Type: status.EventHealthy})
+		health, _ := state.Check(events, at(2*time.Minute))
+		assert.Equal(t, HealthPending, health)
+	})
+
+	t.Run("passed", func(t *testing.T) {
+		events := append(previousRun,
+			status.Event{Timestamp: at(time.Minute), Component: "Etcd", Type: status.EventHealthy},
+			status.Event{Timestamp: at(2 * time.Minute), Component: "APIServer", Type: status.EventHealthy},
+			// after the window
+			status.Event{Timestamp: at(time.Hour), Component: "APIServer", Type: status.EventUnhealthy},
+		)
+		health, _ := state.Check(events, at(2*time.Hour))
+		assert.Equal(t, HealthPassed, health)
+	})
+
+	t.Run("unhealthy within the window", func(t *testing.T) {
+		events := append(previousRun, status.Event{Timestamp: at(time.Minute), Component: "APIServer", Type: status.EventUnhealthy, Message: "APIServer health-check timed out"})
+		health, reason := state.Check(events, at(2*time.Minute))
+		assert.Equal(t, HealthFailed, health)
+		assert.Contains(t, reason, "APIServer health-check timed out")
+	})
+
+	t.Run("not healthy again", func(t *testing.T) {
+		events := append(previousRun, status.Event{Timestamp: at(time.Minute), Component: "Etcd", Type: status.EventHealthy})
+		health, reason := state.Check(events, at(time.Hour))
+		assert.Equal(t, HealthFailed, health)
+		assert.Contains(t, reason, "[APIServer]")
+	})
+}
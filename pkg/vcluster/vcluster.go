@@ -0,0 +1,227 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vcluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/k0sproject/k0s/internal/util"
+	"github.com/k0sproject/k0s/pkg/build"
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+// dirPrefix is the prefix of the manifest dirs of the virtual clusters, the applier deploys and removes them as stacks
+const dirPrefix = "vcluster-"
+
+// Options are the settings of a virtual cluster
+type Options struct {
+	Name        string
+	Image       string
+	StorageSize string
+	ServiceType string
+}
+
+// DefaultImage returns the k0s image matching the running k0s version
+func DefaultImage() string {
+	version := strings.Replace(build.Version, "+", "-", 1)
+	if version == "" {
+		version = "latest"
+	}
+	return "docker.io/k0sproject/k0s:" + version
+}
+
+// Namespace returns the host cluster namespace of the virtual cluster
+func Namespace(name string) string {
+	return dirPrefix + name
+}
+
+// validateName checks the name is usable in the namespace of the virtual cluster, which also keeps it from
+// pointing outside of the manifest dir
+func validateName(name string) error {
+	if errs := validation.IsDNS1123Label(Namespace(name)); len(errs) > 0 {
+		return fmt.Errorf("invalid virtual cluster name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Validate checks the options of the virtual cluster
+func (o Options) Validate() error {
+	if err := validateName(o.Name); err != nil {
+		return err
+	}
+	switch o.ServiceType {
+	case "ClusterIP", "NodePort", "LoadBalancer":
+	default:
+		return fmt.Errorf("unsupported service type %q", o.ServiceType)
+	}
+	return nil
+}
+
+// Create writes the manifests of the virtual cluster control plane into the manifest dir of the host controller
+func Create(k0sVars constant.CfgVars, opts Options) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	dir := filepath.Join(k0sVars.ManifestsDir, dirPrefix+opts.Name)
+	if util.DirExists(dir) {
+		return fmt.Errorf("virtual cluster %s already exists", opts.Name)
+	}
+	if err := util.InitDirectory(dir, constant.ManifestsDirMode); err != nil {
+		return err
+	}
+
+	tw := util.TemplateWriter{
+		Name:     "vcluster",
+		Template: vclusterTemplate,
+		Data: struct {
+			Options
+			Namespace string
+		}{
+			Options:   opts,
+			Namespace: Namespace(opts.Name),
+		},
+		Path: filepath.Join(dir, "vcluster.yaml"),
+	}
+	if err := tw.Write(); err != nil {
+		_ = os.RemoveAll(dir)
+		return fmt.Errorf("failed to write virtual cluster manifests: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the manifests of the virtual cluster, which makes the applier delete its resources
+func Delete(k0sVars constant.CfgVars, name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+	dir := filepath.Join(k0sVars.ManifestsDir, dirPrefix+name)
+	if !util.DirExists(dir) {
+		return fmt.Errorf("virtual cluster %s does not exist", name)
+	}
+	return os.RemoveAll(dir)
+}
+
+// List returns the names of the virtual clusters
+func List(k0sVars constant.CfgVars) ([]string, error) {
+	entries, err := ioutil.ReadDir(k0sVars.ManifestsDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), dirPrefix) {
+			names = append(names, strings.TrimPrefix(e.Name(), dirPrefix))
+		}
+	}
+	return names, nil
+}
+
+// the nested control plane runs as a controller only k0s with kine backed by sqlite on a persistent volume,
+// the tenants don't get any nodes of their own
+const vclusterTemplate = `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: {{ .Namespace }}
+  labels:
+    k0s.k0sproject.io/vcluster: {{ .Name }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: vcluster-config
+  namespace: {{ .Namespace }}
+data:
+  k0s.yaml: |
+    apiVersion: k0s.k0sproject.io/v1beta1
+    kind: Cluster
+    metadata:
+      name: {{ .Name }}
+    spec:
+      api:
+        sans:
+        - vcluster.{{ .Namespace }}
+        - vcluster.{{ .Namespace }}.svc
+      storage:
+        type: kine
+      telemetry:
+        enabled: false
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: vcluster
+  namespace: {{ .Namespace }}
+spec:
+  type: {{ .ServiceType }}
+  selector:
+    app: vcluster
+  ports:
+  - name: kube-apiserver
+    port: 6443
+    targetPort: 6443
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: vcluster
+  namespace: {{ .Namespace }}
+spec:
+  serviceName: vcluster
+  replicas: 1
+  selector:
+    matchLabels:
+      app: vcluster
+  template:
+    metadata:
+      labels:
+        app: vcluster
+    spec:
+      containers:
+      - name: k0s
+        image: {{ .Image }}
+        command: ["k0s", "controller", "--config=/etc/k0s/k0s.yaml"]
+        ports:
+        - containerPort: 6443
+          name: kube-apiserver
+        readinessProbe:
+          tcpSocket:
+            port: 6443
+          initialDelaySeconds: 10
+        volumeMounts:
+        - name: config
+          mountPath: /etc/k0s
+        - name: data
+          mountPath: /var/lib/k0s
+      volumes:
+      - name: config
+        configMap:
+          name: vcluster-config
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      accessModes: ["ReadWriteOnce"]
+      resources:
+        requests:
+          storage: {{ .StorageSize }}
+`
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package vcluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/k0sproject/k0s/pkg/constant"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Options{Name: "team-a", ServiceType: "ClusterIP"}.Validate())
+	assert.Error(t, Options{Name: "Team_A", ServiceType: "ClusterIP"}.Validate())
+	assert.Error(t, Options{Name: "team-a", ServiceType: "ExternalName"}.Validate())
+}
+
+func TestCreateListDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-vcluster")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	k0sVars := constant.CfgVars{ManifestsDir: dir}
+
+	opts := Options{Name: "team-a", Image: "docker.io/k0sproject/k0s:latest", StorageSize: "1Gi", ServiceType: "ClusterIP"}
+	require.NoError(t, Create(k0sVars, opts))
+	assert.Error(t, Create(k0sVars, opts), "creating the same virtual cluster twice should fail")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "vcluster-team-a", "vcluster.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "namespace: vcluster-team-a")
+	assert.Contains(t, string(content), "image: docker.io/k0sproject/k0s:latest")
+	assert.Contains(t, string(content), "storage: 1Gi")
+
+	names, err := List(k0sVars)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a"}, names)
+
+	// the name can't point outside of the virtual clusters
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "vcluster-team-a", "nested"), 0755))
+	assert.Error(t, Delete(k0sVars, "team-a/nested"))
+	assert.Error(t, Delete(k0sVars, "team-a/.."))
+	assert.DirExists(t, filepath.Join(dir, "vcluster-team-a", "nested"))
+
+	require.NoError(t, Delete(k0sVars, "team-a"))
+	assert.Error(t, Delete(k0sVars, "team-a"))
+	names, err = List(k0sVars)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package watchdog
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	heartbeatsMu sync.Mutex
+	heartbeats   = map[string]*Heartbeat{}
+)
+
+// Heartbeat tracks the progress of the reconcile loop of a component
+type Heartbeat struct {
+	// Component is the name of the component running the loop, as known by the component manager
+	Component string
+	// Period is the expected interval of the beats
+	Period time.Duration
+
+	mu     sync.Mutex
+	last   time.Time
+	active bool
+}
+
+// NewHeartbeat registers the heartbeat of the reconcile loop of the given component
+func NewHeartbeat(component string, period time.Duration) *Heartbeat {
+	h := &Heartbeat{Component: component, Period: period}
+	heartbeatsMu.Lock()
+	defer heartbeatsMu.Unlock()
+	heartbeats[component] = h
+	return h
+}
+
+// Start marks the loop running
+func (h *Heartbeat) Start() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+	h.active = true
+}
+
+// Beat records the completion of a loop iteration
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+}
+
+// Stop marks the loop stopped, a stopped loop is never stalled
+func (h *Heartbeat) Stop() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.active = false
+}
+
+// StallTimeout is the time without beats after which the loop is considered stalled. The extra minute leaves
+// room for the slow API calls of the short loops.
+func (h *Heartbeat) StallTimeout() time.Duration {
+	return 3*h.Period + time.Minute
+}
+
+// Stalled returns how long the running loop hasn't made progress for, if over the stall timeout
+func (h *Heartbeat) Stalled(now time.Time) (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	since := now.Sub(h.last)
+	return since, h.active && since > h.StallTimeout()
+}
+
+// registered returns the registered heartbeats ordered by the component name
+func registered() []*Heartbeat {
+	heartbeatsMu.Lock()
+	defer heartbeatsMu.Unlock()
+	list := make([]*Heartbeat, 0, len(heartbeats))
+	for _, h := range heartbeats {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Component < list[j].Component })
+	return list
+}
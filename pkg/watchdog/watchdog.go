@@ -0,0 +1,184 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package watchdog
+
+import (
+	"expvar"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxGoroutines is the goroutine count of the k0s process considered an explosion
+	DefaultMaxGoroutines = 10000
+	// maxDumps is the amount of stack dumps kept in the dump dir
+	maxDumps = 10
+	// goroutineDumpInterval limits the stack dumps taken because of too many goroutines
+	goroutineDumpInterval = 10 * time.Minute
+)
+
+var (
+	// goroutines exposes the goroutine count of the k0s process on the debug server under /debug/vars
+	goroutines = expvar.NewInt("k0s_goroutines")
+	// stalls counts the stalls detected per component
+	stalls = expvar.NewMap("k0s_watchdog_stalls")
+)
+
+// Watchdog detects the stalled reconcile loops and the goroutine explosions in the k0s process, and dumps the
+// stacks of all goroutines into DumpDir
+type Watchdog struct {
+	DumpDir       string
+	MaxGoroutines int
+	Interval      time.Duration
+	// Restart restarts the given component, the stalled components aren't restarted if nil
+	Restart func(component string) error
+
+	log               *logrus.Entry
+	stopCh            chan struct{}
+	reported          map[string]bool
+	lastGoroutineDump time.Time
+}
+
+// Init sets the defaults
+func (w *Watchdog) Init() error {
+	w.log = logrus.WithField("component", "watchdog")
+	if w.Interval == 0 {
+		w.Interval = 10 * time.Second
+	}
+	if w.MaxGoroutines == 0 {
+		w.MaxGoroutines = DefaultMaxGoroutines
+	}
+	w.reported = map[string]bool{}
+	return nil
+}
+
+// Run starts the periodic checks
+func (w *Watchdog) Run() error {
+	w.stopCh = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check(time.Now())
+			case <-w.stopCh:
+				w.log.Info("watchdog done")
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the checks
+func (w *Watchdog) Stop() error {
+	if w.stopCh != nil {
+		close(w.stopCh)
+	}
+	return nil
+}
+
+// Healthy dummy implementation
+func (w *Watchdog) Healthy() error { return nil }
+
+func (w *Watchdog) check(now time.Time) {
+	for _, h := range registered() {
+		since, stalled := h.Stalled(now)
+		if !stalled {
+			delete(w.reported, h.Component)
+			continue
+		}
+		if w.reported[h.Component] {
+			continue
+		}
+		w.reported[h.Component] = true
+		stalls.Add(h.Component, 1)
+		w.log.Errorf("the reconcile loop of %s has not made progress for %s", h.Component, since.Round(time.Second))
+		w.dumpStacks(fmt.Sprintf("%s stalled", h.Component), now)
+
+		if w.Restart != nil {
+			w.log.Warnf("restarting %s", h.Component)
+			if err := w.Restart(h.Component); err != nil {
+				w.log.Errorf("failed to restart %s: %v", h.Component, err)
+				continue
+			}
+			// the restarted loop gets a new chance
+			delete(w.reported, h.Component)
+		}
+	}
+
+	count := runtime.NumGoroutine()
+	goroutines.Set(int64(count))
+	if count > w.MaxGoroutines && now.Sub(w.lastGoroutineDump) > goroutineDumpInterval {
+		w.lastGoroutineDump = now
+		w.log.Errorf("the k0s process is running %d goroutines, more than the limit of %d", count, w.MaxGoroutines)
+		w.dumpStacks(fmt.Sprintf("%d goroutines", count), now)
+	}
+}
+
+// dumpStacks writes the stacks of all the goroutines into a new file in the dump dir
+func (w *Watchdog) dumpStacks(reason string, now time.Time) {
+	path, err := w.writeDump(reason, now)
+	if err != nil {
+		w.log.Errorf("failed to dump the goroutine stacks: %v", err)
+		return
+	}
+	w.log.Infof("goroutine stacks dumped to %s", path)
+	w.pruneDumps()
+}
+
+func (w *Watchdog) writeDump(reason string, now time.Time) (string, error) {
+	if err := os.MkdirAll(w.DumpDir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(w.DumpDir, fmt.Sprintf("k0s-stacks-%s.txt", now.Format("2006-01-02T15_04_05.000")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "# %s at %s\n\n", reason, now.Format(time.RFC3339)); err != nil {
+		return "", err
+	}
+	if err := pprof.Lookup("goroutine").WriteTo(f, 2); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// pruneDumps removes the oldest dumps exceeding maxDumps
+func (w *Watchdog) pruneDumps() {
+	dumps, err := filepath.Glob(filepath.Join(w.DumpDir, "k0s-stacks-*.txt"))
+	if err != nil || len(dumps) <= maxDumps {
+		return
+	}
+	// the timestamps in the names sort chronologically
+	sort.Strings(dumps)
+	for _, dump := range dumps[:len(dumps)-maxDumps] {
+		if err := os.Remove(dump); err != nil {
+			w.log.Warnf("failed to remove old stack dump: %v", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 k0s authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package watchdog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStalledLoop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-watchdog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var restarted []string
+	w := &Watchdog{DumpDir: dir, Restart: func(component string) error {
+		restarted = append(restarted, component)
+		return nil
+	}}
+	require.NoError(t, w.Init())
+
+	h := NewHeartbeat("TestStalledLoop", time.Second)
+	h.Start()
+	now := time.Now()
+	w.check(now)
+	assert.Empty(t, restarted)
+
+	later := now.Add(h.StallTimeout() + time.Second)
+	w.check(later)
+	assert.Equal(t, []string{"TestStalledLoop"}, restarted)
+	dumps, err := filepath.Glob(filepath.Join(dir, "k0s-stacks-*.txt"))
+	require.NoError(t, err)
+	require.Len(t, dumps, 1)
+	content, err := ioutil.ReadFile(dumps[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "TestStalledLoop stalled")
+	assert.Contains(t, string(content), "goroutine")
+
+	// a stopped loop is not stalled
+	h.Stop()
+	w.check(later.Add(time.Hour))
+	assert.Len(t, restarted, 1)
+}
+
+func TestStallReportedOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-watchdog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := &Watchdog{DumpDir: dir}
+	require.NoError(t, w.Init())
+
+	h := NewHeartbeat("TestStallReportedOnce", time.Second)
+	h.Start()
+	later := time.Now().Add(h.StallTimeout() + time.Second)
+	w.check(later)
+	w.check(later.Add(time.Second))
+	assert.True(t, w.reported["TestStallReportedOnce"])
+
+	h.Beat()
+	w.check(time.Now())
+	assert.False(t, w.reported["TestStallReportedOnce"])
+	h.Stop()
+}
+
+func TestPruneDumps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k0s-watchdog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := &Watchdog{DumpDir: dir, MaxGoroutines: 1}
+	require.NoError(t, w.Init())
+	now := time.Now()
+	for i := 0; i < maxDumps+2; i++ {
+		w.dumpStacks("test", now.Add(time.Duration(i)*time.Second))
+	}
+	dumps, err := filepath.Glob(filepath.Join(dir, "k0s-stacks-*.txt"))
+	require.NoError(t, err)
+	assert.Len(t, dumps, maxDumps)
+	assert.NotContains(t, dumps, filepath.Join(dir, "k0s-stacks-"+now.Format("2006-01-02T15_04_05.000")+".txt"))
+}